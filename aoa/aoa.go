@@ -6,7 +6,11 @@
 package aoa
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"runtime"
+	"sync"
 	"time"
 
 	"github.com/google/gousb"
@@ -28,6 +32,20 @@ const (
 	bmRequestTypeOut = 0x40
 
 	usbTimeout = 1000 * time.Millisecond
+
+	// Descriptor registration is retried this many times; a device that
+	// has just re-enumerated sometimes stalls the first control transfers.
+	registerAttempts = 3
+
+	// Android creates the input device for a registered HID a little
+	// later, and reports sent before that are lost without an error.
+	// Registering doesn't wait for it: the first report to a HID waits
+	// until maxSettle after registration, or only minSettle once
+	// ConfirmSettle says reports on this connection get through. If such
+	// a quick first report fails, it is retried at maxSettle and the
+	// connection goes back to waiting maxSettle.
+	minSettle = 50 * time.Millisecond
+	maxSettle = 300 * time.Millisecond
 )
 
 // DescriptorType identifies which HID descriptor to use.
@@ -39,6 +57,7 @@ const (
 	DescSystemControl                        // Generic Desktop / System Control (Usage Page 0x01)
 	DescCameraControl                        // Camera Control (Usage Page 0x90)
 	DescTouchScreen                          // Touch Screen Digitizer (Usage Page 0x0D)
+	DescComposite                            // System Control + Consumer Control + Touch Screen, by report ID
 )
 
 func (d DescriptorType) String() string {
@@ -53,6 +72,8 @@ func (d DescriptorType) String() string {
 		return "Camera Control (0x90)"
 	case DescTouchScreen:
 		return "Touch Screen (0x0D)"
+	case DescComposite:
+		return "Composite (0x01 + 0x0C + 0x0D)"
 	default:
 		return "Unknown"
 	}
@@ -152,7 +173,7 @@ var cameraControlDescriptor = []byte{
 }
 
 // Touch Screen HID report descriptor (single-touch digitizer).
-// 5-byte report: [tip_switch(1bit)+in_range(1bit)+pad(6bits), x_lo, x_hi, y_lo, y_hi]
+// 8-byte report: [tip_switch(1bit)+in_range(1bit)+pad(6bits), x_lo, x_hi, y_lo, y_hi, pressure, width, height]
 // Used to simulate swipe gestures by sending a sequence of touch reports.
 var touchScreenDescriptor = []byte{
 	0x05, 0x0D, // Usage Page (Digitizers)
@@ -191,23 +212,113 @@ var touchScreenDescriptor = []byte{
 	0x09, 0x31, //     Usage (Y)
 	0x81, 0x02, //     Input (Data, Variable, Absolute)
 
+	// Tip Pressure — 8 bits (0-255)
+	0x05, 0x0D,       //     Usage Page (Digitizers)
+	0x09, 0x30,       //     Usage (Tip Pressure)
+	0x15, 0x00,       //     Logical Minimum (0)
+	0x26, 0xFF, 0x00, //     Logical Maximum (255)
+	0x75, 0x08,       //     Report Size (8)
+	0x95, 0x01,       //     Report Count (1)
+	0x81, 0x02,       //     Input (Data, Variable, Absolute)
+
+	// Contact width and height — 8 bits each (0-255)
+	0x09, 0x48, //     Usage (Width)
+	0x09, 0x49, //     Usage (Height)
+	0x95, 0x02, //     Report Count (2)
+	0x81, 0x02, //     Input (Data, Variable, Absolute)
+
 	0xC0, //   End Collection (Logical)
 	0xC0, // End Collection (Application)
 }
 
-// TouchReport builds a 5-byte touch screen report.
+// Contact is how hard and how wide a touch presses on the screen, in
+// the descriptor's 0-255 units. Some firmware builds treat zero-pressure
+// contacts differently, e.g. when recognizing a long press.
+type Contact struct {
+	Pressure uint8
+	Width    uint8
+	Height   uint8
+}
+
+// DefaultContact reports no pressure or size, as touches did before the
+// descriptor had them. Non-zero values are for firmware that needs them.
+var DefaultContact = Contact{}
+
+// TouchReport builds an 8-byte touch screen report with DefaultContact.
 // tip: true = finger touching, false = finger lifted.
 // x, y: coordinates in 0-32767 range.
 func TouchReport(tip bool, x, y uint16) []byte {
+	return TouchReportContact(tip, x, y, DefaultContact)
+}
+
+// TouchReportContact is like TouchReport with the given contact. A lifted
+// finger always reports zero pressure and size.
+func TouchReportContact(tip bool, x, y uint16, c Contact) []byte {
 	var flags byte
 	if tip {
 		flags = 0x03 // bit 0 = Tip Switch, bit 1 = In Range
+	} else {
+		c = Contact{}
 	}
 	return []byte{
 		flags,
 		byte(x & 0xFF), byte(x >> 8),
 		byte(y & 0xFF), byte(y >> 8),
+		c.Pressure, c.Width, c.Height,
+	}
+}
+
+// ConsumerReport builds a 2-byte Consumer Control report for usage; 0
+// releases.
+func ConsumerReport(usage uint16) []byte {
+	return ccDown(usage)
+}
+
+// Keyboard modifier bits and usages used for text entry.
+const (
+	ModLeftShift byte = 0x02
+	KeyEnter     byte = 0x28
+)
+
+// KeyboardReport builds an 8-byte keyboard report with a single key.
+// Pass 0, 0 for the all-keys-released report.
+func KeyboardReport(modifier, usage byte) []byte {
+	return []byte{modifier, 0, usage, 0, 0, 0, 0, 0}
+}
+
+// usShifted maps characters typed with Shift on a US layout to the
+// unshifted character on the same key.
+var usShifted = map[rune]rune{
+	'!': '1', '@': '2', '#': '3', '$': '4', '%': '5', '^': '6', '&': '7', '*': '8', '(': '9', ')': '0',
+	'_': '-', '+': '=', '{': '[', '}': ']', '|': '\\', ':': ';', '"': '\'', '~': '`', '<': ',', '>': '.', '?': '/',
+}
+
+// usKeys maps unshifted punctuation on a US layout to keyboard usages.
+var usKeys = map[rune]byte{
+	'\n': 0x28, '\t': 0x2B, ' ': 0x2C, '-': 0x2D, '=': 0x2E, '[': 0x2F, ']': 0x30, '\\': 0x31,
+	';': 0x33, '\'': 0x34, '`': 0x35, ',': 0x36, '.': 0x37, '/': 0x38,
+}
+
+// KeyForRune returns the modifier and usage that type r on a US keyboard
+// layout. ok is false for characters that can't be typed (non-ASCII).
+func KeyForRune(r rune) (modifier, usage byte, ok bool) {
+	if base, shifted := usShifted[r]; shifted {
+		modifier, r = ModLeftShift, base
+	}
+	switch {
+	case r >= 'A' && r <= 'Z':
+		return ModLeftShift, 0x04 + byte(r-'A'), true
+	case r >= 'a' && r <= 'z':
+		return modifier, 0x04 + byte(r-'a'), true
+	case r >= '1' && r <= '9':
+		return modifier, 0x1E + byte(r-'1'), true
+	case r == '0':
+		return modifier, 0x27, true
 	}
+	if u, found := usKeys[r]; found {
+		return modifier, u, true
+	}
+	return 0, 0, false
 }
 
 // GetDescriptor returns the raw HID descriptor for the given type.
@@ -223,6 +334,8 @@ func GetDescriptor(dt DescriptorType) []byte {
 		return cameraControlDescriptor
 	case DescTouchScreen:
 		return touchScreenDescriptor
+	case DescComposite:
+		return compositeDescriptor
 	default:
 		return nil
 	}
@@ -327,6 +440,10 @@ func consumerTests() []KeyTest {
 			"App Switch — recent apps"},
 		{"Assist (0x1CB)", "KEYCODE_ASSIST", "KEY_ASSISTANT", ccDown(0x01CB), ccUp,
 			"Assistant — could trigger voice assistant / PTT!"},
+		{"Brightness Up (0x6F)", "KEYCODE_BRIGHTNESS_UP", "KEY_BRIGHTNESSUP", ccDown(0x006F), ccUp,
+			"Display brightness up — experimental, may be ignored by the firmware"},
+		{"Brightness Down (0x70)", "KEYCODE_BRIGHTNESS_DOWN", "KEY_BRIGHTNESSDOWN", ccDown(0x0070), ccUp,
+			"Display brightness down — experimental, may be ignored by the firmware"},
 	}
 }
 
@@ -356,22 +473,124 @@ func cameraControlTests() []KeyTest {
 	}
 }
 
-// Device wraps a libusb handle to an Android device with AOA HID set up.
+// Device is an Android device with AOA HID set up, reached through a
+// USB backend.
 type Device struct {
-	ctx        *gousb.Context
-	dev        *gousb.Device
-	serial     string
+	usb        USB
 	nextHIDID  uint16   // next HID ID to assign
 	registered []uint16 // all registered HID IDs for cleanup
 	lastHIDID  uint16   // most recently registered ID (for compat methods)
+
+	mu         sync.Mutex
+	unsettled  map[uint16]time.Time // registration time of HIDs not sent a report yet
+	fastSettle bool                 // first reports wait minSettle; see ConfirmSettle
+
+	descs map[uint16]DescriptorType // descriptor registered as each HID ID, guarded by mu
+}
+
+// Location identifies where a device is enumerated on the USB bus. The
+// address changes whenever the device re-enumerates (reboot, reset).
+type Location struct {
+	Bus     int
+	Address int
+	Path    []int // physical port path from the root hub
+}
+
+// PortPath returns the physical port path in sysfs style, e.g. "1-2.3"
+// for port 3 of a hub on port 2 of bus 1.
+func (l Location) PortPath() string {
+	s := fmt.Sprint(l.Bus) + "-"
+	for i, p := range l.Path {
+		if i > 0 {
+			s += "."
+		}
+		s += fmt.Sprint(p)
+	}
+	return s
+}
+
+// String returns e.g. "bus 1 addr 7 port 1-2.3".
+func (l Location) String() string {
+	return fmt.Sprintf("bus %d addr %d port %s", l.Bus, l.Address, l.PortPath())
+}
+
+// Equal reports whether l and o are the same enumeration of a device.
+func (l Location) Equal(o Location) bool {
+	return l.Bus == o.Bus && l.Address == o.Address && l.PortPath() == o.PortPath()
+}
+
+func locationOf(desc *gousb.DeviceDesc) Location {
+	return Location{Bus: desc.Bus, Address: desc.Address, Path: append([]int(nil), desc.Path...)}
+}
+
+// locateCtx is the libusb context Locate enumerates with. It stays open,
+// so polling for an R1 doesn't set libusb up and tear it down each time.
+var (
+	locateMu  sync.Mutex
+	locateCtx *gousb.Context
+)
+
+// Locate lists the bus locations of all connected R1s without opening them.
+func Locate() ([]Location, error) {
+	if err := checkUSBFS(); err != nil {
+		return nil, err
+	}
+	locateMu.Lock()
+	defer locateMu.Unlock()
+	if locateCtx == nil {
+		locateCtx = gousb.NewContext()
+	}
+
+	var locs []Location
+	_, err := locateCtx.OpenDevices(func(desc *gousb.DeviceDesc) bool {
+		if desc.Vendor == R1VendorID && desc.Product == R1ProductID {
+			locs = append(locs, locationOf(desc))
+		}
+		return false // inspect only, never open
+	})
+	return locs, err
 }
 
 // Open finds a connected R1 and opens a USB connection (no HID registration yet).
 func Open(serial string) (*Device, error) {
+	return OpenFilter(Filter{Serial: serial})
+}
+
+// Filter selects which R1 to open when several are attached. Empty
+// fields match any device.
+type Filter struct {
+	Serial   string // USB serial number
+	PortPath string // physical port path as returned by Location.PortPath, e.g. "1-2.3"
+}
+
+// String describes the filter for log and error messages.
+func (f Filter) String() string {
+	switch {
+	case f.Serial != "" && f.PortPath != "":
+		return fmt.Sprintf("serial %q on port %s", f.Serial, f.PortPath)
+	case f.Serial != "":
+		return fmt.Sprintf("serial %q", f.Serial)
+	case f.PortPath != "":
+		return "port " + f.PortPath
+	default:
+		return "any"
+	}
+}
+
+// OpenFilter finds a connected R1 matching f and opens a USB connection
+// (no HID registration yet). Pinning a port path is useful for units
+// that report an empty serial number.
+func OpenFilter(f Filter) (*Device, error) {
+	if err := checkUSBFS(); err != nil {
+		return nil, err
+	}
 	ctx := gousb.NewContext()
 
 	devs, err := ctx.OpenDevices(func(desc *gousb.DeviceDesc) bool {
-		return desc.Vendor == R1VendorID && desc.Product == R1ProductID
+		if desc.Vendor != R1VendorID || desc.Product != R1ProductID {
+			return false
+		}
+		return f.PortPath == "" || locationOf(desc).PortPath() == f.PortPath
 	})
 	if err != nil && len(devs) == 0 {
 		ctx.Close()
@@ -379,26 +598,71 @@ func Open(serial string) (*Device, error) {
 	}
 
 	var dev *gousb.Device
+	var serial string
 	for _, d := range devs {
 		s, _ := d.SerialNumber()
-		if serial == "" || s == serial {
-			dev = d
+		if dev == nil && (f.Serial == "" || s == f.Serial) {
+			dev, serial = d, s
 		} else {
 			d.Close()
 		}
 	}
 	if dev == nil {
 		ctx.Close()
-		return nil, fmt.Errorf("R1 with serial %q not found", serial)
+		return nil, fmt.Errorf("R1 with %s not found", f)
 	}
 
 	dev.SetAutoDetach(true)
 
-	return &Device{ctx: ctx, dev: dev, nextHIDID: 1}, nil
+	return NewDevice(&libusbConn{ctx: ctx, dev: dev, serial: serial}), nil
+}
+
+// IsAccessDenied reports whether err means the OS refused access to the
+// device (e.g. missing udev rule on Linux, or another driver owns it).
+func IsAccessDenied(err error) bool {
+	return errors.Is(err, gousb.ErrorAccess)
+}
+
+// IsNoUSB reports whether err means the system has no USB bus to look
+// at, e.g. a container started without the host's /dev/bus/usb.
+func IsNoUSB(err error) bool {
+	return errors.Is(err, ErrNoUSB)
+}
+
+// IsGone reports whether err means the device is no longer attached, as
+// opposed to a transfer that failed on a device that is still there.
+func IsGone(err error) bool {
+	return errors.Is(err, gousb.ErrorNoDevice)
+}
+
+// Serial returns the USB serial number read when the device was opened.
+func (d *Device) Serial() string {
+	return d.usb.Serial()
+}
+
+// Product returns the USB product string, or "" if it can't be read.
+func (d *Device) Product() string {
+	return d.usb.Product()
+}
+
+// Location returns where the opened device is enumerated on the bus.
+func (d *Device) Location() Location {
+	return d.usb.Location()
+}
+
+// Speed returns the negotiated bus speed: "low", "full", "high" or "super".
+func (d *Device) Speed() string {
+	return d.usb.Speed()
+}
+
+// Configured reports whether the device has an active USB configuration.
+func (d *Device) Configured() bool {
+	return d.usb.Configured()
 }
 
 // RegisterDescriptor registers an HID descriptor with the device via AOA2.
 // Returns the assigned HID ID for use with SendReportTo/TapTo.
+// Failed registrations are retried with a short backoff.
 func (d *Device) RegisterDescriptor(dt DescriptorType) (uint16, error) {
 	desc := GetDescriptor(dt)
 	if desc == nil {
@@ -408,23 +672,50 @@ func (d *Device) RegisterDescriptor(dt DescriptorType) (uint16, error) {
 	id := d.nextHIDID
 	d.nextHIDID++
 
+	var err error
+	for attempt := 1; attempt <= registerAttempts; attempt++ {
+		if err = d.registerHID(id, desc); err == nil {
+			break
+		}
+		if attempt < registerAttempts {
+			time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Android creates the input device in the background; the first
+	// report waits for it
+	d.mu.Lock()
+	if d.unsettled == nil {
+		d.unsettled = make(map[uint16]time.Time)
+	}
+	d.unsettled[id] = time.Now()
+	if d.descs == nil {
+		d.descs = make(map[uint16]DescriptorType)
+	}
+	d.descs[id] = dt
+	d.mu.Unlock()
+
+	d.registered = append(d.registered, id)
+	d.lastHIDID = id
+	return id, nil
+}
+
+// registerHID performs one REGISTER_HID + SET_HID_REPORT_DESC exchange.
+func (d *Device) registerHID(id uint16, desc []byte) error {
 	// Register HID device (wValue = HID ID, wIndex = descriptor length)
 	if err := d.controlTransfer(reqRegisterHID, id, uint16(len(desc)), nil); err != nil {
-		return 0, fmt.Errorf("REGISTER_HID failed: %w", err)
+		return fmt.Errorf("REGISTER_HID failed: %w", err)
 	}
 
 	// Send the HID report descriptor
 	if err := d.controlTransfer(reqSetHIDDesc, id, 0, desc); err != nil {
 		_ = d.controlTransfer(reqUnregisterHID, id, 0, nil)
-		return 0, fmt.Errorf("SET_HID_REPORT_DESC failed: %w", err)
+		return fmt.Errorf("SET_HID_REPORT_DESC failed: %w", err)
 	}
-
-	// Give Android time to create the input device
-	time.Sleep(300 * time.Millisecond)
-
-	d.registered = append(d.registered, id)
-	d.lastHIDID = id
-	return id, nil
+	return nil
 }
 
 // UnregisterDescriptor removes the most recently registered HID device.
@@ -434,6 +725,10 @@ func (d *Device) UnregisterDescriptor() error {
 	}
 	id := d.registered[len(d.registered)-1]
 	d.registered = d.registered[:len(d.registered)-1]
+	d.mu.Lock()
+	delete(d.unsettled, id)
+	delete(d.descs, id)
+	d.mu.Unlock()
 	err := d.controlTransfer(reqUnregisterHID, id, 0, nil)
 	time.Sleep(200 * time.Millisecond)
 	return err
@@ -445,8 +740,120 @@ func (d *Device) SendReport(report []byte) error {
 }
 
 // SendReportTo sends a raw HID report to a specific descriptor by HID ID.
+// The first report after registration may wait for the HID to settle. A
+// report that doesn't fit the registered descriptor isn't sent; the
+// error wraps ErrBadReport.
 func (d *Device) SendReportTo(hidID uint16, report []byte) error {
-	return d.controlTransfer(reqSendHIDEvent, hidID, 0, report)
+	if err := d.checkReport(hidID, report); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	registeredAt, first := d.unsettled[hidID]
+	delete(d.unsettled, hidID)
+	slow := !d.fastSettle
+	d.mu.Unlock()
+
+	if !first {
+		return d.controlTransfer(reqSendHIDEvent, hidID, 0, report)
+	}
+	if slow {
+		time.Sleep(time.Until(registeredAt.Add(maxSettle)))
+	} else {
+		time.Sleep(time.Until(registeredAt.Add(minSettle)))
+	}
+	err := d.controlTransfer(reqSendHIDEvent, hidID, 0, report)
+	if err == nil || slow {
+		return err
+	}
+
+	// The input device may not exist yet
+	time.Sleep(time.Until(registeredAt.Add(maxSettle)))
+	if err = d.controlTransfer(reqSendHIDEvent, hidID, 0, report); err == nil {
+		d.mu.Lock()
+		d.fastSettle = false
+		d.mu.Unlock()
+	}
+	return err
+}
+
+// ConfirmSettle records that reports on this connection reach the
+// device, e.g. once a keep-awake ping went through, so the first report
+// to a HID registered from then on waits minSettle instead of maxSettle.
+func (d *Device) ConfirmSettle() {
+	d.mu.Lock()
+	d.fastSettle = true
+	d.mu.Unlock()
+}
+
+// awaitSettle waits until a report to hidID can be sent without waiting
+// for the HID to settle, so a sequence's timing starts after it.
+func (d *Device) awaitSettle(hidID uint16) {
+	d.mu.Lock()
+	registeredAt, first := d.unsettled[hidID]
+	settle := maxSettle
+	if d.fastSettle {
+		settle = minSettle
+	}
+	d.mu.Unlock()
+	if first {
+		time.Sleep(time.Until(registeredAt.Add(settle)))
+	}
+}
+
+// TimedReport is one step of a report sequence: Report is sent At after
+// the start of the sequence.
+type TimedReport struct {
+	At     time.Duration
+	Report []byte
+}
+
+// SendReportSequence sends a pre-built sequence of reports to a specific
+// descriptor. Each report is scheduled against the sequence start on the
+// monotonic clock, so the latency of one control transfer doesn't push
+// back every step after it the way sleeping between sends does. The
+// sequence runs on a dedicated, locked OS thread. Stops at the first
+// error or when ctx is cancelled, returning the error with the failed
+// step's index; the caller is responsible for any cleanup report. A
+// sequence with a report that doesn't fit the registered descriptor isn't
+// started.
+func (d *Device) SendReportSequence(ctx context.Context, hidID uint16, seq []TimedReport) error {
+	for i, step := range seq {
+		if err := d.checkReport(hidID, step.Report); err != nil {
+			return fmt.Errorf("step %d: %w", i, err)
+		}
+	}
+	d.awaitSettle(hidID)
+	done := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		<-timer.C
+
+		start := time.Now()
+		for i, step := range seq {
+			if wait := time.Until(start.Add(step.At)); wait > 0 {
+				timer.Reset(wait)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					done <- fmt.Errorf("step %d: %w", i, ctx.Err())
+					return
+				}
+			} else if err := ctx.Err(); err != nil {
+				done <- fmt.Errorf("step %d: %w", i, err)
+				return
+			}
+			if err := d.SendReportTo(hidID, step.Report); err != nil {
+				done <- fmt.Errorf("step %d: %w", i, err)
+				return
+			}
+		}
+		done <- nil
+	}()
+	return <-done
 }
 
 // Tap sends a key-down followed by a key-up with a short delay.
@@ -476,10 +883,9 @@ func (d *Device) Release(up []byte) error {
 	return d.SendReport(up)
 }
 
-// Ping checks if the device is still connected by reading its serial number.
+// Ping checks if the device is still connected.
 func (d *Device) Ping() error {
-	_, err := d.dev.SerialNumber()
-	return err
+	return d.usb.Ping()
 }
 
 // Close releases USB resources.
@@ -488,8 +894,7 @@ func (d *Device) Close() {
 		_ = d.controlTransfer(reqUnregisterHID, id, 0, nil)
 	}
 	d.registered = nil
-	d.dev.Close()
-	d.ctx.Close()
+	d.usb.Close()
 }
 
 // controlTransfer sends a vendor control transfer to the device.
@@ -497,13 +902,15 @@ func (d *Device) controlTransfer(bRequest uint8, wValue uint16, wIndex uint16, d
 	if data == nil {
 		data = []byte{}
 	}
-	_, err := d.dev.Control(
+	start := time.Now()
+	_, err := d.usb.Control(
 		bmRequestTypeOut,
 		bRequest,
 		wValue,
 		wIndex,
 		data,
 	)
+	traceTransfer(start, bRequest, wValue, wIndex, data, err)
 	if err != nil {
 		return fmt.Errorf("control transfer (req=%d wValue=%d wIndex=%d): %w", bRequest, wValue, wIndex, err)
 	}
@@ -0,0 +1,43 @@
+package aoa
+
+import "slices"
+
+// Report IDs of the collections in the composite descriptor. Every
+// report to a composite HID starts with the ID of the collection it is
+// for, followed by the report that collection's own descriptor expects.
+const (
+	ReportIDSystemControl byte = 1
+	ReportIDConsumer      byte = 2
+	ReportIDTouch         byte = 3
+)
+
+// compositeDescriptor combines the System Control, Consumer Control and
+// Touch Screen descriptors into one, so a single registration gives
+// Android one input device for PTT, display controls and gestures.
+var compositeDescriptor = slices.Concat(
+	withReportID(systemControlDescriptor, ReportIDSystemControl),
+	withReportID(consumerDescriptor, ReportIDConsumer),
+	withReportID(touchScreenDescriptor, ReportIDTouch),
+)
+
+// withReportID returns a copy of desc with a Report ID item for id
+// inside its application collection. Every descriptor here opens with
+// Usage Page, Usage and Collection (Application), two bytes each.
+func withReportID(desc []byte, id byte) []byte {
+	return slices.Insert(slices.Clone(desc), 6, 0x85, id) // Report ID (id)
+}
+
+// CompositeReportID returns the report ID of dt's collection in the
+// composite descriptor, or false if dt isn't part of it.
+func CompositeReportID(dt DescriptorType) (byte, bool) {
+	switch dt {
+	case DescSystemControl:
+		return ReportIDSystemControl, true
+	case DescConsumerControl:
+		return ReportIDConsumer, true
+	case DescTouchScreen:
+		return ReportIDTouch, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,238 @@
+package aoa
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"unicode/utf16"
+)
+
+// Standard GET_DESCRIPTOR request (USB 2.0 §9.4.3).
+const (
+	bmRequestTypeIn  = 0x80 // device-to-host, standard, device
+	reqGetDescriptor = 0x06
+
+	descDevice    = 0x01
+	descConfig    = 0x02
+	descString    = 0x03
+	descInterface = 0x04
+	descEndpoint  = 0x05
+)
+
+// Descriptors is a device's USB enumeration data, read with standard
+// GET_DESCRIPTOR requests. Each descriptor's Raw field holds the bytes
+// as the device sent them, in hex, for sharing exactly what it reported.
+type Descriptors struct {
+	Device  DeviceDescriptor   `json:"device"`
+	Configs []ConfigDescriptor `json:"configs"`
+	LangIDs []uint16           `json:"lang_ids,omitempty"`
+	Strings map[uint8]string   `json:"strings,omitempty"` // by index, in the first language
+}
+
+// DeviceDescriptor is the parsed device descriptor.
+type DeviceDescriptor struct {
+	USB            string `json:"usb"` // bcdUSB, e.g. "2.00"
+	Class          uint8  `json:"class"`
+	SubClass       uint8  `json:"subclass"`
+	Protocol       uint8  `json:"protocol"`
+	MaxPacketSize0 uint8  `json:"max_packet_size0"`
+	VendorID       uint16 `json:"vendor_id"`
+	ProductID      uint16 `json:"product_id"`
+	Version        string `json:"version"` // bcdDevice
+	Manufacturer   uint8  `json:"i_manufacturer"`
+	Product        uint8  `json:"i_product"`
+	SerialNumber   uint8  `json:"i_serial_number"`
+	NumConfigs     uint8  `json:"num_configs"`
+	Raw            string `json:"raw"`
+}
+
+// ConfigDescriptor is one configuration with its interfaces.
+type ConfigDescriptor struct {
+	Value      uint8                 `json:"value"`
+	Name       uint8                 `json:"i_configuration"`
+	Attributes uint8                 `json:"attributes"`
+	MaxPowerMA int                   `json:"max_power_ma"`
+	Interfaces []InterfaceDescriptor `json:"interfaces"`
+	Extra      []string              `json:"extra,omitempty"` // other descriptors before the first interface, in hex
+	Raw        string                `json:"raw"`             // the whole configuration, as returned
+}
+
+// InterfaceDescriptor is one alternate setting of an interface.
+type InterfaceDescriptor struct {
+	Number     uint8                `json:"number"`
+	AltSetting uint8                `json:"alt_setting"`
+	Class      uint8                `json:"class"`
+	SubClass   uint8                `json:"subclass"`
+	Protocol   uint8                `json:"protocol"`
+	Name       uint8                `json:"i_interface"`
+	Endpoints  []EndpointDescriptor `json:"endpoints"`
+	Extra      []string             `json:"extra,omitempty"` // class-specific descriptors, in hex
+}
+
+// EndpointDescriptor is one endpoint of an interface.
+type EndpointDescriptor struct {
+	Address       uint8  `json:"address"`
+	Attributes    uint8  `json:"attributes"`
+	MaxPacketSize uint16 `json:"max_packet_size"`
+	Interval      uint8  `json:"interval"`
+}
+
+// Descriptors reads the device, configuration and string descriptors.
+// Backends that can't do control transfers in, e.g. WebUSB, return an
+// error.
+func (d *Device) Descriptors() (*Descriptors, error) {
+	raw, err := d.getDescriptor(descDevice, 0, 0, 18)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < 18 {
+		return nil, fmt.Errorf("device descriptor is %d bytes, want 18", len(raw))
+	}
+	out := &Descriptors{Device: DeviceDescriptor{
+		USB:            bcd(binary.LittleEndian.Uint16(raw[2:])),
+		Class:          raw[4],
+		SubClass:       raw[5],
+		Protocol:       raw[6],
+		MaxPacketSize0: raw[7],
+		VendorID:       binary.LittleEndian.Uint16(raw[8:]),
+		ProductID:      binary.LittleEndian.Uint16(raw[10:]),
+		Version:        bcd(binary.LittleEndian.Uint16(raw[12:])),
+		Manufacturer:   raw[14],
+		Product:        raw[15],
+		SerialNumber:   raw[16],
+		NumConfigs:     raw[17],
+		Raw:            hex.EncodeToString(raw),
+	}}
+	superSpeed := binary.LittleEndian.Uint16(raw[2:]) >= 0x0300
+
+	names := []uint8{raw[14], raw[15], raw[16]}
+	for i := range raw[17] {
+		c, err := d.configDescriptor(i, superSpeed)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, c.Name)
+		for _, intf := range c.Interfaces {
+			names = append(names, intf.Name)
+		}
+		out.Configs = append(out.Configs, c)
+	}
+
+	// String descriptor 0 lists the supported languages
+	langs, err := d.getDescriptor(descString, 0, 0, 255)
+	if err != nil || len(langs) < 4 {
+		return out, nil // no strings; not an error
+	}
+	for i := 2; i+1 < len(langs); i += 2 {
+		out.LangIDs = append(out.LangIDs, binary.LittleEndian.Uint16(langs[i:]))
+	}
+	out.Strings = map[uint8]string{}
+	for _, idx := range names {
+		if _, done := out.Strings[idx]; idx == 0 || done {
+			continue
+		}
+		s, err := d.getDescriptor(descString, idx, out.LangIDs[0], 255)
+		if err != nil {
+			out.Strings[idx] = "(" + err.Error() + ")"
+			continue
+		}
+		out.Strings[idx] = decodeString(s)
+	}
+	return out, nil
+}
+
+// configDescriptor reads and parses configuration index i.
+func (d *Device) configDescriptor(i uint8, superSpeed bool) (ConfigDescriptor, error) {
+	head, err := d.getDescriptor(descConfig, i, 0, 9)
+	if err != nil {
+		return ConfigDescriptor{}, err
+	}
+	if len(head) < 9 {
+		return ConfigDescriptor{}, fmt.Errorf("configuration %d descriptor is %d bytes, want 9", i, len(head))
+	}
+	raw, err := d.getDescriptor(descConfig, i, 0, int(binary.LittleEndian.Uint16(head[2:])))
+	if err != nil {
+		return ConfigDescriptor{}, err
+	}
+	if len(raw) < 9 {
+		return ConfigDescriptor{}, fmt.Errorf("configuration %d is %d bytes, want at least 9", i, len(raw))
+	}
+
+	unit := 2 // mA per bMaxPower unit; 8 on SuperSpeed
+	if superSpeed {
+		unit = 8
+	}
+	c := ConfigDescriptor{
+		Value:      raw[5],
+		Name:       raw[6],
+		Attributes: raw[7],
+		MaxPowerMA: int(raw[8]) * unit,
+		Raw:        hex.EncodeToString(raw),
+	}
+
+	var intf *InterfaceDescriptor
+	for rest := raw[min(int(raw[0]), len(raw)):]; len(rest) >= 2; {
+		n := int(rest[0])
+		if n < 2 || n > len(rest) {
+			break // malformed; Raw still has everything
+		}
+		b := rest[:n]
+		rest = rest[n:]
+
+		switch {
+		case b[1] == descInterface && n >= 9:
+			c.Interfaces = append(c.Interfaces, InterfaceDescriptor{
+				Number:     b[2],
+				AltSetting: b[3],
+				Class:      b[5],
+				SubClass:   b[6],
+				Protocol:   b[7],
+				Name:       b[8],
+			})
+			intf = &c.Interfaces[len(c.Interfaces)-1]
+		case b[1] == descEndpoint && n >= 7 && intf != nil:
+			intf.Endpoints = append(intf.Endpoints, EndpointDescriptor{
+				Address:       b[2],
+				Attributes:    b[3],
+				MaxPacketSize: binary.LittleEndian.Uint16(b[4:]),
+				Interval:      b[6],
+			})
+		case intf != nil:
+			intf.Extra = append(intf.Extra, hex.EncodeToString(b))
+		default:
+			c.Extra = append(c.Extra, hex.EncodeToString(b))
+		}
+	}
+	return c, nil
+}
+
+// getDescriptor issues a GET_DESCRIPTOR request for up to size bytes.
+func (d *Device) getDescriptor(typ, index uint8, lang uint16, size int) ([]byte, error) {
+	buf := make([]byte, size)
+	n, err := d.usb.Control(bmRequestTypeIn, reqGetDescriptor, uint16(typ)<<8|uint16(index), lang, buf)
+	if err != nil {
+		return nil, fmt.Errorf("get descriptor (type=%d index=%d): %w", typ, index, err)
+	}
+	return buf[:n], nil
+}
+
+// decodeString decodes a string descriptor's UTF-16LE text.
+func decodeString(b []byte) string {
+	if len(b) < 2 {
+		return ""
+	}
+	if len(b) > int(b[0]) {
+		b = b[:b[0]]
+	}
+	var u []uint16
+	for i := 2; i+1 < len(b); i += 2 {
+		u = append(u, binary.LittleEndian.Uint16(b[i:]))
+	}
+	return string(utf16.Decode(u))
+}
+
+// bcd formats a binary-coded decimal version such as bcdUSB, e.g.
+// 0x0210 → "2.10".
+func bcd(v uint16) string {
+	return fmt.Sprintf("%x.%02x", v>>8, v&0xFF)
+}
@@ -0,0 +1,190 @@
+package aoa
+
+import (
+	"errors"
+	"fmt"
+)
+
+// HID report descriptor item prefixes (HID 1.11 §6.2.2): the tag in the
+// high nibble, the item type in bits 2-3 and the data size in bits 0-1.
+const (
+	itemMain   = 0
+	itemGlobal = 1
+	itemLong   = 0xFE
+
+	tagInput         = 0x8
+	tagOutput        = 0x9
+	tagCollection    = 0xA
+	tagFeature       = 0xB
+	tagEndCollection = 0xC
+
+	tagReportSize  = 0x7
+	tagReportID    = 0x8
+	tagReportCount = 0x9
+	tagPush        = 0xA
+	tagPop         = 0xB
+)
+
+// maxPush bounds the global item stack, as the Linux HID parser does.
+const maxPush = 4
+
+// HIDDescriptor is what ParseDescriptor learned from a HID report
+// descriptor.
+type HIDDescriptor struct {
+	// InputSizes is the length of each input report in bytes, by report
+	// ID and without the ID byte. ID 0 means the descriptor declares no
+	// report IDs, and its reports are sent without one.
+	InputSizes map[byte]int
+}
+
+// globals is the global item state Push and Pop save and restore.
+type globals struct {
+	reportSize  uint32
+	reportCount uint32
+	reportID    byte
+}
+
+// ParseDescriptor checks that desc is a well-formed HID report descriptor
+// the R1 can use, and derives the size of its input reports. It refuses
+// truncated items, unbalanced collections and Push/Pop, inputs outside a
+// collection, report ID 0, report IDs mixed with reports that have none,
+// and input reports that aren't a whole number of bytes.
+func ParseDescriptor(desc []byte) (*HIDDescriptor, error) {
+	if len(desc) == 0 {
+		return nil, errors.New("HID descriptor is empty")
+	}
+	fail := func(off int, format string, args ...any) error {
+		return fmt.Errorf("HID descriptor offset %d: %s", off, fmt.Sprintf(format, args...))
+	}
+
+	var g globals
+	var stack []globals
+	depth := 0
+	bits := map[byte]uint32{} // input report bits by report ID
+	withoutID := -1           // offset of the first report without an ID; -1 = none yet
+	withID := false           // a Report ID item was seen
+
+	for off := 0; off < len(desc); {
+		prefix := desc[off]
+		if prefix == itemLong {
+			if off+3 > len(desc) || off+3+int(desc[off+1]) > len(desc) {
+				return nil, fail(off, "long item runs past the end")
+			}
+			off += 3 + int(desc[off+1])
+			continue
+		}
+		size := int(prefix & 0x03)
+		if size == 3 {
+			size = 4
+		}
+		if off+1+size > len(desc) {
+			return nil, fail(off, "item 0x%02x runs past the end", prefix)
+		}
+		var value uint32
+		for i := range size {
+			value |= uint32(desc[off+1+i]) << (8 * i)
+		}
+		typ, tag := (prefix>>2)&0x03, prefix>>4
+
+		switch {
+		case typ == 3:
+			return nil, fail(off, "reserved item type in 0x%02x", prefix)
+		case typ == itemGlobal:
+			switch tag {
+			case tagReportSize:
+				g.reportSize = value
+			case tagReportCount:
+				g.reportCount = value
+			case tagReportID:
+				if value == 0 || value > 0xFF {
+					return nil, fail(off, "report ID %d is out of range 1-255", value)
+				}
+				if withoutID >= 0 {
+					return nil, fail(off, "report ID after the report without one at offset %d", withoutID)
+				}
+				g.reportID, withID = byte(value), true
+			case tagPush:
+				if len(stack) == maxPush {
+					return nil, fail(off, "more than %d nested Push items", maxPush)
+				}
+				stack = append(stack, g)
+			case tagPop:
+				if len(stack) == 0 {
+					return nil, fail(off, "Pop without Push")
+				}
+				g, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		case typ == itemMain:
+			switch tag {
+			case tagCollection:
+				depth++
+			case tagEndCollection:
+				if depth == 0 {
+					return nil, fail(off, "End Collection without a Collection")
+				}
+				depth--
+			case tagInput, tagOutput, tagFeature:
+				if depth == 0 {
+					return nil, fail(off, "report item outside a collection")
+				}
+				n := uint64(g.reportSize) * uint64(g.reportCount)
+				if g.reportID == 0 && n > 0 {
+					if withID {
+						return nil, fail(off, "report without an ID after report IDs")
+					}
+					if withoutID < 0 {
+						withoutID = off
+					}
+				}
+				if tag == tagInput {
+					if total := uint64(bits[g.reportID]) + n; total <= 0xFFFF*8 {
+						bits[g.reportID] = uint32(total)
+					} else {
+						return nil, fail(off, "input report is over 64 KiB")
+					}
+				}
+			default:
+				return nil, fail(off, "unknown main item 0x%02x", prefix)
+			}
+		}
+		off += 1 + size
+	}
+
+	if depth > 0 {
+		return nil, fmt.Errorf("HID descriptor ends with %d collection(s) open", depth)
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("HID descriptor ends with %d Push item(s) not popped", len(stack))
+	}
+	out := &HIDDescriptor{InputSizes: make(map[byte]int)}
+	for id, n := range bits {
+		if n == 0 {
+			continue
+		}
+		if n%8 != 0 {
+			if id == 0 {
+				return nil, fmt.Errorf("HID descriptor's input report is %d bits, not whole bytes", n)
+			}
+			return nil, fmt.Errorf("HID descriptor's input report %d is %d bits, not whole bytes", id, n)
+		}
+		out.InputSizes[id] = int(n / 8)
+	}
+	if len(out.InputSizes) == 0 {
+		return nil, errors.New("HID descriptor declares no input reports")
+	}
+	return out, nil
+}
+
+// builtinSizes are the input report sizes of the built-in descriptors, by
+// report ID as in HIDDescriptor.InputSizes.
+var builtinSizes = func() map[DescriptorType]map[byte]int {
+	sizes := make(map[DescriptorType]map[byte]int)
+	for dt := DescKeyboard; dt <= DescComposite; dt++ {
+		h, err := ParseDescriptor(GetDescriptor(dt))
+		if err != nil {
+			panic(fmt.Sprintf("aoa: built-in %s descriptor: %v", dt, err))
+		}
+		sizes[dt] = h.InputSizes
+	}
+	return sizes
+}()
@@ -0,0 +1,216 @@
+package aoa
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TraceEntry is one control transfer read from a trace.
+type TraceEntry struct {
+	Line    int           // line number in the trace, for reporting
+	At      time.Duration // since the first entry
+	Request uint8
+	Value   uint16
+	Index   uint16
+	Data    []byte
+}
+
+// String returns e.g. "line 12: SEND_HID_EVENT wValue=1 wIndex=0 data=0100".
+func (e TraceEntry) String() string {
+	return fmt.Sprintf("line %d: %s wValue=%d wIndex=%d data=%s",
+		e.Line, requestName(e.Request), e.Value, e.Index, hex.EncodeToString(e.Data))
+}
+
+// ParseTrace reads a trace written by SetTrace. The same line format with
+// the first field in seconds (e.g. "12.345678", as in a converted usbmon
+// capture) is accepted too; the request name and any fields other than
+// req, wValue, wIndex and data are optional and ignored. Blank lines and
+// lines starting with "#" are skipped.
+func ParseTrace(r io.Reader) ([]TraceEntry, error) {
+	var entries []TraceEntry
+	var first time.Time
+	var firstSec float64
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		e := TraceEntry{Line: n}
+
+		// Timestamp: absolute or in seconds
+		if t, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+			if first.IsZero() {
+				first = t
+			}
+			e.At = t.Sub(first)
+		} else if sec, err := strconv.ParseFloat(fields[0], 64); err == nil {
+			if len(entries) == 0 {
+				firstSec = sec
+			}
+			e.At = time.Duration((sec - firstSec) * float64(time.Second))
+		} else {
+			return nil, fmt.Errorf("line %d: bad timestamp %q", n, fields[0])
+		}
+
+		var haveReq bool
+		for _, f := range fields[1:] {
+			key, val, ok := strings.Cut(f, "=")
+			if !ok {
+				continue // request name
+			}
+			var err error
+			switch key {
+			case "req":
+				var v uint64
+				v, err = strconv.ParseUint(val, 0, 8)
+				e.Request, haveReq = uint8(v), true
+			case "wValue":
+				var v uint64
+				v, err = strconv.ParseUint(val, 0, 16)
+				e.Value = uint16(v)
+			case "wIndex":
+				var v uint64
+				v, err = strconv.ParseUint(val, 0, 16)
+				e.Index = uint16(v)
+			case "data":
+				e.Data, err = hex.DecodeString(val)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad %s: %w", n, key, err)
+			}
+		}
+		if !haveReq {
+			return nil, fmt.Errorf("line %d: missing req=", n)
+		}
+		if e.At < 0 {
+			return nil, fmt.Errorf("line %d: timestamp goes backwards", n)
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read trace: %w", err)
+	}
+	return entries, nil
+}
+
+// Replay resends entries to the device, keeping their original spacing
+// divided by speed; speed <= 0 sends them back to back. HID IDs
+// registered by the trace are unregistered on Close like ones from
+// RegisterDescriptor, so replay into a freshly opened device. step, if
+// set, is called after each transfer. Stops at the first error or when
+// ctx is cancelled. Nothing is sent if a descriptor the trace registers
+// fails CheckTraceDescriptors.
+func (d *Device) Replay(ctx context.Context, entries []TraceEntry, speed float64, step func(TraceEntry, error)) error {
+	if err := CheckTraceDescriptors(entries); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		var base time.Duration
+		if len(entries) > 0 {
+			base = entries[0].At
+		}
+		start := time.Now()
+		for _, e := range entries {
+			if speed > 0 {
+				at := time.Duration(float64(e.At-base) / speed)
+				if wait := time.Until(start.Add(at)); wait > 0 {
+					select {
+					case <-time.After(wait):
+					case <-ctx.Done():
+						done <- ctx.Err()
+						return
+					}
+				}
+			}
+			if err := ctx.Err(); err != nil {
+				done <- err
+				return
+			}
+			err := d.controlTransfer(e.Request, e.Value, e.Index, e.Data)
+			if err == nil {
+				d.noteReplayed(e)
+			}
+			if step != nil {
+				step(e, err)
+			}
+			if err != nil {
+				done <- fmt.Errorf("%s: %w", e, err)
+				return
+			}
+		}
+		done <- nil
+	}()
+	return <-done
+}
+
+// CheckTraceDescriptors checks every HID descriptor entries register with
+// ParseDescriptor, so a malformed one in a hand-edited or converted trace
+// is caught before it reaches the device. A descriptor sent in several
+// SET_HID_REPORT_DESC transfers is checked once complete; one whose
+// REGISTER_HID isn't in entries can't be, and is skipped.
+func CheckTraceDescriptors(entries []TraceEntry) error {
+	type pending struct {
+		line int    // of the REGISTER_HID
+		size int    // as registered
+		desc []byte // received so far
+	}
+	descs := make(map[uint16]*pending)
+	for _, e := range entries {
+		switch e.Request {
+		case reqRegisterHID:
+			descs[e.Value] = &pending{line: e.Line, size: int(e.Index)}
+		case reqUnregisterHID:
+			delete(descs, e.Value)
+		case reqSetHIDDesc:
+			p, ok := descs[e.Value]
+			if !ok {
+				continue
+			}
+			if int(e.Index) != len(p.desc) || len(p.desc)+len(e.Data) > p.size {
+				return fmt.Errorf("%s: descriptor data doesn't follow on from the previous transfer", e)
+			}
+			p.desc = append(p.desc, e.Data...)
+			if len(p.desc) < p.size {
+				continue
+			}
+			if _, err := ParseDescriptor(p.desc); err != nil {
+				return fmt.Errorf("%s: %w (registered on line %d)", e, err, p.line)
+			}
+			delete(descs, e.Value)
+		}
+	}
+	return nil
+}
+
+// noteReplayed tracks HID registrations made by a replayed transfer.
+func (d *Device) noteReplayed(e TraceEntry) {
+	switch e.Request {
+	case reqRegisterHID:
+		d.registered = append(d.registered, e.Value)
+		d.lastHIDID = e.Value
+		if e.Value >= d.nextHIDID {
+			d.nextHIDID = e.Value + 1
+		}
+	case reqUnregisterHID:
+		for i, id := range d.registered {
+			if id == e.Value {
+				d.registered = append(d.registered[:i], d.registered[i+1:]...)
+				break
+			}
+		}
+	}
+}
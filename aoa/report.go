@@ -0,0 +1,67 @@
+package aoa
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBadReport is wrapped by the errors for reports that don't fit the
+// descriptor registered for their HID ID. Such reports are never sent,
+// so the connection is still fine.
+var ErrBadReport = errors.New("report doesn't fit the HID descriptor")
+
+// IsBadReport reports whether err is a report refused for not fitting
+// its descriptor, as opposed to a failed transfer.
+func IsBadReport(err error) bool {
+	return errors.Is(err, ErrBadReport)
+}
+
+// WithReportID returns report with report ID id in front, for sending to
+// one collection of a descriptor with report IDs such as DescComposite.
+// ID 0 means the descriptor has none, and returns report unchanged.
+func WithReportID(id byte, report []byte) []byte {
+	if id == 0 {
+		return report
+	}
+	return append([]byte{id}, report...)
+}
+
+// ReportSize returns the length of a report for report ID id of dt's
+// descriptor, without the ID byte; id is 0 for descriptors without
+// report IDs. ok is false if the descriptor declares no such report.
+func ReportSize(dt DescriptorType, id byte) (size int, ok bool) {
+	size, ok = builtinSizes[dt][id]
+	return size, ok
+}
+
+// checkReport returns an error wrapping ErrBadReport if report doesn't fit
+// the descriptor registered as hidID: a missing or unknown report ID, or
+// the wrong length. HIDs registered some other way, e.g. by a replayed
+// trace, aren't checked.
+func (d *Device) checkReport(hidID uint16, report []byte) error {
+	d.mu.Lock()
+	dt, ok := d.descs[hidID]
+	d.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	sizes := builtinSizes[dt]
+	if want, ok := sizes[0]; ok {
+		if len(report) != want {
+			return fmt.Errorf("%w: %s report is %d bytes, want %d", ErrBadReport, dt, len(report), want)
+		}
+		return nil
+	}
+	if len(report) == 0 {
+		return fmt.Errorf("%w: empty %s report, want a report ID first", ErrBadReport, dt)
+	}
+	want, ok := sizes[report[0]]
+	if !ok {
+		return fmt.Errorf("%w: %s has no report ID %d", ErrBadReport, dt, report[0])
+	}
+	if len(report)-1 != want {
+		return fmt.Errorf("%w: %s report ID %d is %d bytes, want %d", ErrBadReport, dt, report[0], len(report)-1, want)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package aoa
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// trace receives a line per control transfer while tracing is on. It is
+// package-wide so devices opened on reconnect are traced too.
+var trace struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// SetTrace starts logging every AOA control transfer to w, one line per
+// transfer:
+//
+//	2026-01-02T15:04:05.000000Z SEND_HID_EVENT req=57 wValue=1 wIndex=0 data=0100 dur=412µs ok
+//	2026-01-02T15:04:05.100000Z REGISTER_HID req=54 wValue=2 wIndex=61 data= dur=1.002s err="libusb: timeout [code -7]"
+//
+// A nil w stops tracing. Writes are serialized; w is not closed.
+func SetTrace(w io.Writer) {
+	trace.mu.Lock()
+	trace.w = w
+	trace.mu.Unlock()
+}
+
+// requestName returns the AOA name of a bRequest code.
+func requestName(bRequest uint8) string {
+	switch bRequest {
+	case reqRegisterHID:
+		return "REGISTER_HID"
+	case reqUnregisterHID:
+		return "UNREGISTER_HID"
+	case reqSetHIDDesc:
+		return "SET_HID_REPORT_DESC"
+	case reqSendHIDEvent:
+		return "SEND_HID_EVENT"
+	}
+	return "REQ_" + strconv.Itoa(int(bRequest))
+}
+
+// traceTransfer records one control transfer if tracing is on.
+func traceTransfer(start time.Time, bRequest uint8, wValue, wIndex uint16, data []byte, err error) {
+	dur := time.Since(start)
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	if trace.w == nil {
+		return
+	}
+	result := "ok"
+	if err != nil {
+		result = "err=" + strconv.Quote(err.Error())
+	}
+	fmt.Fprintf(trace.w, "%s %s req=%d wValue=%d wIndex=%d data=%s dur=%v %s\n",
+		start.UTC().Format("2006-01-02T15:04:05.000000Z"), requestName(bRequest),
+		bRequest, wValue, wIndex, hex.EncodeToString(data), dur, result)
+}
@@ -0,0 +1,65 @@
+package aoa
+
+import "github.com/google/gousb"
+
+// USB is the connection a Device talks to the R1 through. OpenFilter uses
+// libusb (gousb); other backends, e.g. a browser relaying WebUSB, pass
+// their own to NewDevice.
+type USB interface {
+	// Control performs a control transfer and returns the bytes
+	// transferred.
+	Control(rType, request uint8, val, idx uint16, data []byte) (int, error)
+	// Ping checks that the device is still there.
+	Ping() error
+	Close()
+
+	Serial() string
+	Product() string
+	Location() Location
+	Speed() string
+	Configured() bool
+}
+
+// NewDevice sets up AOA HID on the device behind u (no HID registration
+// yet). Closing the Device closes u.
+func NewDevice(u USB) *Device {
+	return &Device{usb: u, nextHIDID: 1}
+}
+
+// libusbConn is the gousb backend.
+type libusbConn struct {
+	ctx    *gousb.Context
+	dev    *gousb.Device
+	serial string
+}
+
+func (c *libusbConn) Control(rType, request uint8, val, idx uint16, data []byte) (int, error) {
+	return c.dev.Control(rType, request, val, idx, data)
+}
+
+// Ping reads the serial number.
+func (c *libusbConn) Ping() error {
+	_, err := c.dev.SerialNumber()
+	return err
+}
+
+func (c *libusbConn) Close() {
+	c.dev.Close()
+	c.ctx.Close()
+}
+
+func (c *libusbConn) Serial() string { return c.serial }
+
+func (c *libusbConn) Product() string {
+	p, _ := c.dev.Product()
+	return p
+}
+
+func (c *libusbConn) Location() Location { return locationOf(c.dev.Desc) }
+
+func (c *libusbConn) Speed() string { return c.dev.Desc.Speed.String() }
+
+func (c *libusbConn) Configured() bool {
+	n, err := c.dev.ActiveConfigNum()
+	return err == nil && n > 0
+}
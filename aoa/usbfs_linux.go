@@ -0,0 +1,20 @@
+//go:build linux
+
+package aoa
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrNoUSB means there is no USB bus to open the R1 on.
+var ErrNoUSB = errors.New("no USB bus: /dev/bus/usb is missing (in a container, pass --device /dev/bus/usb)")
+
+// checkUSBFS checks that usbfs is there before libusb is started, which
+// would fail without it; gousb panics when libusb can't start.
+func checkUSBFS() error {
+	if _, err := os.Stat("/dev/bus/usb"); err != nil {
+		return ErrNoUSB
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package aoa
+
+import "errors"
+
+// ErrNoUSB means there is no USB bus to open the R1 on. Only Linux checks
+// for it.
+var ErrNoUSB = errors.New("no USB bus")
+
+// checkUSBFS has nothing to check: libusb starts without a device tree
+// here.
+func checkUSBFS() error {
+	return nil
+}
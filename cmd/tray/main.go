@@ -7,108 +7,682 @@
 //   - Hold: PTT active until release
 //
 // Swipe hotkey (default: Ctrl+Alt+W):
-//   - Each press alternates between swipe left and swipe right
+//   - Each press alternates between swipe left and swipe right, or with
+//     swipe_mode "hold", a tap swipes right and a hold swipes left
+//
+// Flags (also usable as auto-start arguments):
+//
+//	--paused         start with global hotkeys paused
+//	--open-settings  open the settings page once started
+//	--delay=10s      wait before starting (login races with the USB stack)
+//	--wait-usb       hold startup until the R1 enumerates (up to 60s)
+//	--verbose        log at debug level, overriding log_level in the config
+//	--strict-config  refuse to start if the config file has problems
+//
+// One-shot commands for scripts and keybindings (see "r1ptt help"):
+//
+//	r1ptt swipe [left|right]
+//	r1ptt ptt on|off|toggle
+//	r1ptt tap X Y
+//	r1ptt type [--no-submit] TEXT
+//	r1ptt wake
+//	r1ptt settings
+//	r1ptt status|devices [--json]
+//	r1ptt replay [--speed N] [--from N] [--to N] TRACE
+//	r1ptt keytest [--only DESCRIPTOR] [--firmware VERSION] [--submit URL]
+//	r1ptt agent [--listen ADDR] [--cert FILE --key FILE]
+//	r1ptt completion bash|zsh|fish|powershell
 package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
 	"os/exec"
-	"runtime"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/action"
 	"github.com/HopIT-Hub/R1-Control/internal/autostart"
+	"github.com/HopIT-Hub/R1-Control/internal/bridge"
+	"github.com/HopIT-Hub/R1-Control/internal/browser"
+	"github.com/HopIT-Hub/R1-Control/internal/changelog"
+	"github.com/HopIT-Hub/R1-Control/internal/cli"
 	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/container"
+	"github.com/HopIT-Hub/R1-Control/internal/crostini"
 	"github.com/HopIT-Hub/R1-Control/internal/device"
+	"github.com/HopIT-Hub/R1-Control/internal/dockmenu"
+	"github.com/HopIT-Hub/R1-Control/internal/events"
+	"github.com/HopIT-Hub/R1-Control/internal/focus"
+	"github.com/HopIT-Hub/R1-Control/internal/foreground"
+	"github.com/HopIT-Hub/R1-Control/internal/gestures"
+	"github.com/HopIT-Hub/R1-Control/internal/hostcontext"
+	"github.com/HopIT-Hub/R1-Control/internal/hostmic"
+	"github.com/HopIT-Hub/R1-Control/internal/hostnotify"
+	"github.com/HopIT-Hub/R1-Control/internal/hostpower"
 	"github.com/HopIT-Hub/R1-Control/internal/hotkey"
+	"github.com/HopIT-Hub/R1-Control/internal/instance"
+	"github.com/HopIT-Hub/R1-Control/internal/lifecycle"
+	"github.com/HopIT-Hub/R1-Control/internal/logging"
+	"github.com/HopIT-Hub/R1-Control/internal/macro"
+	"github.com/HopIT-Hub/R1-Control/internal/openrgb"
+	"github.com/HopIT-Hub/R1-Control/internal/overlay"
+	"github.com/HopIT-Hub/R1-Control/internal/pedal"
+	"github.com/HopIT-Hub/R1-Control/internal/pttfallback"
+	"github.com/HopIT-Hub/R1-Control/internal/schedule"
 	"github.com/HopIT-Hub/R1-Control/internal/server"
+	"github.com/HopIT-Hub/R1-Control/internal/stats"
+	"github.com/HopIT-Hub/R1-Control/internal/telemetry"
 	"github.com/HopIT-Hub/R1-Control/internal/tray"
+	"github.com/HopIT-Hub/R1-Control/internal/webusb"
 )
 
 var version = "dev"
 
+// telemetryURL is where opted-in usage reports are posted unless the
+// config sets telemetry.endpoint, set at build time like version; "" =
+// nowhere.
+var telemetryURL = ""
+
+// usbWaitTimeout bounds --wait-usb so a missing device can't keep the app
+// from starting.
+const usbWaitTimeout = 60 * time.Second
+
 func main() {
-	// Load or create config
-	cfg, err := config.Load()
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		cli.Version = version
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
+	startPaused := flag.Bool("paused", false, "start with global hotkeys paused")
+	openSettings := flag.Bool("open-settings", false, "open the settings page once started")
+	startDelay := flag.Duration("delay", 0, "wait this long before starting")
+	waitUSB := flag.Bool("wait-usb", false, "wait for the R1 to enumerate before starting")
+	verbose := flag.Bool("verbose", false, "log at debug level")
+	strictConfig := flag.Bool("strict-config", false, "refuse to start if the config file has problems")
+	headless := flag.Bool("headless", crostini.Detected() || container.Detected(), "run without a tray icon; on by default inside ChromeOS Linux and containers")
+	portable := flag.Bool("portable", false, "keep the config and data in "+config.PortableDirName+" next to the executable, e.g. on a USB stick")
+	flag.CommandLine.Parse(launchArgs(os.Args[1:]))
+
+	logging.Install()
+	if *verbose {
+		logging.SetLevel(logging.Debug)
+	}
+	if *portable {
+		dir, err := config.EnablePortable()
+		if err != nil {
+			log.Fatalf("[r1control] --portable: %v", err)
+		}
+		log.Printf("[r1control] portable: config in %s", dir)
+	}
+
+	if *startDelay > 0 {
+		log.Printf("[r1control] delaying startup by %v", *startDelay)
+		time.Sleep(*startDelay)
+	}
+	if *waitUSB {
+		waitForUSB(usbWaitTimeout)
+	}
+
+	// Load or create config. A fresh install has nothing new to show.
+	freshInstall := false
+	if p, err := config.Path(); err == nil {
+		_, statErr := os.Stat(p)
+		freshInstall = os.IsNotExist(statErr)
+	}
+	config.HotkeyValidator = validateHotkey
+	load := config.Load
+	if *strictConfig {
+		load = config.LoadStrict
+	}
+	cfg, err := load()
+	if verr, ok := err.(*config.ValidationError); ok {
+		for _, p := range verr.Problems {
+			log.Printf("[r1control] %s: %s", verr.Path, p)
+		}
+		log.Fatalf("[r1control] config has %d problem(s); fix them or start without --strict-config", len(verr.Problems))
+	}
 	if err != nil {
 		log.Fatalf("[r1control] config: %v", err)
 	}
+	if !*verbose {
+		level, err := logging.ParseLevel(cfg.GetLogLevel())
+		if err != nil {
+			logging.Warnf("[r1control] config: %v", err)
+		}
+		logging.SetLevel(level)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Device manager — auto-detects R1, reconnects on disconnect
-	devMgr := device.NewManager("", func(state device.State) {
-		tray.SetState(state)
+	// Shutdown hooks — each subsystem registers its teardown as it starts
+	life := lifecycle.New()
+	life.OnShutdown(lifecycle.StopInput, "background tasks", cancel)
+
+	// Usage statistics — non-critical, the app runs without them
+	st, err := stats.Open()
+	if err != nil {
+		log.Printf("[r1control] usage stats disabled: %v", err)
+	} else {
+		life.OnShutdown(lifecycle.Flush, "usage stats", func() {
+			st.SetState(device.Disconnected) // close an open PTT session
+			if err := st.Save(); err != nil {
+				log.Printf("[r1control] save usage stats: %v", err)
+			}
+		})
+	}
+
+	// Anonymous usage report — off until turned on in Settings
+	reporter, err := telemetry.Open(cfg, st, version, telemetryURL)
+	if err != nil {
+		log.Printf("[r1control] usage reports disabled: %v", err)
+	} else {
+		life.OnShutdown(lifecycle.Flush, "usage report", func() {
+			if err := reporter.Save(); err != nil {
+				log.Printf("[r1control] save usage report: %v", err)
+			}
+		})
+	}
+
+	// Event bus — the device and hotkeys publish, the tray, stats and
+	// event export subscribe
+	bus := events.NewBus()
+	ui := tray.New()
+	ui.Subscribe(bus)
+	if reporter != nil {
+		reporter.Subscribe(bus)
+	}
+	if st != nil {
+		st.Subscribe(bus)
+	}
+
+	// Event export (opt-in) — nil discards events
+	evLog := openEventLog(cfg.GetEventLog())
+	life.OnShutdown(lifecycle.Flush, "event log", evLog.Close)
+	bus.Subscribe(evLog.Record, events.TypeState, events.TypeAction, events.TypeLink, events.TypeGameMode, events.TypeMacro, events.TypeFocus, events.TypeProfile)
+
+	bus.Subscribe(func(e events.Event) {
+		state := e.Value.(device.State)
+		overlay.SetActive(state == device.PTTActive)
+		if cfg.GetMicSync() {
+			hostmic.SetMuted(state != device.PTTActive)
+		}
+		if cfg.GetPreventHostSleep() {
+			hostpower.Set("ptt", state == device.PTTActive)
+		}
 		log.Printf("[r1control] device: %s", state)
+	}, events.TypeState)
+	bus.Subscribe(func(e events.Event) {
+		if cfg.GetPreventHostSleep() {
+			hostpower.Set("macro", e.Name == "started")
+		}
+	}, events.TypeMacro)
+	life.OnShutdown(lifecycle.ReleaseHost, "overlay", overlay.Hide)
+	life.OnShutdown(lifecycle.ReleaseHost, "host mic", func() {
+		if cfg.GetMicSync() {
+			hostmic.Release()
+		}
+	})
+	life.OnShutdown(lifecycle.ReleaseHost, "host sleep", func() {
+		if cfg.GetPreventHostSleep() {
+			hostpower.Release()
+		}
 	})
+	bus.Subscribe(func(e events.Event) {
+		info := e.Value.(device.Info)
+		ls := config.LastSeen{Serial: info.Serial, Product: info.Product, Time: e.Time}
+		if err := cfg.SetLastSeen(ls); err != nil {
+			log.Printf("[r1control] save last seen: %v", err)
+		}
+	}, events.TypeConnect)
+
+	// Device manager — auto-detects R1, reconnects on disconnect
+	devCfg := cfg.GetDevice()
+	if traceFile := openUSBTrace(devCfg); traceFile != nil {
+		life.OnShutdown(lifecycle.Flush, "USB trace", func() {
+			aoa.SetTrace(nil)
+			traceFile.Close()
+		})
+	}
+	devMgr := device.NewManager(aoa.Filter{Serial: devCfg.Serial, PortPath: devCfg.PortPath}, bus)
+	life.OnShutdown(lifecycle.CloseDevice, "device", devMgr.Close)
+
+	// Host media keys go to the R1 only while it is connected. Capturing
+	// them talks to the OS, so it runs off the publisher's goroutine,
+	// for the latest state only.
+	mediaStates := make(chan device.State, 1)
+	bus.Subscribe(func(e events.Event) {
+		st := e.Value.(device.State)
+		for {
+			select {
+			case mediaStates <- st:
+				return
+			default:
+				select {
+				case <-mediaStates:
+				default:
+				}
+			}
+		}
+	}, events.TypeState)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case st := <-mediaStates:
+				syncMediaKeys(cfg.GetMediaPassthrough(), st, devMgr, bus)
+			}
+		}
+	}()
+	life.OnShutdown(lifecycle.ReleaseHost, "media keys", hotkey.ReleaseMediaKeys)
+
+	// R1 attached to another computer, reached through its bridge agent
+	if devCfg.Bridge.Address != "" {
+		open, name, err := bridge.NewOpener(devCfg.Bridge)
+		if err != nil {
+			log.Fatalf("[r1control] %v", err)
+		}
+		devMgr.SetOpener(name, open)
+		log.Printf("[r1control] using the R1 via %s", name)
+	}
+
+	// R1 opened by a Chrome tab, where libusb can't reach it
+	var webusbRelay *webusb.Relay
+	if devCfg.Backend == "webusb" && devCfg.Bridge.Address == "" {
+		webusbRelay = webusb.New()
+		devMgr.SetOpener("WebUSB", webusbRelay.Opener())
+		log.Printf("[r1control] using the R1 via WebUSB — open /webusb on the settings page in Chrome to share it")
+	}
 
 	// Apply keep-awake settings from config
 	devMgr.SetKeepAwake(cfg.GetKeepAwake(), cfg.GetSleepAfterMinutes())
+	devMgr.SetKeepAwakeSchedule(cfg.KeepAwakeAt)
+	devMgr.SetKeepAwakeDim(cfg.KeepAwakeDimAt)
+	devMgr.SetMaxLatch(time.Duration(cfg.GetPTTAutoRelease()) * time.Minute)
+	devMgr.SetReleaseStuckAfter(time.Duration(devCfg.ReleaseStuckAfterSeconds) * time.Second)
+	devMgr.SetCompositeHID(devCfg.CompositeHID)
+	if tc := devCfg.TouchContact; tc != nil {
+		devMgr.SetTouchContact(aoa.Contact{Pressure: uint8(tc.Pressure), Width: uint8(tc.Width), Height: uint8(tc.Height)})
+	}
+	applyPressMode(devMgr, cfg)
+	applySwipeKeyMode(devMgr, cfg)
+	applyGestureStyle(devMgr, cfg)
+	devMgr.SetOffLimits(offLimits(devCfg.OffLimits))
+	launcher := cfg.GetLauncher()
+	devMgr.SetLauncherApps(launcherApps(launcher))
+	devMgr.SetCarousel(device.Carousel{
+		Cards: launcher.Carousel,
+		Home:  launcher.Home,
+		Trust: time.Duration(launcher.TrustSeconds) * time.Second,
+	})
 
-	// PTT hotkey manager — toggle/hold-to-talk
-	pttHkMgr := hotkey.NewManager(
-		func() {
-			if err := devMgr.PTTDown(); err != nil {
-				log.Printf("[r1control] PTT down error: %v", err)
-			} else {
+	// PTT hotkey manager — toggle/hold-to-talk, with a fallback for
+	// presses that can't reach the R1
+	pttPress := pttfallback.New(cfg.GetPTTFallback(),
+		func() error {
+			err := devMgr.PTTDown()
+			if err == nil {
 				log.Println("[r1control] PTT ON")
 			}
+			return err
 		},
-		func() {
-			if err := devMgr.PTTUp(); err != nil {
-				log.Printf("[r1control] PTT up error: %v", err)
-			} else {
+		func() error {
+			err := devMgr.PTTUp()
+			if err == nil {
 				log.Println("[r1control] PTT OFF")
 			}
+			return err
 		},
 	)
+	pttHkMgr := hotkey.NewManager(pttPress.Down, pttPress.Up)
+	life.OnShutdown(lifecycle.ReleasePTT, "PTT hotkey", pttPress.Release)
+
+	// A crashed hotkey callback may have pressed PTT and never released it
+	hotkey.SetPanicHandler(func(what string, v any) {
+		publishProblem(bus, "panic", fmt.Sprintf("%s crashed: %v", what, v))
+		pttPress.Release()
+		if devMgr.State() == device.PTTActive {
+			if err := devMgr.SetPTT(false); err != nil {
+				log.Printf("[r1control] release PTT after panic: %v", err)
+			}
+		}
+	})
 
-	// Swipe hotkey manager — alternating left/right on each press
+	// Swipe hotkey manager — alternating left/right on each press, or
+	// picking the direction by how long the key is held
 	swipeHkMgr := hotkey.NewManager(
 		func() {
-			if err := devMgr.Swipe(); err != nil {
+			if err := devMgr.SwipeKeyDown(); err != nil {
+				log.Printf("[r1control] swipe error: %v", err)
+			}
+		},
+		func() {
+			if err := devMgr.SwipeKeyUp(); err != nil {
 				log.Printf("[r1control] swipe error: %v", err)
 			}
 		},
-		nil, // no keyup action needed for swipe
 	)
 
+	// Surface registration failures in the tray, not just the log
+	pttHkMgr.SetErrorHandler(func(err error) { publishProblem(bus, "ptt_hotkey", hotkeyProblem("PTT", err)) })
+	swipeHkMgr.SetErrorHandler(func(err error) { publishProblem(bus, "swipe_hotkey", hotkeyProblem("Swipe", err)) })
+
+	// Game mode — hotkeys are suspended while a listed app is in front
+	var hotkeysSuspended atomic.Bool
+
+	// Paused from the tray (or --paused) — no global hotkeys until resumed
+	var hotkeysPaused atomic.Bool
+	hotkeysPaused.Store(*startPaused)
+
 	// Settings HTTP server
-	srv := server.New(pttHkMgr, swipeHkMgr, devMgr, cfg, version)
+	srv := server.New(pttHkMgr, swipeHkMgr, devMgr, cfg, st, version)
+	life.OnShutdown(lifecycle.StopServer, "settings server", func() {
+		instance.Remove()
+		srv.Stop()
+	})
+	srv.Subscribe(bus)
+	if port := cfg.GetSettingsPort(); port != 0 {
+		srv.SetPort(port)
+	} else if crostini.Detected() {
+		srv.SetPort(crostini.SettingsPort)
+	}
+	srv.SetSelfTestHandler(ui.SetSelfTestResult)
+	macroPlayer := macro.NewPlayer(devMgr, bus)
+	macroPlayer.SetProfileSource(cfg.GetActiveProfile)
+	srv.SetMacros(macro.NewRecorder(bus, cfg.PutMacro), macroPlayer)
+	if webusbRelay != nil {
+		srv.SetWebUSB(webusbRelay)
+	}
+	macroHkMgrs := append(newMacroHotkeys(cfg, macroPlayer, bus), newAppHotkeys(cfg, devMgr, bus)...)
+
+	// Focus timer — runs R1 actions as work periods and breaks begin
+	focusTimer := focus.New(func(st config.FocusStep) error {
+		return runFocusStep(st, cfg, devMgr, macroPlayer)
+	}, bus)
+	srv.SetFocus(focusTimer)
+	if reporter != nil {
+		srv.SetTelemetry(reporter)
+	}
+	life.OnShutdown(lifecycle.StopInput, "focus timer", focusTimer.Stop)
+
+	// Profiles — switched in by context rules or the settings API
+	switchProfile := func(name, by string) error {
+		micSync, preventSleep := cfg.GetMicSync(), cfg.GetPreventHostSleep()
+		if err := cfg.ApplyProfile(name); err != nil {
+			return err
+		}
+		devMgr.SetKeepAwake(cfg.GetKeepAwake(), cfg.GetSleepAfterMinutes())
+		applyPressMode(devMgr, cfg)
+		if on := cfg.GetMicSync(); on != micSync {
+			hostmic.SetMuted(on && devMgr.State() != device.PTTActive)
+		}
+		if on := cfg.GetPreventHostSleep(); on != preventSleep {
+			if on {
+				hostpower.Set("ptt", devMgr.State() == device.PTTActive)
+				hostpower.Set("macro", macroPlayer.Running() != "")
+			} else {
+				hostpower.Release()
+			}
+		}
+		syncMediaKeys(cfg.GetMediaPassthrough(), devMgr.State(), devMgr, bus)
+		ui.SetToggles(tray.Toggles{
+			KeepAwake:        cfg.GetKeepAwake(),
+			MicSync:          cfg.GetMicSync(),
+			PreventSleep:     cfg.GetPreventHostSleep(),
+			MediaPassthrough: cfg.GetMediaPassthrough(),
+		})
+		bus.Publish(events.Event{Type: events.TypeProfile, Name: name, Detail: by})
+		log.Printf("[r1control] switched to profile %q (%s)", name, by)
+		return nil
+	}
+	srv.SetProfileHandler(func(name string) error { return switchProfile(name, "api") })
+	ui.SetProfile(cfg.GetActiveProfile())
+
+	// Prompt hotkey manager — opens the "ask rabbit" prompt page
+	promptHkMgr := hotkey.NewManager(
+		func() {
+			url := srv.URL()
+			if url == "" {
+				log.Println("[r1control] settings server not running")
+				return
+			}
+			openBrowser(url + "/prompt")
+		},
+		nil,
+	)
+	promptHkMgr.SetErrorHandler(func(err error) { publishProblem(bus, "prompt_hotkey", hotkeyProblem("Prompt", err)) })
+
+	// Confirm hotkey manager — presses Enter on the R1
+	confirmHkMgr := hotkey.NewManager(
+		func() {
+			if err := devMgr.Confirm(); err != nil {
+				logging.Warnf("[r1control] confirm: %v", err)
+			}
+		},
+		nil,
+	)
+	confirmHkMgr.SetErrorHandler(func(err error) { publishProblem(bus, "confirm_hotkey", hotkeyProblem("Confirm", err)) })
+	srv.SetHotkeyManagers(promptHkMgr, confirmHkMgr)
+
+	// registerHotkeys registers every hotkey that is enabled in config.
+	registerHotkeys := func() {
+		if cfg.GetHotkey().Enabled {
+			registerPTTHotkey(pttHkMgr, cfg)
+		}
+		if cfg.GetSwipeHotkey().Enabled {
+			registerSwipeHotkey(swipeHkMgr, cfg)
+		}
+		if cfg.GetPromptHotkey().Enabled {
+			registerPromptHotkey(promptHkMgr, cfg)
+		}
+		if cfg.GetConfirmHotkey().Enabled {
+			registerConfirmHotkey(confirmHkMgr, cfg)
+		}
+		for _, mh := range macroHkMgrs {
+			mh.register()
+		}
+	}
+	unregisterHotkeys := func() {
+		pttHkMgr.Unregister()
+		swipeHkMgr.Unregister()
+		promptHkMgr.Unregister()
+		confirmHkMgr.Unregister()
+		for _, mh := range macroHkMgrs {
+			mh.mgr.Unregister()
+		}
+	}
+	life.OnShutdown(lifecycle.StopInput, "hotkeys", unregisterHotkeys)
+	hotkeysActive := func() bool {
+		return !hotkeysSuspended.Load() && !hotkeysPaused.Load() && !cfg.GetDisableHotkeys()
+	}
+
+	// shutdown runs the registered hooks, stage by stage
+	shutdown := life.Shutdown
+
+	// restart shuts down cleanly, then starts a fresh copy of the app
+	restart := func() {
+		shutdown()
+		if err := restartSelf(); err != nil {
+			log.Printf("[r1control] restart: %v", err)
+		}
+	}
+
+	// A config restored from a backup is applied by restarting
+	srv.SetRestartHandler(func() {
+		restart()
+		ui.Quit()
+	})
 
 	// System tray — blocks on main thread
-	tray.Run(tray.RunOpts{
-		Version:          version,
-		AutoStartEnabled: cfg.GetAutoStart(),
-		KeepAwakeEnabled: cfg.GetKeepAwake(),
+	opts := tray.RunOpts{
+		Version:             version,
+		AutoStartEnabled:    cfg.GetAutoStart(),
+		KeepAwakeEnabled:    cfg.GetKeepAwake(),
+		OverlayEnabled:      cfg.GetOverlay().Enabled,
+		MicSyncEnabled:      cfg.GetMicSync(),
+		PreventSleep:        cfg.GetPreventHostSleep(),
+		MediaPassthrough:    cfg.GetMediaPassthrough(),
+		PTTHotkeyEnabled:    cfg.GetHotkey().Enabled,
+		SwipeHotkeyEnabled:  cfg.GetSwipeHotkey().Enabled,
+		PromptHotkeyEnabled: cfg.GetPromptHotkey().Enabled,
+		HotkeysPaused:       *startPaused,
+		PTTMode:             devMgr.PTTMode,
+		PTTDeadline:         devMgr.LatchDeadline,
+		Apps:                appNames(cfg.GetLauncher()),
+		LatchedSince:        devMgr.LatchedSince,
+		MenuBarTimer:        cfg.GetMenuBarTimer(),
 
 		// onReady — start background services after tray is initialized
 		OnReady: func() {
+			// Re-register auto-start so the login entry follows the
+			// current install location after an update
+			if cfg.GetAutoStart() && autostart.IsEnabled() {
+				if err := enableAutoStart(cfg); err != nil {
+					log.Printf("[r1control] refresh autostart: %v", err)
+				}
+			}
+
+			ui.SetLastSeen(cfg.GetLastSeen().Time)
+			checkWhatsNew(cfg, ui, freshInstall)
+
 			// Start device manager
 			go devMgr.Run(ctx)
 
-			// Register PTT hotkey
-			hk := cfg.GetHotkey()
-			if err := pttHkMgr.Register(hk.Modifiers, hk.Key); err != nil {
-				log.Printf("[r1control] PTT hotkey register failed: %v", err)
-				log.Printf("[r1control] you can change the hotkey via Settings")
-			} else {
-				log.Printf("[r1control] PTT hotkey: %s (short press=toggle, hold=talk)", hk.String())
+			// Inside ChromeOS Linux the R1 has to be shared with it first
+			if crostini.Detected() && devCfg.Backend != "webusb" && devCfg.Bridge.Address == "" {
+				go hintCrostiniUSB(ctx, bus)
 			}
 
-			// Register Swipe hotkey
-			shk := cfg.GetSwipeHotkey()
-			if err := swipeHkMgr.Register(shk.Modifiers, shk.Key); err != nil {
-				log.Printf("[r1control] swipe hotkey register failed: %v", err)
-			} else {
-				log.Printf("[r1control] swipe hotkey: %s (alternates left/right)", shk.String())
+			// Read the foot pedal, if one is configured
+			if pc := cfg.GetPedal(); pc.Enabled {
+				startPedal(ctx, pc, devMgr, bus)
+			}
+
+			// Light up the R1 when selected apps notify on this computer
+			if nw := cfg.GetNotifyWake(); nw.Enabled {
+				startNotifyWake(ctx, nw, devMgr, bus)
+			}
+
+			// Light up keyboard and mouse RGB while PTT is on
+			if oc := cfg.GetOpenRGB(); oc.Enabled {
+				ind := openrgb.New(oc)
+				ind.SetProblemHandler(func(p string) { publishProblem(bus, "openrgb", p) })
+				ind.Subscribe(bus)
+				life.OnShutdown(lifecycle.ReleaseHost, "OpenRGB", ind.Close)
+				go ind.Run(ctx)
+			}
+
+			// Switch profiles as the computer moves between docks
+			if rules := cfg.GetContextRules(); len(rules) > 0 {
+				go hostcontext.Watch(ctx, rules, cfg.GetActiveProfile(), func(name string, rule int) {
+					if err := switchProfile(name, fmt.Sprintf("context rule %d", rule)); err != nil {
+						logging.Warnf("[r1control] switch profile: %v", err)
+					}
+				})
+			}
+
+			// Scheduled wake-ups, e.g. for a "good morning" routine
+			startWakeSchedule(ctx, cfg.GetWakeSchedule(), devMgr)
+			if st != nil {
+				go st.Run(ctx, time.Minute, devMgr.KeepingAwake)
+			}
+			if reporter != nil {
+				go reporter.Run(ctx)
+			}
+
+			// Register hotkeys (unless disabled in config or started paused)
+			switch {
+			case cfg.GetDisableHotkeys():
+				log.Println("[r1control] hotkeys turned off in the config")
+			case hotkeysActive():
+				registerHotkeys()
+			default:
+				log.Println("[r1control] hotkeys paused")
+			}
+
+			// Suspend hotkeys while a game-mode app is in the foreground
+			if !cfg.GetDisableHotkeys() {
+				go foreground.Watch(ctx, 2*time.Second,
+					func() []string {
+						gm := cfg.GetGameMode()
+						if !gm.Enabled {
+							return nil
+						}
+						return gm.Processes
+					},
+					func(app string) {
+						hotkeysSuspended.Store(app != "")
+						if app != "" {
+							bus.Publish(events.Event{Type: events.TypeGameMode, Name: "suspended", Detail: app})
+							unregisterHotkeys()
+							log.Printf("[r1control] game mode: hotkeys suspended (%s)", app)
+							return
+						}
+						if hotkeysActive() {
+							registerHotkeys()
+						}
+						bus.Publish(events.Event{Type: events.TypeGameMode, Name: "resumed"})
+						log.Println("[r1control] game mode: hotkeys resumed")
+					})
+			}
+
+			// Start with the host mic muted if it mirrors PTT
+			if cfg.GetMicSync() {
+				hostmic.SetMuted(true)
+			}
+
+			// Show PTT overlay if enabled
+			if cfg.GetOverlay().Enabled {
+				showOverlay(cfg.GetOverlay())
 			}
 
 			// Start settings server
-			if _, err := srv.Start(); err != nil {
+			if url, err := srv.Start(); err != nil {
 				log.Printf("[r1control] settings server: %v", err)
+			} else if err := instance.Write(url); err != nil {
+				log.Printf("[r1control] command-line access disabled: %v", err)
+			}
+
+			// Serve the phone remote on the LAN, if enabled. From outside
+			// a container, that is the only way in.
+			if lc := cfg.GetLAN(); !lc.Enabled && container.Detected() {
+				log.Printf(`[r1control] running in a container: set "lan": {"enabled": true} in %s/config.json and publish port %d to reach the phone remote and API`, configDirName(), lc.Port)
+			} else if lc.Enabled {
+				onPIN := ui.SetPairingPIN
+				if *headless {
+					// No tray to show PINs in: they go to the log
+					onPIN = logPairingPIN
+				}
+				startLAN(srv, lc, onPIN, bus)
+			}
+
+			// Quick actions for users who hide the tray icon
+			go setupDockMenu(cfg.GetTaskbarMenu(), devMgr, srv)
+			if cfg.GetToastActions() {
+				if hostnotify.ButtonsSupported() {
+					go startToastActions(ctx, devMgr)
+				} else {
+					log.Printf("[r1control] toast_actions only works on Windows")
+				}
 			}
 
 			log.Printf("[r1control] ready (version %s)", version)
+			if *headless {
+				log.Printf("[r1control] running without a tray icon — settings at %s", srv.URL())
+			}
+
+			if *openSettings && srv.URL() != "" {
+				openBrowser(srv.URL())
+			}
 		},
 
 		// onSettings — open browser to settings page
@@ -121,10 +695,20 @@ func main() {
 			openBrowser(url)
 		},
 
+		// onWhatsNew — open the release notes and stop offering them
+		OnWhatsNew: func() {
+			if url := srv.URL(); url != "" {
+				openBrowser(url + "/#whats-new")
+			}
+			if err := cfg.SetLastSeenVersion(changelog.Normalize(version)); err != nil {
+				log.Printf("[r1control] save last seen version: %v", err)
+			}
+		},
+
 		// onAutoStart — toggle auto-start on login
 		OnAutoStart: func(enabled bool) {
 			if enabled {
-				if err := autostart.Enable(); err != nil {
+				if err := enableAutoStart(cfg); err != nil {
 					log.Printf("[r1control] enable autostart: %v", err)
 					return
 				}
@@ -149,34 +733,839 @@ func main() {
 			log.Printf("[r1control] keep-awake: %v", enabled)
 		},
 
+		// onFocus — start or stop the focus timer
+		OnFocus: func(enabled bool) {
+			if !enabled {
+				focusTimer.Stop()
+				return
+			}
+			if err := focusTimer.Start(cfg.GetFocus()); err != nil {
+				logging.Warnf("[r1control] focus timer: %v", err)
+				ui.SetFocus("", "") // uncheck
+			}
+		},
+
+		// onOverlay — toggle the on-screen PTT indicator
+		OnOverlay: func(enabled bool) {
+			if err := cfg.SetOverlayEnabled(enabled); err != nil {
+				log.Printf("[r1control] save overlay config: %v", err)
+			}
+			if enabled {
+				showOverlay(cfg.GetOverlay())
+				overlay.SetActive(devMgr.State() == device.PTTActive)
+			} else {
+				overlay.Hide()
+			}
+			log.Printf("[r1control] PTT overlay: %v", enabled)
+		},
+
+		// onMicSync — mirror PTT state to the host microphone
+		OnMicSync: func(enabled bool) {
+			if err := cfg.SetMicSync(enabled); err != nil {
+				log.Printf("[r1control] save mic sync config: %v", err)
+			}
+			// When turning sync off, leave the mic unmuted
+			hostmic.SetMuted(enabled && devMgr.State() != device.PTTActive)
+			log.Printf("[r1control] host mic sync: %v", enabled)
+		},
+
+		// onPreventSleep — keep the host awake while PTT is on or a macro runs
+		OnPreventSleep: func(enabled bool) {
+			if err := cfg.SetPreventHostSleep(enabled); err != nil {
+				log.Printf("[r1control] save host sleep config: %v", err)
+			}
+			if enabled {
+				hostpower.Set("ptt", devMgr.State() == device.PTTActive)
+				hostpower.Set("macro", macroPlayer.Running() != "")
+			} else {
+				hostpower.Release()
+			}
+			log.Printf("[r1control] prevent host sleep: %v", enabled)
+		},
+
+		// onMediaPassthrough — send the host's media keys to the R1
+		OnMediaPassthrough: func(enabled bool) {
+			if err := cfg.SetMediaPassthrough(enabled); err != nil {
+				log.Printf("[r1control] save media passthrough config: %v", err)
+			}
+			syncMediaKeys(enabled, devMgr.State(), devMgr, bus)
+			log.Printf("[r1control] media key passthrough: %v", enabled)
+		},
+
+		// onPTTHotkey — enable/disable the PTT hotkey without losing its binding
+		OnPTTHotkey: func(enabled bool) {
+			if err := cfg.SetHotkeyEnabled(enabled); err != nil {
+				log.Printf("[r1control] save hotkey config: %v", err)
+			}
+			if enabled && hotkeysActive() {
+				registerPTTHotkey(pttHkMgr, cfg)
+			} else if !enabled {
+				pttHkMgr.Unregister()
+				log.Println("[r1control] PTT hotkey disabled")
+			}
+		},
+
+		// onSwipeHotkey — enable/disable the swipe hotkey without losing its binding
+		OnSwipeHotkey: func(enabled bool) {
+			if err := cfg.SetSwipeHotkeyEnabled(enabled); err != nil {
+				log.Printf("[r1control] save swipe hotkey config: %v", err)
+			}
+			if enabled && hotkeysActive() {
+				registerSwipeHotkey(swipeHkMgr, cfg)
+			} else if !enabled {
+				swipeHkMgr.Unregister()
+				log.Println("[r1control] swipe hotkey disabled")
+			}
+		},
+
+		// onPromptHotkey — enable/disable the prompt hotkey without losing its binding
+		OnPromptHotkey: func(enabled bool) {
+			if err := cfg.SetPromptHotkeyEnabled(enabled); err != nil {
+				log.Printf("[r1control] save prompt hotkey config: %v", err)
+			}
+			if enabled && hotkeysActive() {
+				registerPromptHotkey(promptHkMgr, cfg)
+			} else if !enabled {
+				promptHkMgr.Unregister()
+				log.Println("[r1control] prompt hotkey disabled")
+			}
+		},
+
+		// onPauseHotkeys — pause/resume all global hotkeys (not persisted)
+		OnPauseHotkeys: func(paused bool) {
+			hotkeysPaused.Store(paused)
+			if paused {
+				unregisterHotkeys()
+				log.Println("[r1control] hotkeys paused")
+				return
+			}
+			if hotkeysActive() {
+				registerHotkeys()
+			}
+			log.Println("[r1control] hotkeys resumed")
+		},
+
 		// onQuit — clean shutdown
-		OnQuit: func() {
-			cancel()
-			pttHkMgr.Unregister()
-			swipeHkMgr.Unregister()
-			devMgr.Close()
-			srv.Stop()
+		OnQuit: shutdown,
+
+		// onReconnect — drop and re-open the USB connection right away
+		OnReconnect: devMgr.Reconnect,
+
+		// onOpenApp — navigate the R1's launcher to the app
+		OnOpenApp: func(name string) {
+			if err := devMgr.OpenApp(name); err != nil {
+				logging.Warnf("[r1control] open %s: %v", name, err)
+			}
 		},
+
+		// onSelfTest — results are shown via the self-test handler
+		OnSelfTest: func() { srv.SelfTest(ctx) },
+
+		// onRestart — shut down cleanly, then start a fresh copy of the app
+		OnRestart: restart,
+	}
+	if *headless {
+		ui.RunHeadless(opts)
+	} else {
+		ui.Run(opts)
+	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// applyPressMode passes the configured PTT hotkey mode to the device
+// manager, falling back to the default for an unknown mode.
+func applyPressMode(devMgr *device.Manager, cfg *config.Config) {
+	name, thresholdMs := cfg.GetPTTMode()
+	mode, err := device.ParsePressMode(name)
+	if err != nil {
+		log.Printf("[r1control] config: %v", err)
+	}
+	devMgr.SetPressMode(mode, time.Duration(thresholdMs)*time.Millisecond)
+}
+
+// applySwipeKeyMode passes the configured swipe hotkey mode to the
+// device manager, falling back to alternating for an unknown mode.
+func applySwipeKeyMode(devMgr *device.Manager, cfg *config.Config) {
+	name, holdMs, resetSeconds := cfg.GetSwipeMode()
+	mode, err := device.ParseSwipeKeyMode(name)
+	if err != nil {
+		log.Printf("[r1control] config: %v", err)
+	}
+	devMgr.SetSwipeKeyMode(mode, time.Duration(holdMs)*time.Millisecond, time.Duration(resetSeconds)*time.Second)
+}
+
+// applyGestureStyle passes the configured swipe easing, jitter and
+// cursor hiding, and the gesture presets for the configured firmware, to
+// the device manager.
+func applyGestureStyle(devMgr *device.Manager, cfg *config.Config) {
+	g := cfg.GetGesture()
+	easing, err := device.ParseEasing(g.Easing)
+	if err != nil {
+		log.Printf("[r1control] config: %v", err)
+	}
+	devMgr.SetGestureStyle(easing, g.Jitter)
+
+	hiding, best := g.CursorHiding, -1
+	for prefix, h := range g.CursorHidingFirmware {
+		if n := gestures.FirmwareMatch(prefix, g.Firmware); n > best {
+			hiding, best = h, n
+		}
+	}
+	strategy, err := device.ParseCursorHiding(hiding)
+	if err != nil {
+		log.Printf("[r1control] config: %v", err)
+	}
+	devMgr.SetCursorHiding(strategy)
+
+	lib, err := gestures.Load()
+	if err != nil {
+		log.Printf("[r1control] gesture presets: %v — using the built-in ones", err)
+	}
+	devMgr.SetGestures(lib, g.Firmware)
+}
+
+// offLimits converts the configured off-limits screen regions for the
+// device manager.
+func offLimits(regions []config.ScreenRegion) []device.Region {
+	out := make([]device.Region, len(regions))
+	for i, r := range regions {
+		out[i] = device.Region{X0: uint16(r.X0), Y0: uint16(r.Y0), X1: uint16(r.X1), Y1: uint16(r.Y1)}
+	}
+	return out
+}
+
+// launcherApps converts the configured launcher shortcuts for the device
+// manager.
+func launcherApps(l config.LauncherConfig) []device.LauncherApp {
+	out := make([]device.LauncherApp, len(l.Apps))
+	for i, a := range l.Apps {
+		out[i] = device.LauncherApp{Name: a.Name, Swipes: a.Swipes, X: a.X, Y: a.Y}
+	}
+	return out
+}
+
+// appNames lists the launcher apps' names, for the tray.
+func appNames(l config.LauncherConfig) []string {
+	names := make([]string, len(l.Apps))
+	for i, a := range l.Apps {
+		names[i] = a.Name
+	}
+	return names
+}
+
+// publishProblem reports an error from source (e.g. "pedal") on bus; an
+// empty msg clears it.
+func publishProblem(bus *events.Bus, source, msg string) {
+	bus.Publish(events.Event{Type: events.TypeProblem, Name: source, Detail: msg})
+}
+
+// setupDockMenu offers PTT toggle, swipe and the settings page in the
+// Windows jump list or the macOS Dock menu when enabled, and takes them
+// away when not.
+func setupDockMenu(enabled bool, devMgr *device.Manager, srv *server.Server) {
+	if !enabled {
+		if err := dockmenu.Remove(); err != nil {
+			log.Printf("[r1control] remove taskbar menu: %v", err)
+		}
+		return
+	}
+	items := []dockmenu.Item{
+		{Title: "Toggle Push-to-Talk", Args: []string{"ptt", "toggle"}, Run: func() {
+			if err := devMgr.TogglePTT(); err != nil {
+				log.Printf("[r1control] PTT toggle error: %v", err)
+			}
+		}},
+		{Title: "Swipe", Args: []string{"swipe"}, Run: func() {
+			if err := devMgr.Swipe(); err != nil {
+				log.Printf("[r1control] swipe error: %v", err)
+			}
+		}},
+		{Title: "Open Settings", Args: []string{"settings"}, Run: func() {
+			if url := srv.URL(); url != "" {
+				openBrowser(url)
+			}
+		}},
+	}
+	if err := dockmenu.Install(items); err != nil {
+		log.Printf("[r1control] taskbar menu: %v", err)
+	}
+}
+
+// startLAN serves the phone remote on the local network. New clients
+// pair with a PIN shown by onPIN and on the settings page.
+func startLAN(srv *server.Server, lc config.LANConfig, onPIN func(name, pin string), bus *events.Bus) {
+	srv.SetPairingHandler(onPIN)
+	if _, err := srv.StartLAN(lc.Port); err != nil {
+		log.Printf("[r1control] phone remote: %v", err)
+		publishProblem(bus, "lan", "Phone remote unavailable: "+err.Error())
+	}
+}
+
+// configDirName returns the config directory for messages.
+func configDirName() string {
+	dir, err := config.Dir()
+	if err != nil {
+		return "the config directory"
+	}
+	return dir
+}
+
+// logPairingPIN logs a new client's pairing PIN, where there is no tray
+// to show it in.
+func logPairingPIN(name, pin string) {
+	if pin != "" {
+		log.Printf("[r1control] pairing %s — PIN %s", name, pin)
+	}
+}
+
+// startPedal reads the configured foot pedal and runs the action mapped
+// to each button: "ptt" follows the pedal like the PTT hotkey, any other
+// action runs on press.
+func startPedal(ctx context.Context, pc config.PedalConfig, devMgr *device.Manager, bus *events.Bus) {
+	opts := pedal.Options{Kind: pc.Kind, Port: pc.Port, Baud: pc.Baud}
+	if pc.Kind != "serial" || pc.Port == "" {
+		vid, err := pedal.ParseID(pc.VID)
+		if err == nil {
+			opts.PID, err = pedal.ParseID(pc.PID)
+		}
+		if err != nil {
+			log.Printf("[r1control] pedal: %v", err)
+			publishProblem(bus, "pedal", "Foot pedal: "+err.Error())
+			return
+		}
+		opts.VID = vid
+	}
+
+	buttons := pc.Buttons
+	if len(buttons) == 0 {
+		buttons = map[string]string{"1": "ptt"}
+	}
+	for b, name := range buttons {
+		if name == "ptt" {
+			continue
+		}
+		if err := action.Validate(name, nil); err != nil {
+			log.Printf("[r1control] pedal button %s: %v", b, err)
+			delete(buttons, b)
+		}
+	}
+
+	r := pedal.New(opts, func(button int, down bool) {
+		name := buttons[strconv.Itoa(button)]
+		var err error
+		switch {
+		case name == "ptt" && down:
+			err = devMgr.PTTDown()
+		case name == "ptt":
+			err = devMgr.PTTUp()
+		case name != "" && down:
+			err = action.Run(devMgr, name, nil)
+		}
+		if err != nil {
+			log.Printf("[r1control] pedal %s: %v", name, err)
+		}
 	})
+	r.SetProblemHandler(func(p string) { publishProblem(bus, "pedal", p) })
+	go r.Run(ctx)
 }
 
-func openBrowser(url string) {
-	var cmd string
+// runFocusStep runs a focus timer step: a macro in the background, or an
+// action.
+func runFocusStep(st config.FocusStep, cfg *config.Config, devMgr *device.Manager, player *macro.Player) error {
+	if st.Macro == "" {
+		return action.Run(devMgr, st.Action, st.Payload)
+	}
+	m, ok := macro.Find(cfg.GetMacros(), st.Macro)
+	if !ok {
+		return fmt.Errorf("no macro named %q", st.Macro)
+	}
+	return player.Start(m, macro.Params(st.Params), nil)
+}
+
+// startNotifyWake wakes the R1's screen when one of the configured apps
+// posts a desktop notification, at most once per cooldown.
+func startNotifyWake(ctx context.Context, nw config.NotifyWakeConfig, devMgr *device.Manager, bus *events.Bus) {
+	cooldown := time.Duration(nw.CooldownSeconds) * time.Second
+	if cooldown == 0 {
+		cooldown = 30 * time.Second
+	}
+	var last time.Time // only touched by the watcher
+	err := hostnotify.Watch(ctx, func(n hostnotify.Notification) {
+		if len(nw.Apps) > 0 && !slices.ContainsFunc(nw.Apps, func(app string) bool { return strings.EqualFold(app, n.App) }) {
+			logging.Debugf("[r1control] notification from %q isn't in notify_wake.apps", n.App)
+			return
+		}
+		if time.Since(last) < cooldown || devMgr.State() != device.Connected {
+			return // just woken, or not connected or talking
+		}
+		if err := devMgr.Wake(); err != nil {
+			logging.Warnf("[r1control] wake for notification: %v", err)
+			return
+		}
+		last = time.Now()
+		log.Printf("[r1control] woke the R1 for a notification from %s", n.App)
+	})
+	if err != nil {
+		publishProblem(bus, "notify_wake", "Wake on notifications: "+err.Error())
+		return
+	}
+	if len(nw.Apps) == 0 {
+		log.Printf("[r1control] waking the R1 on notifications from any app")
+	} else {
+		log.Printf("[r1control] waking the R1 on notifications from %s", strings.Join(nw.Apps, ", "))
+	}
+}
+
+// toastButtons are the buttons of the toast shown while PTT is latched,
+// by the action each runs.
+var toastButtons = []hostnotify.Button{
+	{ID: "ptt_off", Label: "Release PTT"},
+	{ID: "swipe", Label: "Swipe"},
+}
+
+// toastWait is how long the latched PTT toast waits for a click. Windows
+// takes it off the screen sooner, but it stays in the notification
+// center until then.
+const toastWait = 30 * time.Minute
+
+// toastCancelled is reported in place of a button by a toast the app took
+// down itself, so it isn't mistaken for one the user dismissed.
+const toastCancelled = "\x00cancelled"
+
+// startToastActions shows a toast with buttons to release PTT or swipe
+// while PTT is latched, for users who hide the tray icon in the overflow
+// area. Like the tray's latched PTT line, it checks every second; the
+// toast is taken down when PTT is released some other way. After a swipe
+// the toast comes back; once dismissed, it waits for the next latch.
+func startToastActions(ctx context.Context, devMgr *device.Manager) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	clicked := make(chan string, 1)
+	var cancel context.CancelFunc // takes down the toast; nil = none shown
+	dismissed := false
+	for {
+		select {
+		case <-ctx.Done():
+			if cancel != nil {
+				cancel()
+			}
+			return
+		case name := <-clicked:
+			if name == toastCancelled {
+				continue // cancel was cleared when it was taken down
+			}
+			cancel = nil
+			if name == "" {
+				dismissed = true
+				continue
+			}
+			if err := action.Run(devMgr, name, nil); err != nil {
+				logging.Warnf("[r1control] toast %s: %v", name, err)
+			}
+		case <-ticker.C:
+		}
+
+		latched := devMgr.PTTMode() == "latched"
+		switch {
+		case !latched:
+			dismissed = false
+			if cancel != nil {
+				cancel()
+				cancel = nil
+			}
+		case cancel == nil && !dismissed:
+			tctx, c := context.WithCancel(ctx)
+			cancel = c
+			go func() {
+				name, err := hostnotify.PostButtons(tctx, "R1 Control", "Push-to-talk is latched on.", toastButtons, toastWait)
+				if tctx.Err() != nil {
+					name = toastCancelled
+				} else if err != nil {
+					logging.Warnf("[r1control] latched PTT toast: %v", err)
+				}
+				clicked <- name
+			}()
+		}
+	}
+}
+
+// syncMediaKeys captures the host's volume, mute and play/pause keys for
+// the R1 while passthrough is on and the R1 is connected, and gives them
+// back to the host otherwise, so a press never goes nowhere.
+func syncMediaKeys(on bool, state device.State, devMgr *device.Manager, bus *events.Bus) {
+	want := on && (state == device.Connected || state == device.PTTActive)
+	if want == hotkey.MediaKeysCaptured() {
+		return
+	}
+	if !want {
+		hotkey.ReleaseMediaKeys()
+		publishProblem(bus, "media_passthrough", "")
+		return
+	}
+	err := hotkey.CaptureMediaKeys(func(key string) {
+		if err := devMgr.MediaKey(key); err != nil {
+			logging.Warnf("[r1control] media key %s: %v", key, err)
+		}
+	})
+	if err != nil {
+		publishProblem(bus, "media_passthrough", "R1 media keys: "+err.Error())
+		return
+	}
+	publishProblem(bus, "media_passthrough", "")
+}
+
+// startWakeSchedule wakes the R1's screen at the configured times.
+func startWakeSchedule(ctx context.Context, times []config.WakeTime, devMgr *device.Manager) {
+	var entries []schedule.Entry
+	for _, wt := range times {
+		e, err := schedule.Parse(wt.At, wt.Days)
+		if err != nil {
+			log.Printf("[r1control] wake schedule: %v", err)
+			continue
+		}
+		log.Printf("[r1control] waking R1 at %s", e)
+		entries = append(entries, e)
+	}
+	go schedule.Run(ctx, entries, "wake R1", func() {
+		if err := devMgr.Wake(); err != nil {
+			log.Printf("[r1control] scheduled wake: %v", err)
+		}
+	})
+}
+
+// checkWhatsNew offers the release notes in the tray if this version is
+// newer than the last one whose notes were seen. Fresh installs just
+// record the version.
+func checkWhatsNew(cfg *config.Config, ui *tray.Tray, freshInstall bool) {
+	if version == "dev" {
+		return
+	}
+	v := changelog.Normalize(version)
+	lastSeen := cfg.GetLastSeenVersion()
+	if lastSeen == v {
+		return
+	}
+	if freshInstall || len(changelog.Since(lastSeen)) == 0 {
+		if err := cfg.SetLastSeenVersion(v); err != nil {
+			log.Printf("[r1control] save last seen version: %v", err)
+		}
+		return
+	}
+	ui.SetWhatsNew(v)
+}
+
+// restartExitCode is the exit status that asks a service manager to
+// start the app again (EX_TEMPFAIL).
+const restartExitCode = 75
+
+// exitCode is the status main exits with once the UI has quit.
+var exitCode int
+
+// supervised reports whether a service manager started the app and will
+// restart it when it exits with a failure status. A child started by the
+// app itself would be stopped along with the service instead; as a
+// container's first process, the container would stop with the app.
+func supervised() bool {
+	return os.Getenv("INVOCATION_ID") != "" || os.Getenv("NOTIFY_SOCKET") != "" ||
+		container.Detected() && os.Getpid() == 1
+}
+
+// restartSelf starts a new instance with the same arguments, minus the
+// login-only startup delays. Under a service manager it leaves the
+// restart to it, exiting with restartExitCode.
+func restartSelf() error {
+	if supervised() {
+		exitCode = restartExitCode
+		log.Printf("[r1control] exiting with status %d for the service manager to restart", restartExitCode)
+		return nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
 	var args []string
+	for _, a := range os.Args[1:] {
+		if strings.HasPrefix(a, "--delay") || a == "--wait-usb" {
+			continue
+		}
+		args = append(args, a)
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	log.Printf("[r1control] restarted (pid %d)", cmd.Process.Pid)
+	return nil
+}
+
+// hotkeyProblem formats a registration error for display, e.g.
+// "PTT hotkey conflict: Ctrl+Alt+R in use". Returns "" for nil.
+func hotkeyProblem(name string, err error) string {
+	if err == nil {
+		return ""
+	}
+	return name + " " + err.Error()
+}
+
+// hotkeyBindings converts a hotkey config into the bindings to register.
+func hotkeyBindings(hk config.HotkeyConfig) []hotkey.Binding {
+	var bs []hotkey.Binding
+	for _, b := range hk.Bindings() {
+		bs = append(bs, hotkey.Binding{Modifiers: b.Modifiers, Key: b.Key})
+	}
+	return bs
+}
+
+// validateHotkey checks that a binding from the config file can be
+// registered on this platform, for config validation.
+func validateHotkey(modifiers []string, key string) error {
+	if hotkey.IsMouseButton(key) {
+		return nil
+	}
+	if len(modifiers) == 0 {
+		return fmt.Errorf("at least one modifier required")
+	}
+	_, err := hotkey.ParseKey(key)
+	return err
+}
+
+func registerPTTHotkey(m *hotkey.Manager, cfg *config.Config) {
+	hk := cfg.GetHotkey()
+	if err := m.RegisterAll(hotkeyBindings(hk)); err != nil {
+		log.Printf("[r1control] PTT hotkey register failed: %v", err)
+		log.Printf("[r1control] you can change the hotkey via Settings")
+		return
+	}
+	log.Printf("[r1control] PTT hotkey: %s (short press=toggle, hold=talk)", hk.String())
+}
+
+func registerSwipeHotkey(m *hotkey.Manager, cfg *config.Config) {
+	shk := cfg.GetSwipeHotkey()
+	if err := m.RegisterAll(hotkeyBindings(shk)); err != nil {
+		log.Printf("[r1control] swipe hotkey register failed: %v", err)
+		return
+	}
+	log.Printf("[r1control] swipe hotkey: %s (alternates left/right)", shk.String())
+}
+
+// waitForUSB polls the bus until an R1 enumerates or timeout passes.
+func waitForUSB(timeout time.Duration) {
+	log.Printf("[r1control] waiting up to %v for the R1 to enumerate", timeout)
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if locs, err := aoa.Locate(); err == nil && len(locs) > 0 {
+			log.Printf("[r1control] R1 found (%s)", locs[0])
+			return
+		}
+		time.Sleep(time.Second)
+	}
+	log.Println("[r1control] no R1 found yet — starting anyway")
+}
+
+// crostiniUSBGrace is how long the R1 may take to show up inside ChromeOS
+// Linux before the hint to share it is shown.
+const crostiniUSBGrace = 10 * time.Second
+
+// hintCrostiniUSB shows how to share the R1 with ChromeOS Linux if it
+// hasn't shown up after crostiniUSBGrace, until it connects. It stops
+// listening for connects once the hint is settled either way.
+func hintCrostiniUSB(ctx context.Context, bus *events.Bus) {
+	connected := make(chan struct{}, 1)
+	unsubscribe := bus.Subscribe(func(e events.Event) {
+		select {
+		case connected <- struct{}{}:
+		default:
+		}
+	}, events.TypeConnect)
+	defer unsubscribe()
+
+	select {
+	case <-time.After(crostiniUSBGrace):
+	case <-connected:
+		return
+	case <-ctx.Done():
+		return
+	}
+	if locs, err := aoa.Locate(); err == nil && len(locs) > 0 {
+		return
+	}
+	log.Printf("[r1control] %s", crostini.USBHint)
+	publishProblem(bus, "crostini", crostini.USBHint)
+
+	select {
+	case <-connected:
+		publishProblem(bus, "crostini", "")
+	case <-ctx.Done():
+	}
+}
+
+// launchArgs drops arguments that the OS adds on its own: older macOS
+// passes a "-psn_…" process serial number to apps started from Finder.
+func launchArgs(args []string) []string {
+	out := args[:0:0]
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-psn_") {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// enableAutoStart writes the login entry using the launch settings from config.
+func enableAutoStart(cfg *config.Config) error {
+	launch := cfg.GetAutoStartLaunch()
+	return autostart.Enable(autostart.Options{Program: launch.Program, Args: launch.CommandArgs()})
+}
+
+func registerPromptHotkey(m *hotkey.Manager, cfg *config.Config) {
+	phk := cfg.GetPromptHotkey()
+	if err := m.RegisterAll(hotkeyBindings(phk)); err != nil {
+		log.Printf("[r1control] prompt hotkey register failed: %v", err)
+		return
+	}
+	log.Printf("[r1control] prompt hotkey: %s (opens ask rabbit)", phk.String())
+}
+
+func registerConfirmHotkey(m *hotkey.Manager, cfg *config.Config) {
+	chk := cfg.GetConfirmHotkey()
+	if err := m.RegisterAll(hotkeyBindings(chk)); err != nil {
+		log.Printf("[r1control] confirm hotkey register failed: %v", err)
+		return
+	}
+	log.Printf("[r1control] confirm hotkey: %s (presses Enter on the R1)", chk.String())
+}
+
+// macroHotkey is a hotkey that runs a macro with fixed parameters, or
+// opens a launcher app.
+type macroHotkey struct {
+	mgr     *hotkey.Manager
+	binding hotkey.Binding
+	what    string // "macro" or "launcher app"
+	name    string
+}
+
+func (mh macroHotkey) register() {
+	if err := mh.mgr.RegisterAll([]hotkey.Binding{mh.binding}); err != nil {
+		log.Printf("[r1control] %s %q hotkey register failed: %v", mh.what, mh.name, err)
+		return
+	}
+	log.Printf("[r1control] %s hotkey: %s (runs %q)", mh.what, mh.binding.String(), mh.name)
+}
+
+// newMacroHotkeys creates a hotkey manager for each hotkey of each macro.
+// They are read at startup; registering them is left to the caller.
+func newMacroHotkeys(cfg *config.Config, player *macro.Player, bus *events.Bus) []macroHotkey {
+	var out []macroHotkey
+	for _, m := range cfg.GetMacros() {
+		for _, hk := range m.Hotkeys {
+			params := macro.Params(hk.Params)
+			mgr := hotkey.NewManager(func() {
+				if err := player.Start(m, params, nil); err != nil {
+					logging.Warnf("[r1control] macro %q: %v", m.Name, err)
+				}
+			}, nil)
+			source := "macro_hotkey:" + m.Name
+			mgr.SetErrorHandler(func(err error) { publishProblem(bus, source, hotkeyProblem("Macro "+m.Name, err)) })
+			out = append(out, macroHotkey{
+				mgr:     mgr,
+				binding: hotkey.Binding{Modifiers: hk.Modifiers, Key: hk.Key},
+				what:    "macro",
+				name:    m.Name,
+			})
+		}
+	}
+	return out
+}
+
+// newAppHotkeys creates a hotkey manager for each launcher app with a
+// hotkey. They are read at startup; registering them is left to the
+// caller.
+func newAppHotkeys(cfg *config.Config, devMgr *device.Manager, bus *events.Bus) []macroHotkey {
+	var out []macroHotkey
+	for _, a := range cfg.GetLauncher().Apps {
+		if a.Hotkey == nil {
+			continue
+		}
+		mgr := hotkey.NewManager(func() {
+			go func() {
+				if err := devMgr.OpenApp(a.Name); err != nil {
+					logging.Warnf("[r1control] open %s: %v", a.Name, err)
+				}
+			}()
+		}, nil)
+		source := "app_hotkey:" + a.Name
+		mgr.SetErrorHandler(func(err error) { publishProblem(bus, source, hotkeyProblem("Open "+a.Name, err)) })
+		out = append(out, macroHotkey{
+			mgr:     mgr,
+			binding: hotkey.Binding{Modifiers: a.Hotkey.Modifiers, Key: a.Hotkey.Key},
+			what:    "launcher app",
+			name:    a.Name,
+		})
+	}
+	return out
+}
+
+// openEventLog starts the JSONL event export if enabled in config.
+// Returns nil (which discards events) when disabled or on error.
+func openEventLog(ec config.EventLogConfig) *events.Log {
+	if !ec.Enabled {
+		return nil
+	}
+	path := ec.Path
+	if path == "" {
+		dir, err := config.Dir()
+		if err != nil {
+			log.Printf("[r1control] event log: %v", err)
+			return nil
+		}
+		path = filepath.Join(dir, "events.jsonl")
+	}
+	l, err := events.Open(path, ec.MaxSizeMB, ec.MaxFiles)
+	if err != nil {
+		log.Printf("[r1control] event log: %v", err)
+		return nil
+	}
+	log.Printf("[r1control] exporting events to %s", path)
+	return l
+}
 
-	switch runtime.GOOS {
-	case "darwin":
-		cmd = "open"
-		args = []string{url}
-	case "windows":
-		cmd = "cmd"
-		args = []string{"/c", "start", url}
-	default: // linux, bsd
-		cmd = "xdg-open"
-		args = []string{url}
+// openUSBTrace starts tracing USB control transfers if enabled. Each run
+// starts a fresh trace file.
+func openUSBTrace(dc config.DeviceConfig) *os.File {
+	if !dc.Trace {
+		return nil
+	}
+	path := dc.TracePath
+	if path == "" {
+		dir, err := config.Dir()
+		if err != nil {
+			log.Printf("[r1control] USB trace: %v", err)
+			return nil
+		}
+		path = filepath.Join(dir, "usb-trace.log")
 	}
+	f, err := os.Create(path)
+	if err != nil {
+		log.Printf("[r1control] USB trace: %v", err)
+		return nil
+	}
+	aoa.SetTrace(f)
+	log.Printf("[r1control] tracing USB transfers to %s", path)
+	return f
+}
 
-	if err := exec.Command(cmd, args...).Start(); err != nil {
+func showOverlay(oc config.OverlayConfig) {
+	err := overlay.Show(overlay.Options{Position: oc.Position, Size: oc.Size})
+	if err != nil {
+		log.Printf("[r1control] PTT overlay: %v", err)
+	}
+}
+
+func openBrowser(url string) {
+	if err := browser.Open(url); err != nil {
 		log.Printf("[r1control] open browser: %v", err)
 	}
 }
@@ -0,0 +1,226 @@
+// Package action maps named actions, as stored in config and sent by API
+// clients, to device manager operations.
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/device"
+)
+
+// MaxTextLength caps "type_text" input; typing holds the device for
+// about 16ms per character.
+const MaxTextLength = 300
+
+// tapPayload is the payload for the "tap" action.
+type tapPayload struct {
+	X uint16 `json:"x"` // 0-32767
+	Y uint16 `json:"y"` // 0-32767
+}
+
+// swipePayload is the optional payload for the "swipe" action.
+type swipePayload struct {
+	Direction string `json:"direction,omitempty"` // "left", "right" or "" to alternate
+}
+
+// gesturePayload is the payload for the "gesture" action.
+type gesturePayload struct {
+	Name string `json:"name"` // a gesture preset, e.g. "back"
+}
+
+// openAppPayload is the payload for the "open_app" action.
+type openAppPayload struct {
+	Name string `json:"name"` // a launcher app, e.g. "Music"
+}
+
+// typeTextPayload is the payload for the "type_text" action.
+type typeTextPayload struct {
+	Text   string `json:"text"`
+	Submit *bool  `json:"submit,omitempty"` // press Enter afterwards (default true)
+}
+
+// handler runs one action with its raw JSON payload (may be empty).
+type handler func(dev *device.Manager, payload json.RawMessage) error
+
+var handlers = map[string]handler{
+	"ptt_on":     func(dev *device.Manager, _ json.RawMessage) error { return dev.SetPTT(true) },
+	"ptt_off":    func(dev *device.Manager, _ json.RawMessage) error { return dev.SetPTT(false) },
+	"ptt_toggle": func(dev *device.Manager, _ json.RawMessage) error { return dev.TogglePTT() },
+	"wake":       func(dev *device.Manager, _ json.RawMessage) error { return dev.Wake() },
+	"confirm":    func(dev *device.Manager, _ json.RawMessage) error { return dev.Confirm() },
+
+	"volume_up":   func(dev *device.Manager, _ json.RawMessage) error { return dev.MediaKey("volume_up") },
+	"volume_down": func(dev *device.Manager, _ json.RawMessage) error { return dev.MediaKey("volume_down") },
+	"mute":        func(dev *device.Manager, _ json.RawMessage) error { return dev.MediaKey("mute") },
+	"play_pause":  func(dev *device.Manager, _ json.RawMessage) error { return dev.MediaKey("play_pause") },
+
+	// Experimental: the firmware may ignore these
+	"brightness_up":   func(dev *device.Manager, _ json.RawMessage) error { return dev.DisplayKey("brightness_up") },
+	"brightness_down": func(dev *device.Manager, _ json.RawMessage) error { return dev.DisplayKey("brightness_down") },
+	"swipe": func(dev *device.Manager, payload json.RawMessage) error {
+		dir, err := parseSwipe(payload)
+		if err != nil {
+			return err
+		}
+		return dev.SwipeTo(dir)
+	},
+	"tap": func(dev *device.Manager, payload json.RawMessage) error {
+		p, err := parseTap(payload)
+		if err != nil {
+			return err
+		}
+		return dev.Tap(p.X, p.Y)
+	},
+	"gesture": func(dev *device.Manager, payload json.RawMessage) error {
+		p, err := parseGesture(payload)
+		if err != nil {
+			return err
+		}
+		return dev.Gesture(p.Name)
+	},
+	"open_app": func(dev *device.Manager, payload json.RawMessage) error {
+		p, err := parseOpenApp(payload)
+		if err != nil {
+			return err
+		}
+		return dev.OpenApp(p.Name)
+	},
+	"type_text": func(dev *device.Manager, payload json.RawMessage) error {
+		p, err := parseTypeText(payload)
+		if err != nil {
+			return err
+		}
+		return dev.TypeText(p.Text, p.Submit == nil || *p.Submit)
+	},
+}
+
+// Names returns the supported action names in sorted order.
+func Names() []string {
+	names := make([]string, 0, len(handlers))
+	for name := range handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that name is a known action and its payload is well formed.
+func Validate(name string, payload json.RawMessage) error {
+	if _, ok := handlers[name]; !ok {
+		return fmt.Errorf("unknown action %q", name)
+	}
+	switch name {
+	case "swipe":
+		_, err := parseSwipe(payload)
+		return err
+	case "tap":
+		_, err := parseTap(payload)
+		return err
+	case "gesture":
+		_, err := parseGesture(payload)
+		return err
+	case "open_app":
+		_, err := parseOpenApp(payload)
+		return err
+	case "type_text":
+		_, err := parseTypeText(payload)
+		return err
+	}
+	return nil
+}
+
+// Run executes the named action on the device.
+func Run(dev *device.Manager, name string, payload json.RawMessage) error {
+	h, ok := handlers[name]
+	if !ok {
+		return fmt.Errorf("unknown action %q", name)
+	}
+	return h(dev, payload)
+}
+
+func parseSwipe(payload json.RawMessage) (device.SwipeDirection, error) {
+	var p swipePayload
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return device.SwipeNext, fmt.Errorf("invalid swipe payload: %w", err)
+		}
+	}
+	switch p.Direction {
+	case "":
+		return device.SwipeNext, nil
+	case "left":
+		return device.SwipeLeft, nil
+	case "right":
+		return device.SwipeRight, nil
+	default:
+		return device.SwipeNext, fmt.Errorf("swipe direction must be \"left\" or \"right\"")
+	}
+}
+
+func parseTap(payload json.RawMessage) (tapPayload, error) {
+	var p tapPayload
+	if len(payload) == 0 {
+		return p, fmt.Errorf("tap requires an {\"x\", \"y\"} payload")
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return p, fmt.Errorf("invalid tap payload: %w", err)
+	}
+	if p.X > 32767 || p.Y > 32767 {
+		return p, fmt.Errorf("tap x and y must be in range 0-32767")
+	}
+	return p, nil
+}
+
+func parseGesture(payload json.RawMessage) (gesturePayload, error) {
+	var p gesturePayload
+	if len(payload) == 0 {
+		return p, fmt.Errorf("gesture requires a {\"name\"} payload")
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return p, fmt.Errorf("invalid gesture payload: %w", err)
+	}
+	if p.Name == "" {
+		return p, fmt.Errorf("gesture name is empty")
+	}
+	return p, nil
+}
+
+func parseOpenApp(payload json.RawMessage) (openAppPayload, error) {
+	var p openAppPayload
+	if len(payload) == 0 {
+		return p, fmt.Errorf("open_app requires a {\"name\"} payload")
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return p, fmt.Errorf("invalid open_app payload: %w", err)
+	}
+	if p.Name == "" {
+		return p, fmt.Errorf("app name is empty")
+	}
+	return p, nil
+}
+
+func parseTypeText(payload json.RawMessage) (typeTextPayload, error) {
+	var p typeTextPayload
+	if len(payload) == 0 {
+		return p, fmt.Errorf("type_text requires a {\"text\"} payload")
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return p, fmt.Errorf("invalid type_text payload: %w", err)
+	}
+	if p.Text == "" {
+		return p, fmt.Errorf("type_text requires non-empty text")
+	}
+	if utf8.RuneCountInString(p.Text) > MaxTextLength {
+		return p, fmt.Errorf("text is longer than %d characters", MaxTextLength)
+	}
+	for _, r := range p.Text {
+		if _, _, ok := aoa.KeyForRune(r); !ok {
+			return p, fmt.Errorf("character %q cannot be typed on the R1", r)
+		}
+	}
+	return p, nil
+}
@@ -2,7 +2,36 @@
 // Each platform has its own implementation file.
 package autostart
 
-import "os"
+import (
+	"fmt"
+	"os"
+)
+
+// Options controls what the login entry launches.
+type Options struct {
+	// Program overrides the executable or launcher to start, e.g. an
+	// installer's stub launcher or shortcut. Empty = detect.
+	Program string
+	// Args are passed to the program, e.g. ["--profile", "desk"].
+	Args []string
+}
+
+// program returns the path the login entry should start: the configured
+// override, or the running executable mapped to a stable launcher path
+// when it was started from an installer-managed location.
+func (o Options) program() (string, error) {
+	if o.Program != "" {
+		if _, err := os.Stat(o.Program); err != nil {
+			return "", fmt.Errorf("autostart program: %w", err)
+		}
+		return o.Program, nil
+	}
+	exe, err := appPath()
+	if err != nil {
+		return "", fmt.Errorf("get executable path: %w", err)
+	}
+	return launcherPath(exe)
+}
 
 // appPath returns the path to the currently running executable.
 func appPath() (string, error) {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 )
 
@@ -14,7 +15,9 @@ const (
 	launchAgentFile  = "co.hopit.r1control.plist"
 )
 
-var plistTemplate = template.Must(template.New("plist").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+var plistTemplate = template.Must(template.New("plist").Funcs(template.FuncMap{
+	"xml": template.HTMLEscapeString,
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
 <!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
 <plist version="1.0">
 <dict>
@@ -22,7 +25,10 @@ var plistTemplate = template.Must(template.New("plist").Parse(`<?xml version="1.
     <string>{{ .Label }}</string>
     <key>ProgramArguments</key>
     <array>
-        <string>{{ .Program }}</string>
+        <string>{{ xml .Program }}</string>
+{{- range .Args }}
+        <string>{{ xml . }}</string>
+{{- end }}
     </array>
     <key>RunAtLoad</key>
     <true/>
@@ -50,11 +56,20 @@ func IsEnabled() bool {
 	return err == nil
 }
 
+// launcherPath rejects translocated copies: Gatekeeper runs quarantined
+// apps from a random read-only path that is gone after the next launch.
+func launcherPath(exe string) (string, error) {
+	if strings.Contains(exe, "/AppTranslocation/") {
+		return "", fmt.Errorf("app is running from a temporary location; move R1 Control to /Applications first")
+	}
+	return exe, nil
+}
+
 // Enable creates a LaunchAgent plist so the app starts on login.
-func Enable() error {
-	exe, err := appPath()
+func Enable(opts Options) error {
+	exe, err := opts.program()
 	if err != nil {
-		return fmt.Errorf("get executable path: %w", err)
+		return err
 	}
 
 	p, err := plistPath()
@@ -75,9 +90,11 @@ func Enable() error {
 	data := struct {
 		Label   string
 		Program string
+		Args    []string
 	}{
 		Label:   launchAgentLabel,
 		Program: exe,
+		Args:    opts.Args,
 	}
 
 	if err := plistTemplate.Execute(f, data); err != nil {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const desktopFileName = "r1control.desktop"
@@ -39,11 +40,34 @@ func IsEnabled() bool {
 	return err == nil
 }
 
+// launcherPath maps an AppImage's temporary mount point back to the
+// .AppImage file, which is the only path that survives a restart.
+func launcherPath(exe string) (string, error) {
+	if img := os.Getenv("APPIMAGE"); img != "" {
+		return img, nil
+	}
+	return exe, nil
+}
+
+// desktopQuote quotes an Exec argument per the Desktop Entry spec:
+// arguments with reserved characters are double-quoted with ", `, $ and \
+// backslash-escaped, then the value is string-escaped (\ doubled) and %
+// is doubled so it isn't read as a field code.
+func desktopQuote(arg string) string {
+	s := arg
+	if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`") {
+		r := strings.NewReplacer(`"`, `\"`, "`", "\\`", `$`, `\$`, `\`, `\\`)
+		s = `"` + r.Replace(arg) + `"`
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
 // Enable creates an autostart .desktop entry for the current executable.
-func Enable() error {
-	exe, err := appPath()
+func Enable(opts Options) error {
+	exe, err := opts.program()
 	if err != nil {
-		return fmt.Errorf("get executable path: %w", err)
+		return err
 	}
 
 	p, err := desktopFilePath()
@@ -55,7 +79,12 @@ func Enable() error {
 		return fmt.Errorf("create autostart dir: %w", err)
 	}
 
-	content := fmt.Sprintf(desktopEntryTemplate, exe)
+	exec := []string{desktopQuote(exe)}
+	for _, a := range opts.Args {
+		exec = append(exec, desktopQuote(a))
+	}
+
+	content := fmt.Sprintf(desktopEntryTemplate, strings.Join(exec, " "))
 	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
 		return fmt.Errorf("write desktop file: %w", err)
 	}
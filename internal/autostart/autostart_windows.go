@@ -4,6 +4,10 @@ package autostart
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 
 	"golang.org/x/sys/windows/registry"
 )
@@ -25,11 +29,48 @@ func IsEnabled() bool {
 	return err == nil
 }
 
+// launcherPath maps installer-managed locations to a path that survives
+// updates:
+//   - MSIX packages live under a versioned WindowsApps directory; the
+//     app execution alias in %LOCALAPPDATA%\Microsoft\WindowsApps is stable.
+//   - Squirrel-style installers run the app from a versioned "app-x.y.z"
+//     folder next to a stub launcher of the same name.
+func launcherPath(exe string) (string, error) {
+	name := filepath.Base(exe)
+
+	if strings.Contains(strings.ToLower(exe), `\windowsapps\`) {
+		alias := filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "WindowsApps", name)
+		if _, err := os.Stat(alias); err == nil {
+			return alias, nil
+		}
+		return "", fmt.Errorf("packaged app has no execution alias; set the autostart program in config")
+	}
+
+	dir := filepath.Dir(exe)
+	if strings.HasPrefix(strings.ToLower(filepath.Base(dir)), "app-") {
+		stub := filepath.Join(filepath.Dir(dir), name)
+		if _, err := os.Stat(stub); err == nil {
+			return stub, nil
+		}
+	}
+	return exe, nil
+}
+
+// commandLine builds the Run value: the program is always quoted (paths
+// usually contain spaces), arguments are quoted as needed.
+func commandLine(exe string, args []string) string {
+	parts := []string{`"` + exe + `"`}
+	for _, a := range args {
+		parts = append(parts, syscall.EscapeArg(a))
+	}
+	return strings.Join(parts, " ")
+}
+
 // Enable adds an auto-start registry entry for the current executable.
-func Enable() error {
-	exe, err := appPath()
+func Enable(opts Options) error {
+	exe, err := opts.program()
 	if err != nil {
-		return fmt.Errorf("get executable path: %w", err)
+		return err
 	}
 
 	k, err := registry.OpenKey(registry.CURRENT_USER, regKeyPath, registry.SET_VALUE)
@@ -38,7 +79,7 @@ func Enable() error {
 	}
 	defer k.Close()
 
-	if err := k.SetStringValue(regValName, exe); err != nil {
+	if err := k.SetStringValue(regValName, commandLine(exe, opts.Args)); err != nil {
 		return fmt.Errorf("set registry value: %w", err)
 	}
 
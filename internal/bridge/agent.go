@@ -0,0 +1,468 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/device"
+)
+
+// Agent timing. The R1 is kept open between clients, so a network drop
+// doesn't cost a USB reconnect and re-registration.
+const (
+	agentPoll         = 2 * time.Second
+	keepAwakeInterval = 25 * time.Second // beats R1's shortest 30s auto-sleep
+
+	// A connected app pings every 2s; silence this long means the
+	// network dropped without closing the connection.
+	clientIdleTimeout = 30 * time.Second
+
+	// After a failed auth, the address is refused for failDelay, doubling
+	// with each further failure up to maxFailDelay.
+	failDelay    = time.Second
+	maxFailDelay = time.Minute
+)
+
+// powerUp releases the System Control keys a client may have left down.
+var powerUp = []byte{0x00}
+
+// Agent serves the R1 attached to this computer to bridge clients, one
+// at a time. It reconnects to the R1 on its own, and keeps it awake while
+// no client is connected.
+type Agent struct {
+	token  string
+	filter aoa.Filter
+
+	mu           sync.Mutex // guards the R1; held for each transfer
+	dev          *aoa.Device
+	ids          map[aoa.DescriptorType]uint16 // registered on dev
+	client       string                        // address of the connected client; "" = none
+	keepAwake    bool
+	offLimits    []device.Region // where keep-awake taps mustn't land
+	contact      aoa.Contact     // of keep-awake taps
+	sleepAfter   time.Duration   // 0 = never let it sleep
+	lastActivity time.Time       // last client request
+
+	failMu sync.Mutex
+	fails  map[string]*failures // failed auths by client IP
+}
+
+// failures tracks the failed auths from one address.
+type failures struct {
+	n     int
+	until time.Time // refused until then
+}
+
+// NewAgent creates an agent that accepts clients presenting token and
+// serves the R1 matching filter. With keepAwake, the R1 is pinged while
+// no client is connected, until sleepAfter without a client request.
+func NewAgent(token string, filter aoa.Filter, keepAwake bool, sleepAfter time.Duration) *Agent {
+	return &Agent{
+		token:        token,
+		filter:       filter,
+		keepAwake:    keepAwake,
+		contact:      aoa.DefaultContact,
+		sleepAfter:   sleepAfter,
+		lastActivity: time.Now(),
+		fails:        make(map[string]*failures),
+	}
+}
+
+// SetOffLimits sets the screen regions keep-awake taps are moved out of,
+// as device.Manager.SetOffLimits does for the app. Call before Serve.
+func (a *Agent) SetOffLimits(regions []device.Region) {
+	a.offLimits = append([]device.Region(nil), regions...)
+}
+
+// SetTouchContact sets the pressure and contact size of keep-awake taps.
+// Call before Serve.
+func (a *Agent) SetTouchContact(c aoa.Contact) {
+	a.contact = c
+}
+
+// Serve keeps the R1 connected and accepts clients on ln until ctx is
+// cancelled.
+func (a *Agent) Serve(ctx context.Context, ln net.Listener) error {
+	go a.run(ctx)
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go a.handle(ctx, nc)
+	}
+}
+
+// run reconnects the R1 and sends keep-awake pings until ctx is
+// cancelled, then closes it.
+func (a *Agent) run(ctx context.Context) {
+	poll := time.NewTicker(agentPoll)
+	defer poll.Stop()
+	wake := time.NewTicker(keepAwakeInterval)
+	defer wake.Stop()
+
+	a.check()
+	for {
+		select {
+		case <-ctx.Done():
+			a.mu.Lock()
+			a.dropLocked()
+			a.mu.Unlock()
+			return
+		case <-poll.C:
+			a.check()
+		case <-wake.C:
+			a.keepAwakePing()
+		}
+	}
+}
+
+// check opens the R1 if it isn't open, or drops it if it stopped
+// answering.
+func (a *Agent) check() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.dev != nil {
+		if err := a.dev.Ping(); err != nil {
+			log.Printf("[bridge] R1 disconnected: %v", err)
+			a.dropLocked()
+		}
+		return
+	}
+	dev, err := aoa.OpenFilter(a.filter)
+	if err != nil {
+		return // not attached, will retry
+	}
+	a.dev = dev
+	a.ids = make(map[aoa.DescriptorType]uint16)
+	log.Printf("[bridge] R1 connected (%s, %s speed)", dev.Location(), dev.Speed())
+}
+
+// dropLocked closes the R1 and forgets the HIDs registered on it. Must
+// be called with a.mu held.
+func (a *Agent) dropLocked() {
+	if a.dev != nil {
+		a.dev.Close()
+		a.dev = nil
+	}
+	a.ids = nil
+}
+
+// failLocked drops the R1 after a USB error, so the next poll reopens it.
+// Must be called with a.mu held.
+func (a *Agent) failLocked(err error) {
+	if err != nil && !errors.Is(err, context.Canceled) && !aoa.IsBadReport(err) && a.dev != nil {
+		log.Printf("[bridge] USB error: %v — will reconnect", err)
+		a.dropLocked()
+	}
+}
+
+// registerLocked returns the HID ID for dt, registering it on first use.
+// Must be called with a.mu held and a.dev != nil.
+func (a *Agent) registerLocked(dt aoa.DescriptorType) (uint16, error) {
+	if id, ok := a.ids[dt]; ok {
+		return id, nil
+	}
+	id, err := a.dev.RegisterDescriptor(dt)
+	if err != nil {
+		return 0, err
+	}
+	a.ids[dt] = id
+	return id, nil
+}
+
+// keepAwakePing sends the app's keep-awake ping while no client is
+// connected.
+func (a *Agent) keepAwakePing() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.keepAwake || a.dev == nil || a.client != "" {
+		return
+	}
+	if a.sleepAfter > 0 && time.Since(a.lastActivity) >= a.sleepAfter {
+		return
+	}
+	pttID, err := a.registerLocked(aoa.DescSystemControl)
+	if err != nil {
+		a.failLocked(err)
+		return
+	}
+	touchID, err := a.registerLocked(aoa.DescTouchScreen)
+	if err != nil {
+		a.failLocked(err)
+		return
+	}
+
+	var tap func(tip bool) []byte
+	if x, y, ok := device.KeepAwakeSpot(a.offLimits); ok {
+		tap = func(tip bool) []byte { return aoa.TouchReportContact(tip, x, y, a.contact) }
+	}
+	if err := device.SendKeepAwake(a.dev, pttID, touchID, tap); err != nil {
+		log.Printf("[bridge] keep-awake ping: %v", err)
+		if aoa.IsGone(err) {
+			a.dropLocked()
+		}
+	}
+}
+
+// releaseLocked lifts every key and touch a client may have left down,
+// e.g. PTT held when the network dropped. Must be called with a.mu held.
+func (a *Agent) releaseLocked() {
+	if a.dev == nil {
+		return
+	}
+	for dt, id := range a.ids {
+		var reports [][]byte
+		switch dt {
+		case aoa.DescSystemControl:
+			reports = [][]byte{powerUp}
+		case aoa.DescTouchScreen:
+			reports = [][]byte{aoa.TouchReport(false, 0, 0)}
+		case aoa.DescKeyboard:
+			reports = [][]byte{aoa.KeyboardReport(0, 0)}
+		case aoa.DescComposite:
+			reports = [][]byte{
+				aoa.WithReportID(aoa.ReportIDSystemControl, powerUp),
+				aoa.WithReportID(aoa.ReportIDTouch, aoa.TouchReport(false, 0, 0)),
+			}
+		default:
+			continue
+		}
+		for _, report := range reports {
+			if err := a.dev.SendReportTo(id, report); err != nil {
+				a.failLocked(err)
+				return
+			}
+		}
+	}
+}
+
+// attach makes remote the connected client and returns the R1's identity.
+func (a *Agent) attach(remote string) (response, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.client != "" {
+		return response{}, errors.New("bridge: R1 is in use by another client")
+	}
+	if a.dev == nil {
+		return response{}, errors.New("bridge: no R1 attached to the agent")
+	}
+	a.client = remote
+	a.lastActivity = time.Now()
+	loc := a.dev.Location()
+	return response{
+		Serial:     a.dev.Serial(),
+		Product:    a.dev.Product(),
+		Speed:      a.dev.Speed(),
+		Configured: a.dev.Configured(),
+		Bus:        loc.Bus,
+		Address:    loc.Address,
+		Path:       loc.Path,
+	}, nil
+}
+
+// detach releases the R1 from the connected client.
+func (a *Agent) detach() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.releaseLocked()
+	a.client = ""
+	a.lastActivity = time.Now()
+}
+
+// do performs one client request on the R1.
+func (a *Agent) do(ctx context.Context, req request) (response, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.dev == nil {
+		return response{}, errors.New("bridge: R1 disconnected from the agent")
+	}
+	a.lastActivity = time.Now()
+
+	var resp response
+	var err error
+	switch req.Op {
+	case opRegister:
+		resp.ID, err = a.registerLocked(aoa.DescriptorType(req.Desc))
+	case opSend:
+		err = a.dev.SendReportTo(req.ID, req.Report)
+	case opSequence:
+		seq := make([]aoa.TimedReport, len(req.Steps))
+		for i, s := range req.Steps {
+			seq[i] = aoa.TimedReport{At: time.Duration(s.AtMicros) * time.Microsecond, Report: s.Report}
+		}
+		err = a.dev.SendReportSequence(ctx, req.ID, seq)
+	case opPing:
+		err = a.dev.Ping()
+	default:
+		return resp, fmt.Errorf("unknown op %q", req.Op)
+	}
+	a.failLocked(err)
+	return resp, err
+}
+
+// refused reports whether host is refused after failed auths.
+func (a *Agent) refused(host string) bool {
+	a.failMu.Lock()
+	defer a.failMu.Unlock()
+	f, ok := a.fails[host]
+	return ok && time.Now().Before(f.until)
+}
+
+// authResult records whether an auth from host succeeded, refusing host
+// for a while after a failure.
+func (a *Agent) authResult(host string, ok bool) {
+	a.failMu.Lock()
+	defer a.failMu.Unlock()
+	if ok {
+		delete(a.fails, host)
+		return
+	}
+	now := time.Now()
+	for h, f := range a.fails {
+		if now.Sub(f.until) > maxFailDelay {
+			delete(a.fails, h)
+		}
+	}
+	f := a.fails[host]
+	if f == nil {
+		f = &failures{}
+		a.fails[host] = f
+	}
+	f.n++
+	f.until = now.Add(min(failDelay<<min(f.n-1, 6), maxFailDelay))
+}
+
+// handle runs one client connection.
+func (a *Agent) handle(ctx context.Context, nc net.Conn) {
+	defer nc.Close()
+	remote := nc.RemoteAddr().String()
+	host, _, _ := net.SplitHostPort(remote)
+	enc := json.NewEncoder(nc)
+	dec := json.NewDecoder(bufio.NewReader(nc))
+
+	nc.SetDeadline(time.Now().Add(helloTimeout))
+	if a.refused(host) {
+		enc.Encode(response{Error: "bridge: too many failed attempts — try again shortly"})
+		return
+	}
+	var hello request
+	if err := dec.Decode(&hello); err != nil || hello.Op != opHello {
+		log.Printf("[bridge] %s: no hello", remote)
+		return
+	}
+	if hello.Version != Version {
+		enc.Encode(response{Error: fmt.Sprintf("bridge: protocol version %d, agent speaks %d — update both sides", hello.Version, Version)})
+		return
+	}
+	challenge := make([]byte, challengeSize)
+	rand.Read(challenge)
+	if enc.Encode(response{Challenge: challenge}) != nil {
+		return
+	}
+	var auth request
+	if err := dec.Decode(&auth); err != nil || auth.Op != opAuth {
+		log.Printf("[bridge] %s: no auth", remote)
+		return
+	}
+	if !hmac.Equal(auth.MAC, authMAC(a.token, challenge)) {
+		a.authResult(host, false)
+		log.Printf("[bridge] %s: wrong token", remote)
+		enc.Encode(response{Error: "bridge: wrong token"})
+		return
+	}
+	a.authResult(host, true)
+	id, err := a.attach(remote)
+	if err != nil {
+		enc.Encode(response{Error: err.Error()})
+		return
+	}
+	defer a.detach()
+	if enc.Encode(id) != nil {
+		return
+	}
+	log.Printf("[bridge] %s: connected", remote)
+	nc.SetDeadline(time.Time{})
+
+	// Requests are read separately so cancel can reach a running sequence.
+	reqs := make(chan request)
+	var cancelMu sync.Mutex
+	var cancelSeq context.CancelFunc
+	go func() {
+		defer close(reqs)
+		for {
+			var req request
+			nc.SetReadDeadline(time.Now().Add(clientIdleTimeout))
+			if err := dec.Decode(&req); err != nil {
+				return
+			}
+			if req.Op == opCancel {
+				cancelMu.Lock()
+				if cancelSeq != nil {
+					cancelSeq()
+				}
+				cancelMu.Unlock()
+				continue
+			}
+			select {
+			case reqs <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		var req request
+		var ok bool
+		select {
+		case req, ok = <-reqs:
+		case <-ctx.Done():
+		}
+		if !ok {
+			log.Printf("[bridge] %s: disconnected", remote)
+			return
+		}
+
+		reqCtx, cancel := context.WithCancel(ctx)
+		cancelMu.Lock()
+		cancelSeq = cancel
+		cancelMu.Unlock()
+		resp, err := a.do(reqCtx, req)
+		cancelMu.Lock()
+		cancelSeq = nil
+		cancelMu.Unlock()
+		cancel()
+
+		if err != nil {
+			resp.Error = err.Error()
+			if !errors.Is(err, context.Canceled) {
+				log.Printf("[bridge] %s: %s: %v", remote, req.Op, err)
+			}
+		}
+		if enc.Encode(resp) != nil {
+			return
+		}
+	}
+}
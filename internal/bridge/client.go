@@ -0,0 +1,196 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/device"
+)
+
+var _ device.Transport = (*Conn)(nil)
+
+// Conn is a connection to a bridge agent with the R1 opened on it. It
+// implements device.Transport.
+type Conn struct {
+	mu    sync.Mutex // one request at a time
+	wmu   sync.Mutex // guards enc; cancel is written mid-request
+	conn  net.Conn
+	enc   *json.Encoder
+	dec   *json.Decoder
+	hello response
+}
+
+// NewOpener returns a device.Opener that connects to the agent in bc,
+// and a name for it to use in logs.
+func NewOpener(bc config.BridgeConfig) (device.Opener, string, error) {
+	addr := bc.Address
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(DefaultPort))
+	}
+	var tlsConf *tls.Config
+	if bc.TLS {
+		host, _, _ := net.SplitHostPort(addr)
+		tlsConf = &tls.Config{ServerName: host}
+		if bc.CAFile != "" {
+			pem, err := os.ReadFile(bc.CAFile)
+			if err != nil {
+				return nil, "", fmt.Errorf("bridge CA: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, "", fmt.Errorf("bridge CA: no certificates in %s", bc.CAFile)
+			}
+			tlsConf.RootCAs = pool
+		}
+	}
+	open := func() (device.Transport, error) {
+		c, err := Dial(addr, bc.Token, tlsConf)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	return open, "bridge " + addr, nil
+}
+
+// Dial connects to the agent at addr, using TLS if tlsConf is set, and
+// opens its R1.
+func Dial(addr, token string, tlsConf *tls.Config) (*Conn, error) {
+	d := &net.Dialer{Timeout: dialTimeout}
+	var nc net.Conn
+	var err error
+	if tlsConf != nil {
+		nc, err = tls.DialWithDialer(d, "tcp", addr, tlsConf)
+	} else {
+		nc, err = d.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Conn{conn: nc, enc: json.NewEncoder(nc), dec: json.NewDecoder(bufio.NewReader(nc))}
+	resp, err := c.do(request{Op: opHello, Version: Version}, helloTimeout)
+	if err == nil && len(resp.Challenge) != challengeSize {
+		err = errors.New("bridge: the agent sent no challenge — update both sides")
+	}
+	if err == nil {
+		resp, err = c.do(request{Op: opAuth, MAC: authMAC(token, resp.Challenge)}, helloTimeout)
+	}
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	c.hello = resp
+	return c, nil
+}
+
+// do sends req and waits for its response.
+func (c *Conn) do(req request, timeout time.Duration) (response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.doLocked(req, timeout)
+}
+
+func (c *Conn) doLocked(req request, timeout time.Duration) (response, error) {
+	c.conn.SetDeadline(time.Now().Add(timeout))
+	if err := c.write(req); err != nil {
+		return response{}, fmt.Errorf("bridge: %w", err)
+	}
+	var resp response
+	if err := c.dec.Decode(&resp); err != nil {
+		return response{}, fmt.Errorf("bridge: %w", err)
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+func (c *Conn) write(req request) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.enc.Encode(req)
+}
+
+// RegisterDescriptor registers an HID descriptor on the agent's R1.
+func (c *Conn) RegisterDescriptor(dt aoa.DescriptorType) (uint16, error) {
+	resp, err := c.do(request{Op: opRegister, Desc: int(dt)}, requestTimeout)
+	return resp.ID, err
+}
+
+// SendReportTo sends one HID report.
+func (c *Conn) SendReportTo(hidID uint16, report []byte) error {
+	_, err := c.do(request{Op: opSend, ID: hidID, Report: report}, requestTimeout)
+	return err
+}
+
+// SendReportSequence sends seq to the agent, which plays it with the
+// original timing. Cancelling ctx stops it on the agent.
+func (c *Conn) SendReportSequence(ctx context.Context, hidID uint16, seq []aoa.TimedReport) error {
+	req := request{Op: opSequence, ID: hidID, Steps: make([]step, len(seq))}
+	var total time.Duration
+	for i, s := range seq {
+		req.Steps[i] = step{AtMicros: s.At.Microseconds(), Report: s.Report}
+		total = max(total, s.At)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.doLocked(req, total+requestTimeout)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		c.write(request{Op: opCancel})
+		<-done // the agent answers once the sequence stops
+		return ctx.Err()
+	}
+}
+
+// Ping checks that the agent and its R1 are still there.
+func (c *Conn) Ping() error {
+	_, err := c.do(request{Op: opPing}, requestTimeout)
+	return err
+}
+
+// ConfirmSettle does nothing: the agent's R1 settles its own HIDs.
+func (c *Conn) ConfirmSettle() {}
+
+// Close releases the R1 on the agent.
+func (c *Conn) Close() {
+	c.conn.Close()
+}
+
+// Serial returns the R1's USB serial number.
+func (c *Conn) Serial() string { return c.hello.Serial }
+
+// Product returns the R1's USB product string.
+func (c *Conn) Product() string { return c.hello.Product }
+
+// Location returns where the R1 is enumerated on the agent's bus.
+func (c *Conn) Location() aoa.Location {
+	return aoa.Location{Bus: c.hello.Bus, Address: c.hello.Address, Path: c.hello.Path}
+}
+
+// Speed returns the R1's bus speed on the agent.
+func (c *Conn) Speed() string { return c.hello.Speed }
+
+// Configured reports whether the R1 was configured when it was opened.
+func (c *Conn) Configured() bool { return c.hello.Configured }
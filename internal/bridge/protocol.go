@@ -0,0 +1,90 @@
+// Package bridge drives an R1 attached to another computer. A small agent
+// ("r1ptt agent") runs on the computer the R1 is plugged into and
+// performs AOA calls on behalf of the app, which treats the connection
+// like a local device.
+//
+// The protocol is newline-delimited JSON over TCP, optionally TLS. The
+// client opens with a hello; the agent answers with a random challenge,
+// and the client proves it knows the shared token with an auth request
+// carrying the challenge's HMAC, so the token itself never crosses the
+// network. The agent answers that with the identity of the R1 it keeps
+// open. Every other request gets
+// exactly one response, in order, except cancel, which interrupts the
+// report sequence in progress and has no response of its own. When the
+// connection closes, the agent releases any keys the client left down.
+package bridge
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"time"
+)
+
+// Version is the protocol version; client and agent must match.
+const Version = 2
+
+// DefaultPort is the agent's default TCP port.
+const DefaultPort = 9761
+
+// Request operations.
+const (
+	opHello    = "hello"    // Version → Challenge
+	opAuth     = "auth"     // MAC → R1 identity
+	opRegister = "register" // Desc → ID
+	opSend     = "send"     // ID, Report
+	opSequence = "sequence" // ID, Steps
+	opCancel   = "cancel"   // no response
+	opPing     = "ping"
+)
+
+// request is a client→agent message.
+type request struct {
+	Op      string `json:"op"`
+	Version int    `json:"version,omitempty"`
+	MAC     []byte `json:"mac,omitempty"`
+	Desc    int    `json:"desc,omitempty"`
+	ID      uint16 `json:"id,omitempty"`
+	Report  []byte `json:"report,omitempty"`
+	Steps   []step `json:"steps,omitempty"`
+}
+
+// step is one aoa.TimedReport.
+type step struct {
+	AtMicros int64  `json:"at_us"`
+	Report   []byte `json:"report"`
+}
+
+// response is an agent→client message.
+type response struct {
+	Error string `json:"error,omitempty"`
+	ID    uint16 `json:"id,omitempty"` // register
+
+	Challenge []byte `json:"challenge,omitempty"` // hello
+
+	// auth
+	Serial     string `json:"serial,omitempty"`
+	Product    string `json:"product,omitempty"`
+	Speed      string `json:"speed,omitempty"`
+	Configured bool   `json:"configured,omitempty"`
+	Bus        int    `json:"bus,omitempty"`
+	Address    int    `json:"address,omitempty"`
+	Path       []int  `json:"path,omitempty"`
+}
+
+// Timeouts for the connection. Sequences add their own duration.
+const (
+	dialTimeout    = 3 * time.Second
+	requestTimeout = 5 * time.Second
+	helloTimeout   = 5 * time.Second
+)
+
+// challengeSize is the length of the agent's random challenge.
+const challengeSize = 32
+
+// authMAC returns the answer to challenge for token.
+func authMAC(token string, challenge []byte) []byte {
+	h := hmac.New(sha256.New, []byte(token))
+	h.Write([]byte("r1ptt bridge auth\x00"))
+	h.Write(challenge)
+	return h.Sum(nil)
+}
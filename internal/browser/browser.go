@@ -0,0 +1,27 @@
+// Package browser opens URLs in the user's default web browser.
+package browser
+
+import (
+	"os/exec"
+	"runtime"
+)
+
+// Open opens url in the default browser without waiting for it.
+func Open(url string) error {
+	var cmd string
+	var args []string
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = "open"
+		args = []string{url}
+	case "windows":
+		cmd = "cmd"
+		args = []string{"/c", "start", url}
+	default: // linux, bsd
+		cmd = "xdg-open"
+		args = []string{url}
+	}
+
+	return exec.Command(cmd, args...).Start()
+}
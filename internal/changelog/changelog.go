@@ -0,0 +1,63 @@
+// Package changelog embeds the release notes shown in the settings UI's
+// "What's new" section after an update.
+package changelog
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+)
+
+// Unreleased is the version of the notes entry for changes not yet
+// tagged. Rename it to the tag when releasing.
+const Unreleased = "unreleased"
+
+//go:embed releases.json
+var releasesJSON []byte
+
+// Release is the notes for one version.
+type Release struct {
+	Version string `json:"version"`
+	Notes   []Note `json:"notes"`
+}
+
+// Note is one change. Actions and Endpoints name what it added, so the
+// settings UI can document them.
+type Note struct {
+	Text      string   `json:"text"`
+	Actions   []string `json:"actions,omitempty"`
+	Endpoints []string `json:"endpoints,omitempty"`
+}
+
+// Releases returns all release notes, newest first.
+func Releases() []Release {
+	var rs []Release
+	if err := json.Unmarshal(releasesJSON, &rs); err != nil {
+		panic("changelog: releases.json: " + err.Error()) // embedded, so only a broken edit gets here
+	}
+	return rs
+}
+
+// Normalize returns version without the "v" prefix or the git describe
+// suffix, e.g. "v1.2.0-3-gabc1234" → "1.2.0".
+func Normalize(version string) string {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.Index(version, "-"); i >= 0 {
+		version = version[:i]
+	}
+	return version
+}
+
+// Since returns the releases newer than lastSeen, newest first. All
+// releases are returned if lastSeen isn't listed, e.g. a version older
+// than the changelog.
+func Since(lastSeen string) []Release {
+	rs := Releases()
+	lastSeen = Normalize(lastSeen)
+	for i, r := range rs {
+		if Normalize(r.Version) == lastSeen {
+			return rs[:i]
+		}
+	}
+	return rs
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/bridge"
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/device"
+	"github.com/HopIT-Hub/R1-Control/internal/instance"
+)
+
+const agentUsage = "agent [--listen ADDR] [--token T] [--cert FILE --key FILE]"
+
+// bridgeTokenEnv is read when --token isn't given, so the token needn't
+// show up in process listings.
+const bridgeTokenEnv = "R1PTT_BRIDGE_TOKEN"
+
+// runAgent serves the R1 attached to this computer to R1 Control on
+// another one (device.bridge in its config) until interrupted. The R1's
+// connection and keep-awake are handled here, so they survive network
+// drops; keep-awake follows this computer's config.
+func runAgent(out output, args []string) int {
+	listen := ":" + strconv.Itoa(bridge.DefaultPort)
+	token := os.Getenv(bridgeTokenEnv)
+	var certFile, keyFile string
+	for len(args) > 0 {
+		arg := args[0]
+		if len(args) < 2 {
+			return out.fail(2, errors.New("expected: "+agentUsage))
+		}
+		switch arg {
+		case "--listen":
+			listen = args[1]
+		case "--token":
+			token = args[1]
+		case "--cert":
+			certFile = args[1]
+		case "--key":
+			keyFile = args[1]
+		default:
+			return out.fail(2, errors.New("expected: "+agentUsage))
+		}
+		args = args[2:]
+	}
+	if token == "" {
+		return out.fail(2, fmt.Errorf("a token is required: set %s or pass --token", bridgeTokenEnv))
+	}
+	if (certFile == "") != (keyFile == "") {
+		return out.fail(2, errors.New("--cert and --key go together"))
+	}
+
+	// The running app would compete for the R1.
+	if _, err := instance.Lookup(); err == nil {
+		return out.fail(1, errors.New("R1 Control is running — quit it before starting the agent"))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return out.fail(1, fmt.Errorf("config: %w", err))
+	}
+	devCfg := cfg.GetDevice()
+
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return out.fail(1, err)
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			ln.Close()
+			return out.fail(1, fmt.Errorf("TLS: %w", err))
+		}
+		ln = tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	log.Printf("[bridge] listening on %s (TLS: %v)", ln.Addr(), certFile != "")
+	agent := bridge.NewAgent(token, aoa.Filter{Serial: devCfg.Serial, PortPath: devCfg.PortPath},
+		cfg.GetKeepAwake(), time.Duration(cfg.GetSleepAfterMinutes())*time.Minute)
+	regions := make([]device.Region, len(devCfg.OffLimits))
+	for i, r := range devCfg.OffLimits {
+		regions[i] = device.Region{X0: uint16(r.X0), Y0: uint16(r.Y0), X1: uint16(r.X1), Y1: uint16(r.Y1)}
+	}
+	agent.SetOffLimits(regions)
+	if tc := devCfg.TouchContact; tc != nil {
+		agent.SetTouchContact(aoa.Contact{Pressure: uint8(tc.Pressure), Width: uint8(tc.Width), Height: uint8(tc.Height)})
+	}
+	if err := agent.Serve(ctx, ln); err != nil {
+		return out.fail(1, err)
+	}
+	return 0
+}
@@ -0,0 +1,326 @@
+// Package cli implements one-shot commands such as "r1ptt swipe left" for
+// shell scripts and window-manager keybindings. Commands are sent to the
+// running tray app; if none is running, the R1 is opened directly for
+// the duration of the command.
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/action"
+	"github.com/HopIT-Hub/R1-Control/internal/bridge"
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/device"
+	"github.com/HopIT-Hub/R1-Control/internal/instance"
+)
+
+// requestTimeout bounds a command sent to the running app. Typing the
+// longest allowed text takes about 5s.
+const requestTimeout = 15 * time.Second
+
+// command parses its arguments into an action name and payload.
+type command struct {
+	usage string
+	words []string // argument words offered by shell completion
+	parse func(args []string) (name string, payload any, err error)
+}
+
+var commands = map[string]command{
+	"swipe": {
+		usage: "swipe [left|right]",
+		words: []string{"left", "right"},
+		parse: func(args []string) (string, any, error) {
+			switch {
+			case len(args) == 0:
+				return "swipe", nil, nil
+			case len(args) == 1 && (args[0] == "left" || args[0] == "right"):
+				return "swipe", map[string]string{"direction": args[0]}, nil
+			}
+			return "", nil, errors.New("expected: swipe [left|right]")
+		},
+	},
+	"ptt": {
+		usage: "ptt on|off|toggle",
+		words: []string{"on", "off", "toggle"},
+		parse: func(args []string) (string, any, error) {
+			if len(args) == 1 {
+				switch args[0] {
+				case "on", "off", "toggle":
+					return "ptt_" + args[0], nil, nil
+				}
+			}
+			return "", nil, errors.New("expected: ptt on|off|toggle")
+		},
+	},
+	"tap": {
+		usage: "tap X Y          (digitizer units, 0-32767)",
+		parse: func(args []string) (string, any, error) {
+			if len(args) != 2 {
+				return "", nil, errors.New("expected: tap X Y")
+			}
+			x, errX := strconv.ParseUint(args[0], 10, 16)
+			y, errY := strconv.ParseUint(args[1], 10, 16)
+			if errX != nil || errY != nil || x > 32767 || y > 32767 {
+				return "", nil, errors.New("tap X and Y must be numbers in range 0-32767")
+			}
+			return "tap", map[string]uint64{"x": x, "y": y}, nil
+		},
+	},
+	"type": {
+		usage: "type [--no-submit] TEXT",
+		words: []string{"--no-submit"},
+		parse: func(args []string) (string, any, error) {
+			submit := true
+			if len(args) > 0 && args[0] == "--no-submit" {
+				submit, args = false, args[1:]
+			}
+			if len(args) == 0 {
+				return "", nil, errors.New("expected: type [--no-submit] TEXT")
+			}
+			return "type_text", map[string]any{"text": strings.Join(args, " "), "submit": submit}, nil
+		},
+	},
+	"wake": {
+		usage: "wake",
+		parse: func(args []string) (string, any, error) {
+			if len(args) != 0 {
+				return "", nil, errors.New("expected: wake")
+			}
+			return "wake", nil, nil
+		},
+	},
+	"open": {
+		usage: "open APP         (a launcher app from the config, e.g. Music)",
+		parse: func(args []string) (string, any, error) {
+			if len(args) == 0 {
+				return "", nil, errors.New("expected: open APP")
+			}
+			return "open_app", map[string]string{"name": strings.Join(args, " ")}, nil
+		},
+	},
+}
+
+// tool is a command that reports information rather than acting on the
+// device.
+type tool struct {
+	usage string
+	words []string
+	run   func(out output, args []string) int
+}
+
+// tools is filled in init, since completion refers back to it.
+var tools map[string]tool
+
+func init() {
+	tools = map[string]tool{
+		"settings":   {usage: "settings", run: runSettings},
+		"status":     {usage: "status [--json]", run: runStatus},
+		"devices":    {usage: "devices [--json]", run: runDevices},
+		"replay":     {usage: replayUsage, words: []string{"--speed", "--from", "--to"}, run: runReplay},
+		"keytest":    {usage: keytestUsage, words: []string{"--only", "--firmware", "--out", "--submit"}, run: runKeytest},
+		"hidcheck":   {usage: hidcheckUsage, run: runHidcheck},
+		"soak":       {usage: soakUsage, words: []string{"--hours", "--out"}, run: runSoak},
+		"agent":      {usage: agentUsage, words: []string{"--listen", "--token", "--cert", "--key"}, run: runAgent},
+		"completion": {usage: "completion bash|zsh|fish|powershell", words: shells, run: runCompletion},
+	}
+}
+
+// order lists the commands for usage output and completion.
+var order = []string{"swipe", "ptt", "tap", "type", "wake", "open", "settings", "status", "devices", "replay", "keytest", "hidcheck", "soak", "agent", "completion"}
+
+// IsCommand reports whether arg names a one-shot command, as opposed to
+// a flag for starting the tray app.
+func IsCommand(arg string) bool {
+	_, isCmd := commands[arg]
+	_, isTool := tools[arg]
+	return isCmd || isTool || arg == "help"
+}
+
+// output prints results as text or, with --json, as a single JSON value
+// on stdout (errors included) so scripts can parse every outcome.
+type output struct {
+	prog string
+	json bool
+}
+
+// print writes v as indented JSON.
+func (o output) print(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// fail reports err and returns code.
+func (o output) fail(code int, err error) int {
+	if o.json {
+		o.print(map[string]string{"error": err.Error()})
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", o.prog, err)
+	}
+	return code
+}
+
+// Run executes the command in args and returns the process exit code.
+func Run(args []string) int {
+	out := output{prog: filepath.Base(os.Args[0])}
+	if len(args) == 0 || args[0] == "help" {
+		usage(os.Stdout, out.prog)
+		return 0
+	}
+	rest, jsonOut := takeJSONFlag(args[1:])
+	out.json = jsonOut
+
+	if t, ok := tools[args[0]]; ok {
+		return t.run(out, rest)
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		usage(os.Stderr, out.prog)
+		return 2
+	}
+
+	name, p, err := cmd.parse(rest)
+	if err != nil {
+		return out.fail(2, err)
+	}
+	var payload json.RawMessage
+	if p != nil {
+		if payload, err = json.Marshal(p); err != nil {
+			return out.fail(1, err)
+		}
+	}
+	if err := action.Validate(name, payload); err != nil {
+		return out.fail(2, err)
+	}
+
+	state, err := run(name, payload)
+	if err != nil {
+		return out.fail(1, err)
+	}
+	if out.json {
+		out.print(actionResponse{State: state})
+	} else {
+		fmt.Println(state)
+	}
+	return 0
+}
+
+// takeJSONFlag removes a --json flag given as the first or last argument.
+// Anywhere else it is left alone, so "type" can send the literal text.
+func takeJSONFlag(args []string) ([]string, bool) {
+	switch {
+	case len(args) > 0 && args[0] == "--json":
+		return args[1:], true
+	case len(args) > 0 && args[len(args)-1] == "--json":
+		return args[:len(args)-1], true
+	}
+	return args, false
+}
+
+// usage prints the command summary.
+func usage(w io.Writer, prog string) {
+	fmt.Fprintf(w, "Usage: %s <command> [args] [--json]\n\nCommands:\n", prog)
+	for _, name := range order {
+		u := tools[name].usage
+		if c, ok := commands[name]; ok {
+			u = c.usage
+		}
+		fmt.Fprintf(w, "  %s %s\n", prog, u)
+	}
+	fmt.Fprintln(w, "\nActions go to the running app, or open the R1 directly if it isn't running.")
+	fmt.Fprintln(w, "--json prints the result (or error) as JSON on stdout.")
+}
+
+// run sends the action to the running app, or performs it directly.
+func run(name string, payload json.RawMessage) (string, error) {
+	url, err := instance.Lookup()
+	if err == nil {
+		return runRemote(url, name, payload)
+	}
+	if !errors.Is(err, instance.ErrNotRunning) {
+		return "", err
+	}
+	return runLocal(name, payload)
+}
+
+// actionResponse mirrors the server's response for device actions.
+type actionResponse struct {
+	State string `json:"state,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// runRemote posts the action to the running app's HTTP API.
+func runRemote(url, name string, payload json.RawMessage) (string, error) {
+	body, err := json.Marshal(struct {
+		Action  string          `json:"action"`
+		Payload json.RawMessage `json:"payload,omitempty"`
+	}{name, payload})
+	if err != nil {
+		return "", err
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+	resp, err := client.Post(url+"/api/v1/action", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("send to running app: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("running app: %s", strings.TrimSpace(string(msg)))
+	}
+	var ar actionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ar); err != nil {
+		return "", fmt.Errorf("running app: invalid response: %w", err)
+	}
+	if ar.Error != "" {
+		return "", errors.New(ar.Error)
+	}
+	return ar.State, nil
+}
+
+// runLocal opens the R1 for this one action. A latched PTT can't outlive
+// the command, so latching is left to the running app.
+func runLocal(name string, payload json.RawMessage) (string, error) {
+	if name == "ptt_on" || name == "ptt_toggle" {
+		return "", errors.New("latching PTT needs the running app (start R1 Control first)")
+	}
+	if name == "open_app" {
+		return "", errors.New("opening launcher apps needs the running app (start R1 Control first)")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return "", fmt.Errorf("config: %w", err)
+	}
+	devCfg := cfg.GetDevice()
+	mgr := device.NewManager(aoa.Filter{Serial: devCfg.Serial, PortPath: devCfg.PortPath}, nil)
+	defer mgr.Close()
+	if devCfg.Bridge.Address != "" {
+		open, name, err := bridge.NewOpener(devCfg.Bridge)
+		if err != nil {
+			return "", err
+		}
+		mgr.SetOpener(name, open)
+	}
+
+	if err := mgr.ConnectOnce(); err != nil {
+		return "", err
+	}
+	if err := action.Run(mgr, name, payload); err != nil {
+		return "", err
+	}
+	return mgr.State().String(), nil
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// shells lists the shells "completion" can generate a script for.
+var shells = []string{"bash", "zsh", "fish", "powershell"}
+
+// runCompletion prints a completion script for the given shell, e.g.
+//
+//	source <(r1ptt completion bash)
+//	r1ptt completion fish > ~/.config/fish/completions/r1ptt.fish
+//	r1ptt completion powershell | Out-String | Invoke-Expression
+func runCompletion(out output, args []string) int {
+	if len(args) != 1 {
+		return out.fail(2, errors.New("expected: completion bash|zsh|fish|powershell"))
+	}
+	prog := strings.TrimSuffix(out.prog, ".exe")
+
+	var script string
+	switch args[0] {
+	case "bash":
+		script = bashCompletion(prog)
+	case "zsh":
+		script = zshCompletion(prog)
+	case "fish":
+		script = fishCompletion(prog)
+	case "powershell":
+		script = powershellCompletion(prog)
+	default:
+		return out.fail(2, fmt.Errorf("unsupported shell %q (want bash, zsh, fish or powershell)", args[0]))
+	}
+	fmt.Fprint(os.Stdout, script)
+	return 0
+}
+
+// argWords returns the words completed after name.
+func argWords(name string) []string {
+	words := tools[name].words
+	if c, ok := commands[name]; ok {
+		words = c.words
+	}
+	if name == "completion" {
+		return words
+	}
+	return append(append([]string(nil), words...), "--json")
+}
+
+// funcName turns prog into a shell function name.
+func funcName(prog string) string {
+	return "_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, prog)
+}
+
+func bashCompletion(prog string) string {
+	fn := funcName(prog)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# bash completion for %s\n", prog)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("    local cur=${COMP_WORDS[COMP_CWORD]}\n")
+	b.WriteString("    if [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W \"%s help\" -- \"$cur\"))\n", strings.Join(order, " "))
+	b.WriteString("        return\n    fi\n")
+	b.WriteString("    case ${COMP_WORDS[1]} in\n")
+	for _, name := range order {
+		fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", name, strings.Join(argWords(name), " "))
+	}
+	b.WriteString("    esac\n}\n")
+	fmt.Fprintf(&b, "complete -F %s %s\n", fn, prog)
+	return b.String()
+}
+
+func zshCompletion(prog string) string {
+	fn := funcName(prog)
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n\n", prog)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("    if (( CURRENT == 2 )); then\n")
+	fmt.Fprintf(&b, "        compadd -- %s help\n", strings.Join(order, " "))
+	b.WriteString("        return\n    fi\n")
+	b.WriteString("    case $words[2] in\n")
+	for _, name := range order {
+		fmt.Fprintf(&b, "        %s) compadd -- %s ;;\n", name, strings.Join(argWords(name), " "))
+	}
+	b.WriteString("    esac\n}\n\n")
+	fmt.Fprintf(&b, "compdef %s %s\n", fn, prog)
+	return b.String()
+}
+
+func fishCompletion(prog string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# fish completion for %s\n", prog)
+	fmt.Fprintf(&b, "complete -c %s -f\n", prog)
+	fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand -a '%s help'\n", prog, strings.Join(order, " "))
+	for _, name := range order {
+		fmt.Fprintf(&b, "complete -c %s -n '__fish_seen_subcommand_from %s' -a '%s'\n",
+			prog, name, strings.Join(argWords(name), " "))
+	}
+	return b.String()
+}
+
+// psList formats words as a PowerShell array literal.
+func psList(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = "'" + w + "'"
+	}
+	return "@(" + strings.Join(quoted, ", ") + ")"
+}
+
+func powershellCompletion(prog string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# PowerShell completion for %s\n", prog)
+	fmt.Fprintf(&b, "Register-ArgumentCompleter -Native -CommandName '%s', '%s.exe' -ScriptBlock {\n", prog, prog)
+	b.WriteString("    param($wordToComplete, $commandAst, $cursorPosition)\n")
+	b.WriteString("    $words = @($commandAst.CommandElements | ForEach-Object { $_.ToString() })\n")
+	b.WriteString("    $n = $words.Count\n")
+	b.WriteString("    if ($wordToComplete) { $n-- }\n")
+	b.WriteString("    if ($n -le 1) {\n")
+	fmt.Fprintf(&b, "        $candidates = %s\n", psList(append(append([]string(nil), order...), "help")))
+	b.WriteString("    } else {\n")
+	b.WriteString("        $candidates = switch ($words[1]) {\n")
+	for _, name := range order {
+		fmt.Fprintf(&b, "            '%s' { %s }\n", name, psList(argWords(name)))
+	}
+	b.WriteString("            default { @() }\n        }\n    }\n")
+	b.WriteString("    $candidates | Where-Object { $_ -like \"$wordToComplete*\" } | ForEach-Object {\n")
+	b.WriteString("        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)\n")
+	b.WriteString("    }\n}\n")
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+)
+
+const hidcheckUsage = "hidcheck FILE    (a HID report descriptor, in hex or binary)"
+
+// hidcheckResult is the --json output of "hidcheck".
+type hidcheckResult struct {
+	Valid      bool         `json:"valid"`
+	Bytes      int          `json:"bytes"`
+	InputSizes map[byte]int `json:"input_sizes,omitempty"` // by report ID; 0 = no report IDs
+	Error      string       `json:"error,omitempty"`
+}
+
+// runHidcheck checks a custom HID report descriptor with the same parser
+// the app uses, without touching the R1, and lists its input reports.
+// Exits 1 if the descriptor is malformed.
+func runHidcheck(out output, args []string) int {
+	if len(args) != 1 {
+		return out.fail(2, errors.New("expected: hidcheck FILE"))
+	}
+	raw, err := os.ReadFile(args[0])
+	if err != nil {
+		return out.fail(1, err)
+	}
+	desc := decodeDescriptor(raw)
+
+	h, err := aoa.ParseDescriptor(desc)
+	if out.json {
+		res := hidcheckResult{Valid: err == nil, Bytes: len(desc)}
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.InputSizes = h.InputSizes
+		}
+		out.print(res)
+		if err != nil {
+			return 1
+		}
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", out.prog, err)
+		return 1
+	}
+	fmt.Printf("Valid HID descriptor, %d bytes\n", len(desc))
+	for _, id := range slices.Sorted(maps.Keys(h.InputSizes)) {
+		unit := "bytes"
+		if h.InputSizes[id] == 1 {
+			unit = "byte"
+		}
+		if id == 0 {
+			fmt.Printf("Input report: %d %s\n", h.InputSizes[id], unit)
+		} else {
+			fmt.Printf("Input report ID %d: %d %s after the ID\n", id, h.InputSizes[id], unit)
+		}
+	}
+	return 0
+}
+
+// decodeDescriptor reads a descriptor written as hex, with any spaces,
+// commas, newlines and 0x prefixes, e.g. copied from C source or a
+// descriptor dump; anything else is taken as raw bytes.
+func decodeDescriptor(raw []byte) []byte {
+	s := strings.NewReplacer("0x", "", "0X", "", ",", "").Replace(string(raw))
+	s = strings.Join(strings.Fields(s), "")
+	if b, err := hex.DecodeString(s); err == nil && s != "" {
+		return b
+	}
+	return raw
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/browser"
+	"github.com/HopIT-Hub/R1-Control/internal/instance"
+)
+
+// statusResult is the --json output of "status". Status is the running
+// app's /status payload, passed through unchanged.
+type statusResult struct {
+	Running bool            `json:"running"`
+	Status  json.RawMessage `json:"status,omitempty"`
+}
+
+// appStatus holds the /status fields shown in text output.
+type appStatus struct {
+	State       string   `json:"state"`
+	Version     string   `json:"version"`
+	LinkWarning string   `json:"link_warning"`
+	Problems    []string `json:"problems"`
+	LastSeen    *struct {
+		Serial string `json:"serial"`
+		Time   string `json:"time"`
+	} `json:"last_seen"`
+}
+
+// runStatus reports the running app's device state. Exits 3 if the app
+// isn't running.
+func runStatus(out output, args []string) int {
+	if len(args) != 0 {
+		return out.fail(2, errors.New("expected: status [--json]"))
+	}
+
+	url, err := instance.Lookup()
+	if errors.Is(err, instance.ErrNotRunning) {
+		if out.json {
+			out.print(statusResult{Running: false})
+		} else {
+			fmt.Println("R1 Control is not running")
+		}
+		return 3
+	}
+	if err != nil {
+		return out.fail(1, err)
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+	resp, err := client.Get(url + "/api/v1/status")
+	if err != nil {
+		return out.fail(1, fmt.Errorf("query running app: %w", err))
+	}
+	defer resp.Body.Close()
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return out.fail(1, fmt.Errorf("running app: invalid response: %w", err))
+	}
+
+	if out.json {
+		out.print(statusResult{Running: true, Status: raw})
+		return 0
+	}
+	var st appStatus
+	if err := json.Unmarshal(raw, &st); err != nil {
+		return out.fail(1, fmt.Errorf("running app: invalid response: %w", err))
+	}
+	fmt.Printf("State:   %s\n", st.State)
+	fmt.Printf("Version: %s\n", st.Version)
+	if st.LastSeen != nil {
+		fmt.Printf("Last R1: %s at %s\n", st.LastSeen.Serial, st.LastSeen.Time)
+	}
+	if st.LinkWarning != "" {
+		fmt.Printf("Warning: %s\n", st.LinkWarning)
+	}
+	for _, p := range st.Problems {
+		fmt.Printf("Problem: %s\n", p)
+	}
+	return 0
+}
+
+// deviceResult is one entry in the --json output of "devices".
+type deviceResult struct {
+	Bus      int    `json:"bus"`
+	Address  int    `json:"address"`
+	PortPath string `json:"port_path"` // usable as device.port_path in the config
+}
+
+// runSettings opens the running app's settings page in the browser.
+// Exits 3 if the app isn't running.
+func runSettings(out output, args []string) int {
+	if len(args) != 0 {
+		return out.fail(2, errors.New("expected: settings"))
+	}
+	url, err := instance.Lookup()
+	if errors.Is(err, instance.ErrNotRunning) {
+		return out.fail(3, err)
+	}
+	if err != nil {
+		return out.fail(1, err)
+	}
+	if err := browser.Open(url); err != nil {
+		return out.fail(1, fmt.Errorf("open browser: %w", err))
+	}
+	return 0
+}
+
+// runDevices lists the R1s attached to this computer. It only inspects
+// the bus, so it works while the app holds the device.
+func runDevices(out output, args []string) int {
+	if len(args) != 0 {
+		return out.fail(2, errors.New("expected: devices [--json]"))
+	}
+
+	locs, err := aoa.Locate()
+	if err != nil {
+		return out.fail(1, fmt.Errorf("enumerate USB devices: %w", err))
+	}
+	if out.json {
+		devs := make([]deviceResult, 0, len(locs))
+		for _, l := range locs {
+			devs = append(devs, deviceResult{Bus: l.Bus, Address: l.Address, PortPath: l.PortPath()})
+		}
+		out.print(devs)
+		return 0
+	}
+	if len(locs) == 0 {
+		fmt.Println("No R1 found")
+		return 0
+	}
+	for _, l := range locs {
+		fmt.Println(l)
+	}
+	return 0
+}
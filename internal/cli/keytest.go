@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/instance"
+	"github.com/HopIT-Hub/R1-Control/internal/keytest"
+)
+
+const keytestUsage = "keytest [--only keyboard|consumer|system|camera] [--firmware VERSION] [--out FILE] [--submit URL]"
+
+// Version is the app version recorded in exported results.
+var Version = "dev"
+
+// runKeytest is the HID explorer: it sends each usage from the aoa
+// KeyTest tables to the R1, asks what happened, and writes the answers
+// as keytest results, optionally submitting them to url.
+func runKeytest(out output, args []string) int {
+	var only, firmware, submit string
+	outFile := "keytest-results.json"
+	for len(args) > 0 {
+		if len(args) < 2 {
+			return out.fail(2, errors.New("expected: "+keytestUsage))
+		}
+		switch args[0] {
+		case "--only":
+			only = args[1]
+		case "--firmware":
+			firmware = args[1]
+		case "--out":
+			outFile = args[1]
+		case "--submit":
+			submit = args[1]
+		default:
+			return out.fail(2, errors.New("expected: "+keytestUsage))
+		}
+		args = args[2:]
+	}
+	if out.json {
+		return out.fail(2, errors.New("keytest is interactive; its results file is already JSON"))
+	}
+
+	descs := keytest.Descriptors
+	if only != "" {
+		descs = nil
+		for _, d := range keytest.Descriptors {
+			if d.Name == only {
+				descs = append(descs, d)
+			}
+		}
+		if descs == nil {
+			return out.fail(2, fmt.Errorf("unknown descriptor %q", only))
+		}
+	}
+
+	if _, err := instance.Lookup(); err == nil {
+		return out.fail(1, errors.New("R1 Control is running — quit it before testing keys"))
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return out.fail(1, fmt.Errorf("config: %w", err))
+	}
+	devCfg := cfg.GetDevice()
+	dev, err := aoa.OpenFilter(aoa.Filter{Serial: devCfg.Serial, PortPath: devCfg.PortPath})
+	if err != nil {
+		return out.fail(1, err)
+	}
+	defer dev.Close()
+
+	in := bufio.NewScanner(os.Stdin)
+	ask := func(prompt string) (string, bool) {
+		fmt.Print(prompt)
+		if !in.Scan() {
+			return "", false
+		}
+		return strings.TrimSpace(in.Text()), true
+	}
+
+	if firmware == "" {
+		firmware, _ = ask("R1 firmware version (Settings → About): ")
+	}
+	res := keytest.Results{
+		Schema:     keytest.Schema,
+		AppVersion: Version,
+		Date:       time.Now().UTC().Truncate(time.Second),
+		Product:    dev.Product(),
+		Firmware:   firmware,
+	}
+
+	fmt.Println("\nEach key is pressed once. Watch the R1, then answer:")
+	fmt.Print("  Enter = nothing happened, or describe what happened; s = skip, q = stop\n\n")
+	quit := false
+	for _, d := range descs {
+		if quit {
+			break
+		}
+		fmt.Printf("== %s ==\n", d.Type)
+		id, err := dev.RegisterDescriptor(d.Type)
+		if err != nil {
+			fmt.Printf("   register failed: %v\n", err)
+			for _, t := range aoa.GetKeyTests(d.Type) {
+				r := keytest.NewResult(d.Name, t)
+				r.Outcome, r.Observed = keytest.OutcomeError, "register: "+err.Error()
+				res.Results = append(res.Results, r)
+			}
+			continue
+		}
+		for _, t := range aoa.GetKeyTests(d.Type) {
+			if quit {
+				break
+			}
+			r := keytest.NewResult(d.Name, t)
+			fmt.Printf("-- %s (%s)\n   %s\n", t.Name, t.AndroidKey, t.Description)
+			if a, ok := ask("   press? [Enter/s/q] "); !ok || a == "q" {
+				quit = true
+				break
+			} else if a == "s" {
+				r.Outcome = keytest.OutcomeSkipped
+				res.Results = append(res.Results, r)
+				continue
+			}
+
+			if err := dev.TapTo(id, t.ReportDown, t.ReportUp); err != nil {
+				fmt.Printf("   send failed: %v\n", err)
+				r.Outcome, r.Observed = keytest.OutcomeError, err.Error()
+				res.Results = append(res.Results, r)
+				continue
+			}
+			a, ok := ask("   what happened? ")
+			switch {
+			case !ok || a == "q":
+				quit = true
+				continue
+			case a == "":
+				r.Outcome = keytest.OutcomeNoEffect
+			case a == "s":
+				r.Outcome = keytest.OutcomeSkipped
+			default:
+				r.Outcome, r.Observed = keytest.OutcomeEffect, a
+			}
+			res.Results = append(res.Results, r)
+		}
+	}
+
+	f, err := os.Create(outFile)
+	if err != nil {
+		return out.fail(1, err)
+	}
+	err = res.Write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return out.fail(1, err)
+	}
+	fmt.Printf("\n%d results written to %s\n", len(res.Results), outFile)
+
+	if submit == "" {
+		return 0
+	}
+	if err := res.Validate(); err != nil {
+		return out.fail(1, fmt.Errorf("not submitting: %w", err))
+	}
+	if a, _ := ask(fmt.Sprintf("Submit these results to %s? [y/N] ", submit)); !strings.EqualFold(a, "y") {
+		return 0
+	}
+	if err := keytest.Submit(context.Background(), submit, &res); err != nil {
+		return out.fail(1, err)
+	}
+	fmt.Println("Submitted — thank you!")
+	return 0
+}
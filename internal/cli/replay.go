@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/instance"
+)
+
+const replayUsage = "replay [--speed N] [--from N] [--to N] TRACE"
+
+// replayResult is the --json output of "replay".
+type replayResult struct {
+	Sent  int    `json:"sent"`
+	Error string `json:"error,omitempty"`
+}
+
+// runReplay resends a USB trace captured with device.trace to the R1.
+// --from and --to select entries by number (1-based, inclusive) for
+// bisecting; --speed scales the original timing, 0 sends back to back.
+func runReplay(out output, args []string) int {
+	speed := 1.0
+	from, to := 1, 0
+	var path string
+	for len(args) > 0 {
+		arg := args[0]
+		args = args[1:]
+		switch arg {
+		case "--speed", "--from", "--to":
+			if len(args) == 0 {
+				return out.fail(2, fmt.Errorf("%s needs a value", arg))
+			}
+			var err error
+			if arg == "--speed" {
+				speed, err = strconv.ParseFloat(args[0], 64)
+			} else {
+				var n int
+				n, err = strconv.Atoi(args[0])
+				if err == nil && n < 1 {
+					err = errors.New("must be at least 1")
+				}
+				if arg == "--from" {
+					from = n
+				} else {
+					to = n
+				}
+			}
+			if err != nil {
+				return out.fail(2, fmt.Errorf("%s: %v", arg, err))
+			}
+			args = args[1:]
+		default:
+			if path != "" || strings.HasPrefix(arg, "--") {
+				return out.fail(2, errors.New("expected: "+replayUsage))
+			}
+			path = arg
+		}
+	}
+	if path == "" {
+		return out.fail(2, errors.New("expected: "+replayUsage))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return out.fail(1, err)
+	}
+	entries, err := aoa.ParseTrace(f)
+	f.Close()
+	if err != nil {
+		return out.fail(1, err)
+	}
+	if to == 0 || to > len(entries) {
+		to = len(entries)
+	}
+	if from > to {
+		return out.fail(2, fmt.Errorf("nothing to replay: trace has %d entries", len(entries)))
+	}
+	entries = entries[from-1 : to]
+
+	// The app re-registers its descriptors on reconnect, which would
+	// interleave with the replay.
+	if _, err := instance.Lookup(); err == nil {
+		return out.fail(1, errors.New("R1 Control is running — quit it before replaying"))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return out.fail(1, fmt.Errorf("config: %w", err))
+	}
+	devCfg := cfg.GetDevice()
+	dev, err := aoa.OpenFilter(aoa.Filter{Serial: devCfg.Serial, PortPath: devCfg.PortPath})
+	if err != nil {
+		return out.fail(1, err)
+	}
+	defer dev.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var sent int
+	n := from
+	err = dev.Replay(ctx, entries, speed, func(e aoa.TraceEntry, err error) {
+		if err == nil {
+			sent++
+		}
+		if !out.json {
+			result := "ok"
+			if err != nil {
+				result = err.Error()
+			}
+			fmt.Printf("#%d %s: %s\n", n, e, result)
+		}
+		n++
+	})
+	if out.json {
+		res := replayResult{Sent: sent}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		out.print(res)
+		if err != nil {
+			return 1
+		}
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", out.prog, err)
+		return 1
+	}
+	fmt.Printf("Replayed %d transfers\n", sent)
+	return 0
+}
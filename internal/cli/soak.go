@@ -0,0 +1,283 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/bridge"
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/device"
+	"github.com/HopIT-Hub/R1-Control/internal/events"
+	"github.com/HopIT-Hub/R1-Control/internal/instance"
+)
+
+const soakUsage = "soak [--hours N] [--out FILE]"
+
+// Soak test pacing: one PTT press and one swipe per cycle, and a forced
+// reconnect every soakReconnectEvery cycles (every 5 minutes).
+const (
+	soakCycle          = 10 * time.Second
+	soakPTTHold        = time.Second
+	soakReconnectEvery = 30
+	soakConnectTimeout = 30 * time.Second
+	soakProgressEvery  = time.Hour
+)
+
+// soakOp counts the runs and failures of one exercised operation.
+type soakOp struct {
+	Runs      int    `json:"runs"`
+	Errors    int    `json:"errors"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// record counts one run and its result.
+func (o *soakOp) record(err error) {
+	o.Runs++
+	if err != nil {
+		o.Errors++
+		o.LastError = err.Error()
+	}
+}
+
+// errorRate is the share of runs that failed, 0 if there were none.
+func (o *soakOp) errorRate() float64 {
+	if o.Runs == 0 {
+		return 0
+	}
+	return float64(o.Errors) / float64(o.Runs)
+}
+
+// soakMemory tracks one memory figure over the run.
+type soakMemory struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+	Peak  uint64 `json:"peak"`
+}
+
+func (m *soakMemory) sample(v uint64) {
+	if m.Start == 0 {
+		m.Start = v
+	}
+	m.End = v
+	m.Peak = max(m.Peak, v)
+}
+
+// soakReport is the result of "soak", printed at the end and, with
+// --out or --json, written as JSON.
+type soakReport struct {
+	AppVersion  string     `json:"app_version"`
+	Product     string     `json:"product,omitempty"`
+	Started     time.Time  `json:"started"`
+	Seconds     int        `json:"seconds"`
+	Interrupted bool       `json:"interrupted,omitempty"`
+	PTT         soakOp     `json:"ptt"`
+	Swipe       soakOp     `json:"swipe"`
+	Reconnect   soakOp     `json:"reconnect"`
+	HeapBytes   soakMemory `json:"heap_bytes"`
+	Goroutines  soakMemory `json:"goroutines"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// sampleMemory records the current heap size and goroutine count.
+func (r *soakReport) sampleMemory() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	r.HeapBytes.sample(ms.HeapAlloc)
+	r.Goroutines.sample(uint64(runtime.NumGoroutine()))
+}
+
+// runSoak exercises the R1 for hours — PTT presses, swipes and forced
+// reconnects through the same device manager the app uses — and reports
+// error rates and memory use, to validate changes to the USB pipeline
+// before a release. Ctrl+C ends it early with a report so far.
+func runSoak(out output, args []string) int {
+	hours := 8.0
+	var outFile string
+	for len(args) > 0 {
+		if len(args) < 2 {
+			return out.fail(2, errors.New("expected: "+soakUsage))
+		}
+		switch args[0] {
+		case "--hours":
+			h, err := strconv.ParseFloat(args[1], 64)
+			if err != nil || h <= 0 {
+				return out.fail(2, fmt.Errorf("--hours: want a positive number, got %q", args[1]))
+			}
+			hours = h
+		case "--out":
+			outFile = args[1]
+		default:
+			return out.fail(2, errors.New("expected: "+soakUsage))
+		}
+		args = args[2:]
+	}
+
+	if _, err := instance.Lookup(); err == nil {
+		return out.fail(1, errors.New("R1 Control is running — quit it before a soak test"))
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return out.fail(1, fmt.Errorf("config: %w", err))
+	}
+	devCfg := cfg.GetDevice()
+	bus := events.NewBus()
+	mgr := device.NewManager(aoa.Filter{Serial: devCfg.Serial, PortPath: devCfg.PortPath}, bus)
+	defer mgr.Close()
+	if devCfg.Bridge.Address != "" {
+		open, name, err := bridge.NewOpener(devCfg.Bridge)
+		if err != nil {
+			return out.fail(1, err)
+		}
+		mgr.SetOpener(name, open)
+	}
+
+	connected := make(chan struct{}, 1)
+	bus.Subscribe(func(events.Event) {
+		select {
+		case connected <- struct{}{}:
+		default:
+		}
+	}, events.TypeConnect)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go mgr.Run(ctx)
+
+	rep := soakReport{AppVersion: Version, Started: time.Now().UTC().Truncate(time.Second)}
+	if err := soakWaitConnect(ctx, connected); err != nil {
+		return out.fail(1, err)
+	}
+	rep.Product = mgr.Info().Product
+	rep.sampleMemory()
+
+	end := time.Now().Add(time.Duration(hours * float64(time.Hour)))
+	if !out.json {
+		fmt.Printf("Soak test until %s — Ctrl+C to stop early\n", end.Format("15:04"))
+	}
+	progress := time.Now().Add(soakProgressEvery)
+	ticker := time.NewTicker(soakCycle)
+	defer ticker.Stop()
+	for cycle := 1; time.Now().Before(end); cycle++ {
+		err := mgr.SetPTT(true)
+		if err == nil {
+			sleepCtx(ctx, soakPTTHold)
+			err = mgr.SetPTT(false)
+		}
+		rep.PTT.record(err)
+		soakLog(out, "ptt", err)
+
+		err = mgr.Swipe()
+		rep.Swipe.record(err)
+		soakLog(out, "swipe", err)
+
+		if cycle%soakReconnectEvery == 0 {
+			// Drain a connect left over from a reconnect the manager
+			// did on its own, so this one is really waited for.
+			select {
+			case <-connected:
+			default:
+			}
+			mgr.Reconnect()
+			err = soakWaitConnect(ctx, connected)
+			rep.Reconnect.record(err)
+			soakLog(out, "reconnect", err)
+		}
+
+		rep.sampleMemory()
+		if !out.json && time.Now().After(progress) {
+			progress = progress.Add(soakProgressEvery)
+			fmt.Printf("%s: %s\n", time.Since(rep.Started).Round(time.Minute), rep.summary())
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+		if ctx.Err() != nil {
+			rep.Interrupted = true
+			break
+		}
+	}
+	rep.Seconds = int(time.Since(rep.Started).Seconds())
+
+	code := 0
+	if outFile != "" {
+		if err := writeSoakReport(outFile, &rep); err != nil {
+			rep.Error = err.Error()
+			code = 1
+		}
+	}
+	if out.json {
+		out.print(rep)
+		return code
+	}
+	fmt.Printf("\nRan %s: %s\n", time.Duration(rep.Seconds)*time.Second, rep.summary())
+	fmt.Printf("Heap: %s at start, %s at end, %s peak\n", soakBytes(rep.HeapBytes.Start), soakBytes(rep.HeapBytes.End), soakBytes(rep.HeapBytes.Peak))
+	fmt.Printf("Goroutines: %d at start, %d at end, %d peak\n", rep.Goroutines.Start, rep.Goroutines.End, rep.Goroutines.Peak)
+	if rep.Error != "" {
+		fmt.Fprintf(os.Stderr, "%s: write report: %s\n", out.prog, rep.Error)
+	} else if outFile != "" {
+		fmt.Printf("Report written to %s\n", outFile)
+	}
+	return code
+}
+
+// summary lists the error rates of the exercised operations.
+func (r *soakReport) summary() string {
+	return fmt.Sprintf("ptt %d/%d failed (%.1f%%), swipe %d/%d (%.1f%%), reconnect %d/%d (%.1f%%), heap %s, %d goroutines",
+		r.PTT.Errors, r.PTT.Runs, 100*r.PTT.errorRate(),
+		r.Swipe.Errors, r.Swipe.Runs, 100*r.Swipe.errorRate(),
+		r.Reconnect.Errors, r.Reconnect.Runs, 100*r.Reconnect.errorRate(),
+		soakBytes(r.HeapBytes.End), r.Goroutines.End)
+}
+
+// soakWaitConnect waits for the R1 to connect.
+func soakWaitConnect(ctx context.Context, connected <-chan struct{}) error {
+	select {
+	case <-connected:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(soakConnectTimeout):
+		return fmt.Errorf("R1 not connected after %s", soakConnectTimeout)
+	}
+}
+
+// soakLog prints a failed operation as it happens.
+func soakLog(out output, op string, err error) {
+	if err != nil && !out.json {
+		fmt.Printf("%s %s: %v\n", time.Now().Format("15:04:05"), op, err)
+	}
+}
+
+// sleepCtx waits for d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+// soakBytes formats a byte count in MB.
+func soakBytes(n uint64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
+
+// writeSoakReport writes rep to path as indented JSON.
+func writeSoakReport(path string, rep *soakReport) error {
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backup file names: config-20060102-150405.000.json in BackupDir.
+const (
+	backupPrefix     = "config-"
+	backupSuffix     = ".json"
+	backupTimeFormat = "20060102-150405.000"
+)
+
+// Backup is a saved version of the config file.
+type Backup struct {
+	Name string    `json:"name"` // file name in BackupDir
+	Time time.Time `json:"time"` // when it was replaced
+	Size int64     `json:"size"`
+}
+
+// BackupDir returns the directory holding config backups.
+func BackupDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "backups"), nil
+}
+
+// backupBeforeSave copies the config file at p to BackupDir before it is
+// replaced by next, keeping the newest keep backups. Like the config,
+// backups hold tokens, so only the user may read them. Saves that only
+// change what the app records for itself (e.g. the last seen R1) don't
+// make a backup, so they can't push real settings changes out.
+func backupBeforeSave(p string, next []byte, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	cur, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if sameSettings(cur, next) {
+		return nil
+	}
+
+	dir, err := BackupDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create backup dir: %w", err)
+	}
+	// Older versions made the directory and backups readable by everyone
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return fmt.Errorf("restrict backup dir: %w", err)
+	}
+	name := backupPrefix + time.Now().Format(backupTimeFormat) + backupSuffix
+	if err := os.WriteFile(filepath.Join(dir, name), cur, 0o600); err != nil {
+		return fmt.Errorf("write backup: %w", err)
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		return err
+	}
+	for i, b := range backups {
+		if i < keep {
+			os.Chmod(filepath.Join(dir, b.Name), 0o600)
+		} else {
+			os.Remove(filepath.Join(dir, b.Name))
+		}
+	}
+	return nil
+}
+
+// appFields are written by the app rather than the user. Changes to them
+// alone don't make a backup, and Restore keeps their current values.
+var appFields = []string{"last_seen", "last_seen_version"}
+
+// sameSettings reports whether two config files differ only in appFields.
+// Files that don't parse are treated as different.
+func sameSettings(a, b []byte) bool {
+	var ma, mb map[string]json.RawMessage
+	if json.Unmarshal(a, &ma) != nil || json.Unmarshal(b, &mb) != nil {
+		return false
+	}
+	for _, f := range appFields {
+		delete(ma, f)
+		delete(mb, f)
+	}
+	if len(ma) != len(mb) {
+		return false
+	}
+	for k, va := range ma {
+		vb, ok := mb[k]
+		if !ok || !jsonEqual(va, vb) {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonEqual compares two JSON values, ignoring formatting.
+func jsonEqual(a, b json.RawMessage) bool {
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return false
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+// ListBackups returns the config backups, newest first.
+func ListBackups() ([]Backup, error) {
+	dir, err := BackupDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, e := range entries {
+		t, ok := backupTime(e.Name())
+		if !ok || e.IsDir() {
+			continue
+		}
+		b := Backup{Name: e.Name(), Time: t}
+		if info, err := e.Info(); err == nil {
+			b.Size = info.Size()
+		}
+		backups = append(backups, b)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.After(backups[j].Time) })
+	return backups, nil
+}
+
+// backupTime parses the time from a backup file name.
+func backupTime(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+		return time.Time{}, false
+	}
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix)
+	t, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+	return t, err == nil
+}
+
+// Restore replaces the settings with those from the named backup and
+// saves them, which backs up the settings being replaced. appFields and
+// the paired LAN clients are kept, so a restore can't bring back a
+// revoked client token. A backup Validate finds problems in is refused
+// with a *ValidationError. Most settings are only applied at startup, so
+// the app should restart afterwards.
+func (c *Config) Restore(name string) error {
+	if _, ok := backupTime(name); !ok || filepath.Base(name) != name {
+		return fmt.Errorf("no backup named %q", name)
+	}
+	dir, err := BackupDir()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return fmt.Errorf("read backup: %w", err)
+	}
+	// A backup from an older or newer version, or edited by hand, may
+	// have settings this version can't use
+	if problems := Validate(data); len(problems) > 0 {
+		return &ValidationError{Path: name, Problems: problems}
+	}
+	restored := DefaultConfig()
+	if err := json.Unmarshal(data, restored); err != nil {
+		return fmt.Errorf("parse backup: %w", err)
+	}
+
+	c.mu.Lock()
+	restored.LastSeen = c.LastSeen
+	restored.LastSeenVersion = c.LastSeenVersion
+	restored.LAN.Clients = c.LAN.Clients
+	// Copy field by field; Config holds a mutex and can't be assigned
+	dst, src := reflect.ValueOf(c).Elem(), reflect.ValueOf(restored).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		if dst.Type().Field(i).IsExported() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	c.mu.Unlock()
+
+	return c.Save()
+}
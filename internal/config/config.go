@@ -2,27 +2,558 @@
 package config
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"log"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/safefile"
+	"github.com/HopIT-Hub/R1-Control/internal/schedule"
 )
 
 // Config holds the application configuration.
 type Config struct {
-	mu                sync.RWMutex `json:"-"`
-	Hotkey            HotkeyConfig `json:"hotkey"`
-	SwipeHotkey       HotkeyConfig `json:"swipe_hotkey"`
-	AutoStart         bool         `json:"auto_start"`
-	KeepAwake         bool         `json:"keep_awake"`
-	SleepAfterMinutes int          `json:"sleep_after_minutes"`
+	mu                    sync.RWMutex      `json:"-"`
+	Hotkey                HotkeyConfig      `json:"hotkey"`
+	SwipeHotkey           HotkeyConfig      `json:"swipe_hotkey"`
+	PromptHotkey          HotkeyConfig      `json:"prompt_hotkey"`  // opens the "ask rabbit" prompt page
+	ConfirmHotkey         HotkeyConfig      `json:"confirm_hotkey"` // presses Enter on the R1
+	AutoStart             bool              `json:"auto_start"`
+	AutoStartLaunch       LaunchConfig      `json:"auto_start_launch"`
+	DisableHotkeys        bool              `json:"disable_hotkeys"` // no global hotkeys at all, e.g. on a machine without a desktop
+	KeepAwake             bool              `json:"keep_awake"`
+	SleepAfterMinutes     int               `json:"sleep_after_minutes"`
+	KeepAwakeSchedule     []KeepAwakePeriod `json:"keep_awake_schedule"`      // when keep-awake runs; empty = always
+	KeepAwakeDim          bool              `json:"keep_awake_dim"`           // theater mode: press brightness down with the pings
+	PTTAutoReleaseMinutes int               `json:"ptt_auto_release_minutes"` // 0 = never auto-release a latched PTT
+	PTTMode               string            `json:"ptt_mode"`                 // "auto", "hold" (never latch) or "toggle" (every press toggles)
+	ToggleThresholdMs     int               `json:"toggle_threshold_ms"`      // "auto": presses shorter than this toggle
+	SwipeMode             string            `json:"swipe_mode"`               // "alternate" or "hold" (tap swipes right, hold swipes left)
+	SwipeHoldMs           int               `json:"swipe_hold_ms"`            // "hold": presses at least this long swipe left
+	SwipeResetSeconds     int               `json:"swipe_reset_seconds"`      // "alternate": start over with left after this long idle; 0 = never
+	PTTFallback           PTTFallbackConfig `json:"ptt_fallback"`
+	Overlay               OverlayConfig     `json:"overlay"`
+	MicSync               bool              `json:"mic_sync"`           // mute host mic while PTT is off
+	PreventHostSleep      bool              `json:"prevent_host_sleep"` // keep the host awake while PTT is on or a macro runs
+	MediaPassthrough      bool              `json:"media_passthrough"`  // host volume and play/pause keys go to the R1
+	TaskbarMenu           bool              `json:"taskbar_menu"`       // quick actions in the Windows jump list / macOS Dock menu
+	ToastActions          bool              `json:"toast_actions"`      // Windows: a toast with Release PTT and Swipe buttons while PTT is latched
+	MenuBarTimer          bool              `json:"menu_bar_timer"`     // macOS: how long PTT has been latched, next to the menu bar icon
+	GameMode              GameModeConfig    `json:"game_mode"`
+	QuickActions          []QuickAction     `json:"quick_actions"`
+	Macros                []Macro           `json:"macros"`
+	Focus                 FocusConfig       `json:"focus"`
+	Gesture               GestureConfig     `json:"gesture"`
+	Launcher              LauncherConfig    `json:"launcher"`
+	Device                DeviceConfig      `json:"device"`
+	EventLog              EventLogConfig    `json:"event_log"`
+	Pedal                 PedalConfig       `json:"pedal"`
+	NotifyWake            NotifyWakeConfig  `json:"notify_wake"`
+	OpenRGB               OpenRGBConfig     `json:"openrgb"`
+	Telemetry             TelemetryConfig   `json:"telemetry"`
+	Profiles              []Profile         `json:"profiles"`
+	ContextRules          []ContextRule     `json:"context_rules"`           // switch profiles by where the computer is docked
+	ActiveProfile         string            `json:"active_profile"`          // last profile switched in; written by the app
+	SettingsPort          int               `json:"settings_port,omitempty"` // localhost port of the settings page; 0 = any free port
+	LAN                   LANConfig         `json:"lan"`
+	ExtensionOrigins      []string          `json:"extension_origins,omitempty"` // browser extensions allowed to call the API, e.g. "chrome-extension://<id>"
+	ActionGuards          ActionGuards      `json:"action_guards"`
+	LogLevel              string            `json:"log_level"` // "debug", "info", "warn" or "error"
+	WakeSchedule          []WakeTime        `json:"wake_schedule"`
+	Backups               int               `json:"backups"`           // config versions kept in backups/ when settings change; 0 = none
+	LastSeen              LastSeen          `json:"last_seen"`         // written by the app, not meant to be edited
+	LastSeenVersion       string            `json:"last_seen_version"` // app version whose "What's new" was seen; written by the app
+}
+
+// LastSeen records the most recently connected R1, so the UI can tell
+// "never detected" apart from "was connected but dropped".
+type LastSeen struct {
+	Serial  string    `json:"serial,omitempty"`
+	Product string    `json:"product,omitempty"`
+	Time    time.Time `json:"time,omitempty"` // last connected; zero = never
+}
+
+// LaunchConfig customizes the login entry written when auto-start is
+// enabled, e.g. for installs that must be started through a stub
+// launcher.
+type LaunchConfig struct {
+	Program      string   `json:"program,omitempty"`       // launcher/shortcut to start; "" = this executable
+	Args         []string `json:"args,omitempty"`          // e.g. ["--profile", "desk"]
+	DelaySeconds int      `json:"delay_seconds,omitempty"` // wait this long after login before starting
+	WaitForUSB   bool     `json:"wait_for_usb,omitempty"`  // hold startup until the R1 enumerates (bounded)
+}
+
+// CommandArgs returns the arguments for the login entry: Args plus the
+// flags for the startup delay and USB wait.
+func (l LaunchConfig) CommandArgs() []string {
+	args := append([]string(nil), l.Args...)
+	if l.DelaySeconds > 0 {
+		args = append(args, fmt.Sprintf("--delay=%ds", l.DelaySeconds))
+	}
+	if l.WaitForUSB {
+		args = append(args, "--wait-usb")
+	}
+	return args
+}
+
+// StartPausedArg is the command-line flag that starts the app with global
+// hotkeys paused.
+const StartPausedArg = "--paused"
+
+// HasArg reports whether arg is one of the launch arguments.
+func (l LaunchConfig) HasArg(arg string) bool {
+	for _, a := range l.Args {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// EventLogConfig controls the opt-in JSONL export of state changes and
+// actions.
+type EventLogConfig struct {
+	Enabled   bool   `json:"enabled"`
+	Path      string `json:"path"`        // "" = events.jsonl in the config dir, "-" = stdout
+	MaxSizeMB int    `json:"max_size_mb"` // rotate at this size
+	MaxFiles  int    `json:"max_files"`   // rotated files to keep
+}
+
+// WakeTime is a time of day the R1's screen is woken, e.g. for a "good
+// morning" routine.
+type WakeTime struct {
+	At   string   `json:"at"`             // "HH:MM", 24h local time
+	Days []string `json:"days,omitempty"` // "mon".."sun", "weekdays", "weekends"; none = every day
+}
+
+// KeepAwakePeriod is a time range in which keep-awake runs, e.g. 09:00-17:00
+// on weekdays, optionally with its own idle limit.
+type KeepAwakePeriod struct {
+	Between           string   `json:"between,omitempty"`             // "HH:MM-HH:MM", 24h local time; "" = the whole day
+	Days              []string `json:"days,omitempty"`                // as in wake_schedule
+	SleepAfterMinutes *int     `json:"sleep_after_minutes,omitempty"` // idle limit in this period; nil = sleep_after_minutes
+	Dim               *bool    `json:"dim,omitempty"`                 // theater mode in this period; nil = keep_awake_dim
+}
+
+// Window returns the period's time range.
+func (p KeepAwakePeriod) Window() (schedule.Window, error) {
+	span := p.Between
+	if span == "" {
+		span = "00:00-00:00"
+	}
+	return schedule.ParseWindow(span, p.Days)
+}
+
+// PedalConfig reads a foot pedal or button box that isn't a keyboard: a
+// vendor HID device opened over USB, or a serial device. Each report is a
+// bitmask of pressed buttons, numbered from 1.
+type PedalConfig struct {
+	Enabled bool              `json:"enabled"`
+	Kind    string            `json:"kind"`           // "hid" or "serial"
+	VID     string            `json:"vid"`            // USB vendor ID in hex, e.g. "05f3"
+	PID     string            `json:"pid"`            // USB product ID in hex
+	Port    string            `json:"port,omitempty"` // serial port, e.g. "/dev/ttyUSB0" or "COM3"; "" = find by VID/PID (Linux)
+	Baud    int               `json:"baud,omitempty"` // serial only; 0 = 9600
+	Buttons map[string]string `json:"buttons"`        // button number → action name, or "ptt" to hold PTT; empty = button 1 is PTT
+}
+
+// PTTFallbackConfig is what a PTT hotkey press does when it can't reach
+// the R1, e.g. while it is unplugged, so the press doesn't silently do
+// nothing. Retrying comes first; the rest apply once it gives up.
+type PTTFallbackConfig struct {
+	RetrySeconds int         `json:"retry_seconds,omitempty"` // keep trying while the hotkey is held, up to this long; 0 = don't
+	Notify       bool        `json:"notify"`                  // post a desktop notification
+	HostKey      *KeyBinding `json:"host_key,omitempty"`      // hold this key on the host instead, e.g. another app's push-to-talk
+}
+
+// MaxPTTRetrySeconds bounds PTTFallbackConfig.RetrySeconds.
+const MaxPTTRetrySeconds = 60
+
+// NotifyWakeConfig wakes the R1's screen when apps on this computer post
+// desktop notifications, so the docked R1 lights up as a second notifier.
+// Linux only.
+type NotifyWakeConfig struct {
+	Enabled         bool     `json:"enabled"`
+	Apps            []string `json:"apps"`                       // app names, e.g. "Slack", ignoring case; empty = any app
+	CooldownSeconds int      `json:"cooldown_seconds,omitempty"` // ignore notifications this long after a wake; 0 = 30
+}
+
+// OpenRGBConfig lights keyboard and mouse RGB while PTT is on, by loading
+// lighting profiles saved in OpenRGB through its SDK server.
+type OpenRGBConfig struct {
+	Enabled     bool   `json:"enabled"`
+	Address     string `json:"address,omitempty"` // SDK server host:port; "" = 127.0.0.1:6742
+	PTTProfile  string `json:"ptt_profile"`       // loaded while PTT is on
+	IdleProfile string `json:"idle_profile"`      // loaded when PTT goes off
+	Blink       bool   `json:"blink,omitempty"`   // alternate the two profiles while PTT is on
+}
+
+// TelemetryConfig is the anonymous usage report. It is off unless the
+// user turns it on in Settings.
+type TelemetryConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Endpoint string `json:"endpoint,omitempty"` // URL reports are posted to; "" = the build's default, if any
+}
+
+// Profile is a named set of settings switched in together, e.g. by a
+// context rule when the computer is docked at the office. Settings left
+// out keep their current value.
+type Profile struct {
+	Name              string  `json:"name"`
+	KeepAwake         *bool   `json:"keep_awake,omitempty"`
+	SleepAfterMinutes *int    `json:"sleep_after_minutes,omitempty"`
+	KeepAwakeDim      *bool   `json:"keep_awake_dim,omitempty"`
+	PTTMode           *string `json:"ptt_mode,omitempty"`
+	MicSync           *bool   `json:"mic_sync,omitempty"`
+	PreventHostSleep  *bool   `json:"prevent_host_sleep,omitempty"`
+	MediaPassthrough  *bool   `json:"media_passthrough,omitempty"`
+}
+
+// ContextRule switches to Profile when everything it names is true of the
+// computer's surroundings, e.g. a dock's USB hub is attached. Rules are
+// tried in order and the first match wins; when none matches, the
+// current settings stay.
+type ContextRule struct {
+	Profile  string `json:"profile"`
+	USB      string `json:"usb,omitempty"`      // an attached USB device: "vid:pid" in hex, e.g. "0bda:5411", or a serial number (Linux only)
+	Displays int    `json:"displays,omitempty"` // exactly this many displays connected
+	SSID     string `json:"ssid,omitempty"`     // the Wi-Fi network joined
+}
+
+// LANConfig serves the phone remote to other devices on the network. Each
+// client pairs once with a PIN shown on this computer and gets its own
+// token, kept in Clients; WakeToken is generated on first use.
+type LANConfig struct {
+	Enabled   bool        `json:"enabled"`
+	Port      int         `json:"port"`
+	Clients   []LANClient `json:"clients,omitempty"`
+	WakeToken string      `json:"wake_token,omitempty"` // only wakes the R1, for ?token= links; "" = none yet
+}
+
+// LANClient is a paired remote client.
+type LANClient struct {
+	ID     string    `json:"id"`
+	Name   string    `json:"name"` // entered on the client when pairing, e.g. "Pixel"
+	Token  string    `json:"token"`
+	Role   string    `json:"role,omitempty"` // RoleControl or RoleRead; "" = RoleControl
+	Paired time.Time `json:"paired"`
+}
+
+// LAN client roles. Read-only clients can query status and metrics but
+// not act on the device or change settings.
+const (
+	RoleControl = "control"
+	RoleRead    = "read"
+)
+
+// CanControl reports whether the client may trigger device actions.
+func (cl LANClient) CanControl() bool {
+	return cl.Role == "" || cl.Role == RoleControl
+}
+
+// DeviceConfig pins the connection to one R1 when several USB devices are
+// attached. Empty fields match any device.
+type DeviceConfig struct {
+	Serial   string `json:"serial,omitempty"`
+	PortPath string `json:"port_path,omitempty"` // e.g. "1-2.3" = bus 1, hub on port 2, port 3
+
+	// Trace logs every USB control transfer to TracePath, for diagnosing
+	// AOA failures on particular firmware.
+	Trace     bool   `json:"trace,omitempty"`
+	TracePath string `json:"trace_path,omitempty"` // "" = usb-trace.log in the config dir
+
+	// TouchContact sets the pressure and contact size sent with every
+	// touch; nil = zero, as before they were reported. Some launchers
+	// treat a touch with no pressure as a hover.
+	TouchContact *TouchContact `json:"touch_contact,omitempty"`
+
+	// OffLimits are screen regions keep-awake taps and swipe ends stay
+	// out of, e.g. a corner where a tap opens a menu.
+	OffLimits []ScreenRegion `json:"off_limits,omitempty"`
+
+	// ReleaseStuckAfterSeconds is how long a touch or key may stay
+	// pressed on the R1 without a release, e.g. after a lost report,
+	// before it is released; 0 = 5.
+	ReleaseStuckAfterSeconds int `json:"release_stuck_after_seconds,omitempty"`
+
+	// CompositeHID registers one HID combining PTT, display controls and
+	// touch, by report ID, instead of one HID each: fewer registrations
+	// and input devices on the R1, and a shorter connect.
+	CompositeHID bool `json:"composite_hid,omitempty"`
+
+	// Backend is how the local R1 is reached: "libusb" (default) or
+	// "webusb", a Chrome tab relaying USB transfers where libusb can't
+	// reach the R1, e.g. on ChromeOS.
+	Backend string `json:"backend,omitempty"`
+
+	// Bridge drives an R1 attached to another computer instead of a
+	// local one.
+	Bridge BridgeConfig `json:"bridge"`
+}
+
+// TouchContact is the pressure and contact size of a touch, each 0–255.
+type TouchContact struct {
+	Pressure int `json:"pressure"`
+	Width    int `json:"width"`
+	Height   int `json:"height"`
+}
+
+// ScreenRegion is a rectangle on the R1's screen in touch coordinates,
+// 0–32767 on both axes from the top-left corner, edges included.
+type ScreenRegion struct {
+	X0 int `json:"x0"`
+	Y0 int `json:"y0"`
+	X1 int `json:"x1"`
+	Y1 int `json:"y1"`
+}
+
+// BridgeConfig points at an "r1ptt agent" on the computer the R1
+// is plugged into.
+type BridgeConfig struct {
+	Address string `json:"address,omitempty"` // host or host:port; "" = use the local R1
+	Token   string `json:"token,omitempty"`   // shared secret, as given to the agent
+	TLS     bool   `json:"tls,omitempty"`
+	CAFile  string `json:"ca_file,omitempty"` // PEM CA for the agent's certificate; "" = system roots
+}
+
+// QuickAction is a user-defined button shown in the settings UI.
+type QuickAction struct {
+	Label   string          `json:"label"`
+	Action  string          `json:"action"`            // e.g. "ptt_toggle", "swipe", "tap"
+	Payload json.RawMessage `json:"payload,omitempty"` // action-specific, e.g. {"x":100,"y":200}
+}
+
+// Macro is a named sequence of actions, run one after another.
+//
+// Params declares the macro's parameters with their default values (null
+// = must be given). Step payloads and repeat counts use them as
+// "{{name}}", or in integer arithmetic such as "{{col * 6000 + 1500}}".
+type Macro struct {
+	Name    string                     `json:"name"`
+	Params  map[string]json.RawMessage `json:"params,omitempty"`
+	Steps   []MacroStep                `json:"steps"`
+	Hotkeys []MacroHotkey              `json:"hotkeys,omitempty"` // registered at startup
+}
+
+// MacroHotkey runs a macro with the given parameter values.
+type MacroHotkey struct {
+	KeyBinding
+	Params map[string]json.RawMessage `json:"params,omitempty"`
+}
+
+// MacroStep is one action in a macro.
+type MacroStep struct {
+	Action  string          `json:"action"`             // as in QuickAction
+	Payload json.RawMessage `json:"payload,omitempty"`  // action-specific
+	DelayMs int             `json:"delay_ms,omitempty"` // wait this long before the step
+	Repeat  json.RawMessage `json:"repeat,omitempty"`   // run the step this many times, e.g. 3 or "{{count}}"; default 1
+
+	If           *MacroCondition `json:"if,omitempty"`             // skip the step unless this holds
+	Retries      int             `json:"retries,omitempty"`        // run a failed step again up to this many times
+	RetryDelayMs int             `json:"retry_delay_ms,omitempty"` // between tries; 0 = 500
+}
+
+// MacroCondition is checked just before a macro step runs. Every field
+// set must hold.
+type MacroCondition struct {
+	Connected *bool    `json:"connected,omitempty"` // the R1 is (or isn't) connected
+	Awake     *bool    `json:"awake,omitempty"`     // the R1's screen is (or isn't) on, as far as the app knows
+	Between   string   `json:"between,omitempty"`   // local time range, e.g. "09:00-17:00"; may run past midnight
+	Days      []string `json:"days,omitempty"`      // limits Between, as in wake_schedule
+	Profile   string   `json:"profile,omitempty"`   // the profile last switched in, by name
+}
+
+// Window returns the condition's time range, or ok=false if it has none.
+// Days without Between mean the whole of those days.
+func (mc *MacroCondition) Window() (w schedule.Window, ok bool, err error) {
+	if mc == nil || (mc.Between == "" && len(mc.Days) == 0) {
+		return w, false, nil
+	}
+	span := mc.Between
+	if span == "" {
+		span = "00:00-00:00"
+	}
+	w, err = schedule.ParseWindow(span, mc.Days)
+	return w, err == nil, err
+}
+
+// Check reports a condition that can't be evaluated. A nil condition is
+// fine and always holds.
+func (mc *MacroCondition) Check() error {
+	_, _, err := mc.Window()
+	return err
+}
+
+// Macro step limits.
+const (
+	MaxMacroDelayMs = 60_000
+	MaxMacroRetries = 10
+	MaxMacroRepeat  = 100
+)
+
+// FocusConfig is the focus (pomodoro) timer: work periods alternating
+// with breaks, a longer one every few, with R1 actions run as each
+// begins. The timer is started from the tray or the API.
+type FocusConfig struct {
+	WorkMinutes      int         `json:"work_minutes"`
+	BreakMinutes     int         `json:"break_minutes"`
+	LongBreakMinutes int         `json:"long_break_minutes"`
+	LongBreakEvery   int         `json:"long_break_every"` // work periods per long break; 0 = no long breaks
+	Cycles           int         `json:"cycles"`           // work periods before the timer stops; 0 = until stopped
+	OnWork           []FocusStep `json:"on_work"`          // run as each work period begins
+	OnBreak          []FocusStep `json:"on_break"`         // run as each break begins, long ones too
+	OnDone           []FocusStep `json:"on_done"`          // run after the last of Cycles
+}
+
+// FocusStep is run as a focus timer period begins: an action, as in
+// QuickAction, or a macro. A macro plays in the background, so the steps
+// after it don't wait for it.
+type FocusStep struct {
+	Action  string                     `json:"action,omitempty"`
+	Payload json.RawMessage            `json:"payload,omitempty"`
+	Macro   string                     `json:"macro,omitempty"`  // run this macro instead of an action
+	Params  map[string]json.RawMessage `json:"params,omitempty"` // the macro's parameter values
+}
+
+// Focus timer limits.
+const (
+	MaxFocusMinutes = 240
+	MaxFocusCycles  = 100
+)
+
+// Check reports the settings of fc the timer can't use, with fields
+// relative to fc, e.g. "on_work[0]". Action names and payloads aren't
+// checked here; the action package knows them.
+func (fc FocusConfig) Check() []Problem {
+	var ps []Problem
+	inRange := func(field string, n, lo, hi int) {
+		if n < lo || n > hi {
+			ps = append(ps, Problem{Field: field, Message: fmt.Sprintf("%d is out of range (%d-%d)", n, lo, hi)})
+		}
+	}
+	inRange("work_minutes", fc.WorkMinutes, 1, MaxFocusMinutes)
+	inRange("break_minutes", fc.BreakMinutes, 1, MaxFocusMinutes)
+	inRange("long_break_minutes", fc.LongBreakMinutes, 0, MaxFocusMinutes)
+	inRange("long_break_every", fc.LongBreakEvery, 0, MaxFocusCycles)
+	inRange("cycles", fc.Cycles, 0, MaxFocusCycles)
+	steps := func(field string, sts []FocusStep) {
+		for i, st := range sts {
+			if (st.Action == "") == (st.Macro == "") {
+				ps = append(ps, Problem{Field: fmt.Sprintf("%s[%d]", field, i), Message: "needs either an action or a macro"})
+			}
+		}
+	}
+	steps("on_work", fc.OnWork)
+	steps("on_break", fc.OnBreak)
+	steps("on_done", fc.OnDone)
+	return ps
+}
+
+// GameModeConfig lists applications during which global hotkeys are
+// suspended while they are in the foreground.
+type GameModeConfig struct {
+	Enabled   bool     `json:"enabled"`
+	Processes []string `json:"processes"` // executable names, e.g. "eldenring.exe"
+}
+
+// GestureConfig shapes generated swipes and picks gesture presets.
+type GestureConfig struct {
+	Easing string `json:"easing"` // "linear", "ease-in-out" or "ease-out"
+	Jitter bool   `json:"jitter"` // vary each swipe's points and timing slightly
+
+	// Firmware is the R1's RabbitOS version, e.g. "0.8.112", picking the
+	// gesture presets made for its layout; "" = presets for any version.
+	Firmware string `json:"firmware,omitempty"`
+
+	// CursorHiding is how swipes hide the R1's touch dot: "bottom_edge",
+	// "edge_start", "out_of_range" or "in_range_off". CursorHidingFirmware
+	// overrides it for Firmware versions starting with a key, e.g.
+	// {"0.8.112": "in_range_off"}; the longest matching key wins.
+	CursorHiding         string            `json:"cursor_hiding"`
+	CursorHidingFirmware map[string]string `json:"cursor_hiding_firmware,omitempty"`
+}
+
+// LauncherConfig lists shortcuts that open apps from the RabbitOS
+// launcher.
+//
+// Carousel models the home carousel: its cards from left to right, with
+// Home the index of the one the "home" gesture shows. Apps with a card
+// there are reached from the card the app last left the carousel on,
+// while that is known and no older than TrustSeconds; otherwise the
+// "home" gesture resets it first.
+type LauncherConfig struct {
+	Apps         []LauncherApp `json:"apps,omitempty"`
+	Carousel     []string      `json:"carousel,omitempty"` // card names, e.g. ["Home", "Music", "Camera"]
+	Home         int           `json:"home"`
+	TrustSeconds int           `json:"trust_seconds"` // 0 = always start from home
+}
+
+// LauncherApp opens an app from the launcher: the "home" gesture, Swipes
+// swipes across the home carousel, then a tap on the app's card.
+type LauncherApp struct {
+	Name   string      `json:"name"`             // e.g. "Music"; shown in the tray
+	Swipes int         `json:"swipes"`           // from the home card; positive swipes left, negative right; unused if the app is in the carousel
+	X      uint16      `json:"x,omitempty"`      // where to tap; 0 = the middle of the screen
+	Y      uint16      `json:"y,omitempty"`      // 0 = the middle of the screen
+	Hotkey *KeyBinding `json:"hotkey,omitempty"` // registered at startup
+}
+
+// Launcher limits: a shortcut's swipes either way, and how long a tracked
+// carousel position can be trusted.
+const (
+	MaxLauncherSwipes       = 20
+	MaxLauncherTrustSeconds = 3600
+)
+
+// ActionGuards are the guarded actions, by name, e.g. "brightness_down".
+type ActionGuards map[string]ActionGuard
+
+// ActionGuard protects a risky action from stray API calls, e.g. a script
+// in a loop turning the R1's screen off. It applies to actions sent to
+// the API, not to hotkeys; quick actions and macros run through the API
+// are refused a step that needs confirming.
+type ActionGuard struct {
+	Confirm         bool `json:"confirm,omitempty"`          // answer with a challenge that must be sent back to run it
+	CooldownSeconds int  `json:"cooldown_seconds,omitempty"` // refuse another run sooner than this
+}
+
+// OverlayConfig controls the on-screen PTT indicator window.
+type OverlayConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Position string `json:"position"` // "top-left", "top-right", "bottom-left", "bottom-right"
+	Size     int    `json:"size"`     // edge length in pixels
 }
 
 // HotkeyConfig defines a global hotkey binding.
 type HotkeyConfig struct {
 	Modifiers []string `json:"modifiers"` // "ctrl", "shift", "alt", "super"
 	Key       string   `json:"key"`       // "r", "space", "f5", etc.
+	Enabled   bool     `json:"enabled"`   // false keeps the binding but doesn't register it
+
+	// Extra holds additional combos that trigger the same action
+	// (e.g. F13 sent by a foot pedal). They are registered alongside the
+	// primary binding, which is all the hotkey setters change.
+	Extra []KeyBinding `json:"extra,omitempty"`
+}
+
+// KeyBinding is a single key combination.
+type KeyBinding struct {
+	Modifiers []string `json:"modifiers"`
+	Key       string   `json:"key"`
+}
+
+// Bindings returns the primary binding followed by any extra bindings.
+func (h HotkeyConfig) Bindings() []KeyBinding {
+	return append([]KeyBinding{{Modifiers: h.Modifiers, Key: h.Key}}, h.Extra...)
 }
 
 // String returns a human-readable representation like "Ctrl+Alt+R".
@@ -42,35 +573,201 @@ func (h HotkeyConfig) String() string {
 	}
 	if len(h.Key) == 1 {
 		s += string(h.Key[0] - 32) // uppercase single letter
+	} else if strings.HasPrefix(h.Key, "mouse") {
+		s += "Mouse" + h.Key[len("mouse"):]
 	} else {
 		s += h.Key
 	}
 	return s
 }
 
+// clone returns a deep copy of h.
+func (h HotkeyConfig) clone() HotkeyConfig {
+	c := h
+	c.Modifiers = append([]string(nil), h.Modifiers...)
+	c.Extra = make([]KeyBinding, len(h.Extra))
+	for i, b := range h.Extra {
+		c.Extra[i] = KeyBinding{Modifiers: append([]string(nil), b.Modifiers...), Key: b.Key}
+	}
+	return c
+}
+
 // DefaultConfig returns the default configuration.
 func DefaultConfig() *Config {
 	return &Config{
 		Hotkey: HotkeyConfig{
 			Modifiers: []string{"ctrl", "alt"},
 			Key:       "r",
+			Enabled:   true,
 		},
 		SwipeHotkey: HotkeyConfig{
 			Modifiers: []string{"ctrl", "alt"},
 			Key:       "w",
+			Enabled:   true,
+		},
+		PromptHotkey: HotkeyConfig{
+			Modifiers: []string{"ctrl", "alt"},
+			Key:       "p",
+			Enabled:   false, // turned on from the tray
+		},
+		ConfirmHotkey: HotkeyConfig{
+			Modifiers: []string{"ctrl", "alt"},
+			Key:       "return",
+		},
+		KeepAwake:             true,
+		SleepAfterMinutes:     60,
+		PTTAutoReleaseMinutes: 5,
+		PTTMode:               "auto",
+		ToggleThresholdMs:     300,
+		SwipeMode:             "alternate",
+		SwipeHoldMs:           400,
+		MenuBarTimer:          true,
+		Overlay: OverlayConfig{
+			Position: "top-right",
+			Size:     24,
+		},
+		Launcher: LauncherConfig{
+			TrustSeconds: 60,
+		},
+		// The experimental display controls may blank the screen
+		ActionGuards: ActionGuards{
+			"brightness_up":   {Confirm: true},
+			"brightness_down": {Confirm: true},
+		},
+		Gesture: GestureConfig{
+			Easing:       "linear",
+			CursorHiding: "bottom_edge",
+		},
+		EventLog: EventLogConfig{
+			MaxSizeMB: 10,
+			MaxFiles:  3,
+		},
+		Pedal: PedalConfig{
+			Kind: "hid",
+		},
+		LAN: LANConfig{
+			Port: 8765,
+		},
+		LogLevel: "info",
+		Backups:  10,
+		Focus: FocusConfig{
+			WorkMinutes:      25,
+			BreakMinutes:     5,
+			LongBreakMinutes: 15,
+			LongBreakEvery:   4,
+			OnWork:           []FocusStep{{Action: "wake"}},
+			OnBreak:          []FocusStep{{Action: "wake"}},
+			OnDone:           []FocusStep{{Action: "wake"}},
+		},
+		QuickActions: []QuickAction{
+			{Label: "Toggle PTT", Action: "ptt_toggle"},
+			{Label: "Swipe", Action: "swipe"},
+			{Label: "Wake", Action: "wake"},
 		},
-		KeepAwake:         true,
-		SleepAfterMinutes: 60,
 	}
 }
 
-// Dir returns the OS-appropriate config directory for r1ptt.
+// DirEnv overrides the config directory, e.g. for a mounted volume in a
+// container.
+const DirEnv = "R1_CONFIG_DIR"
+
+// PortableDirName is the directory next to the executable that, when it
+// exists, holds the config instead of the user's config directory, e.g.
+// for an install on a USB stick.
+const PortableDirName = "r1control-data"
+
+// Names of the config directory under the user's config directory. It used
+// to be named after the r1ptt binary.
+const (
+	dirName    = "r1control"
+	oldDirName = "r1ptt"
+)
+
+var (
+	userDirOnce sync.Once
+	userDir     string
+	userDirErr  error
+)
+
+// Dir returns the config directory: $DirEnv if set, else the portable
+// directory if there is one, else R1 Control's directory under the
+// OS-appropriate user config directory.
 func Dir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir, nil
+	}
+	if dir := portableDir(); dir != "" {
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return dir, nil
+		}
+	}
+	userDirOnce.Do(func() {
+		userDir, userDirErr = resolveUserDir()
+	})
+	return userDir, userDirErr
+}
+
+// resolveUserDir returns the directory under the user config directory,
+// moving the config over from the old name on first use.
+func resolveUserDir() (string, error) {
 	base, err := os.UserConfigDir()
 	if err != nil {
 		return "", fmt.Errorf("user config dir: %w", err)
 	}
-	return filepath.Join(base, "r1ptt"), nil
+	dir := filepath.Join(base, dirName)
+	old := filepath.Join(base, oldDirName)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		return dir, nil
+	}
+	if _, err := os.Stat(old); err != nil {
+		return dir, nil
+	}
+	if err := os.Rename(old, dir); err != nil {
+		// Keep using the old directory rather than starting over
+		log.Printf("[config] can't move %s to %s, keeping it: %v", old, dir, err)
+		return old, nil
+	}
+	log.Printf("[config] moved config from %s to %s", old, dir)
+	return dir, nil
+}
+
+// portableDir returns where the portable directory goes: next to the
+// AppImage, the .app bundle or the executable. It returns "" if the
+// executable can't be found.
+func portableDir() string {
+	if img := os.Getenv("APPIMAGE"); img != "" {
+		return filepath.Join(filepath.Dir(img), PortableDirName)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	dir := filepath.Dir(exe)
+	// Inside a macOS bundle, go next to the bundle rather than into it
+	if i := strings.Index(dir, ".app/Contents/MacOS"); i >= 0 {
+		dir = filepath.Dir(dir[:i+len(".app")])
+	}
+	return filepath.Join(dir, PortableDirName)
+}
+
+// EnablePortable creates the portable directory, so that Dir returns it
+// from now on, and returns its path. An existing config is not copied
+// over.
+func EnablePortable() (string, error) {
+	if os.Getenv(DirEnv) != "" {
+		return "", fmt.Errorf("%s is set; unset it to run portable", DirEnv)
+	}
+	dir := portableDir()
+	if dir == "" {
+		return "", fmt.Errorf("can't find the executable's directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create portable dir: %w", err)
+	}
+	return dir, nil
 }
 
 // Path returns the full path to the config file.
@@ -90,7 +787,9 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
-	data, err := os.ReadFile(p)
+	data, err := safefile.Read(p, func(data []byte) error {
+		return json.Unmarshal(data, DefaultConfig())
+	})
 	if os.IsNotExist(err) {
 		cfg := DefaultConfig()
 		if saveErr := cfg.Save(); saveErr != nil {
@@ -109,10 +808,12 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
-// Save writes the config to disk atomically (write temp, rename).
+// Save writes the config to disk atomically, keeping the previous version
+// to fall back on if the file is ever damaged.
 func (c *Config) Save() error {
 	c.mu.RLock()
 	data, err := json.MarshalIndent(c, "", "  ")
+	keep := c.Backups
 	c.mu.RUnlock()
 	if err != nil {
 		return fmt.Errorf("marshal config: %w", err)
@@ -128,13 +829,13 @@ func (c *Config) Save() error {
 		return fmt.Errorf("create config dir: %w", err)
 	}
 
-	tmp := p + ".tmp"
-	if err := os.WriteFile(tmp, data, 0o644); err != nil {
-		return fmt.Errorf("write temp config: %w", err)
+	// A failed backup shouldn't lose the user's change
+	if err := backupBeforeSave(p, data, keep); err != nil {
+		log.Printf("[config] backup: %v", err)
 	}
-	if err := os.Rename(tmp, p); err != nil {
-		os.Remove(tmp)
-		return fmt.Errorf("rename config: %w", err)
+
+	if err := safefile.Write(p, data); err != nil {
+		return fmt.Errorf("save config: %w", err)
 	}
 	return nil
 }
@@ -142,7 +843,7 @@ func (c *Config) Save() error {
 // SetHotkey updates the PTT hotkey configuration and saves to disk.
 func (c *Config) SetHotkey(mods []string, key string) error {
 	c.mu.Lock()
-	c.Hotkey = HotkeyConfig{Modifiers: mods, Key: key}
+	c.Hotkey = HotkeyConfig{Modifiers: mods, Key: key, Enabled: c.Hotkey.Enabled, Extra: c.Hotkey.Extra}
 	c.mu.Unlock()
 	return c.Save()
 }
@@ -151,15 +852,21 @@ func (c *Config) SetHotkey(mods []string, key string) error {
 func (c *Config) GetHotkey() HotkeyConfig {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	mods := make([]string, len(c.Hotkey.Modifiers))
-	copy(mods, c.Hotkey.Modifiers)
-	return HotkeyConfig{Modifiers: mods, Key: c.Hotkey.Key}
+	return c.Hotkey.clone()
 }
 
 // SetSwipeHotkey updates the swipe hotkey configuration and saves to disk.
 func (c *Config) SetSwipeHotkey(mods []string, key string) error {
 	c.mu.Lock()
-	c.SwipeHotkey = HotkeyConfig{Modifiers: mods, Key: key}
+	c.SwipeHotkey = HotkeyConfig{Modifiers: mods, Key: key, Enabled: c.SwipeHotkey.Enabled, Extra: c.SwipeHotkey.Extra}
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// SetHotkeyEnabled enables or disables the PTT hotkey and saves to disk.
+func (c *Config) SetHotkeyEnabled(enabled bool) error {
+	c.mu.Lock()
+	c.Hotkey.Enabled = enabled
 	c.mu.Unlock()
 	return c.Save()
 }
@@ -168,9 +875,37 @@ func (c *Config) SetSwipeHotkey(mods []string, key string) error {
 func (c *Config) GetSwipeHotkey() HotkeyConfig {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	mods := make([]string, len(c.SwipeHotkey.Modifiers))
-	copy(mods, c.SwipeHotkey.Modifiers)
-	return HotkeyConfig{Modifiers: mods, Key: c.SwipeHotkey.Key}
+	return c.SwipeHotkey.clone()
+}
+
+// GetPromptHotkey returns a copy of the prompt hotkey config.
+func (c *Config) GetPromptHotkey() HotkeyConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.PromptHotkey.clone()
+}
+
+// GetConfirmHotkey returns a copy of the confirm hotkey config.
+func (c *Config) GetConfirmHotkey() HotkeyConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ConfirmHotkey.clone()
+}
+
+// SetSwipeHotkeyEnabled enables or disables the swipe hotkey and saves to disk.
+func (c *Config) SetSwipeHotkeyEnabled(enabled bool) error {
+	c.mu.Lock()
+	c.SwipeHotkey.Enabled = enabled
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// SetPromptHotkeyEnabled enables or disables the prompt hotkey and saves to disk.
+func (c *Config) SetPromptHotkeyEnabled(enabled bool) error {
+	c.mu.Lock()
+	c.PromptHotkey.Enabled = enabled
+	c.mu.Unlock()
+	return c.Save()
 }
 
 // GetAutoStart returns the current auto-start setting.
@@ -180,6 +915,41 @@ func (c *Config) GetAutoStart() bool {
 	return c.AutoStart
 }
 
+// GetAutoStartLaunch returns a copy of the login entry settings.
+func (c *Config) GetAutoStartLaunch() LaunchConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	l := c.AutoStartLaunch
+	l.Args = append([]string(nil), c.AutoStartLaunch.Args...)
+	return l
+}
+
+// SetAutoStartDelay sets the login startup delay and saves to disk.
+func (c *Config) SetAutoStartDelay(seconds int) error {
+	c.mu.Lock()
+	c.AutoStartLaunch.DelaySeconds = seconds
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// SetAutoStartPaused adds or removes StartPausedArg from the auto-start
+// arguments and saves to disk.
+func (c *Config) SetAutoStartPaused(paused bool) error {
+	c.mu.Lock()
+	var args []string
+	for _, a := range c.AutoStartLaunch.Args {
+		if a != StartPausedArg {
+			args = append(args, a)
+		}
+	}
+	if paused {
+		args = append(args, StartPausedArg)
+	}
+	c.AutoStartLaunch.Args = args
+	c.mu.Unlock()
+	return c.Save()
+}
+
 // SetAutoStart updates the auto-start setting and saves to disk.
 func (c *Config) SetAutoStart(enabled bool) error {
 	c.mu.Lock()
@@ -210,3 +980,676 @@ func (c *Config) SetKeepAwake(enabled bool, sleepAfterMinutes int) error {
 	c.mu.Unlock()
 	return c.Save()
 }
+
+// GetKeepAwakeSchedule returns a copy of the keep-awake periods.
+func (c *Config) GetKeepAwakeSchedule() []KeepAwakePeriod {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ps := make([]KeepAwakePeriod, len(c.KeepAwakeSchedule))
+	for i, p := range c.KeepAwakeSchedule {
+		ps[i] = p
+		ps[i].Days = slices.Clone(p.Days)
+		if p.SleepAfterMinutes != nil {
+			n := *p.SleepAfterMinutes
+			ps[i].SleepAfterMinutes = &n
+		}
+		if p.Dim != nil {
+			d := *p.Dim
+			ps[i].Dim = &d
+		}
+	}
+	return ps
+}
+
+// SetKeepAwakeSchedule replaces the keep-awake periods and saves to disk.
+func (c *Config) SetKeepAwakeSchedule(ps []KeepAwakePeriod) error {
+	c.mu.Lock()
+	c.KeepAwakeSchedule = ps
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// KeepAwakeAt reports whether keep-awake should run at t: whether t falls
+// in a keep_awake_schedule period, or true if there is no schedule. It
+// also returns that period's idle limit, or -1 for sleep_after_minutes.
+// Periods that don't parse are ignored; Validate reports them.
+func (c *Config) KeepAwakeAt(t time.Time) (on bool, sleepAfterMinutes int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.KeepAwakeSchedule) == 0 {
+		return true, -1
+	}
+	p, ok := c.periodAt(t)
+	if !ok {
+		return false, -1
+	}
+	if p.SleepAfterMinutes != nil {
+		return true, *p.SleepAfterMinutes
+	}
+	return true, -1
+}
+
+// GetKeepAwakeDim returns whether theater mode is on outside periods that
+// set their own.
+func (c *Config) GetKeepAwakeDim() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.KeepAwakeDim
+}
+
+// SetKeepAwakeDim turns theater mode on or off and saves to disk.
+func (c *Config) SetKeepAwakeDim(dim bool) error {
+	c.mu.Lock()
+	c.KeepAwakeDim = dim
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// KeepAwakeDimAt reports whether the screen should be kept dimmed while
+// keep-awake runs at t: the dim setting of the period t falls in, if it
+// has one, else keep_awake_dim.
+func (c *Config) KeepAwakeDimAt(t time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if p, ok := c.periodAt(t); ok && p.Dim != nil {
+		return *p.Dim
+	}
+	return c.KeepAwakeDim
+}
+
+// periodAt returns the first keep-awake period t falls in. Must be called
+// with c.mu held.
+func (c *Config) periodAt(t time.Time) (KeepAwakePeriod, bool) {
+	for _, p := range c.KeepAwakeSchedule {
+		w, err := p.Window()
+		if err == nil && w.Contains(t) {
+			return p, true
+		}
+	}
+	return KeepAwakePeriod{}, false
+}
+
+// GetPTTAutoRelease returns the latched-PTT auto-release timeout in minutes.
+func (c *Config) GetPTTAutoRelease() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.PTTAutoReleaseMinutes
+}
+
+// SetPTTAutoRelease updates the latched-PTT auto-release timeout and saves to disk.
+func (c *Config) SetPTTAutoRelease(minutes int) error {
+	c.mu.Lock()
+	c.PTTAutoReleaseMinutes = minutes
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetPTTMode returns the PTT hotkey mode and toggle threshold in milliseconds.
+func (c *Config) GetPTTMode() (mode string, thresholdMs int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.PTTMode, c.ToggleThresholdMs
+}
+
+// SetPTTMode updates the PTT hotkey mode and toggle threshold and saves to disk.
+func (c *Config) SetPTTMode(mode string, thresholdMs int) error {
+	c.mu.Lock()
+	c.PTTMode = mode
+	c.ToggleThresholdMs = thresholdMs
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetSwipeMode returns the swipe hotkey mode, the hold time in
+// milliseconds and the direction reset in seconds.
+func (c *Config) GetSwipeMode() (mode string, holdMs, resetSeconds int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.SwipeMode, c.SwipeHoldMs, c.SwipeResetSeconds
+}
+
+// SetSwipeMode updates the swipe hotkey mode, hold time and direction
+// reset and saves to disk.
+func (c *Config) SetSwipeMode(mode string, holdMs, resetSeconds int) error {
+	c.mu.Lock()
+	c.SwipeMode = mode
+	c.SwipeHoldMs = holdMs
+	c.SwipeResetSeconds = resetSeconds
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetOverlay returns a copy of the PTT overlay configuration.
+func (c *Config) GetOverlay() OverlayConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Overlay
+}
+
+// SetOverlayEnabled turns the PTT overlay on or off and saves to disk.
+func (c *Config) SetOverlayEnabled(enabled bool) error {
+	c.mu.Lock()
+	c.Overlay.Enabled = enabled
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetWakeSchedule returns a copy of the scheduled wake times.
+func (c *Config) GetWakeSchedule() []WakeTime {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]WakeTime(nil), c.WakeSchedule...)
+}
+
+// GetLogLevel returns the configured log level name.
+func (c *Config) GetLogLevel() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.LogLevel
+}
+
+// GetMicSync returns whether the host mic mirrors PTT state.
+func (c *Config) GetMicSync() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.MicSync
+}
+
+// SetMicSync updates the host mic sync setting and saves to disk.
+func (c *Config) SetMicSync(enabled bool) error {
+	c.mu.Lock()
+	c.MicSync = enabled
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetPreventHostSleep returns whether host sleep is blocked while PTT is
+// on or a macro runs.
+func (c *Config) GetPreventHostSleep() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.PreventHostSleep
+}
+
+// SetPreventHostSleep updates the host sleep setting and saves to disk.
+func (c *Config) SetPreventHostSleep(enabled bool) error {
+	c.mu.Lock()
+	c.PreventHostSleep = enabled
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetMediaPassthrough returns whether the host's media keys are sent to
+// the R1 instead of the host.
+func (c *Config) GetMediaPassthrough() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.MediaPassthrough
+}
+
+// SetMediaPassthrough updates the media key passthrough setting and saves
+// to disk.
+func (c *Config) SetMediaPassthrough(enabled bool) error {
+	c.mu.Lock()
+	c.MediaPassthrough = enabled
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetTaskbarMenu returns whether quick actions are offered in the
+// Windows jump list or the macOS Dock menu.
+func (c *Config) GetTaskbarMenu() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.TaskbarMenu
+}
+
+// GetMenuBarTimer returns whether the macOS menu bar shows how long PTT
+// has been latched.
+func (c *Config) GetMenuBarTimer() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.MenuBarTimer
+}
+
+// GetToastActions returns whether a toast with buttons is shown while
+// PTT is latched, on Windows.
+func (c *Config) GetToastActions() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ToastActions
+}
+
+// GetDisableHotkeys returns whether global hotkeys are turned off
+// entirely.
+func (c *Config) GetDisableHotkeys() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.DisableHotkeys
+}
+
+// GetSettingsPort returns the settings page's port; 0 = any free port.
+func (c *Config) GetSettingsPort() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.SettingsPort
+}
+
+// GetExtensionOrigins returns the browser extension origins allowed to
+// call the settings server's API.
+func (c *Config) GetExtensionOrigins() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.ExtensionOrigins)
+}
+
+// GetGameMode returns a copy of the game-mode configuration.
+func (c *Config) GetGameMode() GameModeConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	procs := make([]string, len(c.GameMode.Processes))
+	copy(procs, c.GameMode.Processes)
+	return GameModeConfig{Enabled: c.GameMode.Enabled, Processes: procs}
+}
+
+// GetDevice returns the device selection filter.
+func (c *Config) GetDevice() DeviceConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	d := c.Device
+	if d.TouchContact != nil {
+		tc := *d.TouchContact
+		d.TouchContact = &tc
+	}
+	d.OffLimits = append([]ScreenRegion(nil), c.Device.OffLimits...)
+	return d
+}
+
+// GetGesture returns a copy of the swipe style settings.
+func (c *Config) GetGesture() GestureConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	g := c.Gesture
+	g.CursorHidingFirmware = maps.Clone(g.CursorHidingFirmware)
+	return g
+}
+
+// GetActionGuard returns the guard for the named action, if it has one.
+func (c *Config) GetActionGuard(name string) (ActionGuard, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	g, ok := c.ActionGuards[name]
+	return g, ok
+}
+
+// GetPTTFallback returns a copy of the PTT fallback settings.
+func (c *Config) GetPTTFallback() PTTFallbackConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	fb := c.PTTFallback
+	if fb.HostKey != nil {
+		hk := *fb.HostKey
+		hk.Modifiers = slices.Clone(hk.Modifiers)
+		fb.HostKey = &hk
+	}
+	return fb
+}
+
+// GetProfiles returns a copy of the profiles.
+func (c *Config) GetProfiles() []Profile {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.Profiles)
+}
+
+// GetContextRules returns a copy of the profile switching rules.
+func (c *Config) GetContextRules() []ContextRule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.ContextRules)
+}
+
+// GetActiveProfile returns the name of the profile last switched in, or
+// "" if none was.
+func (c *Config) GetActiveProfile() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ActiveProfile
+}
+
+// ApplyProfile copies the settings of the named profile into the config,
+// records it as the active profile and saves to disk.
+func (c *Config) ApplyProfile(name string) error {
+	c.mu.Lock()
+	i := slices.IndexFunc(c.Profiles, func(p Profile) bool { return p.Name == name })
+	if i < 0 {
+		c.mu.Unlock()
+		return fmt.Errorf("no profile named %q", name)
+	}
+	p := c.Profiles[i]
+	if p.KeepAwake != nil {
+		c.KeepAwake = *p.KeepAwake
+	}
+	if p.SleepAfterMinutes != nil {
+		c.SleepAfterMinutes = *p.SleepAfterMinutes
+	}
+	if p.KeepAwakeDim != nil {
+		c.KeepAwakeDim = *p.KeepAwakeDim
+	}
+	if p.PTTMode != nil {
+		c.PTTMode = *p.PTTMode
+	}
+	if p.MicSync != nil {
+		c.MicSync = *p.MicSync
+	}
+	if p.PreventHostSleep != nil {
+		c.PreventHostSleep = *p.PreventHostSleep
+	}
+	if p.MediaPassthrough != nil {
+		c.MediaPassthrough = *p.MediaPassthrough
+	}
+	c.ActiveProfile = name
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetNotifyWake returns a copy of the wake-on-notification settings.
+func (c *Config) GetNotifyWake() NotifyWakeConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	nw := c.NotifyWake
+	nw.Apps = slices.Clone(nw.Apps)
+	return nw
+}
+
+// GetOpenRGB returns the OpenRGB PTT indicator settings.
+func (c *Config) GetOpenRGB() OpenRGBConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.OpenRGB
+}
+
+// GetTelemetry returns the usage report settings.
+func (c *Config) GetTelemetry() TelemetryConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Telemetry
+}
+
+// SetTelemetryEnabled turns the anonymous usage report on or off.
+func (c *Config) SetTelemetryEnabled(enabled bool) error {
+	c.mu.Lock()
+	c.Telemetry.Enabled = enabled
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetPedal returns a copy of the foot pedal settings.
+func (c *Config) GetPedal() PedalConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	p := c.Pedal
+	p.Buttons = make(map[string]string, len(c.Pedal.Buttons))
+	for k, v := range c.Pedal.Buttons {
+		p.Buttons[k] = v
+	}
+	return p
+}
+
+// GetLAN returns a copy of the LAN remote settings.
+func (c *Config) GetLAN() LANConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	l := c.LAN
+	l.Clients = append([]LANClient(nil), c.LAN.Clients...)
+	return l
+}
+
+// LANClientByToken returns the paired client holding token.
+func (c *Config) LANClientByToken(token string) (LANClient, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	found := -1
+	for i, cl := range c.LAN.Clients {
+		// Compare every entry so timing doesn't reveal a match
+		if subtle.ConstantTimeCompare([]byte(cl.Token), []byte(token)) == 1 {
+			found = i
+		}
+	}
+	if found < 0 || token == "" {
+		return LANClient{}, false
+	}
+	return c.LAN.Clients[found], true
+}
+
+// WakeToken returns the token of wake links, generating a new one and
+// saving to disk if there is none yet or rotate is set.
+func (c *Config) WakeToken(rotate bool) (string, error) {
+	c.mu.RLock()
+	tok := c.LAN.WakeToken
+	c.mu.RUnlock()
+	if tok != "" && !rotate {
+		return tok, nil
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	tok = hex.EncodeToString(b)
+	c.mu.Lock()
+	c.LAN.WakeToken = tok
+	c.mu.Unlock()
+	return tok, c.Save()
+}
+
+// IsWakeToken reports whether token is the token of wake links.
+func (c *Config) IsWakeToken(token string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.LAN.WakeToken != "" && subtle.ConstantTimeCompare([]byte(c.LAN.WakeToken), []byte(token)) == 1
+}
+
+// AddLANClient pairs a new client with the given role, generating its ID
+// and token, and saves to disk.
+func (c *Config) AddLANClient(name, role string) (LANClient, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return LANClient{}, fmt.Errorf("generate token: %w", err)
+	}
+	cl := LANClient{
+		ID:     hex.EncodeToString(b[:4]),
+		Name:   name,
+		Token:  hex.EncodeToString(b[4:]),
+		Role:   role,
+		Paired: time.Now(),
+	}
+	c.mu.Lock()
+	c.LAN.Clients = append(c.LAN.Clients, cl)
+	c.mu.Unlock()
+	return cl, c.Save()
+}
+
+// SetLANClientRole changes a paired client's role and saves to disk.
+// Returns false if there is no such client.
+func (c *Config) SetLANClientRole(id, role string) (bool, error) {
+	c.mu.Lock()
+	found := false
+	for i := range c.LAN.Clients {
+		if c.LAN.Clients[i].ID == id {
+			c.LAN.Clients[i].Role = role
+			found = true
+		}
+	}
+	c.mu.Unlock()
+	if !found {
+		return false, nil
+	}
+	return true, c.Save()
+}
+
+// RemoveLANClient revokes a paired client and saves to disk. Returns
+// false if there is no such client.
+func (c *Config) RemoveLANClient(id string) (bool, error) {
+	c.mu.Lock()
+	kept := c.LAN.Clients[:0:0]
+	for _, cl := range c.LAN.Clients {
+		if cl.ID != id {
+			kept = append(kept, cl)
+		}
+	}
+	removed := len(kept) < len(c.LAN.Clients)
+	c.LAN.Clients = kept
+	c.mu.Unlock()
+	if !removed {
+		return false, nil
+	}
+	return true, c.Save()
+}
+
+// GetEventLog returns the event export settings.
+func (c *Config) GetEventLog() EventLogConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.EventLog
+}
+
+// GetQuickActions returns a copy of the quick-action buttons.
+func (c *Config) GetQuickActions() []QuickAction {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	qa := make([]QuickAction, len(c.QuickActions))
+	copy(qa, c.QuickActions)
+	return qa
+}
+
+// GetLauncher returns a copy of the launcher shortcuts.
+func (c *Config) GetLauncher() LauncherConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	l := c.Launcher
+	l.Apps = slices.Clone(c.Launcher.Apps)
+	l.Carousel = slices.Clone(c.Launcher.Carousel)
+	return l
+}
+
+// GetMacros returns a copy of the macros.
+func (c *Config) GetMacros() []Macro {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ms := make([]Macro, len(c.Macros))
+	for i, m := range c.Macros {
+		ms[i] = m
+		ms[i].Params = maps.Clone(m.Params)
+		ms[i].Steps = slices.Clone(m.Steps)
+		ms[i].Hotkeys = slices.Clone(m.Hotkeys)
+	}
+	return ms
+}
+
+// GetFocus returns a copy of the focus timer configuration.
+func (c *Config) GetFocus() FocusConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	fc := c.Focus
+	fc.OnWork = slices.Clone(fc.OnWork)
+	fc.OnBreak = slices.Clone(fc.OnBreak)
+	fc.OnDone = slices.Clone(fc.OnDone)
+	return fc
+}
+
+// SetFocus replaces the focus timer configuration and saves to disk.
+func (c *Config) SetFocus(fc FocusConfig) error {
+	c.mu.Lock()
+	c.Focus = fc
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// SetMacros replaces the macros and saves to disk.
+func (c *Config) SetMacros(ms []Macro) error {
+	c.mu.Lock()
+	c.Macros = ms
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// PutMacro adds m, replacing the macro with the same name, and saves to
+// disk.
+func (c *Config) PutMacro(m Macro) error {
+	c.mu.Lock()
+	i := slices.IndexFunc(c.Macros, func(old Macro) bool { return old.Name == m.Name })
+	if i >= 0 {
+		c.Macros[i] = m
+	} else {
+		c.Macros = append(c.Macros, m)
+	}
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// AddMacro adds m and saves to disk. It fails if a macro has m's name
+// already.
+func (c *Config) AddMacro(m Macro) error {
+	c.mu.Lock()
+	if slices.ContainsFunc(c.Macros, func(old Macro) bool { return old.Name == m.Name }) {
+		c.mu.Unlock()
+		return fmt.Errorf("there is a macro named %q already", m.Name)
+	}
+	c.Macros = append(c.Macros, m)
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// DeleteMacro removes the macro called name and saves to disk. ok is
+// false if there is no such macro.
+func (c *Config) DeleteMacro(name string) (ok bool, err error) {
+	c.mu.Lock()
+	i := slices.IndexFunc(c.Macros, func(m Macro) bool { return m.Name == name })
+	if i < 0 {
+		c.mu.Unlock()
+		return false, nil
+	}
+	c.Macros = slices.Delete(slices.Clone(c.Macros), i, i+1)
+	c.mu.Unlock()
+	return true, c.Save()
+}
+
+// GetLastSeen returns the most recently connected R1.
+func (c *Config) GetLastSeen() LastSeen {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.LastSeen
+}
+
+// SetLastSeen records a connected R1 and saves to disk.
+func (c *Config) SetLastSeen(ls LastSeen) error {
+	c.mu.Lock()
+	c.LastSeen = ls
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetLastSeenVersion returns the app version whose release notes the
+// user last saw ("" = fresh install).
+func (c *Config) GetLastSeenVersion() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.LastSeenVersion
+}
+
+// SetLastSeenVersion records that the release notes up to version were
+// seen and saves to disk.
+func (c *Config) SetLastSeenVersion(version string) error {
+	c.mu.Lock()
+	c.LastSeenVersion = version
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// SetQuickActions replaces the quick-action buttons and saves to disk.
+func (c *Config) SetQuickActions(qa []QuickAction) error {
+	c.mu.Lock()
+	c.QuickActions = qa
+	c.mu.Unlock()
+	return c.Save()
+}
@@ -0,0 +1,497 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/HopIT-Hub/R1-Control/internal/logging"
+	"github.com/HopIT-Hub/R1-Control/internal/schedule"
+)
+
+// Allowed range for the tap/hold toggle threshold.
+const (
+	MinToggleThresholdMs = 100
+	MaxToggleThresholdMs = 1000
+)
+
+// Allowed ranges for the swipe hotkey's hold time and direction reset.
+const (
+	MinSwipeHoldMs       = 100
+	MaxSwipeHoldMs       = 2000
+	MaxSwipeResetSeconds = 3600
+)
+
+// MaxActionCooldownSeconds caps an action guard's cooldown.
+const MaxActionCooldownSeconds = 86400
+
+// Problem is one error found in a config file.
+type Problem struct {
+	Line    int    `json:"line,omitempty"`  // 1-based; 0 = not tied to a line
+	Field   string `json:"field,omitempty"` // e.g. "hotkey.key" or "quick_actions[2].label"
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	var b strings.Builder
+	if p.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", p.Line)
+	}
+	if p.Field != "" {
+		b.WriteString(p.Field + ": ")
+	}
+	b.WriteString(p.Message)
+	return b.String()
+}
+
+// ValidationError is returned by LoadStrict for a config file with
+// problems, and by Restore for such a backup.
+type ValidationError struct {
+	Path     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.String()
+	}
+	return fmt.Sprintf("%s: %s", e.Path, strings.Join(lines, "; "))
+}
+
+// HotkeyValidator, if set, checks each hotkey binding during validation,
+// e.g. that the key exists on this platform. The config package can't
+// check key names itself, since they come from the hotkey package.
+var HotkeyValidator func(modifiers []string, key string) error
+
+// LoadStrict is like Load, but fails with a *ValidationError listing
+// every problem in the file instead of ignoring unknown fields and
+// starting with values the app can't use.
+func LoadStrict() (*Config, error) {
+	p, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return Load() // nothing hand-edited yet
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	if problems := Validate(data); len(problems) > 0 {
+		return nil, &ValidationError{Path: p, Problems: problems}
+	}
+	return Load()
+}
+
+// Validate checks a config file's contents: JSON syntax and types,
+// unknown fields, hotkey names and out-of-range values. Problems are
+// reported with the line they are on.
+func Validate(data []byte) []Problem {
+	v := validator{data: data, pos: map[string]int64{}}
+
+	cfg := DefaultConfig()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			return []Problem{{Line: v.line(syntaxErr.Offset), Message: syntaxErr.Error()}}
+		case errors.As(err, &typeErr):
+			v.problems = append(v.problems, Problem{
+				Line:    v.line(typeErr.Offset),
+				Field:   typeErr.Field,
+				Message: fmt.Sprintf("must be %s, not %s", typeErr.Type, typeErr.Value),
+			})
+		default:
+			return []Problem{{Message: err.Error()}}
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := v.walk(dec, reflect.TypeOf(Config{}), ""); err != nil {
+		v.problems = append(v.problems, Problem{Line: v.line(dec.InputOffset()), Message: err.Error()})
+	}
+	v.check(cfg)
+	sort.SliceStable(v.problems, func(i, j int) bool { return v.problems[i].Line < v.problems[j].Line })
+	return v.problems
+}
+
+// validator collects the problems in one config file.
+type validator struct {
+	data     []byte
+	pos      map[string]int64 // field path → offset of its value
+	problems []Problem
+}
+
+// line returns the 1-based line containing offset.
+func (v *validator) line(offset int64) int {
+	offset = min(max(offset, 0), int64(len(v.data)))
+	return 1 + bytes.Count(v.data[:offset], []byte("\n"))
+}
+
+// add records a problem with field, on field's line if it is in the file.
+func (v *validator) add(field, format string, args ...any) {
+	p := Problem{Field: field, Message: fmt.Sprintf(format, args...)}
+	if off, ok := v.pos[field]; ok {
+		p.Line = v.line(off)
+	}
+	v.problems = append(v.problems, p)
+}
+
+// walk reads one JSON value of type t, recording where each field is and
+// reporting fields t doesn't have. A nil t accepts anything.
+func (v *validator) walk(dec *json.Decoder, t reflect.Type, path string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if _, ok := v.pos[path]; !ok {
+		v.pos[path] = dec.InputOffset()
+	}
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := tok.(string)
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+			v.pos[field] = dec.InputOffset()
+			ft, ok := fieldType(t, key)
+			if !ok {
+				v.add(field, "unknown field")
+			}
+			if err := v.walk(dec, ft, field); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	case json.Delim('['):
+		var et reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			et = t.Elem()
+		}
+		for i := 0; dec.More(); i++ {
+			if err := v.walk(dec, et, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	}
+	return err
+}
+
+// rawMessageType is accepted as any JSON value.
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// fieldType returns the type of t's field named key in JSON, matched
+// case-insensitively like encoding/json does. ok is false if t is a
+// struct without that field; any key is accepted when t isn't a struct.
+func fieldType(t reflect.Type, key string) (ft reflect.Type, ok bool) {
+	if t == nil || t == rawMessageType {
+		return nil, true
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem(), true
+	case reflect.Struct:
+	default:
+		return nil, true // a type error, already reported by Unmarshal
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.EqualFold(name, key) {
+			if f.Type == rawMessageType {
+				return nil, true
+			}
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
+
+// check reports values the app can't use.
+func (v *validator) check(c *Config) {
+	for _, hk := range []struct {
+		field string
+		cfg   HotkeyConfig
+	}{
+		{"hotkey", c.Hotkey},
+		{"swipe_hotkey", c.SwipeHotkey},
+		{"prompt_hotkey", c.PromptHotkey},
+		{"confirm_hotkey", c.ConfirmHotkey},
+	} {
+		v.checkBinding(hk.field, hk.cfg.Modifiers, hk.cfg.Key)
+		for i, b := range hk.cfg.Extra {
+			v.checkBinding(fmt.Sprintf("%s.extra[%d]", hk.field, i), b.Modifiers, b.Key)
+		}
+	}
+
+	v.checkRange("sleep_after_minutes", c.SleepAfterMinutes, 0, 24*60)
+	v.checkRange("ptt_auto_release_minutes", c.PTTAutoReleaseMinutes, 0, 24*60)
+	v.checkOneOf("ptt_mode", c.PTTMode, "auto", "hold", "toggle")
+	v.checkRange("toggle_threshold_ms", c.ToggleThresholdMs, MinToggleThresholdMs, MaxToggleThresholdMs)
+	v.checkOneOf("swipe_mode", c.SwipeMode, "alternate", "hold")
+	v.checkRange("swipe_hold_ms", c.SwipeHoldMs, MinSwipeHoldMs, MaxSwipeHoldMs)
+	v.checkRange("swipe_reset_seconds", c.SwipeResetSeconds, 0, MaxSwipeResetSeconds)
+	v.checkOneOf("overlay.position", c.Overlay.Position, "top-left", "top-right", "bottom-left", "bottom-right")
+	v.checkRange("overlay.size", c.Overlay.Size, 8, 512)
+	v.checkOneOf("gesture.easing", c.Gesture.Easing, "linear", "ease-in-out", "ease-out")
+	cursorHiding := []string{"bottom_edge", "edge_start", "out_of_range", "in_range_off"}
+	v.checkOneOf("gesture.cursor_hiding", c.Gesture.CursorHiding, cursorHiding...)
+	for _, fw := range slices.Sorted(maps.Keys(c.Gesture.CursorHidingFirmware)) {
+		v.checkOneOf(fmt.Sprintf("gesture.cursor_hiding_firmware[%q]", fw), c.Gesture.CursorHidingFirmware[fw], cursorHiding...)
+	}
+	v.checkRange("auto_start_launch.delay_seconds", c.AutoStartLaunch.DelaySeconds, 0, 600)
+	v.checkRange("event_log.max_size_mb", c.EventLog.MaxSizeMB, 0, 1024)
+	v.checkRange("event_log.max_files", c.EventLog.MaxFiles, 0, 100)
+	v.checkOneOf("pedal.kind", c.Pedal.Kind, "hid", "serial")
+	v.checkRange("pedal.baud", c.Pedal.Baud, 0, 4_000_000)
+	v.checkRange("ptt_fallback.retry_seconds", c.PTTFallback.RetrySeconds, 0, MaxPTTRetrySeconds)
+	if hk := c.PTTFallback.HostKey; hk != nil && hk.Key == "" {
+		v.add("ptt_fallback.host_key.key", "is empty")
+	}
+	v.checkRange("notify_wake.cooldown_seconds", c.NotifyWake.CooldownSeconds, 0, 3600)
+	if o := c.OpenRGB; o.Enabled {
+		if o.PTTProfile == "" {
+			v.add("openrgb.ptt_profile", "is empty")
+		}
+		if o.IdleProfile == "" {
+			v.add("openrgb.idle_profile", "is empty")
+		}
+	}
+	if a := c.OpenRGB.Address; a != "" {
+		if _, _, err := net.SplitHostPort(a); err != nil {
+			v.add("openrgb.address", "%q is not host:port", a)
+		}
+	}
+	if e := c.Telemetry.Endpoint; e != "" {
+		if u, err := url.Parse(e); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			v.add("telemetry.endpoint", "%q is not an http(s) URL", e)
+		}
+	}
+	v.checkRange("lan.port", c.LAN.Port, 1, 65535)
+	v.checkRange("settings_port", c.SettingsPort, 0, 65535)
+	for _, name := range slices.Sorted(maps.Keys(c.ActionGuards)) {
+		v.checkRange(fmt.Sprintf("action_guards[%q].cooldown_seconds", name), c.ActionGuards[name].CooldownSeconds, 0, MaxActionCooldownSeconds)
+	}
+	for i, o := range c.ExtensionOrigins {
+		scheme, id, _ := strings.Cut(o, "://")
+		if (scheme != "chrome-extension" && scheme != "moz-extension") || id == "" || strings.ContainsAny(id, "/:") {
+			v.add(fmt.Sprintf("extension_origins[%d]", i), "%q is not chrome-extension://<id> or moz-extension://<uuid>", o)
+		}
+	}
+	v.checkRange("backups", c.Backups, 0, 100)
+	if tc := c.Device.TouchContact; tc != nil {
+		v.checkRange("device.touch_contact.pressure", tc.Pressure, 0, 255)
+		v.checkRange("device.touch_contact.width", tc.Width, 0, 255)
+		v.checkRange("device.touch_contact.height", tc.Height, 0, 255)
+	}
+	v.checkRange("device.release_stuck_after_seconds", c.Device.ReleaseStuckAfterSeconds, 0, 300)
+	switch c.Device.Backend {
+	case "", "libusb":
+	case "webusb":
+		if c.Device.Bridge.Address != "" {
+			v.add("device.backend", "webusb can't be used with device.bridge")
+		}
+	case "hidapi":
+		// AOA is vendor control transfers to the R1, which isn't a HID
+		// device on the host
+		v.add("device.backend", "hidapi can't send the AOA requests the R1 needs; use libusb or webusb")
+	default:
+		v.add("device.backend", "%q is not one of libusb, webusb", c.Device.Backend)
+	}
+	for i, r := range c.Device.OffLimits {
+		field := fmt.Sprintf("device.off_limits[%d]", i)
+		v.checkRange(field+".x0", r.X0, 0, 32767)
+		v.checkRange(field+".y0", r.Y0, 0, 32767)
+		v.checkRange(field+".x1", r.X1, 0, 32767)
+		v.checkRange(field+".y1", r.Y1, 0, 32767)
+		if r.X0 > r.X1 || r.Y0 > r.Y1 {
+			v.add(field, "x0/y0 must be the top-left corner and x1/y1 the bottom-right")
+		}
+	}
+
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		v.add("log_level", "%v", err)
+	}
+	for i, p := range c.KeepAwakeSchedule {
+		field := fmt.Sprintf("keep_awake_schedule[%d]", i)
+		if _, err := p.Window(); err != nil {
+			v.add(field, "%v", err)
+		}
+		if p.SleepAfterMinutes != nil {
+			v.checkRange(field+".sleep_after_minutes", *p.SleepAfterMinutes, 0, 24*60)
+		}
+	}
+	for i, wt := range c.WakeSchedule {
+		if _, err := schedule.Parse(wt.At, wt.Days); err != nil {
+			v.add(fmt.Sprintf("wake_schedule[%d]", i), "%v", err)
+		}
+	}
+	names := make(map[string]bool)
+	for i, m := range c.Macros {
+		field := fmt.Sprintf("macros[%d]", i)
+		switch {
+		case strings.TrimSpace(m.Name) == "":
+			v.add(field+".name", "is empty")
+		case names[m.Name]:
+			v.add(field+".name", "%q is used by an earlier macro", m.Name)
+		}
+		names[m.Name] = true
+		for j, st := range m.Steps {
+			if st.Action == "" {
+				v.add(fmt.Sprintf("%s.steps[%d].action", field, j), "is empty")
+			}
+			v.checkRange(fmt.Sprintf("%s.steps[%d].delay_ms", field, j), st.DelayMs, 0, MaxMacroDelayMs)
+			v.checkRange(fmt.Sprintf("%s.steps[%d].retries", field, j), st.Retries, 0, MaxMacroRetries)
+			v.checkRange(fmt.Sprintf("%s.steps[%d].retry_delay_ms", field, j), st.RetryDelayMs, 0, MaxMacroDelayMs)
+			if err := st.If.Check(); err != nil {
+				v.add(fmt.Sprintf("%s.steps[%d].if", field, j), "%v", err)
+			}
+		}
+		for j, hk := range m.Hotkeys {
+			v.checkBinding(fmt.Sprintf("%s.hotkeys[%d]", field, j), hk.Modifiers, hk.Key)
+		}
+	}
+	cards := make(map[string]bool)
+	for i, card := range c.Launcher.Carousel {
+		field := fmt.Sprintf("launcher.carousel[%d]", i)
+		switch {
+		case strings.TrimSpace(card) == "":
+			v.add(field, "is empty")
+		case cards[strings.ToLower(card)]:
+			v.add(field, "%q is used by an earlier card", card)
+		}
+		cards[strings.ToLower(card)] = true
+	}
+	if len(c.Launcher.Carousel) > 0 {
+		v.checkRange("launcher.home", c.Launcher.Home, 0, len(c.Launcher.Carousel)-1)
+	}
+	v.checkRange("launcher.trust_seconds", c.Launcher.TrustSeconds, 0, MaxLauncherTrustSeconds)
+	apps := make(map[string]bool)
+	for i, a := range c.Launcher.Apps {
+		field := fmt.Sprintf("launcher.apps[%d]", i)
+		switch {
+		case strings.TrimSpace(a.Name) == "":
+			v.add(field+".name", "is empty")
+		case apps[strings.ToLower(a.Name)]:
+			v.add(field+".name", "%q is used by an earlier app", a.Name)
+		}
+		apps[strings.ToLower(a.Name)] = true
+		v.checkRange(field+".swipes", a.Swipes, -MaxLauncherSwipes, MaxLauncherSwipes)
+		v.checkRange(field+".x", int(a.X), 0, 32767)
+		v.checkRange(field+".y", int(a.Y), 0, 32767)
+		if a.Hotkey != nil {
+			v.checkBinding(field+".hotkey", a.Hotkey.Modifiers, a.Hotkey.Key)
+		}
+	}
+	for _, p := range c.Focus.Check() {
+		v.add("focus."+p.Field, "%s", p.Message)
+	}
+	profiles := make(map[string]bool)
+	for i, p := range c.Profiles {
+		field := fmt.Sprintf("profiles[%d]", i)
+		switch {
+		case strings.TrimSpace(p.Name) == "":
+			v.add(field+".name", "is empty")
+		case profiles[p.Name]:
+			v.add(field+".name", "%q is used by an earlier profile", p.Name)
+		}
+		profiles[p.Name] = true
+		if p.SleepAfterMinutes != nil {
+			v.checkRange(field+".sleep_after_minutes", *p.SleepAfterMinutes, 0, 24*60)
+		}
+		if p.PTTMode != nil {
+			v.checkOneOf(field+".ptt_mode", *p.PTTMode, "auto", "hold", "toggle")
+		}
+	}
+	for i, r := range c.ContextRules {
+		field := fmt.Sprintf("context_rules[%d]", i)
+		if !profiles[r.Profile] {
+			v.add(field+".profile", "no profile named %q", r.Profile)
+		}
+		if r.USB == "" && r.Displays == 0 && r.SSID == "" {
+			v.add(field, "needs at least one of usb, displays or ssid")
+		}
+		v.checkRange(field+".displays", r.Displays, 0, 16)
+	}
+	for i, m := range c.Macros {
+		for j, st := range m.Steps {
+			if st.If != nil && st.If.Profile != "" && !profiles[st.If.Profile] {
+				v.add(fmt.Sprintf("macros[%d].steps[%d].if.profile", i, j), "no profile named %q", st.If.Profile)
+			}
+		}
+	}
+	for i, qa := range c.QuickActions {
+		if strings.TrimSpace(qa.Label) == "" {
+			v.add(fmt.Sprintf("quick_actions[%d].label", i), "is empty")
+		}
+		if qa.Action == "" {
+			v.add(fmt.Sprintf("quick_actions[%d].action", i), "is empty")
+		}
+	}
+}
+
+// checkBinding reports a hotkey binding that can't be registered.
+func (v *validator) checkBinding(field string, modifiers []string, key string) {
+	for i, m := range modifiers {
+		switch strings.ToLower(m) {
+		case "ctrl", "shift", "alt", "super":
+		default:
+			v.add(fmt.Sprintf("%s.modifiers[%d]", field, i), "unknown modifier %q (available: ctrl, shift, alt, super)", m)
+		}
+	}
+	if key == "" {
+		v.add(field+".key", "is empty")
+		return
+	}
+	if HotkeyValidator != nil {
+		if err := HotkeyValidator(modifiers, key); err != nil {
+			v.add(field+".key", "%v", err)
+		}
+	}
+}
+
+// checkRange reports n outside [lo, hi].
+func (v *validator) checkRange(field string, n, lo, hi int) {
+	if n < lo || n > hi {
+		v.add(field, "%d is out of range (%d-%d)", n, lo, hi)
+	}
+}
+
+// checkOneOf reports s not in allowed.
+func (v *validator) checkOneOf(field, s string, allowed ...string) {
+	for _, a := range allowed {
+		if s == a {
+			return
+		}
+	}
+	v.add(field, "%q is not one of %s", s, strings.Join(allowed, ", "))
+}
@@ -0,0 +1,22 @@
+// Package container detects running inside a container such as Docker or
+// Podman, where there is no desktop and the R1 is reached through the
+// host's USB bus passed in.
+package container
+
+import "os"
+
+// markers are created by container runtimes in every container.
+var markers = []string{
+	"/.dockerenv",        // Docker
+	"/run/.containerenv", // Podman
+}
+
+// Detected reports whether the app runs inside a container.
+func Detected() bool {
+	for _, m := range markers {
+		if _, err := os.Stat(m); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+// Package crostini detects the Linux container of ChromeOS ("Crostini"),
+// where the app runs without a tray and reaches the R1 only once ChromeOS
+// shares it with Linux.
+package crostini
+
+import "os"
+
+// marker is created by ChromeOS in every Crostini container.
+const marker = "/dev/.cros_milestone"
+
+// SettingsPort is the settings page's port inside Crostini when none is
+// configured. ChromeOS only forwards fixed ports from the container to
+// Chrome.
+const SettingsPort = 8085
+
+// Detected reports whether the app runs inside Crostini.
+func Detected() bool {
+	_, err := os.Stat(marker)
+	return err == nil
+}
+
+// USBHint tells how to make the R1 visible inside Crostini.
+const USBHint = "R1 not visible to Linux — share it in ChromeOS under Settings → About ChromeOS → Developers → Linux → Manage USB devices, or set device.backend to webusb"
@@ -0,0 +1,78 @@
+package device
+
+import (
+	"context"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+)
+
+// compositeAliasBase is added to a report ID to make the HID ID the rest
+// of the Manager uses for that collection of a composite HID. It is far
+// above the IDs aoa assigns, which count up from 1 per connection.
+const compositeAliasBase = 0xFF00
+
+// compositeMembers are the descriptors the composite HID replaces.
+var compositeMembers = []aoa.DescriptorType{aoa.DescSystemControl, aoa.DescConsumerControl, aoa.DescTouchScreen}
+
+// compositeHID wraps a connection with one composite HID registered in
+// place of the System Control, Consumer Control and Touch Screen
+// descriptors, so Android creates one input device instead of three and
+// a connect waits out one settle delay. Registering a member returns an
+// alias ID without a registration, and reports to an alias go to the
+// composite HID with the member's report ID in front, so the rest of the
+// Manager sends to each member as to a HID of its own.
+type compositeHID struct {
+	Transport
+	id      uint16          // the composite HID
+	aliases map[uint16]byte // report ID by alias
+}
+
+// newCompositeHID registers the composite descriptor on dev.
+func newCompositeHID(dev Transport) (*compositeHID, error) {
+	id, err := dev.RegisterDescriptor(aoa.DescComposite)
+	if err != nil {
+		return nil, err
+	}
+	c := &compositeHID{Transport: dev, id: id, aliases: make(map[uint16]byte)}
+	for _, dt := range compositeMembers {
+		rid, _ := aoa.CompositeReportID(dt)
+		c.aliases[compositeAliasBase+uint16(rid)] = rid
+	}
+	return c, nil
+}
+
+func (c *compositeHID) RegisterDescriptor(dt aoa.DescriptorType) (uint16, error) {
+	if rid, ok := aoa.CompositeReportID(dt); ok {
+		return compositeAliasBase + uint16(rid), nil
+	}
+	return c.Transport.RegisterDescriptor(dt)
+}
+
+func (c *compositeHID) SendReportTo(hidID uint16, report []byte) error {
+	rid, ok := c.aliases[hidID]
+	if !ok {
+		return c.Transport.SendReportTo(hidID, report)
+	}
+	return c.Transport.SendReportTo(c.id, aoa.WithReportID(rid, report))
+}
+
+func (c *compositeHID) SendReportSequence(ctx context.Context, hidID uint16, seq []aoa.TimedReport) error {
+	rid, ok := c.aliases[hidID]
+	if !ok {
+		return c.Transport.SendReportSequence(ctx, hidID, seq)
+	}
+	prefixed := make([]aoa.TimedReport, len(seq))
+	for i, r := range seq {
+		prefixed[i] = aoa.TimedReport{At: r.At, Report: aoa.WithReportID(rid, r.Report)}
+	}
+	return c.Transport.SendReportSequence(ctx, c.id, prefixed)
+}
+
+// SetCompositeHID sets whether the next connection registers one
+// composite HID instead of a descriptor per input type. If the R1
+// refuses it, the connection falls back to separate descriptors.
+func (m *Manager) SetCompositeHID(on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.compositeHID = on
+}
@@ -0,0 +1,79 @@
+package device
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+)
+
+// CursorHiding selects how swipes keep the R1 from showing its touch
+// dot. Every strategy swipes along the bottom edge, where the dot is
+// least visible; the others add a measure for firmware builds where it
+// still flashes.
+type CursorHiding int
+
+const (
+	HideBottomEdge CursorHiding = iota // only swipe along the bottom edge
+	HideEdgeStart                      // start the drag at the screen's side edge
+	HideOutOfRange                     // after lifting, park the pointer off the screen
+	HideInRangeOff                     // report the finger out of range while it moves
+)
+
+// ParseCursorHiding parses a config value ("bottom_edge", "edge_start",
+// "out_of_range", "in_range_off").
+func ParseCursorHiding(s string) (CursorHiding, error) {
+	switch s {
+	case "bottom_edge", "":
+		return HideBottomEdge, nil
+	case "edge_start":
+		return HideEdgeStart, nil
+	case "out_of_range":
+		return HideOutOfRange, nil
+	case "in_range_off":
+		return HideInRangeOff, nil
+	default:
+		return HideBottomEdge, fmt.Errorf("unknown cursor hiding %q (want bottom_edge, edge_start, out_of_range or in_range_off)", s)
+	}
+}
+
+// parkDelay is how long after the lift an HideOutOfRange swipe moves the
+// pointer away.
+const parkDelay = 25 * time.Millisecond
+
+// SetCursorHiding sets how swipes hide the touch dot.
+func (m *Manager) SetCursorHiding(h CursorHiding) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cursorHiding = h
+}
+
+// swipeStartX returns where a swipe from startX begins: the screen's side
+// edge for HideEdgeStart, so the dot appears at the very edge if at all.
+// Must be called with m.mu held.
+func (m *Manager) swipeStartX(startX uint16, left bool) uint16 {
+	if m.cursorHiding != HideEdgeStart {
+		return startX
+	}
+	if left {
+		return 32767
+	}
+	return 0
+}
+
+// hideCursor applies the cursor hiding strategy to a swipe's reports,
+// which end with the lift. Must be called with m.mu held.
+func (m *Manager) hideCursor(seq []aoa.TimedReport) []aoa.TimedReport {
+	switch m.cursorHiding {
+	case HideInRangeOff:
+		for i := range seq[:len(seq)-1] {
+			r := append([]byte(nil), seq[i].Report...)
+			r[0] &^= 0x02 // In Range; the tip stays down
+			seq[i].Report = r
+		}
+	case HideOutOfRange:
+		last := seq[len(seq)-1]
+		seq = append(seq, aoa.TimedReport{At: last.At + parkDelay, Report: m.touchReport(false, 32767, 32767)})
+	}
+	return seq
+}
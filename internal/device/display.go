@@ -0,0 +1,129 @@
+package device
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/logging"
+)
+
+// Experimental display control. The R1 firmware isn't known to act on
+// these usages; they are exposed so users can try them and report what
+// their firmware does.
+
+// Consumer Control display usages.
+const (
+	UsageBrightnessUp   uint16 = 0x6F
+	UsageBrightnessDown uint16 = 0x70
+)
+
+// displayUsages names the usages accepted by DisplayKey.
+var displayUsages = map[string]uint16{
+	"brightness_up":   UsageBrightnessUp,
+	"brightness_down": UsageBrightnessDown,
+}
+
+// DisplayKey taps the named display control ("brightness_up" or
+// "brightness_down") on a Consumer Control descriptor, registered on
+// first use. Experimental: success only means the report was delivered.
+func (m *Manager) DisplayKey(name string) error {
+	usage, ok := displayUsages[name]
+	if !ok {
+		return fmt.Errorf("unknown display control %q (want brightness_up or brightness_down)", name)
+	}
+	m.interruptGesture()
+	return m.queue.do(prioGesture, func() error { return m.consumerTap(name, usage) })
+}
+
+// consumerTap implements DisplayKey on the action queue.
+func (m *Manager) consumerTap(name string, usage uint16) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dev == nil {
+		return fmt.Errorf("no device connected")
+	}
+
+	m.touchActivity() // reset idle timer
+	m.wake()
+
+	if err := m.consumerPress(name, usage); err != nil {
+		return err
+	}
+
+	log.Printf("[device] %s (usage 0x%02X, experimental)", name, usage)
+	m.actionDone(name, nil)
+	return nil
+}
+
+// consumerPress presses and releases usage on the Consumer Control
+// descriptor, registering it first if needed. Must be called with m.mu
+// held and a device connected.
+func (m *Manager) consumerPress(name string, usage uint16) error {
+	if m.ccHIDID == 0 {
+		id, err := m.dev.RegisterDescriptor(aoa.DescConsumerControl)
+		if err != nil {
+			return fmt.Errorf("consumer control HID register: %w", err)
+		}
+		m.ccHIDID = id
+	}
+
+	if err := m.dev.SendReportTo(m.ccHIDID, aoa.ConsumerReport(usage)); err != nil {
+		m.handleError(err)
+		return fmt.Errorf("%s down: %w", name, err)
+	}
+	time.Sleep(80 * time.Millisecond)
+	if err := m.dev.SendReportTo(m.ccHIDID, aoa.ConsumerReport(0)); err != nil {
+		m.handleError(err)
+		return fmt.Errorf("%s up: %w", name, err)
+	}
+	return nil
+}
+
+// Theater mode presses brightness down along with the keep-awake pings,
+// so the R1 can serve as a bedside clock without lighting up the room.
+// Whether it dims depends on the firmware acting on brightness_down.
+const (
+	dimPresses = 10 // presses to reach the lowest brightness from any level
+	dimRepeat  = 2  // presses with each later ping, in case the wake tap brightened it
+)
+
+// KeepAwakeDim reports whether theater mode applies at t, e.g. only in
+// the night's keep-awake period.
+type KeepAwakeDim func(t time.Time) bool
+
+// SetKeepAwakeDim sets when keep-awake also dims the screen. nil = never.
+func (m *Manager) SetKeepAwakeDim(dim KeepAwakeDim) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keepAwakeDim = dim
+}
+
+// theaterMode dims the screen after a keep-awake ping while theater mode
+// applies, and brings the brightness back once it no longer does or
+// keep-awake stops (awake false), so the R1 doesn't wake up dimmed. Must
+// be called with m.mu held and a device connected.
+func (m *Manager) theaterMode(awake bool) {
+	dim := awake && m.keepAwakeDim != nil && m.keepAwakeDim(time.Now())
+	name, usage, presses := "brightness_down", UsageBrightnessDown, dimRepeat
+	switch {
+	case dim && !m.dimmed:
+		presses = dimPresses
+		log.Printf("[device] theater mode — dimming the screen (experimental)")
+	case !dim && m.dimmed:
+		name, usage, presses = "brightness_up", UsageBrightnessUp, dimPresses
+		log.Printf("[device] theater mode off — restoring the brightness")
+	case !dim:
+		return
+	}
+	for range presses {
+		if err := m.consumerPress(name, usage); err != nil {
+			logging.Warnf("[device] theater mode: %v", err)
+			return
+		}
+		time.Sleep(40 * time.Millisecond)
+	}
+	m.dimmed = dim
+}
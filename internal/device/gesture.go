@@ -0,0 +1,187 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/gestures"
+)
+
+// Easing shapes how a gesture's finger speeds up and slows down along its
+// path. A perfectly even swipe is sometimes taken for an edge gesture by
+// the R1; easing and jitter make it look more like a finger.
+type Easing int
+
+const (
+	EaseLinear Easing = iota // constant speed
+	EaseInOut                // speeds up, then slows down
+	EaseOut                  // starts fast and slows down, like a flick
+)
+
+// ParseEasing parses a config value ("linear", "ease-in-out", "ease-out").
+func ParseEasing(s string) (Easing, error) {
+	switch s {
+	case "linear", "":
+		return EaseLinear, nil
+	case "ease-in-out":
+		return EaseInOut, nil
+	case "ease-out":
+		return EaseOut, nil
+	default:
+		return EaseLinear, fmt.Errorf("unknown gesture easing %q (want linear, ease-in-out or ease-out)", s)
+	}
+}
+
+// at maps t, the fraction of the gesture's time elapsed (0–1), to the
+// fraction of its distance covered.
+func (e Easing) at(t float64) float64 {
+	switch e {
+	case EaseInOut:
+		return (1 - math.Cos(math.Pi*t)) / 2
+	case EaseOut:
+		return 1 - (1-t)*(1-t)
+	default:
+		return t
+	}
+}
+
+// Jitter bounds: small enough to stay within the gesture's target, large
+// enough that no two swipes are identical.
+const (
+	jitterPos  = 120                  // ± touch units (of 0–32767) per point
+	jitterTime = 6 * time.Millisecond // ± per step, under half a step
+)
+
+// SetGestureStyle sets the easing of generated swipe paths and whether
+// their points and timing get a small random jitter.
+func (m *Manager) SetGestureStyle(easing Easing, jitter bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.easing = easing
+	m.jitter = jitter
+}
+
+// point is a touch screen position in the digitizer's 0–32767 range.
+type point struct {
+	x, y uint16
+}
+
+// gesturePath builds the reports for a finger moving from `from` to `to`
+// in steps, interval apart, then lifting where it ended. Jitter doesn't
+// move the ends into an off-limits region. Must be called with m.mu held.
+func (m *Manager) gesturePath(from, to point, steps int, interval time.Duration) []aoa.TimedReport {
+	seq := make([]aoa.TimedReport, 0, steps+2)
+	var last point
+	var at time.Duration
+	for i := 0; i <= steps; i++ {
+		d := m.easing.at(float64(i) / float64(steps))
+		p := point{
+			x: lerp(from.x, to.x, d),
+			y: lerp(from.y, to.y, d),
+		}
+		at = time.Duration(i) * interval
+		if m.jitter {
+			j := point{jitterCoord(p.x), jitterCoord(p.y)}
+			if (i > 0 && i < steps) || !m.isOffLimits(j) {
+				p = j
+			}
+			// The first touch goes out right away
+			if i > 0 {
+				at += time.Duration(rand.Int64N(int64(2*jitterTime))) - jitterTime
+			}
+		}
+		seq = append(seq, aoa.TimedReport{At: at, Report: m.touchReport(true, p.x, p.y)})
+		last = p
+	}
+	return append(seq, aoa.TimedReport{At: at, Report: m.touchReport(false, last.x, last.y)})
+}
+
+// lerp returns the point fraction d of the way from a to b.
+func lerp(a, b uint16, d float64) uint16 {
+	return uint16(math.Round(float64(a) + d*(float64(b)-float64(a))))
+}
+
+// jitterCoord moves v by up to jitterPos, staying on the screen.
+func jitterCoord(v uint16) uint16 {
+	j := int(v) + rand.IntN(2*jitterPos+1) - jitterPos
+	return uint16(min(max(j, 0), 32767))
+}
+
+// SetGestures sets the gesture preset library and the R1's firmware
+// version (e.g. "0.8.112", "" = unknown), which picks among presets made
+// for different RabbitOS layouts.
+func (m *Manager) SetGestures(lib *gestures.Library, firmware string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gestures = lib
+	m.firmware = firmware
+}
+
+// Gestures returns the gesture presets available for the R1's firmware.
+func (m *Manager) Gestures() []gestures.Preset {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gestures.For(m.firmware)
+}
+
+// Gesture runs the named preset from the gesture library, interrupting
+// any gesture in progress.
+func (m *Manager) Gesture(name string) error {
+	m.mu.Lock()
+	p, err := m.gestures.Find(name, m.firmware)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	ctx, done := m.beginGesture()
+	defer done()
+	return m.queue.do(prioGesture, func() error { return m.stroke(ctx, p) })
+}
+
+// strokeInterval is the time between touch points of a preset gesture.
+const strokeInterval = 25 * time.Millisecond
+
+// stroke implements Gesture on the action queue.
+func (m *Manager) stroke(ctx context.Context, p gestures.Preset) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("gesture %s interrupted: %w", p.Name, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dev == nil {
+		return fmt.Errorf("no device connected")
+	}
+
+	m.touchActivity()
+	m.wake()
+
+	from, to, err := m.placeGesture(point{p.From.X, p.From.Y}, point{p.To.X, p.To.Y})
+	if err != nil {
+		return fmt.Errorf("gesture %s: %w", p.Name, err)
+	}
+	duration := time.Duration(p.DurationMs) * time.Millisecond
+	steps := max(int(duration/strokeInterval), 2)
+	seq := m.gesturePath(from, to, steps, duration/time.Duration(steps))
+
+	if err := m.dev.SendReportSequence(ctx, m.touchHIDID, seq); err != nil {
+		// Lift the finger, as in swipe
+		liftErr := m.dev.SendReportTo(m.touchHIDID, m.touchReport(false, to.x, to.y))
+		if ctx.Err() != nil && liftErr == nil {
+			log.Printf("[device] gesture %s interrupted", p.Name)
+			return fmt.Errorf("gesture %s interrupted: %w", p.Name, err)
+		}
+		m.handleError(err)
+		return fmt.Errorf("gesture %s: %w", p.Name, err)
+	}
+
+	log.Printf("[device] gesture %s (%s v%d)", p.Name, p.Source, p.Version)
+	m.actionDone("gesture", map[string]string{"name": p.Name})
+	return nil
+}
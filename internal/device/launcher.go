@@ -0,0 +1,189 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// launcherSettle is how long the launcher gets to finish animating
+// between the steps of OpenApp.
+const launcherSettle = 400 * time.Millisecond
+
+// launcherCenter is the middle of the screen, where OpenApp taps unless
+// an app says otherwise.
+const launcherCenter = 16384
+
+// LauncherApp is a shortcut to an app in the RabbitOS launcher: the home
+// gesture, Swipes swipes across the home carousel, then a tap on the
+// app's card.
+type LauncherApp struct {
+	Name   string
+	Swipes int    // from the home card; positive swipes left, negative right; unused if the app is in the Carousel
+	X, Y   uint16 // where to tap; 0 = the middle of the screen
+}
+
+// SetLauncherApps sets the shortcuts OpenApp can open.
+func (m *Manager) SetLauncherApps(apps []LauncherApp) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.launcherApps = append([]LauncherApp(nil), apps...)
+}
+
+// LauncherApps returns the shortcuts OpenApp can open.
+func (m *Manager) LauncherApps() []LauncherApp {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]LauncherApp(nil), m.launcherApps...)
+}
+
+// Carousel models the RabbitOS home carousel, so OpenApp can swipe
+// straight from the card it last left to an app's card instead of going
+// home first.
+type Carousel struct {
+	Cards []string      // card names from left to right
+	Home  int           // index of the card the "home" gesture shows
+	Trust time.Duration // how long a tracked position is trusted; 0 = never
+}
+
+// SetCarousel sets the home carousel model and forgets the tracked
+// position.
+func (m *Manager) SetCarousel(c Carousel) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c.Cards = append([]string(nil), c.Cards...)
+	m.carousel = c
+	m.carouselPos = -1
+}
+
+// carouselTouched forgets the tracked carousel position after input
+// other than OpenApp's that may have moved the carousel or opened a card:
+// touches, typing, Enter and the wake key. Called
+// by actionDone with m.mu held.
+func (m *Manager) carouselTouched(action string) {
+	switch action {
+	case "swipe", "tap", "gesture", "type_text", "confirm", "wake":
+		if !m.openingApp {
+			m.carouselPos = -1
+		}
+	}
+}
+
+// OpenApp opens the named launcher app (case-insensitive): it leaves the
+// open app, swipes to the app's card and taps it, pausing for the
+// launcher's animations in between. Another action interrupts the
+// remaining steps.
+//
+// An app with a card in the carousel is reached from the card OpenApp
+// last left the carousel on, after the "back" gesture, while that
+// position is trusted. Otherwise, as for apps outside the carousel, the
+// "home" gesture first resets the carousel to its home card.
+func (m *Manager) OpenApp(name string) error {
+	m.mu.Lock()
+	var app *LauncherApp
+	for i := range m.launcherApps {
+		if strings.EqualFold(m.launcherApps[i].Name, name) {
+			app = &m.launcherApps[i]
+			break
+		}
+	}
+	if app == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("no launcher app %q", name)
+	}
+	a := *app
+	target := slices.IndexFunc(m.carousel.Cards, func(c string) bool { return strings.EqualFold(c, a.Name) })
+	trusted := target >= 0 && m.carouselPos >= 0 && time.Since(m.carouselAt) < m.carousel.Trust
+	first, from, swipes := "home", m.carousel.Home, a.Swipes
+	if trusted {
+		first, from = "back", m.carouselPos
+	}
+	if target >= 0 {
+		swipes = target - from
+	}
+	start, err := m.gestures.Find(first, m.firmware)
+	m.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("open %s: %w", a.Name, err)
+	}
+
+	ctx, done := m.beginGesture()
+	defer done()
+
+	steps := []func() error{func() error { return m.stroke(ctx, start) }}
+	dir := SwipeLeft
+	if swipes < 0 {
+		dir = SwipeRight
+	}
+	for range abs(swipes) {
+		steps = append(steps, func() error { return m.swipe(ctx, dir) })
+	}
+	x, y := a.X, a.Y
+	if x == 0 {
+		x = launcherCenter
+	}
+	if y == 0 {
+		y = launcherCenter
+	}
+	steps = append(steps, func() error {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("tap interrupted: %w", err)
+		}
+		return m.tap(x, y)
+	})
+
+	for i, step := range steps {
+		if i > 0 {
+			if err := sleepGesture(ctx, launcherSettle); err != nil {
+				return fmt.Errorf("open %s interrupted: %w", a.Name, err)
+			}
+		}
+		if err := m.queue.do(prioGesture, m.launcherStep(step)); err != nil {
+			m.mu.Lock()
+			m.carouselPos = -1
+			m.mu.Unlock()
+			return fmt.Errorf("open %s: %w", a.Name, err)
+		}
+	}
+
+	m.mu.Lock()
+	m.carouselPos, m.carouselAt = target, time.Now()
+	m.mu.Unlock()
+	if trusted {
+		log.Printf("[device] opened %s (%d swipes from card %d)", a.Name, abs(swipes), from)
+	} else {
+		log.Printf("[device] opened %s", a.Name)
+	}
+	return nil
+}
+
+// launcherStep wraps one of OpenApp's steps so its touches don't make
+// the carousel position unknown.
+func (m *Manager) launcherStep(step func() error) func() error {
+	return func() error {
+		m.mu.Lock()
+		m.openingApp = true
+		m.mu.Unlock()
+		defer func() {
+			m.mu.Lock()
+			m.openingApp = false
+			m.mu.Unlock()
+		}()
+		return step()
+	}
+}
+
+// sleepGesture waits for d, or returns early with ctx's error.
+func sleepGesture(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
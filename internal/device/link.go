@@ -0,0 +1,149 @@
+package device
+
+import (
+	"fmt"
+	"time"
+)
+
+// Link health thresholds. A docked R1 should stay enumerated at the same
+// speed indefinitely; repeated reconnects or a drop to a slower speed
+// usually mean a flaky cable, hub or dock.
+const (
+	renegotiateWindow = 10 * time.Minute
+	renegotiateLimit  = 3 // reconnects within renegotiateWindow before warning
+)
+
+// Health score: 100 minus penalties for trouble within
+// renegotiateWindow, floored at 0. Below unstableScore the connection is
+// reported as unstable even if no single check has tripped.
+const (
+	errorPenalty     = 15                    // per USB transfer error
+	reconnectPenalty = 20                    // per reconnect
+	slowPing         = 50 * time.Millisecond // a healthy link answers a ping in a few ms
+	slowPingPenalty  = 30                    // if every recent ping was slow
+	pingSamples      = 30                    // pings kept for the slow share (about a minute)
+	unstableScore    = 60
+)
+
+// speedRank orders negotiated bus speeds from slowest to fastest.
+var speedRank = map[string]int{"low": 1, "full": 2, "high": 3, "super": 4}
+
+// LinkInfo describes the negotiated USB link to the R1.
+type LinkInfo struct {
+	Location   string `json:"location,omitempty"`
+	Speed      string `json:"speed,omitempty"`
+	Configured bool   `json:"configured"`
+	PowerState string `json:"power_state,omitempty"` // "active" or "suspended"; empty if the OS doesn't report it
+	Reconnects int    `json:"reconnects"`            // within the last renegotiateWindow
+	Errors     int    `json:"errors"`                // USB transfer errors within the last renegotiateWindow
+	PingMs     int    `json:"ping_ms,omitempty"`     // mean of recent ping latencies
+	Health     int    `json:"health"`                // 0-100, from errors, reconnects and ping latency
+	Warning    string `json:"warning,omitempty"`
+}
+
+// linkMonitor tracks USB link changes across connections.
+type linkMonitor struct {
+	seen      bool            // a connection has been made this session
+	connects  []time.Time     // reconnect times within renegotiateWindow
+	errors    []time.Time     // transfer error times within renegotiateWindow
+	pings     []time.Duration // latest ping latencies, up to pingSamples
+	speed     string          // speed of the current connection
+	bestSpeed string          // fastest speed seen this session
+	power     string          // last observed power state
+	warning   string          // last reported warning
+}
+
+// connected records a new connection at the given speed.
+func (l *linkMonitor) connected(now time.Time, speed string) {
+	if l.seen {
+		l.connects = append(l.connects, now)
+	}
+	l.seen = true
+	l.speed = speed
+	l.power = ""
+	if speedRank[speed] > speedRank[l.bestSpeed] {
+		l.bestSpeed = speed
+	}
+}
+
+// failed records a USB transfer error.
+func (l *linkMonitor) failed(now time.Time) {
+	l.errors = append(l.errors, now)
+}
+
+// pinged records the latency of a successful ping.
+func (l *linkMonitor) pinged(d time.Duration) {
+	l.pings = append(l.pings, d)
+	if len(l.pings) > pingSamples {
+		l.pings = l.pings[len(l.pings)-pingSamples:]
+	}
+}
+
+// reconnects prunes and returns the number of recent reconnects.
+func (l *linkMonitor) reconnects(now time.Time) int {
+	l.connects = prune(l.connects, now)
+	return len(l.connects)
+}
+
+// transferErrors prunes and returns the number of recent transfer errors.
+func (l *linkMonitor) transferErrors(now time.Time) int {
+	l.errors = prune(l.errors, now)
+	return len(l.errors)
+}
+
+// prune drops the times older than renegotiateWindow.
+func prune(times []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(times) && now.Sub(times[i]) > renegotiateWindow {
+		i++
+	}
+	return times[i:]
+}
+
+// pingMean returns the mean of the recent ping latencies, or 0 if none.
+func (l *linkMonitor) pingMean() time.Duration {
+	if len(l.pings) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range l.pings {
+		sum += d
+	}
+	return sum / time.Duration(len(l.pings))
+}
+
+// health returns the link's health score, 0-100.
+func (l *linkMonitor) health(now time.Time) int {
+	score := 100 - errorPenalty*l.transferErrors(now) - reconnectPenalty*l.reconnects(now)
+	if len(l.pings) > 0 {
+		slow := 0
+		for _, d := range l.pings {
+			if d > slowPing {
+				slow++
+			}
+		}
+		score -= slowPingPenalty * slow / len(l.pings)
+	}
+	return max(score, 0)
+}
+
+// evaluate returns the current dock/cable warning, or "" if the link
+// looks healthy.
+func (l *linkMonitor) evaluate(now time.Time, connected bool) string {
+	if n := l.reconnects(now); n >= renegotiateLimit {
+		return fmt.Sprintf("R1 reconnected %d times in %v — dock/cable issue suspected", n, renegotiateWindow)
+	}
+	if !connected {
+		return ""
+	}
+	if l.power == "suspended" {
+		return "R1 USB link suspended unexpectedly — dock/cable issue suspected"
+	}
+	if speedRank[l.speed] < speedRank[l.bestSpeed] {
+		return fmt.Sprintf("R1 renegotiated at %s speed (was %s) — dock/cable issue suspected", l.speed, l.bestSpeed)
+	}
+	if h := l.health(now); h < unstableScore {
+		return fmt.Sprintf("R1 connection unstable (health %d/100) — check cable/hub", h)
+	}
+	return ""
+}
@@ -9,88 +9,208 @@ package device
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/events"
+	"github.com/HopIT-Hub/R1-Control/internal/gestures"
+	"github.com/HopIT-Hub/R1-Control/internal/logging"
 )
 
-// State represents the current device/PTT state.
-type State int
+// System Control HID reports.
+var (
+	powerDown = []byte{0x01} // System Power Down
+	powerUp   = []byte{0x00} // Release
+	wakeUp    = []byte{0x03} // System Wake Up
+)
+
+// Toggle/hold detection threshold.
+const DefaultToggleThreshold = 300 * time.Millisecond
+
+// PressMode selects how the PTT hotkey's press and release map to PTT.
+type PressMode int
 
 const (
-	Disconnected State = iota
-	Connected
-	PTTActive
+	PressAuto   PressMode = iota // short press toggles, hold talks until release
+	PressHold                    // talk only while held; never latches
+	PressToggle                  // every press toggles; release is ignored
 )
 
-func (s State) String() string {
+// ParsePressMode parses a config value ("auto", "hold", "toggle").
+func ParsePressMode(s string) (PressMode, error) {
 	switch s {
-	case Disconnected:
-		return "disconnected"
-	case Connected:
-		return "connected"
-	case PTTActive:
-		return "ptt_active"
+	case "auto", "":
+		return PressAuto, nil
+	case "hold":
+		return PressHold, nil
+	case "toggle":
+		return PressToggle, nil
 	default:
-		return "unknown"
+		return PressAuto, fmt.Errorf("unknown PTT mode %q (want auto, hold or toggle)", s)
 	}
 }
 
-// System Control HID reports.
-var (
-	powerDown = []byte{0x01} // System Power Down
-	powerUp   = []byte{0x00} // Release
-	wakeUp    = []byte{0x03} // System Wake Up
-)
-
-// Toggle/hold detection threshold.
-const toggleThreshold = 300 * time.Millisecond
+// connectTimeout bounds how long a connection attempt may take before it
+// is reported as stalled. libusb calls can't be interrupted, so a stalled
+// attempt keeps running in the background and its result is discarded.
+const connectTimeout = 5 * time.Second
 
 // Keep-awake defaults.
 const (
 	keepAwakeInterval = 25 * time.Second // beats R1's shortest 30s auto-sleep
+	keepAwakeGrace    = 2 * time.Second  // hold pings back this long after an action
 )
 
 // Manager handles the R1 USB device lifecycle.
 type Manager struct {
-	mu       sync.Mutex
-	dev      *aoa.Device
-	state    State
-	onChange func(State) // callback when state changes
-	serial   string      // optional serial filter
+	mu      sync.Mutex
+	dev     Transport
+	open    Opener       // opens dev; local USB unless SetOpener was called
+	remote  string       // describes a non-USB transport; "" = local USB
+	loc     aoa.Location // bus location of dev, to detect re-enumeration
+	state   State
+	bus     *events.Bus // state changes, actions, connects, problems and link warnings
+	problem string      // last connect failure shown to the user ("" = none)
+	info    Info        // identity of the connected (or last connected) R1
+	filter  aoa.Filter  // optional serial / port path pinning
 
 	// HID descriptor IDs (assigned on connect)
 	pttHIDID   uint16
 	touchHIDID uint16
+	kbdHIDID   uint16 // registered on first TypeText or Confirm; 0 = not yet
+	ccHIDID    uint16 // Consumer Control, registered on first use; 0 = not yet
 
 	// PTT toggle state
-	pttToggled   bool      // true if PTT is toggled on via short press
-	pttPressTime time.Time // when the hotkey was last pressed down
+	pttToggled   bool          // true if PTT is toggled on via short press
+	pttPressTime time.Time     // when the hotkey was last pressed down
+	latchedAt    time.Time     // when PTT was last latched on
+	maxLatch     time.Duration // auto-release latched PTT after this long (0 = never)
+	replayLatch  bool          // PTT was latched when the device dropped; restore on reconnect
+	pressMode    PressMode     // how hotkey presses map to PTT
+	toggleAfter  time.Duration // presses shorter than this toggle (PressAuto)
 
 	// Swipe direction state
-	swipeLeft bool // true = next swipe is left, false = right
+	swipeLeft       bool          // true = next swipe is left, false = right
+	lastSwipe       time.Time     // when the last swipe was sent
+	swipeResetAfter time.Duration // alternating starts over with left after this long idle (0 = never)
+	swipeKey        swipeKey      // swipe hotkey press state
+	cursorHiding    CursorHiding  // how swipes hide the touch dot
+
+	contact aoa.Contact // pressure and size of every touch
+	easing  Easing      // how swipes speed up and slow down
+	jitter  bool        // randomize swipe points and timing slightly
+
+	offLimits []Region // screen regions generated touches avoid
+
+	tracker           *inputTracker // wraps dev; touches and keys left pressed
+	releaseStuckAfter time.Duration // release a touch or key held this long
+	liftOnConnect     []byte        // lifts a touch held when the connection dropped
+	compositeHID      bool          // register one composite HID on connect
+
+	gestures *gestures.Library // named gesture presets
+	firmware string            // RabbitOS version picking among presets; "" = unknown
+
+	launcherApps []LauncherApp // shortcuts for OpenApp
+	carousel     Carousel
+	carouselPos  int       // card OpenApp last left the carousel on; -1 = unknown
+	carouselAt   time.Time // when carouselPos was set
+	openingApp   bool      // OpenApp's touches are running
+
+	// Cancels the gesture in progress, if any. Guarded by gestureMu rather
+	// than mu, since the gesture holds mu while it runs.
+	gestureMu     sync.Mutex
+	cancelGesture context.CancelFunc
+	gestureSeq    uint64 // identifies the gesture cancelGesture belongs to
 
 	// Keep-awake state
-	keepAwake         bool      // whether to send periodic wake pings
-	sleepAfterMinutes int       // 0 = never sleep
-	lastActivity      time.Time // last PTT/Swipe action time
-	sleeping          bool      // true when idle timer has expired
+	keepAwake         bool              // whether to send periodic wake pings
+	sleepAfterMinutes int               // 0 = never sleep
+	keepAwakeAt       KeepAwakeSchedule // nil = always on
+	inPeriod          bool              // keep-awake applied at the last ping
+	lastActivity      time.Time         // last PTT/Swipe action time
+	sleeping          bool              // true when idle timer has expired
+	pingDeferred      bool              // a keep-awake ping was held back; retry on poll
+	keepAwakeMisses   int               // keep-awake pings in a row that failed
+	keepAwakeDim      KeepAwakeDim      // theater mode; nil = never
+	dimmed            bool              // theater mode dimmed the screen on this connection
+	wokeAt            time.Time         // last wake sent, by an action or keep-awake
+
+	reconnectCh chan struct{} // Reconnect requests, handled by Run
+	queue       *actionQueue  // serializes device actions by priority
+	connecting  bool          // a connection attempt is running
+	closed      bool          // Close was called; discard late connections
+	backoff     time.Duration // last retry delay in Backoff; 0 after a connect
+	hiccupAt    time.Time     // last USB error ridden out without reconnecting
+	unsure      bool          // a transfer failed on the open connection since the last health check
+	retryAt     time.Time     // no connection attempts before this (Backoff)
+
+	// USB link health
+	link linkMonitor
 }
 
-// NewManager creates a new device manager.
-// onChange is called whenever the device state changes.
-func NewManager(serial string, onChange func(State)) *Manager {
-	return &Manager{
-		state:        Disconnected,
-		onChange:     onChange,
-		serial:      serial,
-		swipeLeft:    true, // first swipe will be left
-		keepAwake:    true, // default: keep device awake
-		sleepAfterMinutes: 60, // default: 1 hour
-		lastActivity: time.Now(),
+// NewManager creates a new device manager that publishes its events on
+// bus (nil = none): TypeState on every state change, TypeAction after
+// each successful action, TypeConnect when an R1 connects, TypeProblem
+// for connection problems (source "device") and TypeLink for dock/cable
+// warnings.
+func NewManager(filter aoa.Filter, bus *events.Bus) *Manager {
+	m := &Manager{
+		state:             Disconnected,
+		bus:               bus,
+		filter:            filter,
+		open:              usbOpener(filter),
+		swipeLeft:         true, // first swipe will be left
+		contact:           aoa.DefaultContact,
+		gestures:          gestures.Builtin(),
+		carouselPos:       -1,
+		releaseStuckAfter: DefaultReleaseStuckAfter,
+		keepAwake:         true, // default: keep device awake
+		sleepAfterMinutes: 60,   // default: 1 hour
+		lastActivity:      time.Now(),
+		toggleAfter:       DefaultToggleThreshold,
+		reconnectCh:       make(chan struct{}, 1),
+		queue:             newActionQueue(),
+	}
+	m.queue.onPanic = m.actionPanicked
+	return m
+}
+
+// actionPanicked reports a panic in a queued action and lets go of PTT
+// and every touch and key still held, so the R1's power key isn't left
+// down. Called on the queue's worker after the action unwound.
+func (m *Manager) actionPanicked(v any, stack []byte) {
+	log.Printf("[device] panic in a device action: %v\n%s", v, stack)
+	m.bus.Publish(events.Event{Type: events.TypeProblem, Name: "panic", Detail: fmt.Sprintf("A device action crashed: %v", v)})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dev == nil {
+		return
+	}
+	if m.state == PTTActive {
+		_ = m.dev.SendReportTo(m.pttHIDID, powerUp)
+	}
+	m.releaseAll()
+	m.pttToggled = false
+	if m.state == PTTActive {
+		m.setState(Connected)
+	}
+}
+
+// Reconnect drops the current connection (if any) and connects again
+// right away instead of waiting for the next poll. Useful after moving
+// the R1 to another port or changing USB permissions.
+func (m *Manager) Reconnect() {
+	select {
+	case m.reconnectCh <- struct{}{}:
+	default: // a reconnect is already pending
 	}
 }
 
@@ -105,6 +225,307 @@ func (m *Manager) SetKeepAwake(enabled bool, sleepAfterMinutes int) {
 	m.sleeping = false
 }
 
+// KeepAwakeSchedule reports whether keep-awake should run at t, e.g. only
+// on weekdays 09:00-17:00, and the idle limit then (-1 = the one set by
+// SetKeepAwake).
+type KeepAwakeSchedule func(t time.Time) (on bool, sleepAfterMinutes int)
+
+// SetKeepAwakeSchedule limits keep-awake to the times at reports as on.
+// The idle timer restarts when a period begins, so the R1 is kept awake
+// from its start. nil = always on.
+func (m *Manager) SetKeepAwakeSchedule(at KeepAwakeSchedule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keepAwakeAt = at
+}
+
+// keepAwakeNow returns whether keep-awake applies now and its idle limit
+// in minutes (0 = never sleep). Must be called with m.mu held.
+func (m *Manager) keepAwakeNow() (on bool, sleepAfterMinutes int) {
+	if !m.keepAwake {
+		return false, 0
+	}
+	if m.keepAwakeAt == nil {
+		return true, m.sleepAfterMinutes
+	}
+	on, sleepAfterMinutes = m.keepAwakeAt(time.Now())
+	if sleepAfterMinutes < 0 {
+		sleepAfterMinutes = m.sleepAfterMinutes
+	}
+	return on, sleepAfterMinutes
+}
+
+// keepingAwake is KeepingAwake without the connection check. Must be
+// called with m.mu held.
+func (m *Manager) keepingAwake() bool {
+	on, _ := m.keepAwakeNow()
+	return on && !m.sleeping
+}
+
+// Info identifies a connected R1.
+type Info struct {
+	Serial  string
+	Product string
+}
+
+// Info returns the identity of the connected R1, or of the last one
+// connected this session.
+func (m *Manager) Info() Info {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.info
+}
+
+// actionDone publishes a completed action ("swipe", "tap", "wake", …).
+// payload holds its arguments as the action package takes them (nil =
+// none), so the action can be repeated, e.g. by a recorded macro. Must be
+// called with m.mu held.
+func (m *Manager) actionDone(name string, payload any) {
+	m.carouselTouched(name)
+	e := events.Event{Type: events.TypeAction, Name: name}
+	if payload != nil {
+		if b, err := json.Marshal(payload); err == nil {
+			e.Value = json.RawMessage(b)
+		}
+	}
+	m.bus.Publish(e)
+}
+
+// stateChanged publishes a state change. Called by setState with m.mu
+// held.
+func (m *Manager) stateChanged(from, to State) {
+	m.bus.Publish(events.Event{Type: events.TypeState, Name: to.String(), Detail: from.String(), Value: to})
+}
+
+// KeepingAwake reports whether keep-awake pings are currently being sent
+// to a connected device (enabled and the idle timer hasn't expired).
+func (m *Manager) KeepingAwake() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dev != nil && m.keepingAwake()
+}
+
+// screenOnFor is how long the R1's screen is assumed to stay on after a
+// wake: its shortest auto-sleep setting.
+const screenOnFor = 30 * time.Second
+
+// ScreenAwake reports whether the R1's screen is on, as far as the app
+// can tell: the R1 can't be asked, so this means woken by the app less
+// than screenOnFor ago, or kept awake by keep-awake pings.
+func (m *Manager) ScreenAwake() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dev == nil {
+		return false
+	}
+	return time.Since(m.wokeAt) < screenOnFor || m.keepingAwake()
+}
+
+// Problem returns the current connection problem, or "" if none.
+func (m *Manager) Problem() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.problem
+}
+
+// setProblem records a connection problem the user should see (access
+// denied, HID registration failed), publishing changes; "" clears it.
+func (m *Manager) setProblem(p string) {
+	m.mu.Lock()
+	changed := p != m.problem
+	m.problem = p
+	m.mu.Unlock()
+
+	if changed {
+		m.bus.Publish(events.Event{Type: events.TypeProblem, Name: "device", Detail: p})
+	}
+}
+
+// Link returns diagnostics about the negotiated USB link.
+func (m *Manager) Link() LinkInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	info := LinkInfo{
+		Reconnects: m.link.reconnects(now),
+		Errors:     m.link.transferErrors(now),
+		PingMs:     int(m.link.pingMean().Milliseconds()),
+		Health:     m.link.health(now),
+		Warning:    m.link.warning,
+	}
+	if m.dev != nil {
+		info.Location = m.loc.String()
+		info.Speed = m.link.speed
+		info.Configured = m.dev.Configured()
+		info.PowerState = m.link.power
+	}
+	return info
+}
+
+// descriptorReader is a Transport that can read the R1's USB descriptors.
+type descriptorReader interface {
+	Descriptors() (*aoa.Descriptors, error)
+}
+
+// Descriptors reads the R1's USB device, configuration and string
+// descriptors, for compatibility reports. Only a local USB connection
+// can read them.
+func (m *Manager) Descriptors() (*aoa.Descriptors, error) {
+	var out *aoa.Descriptors
+	err := m.queue.do(prioGesture, func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.dev == nil {
+			return fmt.Errorf("no device connected")
+		}
+		t := m.tracker.Transport
+		if c, ok := t.(*compositeHID); ok {
+			t = c.Transport
+		}
+		r, ok := t.(descriptorReader)
+		if !ok {
+			return fmt.Errorf("USB descriptors can't be read through %s", m.remote)
+		}
+		var err error
+		out, err = r.Descriptors()
+		return err
+	})
+	return out, err
+}
+
+// LinkWarning returns the current dock/cable warning, or "" if the link
+// looks healthy.
+func (m *Manager) LinkWarning() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.link.warning
+}
+
+// checkLink samples the USB power state and re-evaluates link health,
+// reporting warning changes to the registered handler.
+func (m *Manager) checkLink() {
+	m.mu.Lock()
+	connected, loc, local := m.dev != nil, m.loc, m.remote == ""
+	m.mu.Unlock()
+
+	ps := ""
+	if connected && local {
+		ps = powerState(loc)
+	}
+
+	m.mu.Lock()
+	if m.dev != nil && ps != m.link.power {
+		if ps == "suspended" {
+			log.Printf("[device] R1 USB link suspended while connected")
+		}
+		m.link.power = ps
+	}
+	w := m.link.evaluate(time.Now(), m.dev != nil)
+	changed := w != m.link.warning
+	m.link.warning = w
+	m.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if w != "" {
+		log.Printf("[device] %s", w)
+	} else {
+		log.Println("[device] USB link healthy again")
+	}
+	m.bus.Publish(events.Event{Type: events.TypeLink, Name: "warning", Detail: w})
+}
+
+// SetMaxLatch configures how long a latched (toggled) PTT may stay on
+// before it is released automatically. 0 disables the safety.
+func (m *Manager) SetMaxLatch(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxLatch = d
+}
+
+// SetTouchContact sets the pressure and size of taps, swipes and
+// keep-awake touches.
+func (m *Manager) SetTouchContact(c aoa.Contact) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.contact = c
+}
+
+// touchReport builds a touch report with the configured contact. Must be
+// called with m.mu held.
+func (m *Manager) touchReport(tip bool, x, y uint16) []byte {
+	return aoa.TouchReportContact(tip, x, y, m.contact)
+}
+
+// SetPressMode sets how the PTT hotkey behaves. threshold is the longest
+// press that still toggles in PressAuto mode; 0 keeps the default.
+func (m *Manager) SetPressMode(mode PressMode, threshold time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if threshold <= 0 {
+		threshold = DefaultToggleThreshold
+	}
+	m.pressMode = mode
+	m.toggleAfter = threshold
+}
+
+// PTTMode reports how PTT is active: "latched" (toggled on), "held"
+// (key held down), or "" when PTT is off.
+func (m *Manager) PTTMode() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case m.state != PTTActive:
+		return ""
+	case m.pttToggled:
+		return "latched"
+	default:
+		return "held"
+	}
+}
+
+// LatchedSince returns when PTT was latched on, or the zero time if it
+// isn't latched.
+func (m *Manager) LatchedSince() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state != PTTActive || !m.pttToggled {
+		return time.Time{}
+	}
+	return m.latchedAt
+}
+
+// LatchDeadline returns when a latched PTT will be auto-released, or the
+// zero time if PTT is not latched or auto-release is disabled.
+func (m *Manager) LatchDeadline() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.pttToggled || m.maxLatch <= 0 {
+		return time.Time{}
+	}
+	return m.latchedAt.Add(m.maxLatch)
+}
+
+// checkLatchTimeout releases a latched PTT that has exceeded maxLatch.
+func (m *Manager) checkLatchTimeout() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dev == nil || !m.pttToggled || m.maxLatch <= 0 {
+		return
+	}
+	if time.Since(m.latchedAt) < m.maxLatch {
+		return
+	}
+
+	log.Printf("[device] PTT latched for %v — auto-releasing", m.maxLatch)
+	if err := m.setPTTLocked(false); err != nil {
+		log.Printf("[device] PTT auto-release failed: %v", err)
+	}
+}
+
 // touchActivity resets the idle timer. Must be called with m.mu held.
 func (m *Manager) touchActivity() {
 	m.lastActivity = time.Now()
@@ -141,138 +562,584 @@ func (m *Manager) Run(ctx context.Context) {
 			state := m.state
 			m.mu.Unlock()
 
-			if state == Disconnected {
-				m.tryConnect()
-			} else {
+			if state.Online() {
 				m.healthCheck()
+				m.checkLatchTimeout()
+				m.releaseStuck()
+				m.retryDeferredPing()
+			} else {
+				m.tryConnect()
 			}
+			m.checkLink()
+		case <-m.reconnectCh:
+			m.interruptGesture()
+			m.mu.Lock()
+			if m.dev != nil {
+				log.Println("[device] reconnect requested")
+				m.dropLocked(Disconnected)
+			} else if m.state == Backoff || m.state == Error {
+				m.setState(Disconnected) // retry now
+			}
+			m.mu.Unlock()
+			m.tryConnect()
 		case <-wakeTicker.C:
-			m.keepAwakePing()
+			m.queue.coalesce(m.keepAwakePing)
 		}
 	}
 }
 
+// retryDeferredPing re-queues a keep-awake ping that was held back
+// because the device was in use.
+func (m *Manager) retryDeferredPing() {
+	m.mu.Lock()
+	deferred := m.pingDeferred
+	m.mu.Unlock()
+	if deferred {
+		m.queue.coalesce(m.keepAwakePing)
+	}
+}
+
 // keepAwakePing sends a wake tap if keep-awake is enabled and the idle
 // timer hasn't expired. This prevents the R1 from auto-sleeping.
+// Pings are deferred while a user action is queued or running, or for a
+// short grace period after one, so the corner tap never lands in the
+// middle of a gesture.
 func (m *Manager) keepAwakePing() {
+	idle := m.queue.idleFor()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.pingDeferred = false
+	if idle < keepAwakeGrace {
+		m.pingDeferred = true
+		return
+	}
+
 	// Only ping if connected (not during PTT active — screen is already on)
 	if m.dev == nil || m.state != Connected {
 		return
 	}
 
-	if !m.keepAwake {
+	on, sleepAfterMinutes := m.keepAwakeNow()
+	if on != m.inPeriod && m.keepAwakeAt != nil && m.keepAwake {
+		if on {
+			log.Printf("[device] keep-awake period started")
+			m.lastActivity = time.Now()
+			m.sleeping = false
+		} else {
+			log.Printf("[device] keep-awake period ended — letting device sleep")
+		}
+	}
+	m.inPeriod = on
+	if !on {
+		m.theaterMode(false)
 		return
 	}
 
 	// Check idle timer (0 = never sleep)
-	if m.sleepAfterMinutes > 0 {
-		idleLimit := time.Duration(m.sleepAfterMinutes) * time.Minute
+	if sleepAfterMinutes > 0 {
+		idleLimit := time.Duration(sleepAfterMinutes) * time.Minute
 		if time.Since(m.lastActivity) >= idleLimit {
 			if !m.sleeping {
 				m.sleeping = true
 				log.Printf("[device] idle for %v — letting device sleep", idleLimit)
 			}
+			m.theaterMode(false)
 			return
 		}
 	}
 
+	logging.Debugf("[device] keep-awake ping")
+	err := m.sendKeepAwake()
+	if err != nil && !aoa.IsGone(err) {
+		// The R1 may be asleep already: press the wake key again first
+		logging.Warnf("[device] keep-awake ping failed (%v) — retrying with the wake key", err)
+		m.link.failed(time.Now())
+		m.unsure = true
+		time.Sleep(keepAwakeRetryDelay)
+		err = m.sendKeepAwake()
+	}
+	if aoa.IsGone(err) {
+		return // the health check reconnects
+	}
+	m.keepAwakeResult(err)
+	if err == nil {
+		m.dev.ConfirmSettle()
+		m.theaterMode(true)
+	}
+}
+
+// Keep-awake ping verification.
+const (
+	// keepAwakeSlow is how long the reports of one keep-awake ping may
+	// take to send: the R1's own USB timeout. Slower than that, the R1 is
+	// likely suspending and may not have acted on them.
+	keepAwakeSlow = time.Second
+
+	// keepAwakeRetryDelay is the pause before retrying a failed ping.
+	keepAwakeRetryDelay = 500 * time.Millisecond
+
+	// keepAwakeMissLimit is how many keep-awake pings in a row may fail,
+	// retry included, before the user is warned.
+	keepAwakeMissLimit = 2
+)
+
+// sendKeepAwake wakes the R1 and taps the keep-awake spot, moved out of
+// the off-limits regions. Must be called with m.mu held.
+func (m *Manager) sendKeepAwake() error {
+	var tap func(tip bool) []byte
+	if spot, ok := m.place(keepAwakeSpot); ok {
+		tap = func(tip bool) []byte { return m.touchReport(tip, spot.x, spot.y) }
+	} else {
+		// Waking without a touch still helps on most firmware
+		logging.Debugf("[device] keep-awake spot is off-limits — skipping the tap")
+	}
+	err := SendKeepAwake(m.dev, m.pttHIDID, m.touchHIDID, tap)
+	if err == nil {
+		m.wokeAt = time.Now()
+	}
+	return err
+}
+
+// SendKeepAwake sends one keep-awake ping on dev: it wakes the R1 on the
+// System Control HID pttID and, unless tap is nil, taps the touch screen
+// HID touchID with the reports tap returns for the finger down and
+// lifted. It then checks that the R1 took it: every report sent without
+// error and in time, and the R1 still answering afterwards. The bridge
+// agent pings with it while no app is connected.
+func SendKeepAwake(dev Transport, pttID, touchID uint16, tap func(tip bool) []byte) error {
+	var busy time.Duration
+	send := func(hidID uint16, report []byte) error {
+		start := time.Now()
+		err := dev.SendReportTo(hidID, report)
+		busy += time.Since(start)
+		return err
+	}
+
 	// Two-step keep-alive:
 	// 1. System Wake Up — wakes the screen if the device is sleeping
 	// 2. Touch tap — resets the R1's sleep countdown timer
 	//    (Wake Up alone doesn't count as "user interaction")
-	_ = m.dev.SendReportTo(m.pttHIDID, wakeUp)
+	if err := send(pttID, wakeUp); err != nil {
+		return fmt.Errorf("wake: %w", err)
+	}
 	time.Sleep(50 * time.Millisecond)
-	_ = m.dev.SendReportTo(m.pttHIDID, powerUp)
-	time.Sleep(150 * time.Millisecond) // let the screen come on before touching
+	if err := send(pttID, powerUp); err != nil {
+		return fmt.Errorf("wake release: %w", err)
+	}
 
-	touchDown := aoa.TouchReport(true, 32590, 32590) // bottom-right corner
-	touchUp := aoa.TouchReport(false, 32590, 32590)
-	_ = m.dev.SendReportTo(m.touchHIDID, touchDown)
-	time.Sleep(30 * time.Millisecond)
-	_ = m.dev.SendReportTo(m.touchHIDID, touchUp)
+	if tap != nil {
+		time.Sleep(150 * time.Millisecond) // let the screen come on before touching
+		if err := send(touchID, tap(true)); err != nil {
+			return fmt.Errorf("tap: %w", err)
+		}
+		time.Sleep(30 * time.Millisecond)
+		if err := send(touchID, tap(false)); err != nil {
+			return fmt.Errorf("tap release: %w", err)
+		}
+	}
+
+	if err := dev.Ping(); err != nil {
+		return fmt.Errorf("no answer afterwards: %w", err)
+	}
+	if busy > keepAwakeSlow {
+		return fmt.Errorf("took %v to send", busy.Round(time.Millisecond))
+	}
+	return nil
 }
 
-// tryConnect attempts to open the R1 and register HID descriptors.
+// keepAwakeResult counts keep-awake pings that failed even when retried,
+// warning once keepAwakeMissLimit failed in a row, until one gets
+// through. Must be called with m.mu held.
+func (m *Manager) keepAwakeResult(err error) {
+	if err == nil {
+		if m.keepAwakeMisses >= keepAwakeMissLimit {
+			log.Printf("[device] keep-awake pings are getting through again")
+			m.setKeepAwakeProblem("")
+		}
+		m.keepAwakeMisses = 0
+		return
+	}
+	m.keepAwakeMisses++
+	logging.Warnf("[device] keep-awake ping failed again: %v", err)
+	if m.keepAwakeMisses == keepAwakeMissLimit {
+		m.setKeepAwakeProblem("Keep-awake pings aren't reaching the R1, so it may fall asleep — try another cable or USB port")
+	}
+}
+
+// setKeepAwakeProblem publishes a keep-awake problem; "" clears it.
+func (m *Manager) setKeepAwakeProblem(p string) {
+	m.bus.Publish(events.Event{Type: events.TypeProblem, Name: "keep_awake", Detail: p})
+}
+
+// tryConnect starts a connection attempt in the background unless one is
+// already running or Backoff is waiting to retry, so a stalled USB stack
+// can't hold up the Run loop (health checks, latch timeout, keep-awake)
+// or device actions.
 func (m *Manager) tryConnect() {
-	dev, err := aoa.Open(m.serial)
+	m.mu.Lock()
+	if m.connecting || m.closed || m.dev != nil || time.Now().Before(m.retryAt) {
+		m.mu.Unlock()
+		return
+	}
+	m.connecting = true
+	m.mu.Unlock()
+
+	go func() {
+		defer func() {
+			m.mu.Lock()
+			m.connecting = false
+			m.mu.Unlock()
+		}()
+
+		done := make(chan *openResult, 1)
+		go func() { done <- m.openDevice() }()
+
+		select {
+		case r := <-done:
+			m.finishConnect(r)
+		case <-time.After(connectTimeout):
+			log.Printf("[device] connection attempt stalled for %v", connectTimeout)
+			m.setProblem("USB stack not responding — try replugging the R1")
+			// Wait it out so stalled attempts don't pile up
+			if r := <-done; r != nil {
+				r.dev.Close()
+				m.mu.Lock()
+				m.setState(Error)
+				m.mu.Unlock()
+			}
+			log.Println("[device] stalled connection attempt returned — discarded")
+		}
+	}()
+}
+
+// openResult is a device opened by openDevice with its HID IDs.
+type openResult struct {
+	dev     Transport
+	pttID   uint16
+	touchID uint16
+	info    Info
+}
+
+// openDevice opens the R1 and registers HID descriptors, moving through
+// Connecting and Registering. Returns nil if no device is available;
+// failures are reported via setProblem and move to Backoff.
+func (m *Manager) openDevice() *openResult {
+	m.mu.Lock()
+	open, remote, composite := m.open, m.remote, m.compositeHID
+	m.mu.Unlock()
+
+	if !m.present(remote) {
+		m.setProblem("")
+		m.mu.Lock()
+		m.setState(Disconnected)
+		m.mu.Unlock()
+		return nil // device not found, will retry
+	}
+	m.mu.Lock()
+	err := m.setState(Connecting)
+	m.mu.Unlock()
+	if err != nil {
+		return nil
+	}
+
+	dev, err := open()
+	if err != nil {
+		if remote != "" {
+			logging.Debugf("[device] open %s: %v", remote, err)
+			m.connectFailed(fmt.Sprintf("R1 via %s not available: %v", remote, err))
+			return nil
+		}
+		if aoa.IsAccessDenied(err) {
+			if m.Problem() == "" {
+				logging.Warnf("[device] R1 access denied: %v", err)
+			}
+			m.connectFailed("R1 found but USB access was denied — check device permissions")
+			return nil
+		}
+		if aoa.IsNoUSB(err) {
+			if m.Problem() == "" {
+				logging.Warnf("[device] %v", err)
+			}
+			m.connectFailed("No USB access — in a container, pass the host's USB bus with --device /dev/bus/usb")
+			return nil
+		}
+		logging.Debugf("[device] open %s: %v", m.filter, err)
+		m.connectFailed("") // e.g. not the pinned serial, or just unplugged
+		return nil
+	}
+	logging.Debugf("[device] opened R1 at %s, registering HID descriptors", dev.Location())
+	m.mu.Lock()
+	err = m.setState(Registering)
+	m.mu.Unlock()
 	if err != nil {
-		return // device not found, will retry
+		dev.Close()
+		return nil
+	}
+
+	// With a composite HID, the registrations below return its aliases
+	if composite {
+		if c, err := newCompositeHID(dev); err == nil {
+			dev = c
+		} else {
+			logging.Warnf("[device] composite HID register failed, registering separate descriptors: %v", err)
+		}
 	}
 
 	// Register System Control descriptor for PTT (Power key)
 	pttID, err := dev.RegisterDescriptor(aoa.DescSystemControl)
 	if err != nil {
-		log.Printf("[device] PTT HID register failed: %v", err)
+		logging.Errorf("[device] PTT HID register failed: %v", err)
 		dev.Close()
-		return
+		m.connectFailed("R1 HID registration failed: " + err.Error())
+		return nil
 	}
 
 	// Register Touch Screen descriptor for swipe gestures
 	touchID, err := dev.RegisterDescriptor(aoa.DescTouchScreen)
 	if err != nil {
-		log.Printf("[device] Touch HID register failed: %v", err)
+		logging.Errorf("[device] Touch HID register failed: %v", err)
 		dev.Close()
+		m.connectFailed("R1 HID registration failed: " + err.Error())
+		return nil
+	}
+	m.setProblem("")
+	logging.Debugf("[device] HID IDs: PTT %d, touch %d", pttID, touchID)
+
+	return &openResult{
+		dev:     dev,
+		pttID:   pttID,
+		touchID: touchID,
+		info:    Info{Serial: dev.Serial(), Product: dev.Product()},
+	}
+}
+
+// present reports whether an R1 the filter matches is attached, without
+// opening it, so polling for an unplugged R1 doesn't go through
+// Connecting every time. Other transports can't tell and always try.
+func (m *Manager) present(remote string) bool {
+	if remote != "" {
+		return true
+	}
+	locs, err := aoa.Locate()
+	if err != nil {
+		return true // let open report it
+	}
+	for _, l := range locs {
+		if m.filter.PortPath == "" || l.PortPath() == m.filter.PortPath {
+			return true
+		}
+	}
+	return false
+}
+
+// connectFailed shows problem ("" = none) and backs off before the next
+// attempt, in Error if there is a problem to show.
+func (m *Manager) connectFailed(problem string) {
+	m.setProblem(problem)
+	next := Backoff
+	if problem != "" {
+		next = Error
+	}
+	m.mu.Lock()
+	m.setState(next)
+	m.mu.Unlock()
+}
+
+// ConnectOnce connects synchronously, for one-shot use without Run.
+func (m *Manager) ConnectOnce() error {
+	r := m.openDevice()
+	if r == nil {
+		if p := m.Problem(); p != "" {
+			return errors.New(p)
+		}
+		return errors.New("no R1 found")
+	}
+	m.finishConnect(r)
+	return nil
+}
+
+// finishConnect adopts an opened device and announces the connection.
+func (m *Manager) finishConnect(r *openResult) {
+	if r == nil {
 		return
 	}
+	dev, pttID, touchID := r.dev, r.pttID, r.touchID
 
 	m.mu.Lock()
-	m.dev = dev
-	m.state = Connected
+	if m.closed || m.dev != nil || m.setState(Connected) != nil {
+		m.mu.Unlock()
+		dev.Close()
+		return
+	}
+	m.tracker = newInputTracker(dev, touchID)
+	m.dev = m.tracker
+	m.loc = dev.Location()
 	m.pttHIDID = pttID
 	m.touchHIDID = touchID
+	m.kbdHIDID = 0
+	m.ccHIDID = 0
+	m.liftStuckTouch()
 	m.pttToggled = false
+	m.unsure = false
 	m.lastActivity = time.Now()
 	m.sleeping = false
+	m.link.connected(time.Now(), dev.Speed())
+	m.info = r.info
+	loc := m.loc
+	remote := m.remote
 	m.mu.Unlock()
 
-	log.Println("[device] R1 connected")
-	if m.onChange != nil {
-		m.onChange(Connected)
+	switch {
+	case remote != "" && dev.Speed() == "": // e.g. WebUSB, which doesn't expose the bus
+		log.Printf("[device] R1 connected via %s", remote)
+	case remote != "":
+		log.Printf("[device] R1 connected via %s (%s, %s speed)", remote, loc, dev.Speed())
+	default:
+		log.Printf("[device] R1 connected (%s, %s speed)", loc, dev.Speed())
 	}
+	m.bus.Publish(events.Event{Type: events.TypeConnect, Name: r.info.Serial, Detail: r.info.Product, Value: r.info})
 
 	// Immediately wake the device on connect if keep-awake is enabled
 	m.keepAwakePing()
+
+	m.replayLatchedPTT()
 }
 
-// healthCheck verifies the device is still connected.
-func (m *Manager) healthCheck() {
+// replayLatchedPTT restores a PTT latch that was active when the device
+// dropped, so a reset mid-conversation doesn't silently end it. The
+// original latch time is kept so the auto-release safety still applies.
+func (m *Manager) replayLatchedPTT() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if !m.replayLatch || m.dev == nil {
+		return
+	}
+	m.replayLatch = false
+
+	if m.maxLatch > 0 && time.Since(m.latchedAt) >= m.maxLatch {
+		log.Printf("[device] latched PTT expired while disconnected — not restoring")
+		return
+	}
+
+	latchedAt := m.latchedAt
+	if err := m.setPTTLocked(true); err != nil {
+		log.Printf("[device] restoring latched PTT failed: %v", err)
+		return
+	}
+	m.latchedAt = latchedAt
+	log.Println("[device] latched PTT restored after reconnect")
+}
+
+// healthCheck verifies the device is still connected and, after a
+// failed transfer, still at the bus location it was opened at. If the R1
+// re-enumerated (reboot, USB reset), the HID descriptors registered on
+// the old handle are gone, so the connection is dropped and
+// re-established right away instead of waiting for the next poll. A
+// healthy connection isn't checked against the bus, which would
+// enumerate every device on it at each poll.
+func (m *Manager) healthCheck() {
+	m.mu.Lock()
 	if m.dev == nil {
+		m.mu.Unlock()
 		return
 	}
+	loc := m.loc
+	local := m.remote == ""
+	start := time.Now()
+	err := m.dev.Ping()
+	if err == nil && local { // a bridge's pings cross the network
+		m.link.pinged(time.Since(start))
+	}
+	if err != nil && m.hiccup(err) {
+		m.link.failed(time.Now())
+		m.unsure = true
+		err = m.retryPing()
+	}
+	unsure := m.unsure
+	m.unsure = false
+	m.mu.Unlock()
 
-	if err := m.dev.Ping(); err != nil {
+	switch {
+	case err != nil:
 		log.Printf("[device] R1 disconnected: %v", err)
-		m.dev.Close()
-		m.dev = nil
-		m.state = Disconnected
-		m.pttToggled = false
-		if m.onChange != nil {
-			m.onChange(Disconnected)
+	case local && unsure && m.reenumerated(loc):
+		log.Printf("[device] R1 re-enumerated (was %s) — re-registering", loc)
+	default:
+		return
+	}
+
+	m.mu.Lock()
+	m.dropLocked(Disconnected)
+	m.mu.Unlock()
+
+	m.tryConnect()
+}
+
+// reenumerated reports whether an R1 is present on the bus but none of
+// them is at loc any more. Enumeration errors are treated as "no change".
+func (m *Manager) reenumerated(loc aoa.Location) bool {
+	locs, err := aoa.Locate()
+	if err != nil || len(locs) == 0 {
+		return false
+	}
+	for _, l := range locs {
+		if l.Equal(loc) {
+			return false
 		}
 	}
+	return true
 }
 
-// wake sends a System Wake Up tap to ensure the R1 screen is on.
-// Must be called with m.mu held and m.dev != nil.
-func (m *Manager) wake() {
+// wake sends a System Wake Up tap to ensure the R1 screen is on. Most
+// callers wake best-effort and ignore the error. Must be called with
+// m.mu held and m.dev != nil.
+func (m *Manager) wake() error {
 	// Send wake-up key tap: down then up
 	if err := m.dev.SendReportTo(m.pttHIDID, wakeUp); err != nil {
-		return // best-effort, don't fail the caller
+		return fmt.Errorf("wake down: %w", err)
 	}
 	time.Sleep(50 * time.Millisecond)
-	_ = m.dev.SendReportTo(m.pttHIDID, powerUp)
+	if err := m.dev.SendReportTo(m.pttHIDID, powerUp); err != nil {
+		return fmt.Errorf("wake up: %w", err)
+	}
+	m.wokeAt = time.Now()
 	time.Sleep(100 * time.Millisecond) // give the screen time to turn on
+	return nil
+}
+
+// Wake sends a System Wake Up tap to turn the R1 screen on.
+func (m *Manager) Wake() error {
+	m.interruptGesture()
+	return m.queue.do(prioGesture, m.wakeAction)
+}
+
+// wakeAction implements Wake on the action queue.
+func (m *Manager) wakeAction() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dev == nil {
+		return fmt.Errorf("no device connected")
+	}
+
+	m.touchActivity() // reset idle timer
+	m.wake()
+	m.actionDone("wake", nil)
+	return nil
 }
 
 // PTTDown is called when the PTT hotkey is pressed down.
 // Implements toggle/hold: short press toggles, hold activates until release.
 func (m *Manager) PTTDown() error {
+	m.interruptGesture()
+	return m.queue.do(prioPTT, m.pttDown)
+}
+
+// pttDown implements PTTDown on the action queue.
+func (m *Manager) pttDown() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -283,6 +1150,10 @@ func (m *Manager) PTTDown() error {
 	m.pttPressTime = time.Now()
 	m.touchActivity() // reset idle timer
 
+	if m.pressMode == PressToggle {
+		return m.setPTTLocked(m.state != PTTActive)
+	}
+
 	if m.pttToggled {
 		// PTT is already on from toggle — don't re-send key down
 		return nil
@@ -297,16 +1168,20 @@ func (m *Manager) PTTDown() error {
 		return err
 	}
 
-	m.state = PTTActive
-	if m.onChange != nil {
-		m.onChange(PTTActive)
-	}
+	m.setState(PTTActive)
 	return nil
 }
 
 // PTTUp is called when the PTT hotkey is released.
 // Short press (<300ms) toggles PTT on/off; long press releases PTT.
+// In hold-only mode every release ends PTT; in toggle-only mode releases
+// are ignored.
 func (m *Manager) PTTUp() error {
+	return m.queue.do(prioPTT, m.pttUp)
+}
+
+// pttUp implements PTTUp on the action queue.
+func (m *Manager) pttUp() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -314,9 +1189,13 @@ func (m *Manager) PTTUp() error {
 		return fmt.Errorf("no device connected")
 	}
 
+	if m.pressMode == PressToggle {
+		return nil
+	}
+
 	duration := time.Since(m.pttPressTime)
 
-	if duration < toggleThreshold {
+	if m.pressMode == PressAuto && duration < m.toggleAfter {
 		// Short press — toggle
 		if m.pttToggled {
 			// Toggle OFF
@@ -325,13 +1204,11 @@ func (m *Manager) PTTUp() error {
 				m.handleError(err)
 				return err
 			}
-			m.state = Connected
-			if m.onChange != nil {
-				m.onChange(Connected)
-			}
+			m.setState(Connected)
 		} else {
 			// Toggle ON — leave PTT active
 			m.pttToggled = true
+			m.latchedAt = time.Now()
 		}
 		return nil
 	}
@@ -343,17 +1220,296 @@ func (m *Manager) PTTUp() error {
 		return err
 	}
 
-	m.state = Connected
-	if m.onChange != nil {
-		m.onChange(Connected)
+	m.setState(Connected)
+	return nil
+}
+
+// SetPTT explicitly latches PTT on or releases it, bypassing the
+// toggle/hold timing used by the hotkey. Used by the HTTP API where
+// press duration has no meaning.
+func (m *Manager) SetPTT(active bool) error {
+	m.interruptGesture()
+	return m.queue.do(prioPTT, func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.setPTTLocked(active)
+	})
+}
+
+// HoldPTT turns PTT on like a held hotkey, without latching it: PTTMode
+// reports "held" and it isn't auto-released. It reports whether this call
+// turned PTT on; PTT that was already on, held or latched, is left as it
+// is. ReleaseHeldPTT ends the press.
+func (m *Manager) HoldPTT() (started bool, err error) {
+	m.interruptGesture()
+	err = m.queue.do(prioPTT, func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if m.dev == nil {
+			return fmt.Errorf("no device connected")
+		}
+		m.touchActivity() // reset idle timer
+		if m.state == PTTActive {
+			return nil
+		}
+		m.wake()
+		if err := m.dev.SendReportTo(m.pttHIDID, powerDown); err != nil {
+			m.handleError(err)
+			return err
+		}
+		m.pttToggled = false
+		m.setState(PTTActive)
+		started = true
+		return nil
+	})
+	return started, err
+}
+
+// ReleaseHeldPTT releases PTT turned on by HoldPTT, unless it has been
+// latched since.
+func (m *Manager) ReleaseHeldPTT() error {
+	return m.queue.do(prioPTT, func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if m.dev == nil || m.state != PTTActive || m.pttToggled {
+			return nil
+		}
+		if err := m.dev.SendReportTo(m.pttHIDID, powerUp); err != nil {
+			m.handleError(err)
+			return err
+		}
+		m.setState(Connected)
+		return nil
+	})
+}
+
+// TogglePTT latches PTT on if it is off, or releases it if it is on.
+func (m *Manager) TogglePTT() error {
+	m.interruptGesture()
+	return m.queue.do(prioPTT, func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.setPTTLocked(m.state != PTTActive)
+	})
+}
+
+// setPTTLocked implements SetPTT. Must be called with m.mu held.
+func (m *Manager) setPTTLocked(active bool) error {
+	if m.dev == nil {
+		return fmt.Errorf("no device connected")
+	}
+
+	m.touchActivity() // reset idle timer
+
+	if active {
+		if m.state == PTTActive {
+			if !m.pttToggled {
+				m.pttToggled = true
+				m.latchedAt = time.Now()
+			}
+			return nil
+		}
+		m.wake()
+		if err := m.dev.SendReportTo(m.pttHIDID, powerDown); err != nil {
+			m.handleError(err)
+			return err
+		}
+		m.pttToggled = true
+		m.latchedAt = time.Now()
+		m.setState(PTTActive)
+		return nil
+	}
+
+	m.pttToggled = false
+	if m.state != PTTActive {
+		return nil
+	}
+	if err := m.dev.SendReportTo(m.pttHIDID, powerUp); err != nil {
+		m.handleError(err)
+		return err
+	}
+	m.setState(Connected)
+	return nil
+}
+
+// Tap sends a single touch tap at the given digitizer coordinates
+// (0-32767 on both axes).
+func (m *Manager) Tap(x, y uint16) error {
+	m.interruptGesture()
+	return m.queue.do(prioGesture, func() error { return m.tap(x, y) })
+}
+
+// tap implements Tap on the action queue.
+func (m *Manager) tap(x, y uint16) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dev == nil {
+		return fmt.Errorf("no device connected")
+	}
+
+	m.touchActivity() // reset idle timer
+	m.wake()
+
+	if err := m.dev.SendReportTo(m.touchHIDID, m.touchReport(true, x, y)); err != nil {
+		m.handleError(err)
+		return fmt.Errorf("tap down: %w", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := m.dev.SendReportTo(m.touchHIDID, m.touchReport(false, x, y)); err != nil {
+		m.handleError(err)
+		return fmt.Errorf("tap up: %w", err)
+	}
+
+	log.Printf("[device] tap (%d, %d)", x, y)
+	m.actionDone("tap", map[string]uint16{"x": x, "y": y})
+	return nil
+}
+
+// Keystroke timing for TypeText.
+const keystrokeDelay = 8 * time.Millisecond
+
+// TypeText types text on the R1 through an AOA2 keyboard (US layout) and,
+// if submit is set, presses Enter to send it. The keyboard HID is only
+// registered on first use, since an attached keyboard hides the R1's
+// on-screen keyboard.
+func (m *Manager) TypeText(text string, submit bool) error {
+	// Validate everything before sending the first key
+	keys := make([][2]byte, 0, len(text))
+	for _, r := range text {
+		mod, usage, ok := aoa.KeyForRune(r)
+		if !ok {
+			return fmt.Errorf("character %q cannot be typed", r)
+		}
+		keys = append(keys, [2]byte{mod, usage})
+	}
+	if submit {
+		keys = append(keys, [2]byte{0, aoa.KeyEnter})
+	}
+
+	m.interruptGesture()
+	return m.queue.do(prioGesture, func() error { return m.typeKeys(keys, text, submit) })
+}
+
+// typeKeys implements TypeText on the action queue.
+func (m *Manager) typeKeys(keys [][2]byte, text string, submit bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dev == nil {
+		return fmt.Errorf("no device connected")
+	}
+
+	m.touchActivity() // reset idle timer
+	m.wake()
+
+	if err := m.ensureKeyboard(); err != nil {
+		return err
+	}
+
+	release := aoa.KeyboardReport(0, 0)
+	for i, k := range keys {
+		if err := m.dev.SendReportTo(m.kbdHIDID, aoa.KeyboardReport(k[0], k[1])); err != nil {
+			m.handleError(err)
+			return fmt.Errorf("key %d down: %w", i, err)
+		}
+		time.Sleep(keystrokeDelay)
+		if err := m.dev.SendReportTo(m.kbdHIDID, release); err != nil {
+			m.handleError(err)
+			return fmt.Errorf("key %d up: %w", i, err)
+		}
+		time.Sleep(keystrokeDelay)
+	}
+
+	log.Printf("[device] typed %d characters (submit=%v)", len([]rune(text)), submit)
+	m.actionDone("type_text", map[string]any{"text": text, "submit": submit})
+	return nil
+}
+
+// ensureKeyboard registers the keyboard HID on first use. Must be called
+// with m.mu held and a device connected.
+func (m *Manager) ensureKeyboard() error {
+	if m.kbdHIDID != 0 {
+		return nil
+	}
+	id, err := m.dev.RegisterDescriptor(aoa.DescKeyboard)
+	if err != nil {
+		return fmt.Errorf("keyboard HID register: %w", err)
+	}
+	m.kbdHIDID = id
+	return nil
+}
+
+// Confirm presses Enter on the R1's keyboard HID, accepting the selection
+// at the end of many RabbitOS flows without touching the screen.
+func (m *Manager) Confirm() error {
+	m.interruptGesture()
+	return m.queue.do(prioGesture, m.confirm)
+}
+
+// confirm implements Confirm on the action queue.
+func (m *Manager) confirm() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dev == nil {
+		return fmt.Errorf("no device connected")
+	}
+
+	m.touchActivity() // reset idle timer
+	m.wake()
+
+	if err := m.ensureKeyboard(); err != nil {
+		return err
 	}
+	if err := m.dev.SendReportTo(m.kbdHIDID, aoa.KeyboardReport(0, aoa.KeyEnter)); err != nil {
+		m.handleError(err)
+		return fmt.Errorf("enter down: %w", err)
+	}
+	time.Sleep(keystrokeDelay)
+	if err := m.dev.SendReportTo(m.kbdHIDID, aoa.KeyboardReport(0, 0)); err != nil {
+		m.handleError(err)
+		return fmt.Errorf("enter up: %w", err)
+	}
+
+	log.Printf("[device] confirm (Enter)")
+	m.actionDone("confirm", nil)
 	return nil
 }
 
+// SwipeDirection selects which way SwipeTo swipes.
+type SwipeDirection int
+
+const (
+	SwipeNext  SwipeDirection = iota // opposite of the previous swipe
+	SwipeLeft                        // finger moves right to left
+	SwipeRight                       // finger moves left to right
+)
+
 // Swipe sends a swipe gesture via AOA2 touch screen HID.
 // Alternates between swipe left and swipe right on each call.
 // Simulates a finger swipe by sending interpolated touch reports.
 func (m *Manager) Swipe() error {
+	return m.SwipeTo(SwipeNext)
+}
+
+// SwipeTo sends a swipe in an explicit direction. The next alternating
+// Swipe goes the other way.
+func (m *Manager) SwipeTo(dir SwipeDirection) error {
+	ctx, done := m.beginGesture()
+	defer done()
+	return m.queue.do(prioGesture, func() error { return m.swipe(ctx, dir) })
+}
+
+// swipe implements SwipeTo on the action queue.
+func (m *Manager) swipe(ctx context.Context, direction SwipeDirection) error {
+	// Interrupted while still queued: don't touch the device at all
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("swipe interrupted: %w", err)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -367,72 +1523,186 @@ func (m *Manager) Swipe() error {
 	m.wake()
 
 	// Determine swipe direction
+	left := m.swipeLeft
+	if m.swipeResetAfter > 0 && !m.lastSwipe.IsZero() && time.Since(m.lastSwipe) > m.swipeResetAfter {
+		left = true // been a while: start over rather than guess where the user left off
+	}
+	if direction != SwipeNext {
+		left = direction == SwipeLeft
+	}
 	var startX, endX uint16
 	var dir string
-	if m.swipeLeft {
+	if left {
 		startX, endX, dir = 27000, 5000, "LEFT"
 	} else {
 		startX, endX, dir = 5000, 27000, "RIGHT"
 	}
-	m.swipeLeft = !m.swipeLeft
+	m.swipeLeft = !left
+	m.lastSwipe = time.Now()
+	startX = m.swipeStartX(startX, left)
 
 	// Y coordinate: near bottom of screen to minimize cursor visibility
 	const y uint16 = 32590
 
-	// Send 8 interpolated touch points with finger down
-	const steps = 8
-	for i := 0; i <= steps; i++ {
-		t := float64(i) / float64(steps)
-		x := uint16(float64(startX) + t*float64(int(endX)-int(startX)))
-		report := aoa.TouchReport(true, x, y)
-		if err := m.dev.SendReportTo(m.touchHIDID, report); err != nil {
-			m.handleError(err)
-			return fmt.Errorf("swipe step %d: %w", i, err)
-		}
-		if i < steps {
-			time.Sleep(25 * time.Millisecond)
-		}
+	from, to, err := m.placeGesture(point{startX, y}, point{endX, y})
+	if err != nil {
+		return fmt.Errorf("swipe: %w", err)
 	}
 
-	// Lift finger
-	liftReport := aoa.TouchReport(false, endX, y)
-	if err := m.dev.SendReportTo(m.touchHIDID, liftReport); err != nil {
+	// 8 interpolated touch points with finger down, then lift
+	seq := m.hideCursor(m.gesturePath(from, to, 8, 25*time.Millisecond))
+
+	if err := m.dev.SendReportSequence(ctx, m.touchHIDID, seq); err != nil {
+		// Always try to lift the finger, so an interrupted or failed
+		// swipe doesn't leave a touch held down on the R1
+		liftErr := m.dev.SendReportTo(m.touchHIDID, m.touchReport(false, to.x, to.y))
+		if ctx.Err() != nil && liftErr == nil {
+			log.Printf("[device] swipe %s interrupted", dir)
+			return fmt.Errorf("swipe interrupted: %w", err)
+		}
 		m.handleError(err)
-		return fmt.Errorf("swipe lift: %w", err)
+		return fmt.Errorf("swipe: %w", err)
 	}
 
 	log.Printf("[device] swipe %s", dir)
+	m.actionDone("swipe", map[string]string{"direction": strings.ToLower(dir)})
 	return nil
 }
 
-// handleError marks the device as disconnected on USB errors.
-// Must be called with m.mu held.
+// beginGesture interrupts any gesture in progress and returns a context
+// for a new one. done must be called when the gesture finishes.
+func (m *Manager) beginGesture() (ctx context.Context, done func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.gestureMu.Lock()
+	if m.cancelGesture != nil {
+		m.cancelGesture()
+	}
+	m.cancelGesture = cancel
+	m.gestureSeq++
+	seq := m.gestureSeq
+	m.gestureMu.Unlock()
+
+	return ctx, func() {
+		cancel()
+		m.gestureMu.Lock()
+		// A newer gesture may have replaced this one already
+		if m.gestureSeq == seq {
+			m.cancelGesture = nil
+		}
+		m.gestureMu.Unlock()
+	}
+}
+
+// interruptGesture cancels the gesture in progress, if any, so a new
+// action doesn't have to wait for it to finish. Must be called without
+// m.mu held.
+func (m *Manager) interruptGesture() {
+	m.gestureMu.Lock()
+	defer m.gestureMu.Unlock()
+	if m.cancelGesture != nil {
+		m.cancelGesture()
+		m.cancelGesture = nil
+	}
+}
+
+// handleError drops the device on USB errors and backs off before
+// reconnecting. A report refused for not fitting its descriptor was never
+// sent, and is only logged. A hiccup is ridden out like a failed health
+// check ping: if the R1 answers pings again, the connection is kept and
+// only the action fails. Must be called with m.mu held.
 func (m *Manager) handleError(err error) {
-	log.Printf("[device] USB error: %v — will reconnect", err)
+	if aoa.IsBadReport(err) {
+		logging.Errorf("[device] %v", err) // never sent; the connection is fine
+		return
+	}
+	m.link.failed(time.Now())
+	if m.dev != nil && m.hiccup(err) {
+		logging.Warnf("[device] USB error: %v — checking the R1 still answers", err)
+		if m.retryPing() == nil {
+			m.unsure = true // have the next health check look at the bus
+			return
+		}
+	}
+	logging.Warnf("[device] USB error: %v — will reconnect", err)
+	m.dropLocked(Backoff)
+}
+
+// hiccupInterval is how far apart failed pings must be to be ridden out
+// on the open connection; a second one sooner means a real problem.
+const hiccupInterval = 30 * time.Second
+
+// hiccup reports whether a failed ping or transfer is worth retrying on
+// the open connection, keeping its HID registrations rather than closing
+// and re-registering: a local R1 that is still attached where it was,
+// and no other hiccup within hiccupInterval. Must be called with m.mu
+// held.
+func (m *Manager) hiccup(err error) bool {
+	if m.remote != "" || aoa.IsGone(err) || time.Since(m.hiccupAt) < hiccupInterval {
+		return false
+	}
+	locs, lerr := aoa.Locate()
+	if lerr != nil || !slices.ContainsFunc(locs, m.loc.Equal) {
+		return false
+	}
+	m.hiccupAt = time.Now()
+	return true
+}
+
+// retryPing pings the R1 again after a failed ping on a device that is
+// still attached, and returns the last error. Must be called with m.mu
+// held.
+func (m *Manager) retryPing() error {
+	var err error
+	for i := 0; i < 2; i++ {
+		time.Sleep(200 * time.Millisecond)
+		if err = m.dev.Ping(); err == nil {
+			log.Printf("[device] R1 answered again — kept the connection")
+			return nil
+		}
+	}
+	return err
+}
+
+// dropLocked closes the device and moves to next (Disconnected or
+// Backoff), remembering a latched PTT so it can be replayed on
+// reconnect. Must be called with m.mu held.
+func (m *Manager) dropLocked(next State) {
 	if m.dev != nil {
+		m.rememberStuckTouch()
 		m.dev.Close()
 		m.dev = nil
+		m.tracker = nil
 	}
-	m.state = Disconnected
+	m.replayLatch = m.pttToggled
 	m.pttToggled = false
-	if m.onChange != nil {
-		m.onChange(Disconnected)
+	if m.keepAwakeMisses >= keepAwakeMissLimit {
+		m.setKeepAwakeProblem("") // the connection's own problems take over
 	}
+	m.keepAwakeMisses = 0
+	m.dimmed = false
+	m.carouselPos = -1
+	m.setState(next)
 }
 
 // Close shuts down the device connection cleanly.
 func (m *Manager) Close() {
+	m.interruptGesture()
+	m.queue.close()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.closed = true
 	if m.dev != nil {
 		// Release PTT if active
 		if m.state == PTTActive {
 			_ = m.dev.SendReportTo(m.pttHIDID, powerUp)
 		}
+		m.releaseAll()
 		m.dev.Close()
 		m.dev = nil
+		m.tracker = nil
 	}
-	m.state = Disconnected
 	m.pttToggled = false
+	m.replayLatch = false
+	m.setState(Disconnected)
 }
@@ -0,0 +1,54 @@
+package device
+
+import (
+	"fmt"
+	"log"
+)
+
+// Consumer Control media usages. Android maps these to its volume and
+// play/pause keys, which act on whatever is playing on the R1.
+const (
+	UsagePlayPause  uint16 = 0xCD
+	UsageMute       uint16 = 0xE2
+	UsageVolumeUp   uint16 = 0xE9
+	UsageVolumeDown uint16 = 0xEA
+)
+
+// mediaUsages names the usages accepted by MediaKey.
+var mediaUsages = map[string]uint16{
+	"volume_up":   UsageVolumeUp,
+	"volume_down": UsageVolumeDown,
+	"mute":        UsageMute,
+	"play_pause":  UsagePlayPause,
+}
+
+// MediaKey taps the named media key ("volume_up", "volume_down", "mute"
+// or "play_pause") on the Consumer Control descriptor. Unlike DisplayKey
+// it doesn't wake the screen first: Android handles these keys with the
+// screen off, and music keeps playing either way.
+func (m *Manager) MediaKey(name string) error {
+	usage, ok := mediaUsages[name]
+	if !ok {
+		return fmt.Errorf("unknown media key %q (want volume_up, volume_down, mute or play_pause)", name)
+	}
+	return m.queue.do(prioGesture, func() error { return m.mediaTap(name, usage) })
+}
+
+// mediaTap implements MediaKey on the action queue.
+func (m *Manager) mediaTap(name string, usage uint16) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dev == nil {
+		return fmt.Errorf("no device connected")
+	}
+
+	m.touchActivity() // reset idle timer
+	if err := m.consumerPress(name, usage); err != nil {
+		return err
+	}
+
+	log.Printf("[device] %s (usage 0x%02X)", name, usage)
+	m.actionDone(name, nil)
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build linux
+
+package device
+
+import (
+	"os"
+	"strings"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+)
+
+// powerState reads the runtime PM status of the device from sysfs
+// ("active", "suspended", ...). Returns "" if it can't be read.
+func powerState(loc aoa.Location) string {
+	b, err := os.ReadFile("/sys/bus/usb/devices/" + loc.PortPath() + "/power/runtime_status")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
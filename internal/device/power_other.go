@@ -0,0 +1,11 @@
+//go:build !linux
+
+package device
+
+import "github.com/HopIT-Hub/R1-Control/aoa"
+
+// powerState is not available on this platform: macOS and Windows don't
+// expose USB runtime suspend state without a kernel-side helper.
+func powerState(aoa.Location) string {
+	return ""
+}
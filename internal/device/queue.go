@@ -0,0 +1,191 @@
+package device
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// errClosed is returned for actions submitted after Close.
+var errClosed = errors.New("device manager closed")
+
+// priority orders queued actions: a PTT press must not wait behind a
+// swipe, and a swipe must not wait behind a keep-awake ping.
+type priority int
+
+const (
+	prioKeepAwake priority = iota
+	prioGesture
+	prioPTT
+	numPriorities
+)
+
+// job is one queued device action. done receives its result; it is nil
+// for fire-and-forget jobs.
+type job struct {
+	run  func() error
+	done chan error
+}
+
+// actionQueue runs device actions one at a time on a single worker,
+// highest priority first and in submission order within a priority.
+// Keep-awake pings are coalesced: at most one is ever pending.
+type actionQueue struct {
+	mu        sync.Mutex
+	lanes     [numPriorities][]*job
+	keepAwake bool          // a keep-awake job is pending
+	ready     chan struct{} // signals the worker that a job was queued
+	closed    bool
+
+	// onPanic is called on the worker after a job panics, with the value
+	// and stack; the job fails with an error instead. Set before use.
+	onPanic func(v any, stack []byte)
+
+	// User actions queued or running, and when the last one finished.
+	// Keep-awake pings are held back while the device is in use.
+	inFlight int
+	lastDone time.Time
+}
+
+func newActionQueue() *actionQueue {
+	q := &actionQueue{ready: make(chan struct{}, 1)}
+	go q.run()
+	return q
+}
+
+// do queues fn and waits for its result.
+func (q *actionQueue) do(p priority, fn func() error) error {
+	j := &job{run: fn, done: make(chan error, 1)}
+	if !q.push(p, j) {
+		return errClosed
+	}
+	return <-j.done
+}
+
+// coalesce queues fn as a keep-awake ping without waiting for it. It is
+// dropped if a ping is already pending.
+func (q *actionQueue) coalesce(fn func()) {
+	q.push(prioKeepAwake, &job{run: func() error { fn(); return nil }})
+}
+
+// push adds j to its lane and wakes the worker. Returns false if the
+// queue is closed.
+func (q *actionQueue) push(p priority, j *job) bool {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return false
+	}
+	if p == prioKeepAwake {
+		if q.keepAwake {
+			q.mu.Unlock()
+			return true
+		}
+		q.keepAwake = true
+	} else {
+		q.inFlight++
+	}
+	q.lanes[p] = append(q.lanes[p], j)
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// pop removes the highest-priority job, or returns nil if none is queued.
+func (q *actionQueue) pop() *job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for p := numPriorities - 1; p >= 0; p-- {
+		if len(q.lanes[p]) == 0 {
+			continue
+		}
+		j := q.lanes[p][0]
+		q.lanes[p][0] = nil
+		q.lanes[p] = q.lanes[p][1:]
+		if p == prioKeepAwake {
+			q.keepAwake = false
+		}
+		return j
+	}
+	return nil
+}
+
+// run is the worker loop. It exits once the queue is closed and drained.
+func (q *actionQueue) run() {
+	for range q.ready {
+		for j := q.pop(); j != nil; j = q.pop() {
+			err := q.runJob(j)
+			if j.done != nil {
+				q.mu.Lock()
+				q.inFlight--
+				q.lastDone = time.Now()
+				q.mu.Unlock()
+				j.done <- err
+			}
+		}
+		q.mu.Lock()
+		closed := q.closed
+		q.mu.Unlock()
+		if closed {
+			return
+		}
+	}
+}
+
+// runJob runs j, turning a panic into its error, so a bug in one action
+// can't take down the app while the R1 has a key held.
+func (q *actionQueue) runJob(j *job) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("device action crashed: %v", v)
+			if q.onPanic != nil {
+				q.onPanic(v, debug.Stack())
+			}
+		}
+	}()
+	return j.run()
+}
+
+// idleFor reports how long it has been since the last user action
+// finished, or 0 while one is queued or running.
+func (q *actionQueue) idleFor() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.inFlight > 0 {
+		return 0
+	}
+	return time.Since(q.lastDone)
+}
+
+// close stops the worker. Jobs still queued fail with errClosed.
+func (q *actionQueue) close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	var pending []*job
+	for p := range q.lanes {
+		pending = append(pending, q.lanes[p]...)
+		q.lanes[p] = nil
+	}
+	q.keepAwake = false
+	q.mu.Unlock()
+
+	for _, j := range pending {
+		if j.done != nil {
+			j.done <- errClosed
+		}
+	}
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,121 @@
+package device
+
+import (
+	"fmt"
+	"log"
+)
+
+// Region is a rectangle on the R1's screen in digitizer coordinates
+// (0-32767 on both axes), edges included.
+type Region struct {
+	X0, Y0, X1, Y1 uint16
+}
+
+func (r Region) contains(p point) bool {
+	return p.x >= r.X0 && p.x <= r.X1 && p.y >= r.Y0 && p.y <= r.Y1
+}
+
+// keepAwakeSpot is where keep-awake and self test touches land unless
+// it is off-limits: the bottom-right corner, clear of the R1's buttons.
+var keepAwakeSpot = point{32590, 32590}
+
+// SetOffLimits sets screen regions that generated touches must avoid:
+// keep-awake taps and the ends of swipes are moved just outside them.
+// Taps at explicit coordinates are sent where asked.
+func (m *Manager) SetOffLimits(regions []Region) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.offLimits = append([]Region(nil), regions...)
+	if len(regions) > 0 {
+		log.Printf("[device] %d off-limits screen regions", len(regions))
+	}
+}
+
+// KeepAwakeSpot returns where keep-awake taps land with regions
+// off-limits, for the bridge agent's keep-awake. ok is false when no
+// spot is left; see Manager.place.
+func KeepAwakeSpot(regions []Region) (x, y uint16, ok bool) {
+	q, ok := place(regions, keepAwakeSpot)
+	return q.x, q.y, ok
+}
+
+// place returns p, or if p is off-limits the nearest point just outside
+// the region it is in that isn't off-limits either. ok is false when no
+// such point was found, e.g. when the regions cover the whole screen.
+// Must be called with m.mu held.
+func (m *Manager) place(p point) (q point, ok bool) {
+	return place(m.offLimits, p)
+}
+
+// place is Manager.place for the given off-limits regions.
+func place(regions []Region, p point) (q point, ok bool) {
+	if !offLimits(regions, p) {
+		return p, true
+	}
+	best, bestDist := point{}, -1
+	for _, r := range regions {
+		if !r.contains(p) {
+			continue
+		}
+		var candidates []point
+		if r.X0 > 0 {
+			candidates = append(candidates, point{r.X0 - 1, p.y})
+		}
+		if r.X1 < 32767 {
+			candidates = append(candidates, point{r.X1 + 1, p.y})
+		}
+		if r.Y0 > 0 {
+			candidates = append(candidates, point{p.x, r.Y0 - 1})
+		}
+		if r.Y1 < 32767 {
+			candidates = append(candidates, point{p.x, r.Y1 + 1})
+		}
+		for _, c := range candidates {
+			if offLimits(regions, c) {
+				continue
+			}
+			d := abs(int(c.x)-int(p.x)) + abs(int(c.y)-int(p.y))
+			if bestDist < 0 || d < bestDist {
+				best, bestDist = c, d
+			}
+		}
+	}
+	return best, bestDist >= 0
+}
+
+// isOffLimits reports whether p is in an off-limits region. Must be
+// called with m.mu held.
+func (m *Manager) isOffLimits(p point) bool {
+	return offLimits(m.offLimits, p)
+}
+
+// offLimits reports whether p is in one of regions.
+func offLimits(regions []Region, p point) bool {
+	for _, r := range regions {
+		if r.contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// placeGesture moves a swipe's start and end out of the off-limits
+// regions. Must be called with m.mu held.
+func (m *Manager) placeGesture(from, to point) (point, point, error) {
+	f, ok := m.place(from)
+	if !ok {
+		return from, to, fmt.Errorf("gesture start (%d, %d) is off-limits", from.x, from.y)
+	}
+	t, ok := m.place(to)
+	if !ok {
+		return from, to, fmt.Errorf("gesture end (%d, %d) is off-limits", to.x, to.y)
+	}
+	return f, t, nil
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
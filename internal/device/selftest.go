@@ -0,0 +1,73 @@
+package device
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// SelfTestResult is the device part of the onboarding self test. Wake
+// and Touch are nil when the reports were delivered; they say nothing
+// about whether the R1 acted on them.
+type SelfTestResult struct {
+	Found      bool  // an R1 is connected
+	Registered bool  // the PTT and touch descriptors are registered
+	Wake       error // System Wake Up tap
+	Touch      error // tap in the bottom-right corner, where it does nothing
+}
+
+// SelfTest checks the connection by waking the R1 and tapping a safe
+// corner of the screen, reporting each step separately.
+func (m *Manager) SelfTest() SelfTestResult {
+	var res SelfTestResult
+	m.interruptGesture()
+	// Only fails once the manager is closed, leaving res "not found"
+	_ = m.queue.do(prioGesture, func() error {
+		res = m.selfTest()
+		return nil
+	})
+	return res
+}
+
+// selfTest implements SelfTest on the action queue.
+func (m *Manager) selfTest() SelfTestResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var res SelfTestResult
+	if m.dev == nil {
+		return res
+	}
+	res.Found = true
+	res.Registered = m.pttHIDID != 0 && m.touchHIDID != 0
+	if !res.Registered {
+		return res
+	}
+	m.touchActivity() // reset idle timer
+
+	if err := m.wake(); err != nil {
+		m.handleError(err)
+		res.Wake = err
+		return res
+	}
+
+	spot, ok := m.place(keepAwakeSpot)
+	if !ok {
+		res.Touch = fmt.Errorf("the corner touch spot is off-limits")
+		return res
+	}
+	if err := m.dev.SendReportTo(m.touchHIDID, m.touchReport(true, spot.x, spot.y)); err != nil {
+		m.handleError(err)
+		res.Touch = fmt.Errorf("touch down: %w", err)
+		return res
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := m.dev.SendReportTo(m.touchHIDID, m.touchReport(false, spot.x, spot.y)); err != nil {
+		m.handleError(err)
+		res.Touch = fmt.Errorf("touch up: %w", err)
+		return res
+	}
+
+	log.Printf("[device] self test: wake and corner touch delivered")
+	return res
+}
@@ -0,0 +1,143 @@
+package device
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/logging"
+)
+
+// State represents the current device/PTT state.
+//
+// The lifecycle is Disconnected → Connecting → Registering → Connected ⇄
+// PTTActive. A failed attempt or a USB error moves to Backoff, which
+// retries after a growing delay; an attempt that failed for a reason the
+// user has to fix, e.g. USB access denied, moves to Error, which retries
+// the same way. Closing or dropping the connection returns to
+// Disconnected from any state.
+type State int
+
+// Values are stable: Disconnected, Connected and PTTActive predate the
+// other states.
+const (
+	Disconnected State = iota
+	Connected
+	PTTActive
+	Connecting  // looking for the R1 and opening it
+	Registering // registering the HID descriptors
+	Backoff     // the last attempt or the connection failed; retrying later
+	Error       // the last attempt failed with a problem to fix; retrying later
+)
+
+func (s State) String() string {
+	switch s {
+	case Disconnected:
+		return "disconnected"
+	case Connecting:
+		return "connecting"
+	case Registering:
+		return "registering"
+	case Connected:
+		return "connected"
+	case PTTActive:
+		return "ptt_active"
+	case Backoff:
+		return "backoff"
+	case Error:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Online reports whether an R1 is connected and usable in state s.
+func (s State) Online() bool {
+	return s == Connected || s == PTTActive
+}
+
+// transitions lists the states each state may move to. Disconnected is
+// reachable from every state.
+var transitions = map[State][]State{
+	Disconnected: {Connecting},
+	Connecting:   {Registering, Backoff, Error},
+	Registering:  {Connected, Backoff, Error},
+	Connected:    {PTTActive, Backoff},
+	PTTActive:    {Connected, Backoff},
+	Backoff:      {Connecting},
+	Error:        {Connecting},
+}
+
+// canBecome reports whether s may move to next.
+func (s State) canBecome(next State) bool {
+	if next == Disconnected {
+		return true
+	}
+	for _, t := range transitions[s] {
+		if t == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Connection retry delays in Backoff and Error, doubling after each
+// failure.
+const (
+	minBackoff = time.Second // the next poll retries
+	maxBackoff = 30 * time.Second
+)
+
+// onEnter runs when the manager enters a state, after m.state changed and
+// before the change is published. Called with m.mu held.
+var onEnter = map[State]func(m *Manager, from State){
+	Disconnected: (*Manager).enterDisconnected,
+	Connected:    (*Manager).enterConnected,
+	Backoff:      (*Manager).enterBackoff,
+	Error:        (*Manager).enterBackoff,
+}
+
+// setState moves the manager to next, running its onEnter hook and
+// publishing the change. Moving to the current state does nothing, and
+// once the manager is closed it stays Disconnected (errClosed).
+// Transitions not in the table are refused and logged, leaving the state
+// unchanged. Must be called with m.mu held.
+func (m *Manager) setState(next State) error {
+	from := m.state
+	if from == next {
+		return nil
+	}
+	if m.closed && next != Disconnected {
+		return errClosed
+	}
+	if !from.canBecome(next) {
+		err := fmt.Errorf("invalid state transition %s → %s", from, next)
+		logging.Warnf("[device] %v — ignored", err)
+		return err
+	}
+
+	m.state = next
+	if hook := onEnter[next]; hook != nil {
+		hook(m, from)
+	}
+	m.stateChanged(from, next)
+	return nil
+}
+
+// enterDisconnected lets the next poll (or Reconnect) try right away.
+func (m *Manager) enterDisconnected(State) {
+	m.retryAt = time.Time{}
+}
+
+// enterConnected resets the retry delay after a successful connect.
+func (m *Manager) enterConnected(State) {
+	m.backoff = 0
+	m.retryAt = time.Time{}
+}
+
+// enterBackoff schedules the next connection attempt, waiting twice as
+// long as the last time.
+func (m *Manager) enterBackoff(from State) {
+	m.backoff = min(max(2*m.backoff, minBackoff), maxBackoff)
+	m.retryAt = time.Now().Add(m.backoff)
+	logging.Debugf("[device] backing off after %s — retrying in %v", from, m.backoff)
+}
@@ -0,0 +1,214 @@
+package device
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+)
+
+// DefaultReleaseStuckAfter is how long a touch or key may stay pressed
+// without a release before the watchdog releases it. Actions send their
+// release within a gesture's length, so only a lost report gets here.
+const DefaultReleaseStuckAfter = 5 * time.Second
+
+// heldInput is a touch or key left pressed on the R1.
+type heldInput struct {
+	since   time.Time
+	release []byte // report that lets go of it
+}
+
+// inputTracker wraps a connection to remember touches and keys the R1
+// was told are pressed and hasn't been told are released, e.g. because
+// the release failed to send. PTT isn't tracked: a latched PTT is meant
+// to stay down, and has its own auto-release.
+type inputTracker struct {
+	Transport
+	touchID uint16
+
+	mu    sync.Mutex
+	kinds map[uint16]aoa.DescriptorType // tracked HIDs
+	held  map[uint16]heldInput
+}
+
+// newInputTracker tracks the touch screen at touchID on dev, and the
+// keyboard and consumer control HIDs registered through it later.
+func newInputTracker(dev Transport, touchID uint16) *inputTracker {
+	return &inputTracker{
+		Transport: dev,
+		touchID:   touchID,
+		kinds:     map[uint16]aoa.DescriptorType{touchID: aoa.DescTouchScreen},
+		held:      make(map[uint16]heldInput),
+	}
+}
+
+func (t *inputTracker) RegisterDescriptor(dt aoa.DescriptorType) (uint16, error) {
+	id, err := t.Transport.RegisterDescriptor(dt)
+	if err == nil && (dt == aoa.DescKeyboard || dt == aoa.DescConsumerControl) {
+		t.mu.Lock()
+		t.kinds[id] = dt
+		t.mu.Unlock()
+	}
+	return id, err
+}
+
+// SendReportTo sends report and notes what it presses or releases. A
+// press counts even if sending failed, since it may have arrived; a
+// release only once sent.
+func (t *inputTracker) SendReportTo(hidID uint16, report []byte) error {
+	err := t.Transport.SendReportTo(hidID, report)
+	t.note(hidID, err == nil, report)
+	return err
+}
+
+// SendReportSequence sends seq and notes its reports, as SendReportTo.
+// A failed sequence counts as pressed if any of its reports press.
+func (t *inputTracker) SendReportSequence(ctx context.Context, hidID uint16, seq []aoa.TimedReport) error {
+	err := t.Transport.SendReportSequence(ctx, hidID, seq)
+	reports := make([][]byte, len(seq))
+	for i, r := range seq {
+		reports[i] = r.Report
+	}
+	t.note(hidID, err == nil, reports...)
+	return err
+}
+
+// note updates the held state of hidID from reports, in order.
+func (t *inputTracker) note(hidID uint16, sent bool, reports ...[]byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	kind, ok := t.kinds[hidID]
+	if !ok {
+		return
+	}
+	for _, r := range reports {
+		release, pressed := releaseFor(kind, r)
+		switch {
+		case pressed:
+			if _, already := t.held[hidID]; !already {
+				t.held[hidID] = heldInput{since: time.Now(), release: release}
+			}
+		case sent:
+			delete(t.held, hidID)
+		}
+	}
+}
+
+// stuck returns the inputs held for at least d, by HID.
+func (t *inputTracker) stuck(d time.Duration) map[uint16]heldInput {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out map[uint16]heldInput
+	for id, h := range t.held {
+		if time.Since(h.since) >= d {
+			if out == nil {
+				out = make(map[uint16]heldInput)
+			}
+			out[id] = h
+		}
+	}
+	return out
+}
+
+// releaseFor reports whether report presses something on a HID of kind,
+// and the report that releases it: the touch lifted where it was, or no
+// keys.
+func releaseFor(kind aoa.DescriptorType, report []byte) (release []byte, pressed bool) {
+	release = make([]byte, len(report))
+	if kind == aoa.DescTouchScreen {
+		if len(report) == 0 || report[0]&0x01 == 0 { // tip switch
+			return nil, false
+		}
+		copy(release, report)
+		release[0] = 0
+		return release, true
+	}
+	for _, b := range report {
+		if b != 0 {
+			return release, true
+		}
+	}
+	return nil, false
+}
+
+// kindName names a tracked HID for logs.
+func kindName(kind aoa.DescriptorType) string {
+	switch kind {
+	case aoa.DescTouchScreen:
+		return "touch"
+	case aoa.DescKeyboard:
+		return "key"
+	default:
+		return "button"
+	}
+}
+
+// SetReleaseStuckAfter sets how long a touch or key may stay pressed
+// without a release before it is released; 0 = DefaultReleaseStuckAfter.
+func (m *Manager) SetReleaseStuckAfter(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if d <= 0 {
+		d = DefaultReleaseStuckAfter
+	}
+	m.releaseStuckAfter = d
+}
+
+// releaseStuck sends the release for any touch or key held longer than
+// the timeout. Called from the Run loop.
+func (m *Manager) releaseStuck() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.tracker
+	if t == nil || m.dev == nil {
+		return
+	}
+	for id, h := range t.stuck(m.releaseStuckAfter) {
+		t.mu.Lock()
+		kind := t.kinds[id]
+		t.mu.Unlock()
+		log.Printf("[device] %s held for %v without a release — releasing it", kindName(kind), time.Since(h.since).Round(time.Second))
+		if err := m.dev.SendReportTo(id, h.release); err != nil {
+			m.handleError(err)
+			return
+		}
+	}
+}
+
+// releaseAll sends the release for every touch and key still held, e.g.
+// before closing the connection. Must be called with m.mu held.
+func (m *Manager) releaseAll() {
+	if m.tracker == nil || m.dev == nil {
+		return
+	}
+	for id, h := range m.tracker.stuck(0) {
+		_ = m.dev.SendReportTo(id, h.release)
+	}
+}
+
+// rememberStuckTouch keeps the release for a touch still held when the
+// connection drops, to send once the R1 is back. Must be called with m.mu
+// held.
+func (m *Manager) rememberStuckTouch() {
+	m.liftOnConnect = nil
+	if m.tracker == nil {
+		return
+	}
+	if h, ok := m.tracker.stuck(0)[m.tracker.touchID]; ok {
+		m.liftOnConnect = h.release
+	}
+}
+
+// liftStuckTouch lifts a touch left held when the connection dropped, in
+// case the R1 still has it down. Must be called with m.mu held.
+func (m *Manager) liftStuckTouch() {
+	if m.liftOnConnect == nil || m.dev == nil {
+		return
+	}
+	log.Printf("[device] touch was held when the R1 disconnected — lifting it")
+	_ = m.dev.SendReportTo(m.touchHIDID, m.liftOnConnect)
+	m.liftOnConnect = nil
+}
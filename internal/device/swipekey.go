@@ -0,0 +1,114 @@
+package device
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultSwipeHold is how long the swipe hotkey must be held to swipe
+// left in SwipeKeyHold mode.
+const DefaultSwipeHold = 400 * time.Millisecond
+
+// SwipeKeyMode selects how the swipe hotkey's presses map to swipes.
+type SwipeKeyMode int
+
+const (
+	SwipeKeyAlternate SwipeKeyMode = iota // each press swipes the other way
+	SwipeKeyHold                          // a tap swipes right, a hold swipes left
+)
+
+// ParseSwipeKeyMode parses a config value ("alternate", "hold").
+func ParseSwipeKeyMode(s string) (SwipeKeyMode, error) {
+	switch s {
+	case "alternate", "":
+		return SwipeKeyAlternate, nil
+	case "hold":
+		return SwipeKeyHold, nil
+	default:
+		return SwipeKeyAlternate, fmt.Errorf("unknown swipe mode %q (want alternate or hold)", s)
+	}
+}
+
+// swipeKey is the swipe hotkey's press state. It has its own lock since
+// the hold timer fires on its own goroutine, and the swipe it starts
+// takes m.mu.
+type swipeKey struct {
+	mu      sync.Mutex
+	mode    SwipeKeyMode
+	hold    time.Duration
+	press   uint64 // counts presses, so a stale hold timer does nothing
+	pending bool   // pressed, and neither the release nor the hold has swiped yet
+	timer   *time.Timer
+}
+
+// SetSwipeKeyMode sets how the swipe hotkey behaves. hold is how long a
+// press must last to swipe left in SwipeKeyHold mode; 0 keeps the
+// default. In SwipeKeyAlternate mode, alternating starts over with a
+// left swipe once resetAfter has passed since the last swipe; 0 never
+// starts over.
+func (m *Manager) SetSwipeKeyMode(mode SwipeKeyMode, hold, resetAfter time.Duration) {
+	if hold <= 0 {
+		hold = DefaultSwipeHold
+	}
+	m.swipeKey.mu.Lock()
+	m.swipeKey.mode = mode
+	m.swipeKey.hold = hold
+	m.swipeKey.mu.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.swipeResetAfter = 0
+	if mode == SwipeKeyAlternate {
+		m.swipeResetAfter = resetAfter
+	}
+}
+
+// SwipeKeyDown is called when the swipe hotkey is pressed down. It
+// swipes right away in SwipeKeyAlternate mode; in SwipeKeyHold mode it
+// swipes left once the press has lasted long enough.
+func (m *Manager) SwipeKeyDown() error {
+	k := &m.swipeKey
+	k.mu.Lock()
+	if k.mode == SwipeKeyAlternate {
+		k.mu.Unlock()
+		return m.Swipe()
+	}
+	if k.pending {
+		k.mu.Unlock()
+		return nil // key repeat while held
+	}
+	k.press++
+	k.pending = true
+	press := k.press
+	k.timer = time.AfterFunc(k.hold, func() {
+		k.mu.Lock()
+		if k.press != press || !k.pending {
+			k.mu.Unlock()
+			return
+		}
+		k.pending = false
+		k.mu.Unlock()
+		if err := m.SwipeTo(SwipeLeft); err != nil {
+			log.Printf("[device] swipe error: %v", err)
+		}
+	})
+	k.mu.Unlock()
+	return nil
+}
+
+// SwipeKeyUp is called when the swipe hotkey is released. In
+// SwipeKeyHold mode a press released before the hold time swipes right.
+func (m *Manager) SwipeKeyUp() error {
+	k := &m.swipeKey
+	k.mu.Lock()
+	if !k.pending {
+		k.mu.Unlock()
+		return nil // alternate mode, or the hold already swiped
+	}
+	k.pending = false
+	k.timer.Stop()
+	k.mu.Unlock()
+	return m.SwipeTo(SwipeRight)
+}
@@ -0,0 +1,54 @@
+package device
+
+import (
+	"context"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+)
+
+// Transport carries AOA HID registrations and reports to an R1. A local
+// USB connection is an *aoa.Device; other transports (e.g. a network
+// bridge to an R1 attached elsewhere) implement the same calls.
+type Transport interface {
+	RegisterDescriptor(dt aoa.DescriptorType) (uint16, error)
+	SendReportTo(hidID uint16, report []byte) error
+	SendReportSequence(ctx context.Context, hidID uint16, seq []aoa.TimedReport) error
+	Ping() error
+	Close()
+
+	// ConfirmSettle tells the transport reports reach the R1, so HIDs
+	// registered from then on need less time to settle.
+	ConfirmSettle()
+
+	Serial() string
+	Product() string
+	Location() aoa.Location
+	Speed() string
+	Configured() bool
+}
+
+// Opener opens a Transport to the R1. It returns an error if none is
+// available; the Manager retries on its next poll.
+type Opener func() (Transport, error)
+
+// usbOpener opens the local R1 matching f.
+func usbOpener(f aoa.Filter) Opener {
+	return func() (Transport, error) {
+		dev, err := aoa.OpenFilter(f)
+		if err != nil {
+			return nil, err // not a typed nil inside the interface
+		}
+		return dev, nil
+	}
+}
+
+// SetOpener replaces the local USB connection with another transport.
+// name describes it in logs and problems, e.g. "bridge 10.0.0.5:9761".
+// Bus-level checks (re-enumeration, USB power state) only apply to local
+// devices and are skipped. Call before Run.
+func (m *Manager) SetOpener(name string, open Opener) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.open = open
+	m.remote = name
+}
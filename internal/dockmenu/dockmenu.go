@@ -0,0 +1,35 @@
+// Package dockmenu offers quick actions outside the tray, for users who
+// hide tray icons: the taskbar jump list on Windows and the Dock menu on
+// macOS. Each platform has its own implementation file; elsewhere
+// Install returns ErrUnsupported.
+package dockmenu
+
+import "errors"
+
+// ErrUnsupported is returned by Install on platforms without a jump list
+// or Dock menu.
+var ErrUnsupported = errors.New("no jump list or Dock menu on this platform")
+
+// Item is one quick action.
+type Item struct {
+	Title string
+	// Args run the action as a one-shot command, e.g. "ptt", "toggle".
+	// Used where the menu starts a new process (the Windows jump list).
+	Args []string
+	// Run runs the action in this process. Used where the menu calls
+	// back into the app (the macOS Dock menu).
+	Run func()
+}
+
+// Install shows items in the jump list or Dock menu, replacing any shown
+// before. On macOS this also shows the app in the Dock, which it
+// otherwise isn't. Call after the tray is running.
+func Install(items []Item) error {
+	return install(items)
+}
+
+// Remove takes the quick actions away again, e.g. after the setting was
+// turned off. It does nothing on platforms Install doesn't support.
+func Remove() error {
+	return remove()
+}
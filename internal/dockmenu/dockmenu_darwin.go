@@ -0,0 +1,158 @@
+//go:build darwin
+
+package dockmenu
+
+/*
+#cgo CFLAGS: -x objective-c -fobjc-arc
+#cgo LDFLAGS: -framework Cocoa
+#import <Cocoa/Cocoa.h>
+#import <objc/runtime.h>
+#include <stdlib.h>
+
+static NSMenu *dockMenu = nil;
+
+// Clicked item tags, handed to dockMenuWait. Clicks arrive on the main
+// thread; dockMenuWait blocks on its own thread.
+#define DOCK_QUEUE 16
+static int dockQueue[DOCK_QUEUE];
+static int dockQueued = 0;
+static dispatch_semaphore_t dockSignal = nil;
+
+@interface R1DockTarget : NSObject
+- (void)clicked:(NSMenuItem *)item;
+@end
+
+@implementation R1DockTarget
+- (void)clicked:(NSMenuItem *)item {
+	@synchronized([R1DockTarget class]) {
+		if (dockQueued < DOCK_QUEUE) {
+			dockQueue[dockQueued++] = (int)item.tag;
+		}
+	}
+	dispatch_semaphore_signal(dockSignal);
+}
+@end
+
+static R1DockTarget *dockTarget = nil;
+
+// applicationDockMenu: for the tray's app delegate, which doesn't have one.
+static NSMenu *dockMenuForApp(id self, SEL _cmd, NSApplication *sender) {
+	return dockMenu;
+}
+
+static void dockMenuInit(void) {
+	if (dockSignal == nil) {
+		dockSignal = dispatch_semaphore_create(0);
+	}
+}
+
+// dockMenuSet replaces the Dock menu with titles (count of them) and
+// shows the Dock icon. Item i has tag i.
+static void dockMenuSet(char **titles, int count) {
+	NSMutableArray<NSString *> *names = [NSMutableArray arrayWithCapacity:count];
+	for (int i = 0; i < count; i++) {
+		[names addObject:[NSString stringWithUTF8String:titles[i]]];
+	}
+	dispatch_async(dispatch_get_main_queue(), ^{
+		if (dockTarget == nil) {
+			dockTarget = [[R1DockTarget alloc] init];
+		}
+		NSMenu *menu = [[NSMenu alloc] init];
+		for (NSUInteger i = 0; i < names.count; i++) {
+			NSMenuItem *item = [[NSMenuItem alloc] initWithTitle:names[i] action:@selector(clicked:) keyEquivalent:@""];
+			item.target = dockTarget;
+			item.tag = (NSInteger)i;
+			[menu addItem:item];
+		}
+		dockMenu = menu;
+		id delegate = [NSApp delegate];
+		if (delegate != nil) {
+			class_replaceMethod([delegate class], @selector(applicationDockMenu:), (IMP)dockMenuForApp, "@@:@");
+		}
+		[NSApp setActivationPolicy:NSApplicationActivationPolicyRegular];
+	});
+}
+
+// dockMenuClear removes the Dock menu and hides the Dock icon again.
+static void dockMenuClear(void) {
+	dispatch_async(dispatch_get_main_queue(), ^{
+		if (dockMenu == nil) {
+			return;
+		}
+		dockMenu = nil;
+		[NSApp setActivationPolicy:NSApplicationActivationPolicyAccessory];
+	});
+}
+
+// dockMenuWait blocks until an item is clicked and returns its tag.
+static int dockMenuWait(void) {
+	for (;;) {
+		dispatch_semaphore_wait(dockSignal, DISPATCH_TIME_FOREVER);
+		@synchronized([R1DockTarget class]) {
+			if (dockQueued > 0) {
+				int tag = dockQueue[0];
+				dockQueued--;
+				memmove(dockQueue, dockQueue + 1, dockQueued * sizeof(int));
+				return tag;
+			}
+		}
+	}
+}
+*/
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+)
+
+var (
+	mu       sync.Mutex
+	items    []Item // shown in the Dock menu; indexed by item tag
+	waitOnce sync.Once
+)
+
+func install(list []Item) error {
+	mu.Lock()
+	items = list
+	mu.Unlock()
+
+	C.dockMenuInit()
+	titles := make([]*C.char, len(list))
+	for i, it := range list {
+		titles[i] = C.CString(it.Title)
+		defer C.free(unsafe.Pointer(titles[i]))
+	}
+	var first **C.char
+	if len(titles) > 0 {
+		first = &titles[0]
+	}
+	C.dockMenuSet(first, C.int(len(titles)))
+
+	waitOnce.Do(func() { go waitClicks() })
+	return nil
+}
+
+func remove() error {
+	mu.Lock()
+	items = nil
+	mu.Unlock()
+	C.dockMenuClear()
+	return nil
+}
+
+// waitClicks runs the action of each clicked Dock menu item.
+func waitClicks() {
+	for {
+		tag := int(C.dockMenuWait())
+		mu.Lock()
+		var run func()
+		if tag >= 0 && tag < len(items) {
+			run = items[tag].Run
+		}
+		mu.Unlock()
+		if run != nil {
+			go run()
+		}
+	}
+}
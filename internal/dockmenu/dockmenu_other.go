@@ -0,0 +1,11 @@
+//go:build !windows && !darwin
+
+package dockmenu
+
+func install([]Item) error {
+	return ErrUnsupported
+}
+
+func remove() error {
+	return nil
+}
@@ -0,0 +1,246 @@
+//go:build windows
+
+package dockmenu
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	ole32                = windows.NewLazySystemDLL("ole32.dll")
+	procCoCreateInstance = ole32.NewProc("CoCreateInstance")
+)
+
+// COM class and interface IDs from shobjidl.h and propsys.h.
+var (
+	clsidDestinationList     = mustGUID("{77f10cf0-3db5-4966-b520-b7c54fd35ed6}")
+	clsidObjectCollection    = mustGUID("{2d3468c1-36a7-43b6-ac24-d3f02fd9607a}")
+	clsidShellLink           = mustGUID("{00021401-0000-0000-c000-000000000046}")
+	iidCustomDestinationList = mustGUID("{6332debf-87b5-4670-90c0-5e57b408a49e}")
+	iidObjectArray           = mustGUID("{92ca9dcd-5622-4bba-a805-5e9f541bd8c9}")
+	iidObjectCollection      = mustGUID("{5632b1a4-e38a-400a-928a-d4cd63230295}")
+	iidShellLink             = mustGUID("{000214f9-0000-0000-c000-000000000046}")
+	iidPropertyStore         = mustGUID("{886d8eeb-8cf2-4446-8d02-cdba1dbdcf99}")
+
+	pkeyTitle = propertyKey{fmtid: mustGUID("{f29f85e0-4ff9-1068-ab91-08002b27b3d9}"), pid: 2}
+)
+
+// Vtable indexes of the methods used. Every interface starts with
+// IUnknown's QueryInterface, AddRef and Release.
+const (
+	mQueryInterface = 0
+	mRelease        = 2
+
+	mListBeginList    = 4 // ICustomDestinationList
+	mListAddUserTasks = 7
+	mListCommitList   = 8
+	mListDeleteList   = 10
+	mListAbortList    = 11
+
+	mCollectionAddObject = 5 // IObjectCollection
+
+	mLinkSetDescription  = 7 // IShellLinkW
+	mLinkSetArguments    = 11
+	mLinkSetIconLocation = 17
+	mLinkSetPath         = 20
+
+	mStoreSetValue = 6 // IPropertyStore
+	mStoreCommit   = 7
+)
+
+const (
+	clsctxInprocServer      = 0x1
+	coinitApartmentThreaded = 0x2
+	vtLPWSTR                = 31
+)
+
+type propertyKey struct {
+	fmtid windows.GUID
+	pid   uint32
+}
+
+// propVariant is a PROPVARIANT holding a string.
+type propVariant struct {
+	vt  uint16
+	_   [3]uint16
+	val uintptr
+	_   uintptr
+}
+
+// comObject is a COM interface pointer.
+type comObject struct {
+	vtbl *[32]uintptr
+}
+
+// call invokes method on o, returning an error for a failed HRESULT.
+func (o *comObject) call(method int, args ...uintptr) error {
+	hr, _, _ := syscall.SyscallN(o.vtbl[method], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	if int32(hr) < 0 {
+		return fmt.Errorf("HRESULT 0x%08x", uint32(hr))
+	}
+	return nil
+}
+
+func (o *comObject) release() {
+	o.call(mRelease)
+}
+
+func (o *comObject) query(iid *windows.GUID) (*comObject, error) {
+	var out *comObject
+	if err := o.call(mQueryInterface, uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(&out))); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func create(clsid, iid *windows.GUID) (*comObject, error) {
+	var out *comObject
+	hr, _, _ := procCoCreateInstance.Call(uintptr(unsafe.Pointer(clsid)), 0, clsctxInprocServer,
+		uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(&out)))
+	if int32(hr) < 0 {
+		return nil, fmt.Errorf("CoCreateInstance: HRESULT 0x%08x", uint32(hr))
+	}
+	return out, nil
+}
+
+func mustGUID(s string) windows.GUID {
+	g, err := windows.GUIDFromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// str returns a pointer to a NUL-terminated UTF-16 copy of s, kept
+// alive by the caller holding the result until the call returns.
+func str(s string) *uint16 {
+	p, _ := windows.UTF16PtrFromString(s)
+	return p
+}
+
+// withCOM runs fn on a thread with COM initialized.
+func withCOM(fn func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	// S_FALSE (1) means COM was already initialized on this thread
+	if err := windows.CoInitializeEx(0, coinitApartmentThreaded); err != nil && err != windows.Errno(1) {
+		return fmt.Errorf("CoInitializeEx: %w", err)
+	}
+	defer windows.CoUninitialize()
+	return fn()
+}
+
+// install replaces the jump list's Tasks with items, each of which
+// starts this executable with the item's Args.
+func install(items []Item) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return withCOM(func() error {
+		list, err := create(&clsidDestinationList, &iidCustomDestinationList)
+		if err != nil {
+			return err
+		}
+		defer list.release()
+
+		var slots uint32
+		var removed *comObject
+		if err := list.call(mListBeginList, uintptr(unsafe.Pointer(&slots)),
+			uintptr(unsafe.Pointer(&iidObjectArray)), uintptr(unsafe.Pointer(&removed))); err != nil {
+			return fmt.Errorf("begin jump list: %w", err)
+		}
+		if removed != nil {
+			removed.release()
+		}
+
+		tasks, err := create(&clsidObjectCollection, &iidObjectCollection)
+		if err != nil {
+			list.call(mListAbortList)
+			return err
+		}
+		defer tasks.release()
+
+		for _, it := range items {
+			if err := addTask(tasks, exe, it); err != nil {
+				list.call(mListAbortList)
+				return fmt.Errorf("jump list task %q: %w", it.Title, err)
+			}
+		}
+		// IObjectCollection extends IObjectArray, so it is passed as is
+		if err := list.call(mListAddUserTasks, uintptr(unsafe.Pointer(tasks))); err != nil {
+			list.call(mListAbortList)
+			return fmt.Errorf("add jump list tasks: %w", err)
+		}
+		if err := list.call(mListCommitList); err != nil {
+			return fmt.Errorf("commit jump list: %w", err)
+		}
+		return nil
+	})
+}
+
+// addTask adds a shell link that runs exe with it.Args to tasks.
+func addTask(tasks *comObject, exe string, it Item) error {
+	link, err := create(&clsidShellLink, &iidShellLink)
+	if err != nil {
+		return err
+	}
+	defer link.release()
+
+	args := make([]string, len(it.Args))
+	for i, a := range it.Args {
+		args[i] = windows.EscapeArg(a)
+	}
+	exeW, argsW, titleW := str(exe), str(strings.Join(args, " ")), str(it.Title)
+	if err := link.call(mLinkSetPath, uintptr(unsafe.Pointer(exeW))); err != nil {
+		return err
+	}
+	if err := link.call(mLinkSetArguments, uintptr(unsafe.Pointer(argsW))); err != nil {
+		return err
+	}
+	if err := link.call(mLinkSetIconLocation, uintptr(unsafe.Pointer(exeW)), 0); err != nil {
+		return err
+	}
+	if err := link.call(mLinkSetDescription, uintptr(unsafe.Pointer(titleW))); err != nil {
+		return err
+	}
+
+	// Tasks show the link's title property, not its description
+	store, err := link.query(&iidPropertyStore)
+	if err != nil {
+		return err
+	}
+	defer store.release()
+	pv := propVariant{vt: vtLPWSTR, val: uintptr(unsafe.Pointer(titleW))}
+	if err := store.call(mStoreSetValue, uintptr(unsafe.Pointer(&pkeyTitle)), uintptr(unsafe.Pointer(&pv))); err != nil {
+		return err
+	}
+	if err := store.call(mStoreCommit); err != nil {
+		return err
+	}
+	runtime.KeepAlive(titleW)
+	runtime.KeepAlive(exeW)
+	runtime.KeepAlive(argsW)
+
+	return tasks.call(mCollectionAddObject, uintptr(unsafe.Pointer(link)))
+}
+
+// remove deletes the jump list.
+func remove() error {
+	return withCOM(func() error {
+		list, err := create(&clsidDestinationList, &iidCustomDestinationList)
+		if err != nil {
+			return err
+		}
+		defer list.release()
+		// NULL app ID = this process's default
+		return list.call(mListDeleteList, 0)
+	})
+}
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published on a Bus.
+const (
+	TypeState    = "state"     // Name: device state, e.g. "ptt_active"; Detail: previous state; Value: device.State
+	TypeAction   = "action"    // Name: completed device action, e.g. "swipe"; Value: its payload, json.RawMessage or nil
+	TypeConnect  = "connect"   // Name: R1 serial; Detail: USB product; Value: device.Info
+	TypeLink     = "link"      // Name: "warning"; Detail: dock/cable warning, "" = healthy again
+	TypeProblem  = "problem"   // Name: source, e.g. "device", "ptt_hotkey"; Detail: message, "" = cleared
+	TypeGameMode = "game_mode" // Name: "suspended" or "resumed"; Detail: foreground app
+	TypeMacro    = "macro"     // Name: "started" or "finished"; Detail: macro name
+	TypeFocus    = "focus"     // Name: period begun ("work", "break", "long_break"), "done" or "stopped"; Detail: period end, "HH:MM"
+	TypeProfile  = "profile"   // Name: profile switched in; Detail: what switched it, e.g. "context rule 2" or "api"
+)
+
+// Bus delivers published events to every subscriber, so components
+// react to the device and each other without knowing who else listens.
+// A nil *Bus discards events.
+type Bus struct {
+	mu   sync.RWMutex
+	subs []*subscriber // in subscription order
+}
+
+type subscriber struct {
+	types map[string]bool // nil = all types
+	fn    func(Event)
+}
+
+// NewBus returns an empty bus.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe calls fn for each event of the given types, or of every type
+// if none are given, in the order subscribers subscribed. fn runs in the
+// publisher's goroutine, possibly with the publisher's locks held, so it
+// must return quickly and must not call back into the publisher. Slow
+// consumers (network hooks, streams) should hand events to their own
+// goroutine. The returned function unsubscribes.
+func (b *Bus) Subscribe(fn func(Event), types ...string) (unsubscribe func()) {
+	if b == nil {
+		return func() {}
+	}
+	s := &subscriber{fn: fn}
+	if len(types) > 0 {
+		s.types = make(map[string]bool, len(types))
+		for _, t := range types {
+			s.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.subs = append(b.subs, s)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, sub := range b.subs {
+			if sub == s {
+				b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish stamps e with the current time, unless it has one, and
+// delivers it to the subscribers of its type.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	var fns []func(Event)
+	for _, s := range b.subs {
+		if s.types == nil || s.types[e.Type] {
+			fns = append(fns, s.fn)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, fn := range fns {
+		fn(e)
+	}
+}
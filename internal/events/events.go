@@ -0,0 +1,133 @@
+// Package events carries state changes and actions between the app's
+// components over a publish/subscribe Bus, and writes them as JSON lines
+// for external analysis. The file is rotated by size; writing to stdout
+// is supported for running under a supervisor that collects output.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Stdout is the path value that selects standard output instead of a file.
+const Stdout = "-"
+
+// Event is one published event, and one exported line.
+type Event struct {
+	Time   time.Time `json:"time"`
+	Type   string    `json:"type"`             // one of the Type constants
+	Name   string    `json:"name"`             // e.g. "ptt_active", "swipe"
+	Detail string    `json:"detail,omitempty"` // free-form context
+	Value  any       `json:"-"`                // typed payload for subscribers in the app, e.g. device.State
+}
+
+// Log is a size-rotated JSONL event writer. A nil *Log discards events.
+type Log struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64 // rotate once the file would exceed this many bytes
+	keep    int   // number of rotated files to keep (path.1 … path.N)
+	out     io.Writer
+	f       *os.File
+	size    int64
+}
+
+// Open starts an event log at path (Stdout for standard output). Files
+// are rotated at maxSizeMB, keeping keep old files.
+func Open(path string, maxSizeMB, keep int) (*Log, error) {
+	l := &Log{path: path, maxSize: int64(maxSizeMB) << 20, keep: keep}
+	if path == Stdout {
+		l.out = os.Stdout
+		return l, nil
+	}
+	if err := l.openFile(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// openFile opens (or creates) the log file for appending.
+func (l *Log) openFile() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("create event log dir: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open event log: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat event log: %w", err)
+	}
+	l.f, l.out, l.size = f, f, fi.Size()
+	return nil
+}
+
+// rotate shifts path → path.1 → … → path.keep, dropping the oldest.
+// Must be called with l.mu held.
+func (l *Log) rotate() error {
+	l.f.Close()
+	l.f, l.out = nil, nil
+
+	os.Remove(fmt.Sprintf("%s.%d", l.path, l.keep))
+	for i := l.keep - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+	}
+	if l.keep > 0 {
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return fmt.Errorf("rotate event log: %w", err)
+		}
+	} else {
+		os.Remove(l.path)
+	}
+	return l.openFile()
+}
+
+// Record writes one event. It can be subscribed to a Bus directly.
+func (l *Log) Record(e Event) {
+	if l == nil {
+		return
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f != nil && l.maxSize > 0 && l.size+int64(len(data)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			log.Printf("[events] %v", err)
+		}
+	}
+	if l.out == nil {
+		return
+	}
+	n, err := l.out.Write(data)
+	l.size += int64(n)
+	if err != nil {
+		log.Printf("[events] write: %v", err)
+	}
+}
+
+// Close flushes and closes the event log file.
+func (l *Log) Close() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f != nil {
+		l.f.Close()
+		l.f, l.out = nil, nil
+	}
+}
@@ -0,0 +1,172 @@
+// Package focus is a focus (pomodoro) timer: work periods alternating
+// with breaks, a longer one every few, with R1 actions run as each
+// period begins, e.g. waking the R1 and swiping to a timer screen.
+package focus
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/events"
+	"github.com/HopIT-Hub/R1-Control/internal/logging"
+)
+
+// Phases of the timer.
+const (
+	Stopped   = ""
+	Work      = "work"
+	Break     = "break"
+	LongBreak = "long_break"
+)
+
+// Status is where the timer is.
+type Status struct {
+	Phase            string `json:"phase"`             // "" = stopped
+	EndsAt           string `json:"ends_at,omitempty"` // RFC 3339
+	RemainingSeconds int    `json:"remaining_seconds"`
+	Completed        int    `json:"completed"` // work periods finished since Start
+	Cycles           int    `json:"cycles"`    // work periods before it stops; 0 = until stopped
+}
+
+// Timer runs focus sessions, one at a time.
+type Timer struct {
+	run func(config.FocusStep) error
+	bus *events.Bus
+
+	mu        sync.Mutex
+	cfg       config.FocusConfig // of the running session
+	phase     string
+	endsAt    time.Time
+	completed int
+	timer     *time.Timer // ends the current period
+	seq       uint64      // identifies the period timer belongs to
+}
+
+// New returns a stopped timer that runs each step with run, and publishes
+// TypeFocus events on bus (nil = none) as periods begin and end.
+func New(run func(config.FocusStep) error, bus *events.Bus) *Timer {
+	return &Timer{run: run, bus: bus}
+}
+
+// Start begins a session with cfg at its first work period, ending any
+// session in progress.
+func (t *Timer) Start(cfg config.FocusConfig) error {
+	if cfg.WorkMinutes <= 0 || cfg.BreakMinutes <= 0 {
+		return fmt.Errorf("work and break periods must be at least a minute")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopLocked()
+	t.cfg = cfg
+	t.completed = 0
+	log.Printf("[focus] started: %dm work, %dm breaks", cfg.WorkMinutes, cfg.BreakMinutes)
+	t.beginLocked(Work)
+	return nil
+}
+
+// Stop ends the session, if one is running.
+func (t *Timer) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.phase == Stopped {
+		return
+	}
+	t.stopLocked()
+	log.Printf("[focus] stopped")
+	t.bus.Publish(events.Event{Type: events.TypeFocus, Name: "stopped"})
+}
+
+// Skip ends the current period now and moves on to the next.
+func (t *Timer) Skip() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.phase == Stopped {
+		return fmt.Errorf("the focus timer isn't running")
+	}
+	t.timer.Stop()
+	t.nextLocked()
+	return nil
+}
+
+// Status returns where the timer is.
+func (t *Timer) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := Status{Phase: t.phase, Completed: t.completed, Cycles: t.cfg.Cycles}
+	if t.phase != Stopped {
+		s.EndsAt = t.endsAt.Format(time.RFC3339)
+		s.RemainingSeconds = max(int(time.Until(t.endsAt).Round(time.Second).Seconds()), 0)
+	}
+	return s
+}
+
+// stopLocked cancels the current period. Must be called with t.mu held.
+func (t *Timer) stopLocked() {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.phase = Stopped
+	t.endsAt = time.Time{}
+}
+
+// beginLocked starts a period of phase and runs its steps. Must be called
+// with t.mu held.
+func (t *Timer) beginLocked(phase string) {
+	minutes, steps := t.cfg.WorkMinutes, t.cfg.OnWork
+	switch phase {
+	case Break:
+		minutes, steps = t.cfg.BreakMinutes, t.cfg.OnBreak
+	case LongBreak:
+		minutes, steps = t.cfg.LongBreakMinutes, t.cfg.OnBreak
+	}
+	t.phase = phase
+	t.endsAt = time.Now().Add(time.Duration(minutes) * time.Minute)
+	t.seq++
+	seq := t.seq
+	t.timer = time.AfterFunc(time.Until(t.endsAt), func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.seq == seq && t.phase != Stopped { // not stopped or skipped meanwhile
+			t.nextLocked()
+		}
+	})
+	log.Printf("[focus] %s for %dm", phase, minutes)
+	t.bus.Publish(events.Event{Type: events.TypeFocus, Name: phase, Detail: t.endsAt.Format("15:04")})
+	go t.runSteps(phase, steps)
+}
+
+// nextLocked ends the current period and begins the next one, or ends
+// the session after its last work period. Must be called with t.mu held.
+func (t *Timer) nextLocked() {
+	if t.phase != Work {
+		t.beginLocked(Work)
+		return
+	}
+	t.completed++
+	if t.cfg.Cycles > 0 && t.completed >= t.cfg.Cycles {
+		t.stopLocked()
+		log.Printf("[focus] done after %d work periods", t.completed)
+		t.bus.Publish(events.Event{Type: events.TypeFocus, Name: "done"})
+		go t.runSteps("done", t.cfg.OnDone)
+		return
+	}
+	if t.cfg.LongBreakEvery > 0 && t.cfg.LongBreakMinutes > 0 && t.completed%t.cfg.LongBreakEvery == 0 {
+		t.beginLocked(LongBreak)
+		return
+	}
+	t.beginLocked(Break)
+}
+
+// runSteps runs the steps for the start of phase in order. A step that
+// fails is logged and the rest still run.
+func (t *Timer) runSteps(phase string, steps []config.FocusStep) {
+	for i, st := range steps {
+		if err := t.run(st); err != nil {
+			logging.Warnf("[focus] %s step %d: %v", phase, i+1, err)
+		}
+	}
+}
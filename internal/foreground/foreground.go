@@ -0,0 +1,64 @@
+// Package foreground reports which application owns the focused window
+// and watches for a configured set of applications (e.g. games) coming to
+// the foreground. Each platform has its own implementation file.
+package foreground
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ProcessName returns the normalized executable name of the process that
+// owns the foreground window (e.g. "eldenring" for "C:\...\eldenring.exe").
+func ProcessName() (string, error) {
+	name, err := processName()
+	if err != nil {
+		return "", err
+	}
+	return normalize(name), nil
+}
+
+// normalize lowercases a process name and strips any directory and ".exe"
+// suffix so config entries match regardless of platform.
+func normalize(name string) string {
+	name = strings.ToLower(filepath.Base(strings.TrimSpace(name)))
+	return strings.TrimSuffix(name, ".exe")
+}
+
+// Watch polls the foreground application every interval and calls
+// onChange whenever it switches between a listed application and anything
+// else. match is the matched name, or "" when no listed application is in
+// front. The list is re-read on every poll so config changes apply live.
+// Blocks until ctx is cancelled.
+func Watch(ctx context.Context, interval time.Duration, list func() []string, onChange func(match string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	current := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		match := ""
+		if names := list(); len(names) > 0 {
+			if name, err := ProcessName(); err == nil {
+				for _, n := range names {
+					if normalize(n) == name {
+						match = name
+						break
+					}
+				}
+			}
+		}
+
+		if match != current {
+			current = match
+			onChange(match)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+//go:build darwin
+
+package foreground
+
+/*
+#cgo CFLAGS: -x objective-c -fobjc-arc
+#cgo LDFLAGS: -framework AppKit
+#import <AppKit/AppKit.h>
+#include <stdlib.h>
+
+// foregroundExecutable returns a malloc'd copy of the frontmost app's
+// executable name, or NULL.
+static char *foregroundExecutable(void) {
+	NSRunningApplication *app = [[NSWorkspace sharedWorkspace] frontmostApplication];
+	if (app == nil) {
+		return NULL;
+	}
+	NSString *name = app.executableURL.lastPathComponent;
+	if (name == nil) {
+		name = app.localizedName;
+	}
+	if (name == nil) {
+		return NULL;
+	}
+	return strdup(name.UTF8String);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// processName returns the frontmost application's executable name.
+func processName() (string, error) {
+	cs := C.foregroundExecutable()
+	if cs == nil {
+		return "", fmt.Errorf("no frontmost application")
+	}
+	defer C.free(unsafe.Pointer(cs))
+	return C.GoString(cs), nil
+}
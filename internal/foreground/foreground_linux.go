@@ -0,0 +1,73 @@
+//go:build linux
+
+package foreground
+
+/*
+#cgo LDFLAGS: -lX11
+#include <X11/Xlib.h>
+#include <X11/Xatom.h>
+
+// windowProperty reads a single 32-bit CARDINAL/WINDOW property; returns 0
+// if it is missing.
+static unsigned long windowProperty(Display *d, Window w, const char *name, Atom type) {
+	Atom prop = XInternAtom(d, name, True);
+	if (prop == None) {
+		return 0;
+	}
+	Atom actualType;
+	int actualFormat;
+	unsigned long nitems, bytesAfter;
+	unsigned char *data = NULL;
+	unsigned long value = 0;
+	if (XGetWindowProperty(d, w, prop, 0, 1, False, type, &actualType, &actualFormat,
+			&nitems, &bytesAfter, &data) == Success && data != NULL) {
+		if (nitems > 0 && actualFormat == 32) {
+			value = ((unsigned long *)data)[0];
+		}
+		XFree(data);
+	}
+	return value;
+}
+
+// activeWindowPID returns the _NET_WM_PID of the _NET_ACTIVE_WINDOW, or 0.
+static unsigned long activeWindowPID(Display *d) {
+	Window active = (Window)windowProperty(d, DefaultRootWindow(d), "_NET_ACTIVE_WINDOW", XA_WINDOW);
+	if (active == 0) {
+		return 0;
+	}
+	return windowProperty(d, active, "_NET_WM_PID", XA_CARDINAL);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	displayOnce sync.Once
+	display     *C.Display
+)
+
+// processName asks the window manager (EWMH) for the active window's PID
+// and reads the process name from /proc.
+func processName() (string, error) {
+	displayOnce.Do(func() { display = C.XOpenDisplay(nil) })
+	if display == nil {
+		return "", fmt.Errorf("cannot open X display")
+	}
+
+	pid := C.activeWindowPID(display)
+	if pid == 0 {
+		return "", fmt.Errorf("no active window PID")
+	}
+
+	comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", uint64(pid)))
+	if err != nil {
+		return "", fmt.Errorf("read process name: %w", err)
+	}
+	return strings.TrimSpace(string(comm)), nil
+}
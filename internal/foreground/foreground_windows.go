@@ -0,0 +1,35 @@
+//go:build windows
+
+package foreground
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// processName resolves the foreground window's owning process image path.
+func processName() (string, error) {
+	hwnd := windows.GetForegroundWindow()
+	if hwnd == 0 {
+		return "", fmt.Errorf("no foreground window")
+	}
+
+	var pid uint32
+	if _, err := windows.GetWindowThreadProcessId(hwnd, &pid); err != nil {
+		return "", fmt.Errorf("get window process: %w", err)
+	}
+
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", fmt.Errorf("open process %d: %w", pid, err)
+	}
+	defer windows.CloseHandle(h)
+
+	buf := make([]uint16, windows.MAX_PATH)
+	n := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(h, 0, &buf[0], &n); err != nil {
+		return "", fmt.Errorf("query process image: %w", err)
+	}
+	return windows.UTF16ToString(buf[:n]), nil
+}
@@ -0,0 +1,355 @@
+// Package gestures is the library of named gesture presets, e.g. "back",
+// embedded in the binary and overridable from gestures.json in the config
+// dir. A preset can be limited to firmware versions, since RabbitOS
+// layouts move between releases.
+package gestures
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/safefile"
+)
+
+// Schema identifies the presets format. Bump it on incompatible changes.
+const Schema = "r1-control/gestures/v1"
+
+//go:embed presets.json
+var builtinJSON []byte
+
+// Duration limits for a preset's stroke.
+const (
+	DefaultDurationMs = 200
+	MinDurationMs     = 50
+	MaxDurationMs     = 5000
+)
+
+// Where a preset came from.
+const (
+	SourceBuiltin = "builtin"
+	SourceUser    = "user" // gestures.json in the config dir
+)
+
+// File is the format of presets.json and the user's gestures.json.
+type File struct {
+	Schema  string   `json:"schema"`
+	Presets []Preset `json:"presets"`
+}
+
+// Preset is one named gesture: a finger moving from From to To.
+type Preset struct {
+	Name        string   `json:"name"`
+	Version     int      `json:"version"` // bumped when the preset changes
+	Description string   `json:"description,omitempty"`
+	Firmware    []string `json:"firmware,omitempty"` // version prefixes, e.g. "0.8"; empty = any
+	From        Point    `json:"from"`
+	To          Point    `json:"to"`
+	DurationMs  int      `json:"duration_ms,omitempty"` // 0 = DefaultDurationMs
+	Source      string   `json:"source,omitempty"`      // set on load
+}
+
+// Point is a touch position, 0-32767 on both axes from the top-left.
+type Point struct {
+	X uint16 `json:"x"`
+	Y uint16 `json:"y"`
+}
+
+// Library holds the presets available to choose from.
+type Library struct {
+	presets []Preset
+}
+
+// Path returns the full path to the user's preset overrides.
+func Path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gestures.json"), nil
+}
+
+// Builtin returns the presets shipped with the app.
+func Builtin() *Library {
+	f, err := parse(builtinJSON, SourceBuiltin)
+	if err != nil {
+		panic("gestures: presets.json: " + err.Error()) // embedded, so only a broken edit gets here
+	}
+	return &Library{presets: f.Presets}
+}
+
+// Load returns the built-in presets with the user's gestures.json applied
+// on top: a user preset replaces the built-in one with the same name and
+// firmware list, and any other is added. A missing file is not an error;
+// a broken one is, along with the built-in presets alone.
+func Load() (*Library, error) {
+	lib := Builtin()
+	presets, err := UserPresets()
+	if err != nil {
+		return lib, err
+	}
+	for _, up := range presets {
+		lib.override(up)
+	}
+	if len(presets) > 0 {
+		log.Printf("[gestures] %d presets from gestures.json", len(presets))
+	}
+	return lib, nil
+}
+
+// userMu serializes changes to gestures.json made through the app.
+var userMu sync.Mutex
+
+// UserPresets returns the presets in the user's gestures.json; none if
+// there is no such file.
+func UserPresets() ([]Preset, error) {
+	p, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	var f *File
+	_, err = safefile.Read(p, func(data []byte) (err error) {
+		f, err = parse(data, SourceUser)
+		return err
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	return f.Presets, nil
+}
+
+// AddUser adds p to gestures.json and returns it as saved. It fails if
+// there is a user preset with p's name and firmware list already.
+func AddUser(p Preset) (Preset, error) {
+	return putUser(p, false)
+}
+
+// PutUser adds p to gestures.json, replacing the user preset with p's
+// name and firmware list, and returns it as saved.
+func PutUser(p Preset) (Preset, error) {
+	return putUser(p, true)
+}
+
+func putUser(p Preset, replace bool) (Preset, error) {
+	if err := p.normalize(); err != nil {
+		return p, err
+	}
+	p.Source = SourceUser
+	userMu.Lock()
+	defer userMu.Unlock()
+	presets, err := UserPresets()
+	if err != nil {
+		return p, err
+	}
+	i := slices.IndexFunc(presets, func(old Preset) bool {
+		return old.Name == p.Name && sameFirmware(old.Firmware, p.Firmware)
+	})
+	switch {
+	case i < 0:
+		presets = append(presets, p)
+	case replace:
+		presets[i] = p
+	default:
+		return p, fmt.Errorf("there is a preset %q for these firmware versions already", p.Name)
+	}
+	return p, saveUser(presets)
+}
+
+// DeleteUser removes the user presets called name from gestures.json,
+// only the one for the firmware list if firmware isn't nil, and returns
+// how many it removed. Built-in presets can't be removed.
+func DeleteUser(name string, firmware []string) (int, error) {
+	userMu.Lock()
+	defer userMu.Unlock()
+	presets, err := UserPresets()
+	if err != nil {
+		return 0, err
+	}
+	kept := slices.DeleteFunc(slices.Clone(presets), func(p Preset) bool {
+		return p.Name == name && (firmware == nil || sameFirmware(p.Firmware, firmware))
+	})
+	n := len(presets) - len(kept)
+	if n == 0 {
+		return 0, nil
+	}
+	return n, saveUser(kept)
+}
+
+// saveUser writes presets to gestures.json.
+func saveUser(presets []Preset) error {
+	f := File{Schema: Schema, Presets: make([]Preset, len(presets))}
+	for i, p := range presets {
+		p.Source = ""
+		f.Presets[i] = p
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal gestures: %w", err)
+	}
+	p, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	if err := safefile.Write(p, data); err != nil {
+		return fmt.Errorf("save gestures: %w", err)
+	}
+	return nil
+}
+
+// override replaces the preset with p's name and firmware list, or adds p.
+func (l *Library) override(p Preset) {
+	for i, old := range l.presets {
+		if old.Name != p.Name || !sameFirmware(old.Firmware, p.Firmware) {
+			continue
+		}
+		if old.Version > p.Version {
+			log.Printf("[gestures] %q in gestures.json is version %d, the built-in one is %d — using yours", p.Name, p.Version, old.Version)
+		}
+		l.presets[i] = p
+		return
+	}
+	l.presets = append(l.presets, p)
+}
+
+// Find returns the preset called name that best fits firmware: the one
+// limited to the longest matching version prefix, or else one for any
+// firmware. An empty firmware only matches presets for any firmware.
+func (l *Library) Find(name, firmware string) (Preset, error) {
+	best, bestLen := Preset{}, -1
+	for _, p := range l.presets {
+		if p.Name != name {
+			continue
+		}
+		// Ties go to the later preset, so user presets win
+		if n := matchLen(p.Firmware, firmware); n >= bestLen && n >= 0 {
+			best, bestLen = p, n
+		}
+	}
+	if bestLen < 0 {
+		return Preset{}, fmt.Errorf("no gesture preset %q for firmware %q", name, firmware)
+	}
+	return best, nil
+}
+
+// For returns the best preset of each name for firmware, sorted by name.
+func (l *Library) For(firmware string) []Preset {
+	seen := make(map[string]bool)
+	var out []Preset
+	for _, p := range l.presets {
+		if seen[p.Name] {
+			continue
+		}
+		seen[p.Name] = true
+		if best, err := l.Find(p.Name, firmware); err == nil {
+			out = append(out, best)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// parse decodes and checks a presets file, marking each preset with
+// source.
+func parse(data []byte, source string) (*File, error) {
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Schema != Schema {
+		return nil, fmt.Errorf("schema %q, want %q", f.Schema, Schema)
+	}
+	for i := range f.Presets {
+		p := &f.Presets[i]
+		if err := p.normalize(); err != nil {
+			if strings.TrimSpace(p.Name) == "" {
+				return nil, fmt.Errorf("preset %d: %w", i, err)
+			}
+			return nil, fmt.Errorf("preset %q: %w", p.Name, err)
+		}
+		p.Source = source
+	}
+	return &f, nil
+}
+
+// Validate checks a preset as it would be checked in gestures.json.
+func Validate(p Preset) error {
+	return p.normalize()
+}
+
+// normalize checks p and fills in defaults.
+func (p *Preset) normalize() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is empty")
+	}
+	if p.From.X > 32767 || p.From.Y > 32767 || p.To.X > 32767 || p.To.Y > 32767 {
+		return errors.New("coordinates must be in range 0-32767")
+	}
+	if p.DurationMs == 0 {
+		p.DurationMs = DefaultDurationMs
+	}
+	if p.DurationMs < MinDurationMs || p.DurationMs > MaxDurationMs {
+		return fmt.Errorf("duration_ms must be in range %d-%d", MinDurationMs, MaxDurationMs)
+	}
+	return nil
+}
+
+// NormalizeFirmware returns the version number in a firmware string,
+// e.g. "rabbitOS v0.8.112" → "0.8.112".
+func NormalizeFirmware(s string) string {
+	if i := strings.IndexFunc(s, unicode.IsDigit); i >= 0 {
+		return strings.TrimSpace(s[i:])
+	}
+	return ""
+}
+
+// matchLen returns how specifically prefixes match firmware: the length
+// of the longest matching prefix, 0 for a preset for any firmware, or -1
+// if none matches.
+func matchLen(prefixes []string, firmware string) int {
+	if len(prefixes) == 0 {
+		return 0
+	}
+	v := NormalizeFirmware(firmware)
+	best := -1
+	for _, p := range prefixes {
+		p = NormalizeFirmware(p)
+		if p != "" && (v == p || strings.HasPrefix(v, p+".")) && len(p) > best {
+			best = len(p)
+		}
+	}
+	return best
+}
+
+// FirmwareMatch reports how specifically a version prefix, e.g. "0.8",
+// matches firmware: the prefix's length, or -1 if it doesn't match.
+func FirmwareMatch(prefix, firmware string) int {
+	return matchLen([]string{prefix}, firmware)
+}
+
+func sameFirmware(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if NormalizeFirmware(a[i]) != NormalizeFirmware(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+//go:build darwin
+
+package hostcontext
+
+/*
+#cgo LDFLAGS: -framework CoreGraphics
+#include <CoreGraphics/CoreGraphics.h>
+
+static int activeDisplays(void) {
+	uint32_t n = 0;
+	if (CGGetOnlineDisplayList(0, NULL, &n) != kCGErrorSuccess) {
+		return 0;
+	}
+	return (int)n;
+}
+*/
+import "C"
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// displays counts the online displays, including mirrored ones.
+func displays() int {
+	return int(C.activeDisplays())
+}
+
+// ssid finds the Wi-Fi interface and asks networksetup for its network.
+func ssid() string {
+	out, err := exec.Command("networksetup", "-listallhardwareports").Output()
+	if err != nil {
+		return ""
+	}
+	// "Hardware Port: Wi-Fi" is followed by "Device: en0"
+	lines := strings.Split(string(out), "\n")
+	for i, line := range lines {
+		if !strings.HasSuffix(strings.TrimSpace(line), "Wi-Fi") || i+1 >= len(lines) {
+			continue
+		}
+		dev, ok := strings.CutPrefix(strings.TrimSpace(lines[i+1]), "Device: ")
+		if !ok {
+			continue
+		}
+		out, err := exec.Command("networksetup", "-getairportnetwork", dev).Output()
+		if err != nil {
+			return ""
+		}
+		name, ok := strings.CutPrefix(strings.TrimSpace(string(out)), "Current Wi-Fi Network: ")
+		if !ok {
+			return "" // "You are not associated with an AirPort network."
+		}
+		return name
+	}
+	return ""
+}
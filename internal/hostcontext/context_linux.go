@@ -0,0 +1,67 @@
+//go:build linux
+
+package hostcontext
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// usbDevices lists the attached USB devices from sysfs, which also has
+// their serial numbers without opening them.
+func usbDevices() []string {
+	dirs, _ := filepath.Glob("/sys/bus/usb/devices/*")
+	var devs []string
+	for _, dir := range dirs {
+		vid, pid := readAttr(dir, "idVendor"), readAttr(dir, "idProduct")
+		if vid == "" || pid == "" {
+			continue // an interface, not a device
+		}
+		devs = append(devs, vid+":"+pid)
+		if serial := readAttr(dir, "serial"); serial != "" {
+			devs = append(devs, serial)
+		}
+	}
+	return devs
+}
+
+// displays counts the connected outputs of the graphics cards in sysfs.
+func displays() int {
+	files, _ := filepath.Glob("/sys/class/drm/card*-*/status")
+	n := 0
+	for _, f := range files {
+		if b, err := os.ReadFile(f); err == nil && strings.TrimSpace(string(b)) == "connected" {
+			n++
+		}
+	}
+	return n
+}
+
+// ssid asks NetworkManager for the active Wi-Fi network, falling back to
+// iwgetid.
+func ssid() string {
+	if out, err := exec.Command("nmcli", "-t", "-f", "active,ssid", "dev", "wifi").Output(); err == nil {
+		for _, line := range strings.Split(string(out), "\n") {
+			if name, ok := strings.CutPrefix(line, "yes:"); ok {
+				// Terse output escapes colons and backslashes
+				return strings.NewReplacer(`\:`, ":", `\\`, `\`).Replace(name)
+			}
+		}
+		return ""
+	}
+	if out, err := exec.Command("iwgetid", "-r").Output(); err == nil {
+		return strings.TrimSpace(string(out))
+	}
+	return ""
+}
+
+// readAttr returns the trimmed sysfs attribute name in dir, or "".
+func readAttr(dir, name string) string {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
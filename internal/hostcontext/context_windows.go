@@ -0,0 +1,42 @@
+//go:build windows
+
+package hostcontext
+
+import (
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+var procGetSystemMetrics = windows.NewLazySystemDLL("user32.dll").NewProc("GetSystemMetrics")
+
+const (
+	smCMonitors    = 80
+	createNoWindow = 0x08000000
+)
+
+// displays counts the monitors on the desktop.
+func displays() int {
+	n, _, _ := procGetSystemMetrics.Call(smCMonitors)
+	return int(n)
+}
+
+// ssid asks netsh for the network of the first connected Wi-Fi
+// interface.
+func ssid() string {
+	cmd := exec.Command("netsh", "wlan", "show", "interfaces")
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: createNoWindow}
+	out, err := cmd.Output()
+	if err != nil {
+		return "" // no WLAN service, e.g. a desktop without Wi-Fi
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "SSID" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
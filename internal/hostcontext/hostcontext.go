@@ -0,0 +1,78 @@
+// Package hostcontext works out where the computer is — which USB
+// devices are attached, how many displays are connected and which Wi-Fi
+// network it has joined — and matches that against the configured
+// rules, so profiles can follow a laptop between docks.
+package hostcontext
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+)
+
+// pollInterval is how often Watch looks again.
+const pollInterval = 30 * time.Second
+
+// Snapshot is the computer's surroundings at one moment.
+type Snapshot struct {
+	USB      []string `json:"usb"`            // attached USB devices as "vid:pid", and serial numbers where readable
+	Displays int      `json:"displays"`       // connected displays; 0 = unknown
+	SSID     string   `json:"ssid,omitempty"` // Wi-Fi network; "" = none or unknown
+}
+
+// Detect looks at the computer's surroundings now. Anything that can't be
+// read is left empty rather than failing the rest.
+func Detect() Snapshot {
+	return Snapshot{USB: usbDevices(), Displays: displays(), SSID: ssid()}
+}
+
+// Match returns the index of the first rule that holds in s, or -1.
+func Match(rules []config.ContextRule, s Snapshot) int {
+	return slices.IndexFunc(rules, func(r config.ContextRule) bool { return matches(r, s) })
+}
+
+// matches reports whether everything r names is true in s. A rule that
+// names nothing never matches.
+func matches(r config.ContextRule, s Snapshot) bool {
+	if r.USB == "" && r.Displays == 0 && r.SSID == "" {
+		return false
+	}
+	if r.USB != "" && !slices.ContainsFunc(s.USB, func(d string) bool { return strings.EqualFold(d, r.USB) }) {
+		return false
+	}
+	if r.Displays != 0 && r.Displays != s.Displays {
+		return false
+	}
+	return r.SSID == "" || r.SSID == s.SSID
+}
+
+// Watch looks at the surroundings now and every pollInterval until ctx is
+// done, and calls fn with the profile of the first matching rule, and the
+// rule's number from 1, whenever it differs from the last one, starting
+// from current. While no rule matches, the last profile stays; matching
+// it again calls fn again.
+func Watch(ctx context.Context, rules []config.ContextRule, current string, fn func(profile string, rule int)) {
+	last := current
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		s := Detect()
+		switch i := Match(rules, s); {
+		case i < 0:
+			last = ""
+		case rules[i].Profile != last:
+			last = rules[i].Profile
+			log.Printf("[hostcontext] rule %d matched: profile %q", i+1, last)
+			fn(last, i+1)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
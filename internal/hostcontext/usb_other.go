@@ -0,0 +1,23 @@
+//go:build !linux
+
+package hostcontext
+
+import (
+	"fmt"
+
+	"github.com/google/gousb"
+)
+
+// usbDevices lists the attached USB devices through libusb. Serial
+// numbers would mean opening each device, which needs a driver on
+// Windows, so only vendor and product IDs are listed.
+func usbDevices() []string {
+	ctx := gousb.NewContext()
+	defer ctx.Close()
+	var devs []string
+	ctx.OpenDevices(func(desc *gousb.DeviceDesc) bool {
+		devs = append(devs, fmt.Sprintf("%04x:%04x", uint16(desc.Vendor), uint16(desc.Product)))
+		return false // inspect only, never open
+	})
+	return devs
+}
@@ -0,0 +1,75 @@
+// Package hostmic mirrors the R1's PTT state to the host microphone: the
+// default input device is muted while PTT is off and unmuted while it is
+// on. Each platform has its own backend (PulseAudio, CoreAudio, WASAPI).
+package hostmic
+
+import (
+	"log"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// request is a queued mute change. done, if non-nil, is closed once the
+// change has been applied.
+type request struct {
+	muted bool
+	done  chan struct{}
+}
+
+var (
+	startOnce sync.Once
+	pending   = make(chan request, 1)
+)
+
+// SetMuted requests that the host's default microphone be muted or
+// unmuted. The change is applied asynchronously on a dedicated goroutine
+// so callers (device state callbacks) never block on the audio backend;
+// if several requests queue up, only the most recent one is applied.
+func SetMuted(muted bool) {
+	enqueue(request{muted: muted})
+}
+
+// Release unmutes the host microphone and waits (up to 2 seconds) for the
+// change to be applied. Call it on shutdown so the mic isn't left muted.
+func Release() {
+	done := make(chan struct{})
+	enqueue(request{muted: false, done: done})
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		log.Println("[hostmic] timed out unmuting microphone")
+	}
+}
+
+func enqueue(req request) {
+	startOnce.Do(func() { go worker() })
+
+	// Replace any request that hasn't been applied yet.
+	select {
+	case old := <-pending:
+		if old.done != nil {
+			close(old.done)
+		}
+	default:
+	}
+	pending <- req
+}
+
+// worker applies mute requests in order. It stays on one OS thread because
+// some backends (COM on Windows) require per-thread initialization.
+func worker() {
+	runtime.LockOSThread()
+
+	if err := initBackend(); err != nil {
+		log.Printf("[hostmic] init: %v", err)
+	}
+	for req := range pending {
+		if err := setMuted(req.muted); err != nil {
+			log.Printf("[hostmic] set muted=%v: %v", req.muted, err)
+		}
+		if req.done != nil {
+			close(req.done)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+//go:build darwin
+
+package hostmic
+
+/*
+#cgo LDFLAGS: -framework CoreAudio
+#include <CoreAudio/CoreAudio.h>
+
+static OSStatus hostmicSetMute(UInt32 mute) {
+	AudioObjectPropertyAddress addr = {
+		kAudioHardwarePropertyDefaultInputDevice,
+		kAudioObjectPropertyScopeGlobal,
+		0, // main element
+	};
+	AudioDeviceID dev = 0;
+	UInt32 size = sizeof(dev);
+	OSStatus st = AudioObjectGetPropertyData(kAudioObjectSystemObject, &addr, 0, NULL, &size, &dev);
+	if (st != noErr) {
+		return st;
+	}
+
+	AudioObjectPropertyAddress muteAddr = {
+		kAudioDevicePropertyMute,
+		kAudioDevicePropertyScopeInput,
+		0, // main element
+	};
+	if (!AudioObjectHasProperty(dev, &muteAddr)) {
+		return kAudioHardwareUnknownPropertyError;
+	}
+	return AudioObjectSetPropertyData(dev, &muteAddr, 0, NULL, sizeof(mute), &mute);
+}
+*/
+import "C"
+
+import "fmt"
+
+// initBackend is a no-op: CoreAudio needs no per-thread setup.
+func initBackend() error { return nil }
+
+// setMuted sets the mute property of the default input device.
+func setMuted(muted bool) error {
+	var v C.UInt32
+	if muted {
+		v = 1
+	}
+	if st := C.hostmicSetMute(v); st != 0 {
+		return fmt.Errorf("CoreAudio error %d", int32(st))
+	}
+	return nil
+}
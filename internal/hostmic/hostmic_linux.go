@@ -0,0 +1,25 @@
+//go:build linux
+
+package hostmic
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// initBackend is a no-op: PulseAudio (and PipeWire's pulse server) are
+// driven through pactl, which needs no per-thread setup.
+func initBackend() error { return nil }
+
+// setMuted mutes the default PulseAudio source.
+func setMuted(muted bool) error {
+	arg := "0"
+	if muted {
+		arg = "1"
+	}
+	out, err := exec.Command("pactl", "set-source-mute", "@DEFAULT_SOURCE@", arg).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("pactl: %w (%s)", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+//go:build windows
+
+package hostmic
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	ole32                = windows.NewLazySystemDLL("ole32.dll")
+	procCoInitializeEx   = ole32.NewProc("CoInitializeEx")
+	procCoCreateInstance = ole32.NewProc("CoCreateInstance")
+)
+
+var (
+	clsidMMDeviceEnumerator = windows.GUID{Data1: 0xBCDE0395, Data2: 0xE52F, Data3: 0x467C, Data4: [8]byte{0x8E, 0x3D, 0xC4, 0x57, 0x92, 0x91, 0x69, 0x2E}}
+	iidIMMDeviceEnumerator  = windows.GUID{Data1: 0xA95664D2, Data2: 0x9614, Data3: 0x4F35, Data4: [8]byte{0xA7, 0x46, 0xDE, 0x8D, 0xB6, 0x36, 0x17, 0xE6}}
+	iidIAudioEndpointVolume = windows.GUID{Data1: 0x5CDF2C82, Data2: 0x841E, Data3: 0x4546, Data4: [8]byte{0x97, 0x22, 0x0C, 0xF7, 0x40, 0x78, 0x22, 0x9A}}
+)
+
+const (
+	coinitMultithreaded = 0x0
+	clsctxAll           = 0x17
+	eCapture            = 1
+	eConsole            = 0
+
+	// vtable slots (IUnknown occupies 0-2)
+	vtRelease                 = 2
+	vtGetDefaultAudioEndpoint = 4  // IMMDeviceEnumerator
+	vtActivate                = 3  // IMMDevice
+	vtSetMute                 = 14 // IAudioEndpointVolume
+)
+
+// comObject is the memory layout of a COM interface pointer.
+type comObject struct {
+	vtbl *[32]uintptr
+}
+
+// comCall invokes method slot i on a COM object.
+func comCall(obj *comObject, i int, args ...uintptr) uintptr {
+	r, _, _ := syscall.SyscallN(obj.vtbl[i], append([]uintptr{uintptr(unsafe.Pointer(obj))}, args...)...)
+	return r
+}
+
+func comRelease(obj *comObject) {
+	if obj != nil {
+		comCall(obj, vtRelease)
+	}
+}
+
+// initBackend initializes COM on the worker's OS thread.
+func initBackend() error {
+	hr, _, _ := procCoInitializeEx.Call(0, coinitMultithreaded)
+	if int32(hr) < 0 {
+		return fmt.Errorf("CoInitializeEx: 0x%08x", uint32(hr))
+	}
+	return nil
+}
+
+// setMuted mutes the default capture endpoint via IAudioEndpointVolume.
+func setMuted(muted bool) error {
+	var enum *comObject
+	hr, _, _ := procCoCreateInstance.Call(
+		uintptr(unsafe.Pointer(&clsidMMDeviceEnumerator)), 0, clsctxAll,
+		uintptr(unsafe.Pointer(&iidIMMDeviceEnumerator)), uintptr(unsafe.Pointer(&enum)))
+	if int32(hr) < 0 {
+		return fmt.Errorf("create device enumerator: 0x%08x", uint32(hr))
+	}
+	defer comRelease(enum)
+
+	var dev *comObject
+	if hr := comCall(enum, vtGetDefaultAudioEndpoint, eCapture, eConsole, uintptr(unsafe.Pointer(&dev))); int32(hr) < 0 {
+		return fmt.Errorf("get default capture endpoint: 0x%08x", uint32(hr))
+	}
+	defer comRelease(dev)
+
+	var vol *comObject
+	if hr := comCall(dev, vtActivate, uintptr(unsafe.Pointer(&iidIAudioEndpointVolume)), clsctxAll, 0, uintptr(unsafe.Pointer(&vol))); int32(hr) < 0 {
+		return fmt.Errorf("activate endpoint volume: 0x%08x", uint32(hr))
+	}
+	defer comRelease(vol)
+
+	var b uintptr
+	if muted {
+		b = 1
+	}
+	if hr := comCall(vol, vtSetMute, b, 0); int32(hr) < 0 {
+		return fmt.Errorf("SetMute: 0x%08x", uint32(hr))
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package hostnotify
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// Button is an action button on a posted notification.
+type Button struct {
+	ID    string // returned by PostButtons when the button is clicked
+	Label string
+}
+
+// ErrNoButtons is returned by PostButtons where notifications can't
+// carry buttons.
+var ErrNoButtons = errors.New("notification buttons are only supported on Windows")
+
+// ButtonsSupported reports whether PostButtons can show buttons here.
+func ButtonsSupported() bool {
+	return buttonsSupported
+}
+
+// PostButtons shows a desktop notification with buttons and waits up to
+// wait for one to be clicked, returning its ID. It returns "" if the
+// notification was dismissed or wait passed first. Cancelling ctx takes
+// the notification down and returns ctx's error. Only Windows toasts
+// support buttons; elsewhere it returns ErrNoButtons without posting.
+func PostButtons(ctx context.Context, title, body string, buttons []Button, wait time.Duration) (string, error) {
+	if !buttonsSupported {
+		return "", ErrNoButtons
+	}
+	log.Printf("[hostnotify] posted %q with %d buttons", title, len(buttons))
+	return postButtons(ctx, title, body, buttons, wait)
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package hostnotify
+
+import (
+	"context"
+	"time"
+)
+
+const buttonsSupported = false
+
+func postButtons(context.Context, string, string, []Button, time.Duration) (string, error) {
+	return "", ErrNoButtons
+}
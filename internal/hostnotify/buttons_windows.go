@@ -0,0 +1,97 @@
+//go:build windows
+
+package hostnotify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const buttonsSupported = true
+
+// buttonsScript shows a toast like toastScript, with a button for each
+// "id<TAB>label" line of R1_NOTIFY_BUTTONS, then stays running so the
+// toast's events reach it: it prints the ID of the button clicked, or
+// nothing if the toast is dismissed or R1_NOTIFY_WAIT seconds pass.
+const buttonsScript = `$ErrorActionPreference = 'Stop'
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($env:R1_NOTIFY_TITLE)) | Out-Null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:R1_NOTIFY_BODY)) | Out-Null
+$actions = $xml.CreateElement('actions')
+foreach ($line in $env:R1_NOTIFY_BUTTONS -split "` + "`" + `n") {
+	$id, $label = $line -split "` + "`" + `t", 2
+	$button = $xml.CreateElement('action')
+	$button.SetAttribute('content', $label)
+	$button.SetAttribute('arguments', $id)
+	$button.SetAttribute('activationType', 'foreground')
+	$actions.AppendChild($button) | Out-Null
+}
+$xml.DocumentElement.AppendChild($actions) | Out-Null
+$wait = [int]$env:R1_NOTIFY_WAIT
+$toast = [Windows.UI.Notifications.ToastNotification]::new($xml)
+$toast.Tag = $env:R1_NOTIFY_TAG
+$toast.Group = 'r1control'
+$toast.ExpirationTime = [DateTimeOffset]::Now.AddSeconds($wait)
+Register-ObjectEvent -InputObject $toast -EventName Activated -SourceIdentifier Clicked | Out-Null
+Register-ObjectEvent -InputObject $toast -EventName Dismissed -SourceIdentifier Dismissed | Out-Null
+$app = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($app).Show($toast)
+$event = Wait-Event -Timeout $wait
+if ($event -and $event.SourceIdentifier -eq 'Clicked') {
+	[Console]::Out.Write($event.SourceArgs[1].Arguments)
+}`
+
+// removeScript takes down the toast tagged R1_NOTIFY_TAG, which would
+// otherwise stay in the notification center with buttons nothing
+// listens to any more.
+const removeScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$app = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::History.Remove($env:R1_NOTIFY_TAG, 'r1control', $app)`
+
+// postButtons shows the toast through PowerShell and waits for its
+// answer.
+func postButtons(ctx context.Context, title, body string, buttons []Button, wait time.Duration) (string, error) {
+	lines := make([]string, len(buttons))
+	for i, b := range buttons {
+		lines[i] = b.ID + "\t" + b.Label
+	}
+	tag := "r1-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", buttonsScript)
+	cmd.Env = append(os.Environ(),
+		"R1_NOTIFY_TITLE="+title,
+		"R1_NOTIFY_BODY="+body,
+		"R1_NOTIFY_BUTTONS="+strings.Join(lines, "\n"),
+		"R1_NOTIFY_WAIT="+strconv.Itoa(int(wait.Seconds())),
+		"R1_NOTIFY_TAG="+tag,
+	)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: createNoWindow}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		removeToast(tag)
+		return "", ctx.Err()
+	}
+	if err != nil {
+		return "", fmt.Errorf("powershell: %w (%s)", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// removeToast takes down the toast tagged tag, if it is still shown.
+func removeToast(tag string) {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", removeScript)
+	cmd.Env = append(os.Environ(), "R1_NOTIFY_TAG="+tag)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: createNoWindow}
+	_ = cmd.Run()
+}
@@ -0,0 +1,84 @@
+// Package hostnotify watches the host's desktop notifications, so the R1
+// can light up as a second notifier when selected apps post one, and
+// posts the app's own. Only Linux desktops can be watched, through the
+// freedesktop notification service on the session D-Bus: macOS has no
+// public way for one app to read another's notifications, and Windows
+// only grants it to packaged apps the user approves.
+package hostnotify
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// retryInterval is how long to wait before restarting a watcher that
+// stopped, e.g. when the desktop session's bus restarted.
+const retryInterval = 5 * time.Second
+
+// ErrUnsupported is returned by Watch where notifications can't be read.
+var ErrUnsupported = errors.New("reading the host's notifications isn't supported on this system")
+
+// Notification is a desktop notification posted on the host.
+type Notification struct {
+	App     string // as the app names itself, e.g. "Slack", "Thunderbird"
+	Summary string // the notification's title
+}
+
+// Watch calls fn for every notification posted on the host until ctx is
+// done, restarting the watcher if it stops. It returns right away with an
+// error if notifications can't be watched here at all.
+func Watch(ctx context.Context, fn func(Notification)) error {
+	if err := available(); err != nil {
+		return err
+	}
+	go func() {
+		for {
+			if err := watch(ctx, fn); err != nil && ctx.Err() == nil {
+				log.Printf("[hostnotify] %v — restarting in %v", err, retryInterval)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryInterval):
+			}
+		}
+	}()
+	return nil
+}
+
+// parseMonitor reads dbus-monitor output from r, calling fn for each call
+// to org.freedesktop.Notifications.Notify, whose first string arguments
+// are the app name, the icon and the summary. It returns when r ends.
+func parseMonitor(r io.Reader, fn func(Notification)) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	var args []string // string arguments of the Notify call being read; nil = none
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "method call ") || strings.HasPrefix(line, "signal ") || strings.HasPrefix(line, "method return ") {
+			args = nil
+			if strings.Contains(line, "interface=org.freedesktop.Notifications;") && strings.HasSuffix(line, "member=Notify") {
+				args = []string{}
+			}
+			continue
+		}
+		if args == nil {
+			continue
+		}
+		s, ok := strings.CutPrefix(strings.TrimSpace(line), `string "`)
+		if !ok {
+			continue
+		}
+		args = append(args, strings.TrimSuffix(s, `"`))
+		if len(args) == 3 {
+			fn(Notification{App: args[0], Summary: args[2]})
+			args = nil
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,43 @@
+//go:build linux
+
+package hostnotify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// monitorRule selects the calls that post notifications.
+const monitorRule = "interface='org.freedesktop.Notifications',member='Notify'"
+
+// available checks that there is a session bus and dbus-monitor to watch
+// it with.
+func available() error {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" && os.Getenv("XDG_RUNTIME_DIR") == "" {
+		return fmt.Errorf("%w: no desktop session bus", ErrUnsupported)
+	}
+	if _, err := exec.LookPath("dbus-monitor"); err != nil {
+		return fmt.Errorf("dbus-monitor not found — install dbus (e.g. the dbus-bin or dbus-tools package) to wake the R1 on notifications")
+	}
+	return nil
+}
+
+// watch runs dbus-monitor on the session bus until ctx is done or it
+// exits.
+func watch(ctx context.Context, fn func(Notification)) error {
+	cmd := exec.CommandContext(ctx, "dbus-monitor", "--session", monitorRule)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("dbus-monitor: %w", err)
+	}
+	parseErr := parseMonitor(out, fn)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("dbus-monitor: %w", err)
+	}
+	return parseErr
+}
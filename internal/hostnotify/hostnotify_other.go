@@ -0,0 +1,9 @@
+//go:build !linux
+
+package hostnotify
+
+import "context"
+
+func available() error { return ErrUnsupported }
+
+func watch(context.Context, func(Notification)) error { return ErrUnsupported }
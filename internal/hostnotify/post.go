@@ -0,0 +1,16 @@
+package hostnotify
+
+import "log"
+
+// appName is how posted notifications name their sender.
+const appName = "R1 Control"
+
+// Post shows a desktop notification on the host with title and body.
+// It doesn't wait for the user to see or dismiss it.
+func Post(title, body string) error {
+	if err := post(title, body); err != nil {
+		return err
+	}
+	log.Printf("[hostnotify] posted %q", title)
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build darwin
+
+package hostnotify
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// postScript shows a notification with the title and body passed as
+// arguments, so neither needs quoting for AppleScript.
+const postScript = `on run argv
+	display notification (item 2 of argv) with title (item 1 of argv)
+end run`
+
+// post sends the notification through osascript. It appears as coming
+// from Script Editor.
+func post(title, body string) error {
+	out, err := exec.Command("osascript", "-e", postScript, title, body).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("osascript: %w (%s)", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build linux
+
+package hostnotify
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// post sends the notification through notify-send, which talks to the
+// desktop's freedesktop notification service.
+func post(title, body string) error {
+	out, err := exec.Command("notify-send", "--app-name="+appName, title, body).CombinedOutput()
+	if err != nil {
+		if _, lerr := exec.LookPath("notify-send"); lerr != nil {
+			return fmt.Errorf("notify-send not found — install libnotify (e.g. the libnotify-bin package) for desktop notifications")
+		}
+		return fmt.Errorf("notify-send: %w (%s)", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Package hostpower keeps the host from sleeping while the app is in the
+// middle of something on the R1, e.g. a latched PTT or a running macro: a
+// host that sleeps mid-gesture leaves the R1 with a finger held down.
+// Each platform has its own backend (systemd-inhibit, caffeinate,
+// SetThreadExecutionState).
+package hostpower
+
+import (
+	"log"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// request is a queued change of whether sleep is blocked. done, if
+// non-nil, is closed once the change has been applied.
+type request struct {
+	block bool
+	done  chan struct{}
+}
+
+var (
+	startOnce sync.Once
+	pending   = make(chan request, 1)
+
+	mu      sync.Mutex
+	reasons = make(map[string]bool) // why sleep is blocked, e.g. "ptt"
+)
+
+// Set blocks host sleep for reason (e.g. "ptt", "macro") while held, and
+// lets the host sleep again once no reason is held. The change is applied
+// asynchronously, so callers (event subscribers) never block on the
+// backend.
+func Set(reason string, held bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if held {
+		reasons[reason] = true
+	} else {
+		delete(reasons, reason)
+	}
+	enqueue(request{block: len(reasons) > 0})
+}
+
+// Reasons returns why host sleep is blocked, sorted; nil if it isn't.
+func Reasons() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	var out []string
+	for r := range reasons {
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Release drops every reason and waits (up to 2 seconds) for the host to
+// be allowed to sleep again. Call it on shutdown and when the option is
+// turned off.
+func Release() {
+	done := make(chan struct{})
+	mu.Lock()
+	clear(reasons)
+	enqueue(request{block: false, done: done})
+	mu.Unlock()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		log.Println("[hostpower] timed out releasing the sleep block")
+	}
+}
+
+// enqueue hands req to the worker. Must be called with mu held, so
+// requests are queued in the order reasons changed and the last one
+// queued matches reasons.
+func enqueue(req request) {
+	startOnce.Do(func() { go worker() })
+
+	// Replace any request that hasn't been applied yet.
+	select {
+	case old := <-pending:
+		if old.done != nil {
+			close(old.done)
+		}
+	default:
+	}
+	pending <- req
+}
+
+// worker applies requests in order. It stays on one OS thread because
+// Windows tracks the execution state per thread.
+func worker() {
+	runtime.LockOSThread()
+
+	blocked := false
+	for req := range pending {
+		if req.block != blocked {
+			if err := setBlocked(req.block); err != nil {
+				log.Printf("[hostpower] block sleep=%v: %v", req.block, err)
+			} else {
+				blocked = req.block
+				log.Printf("[hostpower] host sleep blocked: %v", blocked)
+			}
+		}
+		if req.done != nil {
+			close(req.done)
+		}
+	}
+}
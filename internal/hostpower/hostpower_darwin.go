@@ -0,0 +1,38 @@
+//go:build darwin
+
+package hostpower
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// caffeinate is the running caffeinate, holding a power assertion; nil =
+// none. Only touched by the worker.
+var caffeinate *exec.Cmd
+
+// setBlocked starts or stops caffeinate, which prevents idle sleep while
+// it runs. -w ends it with this process, so a crash doesn't keep the host
+// awake.
+func setBlocked(block bool) error {
+	if !block {
+		if caffeinate == nil {
+			return nil
+		}
+		caffeinate.Process.Kill()
+		caffeinate.Wait()
+		caffeinate = nil
+		return nil
+	}
+	if caffeinate != nil {
+		return nil
+	}
+	cmd := exec.Command("caffeinate", "-i", "-w", strconv.Itoa(os.Getpid()))
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("caffeinate: %w", err)
+	}
+	caffeinate = cmd
+	return nil
+}
@@ -0,0 +1,43 @@
+//go:build linux
+
+package hostpower
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// inhibitor is the running systemd-inhibit, holding a sleep lock; nil =
+// none. Only touched by the worker.
+var inhibitor *exec.Cmd
+
+// setBlocked takes or releases a logind sleep inhibitor lock. The lock is
+// held by systemd-inhibit for as long as its child runs; the child also
+// exits with this process, so a crash doesn't keep the host awake.
+func setBlocked(block bool) error {
+	if !block {
+		if inhibitor == nil {
+			return nil
+		}
+		// Kill systemd-inhibit and its child together
+		syscall.Kill(-inhibitor.Process.Pid, syscall.SIGTERM)
+		inhibitor.Wait()
+		inhibitor = nil
+		return nil
+	}
+	if inhibitor != nil {
+		return nil
+	}
+	cmd := exec.Command("systemd-inhibit", "--what=sleep:idle", "--who=R1 Control",
+		"--why=Talking to the R1", "--mode=block",
+		"tail", "--pid="+strconv.Itoa(os.Getpid()), "-f", "/dev/null")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("systemd-inhibit: %w", err)
+	}
+	inhibitor = cmd
+	return nil
+}
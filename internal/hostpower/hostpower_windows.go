@@ -0,0 +1,30 @@
+//go:build windows
+
+package hostpower
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+var procSetThreadExecutionState = windows.NewLazySystemDLL("kernel32.dll").NewProc("SetThreadExecutionState")
+
+const (
+	esContinuous     = 0x80000000
+	esSystemRequired = 0x00000001
+)
+
+// setBlocked sets the worker thread's execution state. The state lasts
+// until it is changed or the thread ends, so the worker stays on one
+// thread.
+func setBlocked(block bool) error {
+	state := uintptr(esContinuous)
+	if block {
+		state |= esSystemRequired
+	}
+	if r, _, err := procSetThreadExecutionState.Call(state); r == 0 {
+		return fmt.Errorf("SetThreadExecutionState: %w", err)
+	}
+	return nil
+}
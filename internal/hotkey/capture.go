@@ -0,0 +1,38 @@
+package hotkey
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"golang.design/x/hotkey"
+)
+
+// ErrCaptureBusy is returned by Capture when another capture is running.
+var ErrCaptureBusy = errors.New("a hotkey capture is already in progress")
+
+var captureMu sync.Mutex
+
+// Capture waits for the next key combination pressed anywhere on the host
+// and returns it as config modifier and key names. Unlike the browser's
+// keydown event, this sees combinations the OS reserves (e.g. Super-based
+// shortcuts). Bare modifier presses and keys without a config name are
+// ignored. The wait ends when ctx is done.
+func Capture(ctx context.Context) (mods []string, key string, err error) {
+	if !captureMu.TryLock() {
+		return nil, "", ErrCaptureBusy
+	}
+	defer captureMu.Unlock()
+
+	return captureNext(ctx, keyName)
+}
+
+// keyName returns the config name for a platform key code, if it has one.
+func keyName(k hotkey.Key) (string, bool) {
+	for name, v := range keyMap {
+		if v == k {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,119 @@
+//go:build darwin
+
+package hotkey
+
+/*
+#cgo LDFLAGS: -framework ApplicationServices -framework CoreFoundation
+#include <ApplicationServices/ApplicationServices.h>
+
+static CFMachPortRef captureTap = NULL;
+static CFRunLoopSourceRef captureSource = NULL;
+static volatile int capturedKey = -1;
+static volatile CGEventFlags capturedFlags = 0;
+
+static CGEventRef captureCallback(CGEventTapProxy proxy, CGEventType type, CGEventRef event, void *info) {
+	if (type == kCGEventTapDisabledByTimeout || type == kCGEventTapDisabledByUserInput) {
+		CGEventTapEnable(captureTap, true);
+		return event;
+	}
+	if (type == kCGEventKeyDown && capturedKey < 0) {
+		capturedKey = (int)CGEventGetIntegerValueField(event, kCGKeyboardEventKeycode);
+		capturedFlags = CGEventGetFlags(event);
+		return NULL; // swallow the captured press
+	}
+	return event;
+}
+
+// captureStart installs the event tap on the current thread's run loop.
+// Returns 0 if the tap could not be created (no Input Monitoring permission).
+static int captureStart(void) {
+	capturedKey = -1;
+	captureTap = CGEventTapCreate(kCGSessionEventTap, kCGHeadInsertEventTap,
+		kCGEventTapOptionDefault, CGEventMaskBit(kCGEventKeyDown), captureCallback, NULL);
+	if (captureTap == NULL) {
+		return 0;
+	}
+	captureSource = CFMachPortCreateRunLoopSource(kCFAllocatorDefault, captureTap, 0);
+	CFRunLoopAddSource(CFRunLoopGetCurrent(), captureSource, kCFRunLoopCommonModes);
+	CGEventTapEnable(captureTap, true);
+	return 1;
+}
+
+// captureStep runs the run loop briefly; returns the key code or -1.
+static int captureStep(double seconds, CGEventFlags *flags) {
+	CFRunLoopRunInMode(kCFRunLoopDefaultMode, seconds, true);
+	*flags = capturedFlags;
+	return capturedKey;
+}
+
+// captureReset discards a captured key so the tap keeps listening.
+static void captureReset(void) {
+	capturedKey = -1;
+}
+
+static void captureStop(void) {
+	if (captureTap != NULL) {
+		CGEventTapEnable(captureTap, false);
+		CFRunLoopRemoveSource(CFRunLoopGetCurrent(), captureSource, kCFRunLoopCommonModes);
+		CFRelease(captureSource);
+		CFRelease(captureTap);
+		captureTap = NULL;
+		captureSource = NULL;
+	}
+}
+*/
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"golang.design/x/hotkey"
+)
+
+// captureNext installs a Quartz event tap and runs a run loop on a locked
+// thread until a key press is seen. Keys are virtual key codes (kVK_*),
+// matching keyMap on macOS. Requires the Input Monitoring permission.
+func captureNext(ctx context.Context, name func(hotkey.Key) (string, bool)) ([]string, string, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if C.captureStart() == 0 {
+		return nil, "", fmt.Errorf("cannot create event tap (grant Input Monitoring permission in System Settings)")
+	}
+	defer C.captureStop()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, "", err
+		}
+
+		var flags C.CGEventFlags
+		code := C.captureStep(0.1, &flags)
+		if code < 0 {
+			continue
+		}
+
+		key, ok := name(hotkey.Key(code))
+		if !ok {
+			C.captureReset() // bare modifier or unsupported key
+			continue
+		}
+
+		var mods []string
+		if flags&C.kCGEventFlagMaskControl != 0 {
+			mods = append(mods, "ctrl")
+		}
+		if flags&C.kCGEventFlagMaskShift != 0 {
+			mods = append(mods, "shift")
+		}
+		if flags&C.kCGEventFlagMaskAlternate != 0 {
+			mods = append(mods, "alt")
+		}
+		if flags&C.kCGEventFlagMaskCommand != 0 {
+			mods = append(mods, "super")
+		}
+		return mods, key, nil
+	}
+}
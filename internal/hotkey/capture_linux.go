@@ -0,0 +1,78 @@
+//go:build linux
+
+package hotkey
+
+/*
+#cgo LDFLAGS: -lX11
+#include <X11/Xlib.h>
+#include <X11/Xutil.h>
+
+static int captureGrab(Display *d) {
+	return XGrabKeyboard(d, DefaultRootWindow(d), False, GrabModeAsync, GrabModeAsync, CurrentTime);
+}
+
+// captureNextKey returns 1 and fills keysym/state if a key press is queued.
+static int captureNextKey(Display *d, unsigned long *keysym, unsigned int *state) {
+	while (XPending(d) > 0) {
+		XEvent ev;
+		XNextEvent(d, &ev);
+		if (ev.type == KeyPress) {
+			*keysym = XLookupKeysym(&ev.xkey, 0); // unshifted keysym
+			*state = ev.xkey.state;
+			return 1;
+		}
+	}
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.design/x/hotkey"
+)
+
+// captureNext grabs the keyboard on the X root window and polls for the
+// next key press. Keys are X keysyms, matching keyMap on Linux.
+func captureNext(ctx context.Context, name func(hotkey.Key) (string, bool)) ([]string, string, error) {
+	d := C.XOpenDisplay(nil)
+	if d == nil {
+		return nil, "", fmt.Errorf("cannot open X display")
+	}
+	defer C.XCloseDisplay(d)
+
+	if st := C.captureGrab(d); st != C.GrabSuccess {
+		return nil, "", fmt.Errorf("keyboard grab failed (status %d)", int(st))
+	}
+	defer C.XUngrabKeyboard(d, C.CurrentTime)
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		case <-ticker.C:
+		}
+
+		var keysym C.ulong
+		var state C.uint
+		for C.captureNextKey(d, &keysym, &state) == 1 {
+			key, ok := name(hotkey.Key(keysym))
+			if !ok {
+				continue // bare modifier or unsupported key
+			}
+			var mods []string
+			for _, m := range []string{"ctrl", "shift", "alt", "super"} {
+				if uint32(state)&uint32(modMap[m]) != 0 {
+					mods = append(mods, m)
+				}
+			}
+			return mods, key, nil
+		}
+	}
+}
@@ -0,0 +1,148 @@
+//go:build windows
+
+package hotkey
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.design/x/hotkey"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32                  = windows.NewLazySystemDLL("user32.dll")
+	procSetWindowsHookExW   = user32.NewProc("SetWindowsHookExW")
+	procUnhookWindowsHookEx = user32.NewProc("UnhookWindowsHookEx")
+	procCallNextHookEx      = user32.NewProc("CallNextHookEx")
+	procGetMessageW         = user32.NewProc("GetMessageW")
+	procPostThreadMessageW  = user32.NewProc("PostThreadMessageW")
+	procGetAsyncKeyState    = user32.NewProc("GetAsyncKeyState")
+)
+
+const (
+	whKeyboardLL = 13
+	wmKeyDown    = 0x0100
+	wmSysKeyDown = 0x0104
+	wmQuit       = 0x0012
+
+	vkShift   = 0x10
+	vkControl = 0x11
+	vkMenu    = 0x12
+	vkLWin    = 0x5B
+	vkRWin    = 0x5C
+)
+
+// kbdLLHookStruct mirrors KBDLLHOOKSTRUCT.
+type kbdLLHookStruct struct {
+	vkCode    uint32
+	scanCode  uint32
+	flags     uint32
+	time      uint32
+	extraInfo uintptr
+}
+
+type winMsg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	pt      struct{ x, y int32 }
+}
+
+// captured is written by the hook callback, which runs on the capture
+// thread inside GetMessageW, and read after the message loop exits.
+var captured struct {
+	mods []string
+	key  string
+}
+
+func keyDown(vk int) bool {
+	r, _, _ := procGetAsyncKeyState.Call(uintptr(vk))
+	return r&0x8000 != 0
+}
+
+// captureNext installs a low-level keyboard hook on a dedicated thread and
+// pumps messages until a key press is seen. Keys are virtual-key codes,
+// matching keyMap on Windows. The captured press is swallowed so it
+// doesn't reach the focused application.
+func captureNext(ctx context.Context, name func(hotkey.Key) (string, bool)) ([]string, string, error) {
+	type result struct {
+		mods []string
+		key  string
+		err  error
+	}
+	done := make(chan result, 1)
+	threadID := make(chan uint32, 1)
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		captured.mods, captured.key = nil, ""
+		var hook uintptr
+		proc := windows.NewCallback(func(code int, wParam uintptr, lParam *kbdLLHookStruct) uintptr {
+			if code >= 0 && (wParam == wmKeyDown || wParam == wmSysKeyDown) {
+				if key, ok := name(hotkey.Key(lParam.vkCode)); ok {
+					var mods []string
+					if keyDown(vkControl) {
+						mods = append(mods, "ctrl")
+					}
+					if keyDown(vkShift) {
+						mods = append(mods, "shift")
+					}
+					if keyDown(vkMenu) {
+						mods = append(mods, "alt")
+					}
+					if keyDown(vkLWin) || keyDown(vkRWin) {
+						mods = append(mods, "super")
+					}
+					captured.mods, captured.key = mods, key
+					procPostThreadMessageW.Call(uintptr(windows.GetCurrentThreadId()), wmQuit, 0, 0)
+					return 1
+				}
+			}
+			r, _, _ := procCallNextHookEx.Call(hook, uintptr(code), wParam, uintptr(unsafe.Pointer(lParam)))
+			return r
+		})
+
+		h, _, err := procSetWindowsHookExW.Call(whKeyboardLL, proc, 0, 0)
+		if h == 0 {
+			threadID <- 0
+			done <- result{err: fmt.Errorf("install keyboard hook: %w", err)}
+			return
+		}
+		hook = h
+		defer procUnhookWindowsHookEx.Call(hook)
+
+		threadID <- windows.GetCurrentThreadId()
+
+		var m winMsg
+		for {
+			r, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+			if int32(r) <= 0 {
+				break
+			}
+		}
+		if captured.key == "" {
+			done <- result{err: context.Canceled}
+			return
+		}
+		done <- result{mods: captured.mods, key: captured.key}
+	}()
+
+	tid := <-threadID
+	select {
+	case res := <-done:
+		return res.mods, res.key, res.err
+	case <-ctx.Done():
+		if tid != 0 {
+			procPostThreadMessageW.Call(uintptr(tid), wmQuit, 0, 0)
+		}
+		<-done
+		return nil, "", ctx.Err()
+	}
+}
@@ -0,0 +1,31 @@
+package hotkey
+
+import (
+	"errors"
+	"strings"
+)
+
+// PressHostKey presses (down) or releases the key combination b on this
+// computer as if typed on its keyboard, e.g. to hold another app's
+// push-to-talk key. Modifiers go down before the key and up after it.
+// Unlike hotkey bindings, b needs no modifiers.
+func PressHostKey(b Binding, down bool) error {
+	if IsMouseButton(b.Key) {
+		return &BindingError{b, errors.New("mouse buttons can't be pressed on the host")}
+	}
+	key, err := ParseKey(b.Key)
+	if err != nil {
+		return &BindingError{b, err}
+	}
+	if _, err := ParseModifiers(b.Modifiers); err != nil {
+		return &BindingError{b, err}
+	}
+	mods := make([]string, len(b.Modifiers))
+	for i, m := range b.Modifiers {
+		mods[i] = strings.ToLower(m)
+	}
+	if err := pressHostKey(mods, key, down); err != nil {
+		return &BindingError{b, err}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+//go:build darwin
+
+package hotkey
+
+/*
+#cgo LDFLAGS: -framework ApplicationServices -framework CoreFoundation
+#include <ApplicationServices/ApplicationServices.h>
+
+// hostKeyPost posts a key event with flags as the modifiers held.
+// Returns 0 if the app may not post events (no Accessibility permission).
+static int hostKeyPost(CGKeyCode key, int down, CGEventFlags flags) {
+	if (!AXIsProcessTrusted()) {
+		return 0;
+	}
+	CGEventRef ev = CGEventCreateKeyboardEvent(NULL, key, down != 0);
+	CGEventSetFlags(ev, flags);
+	CGEventPost(kCGHIDEventTap, ev);
+	CFRelease(ev);
+	return 1;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+
+	"golang.design/x/hotkey"
+)
+
+// modFlags maps modifier names to their event flags.
+var modFlags = map[string]C.CGEventFlags{
+	"ctrl":  C.kCGEventFlagMaskControl,
+	"shift": C.kCGEventFlagMaskShift,
+	"alt":   C.kCGEventFlagMaskAlternate,
+	"super": C.kCGEventFlagMaskCommand,
+}
+
+// pressHostKey posts the key to the HID event stream with the modifiers
+// as flags. Requires the Accessibility permission.
+func pressHostKey(mods []string, key hotkey.Key, down bool) error {
+	var flags C.CGEventFlags
+	for _, m := range mods {
+		flags |= modFlags[m]
+	}
+	d := C.int(0)
+	if down {
+		d = 1
+	}
+	if C.hostKeyPost(C.CGKeyCode(key), d, flags) == 0 {
+		return fmt.Errorf("cannot press keys (grant Accessibility permission in System Settings)")
+	}
+	return nil
+}
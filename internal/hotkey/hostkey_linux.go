@@ -0,0 +1,39 @@
+//go:build linux
+
+package hotkey
+
+/*
+#cgo LDFLAGS: -lX11
+#include <X11/Xlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"golang.design/x/hotkey"
+)
+
+// pressHostKey sends the combination through xdotool, which fakes input
+// with the XTEST extension. Keys are named by their X keysyms, e.g. F13.
+func pressHostKey(mods []string, key hotkey.Key, down bool) error {
+	name := C.XKeysymToString(C.KeySym(key))
+	if name == nil {
+		return fmt.Errorf("no X keysym for key %#x", key)
+	}
+	combo := strings.Join(append(mods, C.GoString(name)), "+")
+	cmd := "keyup"
+	if down {
+		cmd = "keydown"
+	}
+	out, err := exec.Command("xdotool", cmd, "--", combo).CombinedOutput()
+	if err != nil {
+		if _, lerr := exec.LookPath("xdotool"); lerr != nil {
+			return fmt.Errorf("xdotool not found — install it to press keys on this computer")
+		}
+		return fmt.Errorf("xdotool: %w (%s)", err, out)
+	}
+	return nil
+}
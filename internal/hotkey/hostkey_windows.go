@@ -0,0 +1,74 @@
+//go:build windows
+
+package hotkey
+
+import (
+	"fmt"
+	"slices"
+	"unsafe"
+
+	"golang.design/x/hotkey"
+)
+
+var procSendInput = user32.NewProc("SendInput")
+
+const (
+	inputKeyboard       = 1
+	keyEventExtendedKey = 0x0001
+	keyEventKeyUp       = 0x0002
+)
+
+// modVKs maps modifier names to their virtual-key codes.
+var modVKs = map[string]uint16{
+	"ctrl":  vkControl,
+	"shift": vkShift,
+	"alt":   vkMenu,
+	"super": vkLWin,
+}
+
+// extendedVKs are keys whose scan codes need the extended flag: the
+// arrows and Delete, rather than their number pad twins.
+var extendedVKs = map[uint16]bool{0x25: true, 0x26: true, 0x27: true, 0x28: true, 0x2E: true}
+
+// keyboardInput mirrors INPUT holding a KEYBDINPUT, padded to the size
+// of the union's largest member.
+type keyboardInput struct {
+	typ uint32
+	ki  struct {
+		vk        uint16
+		scan      uint16
+		flags     uint32
+		time      uint32
+		extraInfo uintptr
+	}
+	_ [8]byte
+}
+
+// pressHostKey sends the combination with SendInput.
+func pressHostKey(mods []string, key hotkey.Key, down bool) error {
+	vks := make([]uint16, 0, len(mods)+1)
+	for _, m := range mods {
+		vks = append(vks, modVKs[m])
+	}
+	vks = append(vks, uint16(key))
+	if !down {
+		slices.Reverse(vks)
+	}
+
+	inputs := make([]keyboardInput, len(vks))
+	for i, vk := range vks {
+		inputs[i].typ = inputKeyboard
+		inputs[i].ki.vk = vk
+		if extendedVKs[vk] {
+			inputs[i].ki.flags |= keyEventExtendedKey
+		}
+		if !down {
+			inputs[i].ki.flags |= keyEventKeyUp
+		}
+	}
+	n, _, err := procSendInput.Call(uintptr(len(inputs)), uintptr(unsafe.Pointer(&inputs[0])), unsafe.Sizeof(inputs[0]))
+	if int(n) != len(inputs) {
+		return fmt.Errorf("SendInput: %w", err)
+	}
+	return nil
+}
@@ -2,22 +2,135 @@ package hotkey
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"runtime"
+	"runtime/debug"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.design/x/hotkey"
 )
 
+// Binding is one key combination that triggers a Manager's callbacks.
+type Binding struct {
+	Modifiers []string
+	Key       string
+}
+
+// String returns a human-readable combination like "Ctrl+Alt+R".
+func (b Binding) String() string {
+	var parts []string
+	for _, m := range b.Modifiers {
+		switch m {
+		case "ctrl":
+			parts = append(parts, "Ctrl")
+		case "shift":
+			parts = append(parts, "Shift")
+		case "alt":
+			parts = append(parts, "Alt")
+		case "super":
+			parts = append(parts, "Super")
+		}
+	}
+	if len(b.Key) == 1 || IsMouseButton(b.Key) {
+		parts = append(parts, strings.ToUpper(b.Key[:1])+b.Key[1:])
+	} else {
+		parts = append(parts, b.Key)
+	}
+	return strings.Join(parts, "+")
+}
+
+// ErrInUse is wrapped by registration errors that mean another
+// application already holds the binding.
+var ErrInUse = errors.New("in use")
+
+// BindingError reports why one binding could not be registered.
+type BindingError struct {
+	Binding Binding
+	Err     error
+}
+
+func (e *BindingError) Error() string {
+	if errors.Is(e.Err, ErrInUse) {
+		return fmt.Sprintf("hotkey conflict: %s in use", e.Binding)
+	}
+	return fmt.Sprintf("hotkey %s: %v", e.Binding, e.Err)
+}
+
+func (e *BindingError) Unwrap() error { return e.Err }
+
+// panicHandler is told about callbacks that panicked; see SetPanicHandler.
+var panicHandler atomic.Pointer[func(what string, v any)]
+
+// SetPanicHandler registers fn to be told when a hotkey, mouse button or
+// media key callback panics. The panic is recovered either way, so one
+// bad callback can't take down the app; fn is the place to undo what it
+// may have left half done, e.g. a held PTT key.
+func SetPanicHandler(fn func(what string, v any)) {
+	panicHandler.Store(&fn)
+}
+
+// guard calls fn, recovering and reporting a panic. what names the
+// callback in the report.
+func guard(what string, fn func()) {
+	defer func() {
+		if v := recover(); v != nil {
+			log.Printf("[hotkey] panic in %s: %v\n%s", what, v, debug.Stack())
+			if h := panicHandler.Load(); h != nil {
+				(*h)(what, v)
+			}
+		}
+	}()
+	fn()
+}
+
 // Manager handles global hotkey registration with hold-to-talk support.
+// Several bindings can be registered at once; all of them drive the same
+// key-down and key-up callbacks.
 type Manager struct {
 	mu     sync.Mutex
-	hk     *hotkey.Hotkey
+	hks    []osHotkey
 	cancel context.CancelFunc
 	onDown func()
 	onUp   func()
+
+	err     error       // result of the last RegisterAll; nil after Unregister
+	onError func(error) // called whenever err changes
+}
+
+// SetErrorHandler registers a callback that receives the registration
+// error after every RegisterAll, and nil after Unregister.
+func (m *Manager) SetErrorHandler(fn func(error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onError = fn
+}
+
+// Err returns the error from the last registration, or nil if every
+// binding is registered (or none are).
+func (m *Manager) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Registered returns how many bindings are currently registered with the OS.
+func (m *Manager) Registered() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.hks) + mouseCount(m)
+}
+
+// setErrLocked records a registration result. Must be called with m.mu held.
+func (m *Manager) setErrLocked(err error) {
+	m.err = err
+	if m.onError != nil {
+		m.onError(err)
+	}
 }
 
 // NewManager creates a hotkey manager with callbacks for key-down and key-up.
@@ -28,89 +141,170 @@ func NewManager(onDown, onUp func()) *Manager {
 	}
 }
 
-// Register sets up a global hotkey with the given modifiers and key.
-// If a hotkey is already registered, it is unregistered first.
+// Register sets up a single global hotkey with the given modifiers and key.
+// Any previously registered hotkeys are unregistered first.
 func (m *Manager) Register(mods []string, key string) error {
+	return m.RegisterAll([]Binding{{Modifiers: mods, Key: key}})
+}
+
+// RegisterAll replaces the registered hotkeys with the given bindings.
+// Bindings that fail to parse or register are skipped; the returned error
+// describes every failure, and is nil only if all bindings registered.
+func (m *Manager) RegisterAll(bindings []Binding) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Unregister existing hotkey
+	// Unregister existing hotkeys
 	m.unregisterLocked()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	var errs []error
+	var mouseBindings []Binding
+	for _, b := range bindings {
+		if IsMouseButton(b.Key) {
+			mouseBindings = append(mouseBindings, b)
+			continue
+		}
+		hk, err := registerHotkey(b)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		m.hks = append(m.hks, hk)
+
+		// Start listening for events
+		go m.listen(ctx, hk)
+		log.Printf("[hotkey] registered: %v+%s", b.Modifiers, b.Key)
+	}
+	if err := claimMouse(m, mouseBindings); err != nil {
+		errs = append(errs, err)
+	}
+	err := errors.Join(errs...)
+	m.setErrLocked(err)
+	return err
+}
+
+// osHotkey is a keyboard binding registered with the OS.
+type osHotkey interface {
+	Keydown() <-chan hotkey.Event
+	Keyup() <-chan hotkey.Event
+	Unregister() error
+}
+
+// registerHotkey registers one binding with the OS; tests replace it.
+var registerHotkey = func(b Binding) (osHotkey, error) {
+	return newHotkey(b)
+}
+
+// newHotkey parses and registers one binding with the OS.
+func newHotkey(b Binding) (*hotkey.Hotkey, error) {
 	// Parse modifiers and key
-	parsedMods, err := ParseModifiers(mods)
+	parsedMods, err := ParseModifiers(b.Modifiers)
 	if err != nil {
-		return fmt.Errorf("parse modifiers: %w", err)
+		return nil, &BindingError{b, fmt.Errorf("parse modifiers: %w", err)}
 	}
-	parsedKey, err := ParseKey(key)
+	parsedKey, err := ParseKey(b.Key)
 	if err != nil {
-		return fmt.Errorf("parse key: %w", err)
+		return nil, &BindingError{b, fmt.Errorf("parse key: %w", err)}
 	}
 
 	// Create and register the hotkey
 	hk := hotkey.New(parsedMods, parsedKey)
 	if err := hk.Register(); err != nil {
-		return fmt.Errorf("register hotkey: %w", err)
+		if grabbedByOther(err) {
+			err = fmt.Errorf("%w: %v", ErrInUse, err)
+		}
+		return nil, &BindingError{b, err}
 	}
+	return hk, nil
+}
 
-	m.hk = hk
-
-	// Start listening for events
-	ctx, cancel := context.WithCancel(context.Background())
-	m.cancel = cancel
-	go m.listen(ctx, hk)
+// listeners counts the listen goroutines running, for diagnostics.
+var listeners atomic.Int32
 
-	log.Printf("[hotkey] registered: %v", mods)
-	return nil
+// Listeners returns how many hotkey listener goroutines are running. It
+// should match the registered keyboard bindings; more means a listener
+// outlived its Unregister.
+func Listeners() int {
+	return int(listeners.Load())
 }
 
 // listen loops on keydown/keyup channels and calls the callbacks.
-func (m *Manager) listen(ctx context.Context, hk *hotkey.Hotkey) {
+func (m *Manager) listen(ctx context.Context, hk osHotkey) {
+	listeners.Add(1)
+	defer listeners.Add(-1)
+
 	// Linux X11 auto-repeat generates spurious keyup/keydown pairs.
 	// Debounce: on keyup, wait 50ms; if keydown fires within that window,
-	// treat it as auto-repeat and ignore both events.
+	// treat it as auto-repeat and ignore both events. The timer is only
+	// touched by this goroutine, so it needs no locking.
 	isLinux := runtime.GOOS == "linux"
-	var debounceTimer *time.Timer
+	var debounce *time.Timer
+	var release <-chan time.Time // debounce.C while a keyup is pending
+	stopDebounce := func() bool {
+		if release == nil {
+			return false
+		}
+		debounce.Stop()
+		release = nil
+		return true
+	}
+
+	// pressed is whether onDown ran without its onUp yet, counting a
+	// keyup that is still being debounced as pressed
+	pressed := false
+	up := func() {
+		pressed = false
+		if m.onUp != nil {
+			guard("hotkey release", m.onUp)
+		}
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
+			// Unregistered while held: release, so the key isn't left
+			// held, e.g. PTT stuck on after the hotkey was changed
+			stopDebounce()
+			if pressed {
+				up()
+			}
 			return
 		case <-hk.Keydown():
-			if isLinux && debounceTimer != nil {
-				// Cancel pending keyup — this is auto-repeat, not a real release
-				debounceTimer.Stop()
-				debounceTimer = nil
+			if isLinux && stopDebounce() {
+				// Pending keyup cancelled — this is auto-repeat, not a real release
 				continue
 			}
+			pressed = true
 			if m.onDown != nil {
-				m.onDown()
+				guard("hotkey press", m.onDown)
 			}
 		case <-hk.Keyup():
 			if isLinux {
 				// Delay the keyup callback to check for auto-repeat
-				debounceTimer = time.AfterFunc(50*time.Millisecond, func() {
-					if m.onUp != nil {
-						m.onUp()
-					}
-					m.mu.Lock()
-					debounceTimer = nil
-					m.mu.Unlock()
-				})
-			} else {
-				if m.onUp != nil {
-					m.onUp()
-				}
+				stopDebounce()
+				debounce = time.NewTimer(50 * time.Millisecond)
+				release = debounce.C
+				continue
 			}
+			up()
+		case <-release:
+			release = nil
+			up()
 		}
 	}
 }
 
-// Unregister removes the current global hotkey.
+// Unregister removes all registered global hotkeys.
 func (m *Manager) Unregister() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.unregisterLocked()
+	if m.err != nil {
+		m.setErrLocked(nil)
+	}
 }
 
 func (m *Manager) unregisterLocked() {
@@ -118,8 +312,9 @@ func (m *Manager) unregisterLocked() {
 		m.cancel()
 		m.cancel = nil
 	}
-	if m.hk != nil {
-		m.hk.Unregister()
-		m.hk = nil
+	for _, hk := range m.hks {
+		hk.Unregister()
 	}
+	m.hks = nil
+	releaseMouse(m)
 }
@@ -0,0 +1,11 @@
+//go:build darwin
+
+package hotkey
+
+// grabbedByOther reports whether err from registering a hotkey means
+// another application holds the key. RegisterEventHotKey fails this way
+// when the combination is already taken, and the backend reports it
+// without the status.
+func grabbedByOther(err error) bool {
+	return err.Error() == "failed to register the hotkey"
+}
@@ -0,0 +1,10 @@
+//go:build linux
+
+package hotkey
+
+// grabbedByOther reports whether err from registering a hotkey means
+// another client holds the key. X11 reports a taken grab asynchronously,
+// so registration never fails for that reason here.
+func grabbedByOther(err error) bool {
+	return false
+}
@@ -0,0 +1,96 @@
+package hotkey
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"golang.design/x/hotkey"
+)
+
+// fakeHotkey stands in for an OS registration.
+type fakeHotkey struct {
+	down, up chan hotkey.Event
+}
+
+func (f *fakeHotkey) Keydown() <-chan hotkey.Event { return f.down }
+func (f *fakeHotkey) Keyup() <-chan hotkey.Event   { return f.up }
+func (f *fakeHotkey) Unregister() error            { return nil }
+
+// useFakeHotkeys registers bindings with fakes for the rest of t.
+func useFakeHotkeys(t *testing.T) {
+	prev := registerHotkey
+	registerHotkey = func(Binding) (osHotkey, error) {
+		return &fakeHotkey{down: make(chan hotkey.Event), up: make(chan hotkey.Event)}, nil
+	}
+	t.Cleanup(func() { registerHotkey = prev })
+}
+
+// settle waits for the goroutine count to drop back to want, as
+// listeners exit after Unregister returns.
+func settle(want int) int {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		n := runtime.NumGoroutine()
+		if n <= want || time.Now().After(deadline) {
+			return n
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRegisterUnregisterDoesNotLeakListeners(t *testing.T) {
+	useFakeHotkeys(t)
+	baseListeners := Listeners()
+	baseGoroutines := runtime.NumGoroutine()
+
+	m := NewManager(func() {}, func() {})
+	bindings := []Binding{
+		{Modifiers: []string{"ctrl", "alt"}, Key: "r"},
+		{Modifiers: []string{"ctrl", "shift"}, Key: "f9"},
+	}
+	for range 200 {
+		if err := m.RegisterAll(bindings); err != nil {
+			t.Fatalf("RegisterAll: %v", err)
+		}
+		if n := m.Registered(); n != len(bindings) {
+			t.Fatalf("Registered() = %d, want %d", n, len(bindings))
+		}
+		m.Unregister()
+	}
+
+	if n := settle(baseGoroutines); n > baseGoroutines {
+		t.Errorf("goroutines = %d after Unregister, want %d", n, baseGoroutines)
+	}
+	if n := Listeners(); n != baseListeners {
+		t.Errorf("Listeners() = %d after Unregister, want %d", n, baseListeners)
+	}
+}
+
+func TestReRegisterReplacesListeners(t *testing.T) {
+	useFakeHotkeys(t)
+	baseListeners := Listeners()
+	baseGoroutines := runtime.NumGoroutine()
+
+	m := NewManager(func() {}, func() {})
+	binding := []Binding{{Modifiers: []string{"ctrl", "alt"}, Key: "r"}}
+	for range 200 {
+		if err := m.RegisterAll(binding); err != nil {
+			t.Fatalf("RegisterAll: %v", err)
+		}
+	}
+	if n := settle(baseGoroutines + 1); n > baseGoroutines+1 {
+		t.Errorf("goroutines = %d with one binding registered, want %d", n, baseGoroutines+1)
+	}
+	if n := Listeners(); n != baseListeners+1 {
+		t.Errorf("Listeners() = %d with one binding registered, want %d", n, baseListeners+1)
+	}
+
+	m.Unregister()
+	if n := settle(baseGoroutines); n > baseGoroutines {
+		t.Errorf("goroutines = %d after Unregister, want %d", n, baseGoroutines)
+	}
+	if n := Listeners(); n != baseListeners {
+		t.Errorf("Listeners() = %d after Unregister, want %d", n, baseListeners)
+	}
+}
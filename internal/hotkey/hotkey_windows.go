@@ -0,0 +1,15 @@
+//go:build windows
+
+package hotkey
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// grabbedByOther reports whether err from registering a hotkey means
+// another application holds the key.
+func grabbedByOther(err error) bool {
+	return errors.Is(err, windows.ERROR_HOTKEY_ALREADY_REGISTERED)
+}
@@ -0,0 +1,83 @@
+package hotkey
+
+import (
+	"log"
+	"sync"
+)
+
+// Media keys the passthrough hook takes from the host, by the names of
+// the matching R1 actions.
+const (
+	MediaVolumeUp   = "volume_up"
+	MediaVolumeDown = "volume_down"
+	MediaMute       = "mute"
+	MediaPlayPause  = "play_pause"
+)
+
+// media is the process-wide media key hook. While it runs the host's
+// volume, mute and play/pause keys are swallowed and handed to its
+// callback instead.
+var media struct {
+	mu     sync.Mutex
+	stop   func()
+	events chan string // closed once the hook has stopped
+}
+
+// CaptureMediaKeys takes the host's volume, mute and play/pause keys and
+// calls handle with each one pressed, e.g. MediaVolumeUp, instead of the
+// host acting on it, until ReleaseMediaKeys. Holding a key repeats it.
+// Calling it again replaces handle.
+func CaptureMediaKeys(handle func(key string)) error {
+	media.mu.Lock()
+	defer media.mu.Unlock()
+	stopMediaLocked()
+
+	// The hook callback only queues keys: OS hooks that block (e.g. on a
+	// busy device) get dropped or time out.
+	events := make(chan string, 16)
+	stop, err := startMediaHook(func(key string) {
+		select {
+		case events <- key:
+		default: // dispatcher is stuck; drop rather than stall the hook
+		}
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		for key := range events {
+			guard("media key "+key, func() { handle(key) })
+		}
+	}()
+	media.stop, media.events = stop, events
+	log.Printf("[hotkey] media keys captured")
+	return nil
+}
+
+// ReleaseMediaKeys gives the media keys back to the host.
+func ReleaseMediaKeys() {
+	media.mu.Lock()
+	defer media.mu.Unlock()
+	if media.stop != nil {
+		stopMediaLocked()
+		log.Printf("[hotkey] media keys released")
+	}
+}
+
+// MediaKeysCaptured reports whether the media keys are captured.
+func MediaKeysCaptured() bool {
+	media.mu.Lock()
+	defer media.mu.Unlock()
+	return media.stop != nil
+}
+
+// stopMediaLocked stops the hook, if running. Must be called with
+// media.mu held.
+func stopMediaLocked() {
+	if media.stop == nil {
+		return
+	}
+	media.stop()
+	close(media.events)
+	media.stop, media.events = nil, nil
+}
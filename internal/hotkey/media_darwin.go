@@ -0,0 +1,144 @@
+//go:build darwin
+
+package hotkey
+
+/*
+#cgo CFLAGS: -x objective-c -fobjc-arc
+#cgo LDFLAGS: -framework ApplicationServices -framework CoreFoundation -framework AppKit
+#import <AppKit/AppKit.h>
+#include <ApplicationServices/ApplicationServices.h>
+
+// Media keys arrive as system-defined events of subtype 8, with the key
+// (an NX_KEYTYPE_*) and its state packed into data1.
+#define MEDIA_EVENT 14 // NSEventTypeSystemDefined
+#define MEDIA_SUBTYPE 8
+#define MEDIA_SOUND_UP 0
+#define MEDIA_SOUND_DOWN 1
+#define MEDIA_MUTE 7
+#define MEDIA_PLAY 16
+
+static CFMachPortRef mediaTap = NULL;
+static CFRunLoopSourceRef mediaSource = NULL;
+
+// Key presses seen by the callback, drained by mediaStep. The callback
+// and mediaStep both run on the tap's thread, so no locking is needed.
+#define MEDIA_QUEUE 32
+static int mediaQueue[MEDIA_QUEUE];
+static int mediaQueued = 0;
+
+static CGEventRef mediaCallback(CGEventTapProxy proxy, CGEventType type, CGEventRef event, void *info) {
+	if (type == kCGEventTapDisabledByTimeout || type == kCGEventTapDisabledByUserInput) {
+		CGEventTapEnable(mediaTap, true);
+		return event;
+	}
+	if (type != MEDIA_EVENT) {
+		return event;
+	}
+	NSEvent *ev = [NSEvent eventWithCGEvent:event];
+	if (ev == nil || ev.subtype != MEDIA_SUBTYPE) {
+		return event;
+	}
+	int key = (int)((ev.data1 & 0xFFFF0000) >> 16);
+	int state = (int)((ev.data1 & 0xFF00) >> 8); // 0xA down, 0xB up
+	if (key != MEDIA_SOUND_UP && key != MEDIA_SOUND_DOWN && key != MEDIA_MUTE && key != MEDIA_PLAY) {
+		return event;
+	}
+	if (state == 0xA && mediaQueued < MEDIA_QUEUE) {
+		mediaQueue[mediaQueued++] = key;
+	}
+	return NULL; // swallow releases too, so the host sees nothing
+}
+
+// mediaStart installs the event tap on the current thread's run loop.
+// Returns 0 if the tap could not be created (no Input Monitoring permission).
+static int mediaStart(void) {
+	mediaQueued = 0;
+	mediaTap = CGEventTapCreate(kCGSessionEventTap, kCGHeadInsertEventTap, kCGEventTapOptionDefault,
+		CGEventMaskBit(MEDIA_EVENT), mediaCallback, NULL);
+	if (mediaTap == NULL) {
+		return 0;
+	}
+	mediaSource = CFMachPortCreateRunLoopSource(kCFAllocatorDefault, mediaTap, 0);
+	CFRunLoopAddSource(CFRunLoopGetCurrent(), mediaSource, kCFRunLoopCommonModes);
+	CGEventTapEnable(mediaTap, true);
+	return 1;
+}
+
+// mediaStep runs the run loop briefly and copies out queued key presses.
+static int mediaStep(double seconds, int *out) {
+	CFRunLoopRunInMode(kCFRunLoopDefaultMode, seconds, true);
+	int n = mediaQueued;
+	for (int i = 0; i < n; i++) {
+		out[i] = mediaQueue[i];
+	}
+	mediaQueued = 0;
+	return n;
+}
+
+static void mediaStop(void) {
+	if (mediaTap != NULL) {
+		CGEventTapEnable(mediaTap, false);
+		CFRunLoopRemoveSource(CFRunLoopGetCurrent(), mediaSource, kCFRunLoopCommonModes);
+		CFRelease(mediaSource);
+		CFRelease(mediaTap);
+		mediaTap = NULL;
+		mediaSource = NULL;
+	}
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+)
+
+// mediaKeyTypes maps NX_KEYTYPE_* codes to the media keys.
+var mediaKeyTypes = map[C.int]string{
+	C.MEDIA_SOUND_UP:   MediaVolumeUp,
+	C.MEDIA_SOUND_DOWN: MediaVolumeDown,
+	C.MEDIA_MUTE:       MediaMute,
+	C.MEDIA_PLAY:       MediaPlayPause,
+}
+
+// startMediaHook installs a Quartz event tap for the media keys and runs
+// a run loop on a locked thread until stopped. Requires the Input
+// Monitoring permission.
+func startMediaHook(handle func(key string)) (func(), error) {
+	started := make(chan error, 1)
+	done := make(chan struct{})
+	var stopped atomic.Bool
+	go func() {
+		defer close(done)
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		if C.mediaStart() == 0 {
+			C.mediaStop()
+			started <- fmt.Errorf("cannot create event tap (grant Input Monitoring permission in System Settings)")
+			return
+		}
+		defer C.mediaStop()
+		started <- nil
+
+		var keys [C.MEDIA_QUEUE]C.int
+		for !stopped.Load() {
+			n := int(C.mediaStep(0.05, &keys[0]))
+			for _, k := range keys[:n] {
+				if name, ok := mediaKeyTypes[k]; ok {
+					handle(name)
+				}
+			}
+		}
+	}()
+
+	if err := <-started; err != nil {
+		<-done
+		return nil, err
+	}
+	return func() {
+		stopped.Store(true)
+		<-done
+	}, nil
+}
@@ -0,0 +1,110 @@
+//go:build linux
+
+package hotkey
+
+/*
+#cgo LDFLAGS: -lX11
+#include <X11/Xlib.h>
+#include <X11/XF86keysym.h>
+
+static volatile int mediaGrabFailed = 0;
+
+static int mediaGrabError(Display *d, XErrorEvent *e) {
+	mediaGrabFailed = 1;
+	return 0;
+}
+
+// mediaGrab grabs the key for keysym on the root window regardless of
+// modifiers and returns its keycode. Returns 0 if the keyboard has no
+// such key, -1 if another client (usually the desktop) holds the grab.
+static int mediaGrab(Display *d, KeySym keysym) {
+	KeyCode code = XKeysymToKeycode(d, keysym);
+	if (code == 0) {
+		return 0;
+	}
+	mediaGrabFailed = 0;
+	XErrorHandler prev = XSetErrorHandler(mediaGrabError);
+	XGrabKey(d, code, AnyModifier, DefaultRootWindow(d), False, GrabModeAsync, GrabModeAsync);
+	XSync(d, False);
+	XSetErrorHandler(prev);
+	return mediaGrabFailed ? -1 : code;
+}
+
+// mediaNextKey returns 1 and fills code if a key press is queued.
+static int mediaNextKey(Display *d, unsigned int *code) {
+	while (XPending(d) > 0) {
+		XEvent ev;
+		XNextEvent(d, &ev);
+		if (ev.type == KeyPress) {
+			*code = ev.xkey.keycode;
+			return 1;
+		}
+	}
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+)
+
+// mediaKeysyms maps the media keys to their XF86 keysyms.
+var mediaKeysyms = map[string]C.KeySym{
+	MediaVolumeUp:   C.XF86XK_AudioRaiseVolume,
+	MediaVolumeDown: C.XF86XK_AudioLowerVolume,
+	MediaMute:       C.XF86XK_AudioMute,
+	MediaPlayPause:  C.XF86XK_AudioPlay,
+}
+
+// startMediaHook grabs the media keys on the X root window on its own
+// display connection and polls for presses until stopped. Desktops that
+// handle the keys themselves (GNOME, KDE) may already hold the grabs.
+func startMediaHook(handle func(key string)) (func(), error) {
+	d := C.XOpenDisplay(nil)
+	if d == nil {
+		return nil, fmt.Errorf("cannot open X display")
+	}
+	keys := make(map[C.uint]string, len(mediaKeysyms))
+	for name, sym := range mediaKeysyms {
+		switch code := C.mediaGrab(d, sym); {
+		case code < 0:
+			C.XCloseDisplay(d)
+			return nil, fmt.Errorf("%s: %w", name, ErrInUse)
+		case code > 0:
+			keys[C.uint(code)] = name
+		}
+	}
+	if len(keys) == 0 {
+		C.XCloseDisplay(d)
+		return nil, fmt.Errorf("the keyboard has no media keys")
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer C.XCloseDisplay(d) // releases the grabs
+
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			var code C.uint
+			for C.mediaNextKey(d, &code) == 1 {
+				if name, ok := keys[code]; ok {
+					handle(name)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}, nil
+}
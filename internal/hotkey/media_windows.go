@@ -0,0 +1,94 @@
+//go:build windows
+
+package hotkey
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// mediaVKs maps the media keys' virtual-key codes to their names.
+var mediaVKs = map[uint32]string{
+	0xAF: MediaVolumeUp,   // VK_VOLUME_UP
+	0xAE: MediaVolumeDown, // VK_VOLUME_DOWN
+	0xAD: MediaMute,       // VK_VOLUME_MUTE
+	0xB3: MediaPlayPause,  // VK_MEDIA_PLAY_PAUSE
+}
+
+// mediaHook is read by the hook callback, which runs on the hook thread
+// inside GetMessageW. It is set before the hook is installed and not
+// changed while it runs.
+var mediaHook struct {
+	hook   uintptr
+	handle func(key string)
+}
+
+// mediaProc is created once: Windows callbacks are never freed, and the
+// hook is reinstalled every time passthrough is turned on.
+var (
+	mediaProcOnce sync.Once
+	mediaProc     uintptr
+)
+
+func mediaCallback(code int, wParam uintptr, lParam *kbdLLHookStruct) uintptr {
+	if code >= 0 {
+		if name, ok := mediaVKs[lParam.vkCode]; ok {
+			if wParam == wmKeyDown || wParam == wmSysKeyDown {
+				mediaHook.handle(name)
+			}
+			return 1 // swallow releases too, so the host sees nothing
+		}
+	}
+	r, _, _ := procCallNextHookEx.Call(mediaHook.hook, uintptr(code), wParam, uintptr(unsafe.Pointer(lParam)))
+	return r
+}
+
+// startMediaHook installs a low-level keyboard hook on a dedicated
+// thread and pumps messages until stopped.
+func startMediaHook(handle func(key string)) (func(), error) {
+	mediaProcOnce.Do(func() {
+		mediaProc = windows.NewCallback(mediaCallback)
+	})
+
+	started := make(chan error, 1)
+	done := make(chan struct{})
+	var tid uint32
+	go func() {
+		defer close(done)
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		mediaHook.handle = handle
+		h, _, err := procSetWindowsHookExW.Call(whKeyboardLL, mediaProc, 0, 0)
+		if h == 0 {
+			started <- fmt.Errorf("install keyboard hook: %w", err)
+			return
+		}
+		mediaHook.hook = h
+		defer procUnhookWindowsHookEx.Call(h)
+
+		tid = windows.GetCurrentThreadId()
+		started <- nil
+
+		var m winMsg
+		for {
+			r, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+			if int32(r) <= 0 {
+				break
+			}
+		}
+	}()
+
+	if err := <-started; err != nil {
+		<-done
+		return nil, err
+	}
+	return func() {
+		procPostThreadMessageW.Call(uintptr(tid), wmQuit, 0, 0)
+		<-done
+	}, nil
+}
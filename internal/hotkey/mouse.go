@@ -0,0 +1,188 @@
+package hotkey
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// mouseButtons maps config key names to extra mouse buttons. Mouse4 is
+// usually the thumb "back" button and Mouse5 "forward".
+var mouseButtons = map[string]int{
+	"mouse4": 4,
+	"mouse5": 5,
+}
+
+// IsMouseButton reports whether key names a mouse button rather than a
+// keyboard key.
+func IsMouseButton(key string) bool {
+	_, ok := mouseButtons[strings.ToLower(key)]
+	return ok
+}
+
+// mouse routes button events from the process-wide low-level mouse hook
+// to the Manager that owns each button. The platform hook runs only
+// while at least one button is claimed, and swallows claimed buttons so
+// they don't also trigger "back"/"forward" in the focused app.
+var mouse struct {
+	mu     sync.Mutex
+	owners map[int]*Manager
+	held   map[int]bool // claimed buttons pressed and not yet released
+	stop   func()
+
+	// The hook callback only queues events: OS hooks that block (e.g. on
+	// a busy device) get dropped or time out.
+	events   chan mouseEvent
+	dispatch sync.Once
+}
+
+// mouseEvent is one button press or release seen by the hook.
+type mouseEvent struct {
+	button int
+	down   bool
+}
+
+// mouseButton parses a mouse binding. Modifiers aren't supported: the
+// hooks see button events, not keyboard state.
+func mouseButton(b Binding) (int, error) {
+	if len(b.Modifiers) > 0 {
+		return 0, &BindingError{b, errors.New("mouse buttons can't be combined with modifiers")}
+	}
+	return mouseButtons[strings.ToLower(b.Key)], nil
+}
+
+// claimMouse gives m the buttons, replacing any it held before, and
+// (re)starts the hook for the new set of buttons.
+func claimMouse(m *Manager, bindings []Binding) error {
+	mouse.mu.Lock()
+	defer mouse.mu.Unlock()
+
+	for btn, owner := range mouse.owners {
+		if owner == m {
+			delete(mouse.owners, btn)
+		}
+	}
+
+	var errs []error
+	for _, b := range bindings {
+		btn, err := mouseButton(b)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if owner, ok := mouse.owners[btn]; ok && owner != m {
+			errs = append(errs, &BindingError{b, fmt.Errorf("%w: bound to another action", ErrInUse)})
+			continue
+		}
+		if mouse.owners == nil {
+			mouse.owners = make(map[int]*Manager)
+		}
+		mouse.owners[btn] = m
+		log.Printf("[hotkey] registered: %s", b)
+	}
+
+	if err := restartMouseLocked(); err != nil {
+		for btn, owner := range mouse.owners {
+			if owner == m {
+				delete(mouse.owners, btn)
+			}
+		}
+		errs = append(errs, fmt.Errorf("mouse hook: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// releaseMouse drops every button held by m. A button still pressed is
+// released, so its action isn't left held.
+func releaseMouse(m *Manager) {
+	mouse.mu.Lock()
+	changed, pressed := false, false
+	for btn, owner := range mouse.owners {
+		if owner == m {
+			delete(mouse.owners, btn)
+			pressed = pressed || mouse.held[btn]
+			delete(mouse.held, btn)
+			changed = true
+		}
+	}
+	if changed {
+		if err := restartMouseLocked(); err != nil {
+			log.Printf("[hotkey] mouse hook: %v", err)
+		}
+	}
+	mouse.mu.Unlock()
+
+	if pressed && m.onUp != nil {
+		guard("mouse button release", m.onUp)
+	}
+}
+
+// mouseCount returns how many buttons m holds.
+func mouseCount(m *Manager) int {
+	mouse.mu.Lock()
+	defer mouse.mu.Unlock()
+	n := 0
+	for _, owner := range mouse.owners {
+		if owner == m {
+			n++
+		}
+	}
+	return n
+}
+
+// restartMouseLocked stops the hook and starts it again for the claimed
+// buttons, if any. Must be called with mouse.mu held.
+func restartMouseLocked() error {
+	if mouse.stop != nil {
+		mouse.stop()
+		mouse.stop = nil
+	}
+	if len(mouse.owners) == 0 {
+		return nil
+	}
+	buttons := make([]int, 0, len(mouse.owners))
+	for btn := range mouse.owners {
+		buttons = append(buttons, btn)
+	}
+	mouse.dispatch.Do(func() {
+		mouse.events = make(chan mouseEvent, 16)
+		go dispatchMouse(mouse.events)
+	})
+	events := mouse.events
+	stop, err := startMouseHook(buttons, func(button int, down bool) {
+		select {
+		case events <- mouseEvent{button, down}:
+		default: // dispatcher is stuck; drop rather than stall the hook
+		}
+	})
+	if err != nil {
+		return err
+	}
+	mouse.stop = stop
+	return nil
+}
+
+// dispatchMouse delivers button events to their owners' callbacks.
+func dispatchMouse(events <-chan mouseEvent) {
+	for ev := range events {
+		mouse.mu.Lock()
+		m := mouse.owners[ev.button]
+		if m != nil {
+			if mouse.held == nil {
+				mouse.held = make(map[int]bool)
+			}
+			mouse.held[ev.button] = ev.down
+		}
+		mouse.mu.Unlock()
+		if m == nil {
+			continue
+		}
+		if ev.down && m.onDown != nil {
+			guard("mouse button press", m.onDown)
+		} else if !ev.down && m.onUp != nil {
+			guard("mouse button release", m.onUp)
+		}
+	}
+}
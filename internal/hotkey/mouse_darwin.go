@@ -0,0 +1,135 @@
+//go:build darwin
+
+package hotkey
+
+/*
+#cgo LDFLAGS: -framework ApplicationServices -framework CoreFoundation
+#include <ApplicationServices/ApplicationServices.h>
+
+static CFMachPortRef mouseTap = NULL;
+static CFRunLoopSourceRef mouseSource = NULL;
+static int mouseWanted[8];
+
+// Events seen by the callback, drained by mouseStep. The callback and
+// mouseStep both run on the tap's thread, so no locking is needed.
+#define MOUSE_QUEUE 32
+static int mouseQueue[MOUSE_QUEUE];
+static int mouseQueued = 0;
+
+static CGEventRef mouseCallback(CGEventTapProxy proxy, CGEventType type, CGEventRef event, void *info) {
+	if (type == kCGEventTapDisabledByTimeout || type == kCGEventTapDisabledByUserInput) {
+		CGEventTapEnable(mouseTap, true);
+		return event;
+	}
+	if (type != kCGEventOtherMouseDown && type != kCGEventOtherMouseUp) {
+		return event;
+	}
+	// Quartz numbers buttons from 0, so mouse4 is 3.
+	int btn = (int)CGEventGetIntegerValueField(event, kCGMouseEventButtonNumber) + 1;
+	if (btn < 0 || btn >= 8 || !mouseWanted[btn]) {
+		return event;
+	}
+	if (mouseQueued < MOUSE_QUEUE) {
+		mouseQueue[mouseQueued++] = type == kCGEventOtherMouseDown ? btn : -btn;
+	}
+	return NULL; // swallow so the focused app doesn't go back/forward
+}
+
+// mouseStart installs the event tap on the current thread's run loop.
+// Returns 0 if the tap could not be created (no Input Monitoring permission).
+static int mouseStart(void) {
+	mouseQueued = 0;
+	mouseTap = CGEventTapCreate(kCGSessionEventTap, kCGHeadInsertEventTap, kCGEventTapOptionDefault,
+		CGEventMaskBit(kCGEventOtherMouseDown) | CGEventMaskBit(kCGEventOtherMouseUp), mouseCallback, NULL);
+	if (mouseTap == NULL) {
+		return 0;
+	}
+	mouseSource = CFMachPortCreateRunLoopSource(kCFAllocatorDefault, mouseTap, 0);
+	CFRunLoopAddSource(CFRunLoopGetCurrent(), mouseSource, kCFRunLoopCommonModes);
+	CGEventTapEnable(mouseTap, true);
+	return 1;
+}
+
+static void mouseWant(int btn) {
+	mouseWanted[btn] = 1;
+}
+
+// mouseStep runs the run loop briefly and copies out queued events:
+// positive for a press, negative for a release.
+static int mouseStep(double seconds, int *out) {
+	CFRunLoopRunInMode(kCFRunLoopDefaultMode, seconds, true);
+	int n = mouseQueued;
+	for (int i = 0; i < n; i++) {
+		out[i] = mouseQueue[i];
+	}
+	mouseQueued = 0;
+	return n;
+}
+
+static void mouseStop(void) {
+	for (int i = 0; i < 8; i++) {
+		mouseWanted[i] = 0;
+	}
+	if (mouseTap != NULL) {
+		CGEventTapEnable(mouseTap, false);
+		CFRunLoopRemoveSource(CFRunLoopGetCurrent(), mouseSource, kCFRunLoopCommonModes);
+		CFRelease(mouseSource);
+		CFRelease(mouseTap);
+		mouseTap = NULL;
+		mouseSource = NULL;
+	}
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+)
+
+// startMouseHook installs a Quartz event tap for the extra mouse buttons
+// and runs a run loop on a locked thread until stopped. Requires the
+// Input Monitoring permission.
+func startMouseHook(buttons []int, handle func(button int, down bool)) (func(), error) {
+	started := make(chan error, 1)
+	done := make(chan struct{})
+	var stopped atomic.Bool
+	go func() {
+		defer close(done)
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		for _, b := range buttons {
+			C.mouseWant(C.int(b))
+		}
+		if C.mouseStart() == 0 {
+			C.mouseStop()
+			started <- fmt.Errorf("cannot create event tap (grant Input Monitoring permission in System Settings)")
+			return
+		}
+		defer C.mouseStop()
+		started <- nil
+
+		var events [C.MOUSE_QUEUE]C.int
+		for !stopped.Load() {
+			n := int(C.mouseStep(0.05, &events[0]))
+			for _, ev := range events[:n] {
+				if ev > 0 {
+					handle(int(ev), true)
+				} else {
+					handle(int(-ev), false)
+				}
+			}
+		}
+	}()
+
+	if err := <-started; err != nil {
+		<-done
+		return nil, err
+	}
+	return func() {
+		stopped.Store(true)
+		<-done
+	}, nil
+}
@@ -0,0 +1,91 @@
+//go:build linux
+
+package hotkey
+
+/*
+#cgo LDFLAGS: -lX11
+#include <X11/Xlib.h>
+
+static volatile int mouseGrabFailed = 0;
+
+static int mouseGrabError(Display *d, XErrorEvent *e) {
+	mouseGrabFailed = 1;
+	return 0;
+}
+
+// mouseGrab grabs button on the root window regardless of modifiers.
+// Returns 0 if another client already holds the grab.
+static int mouseGrab(Display *d, unsigned int button) {
+	mouseGrabFailed = 0;
+	XErrorHandler prev = XSetErrorHandler(mouseGrabError);
+	XGrabButton(d, button, AnyModifier, DefaultRootWindow(d), False,
+		ButtonPressMask | ButtonReleaseMask, GrabModeAsync, GrabModeAsync, None, None);
+	XSync(d, False);
+	XSetErrorHandler(prev);
+	return !mouseGrabFailed;
+}
+
+// mouseNextButton returns 1 and fills button/down if a button event is queued.
+static int mouseNextButton(Display *d, unsigned int *button, int *down) {
+	while (XPending(d) > 0) {
+		XEvent ev;
+		XNextEvent(d, &ev);
+		if (ev.type == ButtonPress || ev.type == ButtonRelease) {
+			*button = ev.xbutton.button;
+			*down = ev.type == ButtonPress;
+			return 1;
+		}
+	}
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+)
+
+// X numbers the side buttons 8 and 9, after the wheel's 4-7.
+const xButtonOffset = 4
+
+// startMouseHook grabs the buttons on the X root window on its own
+// display connection and polls for presses until stopped.
+func startMouseHook(buttons []int, handle func(button int, down bool)) (func(), error) {
+	d := C.XOpenDisplay(nil)
+	if d == nil {
+		return nil, fmt.Errorf("cannot open X display")
+	}
+	for _, b := range buttons {
+		if C.mouseGrab(d, C.uint(b+xButtonOffset)) == 0 {
+			C.XCloseDisplay(d)
+			return nil, fmt.Errorf("mouse%d: %w", b, ErrInUse)
+		}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer C.XCloseDisplay(d) // releases the grabs
+
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			var button C.uint
+			var down C.int
+			for C.mouseNextButton(d, &button, &down) == 1 {
+				handle(int(button)-xButtonOffset, down != 0)
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}, nil
+}
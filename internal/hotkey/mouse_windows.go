@@ -0,0 +1,107 @@
+//go:build windows
+
+package hotkey
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	whMouseLL     = 14
+	wmXButtonDown = 0x020B
+	wmXButtonUp   = 0x020C
+)
+
+// msLLHookStruct mirrors MSLLHOOKSTRUCT.
+type msLLHookStruct struct {
+	pt        struct{ x, y int32 }
+	mouseData uint32
+	flags     uint32
+	time      uint32
+	extraInfo uintptr
+}
+
+// mouseHook is read by the hook callback, which runs on the hook thread
+// inside GetMessageW. It is set before the hook is installed and not
+// changed while it runs.
+var mouseHook struct {
+	hook    uintptr
+	buttons map[int]bool
+	handle  func(button int, down bool)
+}
+
+// mouseProc is created once: Windows callbacks are never freed, and the
+// hook is reinstalled every time the bindings change.
+var (
+	mouseProcOnce sync.Once
+	mouseProc     uintptr
+)
+
+func mouseCallback(code int, wParam uintptr, lParam *msLLHookStruct) uintptr {
+	if code >= 0 && (wParam == wmXButtonDown || wParam == wmXButtonUp) {
+		// HIWORD of mouseData is XBUTTON1 (1) or XBUTTON2 (2).
+		btn := 3 + int(lParam.mouseData>>16)
+		if mouseHook.buttons[btn] {
+			mouseHook.handle(btn, wParam == wmXButtonDown)
+			return 1 // swallow so the focused app doesn't go back/forward
+		}
+	}
+	r, _, _ := procCallNextHookEx.Call(mouseHook.hook, uintptr(code), wParam, uintptr(unsafe.Pointer(lParam)))
+	return r
+}
+
+// startMouseHook installs a low-level mouse hook on a dedicated thread
+// and pumps messages until stopped.
+func startMouseHook(buttons []int, handle func(button int, down bool)) (func(), error) {
+	mouseProcOnce.Do(func() {
+		mouseProc = windows.NewCallback(mouseCallback)
+	})
+
+	set := make(map[int]bool, len(buttons))
+	for _, b := range buttons {
+		set[b] = true
+	}
+
+	started := make(chan error, 1)
+	done := make(chan struct{})
+	var tid uint32
+	go func() {
+		defer close(done)
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		mouseHook.buttons, mouseHook.handle = set, handle
+		h, _, err := procSetWindowsHookExW.Call(whMouseLL, mouseProc, 0, 0)
+		if h == 0 {
+			started <- fmt.Errorf("install mouse hook: %w", err)
+			return
+		}
+		mouseHook.hook = h
+		defer procUnhookWindowsHookEx.Call(h)
+
+		tid = windows.GetCurrentThreadId()
+		started <- nil
+
+		var m winMsg
+		for {
+			r, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+			if int32(r) <= 0 {
+				break
+			}
+		}
+	}()
+
+	if err := <-started; err != nil {
+		<-done
+		return nil, err
+	}
+	return func() {
+		procPostThreadMessageW.Call(uintptr(tid), wmQuit, 0, 0)
+		<-done
+	}, nil
+}
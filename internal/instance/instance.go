@@ -0,0 +1,103 @@
+// Package instance lets command-line invocations find the running tray
+// app. The app's settings server listens on a random port, so its URL is
+// recorded in a small file in the config directory while it runs.
+package instance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+)
+
+// ErrNotRunning is returned by Lookup when no running instance answers.
+var ErrNotRunning = errors.New("R1 Control is not running")
+
+// probeTimeout bounds how long Lookup waits for the recorded instance.
+const probeTimeout = time.Second
+
+// info is the content of the instance file.
+type info struct {
+	URL string `json:"url"`
+	PID int    `json:"pid"`
+}
+
+// Path returns the full path to the instance file.
+func Path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "instance.json"), nil
+}
+
+// Write records url as the running instance's settings server.
+func Write(url string) error {
+	p, err := Path()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(info{URL: url, PID: os.Getpid()})
+	if err != nil {
+		return fmt.Errorf("marshal instance: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		return fmt.Errorf("write instance file: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the instance file if it still belongs to this process.
+func Remove() {
+	p, err := Path()
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return
+	}
+	var in info
+	if json.Unmarshal(data, &in) == nil && in.PID == os.Getpid() {
+		os.Remove(p)
+	}
+}
+
+// Lookup returns the settings server URL of the running instance. A
+// stale file left by a crashed instance is detected by probing the URL.
+func Lookup() (string, error) {
+	p, err := Path()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return "", ErrNotRunning
+	}
+	if err != nil {
+		return "", fmt.Errorf("read instance file: %w", err)
+	}
+	var in info
+	if err := json.Unmarshal(data, &in); err != nil || in.URL == "" {
+		return "", ErrNotRunning
+	}
+
+	client := &http.Client{Timeout: probeTimeout}
+	resp, err := client.Get(in.URL + "/api/v1/status")
+	if err != nil {
+		return "", ErrNotRunning
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", ErrNotRunning
+	}
+	return in.URL, nil
+}
@@ -0,0 +1,129 @@
+// Package keytest records what the R1 did for each HID usage in the
+// aoa KeyTest tables, in a shared JSON format, so results from many units
+// and firmware versions can be combined into a compatibility matrix.
+package keytest
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+)
+
+// Schema identifies the results format. Bump it on incompatible changes.
+const Schema = "r1-control/keytest/v1"
+
+// Outcomes of one test.
+const (
+	OutcomeEffect   = "effect"    // the R1 did something; see Observed
+	OutcomeNoEffect = "no_effect" // nothing visible happened
+	OutcomeSkipped  = "skipped"
+	OutcomeError    = "error" // the report couldn't be sent; see Observed
+)
+
+// Descriptors are the descriptor types with key tests, by the names used
+// in results.
+var Descriptors = []struct {
+	Name string
+	Type aoa.DescriptorType
+}{
+	{"keyboard", aoa.DescKeyboard},
+	{"consumer", aoa.DescConsumerControl},
+	{"system", aoa.DescSystemControl},
+	{"camera", aoa.DescCameraControl},
+}
+
+// Results is a complete test run.
+type Results struct {
+	Schema     string    `json:"schema"`
+	AppVersion string    `json:"app_version"`
+	Date       time.Time `json:"date"`
+	Product    string    `json:"product"`  // USB product string
+	Firmware   string    `json:"firmware"` // as entered by the tester, e.g. "rabbitOS v0.8.112"
+	Results    []Result  `json:"results"`
+}
+
+// Result is the outcome of one KeyTest.
+type Result struct {
+	Descriptor string `json:"descriptor"` // a Descriptors name
+	Name       string `json:"name"`
+	AndroidKey string `json:"android_key"`
+	LinuxKey   string `json:"linux_key"`
+	Report     string `json:"report"` // key-down report, hex
+	Outcome    string `json:"outcome"`
+	Observed   string `json:"observed,omitempty"`
+}
+
+// NewResult returns the Result for t, before it has an outcome.
+func NewResult(descriptor string, t aoa.KeyTest) Result {
+	return Result{
+		Descriptor: descriptor,
+		Name:       t.Name,
+		AndroidKey: t.AndroidKey,
+		LinuxKey:   t.LinuxKey,
+		Report:     hex.EncodeToString(t.ReportDown),
+	}
+}
+
+// Validate checks r before it is shared.
+func (r *Results) Validate() error {
+	if r.Schema != Schema {
+		return fmt.Errorf("schema is %q, want %q", r.Schema, Schema)
+	}
+	if strings.TrimSpace(r.Firmware) == "" {
+		return fmt.Errorf("firmware version is required")
+	}
+	for i, res := range r.Results {
+		switch res.Outcome {
+		case OutcomeEffect, OutcomeNoEffect, OutcomeSkipped, OutcomeError:
+		default:
+			return fmt.Errorf("result %d (%s): bad outcome %q", i, res.Name, res.Outcome)
+		}
+	}
+	return nil
+}
+
+// Write writes r as indented JSON.
+func (r *Results) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// submitTimeout bounds Submit.
+const submitTimeout = 15 * time.Second
+
+// Submit posts r as JSON to url, e.g. a community collection endpoint.
+func Submit(ctx context.Context, url string, r *Results) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, submitTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", url, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("submit: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("submit: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
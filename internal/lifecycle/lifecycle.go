@@ -0,0 +1,98 @@
+// Package lifecycle runs the app's shutdown in a fixed order. Each
+// subsystem registers its teardown in a stage as it starts, so adding one
+// can't miss cleanup or undo it in the wrong order, e.g. stop the
+// settings server while PTT is still held.
+package lifecycle
+
+import (
+	"log"
+	"sync"
+
+	"github.com/HopIT-Hub/R1-Control/internal/logging"
+)
+
+// Stage orders shutdown hooks: every hook of one stage runs before any of
+// the next.
+type Stage int
+
+const (
+	StopInput   Stage = iota // stop taking new work: background tasks, hotkeys, timers
+	ReleasePTT               // let go of PTT and anything held on its behalf
+	CloseDevice              // close the R1, unregistering its HID descriptors
+	ReleaseHost              // give back the host's microphone, sleep, media keys and screen
+	StopServer               // stop the settings server and withdraw its address
+	Flush                    // save state and close logs
+	numStages
+)
+
+var stageNames = [numStages]string{"stop input", "release PTT", "close device", "release host", "stop server", "flush"}
+
+func (s Stage) String() string {
+	if s < 0 || s >= numStages {
+		return "unknown"
+	}
+	return stageNames[s]
+}
+
+// hook is one registered teardown.
+type hook struct {
+	name string
+	fn   func()
+}
+
+// Manager collects shutdown hooks and runs them once.
+type Manager struct {
+	mu    sync.Mutex
+	hooks [numStages][]hook
+	done  bool
+}
+
+// New returns a manager with no hooks.
+func New() *Manager {
+	return &Manager{}
+}
+
+// OnShutdown registers fn, described by name in the log, to run in
+// stage. Within a stage, hooks run in reverse order of registration,
+// like deferred calls, so later subsystems go first. Hooks registered
+// after Shutdown never run.
+func (m *Manager) OnShutdown(stage Stage, name string, fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if stage < 0 || stage >= numStages {
+		panic("lifecycle: unknown stage")
+	}
+	m.hooks[stage] = append(m.hooks[stage], hook{name, fn})
+}
+
+// Shutdown runs every hook, stage by stage. A hook that panics is logged
+// and the rest still run. Only the first call does anything, so quitting
+// and restarting can share it.
+func (m *Manager) Shutdown() {
+	m.mu.Lock()
+	if m.done {
+		m.mu.Unlock()
+		return
+	}
+	m.done = true
+	hooks := m.hooks
+	m.mu.Unlock()
+
+	log.Printf("[lifecycle] shutting down")
+	for stage, hs := range hooks {
+		for i := len(hs) - 1; i >= 0; i-- {
+			logging.Debugf("[lifecycle] %s: %s", Stage(stage), hs[i].name)
+			run(hs[i])
+		}
+	}
+}
+
+// run calls h, recovering from a panic so the other hooks still run.
+func run(h hook) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Warnf("[lifecycle] %s panicked: %v", h.name, r)
+		}
+	}()
+	h.fn()
+}
@@ -0,0 +1,128 @@
+// Package logging adds levels on top of the standard log package. Plain
+// log.Printf calls are info level; Debugf lines only appear once the
+// level is lowered to debug (config, --verbose, or POST /loglevel), and
+// Warnf/Errorf lines survive raising it.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Level is a log severity.
+type Level int32
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return fmt.Sprintf("Level(%d)", int32(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses "debug", "info", "warn" or "error". "" means info.
+func ParseLevel(s string) (Level, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return Info, nil
+	}
+	if s == "warning" {
+		return Warn, nil
+	}
+	for i, name := range levelNames {
+		if s == name {
+			return Level(i), nil
+		}
+	}
+	return Info, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+}
+
+var (
+	level atomic.Int32 // Info by default
+
+	installOnce sync.Once
+	// direct writes to the real output, bypassing the info filter.
+	direct = log.New(os.Stderr, "", log.LstdFlags)
+)
+
+func init() {
+	level.Store(int32(Info))
+}
+
+// Install routes the standard logger through the level filter, so plain
+// log.Printf output is dropped above info. Call once at startup, after
+// any log.SetOutput/SetFlags.
+func Install() {
+	installOnce.Do(func() {
+		out := log.Writer()
+		direct = log.New(out, log.Prefix(), log.Flags())
+		log.SetOutput(filter{out})
+	})
+}
+
+// filter drops standard logger output when info is disabled.
+type filter struct{ w io.Writer }
+
+func (f filter) Write(p []byte) (int, error) {
+	if !Enabled(Info) {
+		return len(p), nil
+	}
+	return f.w.Write(p)
+}
+
+// SetLevel changes the level at runtime.
+func SetLevel(l Level) {
+	level.Store(int32(l))
+}
+
+// GetLevel returns the current level.
+func GetLevel() Level {
+	return Level(level.Load())
+}
+
+// Enabled reports whether messages at l are logged, e.g. to skip
+// building an expensive debug message.
+func Enabled(l Level) bool {
+	return l >= GetLevel()
+}
+
+// Debugf logs at debug level.
+func Debugf(format string, args ...any) {
+	if Enabled(Debug) {
+		direct.Output(2, fmt.Sprintf(format, args...))
+	}
+}
+
+// Infof logs at info level, like log.Printf.
+func Infof(format string, args ...any) {
+	if Enabled(Info) {
+		direct.Output(2, fmt.Sprintf(format, args...))
+	}
+}
+
+// Warnf logs at warn level.
+func Warnf(format string, args ...any) {
+	if Enabled(Warn) {
+		direct.Output(2, fmt.Sprintf(format, args...))
+	}
+}
+
+// Errorf logs at error level.
+func Errorf(format string, args ...any) {
+	if Enabled(Error) {
+		direct.Output(2, fmt.Sprintf(format, args...))
+	}
+}
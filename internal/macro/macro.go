@@ -0,0 +1,413 @@
+// Package macro runs macros — named sequences of actions from the config —
+// and records new ones from what the user does.
+package macro
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/action"
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/device"
+	"github.com/HopIT-Hub/R1-Control/internal/events"
+	"github.com/HopIT-Hub/R1-Control/internal/logging"
+)
+
+// defaultRetryDelay is the wait between tries of a failed step.
+const defaultRetryDelay = 500 * time.Millisecond
+
+// Hooks connect a running macro to the rest of the app. Nil fields are
+// left out.
+type Hooks struct {
+	Profile func() string       // the active profile's name, for steps that depend on it
+	Ran     func(action string) // told after each step that ran
+}
+
+// Run runs the steps of m in order with the given parameter values,
+// waiting each step's delay first and skipping steps whose condition
+// doesn't hold. A failed step is tried again up to its Retries; the macro
+// stops at a step that still fails, or when ctx is cancelled.
+func Run(ctx context.Context, dev *device.Manager, hooks Hooks, m config.Macro, given Params) error {
+	params, err := resolve(m, given)
+	if err != nil {
+		return err
+	}
+	for i, st := range m.Steps {
+		payload, repeat, err := prepare(st, params)
+		if err != nil {
+			return fmt.Errorf("macro %q step %d (%s): %w", m.Name, i+1, st.Action, err)
+		}
+		if err := sleep(ctx, time.Duration(st.DelayMs)*time.Millisecond); err != nil {
+			return fmt.Errorf("macro %q: %w", m.Name, err)
+		}
+		if ok, why := holds(dev, hooks.Profile, st.If, time.Now()); !ok {
+			logging.Debugf("[macro] %q step %d (%s) skipped: %s", m.Name, i+1, st.Action, why)
+			continue
+		}
+		for range repeat {
+			if err := runStep(ctx, dev, st, payload); err != nil {
+				return fmt.Errorf("macro %q step %d (%s): %w", m.Name, i+1, st.Action, err)
+			}
+			if hooks.Ran != nil {
+				hooks.Ran(st.Action)
+			}
+		}
+	}
+	log.Printf("[macro] %q done (%d steps)", m.Name, len(m.Steps))
+	return nil
+}
+
+// prepare fills the parameters into a step's payload and repeat count.
+func prepare(st config.MacroStep, params Params) (payload json.RawMessage, repeat int, err error) {
+	payload, err = expand(st.Payload, params)
+	if err != nil {
+		return nil, 0, err
+	}
+	repeat = 1
+	if len(st.Repeat) > 0 {
+		raw, err := expand(st.Repeat, params)
+		if err != nil {
+			return nil, 0, err
+		}
+		if err := json.Unmarshal(raw, &repeat); err != nil || repeat < 0 || repeat > config.MaxMacroRepeat {
+			return nil, 0, fmt.Errorf("repeat must be a whole number in range 0-%d", config.MaxMacroRepeat)
+		}
+	}
+	return payload, repeat, nil
+}
+
+// runStep runs a step's action once, trying again up to its Retries.
+func runStep(ctx context.Context, dev *device.Manager, st config.MacroStep, payload json.RawMessage) error {
+	retryDelay := time.Duration(st.RetryDelayMs) * time.Millisecond
+	if retryDelay == 0 {
+		retryDelay = defaultRetryDelay
+	}
+	err := action.Run(dev, st.Action, payload)
+	for try := 1; err != nil && try <= st.Retries; try++ {
+		logging.Warnf("[macro] %s: %v — retrying (%d/%d)", st.Action, err, try, st.Retries)
+		if err := sleep(ctx, retryDelay); err != nil {
+			return err
+		}
+		err = action.Run(dev, st.Action, payload)
+	}
+	return err
+}
+
+// Check reports whether m can run with the given parameter values: that
+// they fit its parameters, and fill into every step.
+func Check(m config.Macro, given Params) error {
+	params, err := resolve(m, given)
+	if err != nil {
+		return err
+	}
+	for i, st := range m.Steps {
+		payload, _, err := prepare(st, params)
+		if err == nil {
+			err = action.Validate(st.Action, payload)
+		}
+		if err != nil {
+			return fmt.Errorf("macro %q step %d (%s): %w", m.Name, i+1, st.Action, err)
+		}
+	}
+	return nil
+}
+
+// Find returns the macro called name.
+func Find(macros []config.Macro, name string) (config.Macro, bool) {
+	i := slices.IndexFunc(macros, func(m config.Macro) bool { return m.Name == name })
+	if i < 0 {
+		return config.Macro{}, false
+	}
+	return macros[i], true
+}
+
+// Player plays macros in the background, one at a time.
+type Player struct {
+	dev     *device.Manager
+	bus     *events.Bus
+	profile func() string // the active profile's name; nil until SetProfileSource
+
+	mu      sync.Mutex
+	running string // macro being played; "" = none
+}
+
+// NewPlayer returns a player for macros on dev that publishes TypeMacro
+// events on bus (nil = none) when a macro starts and finishes.
+func NewPlayer(dev *device.Manager, bus *events.Bus) *Player {
+	return &Player{dev: dev, bus: bus}
+}
+
+// SetProfileSource sets where the player learns the active profile's
+// name, for steps that only run under a profile. Set before Start.
+func (p *Player) SetProfileSource(fn func() string) {
+	p.profile = fn
+}
+
+// Running returns the name of the macro being played, or "".
+func (p *Player) Running() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running
+}
+
+// Start checks m against the given parameter values and plays it in the
+// background, unless another macro is still playing. ran, if not nil, is
+// told after each step that ran.
+func (p *Player) Start(m config.Macro, params Params, ran func(action string)) error {
+	if err := Check(m, params); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running != "" {
+		return fmt.Errorf("macro %q is still running", p.running)
+	}
+	p.running = m.Name
+	p.bus.Publish(events.Event{Type: events.TypeMacro, Name: "started", Detail: m.Name})
+	go func() {
+		if err := Run(context.Background(), p.dev, Hooks{Profile: p.profile, Ran: ran}, m, params); err != nil {
+			logging.Warnf("[macro] %v", err)
+		}
+		p.mu.Lock()
+		p.running = ""
+		p.mu.Unlock()
+		p.bus.Publish(events.Event{Type: events.TypeMacro, Name: "finished", Detail: m.Name})
+	}()
+	return nil
+}
+
+// holds reports whether c holds at now, or else which part doesn't. A nil
+// condition always holds.
+func holds(dev *device.Manager, profile func() string, c *config.MacroCondition, now time.Time) (ok bool, why string) {
+	if c == nil {
+		return true, ""
+	}
+	if c.Profile != "" {
+		active := ""
+		if profile != nil {
+			active = profile()
+		}
+		if active != c.Profile {
+			return false, fmt.Sprintf("profile is %q", active)
+		}
+	}
+	if c.Connected != nil && dev.State().Online() != *c.Connected {
+		return false, fmt.Sprintf("connected is %v", !*c.Connected)
+	}
+	if c.Awake != nil && dev.ScreenAwake() != *c.Awake {
+		return false, fmt.Sprintf("awake is %v", !*c.Awake)
+	}
+	// Checked by Validate and the config, so an error here is a bug
+	if w, ok, err := c.Window(); err != nil || (ok && !w.Contains(now)) {
+		return false, "outside " + strings.TrimSpace(c.Between+" "+strings.Join(c.Days, ","))
+	}
+	return true, ""
+}
+
+// sleep waits for d, or until ctx is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Validate checks that every step of m is a known action with a well
+// formed payload. Payloads that use a parameter without a default are
+// only checked for their placeholders.
+func Validate(m config.Macro) error {
+	if m.Name == "" {
+		return errors.New("macro name is empty")
+	}
+	params, complete := standIns(m)
+	for i, st := range m.Steps {
+		payload, _, err := prepare(st, params)
+		if err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+		if complete {
+			err = action.Validate(st.Action, payload)
+		} else if !slices.Contains(action.Names(), st.Action) {
+			err = fmt.Errorf("unknown action %q", st.Action)
+		}
+		if err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+		if st.DelayMs < 0 || st.DelayMs > config.MaxMacroDelayMs {
+			return fmt.Errorf("step %d: delay_ms must be in range 0-%d", i+1, config.MaxMacroDelayMs)
+		}
+		if st.RetryDelayMs < 0 || st.RetryDelayMs > config.MaxMacroDelayMs {
+			return fmt.Errorf("step %d: retry_delay_ms must be in range 0-%d", i+1, config.MaxMacroDelayMs)
+		}
+		if st.Retries < 0 || st.Retries > config.MaxMacroRetries {
+			return fmt.Errorf("step %d: retries must be in range 0-%d", i+1, config.MaxMacroRetries)
+		}
+		if err := st.If.Check(); err != nil {
+			return fmt.Errorf("step %d: if: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Recording limits.
+const (
+	DefaultRecordTime = 10 * time.Second
+	MaxRecordTime     = 2 * time.Minute
+)
+
+// ErrRecording is returned by Start while a recording is running.
+var ErrRecording = errors.New("a macro is already being recorded")
+
+// Recorder records device actions and PTT presses into a macro for a
+// set time, keeping the pauses between them.
+type Recorder struct {
+	bus  *events.Bus
+	save func(config.Macro) error
+
+	mu          sync.Mutex
+	name        string // macro being recorded; "" = not recording
+	until       time.Time
+	last        time.Time // time of the previous step, or the start
+	steps       []config.MacroStep
+	timer       *time.Timer
+	unsubscribe func()
+}
+
+// NewRecorder returns a recorder that listens on bus and hands each
+// finished recording to save.
+func NewRecorder(bus *events.Bus, save func(config.Macro) error) *Recorder {
+	return &Recorder{bus: bus, save: save}
+}
+
+// Status describes the recording in progress.
+type Status struct {
+	Recording bool   `json:"recording"`
+	Name      string `json:"name,omitempty"`
+	Remaining int    `json:"remaining_seconds,omitempty"`
+	Steps     int    `json:"steps"`
+}
+
+// Status reports whether a macro is being recorded.
+func (r *Recorder) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.name == "" {
+		return Status{}
+	}
+	return Status{
+		Recording: true,
+		Name:      r.name,
+		Remaining: int(time.Until(r.until).Round(time.Second).Seconds()),
+		Steps:     len(r.steps),
+	}
+}
+
+// Start records into a macro called name for d (0 = DefaultRecordTime),
+// replacing any macro with that name when done.
+func (r *Recorder) Start(name string, d time.Duration) error {
+	if name == "" {
+		return errors.New("macro name is empty")
+	}
+	if d <= 0 {
+		d = DefaultRecordTime
+	}
+	if d > MaxRecordTime {
+		return fmt.Errorf("recording time is limited to %v", MaxRecordTime)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.name != "" {
+		return ErrRecording
+	}
+	now := time.Now()
+	r.name = name
+	r.until = now.Add(d)
+	r.last = now
+	r.steps = nil
+	r.unsubscribe = r.bus.Subscribe(r.record, events.TypeAction, events.TypeState)
+	r.timer = time.AfterFunc(d, func() { r.Stop() })
+	log.Printf("[macro] recording %q for %v", name, d)
+	return nil
+}
+
+// Stop ends the recording in progress, if any, and saves it unless
+// nothing was recorded or it doesn't pass Validate. The macro is
+// returned either way.
+func (r *Recorder) Stop() (config.Macro, error) {
+	r.mu.Lock()
+	if r.name == "" {
+		r.mu.Unlock()
+		return config.Macro{}, errors.New("not recording")
+	}
+	r.unsubscribe()
+	r.timer.Stop()
+	m := config.Macro{Name: r.name, Steps: r.steps}
+	r.name, r.steps = "", nil
+	r.mu.Unlock()
+
+	if len(m.Steps) == 0 {
+		log.Printf("[macro] nothing recorded for %q", m.Name)
+		return m, errors.New("nothing was recorded")
+	}
+	if err := Validate(m); err != nil {
+		log.Printf("[macro] recorded %q is invalid, not saved: %v", m.Name, err)
+		return m, fmt.Errorf("recorded macro not saved: %w", err)
+	}
+	if err := r.save(m); err != nil {
+		return m, fmt.Errorf("save macro: %w", err)
+	}
+	log.Printf("[macro] recorded %q (%d steps)", m.Name, len(m.Steps))
+	return m, nil
+}
+
+// record turns an action or a PTT change into a step. Runs in the
+// publisher's goroutine.
+func (r *Recorder) record(e events.Event) {
+	st, ok := stepFor(e)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.name == "" {
+		return
+	}
+	st.DelayMs = min(int(e.Time.Sub(r.last).Milliseconds()), config.MaxMacroDelayMs)
+	if len(r.steps) == 0 {
+		st.DelayMs = 0 // no point waiting for the time it took to start
+	}
+	r.last = e.Time
+	r.steps = append(r.steps, st)
+}
+
+// stepFor returns the step repeating e, if e is something the user did.
+func stepFor(e events.Event) (config.MacroStep, bool) {
+	switch e.Type {
+	case events.TypeAction:
+		payload, _ := e.Value.(json.RawMessage)
+		return config.MacroStep{Action: e.Name, Payload: payload}, true
+	case events.TypeState:
+		switch {
+		case e.Name == device.PTTActive.String():
+			return config.MacroStep{Action: "ptt_on"}, true
+		case e.Detail == device.PTTActive.String() && e.Name == device.Connected.String():
+			return config.MacroStep{Action: "ptt_off"}, true
+		}
+	}
+	return config.MacroStep{}, false
+}
@@ -0,0 +1,261 @@
+package macro
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+)
+
+// Params are the values of a macro's parameters, as JSON.
+type Params map[string]json.RawMessage
+
+// resolve returns the macro's parameter defaults overridden by given,
+// refusing unknown parameters and missing required ones (default null).
+func resolve(m config.Macro, given Params) (Params, error) {
+	for name := range given {
+		if _, ok := m.Params[name]; !ok {
+			return nil, fmt.Errorf("macro %q has no parameter %q", m.Name, name)
+		}
+	}
+	params := make(Params, len(m.Params))
+	for name, def := range m.Params {
+		v, ok := given[name]
+		if !ok {
+			v = def
+		}
+		if isNull(v) {
+			return nil, fmt.Errorf("macro %q needs parameter %q", m.Name, name)
+		}
+		params[name] = v
+	}
+	return params, nil
+}
+
+func isNull(v json.RawMessage) bool {
+	v = bytes.TrimSpace(v)
+	return len(v) == 0 || string(v) == "null"
+}
+
+// placeholder matches {{expression}} in a JSON string.
+var placeholder = regexp.MustCompile(`\{\{([^{}]*)\}\}`)
+
+// expand replaces the placeholders in the strings of raw, a step's
+// payload or repeat count. A string that is only a placeholder becomes
+// its value, keeping its type: "{{x}}" with x = 120 is the number 120.
+// Elsewhere the value is put into the text.
+func expand(raw json.RawMessage, params Params) (json.RawMessage, error) {
+	if len(raw) == 0 || !bytes.Contains(raw, []byte("{{")) {
+		return raw, nil
+	}
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	v, err := expandValue(v, params)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func expandValue(v any, params Params) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			x, err := expandValue(e, params)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = x
+		}
+		return v, nil
+	case []any:
+		for i, e := range v {
+			x, err := expandValue(e, params)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = x
+		}
+		return v, nil
+	case string:
+		return expandString(v, params)
+	default:
+		return v, nil
+	}
+}
+
+func expandString(s string, params Params) (any, error) {
+	if m := placeholder.FindStringSubmatch(s); m != nil && m[0] == s {
+		return eval(m[1], params)
+	}
+	var firstErr error
+	out := placeholder.ReplaceAllStringFunc(s, func(ph string) string {
+		v, err := eval(placeholder.FindStringSubmatch(ph)[1], params)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return ph
+		}
+		if s, ok := v.(string); ok {
+			return s
+		}
+		b, _ := json.Marshal(v)
+		return string(b)
+	})
+	return out, firstErr
+}
+
+// eval evaluates a placeholder: a parameter name, which gives its value,
+// or integer arithmetic on parameters and numbers with + - * / and
+// parentheses, e.g. "col * 6000 + 1500".
+func eval(expr string, params Params) (any, error) {
+	expr = strings.TrimSpace(expr)
+	if raw, ok := params[expr]; ok {
+		var v any
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("parameter %q: %w", expr, err)
+		}
+		return v, nil
+	}
+	p := &parser{src: expr, params: params}
+	n, err := p.sum()
+	if err == nil && p.peek() != 0 {
+		err = fmt.Errorf("unexpected %q", string(p.peek()))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("{{%s}}: %w", expr, err)
+	}
+	return n, nil
+}
+
+// parser evaluates integer arithmetic by recursive descent.
+type parser struct {
+	src    string
+	pos    int
+	params Params
+}
+
+func (p *parser) peek() byte {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos == len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// sum = product { ("+" | "-") product }
+func (p *parser) sum() (int64, error) {
+	n, err := p.product()
+	for err == nil {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			break
+		}
+		p.pos++
+		var m int64
+		if m, err = p.product(); op == '+' {
+			n += m
+		} else {
+			n -= m
+		}
+	}
+	return n, err
+}
+
+// product = operand { ("*" | "/") operand }
+func (p *parser) product() (int64, error) {
+	n, err := p.operand()
+	for err == nil {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			break
+		}
+		p.pos++
+		var m int64
+		if m, err = p.operand(); err != nil {
+			break
+		}
+		if op == '*' {
+			n *= m
+		} else if m == 0 {
+			err = fmt.Errorf("division by zero")
+		} else {
+			n /= m
+		}
+	}
+	return n, err
+}
+
+// operand = number | name | "(" sum ")" | "-" operand
+func (p *parser) operand() (int64, error) {
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.pos++
+		n, err := p.sum()
+		if err == nil && p.peek() != ')' {
+			err = fmt.Errorf("missing )")
+		}
+		p.pos++
+		return n, err
+	case c == '-':
+		p.pos++
+		n, err := p.operand()
+		return -n, err
+	case c >= '0' && c <= '9':
+		start := p.pos
+		for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			p.pos++
+		}
+		return strconv.ParseInt(p.src[start:p.pos], 10, 64)
+	case c == '_' || unicode.IsLetter(rune(c)):
+		start := p.pos
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' ||
+			unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+		name := p.src[start:p.pos]
+		raw, ok := p.params[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown parameter %q", name)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parameter %q is not a whole number", name)
+		}
+		return n, nil
+	case c == 0:
+		return 0, fmt.Errorf("unexpected end")
+	default:
+		return 0, fmt.Errorf("unexpected %q", string(c))
+	}
+}
+
+// standIns returns each parameter's default, or 1 for a required one,
+// for checking a macro before it has values. complete is false if any
+// parameter is required, so payloads can't be fully checked.
+func standIns(m config.Macro) (params Params, complete bool) {
+	params = make(Params, len(m.Params))
+	complete = true
+	for name, def := range m.Params {
+		if isNull(def) {
+			def, complete = json.RawMessage("1"), false
+		}
+		params[name] = def
+	}
+	return params, complete
+}
@@ -0,0 +1,335 @@
+// Package openrgb turns keyboard and mouse RGB into a PTT indicator: it
+// follows the device state on the event bus and, through OpenRGB's SDK
+// server, loads one lighting profile saved in OpenRGB while PTT is on and
+// another when it goes off.
+package openrgb
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/device"
+	"github.com/HopIT-Hub/R1-Control/internal/events"
+	"github.com/HopIT-Hub/R1-Control/internal/logging"
+)
+
+// DefaultAddress is where OpenRGB's SDK server listens unless told
+// otherwise.
+const DefaultAddress = "127.0.0.1:6742"
+
+// clientName is how this app shows up in OpenRGB's SDK client list.
+const clientName = "R1 Control"
+
+// SDK protocol versions: the newest this client speaks, and the oldest
+// with profiles.
+const (
+	protocolVersion = 3
+	minProtocol     = 2
+)
+
+// SDK packet IDs (OpenRGB's NetworkProtocol.h).
+const (
+	pktProtocolVersion = 40
+	pktSetClientName   = 50
+	pktProfileList     = 150
+	pktLoadProfile     = 152
+)
+
+const (
+	dialTimeout   = 2 * time.Second
+	replyTimeout  = 2 * time.Second
+	retryInterval = 10 * time.Second // after a failed connection, before the next try
+	blinkInterval = 500 * time.Millisecond
+	maxPacket     = 1 << 20
+)
+
+// Indicator loads OpenRGB profiles as PTT goes on and off. Subscribe it
+// to the bus, then Run it.
+type Indicator struct {
+	cfg       config.OpenRGBConfig
+	onProblem func(string)
+
+	on      atomic.Bool   // PTT is on, as last published
+	changed chan struct{} // signalled when on may have changed
+
+	// mu guards the connection, which Run and Close share.
+	mu       sync.Mutex
+	conn     net.Conn
+	failedAt time.Time // last failed connection
+	shown    string    // profile last loaded
+	closed   bool
+}
+
+// New returns an indicator for cfg.
+func New(cfg config.OpenRGBConfig) *Indicator {
+	if cfg.Address == "" {
+		cfg.Address = DefaultAddress
+	}
+	return &Indicator{cfg: cfg, changed: make(chan struct{}, 1)}
+}
+
+// SetProblemHandler sets a callback for user-visible problems, such as
+// OpenRGB not running or a missing profile. It is called with "" once
+// OpenRGB is reached.
+func (in *Indicator) SetProblemHandler(fn func(string)) {
+	in.onProblem = fn
+}
+
+func (in *Indicator) problem(p string) {
+	if in.onProblem != nil {
+		in.onProblem(p)
+	}
+}
+
+// Subscribe follows the device state published on bus.
+func (in *Indicator) Subscribe(bus *events.Bus) {
+	bus.Subscribe(func(e events.Event) {
+		state, ok := e.Value.(device.State)
+		if !ok {
+			return
+		}
+		in.on.Store(state == device.PTTActive)
+		select {
+		case in.changed <- struct{}{}:
+		default:
+		}
+	}, events.TypeState)
+}
+
+// Run loads the profiles as PTT goes on and off until ctx is done. With
+// blink set, it alternates them while PTT is on.
+func (in *Indicator) Run(ctx context.Context) {
+	var blink *time.Ticker
+	var blinkC <-chan time.Time
+	stopBlink := func() {
+		if blink != nil {
+			blink.Stop()
+			blink, blinkC = nil, nil
+		}
+	}
+	defer stopBlink()
+
+	on, lit := false, false
+	in.load(in.cfg.IdleProfile)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-in.changed:
+			if now := in.on.Load(); now != on {
+				on, lit = now, now
+				stopBlink()
+				if on && in.cfg.Blink {
+					blink = time.NewTicker(blinkInterval)
+					blinkC = blink.C
+				}
+				in.load(in.profile(lit))
+			}
+		case <-blinkC:
+			lit = !lit
+			in.load(in.profile(lit))
+		}
+	}
+}
+
+// profile returns the PTT profile if lit, else the idle one.
+func (in *Indicator) profile(lit bool) string {
+	if lit {
+		return in.cfg.PTTProfile
+	}
+	return in.cfg.IdleProfile
+}
+
+// Close restores the idle profile if PTT's is showing and disconnects
+// from OpenRGB. Call it after Run has returned.
+func (in *Indicator) Close() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.closed = true
+	if in.conn == nil {
+		return
+	}
+	if in.shown != in.cfg.IdleProfile {
+		if err := writePacket(in.conn, pktLoadProfile, cstring(in.cfg.IdleProfile)); err != nil {
+			logging.Warnf("[openrgb] restore %q: %v", in.cfg.IdleProfile, err)
+		}
+	}
+	in.conn.Close()
+	in.conn = nil
+}
+
+// load loads the named profile, connecting first if need be. A
+// connection that went stale, e.g. because OpenRGB restarted, is
+// replaced once.
+func (in *Indicator) load(name string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.closed {
+		return
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		if in.conn == nil {
+			if !in.failedAt.IsZero() && time.Since(in.failedAt) < retryInterval {
+				return
+			}
+			conn, err := in.connect()
+			if err != nil {
+				in.failedAt = time.Now()
+				logging.Warnf("[openrgb] %v", err)
+				in.problem("OpenRGB PTT indicator: " + err.Error())
+				return
+			}
+			in.conn, in.failedAt = conn, time.Time{}
+			in.problem("")
+		}
+		err := writePacket(in.conn, pktLoadProfile, cstring(name))
+		if err == nil {
+			in.shown = name
+			return
+		}
+		logging.Warnf("[openrgb] load %q: %v", name, err)
+		in.conn.Close()
+		in.conn = nil
+	}
+}
+
+// connect opens an SDK connection, agrees on a protocol version with
+// profiles, and checks that both profiles exist.
+func (in *Indicator) connect() (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", in.cfg.Address, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("can't reach OpenRGB's SDK server at %s; is OpenRGB running with it started? (%v)", in.cfg.Address, err)
+	}
+	if err := in.handshake(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// OpenRGB sends updates, e.g. when its device list changes; nothing
+	// here needs them, but they must be read so its writes don't stall.
+	go io.Copy(io.Discard, conn)
+	return conn, nil
+}
+
+func (in *Indicator) handshake(conn net.Conn) error {
+	conn.SetDeadline(time.Now().Add(replyTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	// Servers before protocol 1 don't answer the version request
+	version := uint32(0)
+	if err := writePacket(conn, pktProtocolVersion, binary.LittleEndian.AppendUint32(nil, protocolVersion)); err != nil {
+		return err
+	}
+	data, err := readReply(conn, pktProtocolVersion)
+	var ne net.Error
+	switch {
+	case err == nil && len(data) >= 4:
+		version = min(binary.LittleEndian.Uint32(data), protocolVersion)
+	case errors.As(err, &ne) && ne.Timeout():
+	case err != nil:
+		return err
+	}
+	if version < minProtocol {
+		return fmt.Errorf("OpenRGB at %s speaks SDK protocol %d; profiles need OpenRGB 0.6 or later", in.cfg.Address, version)
+	}
+	if err := writePacket(conn, pktSetClientName, cstring(clientName)); err != nil {
+		return err
+	}
+
+	if err := writePacket(conn, pktProfileList, nil); err != nil {
+		return err
+	}
+	data, err = readReply(conn, pktProfileList)
+	if err != nil {
+		return fmt.Errorf("list OpenRGB profiles: %w", err)
+	}
+	profiles, err := parseProfiles(data)
+	if err != nil {
+		return fmt.Errorf("list OpenRGB profiles: %w", err)
+	}
+	for _, name := range []string{in.cfg.PTTProfile, in.cfg.IdleProfile} {
+		if !slices.Contains(profiles, name) {
+			return fmt.Errorf("OpenRGB has no profile named %q (it has: %s)", name, strings.Join(profiles, ", "))
+		}
+	}
+	logging.Debugf("[openrgb] connected to %s (protocol %d)", in.cfg.Address, version)
+	return nil
+}
+
+// writePacket sends an SDK packet: the "ORGB" magic, then the device
+// index (unused here), packet ID and data size as little-endian uint32s,
+// then the data.
+func writePacket(w io.Writer, id uint32, data []byte) error {
+	b := make([]byte, 0, 16+len(data))
+	b = append(b, "ORGB"...)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint32(b, id)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	b = append(b, data...)
+	_, err := w.Write(b)
+	return err
+}
+
+// readReply reads packets until one with id arrives and returns its
+// data, skipping the updates OpenRGB sends unasked.
+func readReply(r io.Reader, id uint32) ([]byte, error) {
+	head := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(r, head); err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(head[:4], []byte("ORGB")) {
+			return nil, errors.New("not an OpenRGB SDK server")
+		}
+		size := binary.LittleEndian.Uint32(head[12:])
+		if size > maxPacket {
+			return nil, fmt.Errorf("OpenRGB packet of %d bytes is too big", size)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		if binary.LittleEndian.Uint32(head[8:]) == id {
+			return data, nil
+		}
+	}
+}
+
+// parseProfiles decodes a profile list: its size as a uint32, the count
+// as a uint16, then each name as a uint16 length and that many bytes,
+// NUL included.
+func parseProfiles(data []byte) ([]string, error) {
+	if len(data) < 6 {
+		return nil, errors.New("short profile list")
+	}
+	n := int(binary.LittleEndian.Uint16(data[4:]))
+	rest := data[6:]
+	names := make([]string, 0, n)
+	for range n {
+		if len(rest) < 2 {
+			return nil, errors.New("truncated profile list")
+		}
+		l := int(binary.LittleEndian.Uint16(rest))
+		if len(rest) < 2+l {
+			return nil, errors.New("truncated profile list")
+		}
+		names = append(names, strings.TrimRight(string(rest[2:2+l]), "\x00"))
+		rest = rest[2+l:]
+	}
+	return names, nil
+}
+
+// cstring returns s NUL-terminated, as the SDK sends strings.
+func cstring(s string) []byte {
+	return append([]byte(s), 0)
+}
@@ -0,0 +1,77 @@
+// Package overlay shows a small always-on-top, borderless indicator window
+// that turns red while PTT is active, similar to the mic indicators in
+// conferencing apps. Each platform has its own implementation file.
+package overlay
+
+// Corner positions for the overlay window.
+const (
+	TopLeft     = "top-left"
+	TopRight    = "top-right"
+	BottomLeft  = "bottom-left"
+	BottomRight = "bottom-right"
+)
+
+// Overlay defaults.
+const (
+	defaultSize   = 24 // pixels
+	defaultMargin = 16 // pixels from the screen edges
+)
+
+// Options configures the overlay window.
+type Options struct {
+	Position string // one of the corner constants (default: top-right)
+	Size     int    // edge length in pixels (default: 24)
+}
+
+// rgb is a 24-bit color.
+type rgb struct{ r, g, b uint8 }
+
+var (
+	idleColor   = rgb{0x3a, 0x3a, 0x3a} // dark grey — PTT off
+	activeColor = rgb{0xe5, 0x39, 0x35} // red — PTT on
+)
+
+// Show opens the overlay window in its idle color. Calling Show while the
+// overlay is already open moves it to the new position.
+func Show(opts Options) error {
+	if opts.Size <= 0 {
+		opts.Size = defaultSize
+	}
+	switch opts.Position {
+	case TopLeft, TopRight, BottomLeft, BottomRight:
+	default:
+		opts.Position = TopRight
+	}
+	return show(opts)
+}
+
+// SetActive switches the overlay between its idle and PTT-active colors.
+// It is a no-op if the overlay is not shown.
+func SetActive(active bool) {
+	if active {
+		setColor(activeColor)
+	} else {
+		setColor(idleColor)
+	}
+}
+
+// Hide closes the overlay window if it is open.
+func Hide() {
+	hide()
+}
+
+// origin returns the top-left corner of the overlay window for a screen
+// of the given size, in top-left-origin screen coordinates.
+func origin(opts Options, screenW, screenH int) (x, y int) {
+	x, y = defaultMargin, defaultMargin
+	switch opts.Position {
+	case TopRight:
+		x = screenW - opts.Size - defaultMargin
+	case BottomLeft:
+		y = screenH - opts.Size - defaultMargin
+	case BottomRight:
+		x = screenW - opts.Size - defaultMargin
+		y = screenH - opts.Size - defaultMargin
+	}
+	return x, y
+}
@@ -0,0 +1,81 @@
+//go:build darwin
+
+package overlay
+
+/*
+#cgo CFLAGS: -x objective-c -fobjc-arc
+#cgo LDFLAGS: -framework Cocoa
+#import <Cocoa/Cocoa.h>
+
+static NSPanel *overlayPanel = nil;
+
+static NSColor *overlayColor(int r, int g, int b) {
+	return [NSColor colorWithSRGBRed:r/255.0 green:g/255.0 blue:b/255.0 alpha:1.0];
+}
+
+static void overlayScreenSize(int *w, int *h) {
+	CGRect bounds = CGDisplayBounds(CGMainDisplayID());
+	*w = (int)bounds.size.width;
+	*h = (int)bounds.size.height;
+}
+
+// x, y are top-left-origin coordinates; Cocoa uses a bottom-left origin.
+static void overlayShow(int x, int y, int size, int r, int g, int b) {
+	dispatch_async(dispatch_get_main_queue(), ^{
+		if (overlayPanel != nil) {
+			[overlayPanel close];
+			overlayPanel = nil;
+		}
+		CGFloat screenH = [[NSScreen screens][0] frame].size.height;
+		NSRect frame = NSMakeRect(x, screenH - y - size, size, size);
+		NSPanel *p = [[NSPanel alloc] initWithContentRect:frame
+			styleMask:NSWindowStyleMaskBorderless | NSWindowStyleMaskNonactivatingPanel
+			backing:NSBackingStoreBuffered
+			defer:NO];
+		p.level = NSStatusWindowLevel;
+		p.ignoresMouseEvents = YES;
+		p.hasShadow = NO;
+		p.releasedWhenClosed = NO;
+		p.collectionBehavior = NSWindowCollectionBehaviorCanJoinAllSpaces |
+			NSWindowCollectionBehaviorStationary;
+		p.backgroundColor = overlayColor(r, g, b);
+		[p orderFrontRegardless];
+		overlayPanel = p;
+	});
+}
+
+static void overlaySetColor(int r, int g, int b) {
+	dispatch_async(dispatch_get_main_queue(), ^{
+		if (overlayPanel != nil) {
+			overlayPanel.backgroundColor = overlayColor(r, g, b);
+		}
+	});
+}
+
+static void overlayHide(void) {
+	dispatch_async(dispatch_get_main_queue(), ^{
+		if (overlayPanel != nil) {
+			[overlayPanel close];
+			overlayPanel = nil;
+		}
+	});
+}
+*/
+import "C"
+
+func show(opts Options) error {
+	var w, h C.int
+	C.overlayScreenSize(&w, &h)
+	x, y := origin(opts, int(w), int(h))
+	c := idleColor
+	C.overlayShow(C.int(x), C.int(y), C.int(opts.Size), C.int(c.r), C.int(c.g), C.int(c.b))
+	return nil
+}
+
+func setColor(c rgb) {
+	C.overlaySetColor(C.int(c.r), C.int(c.g), C.int(c.b))
+}
+
+func hide() {
+	C.overlayHide()
+}
@@ -0,0 +1,93 @@
+//go:build linux
+
+package overlay
+
+/*
+#cgo LDFLAGS: -lX11
+#include <stdlib.h>
+#include <X11/Xlib.h>
+#include <X11/Xutil.h>
+
+static Window overlayCreate(Display *d, int x, int y, int size, unsigned long color) {
+	int screen = DefaultScreen(d);
+	XSetWindowAttributes attrs;
+	attrs.override_redirect = True; // no decorations, not managed by the WM
+	attrs.background_pixel = color;
+	Window w = XCreateWindow(d, RootWindow(d, screen), x, y, size, size, 0,
+		CopyFromParent, InputOutput, CopyFromParent,
+		CWOverrideRedirect | CWBackPixel, &attrs);
+	XMapRaised(d, w);
+	XFlush(d);
+	return w;
+}
+
+static void overlaySetColor(Display *d, Window w, unsigned long color) {
+	XSetWindowBackground(d, w, color);
+	XClearWindow(d, w);
+	XRaiseWindow(d, w);
+	XFlush(d);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	mu      sync.Mutex
+	display *C.Display
+	window  C.Window
+)
+
+func pixel(c rgb) C.ulong {
+	// TrueColor visuals (all modern X servers) use 0xRRGGBB pixels.
+	return C.ulong(uint32(c.r)<<16 | uint32(c.g)<<8 | uint32(c.b))
+}
+
+func show(opts Options) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if display == nil {
+		display = C.XOpenDisplay(nil)
+		if display == nil {
+			return fmt.Errorf("cannot open X display")
+		}
+	}
+	if window != 0 {
+		C.XDestroyWindow(display, window)
+		window = 0
+	}
+
+	screen := C.XDefaultScreen(display)
+	w := int(C.XDisplayWidth(display, screen))
+	h := int(C.XDisplayHeight(display, screen))
+	x, y := origin(opts, w, h)
+
+	window = C.overlayCreate(display, C.int(x), C.int(y), C.int(opts.Size), pixel(idleColor))
+	return nil
+}
+
+func setColor(c rgb) {
+	mu.Lock()
+	defer mu.Unlock()
+	if window == 0 {
+		return
+	}
+	C.overlaySetColor(display, window, pixel(c))
+}
+
+func hide() {
+	mu.Lock()
+	defer mu.Unlock()
+	if window != 0 {
+		C.XDestroyWindow(display, window)
+		window = 0
+	}
+	if display != nil {
+		C.XCloseDisplay(display)
+		display = nil
+	}
+}
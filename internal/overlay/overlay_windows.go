@@ -0,0 +1,193 @@
+//go:build windows
+
+package overlay
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32 = windows.NewLazySystemDLL("user32.dll")
+	gdi32  = windows.NewLazySystemDLL("gdi32.dll")
+
+	procRegisterClassExW = user32.NewProc("RegisterClassExW")
+	procCreateWindowExW  = user32.NewProc("CreateWindowExW")
+	procDefWindowProcW   = user32.NewProc("DefWindowProcW")
+	procShowWindow       = user32.NewProc("ShowWindow")
+	procGetMessageW      = user32.NewProc("GetMessageW")
+	procTranslateMessage = user32.NewProc("TranslateMessage")
+	procDispatchMessageW = user32.NewProc("DispatchMessageW")
+	procPostMessageW     = user32.NewProc("PostMessageW")
+	procPostQuitMessage  = user32.NewProc("PostQuitMessage")
+	procInvalidateRect   = user32.NewProc("InvalidateRect")
+	procGetClientRect    = user32.NewProc("GetClientRect")
+	procFillRect         = user32.NewProc("FillRect")
+	procGetSystemMetrics = user32.NewProc("GetSystemMetrics")
+	procCreateSolidBrush = gdi32.NewProc("CreateSolidBrush")
+	procDeleteObject     = gdi32.NewProc("DeleteObject")
+)
+
+const (
+	wsPopup          = 0x80000000
+	wsExTopmost      = 0x00000008
+	wsExToolWindow   = 0x00000080
+	wsExNoActivate   = 0x08000000
+	wsExTransparent  = 0x00000020
+	swShowNoActivate = 4
+	wmDestroy        = 0x0002
+	wmClose          = 0x0010
+	wmEraseBkgnd     = 0x0014
+	smCxScreen       = 0
+	smCyScreen       = 1
+)
+
+type wndClassEx struct {
+	size       uint32
+	style      uint32
+	wndProc    uintptr
+	clsExtra   int32
+	wndExtra   int32
+	instance   windows.Handle
+	icon       windows.Handle
+	cursor     windows.Handle
+	background windows.Handle
+	menuName   *uint16
+	className  *uint16
+	iconSm     windows.Handle
+}
+
+type rect struct{ left, top, right, bottom int32 }
+
+type msg struct {
+	hwnd    windows.HWND
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	pt      struct{ x, y int32 }
+}
+
+var (
+	mu         sync.Mutex
+	hwnd       windows.HWND
+	color      atomic.Uint32 // COLORREF (0x00BBGGRR)
+	classOnce  sync.Once
+	classErr   error
+	className  = windows.StringToUTF16Ptr("R1ControlOverlay")
+	wndProcPtr = windows.NewCallback(wndProc)
+)
+
+func colorref(c rgb) uint32 {
+	return uint32(c.b)<<16 | uint32(c.g)<<8 | uint32(c.r)
+}
+
+func wndProc(h windows.HWND, m uint32, wParam, lParam uintptr) uintptr {
+	switch m {
+	case wmEraseBkgnd:
+		var rc rect
+		procGetClientRect.Call(uintptr(h), uintptr(unsafe.Pointer(&rc)))
+		brush, _, _ := procCreateSolidBrush.Call(uintptr(color.Load()))
+		procFillRect.Call(wParam, uintptr(unsafe.Pointer(&rc)), brush)
+		procDeleteObject.Call(brush)
+		return 1
+	case wmDestroy:
+		procPostQuitMessage.Call(0)
+		return 0
+	}
+	ret, _, _ := procDefWindowProcW.Call(uintptr(h), uintptr(m), wParam, lParam)
+	return ret
+}
+
+func registerClass() error {
+	classOnce.Do(func() {
+		wc := wndClassEx{
+			wndProc:   wndProcPtr,
+			className: className,
+		}
+		wc.size = uint32(unsafe.Sizeof(wc))
+		if r, _, err := procRegisterClassExW.Call(uintptr(unsafe.Pointer(&wc))); r == 0 {
+			classErr = fmt.Errorf("register window class: %w", err)
+		}
+	})
+	return classErr
+}
+
+func show(opts Options) error {
+	hide()
+
+	color.Store(colorref(idleColor))
+	created := make(chan error, 1)
+
+	// Windows delivers messages to the thread that created the window, so
+	// the window and its message loop live on one locked OS thread.
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		if err := registerClass(); err != nil {
+			created <- err
+			return
+		}
+
+		w, _, _ := procGetSystemMetrics.Call(smCxScreen)
+		h, _, _ := procGetSystemMetrics.Call(smCyScreen)
+		x, y := origin(opts, int(w), int(h))
+
+		exStyle := uintptr(wsExTopmost | wsExToolWindow | wsExNoActivate | wsExTransparent)
+		hw, _, err := procCreateWindowExW.Call(
+			exStyle,
+			uintptr(unsafe.Pointer(className)),
+			0,
+			uintptr(wsPopup),
+			uintptr(x), uintptr(y), uintptr(opts.Size), uintptr(opts.Size),
+			0, 0, 0, 0,
+		)
+		if hw == 0 {
+			created <- fmt.Errorf("create overlay window: %w", err)
+			return
+		}
+
+		mu.Lock()
+		hwnd = windows.HWND(hw)
+		mu.Unlock()
+		procShowWindow.Call(hw, swShowNoActivate)
+		created <- nil
+
+		var m msg
+		for {
+			r, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+			if int32(r) <= 0 {
+				return
+			}
+			procTranslateMessage.Call(uintptr(unsafe.Pointer(&m)))
+			procDispatchMessageW.Call(uintptr(unsafe.Pointer(&m)))
+		}
+	}()
+
+	return <-created
+}
+
+func setColor(c rgb) {
+	mu.Lock()
+	defer mu.Unlock()
+	if hwnd == 0 {
+		return
+	}
+	color.Store(colorref(c))
+	procInvalidateRect.Call(uintptr(hwnd), 0, 1)
+}
+
+func hide() {
+	mu.Lock()
+	defer mu.Unlock()
+	if hwnd != 0 {
+		procPostMessageW.Call(uintptr(hwnd), wmClose, 0, 0)
+		hwnd = 0
+	}
+}
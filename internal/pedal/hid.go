@@ -0,0 +1,78 @@
+package pedal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/gousb"
+)
+
+// hidSource reads input reports from a vendor HID device's interrupt
+// endpoint over libusb. The kernel's HID driver is detached while the
+// device is open; on Windows the device needs the WinUSB driver.
+type hidSource struct {
+	ctx  *gousb.Context
+	dev  *gousb.Device
+	done func()
+	in   *gousb.InEndpoint
+	buf  []byte
+}
+
+func openHID(vid, pid uint16) (source, error) {
+	ctx := gousb.NewContext()
+	dev, err := ctx.OpenDeviceWithVIDPID(gousb.ID(vid), gousb.ID(pid))
+	if err != nil {
+		ctx.Close()
+		return nil, fmt.Errorf("open USB device: %w", err)
+	}
+	if dev == nil {
+		ctx.Close()
+		return nil, errors.New("device not connected")
+	}
+	dev.SetAutoDetach(true)
+
+	intf, done, err := dev.DefaultInterface()
+	if err != nil {
+		dev.Close()
+		ctx.Close()
+		return nil, fmt.Errorf("claim interface: %w", err)
+	}
+
+	for _, ep := range intf.Setting.Endpoints {
+		if ep.Direction != gousb.EndpointDirectionIn || ep.TransferType != gousb.TransferTypeInterrupt {
+			continue
+		}
+		in, err := intf.InEndpoint(ep.Number)
+		if err != nil {
+			break
+		}
+		return &hidSource{ctx: ctx, dev: dev, done: done, in: in, buf: make([]byte, ep.MaxPacketSize)}, nil
+	}
+	done()
+	dev.Close()
+	ctx.Close()
+	return nil, errors.New("no interrupt IN endpoint (not a HID device?)")
+}
+
+// read returns the first four bytes of the next report as a mask.
+func (s *hidSource) read(ctx context.Context) (uint32, error) {
+	n, err := s.in.ReadContext(ctx, s.buf)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, err
+	}
+	var mask uint32
+	for i := 0; i < n && i < 4; i++ {
+		mask |= uint32(s.buf[i]) << (8 * i)
+	}
+	return mask, nil
+}
+
+func (s *hidSource) close() {
+	s.done()
+	s.dev.Close()
+	s.ctx.Close()
+}
@@ -0,0 +1,166 @@
+// Package pedal reads foot pedals and button boxes that don't present as
+// keyboards: vendor HID devices opened over USB, and serial devices. Each
+// report is read as a bitmask of pressed buttons, numbered from 1 at the
+// lowest bit, and turned into press and release events.
+package pedal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryInterval is how often a missing or unplugged device is retried.
+const retryInterval = 3 * time.Second
+
+// Options selects the device to read.
+type Options struct {
+	Kind string // "hid" or "serial"
+	VID  uint16 // USB vendor ID
+	PID  uint16 // USB product ID
+	Port string // serial port; "" = find by VID/PID (Linux only)
+	Baud int    // serial only; 0 = 9600
+}
+
+func (o Options) String() string {
+	if o.Kind == "serial" && o.Port != "" {
+		return o.Port
+	}
+	return fmt.Sprintf("%04x:%04x", o.VID, o.PID)
+}
+
+// ParseID parses a USB vendor or product ID written in hex, with or
+// without a "0x" prefix.
+func ParseID(s string) (uint16, error) {
+	s = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "0x")
+	id, err := strconv.ParseUint(s, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid USB ID %q (want hex, e.g. 05f3)", s)
+	}
+	return uint16(id), nil
+}
+
+// source yields button masks from an open device.
+type source interface {
+	// read blocks until the next report, ctx is done or the device fails.
+	read(ctx context.Context) (uint32, error)
+	close()
+}
+
+// Reader watches one device and reports button presses and releases.
+type Reader struct {
+	opts      Options
+	handle    func(button int, down bool)
+	onProblem func(string)
+}
+
+// New creates a Reader that calls handle for every button press and
+// release. Call Run to start reading.
+func New(opts Options, handle func(button int, down bool)) *Reader {
+	return &Reader{opts: opts, handle: handle}
+}
+
+// SetProblemHandler sets a callback for user-visible problems, such as a
+// missing device. It is called with "" once the device is open.
+func (r *Reader) SetProblemHandler(fn func(string)) {
+	r.onProblem = fn
+}
+
+func (r *Reader) problem(p string) {
+	if r.onProblem != nil {
+		r.onProblem(p)
+	}
+}
+
+// Run opens the device and reads it until ctx is done, reopening it after
+// it is unplugged. Buttons still held when the device goes away are
+// released, so a pedal can't leave PTT on.
+func (r *Reader) Run(ctx context.Context) {
+	var lastErr string
+	for {
+		src, err := r.open()
+		if err != nil {
+			if err.Error() != lastErr {
+				log.Printf("[pedal] %s: %v", r.opts, err)
+				lastErr = err.Error()
+			}
+			r.problem("Foot pedal not available: " + err.Error())
+		} else {
+			log.Printf("[pedal] reading %s", r.opts)
+			lastErr = ""
+			r.problem("")
+			err = r.read(ctx, src)
+			src.close()
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[pedal] %s: %v", r.opts, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// open opens the configured device.
+func (r *Reader) open() (source, error) {
+	switch r.opts.Kind {
+	case "hid", "":
+		return openHID(r.opts.VID, r.opts.PID)
+	case "serial":
+		port := r.opts.Port
+		if port == "" {
+			p, err := findSerial(r.opts.VID, r.opts.PID)
+			if err != nil {
+				return nil, err
+			}
+			port = p
+		}
+		baud := r.opts.Baud
+		if baud == 0 {
+			baud = 9600
+		}
+		return openSerial(port, baud)
+	}
+	return nil, fmt.Errorf("unknown pedal kind %q (want hid or serial)", r.opts.Kind)
+}
+
+// read turns masks into events until the source fails or ctx is done.
+func (r *Reader) read(ctx context.Context, src source) error {
+	var held uint32
+	defer func() { r.emit(held, 0) }()
+	for {
+		mask, err := src.read(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		}
+		r.emit(held, mask)
+		held = mask
+	}
+}
+
+// emit reports the buttons whose state differs between prev and next,
+// releases first.
+func (r *Reader) emit(prev, next uint32) {
+	for i := 0; i < 32; i++ {
+		if bit := uint32(1) << i; prev&bit != 0 && next&bit == 0 {
+			r.handle(i+1, false)
+		}
+	}
+	for i := 0; i < 32; i++ {
+		if bit := uint32(1) << i; prev&bit == 0 && next&bit != 0 {
+			log.Printf("[pedal] button %d pressed", i+1)
+			r.handle(i+1, true)
+		}
+	}
+}
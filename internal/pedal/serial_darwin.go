@@ -0,0 +1,35 @@
+//go:build darwin
+
+package pedal
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioctlGetTermios = unix.TIOCGETA
+	ioctlSetTermios = unix.TIOCSETA
+)
+
+// macOS takes the rate itself rather than a B* code.
+var baudRates = map[int]uint64{
+	1200:   1200,
+	2400:   2400,
+	4800:   4800,
+	9600:   9600,
+	19200:  19200,
+	38400:  38400,
+	57600:  57600,
+	115200: 115200,
+}
+
+func setSpeed(t *unix.Termios, speed uint64) {
+	t.Ispeed = speed
+	t.Ospeed = speed
+}
+
+func findSerial(vid, pid uint16) (string, error) {
+	return "", errors.New("set pedal.port to the serial device, e.g. /dev/cu.usbserial-1410")
+}
@@ -0,0 +1,60 @@
+//go:build linux
+
+package pedal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+)
+
+var baudRates = map[int]uint32{
+	1200:   unix.B1200,
+	2400:   unix.B2400,
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+}
+
+func setSpeed(t *unix.Termios, speed uint32) {
+	t.Cflag &^= unix.CBAUD
+	t.Cflag |= speed
+	t.Ispeed = speed
+	t.Ospeed = speed
+}
+
+// findSerial returns the tty of the USB serial device with the given
+// IDs, found by walking up from each tty's sysfs node to its USB device.
+func findSerial(vid, pid uint16) (string, error) {
+	ttys, _ := filepath.Glob("/sys/class/tty/*/device")
+	want := fmt.Sprintf("%04x:%04x", vid, pid)
+	for _, link := range ttys {
+		dir, err := filepath.EvalSymlinks(link)
+		if err != nil {
+			continue
+		}
+		for i := 0; i < 4 && dir != "/"; i, dir = i+1, filepath.Dir(dir) {
+			v, errV := os.ReadFile(filepath.Join(dir, "idVendor"))
+			p, errP := os.ReadFile(filepath.Join(dir, "idProduct"))
+			if errV != nil || errP != nil {
+				continue
+			}
+			if strings.TrimSpace(string(v))+":"+strings.TrimSpace(string(p)) == want {
+				return "/dev/" + filepath.Base(filepath.Dir(link)), nil
+			}
+			break
+		}
+	}
+	return "", fmt.Errorf("no serial port for USB device %s", want)
+}
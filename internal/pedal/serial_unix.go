@@ -0,0 +1,90 @@
+//go:build linux || darwin
+
+package pedal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// pollInterval bounds how long a serial read waits before checking ctx.
+const pollInterval = 200 * time.Millisecond
+
+// serialSource reads a serial port in raw mode, one mask per byte.
+type serialSource struct {
+	f   *os.File
+	buf [1]byte
+}
+
+func openSerial(port string, baud int) (source, error) {
+	speed, ok := baudRates[baud]
+	if !ok {
+		return nil, fmt.Errorf("unsupported baud rate %d", baud)
+	}
+	f, err := os.OpenFile(port, os.O_RDONLY|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Configure through SyscallConn so the file stays in the poller and
+	// read deadlines work.
+	rc, err := f.SyscallConn()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	var terr error
+	err = rc.Control(func(fd uintptr) {
+		t, err := unix.IoctlGetTermios(int(fd), ioctlGetTermios)
+		if err != nil {
+			terr = err
+			return
+		}
+		// Raw 8N1, reads return as soon as a byte arrives
+		t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+		t.Oflag &^= unix.OPOST
+		t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+		t.Cflag &^= unix.CSIZE | unix.PARENB
+		t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL
+		t.Cc[unix.VMIN] = 1
+		t.Cc[unix.VTIME] = 0
+		setSpeed(t, speed)
+		terr = unix.IoctlSetTermios(int(fd), ioctlSetTermios, t)
+	})
+	if err == nil {
+		err = terr
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("configure %s: %w", port, err)
+	}
+	return &serialSource{f: f}, nil
+}
+
+func (s *serialSource) read(ctx context.Context) (uint32, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		s.f.SetReadDeadline(time.Now().Add(pollInterval))
+		n, err := s.f.Read(s.buf[:])
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if n == 1 {
+			return uint32(s.buf[0]), nil
+		}
+	}
+}
+
+func (s *serialSource) close() {
+	s.f.Close()
+}
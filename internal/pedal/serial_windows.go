@@ -0,0 +1,80 @@
+//go:build windows
+
+package pedal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// serialSource reads a COM port, one mask per byte. Reads time out every
+// 200ms so ctx is checked.
+type serialSource struct {
+	h   windows.Handle
+	buf [1]byte
+}
+
+func openSerial(port string, baud int) (source, error) {
+	name, err := windows.UTF16PtrFromString(`\\.\` + port)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.CreateFile(name, windows.GENERIC_READ, 0, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", port, err)
+	}
+
+	var dcb windows.DCB
+	dcb.DCBlength = uint32(unsafe.Sizeof(dcb))
+	if err := windows.GetCommState(h, &dcb); err != nil {
+		windows.CloseHandle(h)
+		return nil, fmt.Errorf("configure %s: %w", port, err)
+	}
+	dcb.BaudRate = uint32(baud)
+	dcb.ByteSize = 8
+	dcb.Parity = windows.NOPARITY
+	dcb.StopBits = windows.ONESTOPBIT
+	if err := windows.SetCommState(h, &dcb); err != nil {
+		windows.CloseHandle(h)
+		return nil, fmt.Errorf("configure %s: %w", port, err)
+	}
+	// MAXDWORD interval and multiplier: return as soon as a byte arrives,
+	// or after the constant timeout with nothing read
+	timeouts := windows.CommTimeouts{
+		ReadIntervalTimeout:        ^uint32(0),
+		ReadTotalTimeoutMultiplier: ^uint32(0),
+		ReadTotalTimeoutConstant:   200,
+	}
+	if err := windows.SetCommTimeouts(h, &timeouts); err != nil {
+		windows.CloseHandle(h)
+		return nil, fmt.Errorf("configure %s: %w", port, err)
+	}
+	return &serialSource{h: h}, nil
+}
+
+func (s *serialSource) read(ctx context.Context) (uint32, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		var n uint32
+		if err := windows.ReadFile(s.h, s.buf[:], &n, nil); err != nil {
+			return 0, err
+		}
+		if n == 1 {
+			return uint32(s.buf[0]), nil
+		}
+	}
+}
+
+func (s *serialSource) close() {
+	windows.CloseHandle(s.h)
+}
+
+func findSerial(vid, pid uint16) (string, error) {
+	return "", errors.New("set pedal.port to the COM port, e.g. COM3")
+}
@@ -0,0 +1,157 @@
+// Package pttfallback keeps a PTT hotkey press from silently doing
+// nothing when it can't reach the R1: as configured, it retries while the
+// hotkey is held, then posts a desktop notification or holds a key on
+// this computer instead, e.g. another app's push-to-talk.
+package pttfallback
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/hostnotify"
+	"github.com/HopIT-Hub/R1-Control/internal/hotkey"
+	"github.com/HopIT-Hub/R1-Control/internal/logging"
+)
+
+// retryInterval is how often a press that failed is tried again.
+const retryInterval = 500 * time.Millisecond
+
+// notifyCooldown spaces out notifications while the R1 stays out of
+// reach, so every press doesn't post one.
+const notifyCooldown = time.Minute
+
+// Handler runs PTT for a hotkey, falling back as configured when it
+// fails. Call Down and Up as the hotkey is pressed and released.
+type Handler struct {
+	cfg  config.PTTFallbackConfig
+	down func() error
+	up   func() error
+
+	// mu is held while PTT is sent, so a release waits for a retry in
+	// flight and then undoes it.
+	mu       sync.Mutex
+	press    uint64 // counts presses, so a retry ends with its press
+	held     bool   // the hotkey is down
+	reached  bool   // this press reached the R1
+	hostKey  bool   // the host key is down for this press
+	notified time.Time
+}
+
+// New returns a handler that presses and releases PTT with down and up,
+// e.g. the device manager's PTTDown and PTTUp, and falls back per cfg.
+func New(cfg config.PTTFallbackConfig, down, up func() error) *Handler {
+	return &Handler{cfg: cfg, down: down, up: up}
+}
+
+// Down handles a press of the hotkey.
+func (h *Handler) Down() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.press++
+	h.held, h.reached = true, false
+
+	err := h.down()
+	if err == nil {
+		h.reached = true
+		return
+	}
+	logging.Warnf("[pttfallback] PTT down: %v", err)
+	if h.cfg.RetrySeconds > 0 {
+		deadline := time.Now().Add(time.Duration(h.cfg.RetrySeconds) * time.Second)
+		go h.retry(h.press, deadline)
+		return
+	}
+	h.giveUpLocked(err)
+}
+
+// Up handles a release of the hotkey: it releases PTT, or the host key
+// held in its place. A retry still going stops.
+func (h *Handler) Up() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.held = false
+
+	if h.hostKey {
+		h.hostKey = false
+		if err := hotkey.PressHostKey(h.hostBinding(), false); err != nil {
+			logging.Warnf("[pttfallback] release host key: %v", err)
+		}
+		return
+	}
+	if !h.reached {
+		return // nothing reached the R1 to release
+	}
+	if err := h.up(); err != nil {
+		logging.Warnf("[pttfallback] PTT up: %v", err)
+	}
+}
+
+// Release ends a press still held, e.g. on shutdown, so the host key
+// isn't left down.
+func (h *Handler) Release() {
+	h.mu.Lock()
+	held := h.held
+	h.mu.Unlock()
+	if held {
+		h.Up()
+	}
+}
+
+// retry tries the press again until it reaches the R1, the hotkey is
+// released or deadline passes, and falls back if it never does.
+func (h *Handler) retry(press uint64, deadline time.Time) {
+	for {
+		time.Sleep(retryInterval)
+		h.mu.Lock()
+		if h.press != press || !h.held {
+			h.mu.Unlock()
+			return
+		}
+		err := h.down()
+		if err == nil {
+			h.reached = true
+			h.mu.Unlock()
+			log.Printf("[pttfallback] PTT reached the R1 on a retry")
+			return
+		}
+		if time.Now().After(deadline) {
+			logging.Warnf("[pttfallback] gave up retrying PTT after %ds: %v", h.cfg.RetrySeconds, err)
+			h.giveUpLocked(err)
+			h.mu.Unlock()
+			return
+		}
+		h.mu.Unlock()
+	}
+}
+
+// giveUpLocked holds the host key while the hotkey stays down and posts
+// a notification, as configured. Must be called with h.mu held.
+func (h *Handler) giveUpLocked(err error) {
+	body := fmt.Sprintf("Push-to-talk didn't reach the R1: %v.", err)
+	if h.cfg.HostKey != nil && h.held {
+		b := h.hostBinding()
+		if err := hotkey.PressHostKey(b, true); err != nil {
+			logging.Warnf("[pttfallback] press host key: %v", err)
+		} else {
+			h.hostKey = true
+			body += fmt.Sprintf(" Holding %s on this computer instead.", b)
+			log.Printf("[pttfallback] holding %s on this computer instead", b)
+		}
+	}
+	if h.cfg.Notify && time.Since(h.notified) >= notifyCooldown {
+		h.notified = time.Now()
+		go func() {
+			if err := hostnotify.Post("R1 Control", body); err != nil {
+				logging.Warnf("[pttfallback] notification: %v", err)
+			}
+		}()
+	}
+}
+
+// hostBinding returns the configured host key as a binding.
+func (h *Handler) hostBinding() hotkey.Binding {
+	return hotkey.Binding{Modifiers: h.cfg.HostKey.Modifiers, Key: h.cfg.HostKey.Key}
+}
@@ -0,0 +1,178 @@
+// Package safefile keeps the app's state files readable across crashes
+// and power loss. Write replaces a file atomically and durably, keeping
+// the version it replaces as a backup; Read falls back to that backup
+// when the file is damaged.
+//
+// Next to each file name.json there may be:
+//
+//	name.json.bak      the version before the last write
+//	name.json.sum      SHA-256 checksums of both, in sha256sum format
+//	name.json.damaged  a file Read couldn't use, kept for inspection
+//
+// Files like config.json are also edited by hand, so a checksum that
+// doesn't match the file isn't taken as damage on its own: the file is
+// used as long as it passes the caller's check. The backup is never
+// edited by hand, and is only used if it matches its checksum.
+//
+// Files are written readable by their owner only, since some, like
+// config.json, hold tokens and shared secrets.
+package safefile
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Suffixes of the files kept next to a file.
+const (
+	BackupSuffix  = ".bak"
+	sumSuffix     = ".sum"
+	damagedSuffix = ".damaged"
+)
+
+// Write replaces the file at path with data. The new contents are synced
+// to disk before they replace the old, so path holds either the old or
+// the new version after a crash, never a mix. The old version, unless
+// empty, becomes the backup.
+func Write(path string, data []byte) error {
+	bak := path + BackupSuffix
+	sums := readSums(path)
+	if cur, err := os.ReadFile(path); err == nil && len(bytes.TrimSpace(cur)) > 0 {
+		if err := writeAtomic(bak, cur); err != nil {
+			return fmt.Errorf("back up %s: %w", filepath.Base(path), err)
+		}
+		sums[filepath.Base(bak)] = checksum(cur)
+	}
+	if err := writeAtomic(path, data); err != nil {
+		return err
+	}
+	sums[filepath.Base(path)] = checksum(data)
+	// The data is safe already; a stale checksum only costs the backup
+	if err := writeSums(path, sums); err != nil {
+		log.Printf("[safefile] %v", err)
+	}
+	return nil
+}
+
+// Read returns the contents of the file at path. If they fail check, e.g.
+// because they don't parse, the file is moved aside and the backup
+// restored in its place, provided the backup passes check and matches its
+// checksum. Otherwise the error is check's. A missing file is reported as
+// by os.ReadFile.
+func Read(path string, check func([]byte) error) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	checkErr := check(data)
+	if checkErr == nil {
+		return data, nil
+	}
+
+	bak := path + BackupSuffix
+	prev, err := os.ReadFile(bak)
+	if err != nil {
+		return nil, checkErr
+	}
+	if want, ok := readSums(path)[filepath.Base(bak)]; !ok || want != checksum(prev) {
+		log.Printf("[safefile] %s is damaged, and its backup doesn't match its checksum", filepath.Base(path))
+		return nil, checkErr
+	}
+	if check(prev) != nil {
+		return nil, checkErr
+	}
+
+	damaged := path + damagedSuffix
+	if err := os.Rename(path, damaged); err != nil {
+		return nil, fmt.Errorf("%w (move aside: %v)", checkErr, err)
+	}
+	if err := writeAtomic(path, prev); err != nil {
+		return nil, fmt.Errorf("%w (restore backup: %v)", checkErr, err)
+	}
+	log.Printf("[safefile] %s was damaged (%v): restored the previous version, the damaged file is %s", filepath.Base(path), checkErr, damaged)
+	return prev, nil
+}
+
+// writeAtomic writes data to a temp file next to path, syncs it, renames
+// it over path and syncs the directory.
+func writeAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0o600)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename %s: %w", filepath.Base(path), err)
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir makes a rename in dir durable. Not every OS can sync a
+// directory (Windows can't), and the rename is atomic regardless, so
+// failures are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readSums returns the checksums recorded for path and its backup, by
+// file name. A missing or unreadable checksum file gives none.
+func readSums(path string) map[string]string {
+	sums := make(map[string]string)
+	data, err := os.ReadFile(path + sumSuffix)
+	if err != nil {
+		return sums
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		sum, name, ok := strings.Cut(sc.Text(), "  ")
+		if ok {
+			sums[name] = sum
+		}
+	}
+	return sums
+}
+
+// writeSums records the checksums for path and its backup.
+func writeSums(path string, sums map[string]string) error {
+	var b strings.Builder
+	for _, name := range []string{filepath.Base(path), filepath.Base(path) + BackupSuffix} {
+		if sum, ok := sums[name]; ok {
+			fmt.Fprintf(&b, "%s  %s\n", sum, name)
+		}
+	}
+	return writeAtomic(path+sumSuffix, []byte(b.String()))
+}
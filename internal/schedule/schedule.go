@@ -0,0 +1,184 @@
+// Package schedule runs an action at fixed local times of day, e.g.
+// waking the R1 every weekday at 07:00.
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Entry is one daily time, optionally limited to some weekdays.
+type Entry struct {
+	Hour, Minute int
+	Days         [7]bool // indexed by time.Weekday; all false = every day
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Parse parses a time of day "HH:MM" (24h) and day names ("mon", "tue",
+// …, or "weekdays"/"weekends"). No days means every day.
+func Parse(at string, days []string) (Entry, error) {
+	var e Entry
+	if _, err := fmt.Sscanf(at, "%d:%d", &e.Hour, &e.Minute); err != nil ||
+		e.Hour < 0 || e.Hour > 23 || e.Minute < 0 || e.Minute > 59 {
+		return e, fmt.Errorf("bad time %q (want HH:MM)", at)
+	}
+	for _, d := range days {
+		d = strings.ToLower(strings.TrimSpace(d))
+		switch d {
+		case "weekdays":
+			for wd := time.Monday; wd <= time.Friday; wd++ {
+				e.Days[wd] = true
+			}
+		case "weekends":
+			e.Days[time.Saturday], e.Days[time.Sunday] = true, true
+		default:
+			wd, ok := weekdays[d[:min(3, len(d))]]
+			if !ok {
+				return e, fmt.Errorf("bad day %q", d)
+			}
+			e.Days[wd] = true
+		}
+	}
+	return e, nil
+}
+
+// on reports whether e applies on wd.
+func (e Entry) on(wd time.Weekday) bool {
+	return e.Days == [7]bool{} || e.Days[wd]
+}
+
+// Next returns the first time after now that e is due.
+func (e Entry) Next(now time.Time) time.Time {
+	for i := 0; i < 8; i++ {
+		d := now.AddDate(0, 0, i)
+		t := time.Date(d.Year(), d.Month(), d.Day(), e.Hour, e.Minute, 0, 0, now.Location())
+		if t.After(now) && e.on(t.Weekday()) {
+			return t
+		}
+	}
+	return time.Time{} // unreachable: every entry applies on some day
+}
+
+// String returns e.g. "07:00 mon,tue" or "07:00 daily".
+func (e Entry) String() string {
+	var days []string
+	for wd, on := range e.Days {
+		if on {
+			days = append(days, strings.ToLower(time.Weekday(wd).String()[:3]))
+		}
+	}
+	if len(days) == 0 {
+		days = []string{"daily"}
+	}
+	return fmt.Sprintf("%02d:%02d %s", e.Hour, e.Minute, strings.Join(days, ","))
+}
+
+// Run calls fn whenever one of entries is due, until ctx is cancelled.
+// The next time is recomputed after each run and after the clock jumps
+// (sleep, DST), so a missed time is skipped rather than run late.
+func Run(ctx context.Context, entries []Entry, name string, fn func()) {
+	if len(entries) == 0 {
+		return
+	}
+	const maxWait = time.Minute // re-check periodically in case the clock jumped
+	for {
+		now := time.Now()
+		var next time.Time
+		for _, e := range entries {
+			if t := e.Next(now); next.IsZero() || t.Before(next) {
+				next = t
+			}
+		}
+
+		wait := min(time.Until(next), maxWait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		// Due if we reached it, but not if we overslept by a lot
+		if late := time.Since(next); late >= 0 && late < maxWait {
+			log.Printf("[schedule] %s (%s)", name, next.Format("15:04"))
+			fn()
+		}
+	}
+}
+
+// Window is a span of local time each day, optionally limited to some
+// weekdays. A window ending before it starts runs past midnight, e.g.
+// 22:00-06:00, and belongs to the day it starts on.
+type Window struct {
+	Start, End Entry // End.Days is unused
+}
+
+// ParseWindow parses a span "HH:MM-HH:MM" and day names as in Parse.
+// Equal start and end times mean the whole day.
+func ParseWindow(span string, days []string) (Window, error) {
+	from, to, ok := strings.Cut(span, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("bad time range %q (want HH:MM-HH:MM)", span)
+	}
+	start, err := Parse(strings.TrimSpace(from), days)
+	if err != nil {
+		return Window{}, err
+	}
+	end, err := Parse(strings.TrimSpace(to), nil)
+	if err != nil {
+		return Window{}, err
+	}
+	return Window{Start: start, End: end}, nil
+}
+
+// Contains reports whether t falls in w.
+func (w Window) Contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	start := w.Start.Hour*60 + w.Start.Minute
+	end := w.End.Hour*60 + w.End.Minute
+	wd := t.Weekday()
+	switch {
+	case start == end:
+		return w.Start.on(wd)
+	case start < end:
+		return w.Start.on(wd) && m >= start && m < end
+	default: // past midnight: the evening part, or the morning after
+		return (w.Start.on(wd) && m >= start) || (w.Start.on((wd+6)%7) && m < end)
+	}
+}
+
+// Span is the part of one weekday a window covers, in minutes from
+// midnight; End is 24*60 for a span running to midnight.
+type Span struct {
+	Day        time.Weekday
+	Start, End int
+}
+
+// Spans returns the parts of each weekday that w covers, for drawing it
+// on a week calendar. A window past midnight gives two spans, one on
+// each day.
+func (w Window) Spans() []Span {
+	start := w.Start.Hour*60 + w.Start.Minute
+	end := w.End.Hour*60 + w.End.Minute
+	var spans []Span
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if !w.Start.on(wd) {
+			continue
+		}
+		switch {
+		case start == end:
+			spans = append(spans, Span{wd, 0, 24 * 60})
+		case start < end:
+			spans = append(spans, Span{wd, start, end})
+		default:
+			spans = append(spans, Span{wd, start, 24 * 60}, Span{(wd + 1) % 7, 0, end})
+		}
+	}
+	return spans
+}
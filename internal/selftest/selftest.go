@@ -0,0 +1,143 @@
+// Package selftest runs the onboarding hardware self test: is the R1
+// found and usable, are the hotkeys registered, and is the settings
+// server reachable.
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/device"
+	"github.com/HopIT-Hub/R1-Control/internal/hotkey"
+)
+
+// Check is one line of the checklist.
+type Check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"` // why it failed, or a note
+}
+
+// Hotkey is a hotkey to check.
+type Hotkey struct {
+	Name    string // e.g. "PTT hotkey"
+	Manager *hotkey.Manager
+	Enabled bool // disabled hotkeys pass without being registered
+}
+
+// Target is what Run checks.
+type Target struct {
+	Device    *device.Manager
+	Hotkeys   []Hotkey
+	StatusURL string // settings server status endpoint; "" = not running
+}
+
+// serverTimeout bounds the server reachability check.
+const serverTimeout = 3 * time.Second
+
+// Run runs every check in order. Checks that depend on an earlier one
+// fail with "skipped" when it fails.
+func Run(ctx context.Context, t Target) []Check {
+	var checks []Check
+	add := func(name string, err error) {
+		c := Check{Name: name, OK: err == nil}
+		if err != nil {
+			c.Detail = err.Error()
+		}
+		checks = append(checks, c)
+	}
+	skipped := fmt.Errorf("skipped")
+
+	dev := t.Device.SelfTest()
+	switch {
+	case !dev.Found:
+		add("Device found", fmt.Errorf("no R1 connected"))
+		add("Descriptors registered", skipped)
+		add("Wake", skipped)
+		add("Corner touch", skipped)
+	case !dev.Registered:
+		add("Device found", nil)
+		add("Descriptors registered", fmt.Errorf("HID descriptors not registered — try Reconnect Now"))
+		add("Wake", skipped)
+		add("Corner touch", skipped)
+	default:
+		add("Device found", nil)
+		add("Descriptors registered", nil)
+		add("Wake", dev.Wake)
+		if dev.Wake != nil {
+			add("Corner touch", skipped)
+		} else {
+			add("Corner touch", dev.Touch)
+		}
+	}
+
+	for _, hk := range t.Hotkeys {
+		name := hk.Name + " registered"
+		switch {
+		case !hk.Enabled:
+			checks = append(checks, Check{Name: name, OK: true, Detail: "disabled"})
+		case hk.Manager.Err() != nil:
+			add(name, hk.Manager.Err())
+		case hk.Manager.Registered() == 0:
+			add(name, fmt.Errorf("not registered (paused?)"))
+		default:
+			add(name, nil)
+		}
+	}
+
+	add("Settings server reachable", checkServer(ctx, t.StatusURL))
+	return checks
+}
+
+// checkServer fetches url the way the browser would.
+func checkServer(ctx context.Context, url string) error {
+	if url == "" {
+		return fmt.Errorf("settings server not running")
+	}
+	ctx, cancel := context.WithTimeout(ctx, serverTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// Passed reports whether every check passed.
+func Passed(checks []Check) bool {
+	for _, c := range checks {
+		if !c.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// Summary returns a one-line result, e.g. "Self test passed" or "Self
+// test: 2 of 9 checks failed (Wake, …)".
+func Summary(checks []Check) string {
+	var failed []string
+	for _, c := range checks {
+		if !c.OK {
+			failed = append(failed, c.Name)
+		}
+	}
+	switch len(failed) {
+	case 0:
+		return "Self test passed"
+	case 1:
+		return "Self test failed: " + failed[0]
+	default:
+		return fmt.Sprintf("Self test: %d of %d checks failed (%s, …)", len(failed), len(checks), failed[0])
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// extensionPaths are the endpoints browser extensions may call: PTT and
+// macros to act on a browser keyboard shortcut, status and the macro
+// list to show in a popup.
+var extensionPaths = map[string]bool{
+	apiPrefix + "/ptt":    true,
+	apiPrefix + "/status": true,
+	apiPrefix + "/macros": true,
+}
+
+// isExtensionPath reports whether path is open to browser extensions.
+func isExtensionPath(path string) bool {
+	return extensionPaths[path] || strings.HasPrefix(path, apiPrefix+"/macro/")
+}
+
+// isExtensionOrigin reports whether origin is a browser extension's
+// rather than a web page's.
+func isExtensionOrigin(origin string) bool {
+	return strings.HasPrefix(origin, "chrome-extension://") || strings.HasPrefix(origin, "moz-extension://")
+}
+
+// withExtensionCORS lets the browser extensions listed in the config's
+// extension_origins call extensionPaths, for triggering PTT from a
+// browser shortcut where global hotkeys don't work, e.g. on Wayland. It
+// answers their CORS preflights and refuses every other extension, since
+// extensions with host permissions skip CORS and would otherwise reach
+// the whole API. Requests from web pages and outside a browser pass
+// through unchanged.
+func (s *Server) withExtensionCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if !isExtensionOrigin(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !slices.Contains(s.cfg.GetExtensionOrigins(), origin) {
+			http.Error(w, "forbidden: add this extension's origin to extension_origins in config.json", http.StatusForbidden)
+			return
+		}
+		if !isExtensionPath(r.URL.Path) {
+			http.Error(w, "forbidden: not available to browser extensions", http.StatusForbidden)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Add("Vary", "Origin")
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", "GET, POST")
+			h.Set("Access-Control-Allow-Headers", "Content-Type")
+			h.Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
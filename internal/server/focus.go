@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/HopIT-Hub/R1-Control/internal/action"
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/focus"
+)
+
+// SetFocus sets the timer behind /focus. Set before Start.
+func (s *Server) SetFocus(t *focus.Timer) {
+	s.focus = t
+}
+
+// focusResponse is the JSON response for /focus and its commands.
+type focusResponse struct {
+	focus.Status
+	Config *config.FocusConfig `json:"config,omitempty"` // for GET and POST /focus
+	Error  string              `json:"error,omitempty"`
+}
+
+// handleFocus returns (GET) the focus timer's status and settings, or
+// replaces (POST) the settings, which apply from the next start.
+func (s *Server) handleFocus(w http.ResponseWriter, r *http.Request) {
+	if s.focus == nil {
+		writeJSON(w, focusResponse{Error: "the focus timer is not available"})
+		return
+	}
+	switch r.Method {
+	case "GET":
+		fc := s.cfg.GetFocus()
+		writeJSON(w, focusResponse{Status: s.focus.Status(), Config: &fc})
+	case "POST":
+		var fc config.FocusConfig
+		if err := json.NewDecoder(r.Body).Decode(&fc); err != nil {
+			writeJSON(w, focusResponse{Error: "invalid JSON"})
+			return
+		}
+		if err := checkFocus(fc); err != nil {
+			writeJSON(w, focusResponse{Error: err.Error()})
+			return
+		}
+		if err := s.cfg.SetFocus(fc); err != nil {
+			log.Printf("[server] save focus timer: %v", err)
+			writeJSON(w, focusResponse{Error: "failed to persist setting"})
+			return
+		}
+		log.Printf("[server] focus timer updated (%dm work, %dm breaks)", fc.WorkMinutes, fc.BreakMinutes)
+		writeJSON(w, focusResponse{Status: s.focus.Status(), Config: &fc})
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+// checkFocus reports the first problem with fc, including unknown
+// actions and malformed payloads.
+func checkFocus(fc config.FocusConfig) error {
+	if ps := fc.Check(); len(ps) > 0 {
+		return errors.New(ps[0].String())
+	}
+	for _, phase := range []struct {
+		name  string
+		steps []config.FocusStep
+	}{{"on_work", fc.OnWork}, {"on_break", fc.OnBreak}, {"on_done", fc.OnDone}} {
+		for i, st := range phase.steps {
+			if st.Action == "" {
+				continue
+			}
+			if err := action.Validate(st.Action, st.Payload); err != nil {
+				return fmt.Errorf("%s[%d]: %v", phase.name, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// handleFocusCommand returns the handler for POST /focus/start, /stop or
+// /skip.
+func (s *Server) handleFocusCommand(cmd string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "method not allowed", 405)
+			return
+		}
+		if s.focus == nil {
+			writeJSON(w, focusResponse{Error: "the focus timer is not available"})
+			return
+		}
+		var err error
+		switch cmd {
+		case "start":
+			err = s.focus.Start(s.cfg.GetFocus())
+		case "stop":
+			s.focus.Stop()
+		case "skip":
+			err = s.focus.Skip()
+		}
+		if err != nil {
+			writeJSON(w, focusResponse{Status: s.focus.Status(), Error: err.Error()})
+			return
+		}
+		writeJSON(w, focusResponse{Status: s.focus.Status()})
+	}
+}
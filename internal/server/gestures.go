@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/HopIT-Hub/R1-Control/internal/gestures"
+)
+
+// gesturesResponse is the JSON response for GET /gestures.
+type gesturesResponse struct {
+	Presets []gestures.Preset `json:"presets"`        // for the configured firmware, sorted by name
+	User    []gestures.Preset `json:"user,omitempty"` // the presets in gestures.json
+	Error   string            `json:"error,omitempty"`
+}
+
+// gestureResponse is the JSON response for POST /gestures and
+// /gestures/<name>.
+type gestureResponse struct {
+	Preset  *gestures.Preset `json:"preset,omitempty"`
+	Deleted int              `json:"deleted,omitempty"` // presets removed by DELETE
+	Error   string           `json:"error,omitempty"`
+}
+
+// handleGestures lists (GET) the gesture presets available for the
+// configured firmware, each runnable as the "gesture" action, along with
+// the user's own, or adds (POST) a preset to gestures.json.
+func (s *Server) handleGestures(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		user, err := gestures.UserPresets()
+		resp := gesturesResponse{Presets: s.deviceMgr.Gestures(), User: user}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		writeJSON(w, resp)
+	case "POST":
+		var p gestures.Preset
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			writeJSON(w, gestureResponse{Error: "invalid JSON"})
+			return
+		}
+		p, err := gestures.AddUser(p)
+		if err != nil {
+			writeJSON(w, gestureResponse{Error: err.Error()})
+			return
+		}
+		s.reloadGestures()
+		log.Printf("[server] gesture %q added", p.Name)
+		writeJSON(w, gestureResponse{Preset: &p})
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+// handleGesture returns (GET) the preset named in the path, e.g.
+// /gestures/back, as it runs on the configured firmware, replaces (PUT)
+// the user preset with that name and the body's firmware list, or deletes
+// (DELETE) the user presets with that name — only the one for
+// ?firmware=0.8,0.9 if given.
+func (s *Server) handleGesture(w http.ResponseWriter, r *http.Request) {
+	name, ok := pathName(r, apiPrefix+"/gestures/")
+	if !ok {
+		http.Error(w, "not found", 404)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		for _, p := range s.deviceMgr.Gestures() {
+			if p.Name == name {
+				writeJSON(w, gestureResponse{Preset: &p})
+				return
+			}
+		}
+		writeJSON(w, gestureResponse{Error: "no such preset for this firmware"})
+	case "PUT":
+		var p gestures.Preset
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			writeJSON(w, gestureResponse{Error: "invalid JSON"})
+			return
+		}
+		if p.Name != "" && p.Name != name {
+			writeJSON(w, gestureResponse{Error: "the preset's name doesn't match the URL"})
+			return
+		}
+		p.Name = name
+		p, err := gestures.PutUser(p)
+		if err != nil {
+			writeJSON(w, gestureResponse{Error: err.Error()})
+			return
+		}
+		s.reloadGestures()
+		log.Printf("[server] gesture %q saved", name)
+		writeJSON(w, gestureResponse{Preset: &p})
+	case "DELETE":
+		var firmware []string
+		if v, ok := r.URL.Query()["firmware"]; ok {
+			firmware = []string{}
+			if v[0] != "" {
+				firmware = strings.Split(v[0], ",")
+			}
+		}
+		n, err := gestures.DeleteUser(name, firmware)
+		if err != nil {
+			writeJSON(w, gestureResponse{Error: err.Error()})
+			return
+		}
+		if n == 0 {
+			writeJSON(w, gestureResponse{Error: "no such preset in gestures.json; built-in presets can't be deleted"})
+			return
+		}
+		s.reloadGestures()
+		log.Printf("[server] gesture %q deleted (%d)", name, n)
+		writeJSON(w, gestureResponse{Deleted: n})
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+// reloadGestures hands the presets, with gestures.json as just changed,
+// to the device manager.
+func (s *Server) reloadGestures() {
+	lib, err := gestures.Load()
+	if err != nil {
+		log.Printf("[server] gesture presets: %v", err)
+	}
+	s.deviceMgr.SetGestures(lib, s.cfg.GetGesture().Firmware)
+}
+
+// pathName returns the unescaped name after prefix in r's path, e.g. a
+// macro name, which may contain slashes as %2F.
+func pathName(r *http.Request, prefix string) (string, bool) {
+	rest, ok := strings.CutPrefix(r.URL.EscapedPath(), prefix)
+	if !ok || rest == "" || strings.Contains(rest, "/") {
+		return "", false
+	}
+	name, err := url.PathUnescape(rest)
+	return name, err == nil
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+)
+
+// Confirmation limits: a challenge must be sent back within challengeTTL,
+// and at most maxChallenges can be outstanding at once.
+const (
+	challengeTTL  = 30 * time.Second
+	maxChallenges = 16
+)
+
+// challenge is an issued confirmation for one action and payload.
+type challenge struct {
+	action  string
+	payload string // compacted JSON, so the confirmed call must match
+	expires time.Time
+}
+
+// guards tracks confirmation challenges and cooldowns of the actions in
+// the config's action_guards.
+type guards struct {
+	mu         sync.Mutex
+	challenges map[string]challenge // by token
+	lastRun    map[string]time.Time // by action
+}
+
+// errConfirm is returned by checkGuard when the action needs confirming;
+// the caller replies with the challenge.
+type errConfirm struct {
+	challenge string
+}
+
+func (e *errConfirm) Error() string {
+	return "confirmation required: send the same request again with \"confirm\": \"" + e.challenge + "\""
+}
+
+// checkGuard applies name's guard to an API call with payload and the
+// challenge echoed in confirm, if any. It returns nil if the action may
+// run, an *errConfirm if it must be confirmed first, or an error while it
+// is cooling down. Actions without a guard always run. The cooldown starts
+// only once the caller reports the action ran, with ranGuarded.
+func (s *Server) checkGuard(name string, payload json.RawMessage, confirm string) error {
+	g, ok := s.cfg.GetActionGuard(name)
+	if !ok || (!g.Confirm && g.CooldownSeconds <= 0) {
+		return nil
+	}
+	var compact bytes.Buffer
+	if len(payload) > 0 && json.Compact(&compact, payload) != nil {
+		compact.Write(payload)
+	}
+
+	s.guards.mu.Lock()
+	defer s.guards.mu.Unlock()
+	now := time.Now()
+	for token, c := range s.guards.challenges {
+		if now.After(c.expires) {
+			delete(s.guards.challenges, token)
+		}
+	}
+
+	if err := s.coolingDownLocked(name, g, now); err != nil {
+		return err
+	}
+
+	if g.Confirm {
+		c, ok := s.guards.challenges[confirm]
+		if !ok || c.action != name || c.payload != compact.String() {
+			if len(s.guards.challenges) >= maxChallenges {
+				return fmt.Errorf("too many unconfirmed requests, try again in %s", challengeTTL)
+			}
+			b := make([]byte, 8)
+			if _, err := rand.Read(b); err != nil {
+				return err
+			}
+			token := hex.EncodeToString(b)
+			if s.guards.challenges == nil {
+				s.guards.challenges = make(map[string]challenge)
+			}
+			s.guards.challenges[token] = challenge{action: name, payload: compact.String(), expires: now.Add(challengeTTL)}
+			return &errConfirm{challenge: token}
+		}
+		delete(s.guards.challenges, confirm)
+	}
+	return nil
+}
+
+// coolingDownLocked returns an error if name ran less than its guard's
+// cooldown ago. s.guards.mu must be held.
+func (s *Server) coolingDownLocked(name string, g config.ActionGuard, now time.Time) error {
+	cooldown := time.Duration(g.CooldownSeconds) * time.Second
+	if wait := s.guards.lastRun[name].Add(cooldown).Sub(now); wait > 0 {
+		return fmt.Errorf("%s is cooling down; retry in %ds", name, int(math.Ceil(wait.Seconds())))
+	}
+	return nil
+}
+
+// ranGuarded starts name's cooldown, if it has one. Callers report only
+// actions that ran, so a failed one can be retried straight away.
+func (s *Server) ranGuarded(name string) {
+	g, ok := s.cfg.GetActionGuard(name)
+	if !ok || g.CooldownSeconds <= 0 {
+		return
+	}
+	s.guards.mu.Lock()
+	defer s.guards.mu.Unlock()
+	if s.guards.lastRun == nil {
+		s.guards.lastRun = make(map[string]time.Time)
+	}
+	s.guards.lastRun[name] = time.Now()
+}
+
+// checkSteps applies the guards of actions sent where no challenge can be
+// answered: quick actions, macros and the dedicated action endpoints. A
+// step that needs confirming is refused, as is one still cooling down.
+func (s *Server) checkSteps(names []string) error {
+	s.guards.mu.Lock()
+	defer s.guards.mu.Unlock()
+	now := time.Now()
+	for _, name := range names {
+		g, ok := s.cfg.GetActionGuard(name)
+		if !ok {
+			continue
+		}
+		if g.Confirm {
+			return fmt.Errorf("%s needs confirming; send it through /api/v1/action instead", name)
+		}
+		if err := s.coolingDownLocked(name, g, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runGuarded runs an action sent to a dedicated endpoint, e.g. /wake,
+// under name's guard: see checkSteps. Its cooldown starts if run succeeds.
+func (s *Server) runGuarded(name string, run func() error) error {
+	if err := s.checkSteps([]string{name}); err != nil {
+		return err
+	}
+	if err := run(); err != nil {
+		return err
+	}
+	s.ranGuarded(name)
+	return nil
+}
+
+// challengeOf returns the challenge carried by err, if it is an
+// *errConfirm.
+func challengeOf(err error) string {
+	if e, ok := err.(*errConfirm); ok {
+		return e.challenge
+	}
+	return ""
+}
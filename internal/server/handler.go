@@ -1,14 +1,35 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"log"
+	"maps"
 	"net/http"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/action"
 	"github.com/HopIT-Hub/R1-Control/internal/autostart"
+	"github.com/HopIT-Hub/R1-Control/internal/changelog"
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/device"
+	"github.com/HopIT-Hub/R1-Control/internal/events"
+	"github.com/HopIT-Hub/R1-Control/internal/hostpower"
 	"github.com/HopIT-Hub/R1-Control/internal/hotkey"
+	"github.com/HopIT-Hub/R1-Control/internal/logging"
+	"github.com/HopIT-Hub/R1-Control/internal/selftest"
+	"github.com/HopIT-Hub/R1-Control/internal/stats"
 	"github.com/HopIT-Hub/R1-Control/internal/web"
 )
 
@@ -18,9 +39,34 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	servePage(w, "index.html")
+}
+
+// handleSettingsPage serves the settings page at /settings too, the
+// address older headless setups used.
+func (s *Server) handleSettingsPage(w http.ResponseWriter, r *http.Request) {
+	servePage(w, "index.html")
+}
+
+// handlePromptPage serves the "ask rabbit" prompt page.
+func (s *Server) handlePromptPage(w http.ResponseWriter, r *http.Request) {
+	servePage(w, "prompt.html")
+}
+
+// handleRemotePage serves the phone remote page.
+func (s *Server) handleRemotePage(w http.ResponseWriter, r *http.Request) {
+	servePage(w, "remote.html")
+}
+
+// handlePairPage serves the page where a new remote client pairs.
+func (s *Server) handlePairPage(w http.ResponseWriter, r *http.Request) {
+	servePage(w, "pair.html")
+}
 
+// servePage writes an embedded HTML page.
+func servePage(w http.ResponseWriter, name string) {
 	staticFS, _ := fs.Sub(web.StaticFiles, "static")
-	f, err := staticFS.Open("index.html")
+	f, err := staticFS.Open(name)
 	if err != nil {
 		http.Error(w, "not found", 404)
 		return
@@ -33,13 +79,53 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 
 // statusResponse is the JSON response for GET /status.
 type statusResponse struct {
-	State             string `json:"state"`
-	Hotkey            string `json:"hotkey"`
-	SwipeHotkey       string `json:"swipe_hotkey"`
-	Version           string `json:"version"`
-	AutoStart         bool   `json:"auto_start"`
-	KeepAwake         bool   `json:"keep_awake"`
-	SleepAfterMinutes int    `json:"sleep_after_minutes"`
+	State                 string                  `json:"state"`
+	Hotkey                string                  `json:"hotkey"`
+	HotkeyEnabled         bool                    `json:"hotkey_enabled"`
+	SwipeHotkey           string                  `json:"swipe_hotkey"`
+	SwipeHotkeyEnabled    bool                    `json:"swipe_hotkey_enabled"`
+	Version               string                  `json:"version"`
+	AutoStart             bool                    `json:"auto_start"`
+	AutoStartPaused       bool                    `json:"auto_start_paused"`
+	AutoStartDelaySeconds int                     `json:"auto_start_delay_seconds"`
+	KeepAwake             bool                    `json:"keep_awake"`
+	SleepAfterMinutes     int                     `json:"sleep_after_minutes"`
+	KeepAwakeDim          bool                    `json:"keep_awake_dim"`
+	PTTAutoReleaseMinutes int                     `json:"ptt_auto_release_minutes"`
+	PTTMode               string                  `json:"ptt_mode"`
+	ToggleThresholdMs     int                     `json:"toggle_threshold_ms"`
+	SwipeMode             string                  `json:"swipe_mode"`
+	SwipeHoldMs           int                     `json:"swipe_hold_ms"`
+	SwipeResetSeconds     int                     `json:"swipe_reset_seconds"`
+	LinkWarning           string                  `json:"link_warning,omitempty"`       // dock/cable health warning
+	Problems              []string                `json:"problems,omitempty"`           // hotkey/device errors the user should fix
+	LastSeen              *config.LastSeen        `json:"last_seen,omitempty"`          // most recently connected R1; absent = never detected
+	PTT                   string                  `json:"ptt,omitempty"`                // "latched" or "held" while PTT is active
+	HostSleepBlocked      []string                `json:"host_sleep_blocked,omitempty"` // why the host is kept awake, e.g. "ptt", "macro"
+	Profile               string                  `json:"profile,omitempty"`            // profile last switched in
+	Hotkeys               map[string]hotkeyStatus `json:"hotkeys"`                      // keyed by "ptt", "swipe", "prompt", "confirm"
+	HotkeysDisabled       bool                    `json:"hotkeys_disabled,omitempty"`   // disable_hotkeys is set
+	RemoteURL             string                  `json:"remote_url,omitempty"`         // phone remote address; only sent to this machine
+	Pairing               []pairingStatus         `json:"pairing,omitempty"`            // PINs waiting to be entered; only sent to this machine
+}
+
+// hotkeyStatus reports whether a hotkey is actually registered with the
+// OS, so a UI can tell "hotkey broken" from "working but unused".
+type hotkeyStatus struct {
+	Enabled    bool   `json:"enabled"`         // enabled in the config
+	Registered bool   `json:"registered"`      // held by this app right now
+	Bindings   int    `json:"bindings"`        // registered combos, including extras
+	Error      string `json:"error,omitempty"` // why registration failed
+}
+
+// hotkeyState builds the status of one hotkey manager.
+func hotkeyState(mgr *hotkey.Manager, hk config.HotkeyConfig) hotkeyStatus {
+	st := hotkeyStatus{Enabled: hk.Enabled, Bindings: mgr.Registered()}
+	st.Registered = st.Bindings > 0
+	if err := mgr.Err(); err != nil {
+		st.Error = err.Error()
+	}
+	return st
 }
 
 // handleStatus returns the current device state and hotkey config.
@@ -51,25 +137,353 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 
 	hk := s.cfg.GetHotkey()
 	shk := s.cfg.GetSwipeHotkey()
+	launch := s.cfg.GetAutoStartLaunch()
+	pttMode, toggleMs := s.cfg.GetPTTMode()
+	swipeMode, swipeHoldMs, swipeReset := s.cfg.GetSwipeMode()
 
 	resp := statusResponse{
-		State:             s.deviceMgr.State().String(),
-		Hotkey:            hk.String(),
-		SwipeHotkey:       shk.String(),
-		Version:           s.version,
-		AutoStart:         s.cfg.GetAutoStart(),
-		KeepAwake:         s.cfg.GetKeepAwake(),
-		SleepAfterMinutes: s.cfg.GetSleepAfterMinutes(),
+		State:                 s.deviceMgr.State().String(),
+		Hotkey:                hk.String(),
+		HotkeyEnabled:         hk.Enabled,
+		SwipeHotkey:           shk.String(),
+		SwipeHotkeyEnabled:    shk.Enabled,
+		Version:               s.version,
+		AutoStart:             s.cfg.GetAutoStart(),
+		AutoStartPaused:       launch.HasArg(config.StartPausedArg),
+		AutoStartDelaySeconds: launch.DelaySeconds,
+		KeepAwake:             s.cfg.GetKeepAwake(),
+		SleepAfterMinutes:     s.cfg.GetSleepAfterMinutes(),
+		KeepAwakeDim:          s.cfg.GetKeepAwakeDim(),
+		PTTAutoReleaseMinutes: s.cfg.GetPTTAutoRelease(),
+		PTTMode:               pttMode,
+		ToggleThresholdMs:     toggleMs,
+		SwipeMode:             swipeMode,
+		SwipeHoldMs:           swipeHoldMs,
+		SwipeResetSeconds:     swipeReset,
+		LinkWarning:           s.deviceMgr.LinkWarning(),
+		Problems:              s.problems(),
+		PTT:                   s.deviceMgr.PTTMode(),
+		HostSleepBlocked:      hostpower.Reasons(),
+		Profile:               s.cfg.GetActiveProfile(),
+		Hotkeys: map[string]hotkeyStatus{
+			"ptt":   hotkeyState(s.hotkeyMgr, hk),
+			"swipe": hotkeyState(s.swipeHkMgr, shk),
+		},
+		HotkeysDisabled: s.cfg.GetDisableHotkeys(),
+	}
+	if s.promptHkMgr != nil {
+		resp.Hotkeys["prompt"] = hotkeyState(s.promptHkMgr, s.cfg.GetPromptHotkey())
+	}
+	if s.confirmHkMgr != nil {
+		resp.Hotkeys["confirm"] = hotkeyState(s.confirmHkMgr, s.cfg.GetConfirmHotkey())
+	}
+	if ls := s.cfg.GetLastSeen(); !ls.Time.IsZero() {
+		resp.LastSeen = &ls
+	}
+	if isLoopback(r) {
+		resp.RemoteURL = s.lanURL
+		resp.Pairing = s.pairs.list()
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// problems collects current hotkey and device errors, and other problems
+// published on the bus, for display.
+func (s *Server) problems() []string {
+	var out []string
+	if err := s.hotkeyMgr.Err(); err != nil {
+		out = append(out, "PTT "+err.Error())
+	}
+	if err := s.swipeHkMgr.Err(); err != nil {
+		out = append(out, "Swipe "+err.Error())
+	}
+	if p := s.deviceMgr.Problem(); p != "" {
+		out = append(out, p)
+	}
+	s.problemsMu.Lock()
+	defer s.problemsMu.Unlock()
+	for _, source := range slices.Sorted(maps.Keys(s.published)) {
+		out = append(out, s.published[source])
+	}
+	return out
+}
+
+// Subscribe shows problems published on bus, e.g. with the phone remote
+// or the pedal, in the status, for when there is no tray to show them.
+// Hotkey and device problems are read from their managers instead. It
+// also follows PTT going off, for endHold.
+func (s *Server) Subscribe(bus *events.Bus) {
+	s.bus = bus
+	bus.Subscribe(func(e events.Event) {
+		if state, ok := e.Value.(device.State); ok && state != device.PTTActive {
+			s.heldByStream.Store(false)
+		}
+	}, events.TypeState)
+	bus.Subscribe(func(e events.Event) {
+		switch e.Name {
+		case "device", "ptt_hotkey", "swipe_hotkey":
+			return
+		}
+		s.problemsMu.Lock()
+		defer s.problemsMu.Unlock()
+		if e.Detail == "" {
+			delete(s.published, e.Name)
+			return
+		}
+		if s.published == nil {
+			s.published = make(map[string]string)
+		}
+		s.published[e.Name] = e.Detail
+	}, events.TypeProblem)
+}
+
+// diagnosticsResponse is the JSON response for GET /diagnostics.
+type diagnosticsResponse struct {
+	State   string          `json:"state"`
+	Version string          `json:"version"`
+	OS      string          `json:"os"`
+	Link    device.LinkInfo `json:"link"`
+
+	// Runtime figures for spotting leaks: goroutines should stay flat
+	// over hours, and hotkey listeners should match the keyboard hotkeys
+	// registered.
+	Goroutines      int    `json:"goroutines"`
+	HeapBytes       uint64 `json:"heap_bytes"`
+	HotkeyListeners int    `json:"hotkey_listeners"`
+}
+
+// handleDiagnostics reports USB link details for troubleshooting docks
+// and cables, and goroutine and memory figures for spotting leaks.
+func (s *Server) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	writeJSON(w, diagnosticsResponse{
+		State:           s.deviceMgr.State().String(),
+		Version:         s.version,
+		OS:              runtime.GOOS + "/" + runtime.GOARCH,
+		Link:            s.deviceMgr.Link(),
+		Goroutines:      runtime.NumGoroutine(),
+		HeapBytes:       ms.HeapAlloc,
+		HotkeyListeners: hotkey.Listeners(),
+	})
+}
+
+// descriptorsResponse is the JSON response for GET /device/descriptors.
+type descriptorsResponse struct {
+	*aoa.Descriptors
+	Error string `json:"error,omitempty"`
+}
+
+// handleDescriptors dumps the R1's USB descriptors, for sharing exact
+// enumeration data in compatibility reports.
+func (s *Server) handleDescriptors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	desc, err := s.deviceMgr.Descriptors()
+	if err != nil {
+		writeJSON(w, descriptorsResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, descriptorsResponse{Descriptors: desc})
+}
+
+// configValidateResponse is the JSON response for POST /config/validate.
+type configValidateResponse struct {
+	Valid    bool             `json:"valid"`
+	Problems []config.Problem `json:"problems,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// maxConfigSize bounds the body of POST /config/validate.
+const maxConfigSize = 1 << 20
+
+// handleConfigValidate checks a config file's contents, given as the
+// request body, or the config file on disk if the body is empty. Nothing
+// is applied.
+func (s *Server) handleConfigValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxConfigSize))
+	if err != nil {
+		writeJSON(w, configValidateResponse{Error: "read body: " + err.Error()})
+		return
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		p, err := config.Path()
+		if err == nil {
+			data, err = os.ReadFile(p)
+		}
+		if err != nil {
+			writeJSON(w, configValidateResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	problems := config.Validate(data)
+	writeJSON(w, configValidateResponse{Valid: len(problems) == 0, Problems: problems})
+}
+
+// configBackupsResponse is the JSON response for GET /config/backups.
+type configBackupsResponse struct {
+	Backups []config.Backup `json:"backups"` // newest first
+	Error   string          `json:"error,omitempty"`
+}
+
+// handleConfigBackups lists the saved config versions.
+func (s *Server) handleConfigBackups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	backups, err := config.ListBackups()
+	if err != nil {
+		writeJSON(w, configBackupsResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, configBackupsResponse{Backups: backups})
+}
+
+// configRestoreRequest is the JSON body for POST /config/restore.
+type configRestoreRequest struct {
+	Name string `json:"name"` // a name from GET /config/backups
+}
+
+// configRestoreResponse is the JSON response for POST /config/restore.
+type configRestoreResponse struct {
+	Restored   string           `json:"restored,omitempty"`
+	Restarting bool             `json:"restarting,omitempty"`
+	Problems   []config.Problem `json:"problems,omitempty"` // why the backup was refused
+	Error      string           `json:"error,omitempty"`
+}
+
+// SetRestartHandler sets a callback that restarts the app, used to apply
+// a restored config.
+func (s *Server) SetRestartHandler(fn func()) {
+	s.onRestart = fn
+}
+
+// handleConfigRestore replaces the settings with a backup, then restarts
+// the app so they take effect.
+func (s *Server) handleConfigRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var req configRestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, configRestoreResponse{Error: "invalid JSON"})
+		return
+	}
+	if err := s.cfg.Restore(req.Name); err != nil {
+		resp := configRestoreResponse{Error: err.Error()}
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			resp.Error = "not restored, the backup has problems: " + err.Error()
+			resp.Problems = verr.Problems
+		}
+		writeJSON(w, resp)
+		return
+	}
+	log.Printf("[server] config restored from %s", req.Name)
+
+	writeJSON(w, configRestoreResponse{Restored: req.Name, Restarting: s.onRestart != nil})
+	if s.onRestart != nil {
+		// Let the response reach the browser before the server stops
+		time.AfterFunc(500*time.Millisecond, s.onRestart)
+	}
+}
+
+// changelogResponse is the JSON response for GET /changelog.
+type changelogResponse struct {
+	Version  string              `json:"version"`
+	Releases []changelog.Release `json:"releases"` // newest first
+}
+
+// handleChangelog returns the embedded release notes for the settings
+// UI's "What's new" section.
+func (s *Server) handleChangelog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	writeJSON(w, changelogResponse{
+		Version:  s.version,
+		Releases: changelog.Releases(),
+	})
+}
+
+// statsResponse is the JSON response for GET /stats.
+type statsResponse struct {
+	Days  []stats.Day `json:"days,omitempty"` // oldest first, today last
+	Total *stats.Day  `json:"total,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// handleStats returns per-day usage counters. ?days=N selects how many
+// days back to include (default 7, max 365).
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	if s.stats == nil {
+		writeJSON(w, statsResponse{Error: "usage statistics unavailable"})
+		return
+	}
+
+	n := 7
+	if v := r.URL.Query().Get("days"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 1 || d > 365 {
+			writeJSON(w, statsResponse{Error: "days must be between 1 and 365"})
+			return
+		}
+		n = d
+	}
+
+	days := s.stats.Days(n)
+	total := stats.Sum(days)
+	writeJSON(w, statsResponse{Days: days, Total: &total})
+}
+
 // hotkeyRequest is the JSON body for POST /hotkey.
 type hotkeyRequest struct {
 	Modifiers []string `json:"modifiers"`
 	JSCode    string   `json:"js_code"`
+	Key       string   `json:"key,omitempty"` // config key name; used instead of js_code (e.g. from host-side capture)
+}
+
+// keyName resolves the request's key to a config key name.
+func (req hotkeyRequest) keyName() (string, error) {
+	if hotkey.IsMouseButton(req.Key) {
+		if len(req.Modifiers) > 0 {
+			return "", errors.New("mouse buttons can't be combined with modifiers")
+		}
+		return strings.ToLower(req.Key), nil
+	}
+	if req.Key != "" {
+		if _, err := hotkey.ParseKey(req.Key); err != nil {
+			return "", err
+		}
+		return req.Key, nil
+	}
+	return hotkey.JSCodeToKeyName(req.JSCode)
 }
 
 // hotkeyResponse is the JSON response for POST /hotkey.
@@ -91,23 +505,32 @@ func (s *Server) handleHotkey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate modifiers
-	if len(req.Modifiers) == 0 {
+	// Validate modifiers (mouse buttons are bound on their own)
+	if len(req.Modifiers) == 0 && !hotkey.IsMouseButton(req.Key) {
 		writeJSON(w, hotkeyResponse{Error: "at least one modifier required"})
 		return
 	}
 
-	// Convert JS code to our key name
-	keyName, err := hotkey.JSCodeToKeyName(req.JSCode)
+	// Convert JS code (or captured key) to our key name
+	keyName, err := req.keyName()
 	if err != nil {
-		writeJSON(w, hotkeyResponse{Error: "unsupported key: " + req.JSCode})
+		writeJSON(w, hotkeyResponse{Error: "unsupported key: " + err.Error()})
 		return
 	}
 
-	// Try to register the new hotkey
-	if err := s.hotkeyMgr.Register(req.Modifiers, keyName); err != nil {
-		log.Printf("[server] hotkey register failed: %v", err)
-		writeJSON(w, hotkeyResponse{Error: "failed to register hotkey: " + err.Error()})
+	// Try to register the new hotkey (a disabled hotkey is only validated)
+	if cur := s.cfg.GetHotkey(); cur.Enabled && !s.cfg.GetDisableHotkeys() {
+		bindings := []hotkey.Binding{{Modifiers: req.Modifiers, Key: keyName}}
+		for _, b := range cur.Extra {
+			bindings = append(bindings, hotkey.Binding{Modifiers: b.Modifiers, Key: b.Key})
+		}
+		if err := s.hotkeyMgr.RegisterAll(bindings); err != nil {
+			log.Printf("[server] hotkey register failed: %v", err)
+			writeJSON(w, hotkeyResponse{Error: "failed to register hotkey: " + err.Error()})
+			return
+		}
+	} else if _, err := hotkey.ParseModifiers(req.Modifiers); err != nil {
+		writeJSON(w, hotkeyResponse{Error: err.Error()})
 		return
 	}
 
@@ -136,23 +559,32 @@ func (s *Server) handleSwipeHotkey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate modifiers
-	if len(req.Modifiers) == 0 {
+	// Validate modifiers (mouse buttons are bound on their own)
+	if len(req.Modifiers) == 0 && !hotkey.IsMouseButton(req.Key) {
 		writeJSON(w, hotkeyResponse{Error: "at least one modifier required"})
 		return
 	}
 
-	// Convert JS code to our key name
-	keyName, err := hotkey.JSCodeToKeyName(req.JSCode)
+	// Convert JS code (or captured key) to our key name
+	keyName, err := req.keyName()
 	if err != nil {
-		writeJSON(w, hotkeyResponse{Error: "unsupported key: " + req.JSCode})
+		writeJSON(w, hotkeyResponse{Error: "unsupported key: " + err.Error()})
 		return
 	}
 
-	// Try to register the new hotkey
-	if err := s.swipeHkMgr.Register(req.Modifiers, keyName); err != nil {
-		log.Printf("[server] swipe hotkey register failed: %v", err)
-		writeJSON(w, hotkeyResponse{Error: "failed to register hotkey: " + err.Error()})
+	// Try to register the new hotkey (a disabled hotkey is only validated)
+	if cur := s.cfg.GetSwipeHotkey(); cur.Enabled && !s.cfg.GetDisableHotkeys() {
+		bindings := []hotkey.Binding{{Modifiers: req.Modifiers, Key: keyName}}
+		for _, b := range cur.Extra {
+			bindings = append(bindings, hotkey.Binding{Modifiers: b.Modifiers, Key: b.Key})
+		}
+		if err := s.swipeHkMgr.RegisterAll(bindings); err != nil {
+			log.Printf("[server] swipe hotkey register failed: %v", err)
+			writeJSON(w, hotkeyResponse{Error: "failed to register hotkey: " + err.Error()})
+			return
+		}
+	} else if _, err := hotkey.ParseModifiers(req.Modifiers); err != nil {
+		writeJSON(w, hotkeyResponse{Error: err.Error()})
 		return
 	}
 
@@ -168,9 +600,86 @@ func (s *Server) handleSwipeHotkey(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, hotkeyResponse{Hotkey: shk.String()})
 }
 
+// captureTimeout bounds a host-side hotkey capture. It must stay below the
+// server's WriteTimeout so the response can still be sent.
+const captureTimeout = 8 * time.Second
+
+// captureResponse is the JSON response for POST /hotkey/capture/start.
+type captureResponse struct {
+	Modifiers []string `json:"modifiers,omitempty"`
+	Key       string   `json:"key,omitempty"`
+	Hotkey    string   `json:"hotkey,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// handleHotkeyCaptureStart captures the next key combination pressed
+// anywhere on the host and returns it. The request blocks until a key is
+// pressed, the capture is cancelled, or captureTimeout elapses.
+func (s *Server) handleHotkeyCaptureStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	if s.cfg.GetDisableHotkeys() {
+		writeJSON(w, captureResponse{Error: "hotkeys are turned off (disable_hotkeys in the config)"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), captureTimeout)
+	defer cancel()
+
+	s.captureMu.Lock()
+	s.captureCancel = cancel
+	s.captureMu.Unlock()
+
+	mods, key, err := hotkey.Capture(ctx)
+
+	s.captureMu.Lock()
+	s.captureCancel = nil
+	s.captureMu.Unlock()
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		writeJSON(w, captureResponse{Error: "no key pressed"})
+		return
+	case errors.Is(err, context.Canceled):
+		writeJSON(w, captureResponse{Error: "capture cancelled"})
+		return
+	case err != nil:
+		log.Printf("[server] hotkey capture: %v", err)
+		writeJSON(w, captureResponse{Error: err.Error()})
+		return
+	}
+	if len(mods) == 0 {
+		writeJSON(w, captureResponse{Error: "at least one modifier required"})
+		return
+	}
+
+	display := config.HotkeyConfig{Modifiers: mods, Key: key}.String()
+	writeJSON(w, captureResponse{Modifiers: mods, Key: key, Hotkey: display})
+}
+
+// handleHotkeyCaptureCancel aborts a running hotkey capture.
+func (s *Server) handleHotkeyCaptureCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	s.captureMu.Lock()
+	if s.captureCancel != nil {
+		s.captureCancel()
+	}
+	s.captureMu.Unlock()
+	writeJSON(w, captureResponse{})
+}
+
 // autoStartRequest is the JSON body for POST /autostart.
 type autoStartRequest struct {
-	Enabled bool `json:"enabled"`
+	Enabled     bool  `json:"enabled"`
+	StartPaused *bool `json:"start_paused,omitempty"`  // start with hotkeys paused; nil = unchanged
+	Delay       *int  `json:"delay_seconds,omitempty"` // login startup delay; nil = unchanged
 }
 
 // autoStartResponse is the JSON response for POST /autostart.
@@ -192,9 +701,30 @@ func (s *Server) handleAutoStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Enable or disable OS autostart
+	if req.StartPaused != nil {
+		if err := s.cfg.SetAutoStartPaused(*req.StartPaused); err != nil {
+			log.Printf("[server] save autostart args: %v", err)
+			writeJSON(w, autoStartResponse{Error: "failed to persist auto-start arguments"})
+			return
+		}
+	}
+
+	if req.Delay != nil {
+		if *req.Delay < 0 || *req.Delay > 300 {
+			writeJSON(w, autoStartResponse{Error: "delay_seconds must be between 0 and 300"})
+			return
+		}
+		if err := s.cfg.SetAutoStartDelay(*req.Delay); err != nil {
+			log.Printf("[server] save autostart delay: %v", err)
+			writeJSON(w, autoStartResponse{Error: "failed to persist auto-start delay"})
+			return
+		}
+	}
+
+	// Enable or disable OS autostart (re-enabling rewrites the entry's arguments)
 	if req.Enabled {
-		if err := autostart.Enable(); err != nil {
+		launch := s.cfg.GetAutoStartLaunch()
+		if err := autostart.Enable(autostart.Options{Program: launch.Program, Args: launch.CommandArgs()}); err != nil {
 			log.Printf("[server] enable autostart: %v", err)
 			writeJSON(w, autoStartResponse{Error: "failed to enable auto-start: " + err.Error()})
 			return
@@ -220,14 +750,16 @@ func (s *Server) handleAutoStart(w http.ResponseWriter, r *http.Request) {
 
 // keepAwakeRequest is the JSON body for POST /keepawake.
 type keepAwakeRequest struct {
-	Enabled           bool `json:"enabled"`
-	SleepAfterMinutes int  `json:"sleep_after_minutes"`
+	Enabled           bool  `json:"enabled"`
+	SleepAfterMinutes int   `json:"sleep_after_minutes"`
+	Dim               *bool `json:"dim,omitempty"` // theater mode; absent = unchanged
 }
 
 // keepAwakeResponse is the JSON response for POST /keepawake.
 type keepAwakeResponse struct {
 	KeepAwake         bool   `json:"keep_awake"`
 	SleepAfterMinutes int    `json:"sleep_after_minutes"`
+	Dim               bool   `json:"dim"`
 	Error             string `json:"error,omitempty"`
 }
 
@@ -258,16 +790,744 @@ func (s *Server) handleKeepAwake(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Dim != nil {
+		// The device manager reads it from the config at each ping
+		if err := s.cfg.SetKeepAwakeDim(*req.Dim); err != nil {
+			log.Printf("[server] save keep-awake config: %v", err)
+			writeJSON(w, keepAwakeResponse{Error: "failed to persist setting"})
+			return
+		}
+	}
+
 	// Apply to device manager
 	s.deviceMgr.SetKeepAwake(req.Enabled, req.SleepAfterMinutes)
 
-	log.Printf("[server] keep-awake: enabled=%v, sleep_after=%dm", req.Enabled, req.SleepAfterMinutes)
+	dim := s.cfg.GetKeepAwakeDim()
+	log.Printf("[server] keep-awake: enabled=%v, sleep_after=%dm, dim=%v", req.Enabled, req.SleepAfterMinutes, dim)
 	writeJSON(w, keepAwakeResponse{
 		KeepAwake:         req.Enabled,
 		SleepAfterMinutes: req.SleepAfterMinutes,
+		Dim:               dim,
 	})
 }
 
+// keepAwakeScheduleResponse is the JSON response for /keepawake/schedule.
+type keepAwakeScheduleResponse struct {
+	Periods  []config.KeepAwakePeriod `json:"periods"`
+	Calendar []calendarSpan           `json:"calendar"` // the periods laid out on a week
+	Active   bool                     `json:"active"`   // keep-awake applies right now
+	Error    string                   `json:"error,omitempty"`
+}
+
+// calendarSpan is the part of one day a keep-awake period covers.
+type calendarSpan struct {
+	Day               string `json:"day"`                 // "mon".."sun"
+	From              string `json:"from"`                // "HH:MM"
+	To                string `json:"to"`                  // "HH:MM"; "24:00" = until midnight
+	Period            int    `json:"period"`              // index in periods
+	SleepAfterMinutes int    `json:"sleep_after_minutes"` // idle limit; 0 = never sleep
+	Dim               bool   `json:"dim"`                 // theater mode
+}
+
+// handleKeepAwakeSchedule returns (GET) or replaces (POST) the periods in
+// which keep-awake runs; an empty list means always.
+func (s *Server) handleKeepAwakeSchedule(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		writeJSON(w, s.keepAwakeSchedule(s.cfg.GetKeepAwakeSchedule()))
+	case "POST":
+		var ps []config.KeepAwakePeriod
+		if err := json.NewDecoder(r.Body).Decode(&ps); err != nil {
+			writeJSON(w, keepAwakeScheduleResponse{Error: "invalid JSON"})
+			return
+		}
+		for i, p := range ps {
+			if _, err := p.Window(); err != nil {
+				writeJSON(w, keepAwakeScheduleResponse{Error: fmt.Sprintf("period %d: %v", i, err)})
+				return
+			}
+			if n := p.SleepAfterMinutes; n != nil && (*n < 0 || *n > 24*60) {
+				writeJSON(w, keepAwakeScheduleResponse{Error: fmt.Sprintf("period %d: sleep_after_minutes must be in range 0-1440", i)})
+				return
+			}
+		}
+		if err := s.cfg.SetKeepAwakeSchedule(ps); err != nil {
+			log.Printf("[server] save keep-awake schedule: %v", err)
+			writeJSON(w, keepAwakeScheduleResponse{Error: "failed to persist setting"})
+			return
+		}
+		log.Printf("[server] keep-awake schedule updated (%d periods)", len(ps))
+		writeJSON(w, s.keepAwakeSchedule(ps))
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+// keepAwakeSchedule builds the /keepawake/schedule response for ps.
+func (s *Server) keepAwakeSchedule(ps []config.KeepAwakePeriod) keepAwakeScheduleResponse {
+	resp := keepAwakeScheduleResponse{Periods: ps, Calendar: []calendarSpan{}}
+	if resp.Periods == nil {
+		resp.Periods = []config.KeepAwakePeriod{}
+	}
+	for i, p := range ps {
+		win, err := p.Window()
+		if err != nil {
+			continue
+		}
+		sleepAfter := s.cfg.GetSleepAfterMinutes()
+		if p.SleepAfterMinutes != nil {
+			sleepAfter = *p.SleepAfterMinutes
+		}
+		dim := s.cfg.GetKeepAwakeDim()
+		if p.Dim != nil {
+			dim = *p.Dim
+		}
+		for _, sp := range win.Spans() {
+			resp.Calendar = append(resp.Calendar, calendarSpan{
+				Day:               strings.ToLower(sp.Day.String()[:3]),
+				From:              fmt.Sprintf("%02d:%02d", sp.Start/60, sp.Start%60),
+				To:                fmt.Sprintf("%02d:%02d", sp.End/60, sp.End%60),
+				Period:            i,
+				SleepAfterMinutes: sleepAfter,
+				Dim:               dim,
+			})
+		}
+	}
+	on, _ := s.cfg.KeepAwakeAt(time.Now())
+	resp.Active = on && s.cfg.GetKeepAwake()
+	return resp
+}
+
+// pttAutoReleaseRequest is the JSON body for POST /ptt-auto-release.
+type pttAutoReleaseRequest struct {
+	Minutes int `json:"minutes"`
+}
+
+// pttAutoReleaseResponse is the JSON response for POST /ptt-auto-release.
+type pttAutoReleaseResponse struct {
+	Minutes int    `json:"minutes"`
+	Error   string `json:"error,omitempty"`
+}
+
+// handlePTTAutoRelease updates how long a latched PTT may stay on.
+func (s *Server) handlePTTAutoRelease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var req pttAutoReleaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, pttAutoReleaseResponse{Error: "invalid JSON"})
+		return
+	}
+
+	validValues := map[int]bool{0: true, 1: true, 2: true, 5: true, 10: true, 30: true}
+	if !validValues[req.Minutes] {
+		writeJSON(w, pttAutoReleaseResponse{Error: "invalid minutes value"})
+		return
+	}
+
+	if err := s.cfg.SetPTTAutoRelease(req.Minutes); err != nil {
+		log.Printf("[server] save ptt auto-release config: %v", err)
+		writeJSON(w, pttAutoReleaseResponse{Error: "failed to persist setting"})
+		return
+	}
+
+	s.deviceMgr.SetMaxLatch(time.Duration(req.Minutes) * time.Minute)
+
+	log.Printf("[server] ptt auto-release: %dm", req.Minutes)
+	writeJSON(w, pttAutoReleaseResponse{Minutes: req.Minutes})
+}
+
+// pttModeRequest is the JSON body for POST /ptt-mode.
+type pttModeRequest struct {
+	Mode              string `json:"mode"`                          // "auto", "hold" or "toggle"
+	ToggleThresholdMs *int   `json:"toggle_threshold_ms,omitempty"` // omitted = unchanged
+}
+
+// pttModeResponse is the JSON response for POST /ptt-mode.
+type pttModeResponse struct {
+	Mode              string `json:"mode,omitempty"`
+	ToggleThresholdMs int    `json:"toggle_threshold_ms,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+// handlePTTMode updates how the PTT hotkey's presses map to PTT.
+func (s *Server) handlePTTMode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var req pttModeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, pttModeResponse{Error: "invalid JSON"})
+		return
+	}
+	mode, err := device.ParsePressMode(req.Mode)
+	if err != nil || req.Mode == "" {
+		writeJSON(w, pttModeResponse{Error: "mode must be one of: auto, hold, toggle"})
+		return
+	}
+	_, thresholdMs := s.cfg.GetPTTMode()
+	if req.ToggleThresholdMs != nil {
+		thresholdMs = *req.ToggleThresholdMs
+		if thresholdMs < config.MinToggleThresholdMs || thresholdMs > config.MaxToggleThresholdMs {
+			writeJSON(w, pttModeResponse{Error: fmt.Sprintf("toggle_threshold_ms must be in range %d-%d", config.MinToggleThresholdMs, config.MaxToggleThresholdMs)})
+			return
+		}
+	}
+
+	if err := s.cfg.SetPTTMode(req.Mode, thresholdMs); err != nil {
+		log.Printf("[server] save ptt mode config: %v", err)
+		writeJSON(w, pttModeResponse{Error: "failed to persist setting"})
+		return
+	}
+
+	s.deviceMgr.SetPressMode(mode, time.Duration(thresholdMs)*time.Millisecond)
+
+	log.Printf("[server] ptt mode: %s (toggle threshold %dms)", req.Mode, thresholdMs)
+	writeJSON(w, pttModeResponse{Mode: req.Mode, ToggleThresholdMs: thresholdMs})
+}
+
+// swipeModeRequest is the JSON body for POST /swipe-mode. Omitted
+// fields are left unchanged.
+type swipeModeRequest struct {
+	Mode         string `json:"mode"` // "alternate" or "hold"
+	HoldMs       *int   `json:"hold_ms,omitempty"`
+	ResetSeconds *int   `json:"reset_seconds,omitempty"` // 0 = never
+}
+
+// swipeModeResponse is the JSON response for POST /swipe-mode.
+type swipeModeResponse struct {
+	Mode         string `json:"mode,omitempty"`
+	HoldMs       int    `json:"hold_ms,omitempty"`
+	ResetSeconds int    `json:"reset_seconds"`
+	Error        string `json:"error,omitempty"`
+}
+
+// handleSwipeMode updates how the swipe hotkey's presses pick a
+// direction.
+func (s *Server) handleSwipeMode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var req swipeModeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, swipeModeResponse{Error: "invalid JSON"})
+		return
+	}
+	mode, err := device.ParseSwipeKeyMode(req.Mode)
+	if err != nil || req.Mode == "" {
+		writeJSON(w, swipeModeResponse{Error: "mode must be one of: alternate, hold"})
+		return
+	}
+	_, holdMs, resetSeconds := s.cfg.GetSwipeMode()
+	if req.HoldMs != nil {
+		holdMs = *req.HoldMs
+		if holdMs < config.MinSwipeHoldMs || holdMs > config.MaxSwipeHoldMs {
+			writeJSON(w, swipeModeResponse{Error: fmt.Sprintf("hold_ms must be in range %d-%d", config.MinSwipeHoldMs, config.MaxSwipeHoldMs)})
+			return
+		}
+	}
+	if req.ResetSeconds != nil {
+		resetSeconds = *req.ResetSeconds
+		if resetSeconds < 0 || resetSeconds > config.MaxSwipeResetSeconds {
+			writeJSON(w, swipeModeResponse{Error: fmt.Sprintf("reset_seconds must be in range 0-%d", config.MaxSwipeResetSeconds)})
+			return
+		}
+	}
+
+	if err := s.cfg.SetSwipeMode(req.Mode, holdMs, resetSeconds); err != nil {
+		log.Printf("[server] save swipe mode config: %v", err)
+		writeJSON(w, swipeModeResponse{Error: "failed to persist setting"})
+		return
+	}
+
+	s.deviceMgr.SetSwipeKeyMode(mode, time.Duration(holdMs)*time.Millisecond, time.Duration(resetSeconds)*time.Second)
+
+	log.Printf("[server] swipe mode: %s (hold %dms, reset after %ds)", req.Mode, holdMs, resetSeconds)
+	writeJSON(w, swipeModeResponse{Mode: req.Mode, HoldMs: holdMs, ResetSeconds: resetSeconds})
+}
+
+// logLevelRequest is the JSON body for POST /loglevel.
+type logLevelRequest struct {
+	Level string `json:"level"` // "debug", "info", "warn" or "error"
+}
+
+// logLevelResponse is the JSON response for /loglevel.
+type logLevelResponse struct {
+	Level string `json:"level,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleLogLevel reports (GET) or changes (POST) the log level until the
+// app restarts; log_level in the config sets it at startup.
+func (s *Server) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		writeJSON(w, logLevelResponse{Level: logging.GetLevel().String()})
+		return
+	case "POST":
+	default:
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, logLevelResponse{Error: "invalid JSON"})
+		return
+	}
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil || req.Level == "" {
+		writeJSON(w, logLevelResponse{Error: "level must be one of: debug, info, warn, error"})
+		return
+	}
+
+	logging.SetLevel(level)
+	logging.Warnf("[server] log level: %s", level)
+	writeJSON(w, logLevelResponse{Level: level.String()})
+}
+
+// actionResponse is the JSON response for device action endpoints.
+type actionResponse struct {
+	State     string `json:"state,omitempty"`
+	Challenge string `json:"challenge,omitempty"` // send back as "confirm" to run a guarded action
+	Error     string `json:"error,omitempty"`
+}
+
+// pttRequest is the JSON body for POST /ptt.
+type pttRequest struct {
+	Action string `json:"action"` // "on", "off" or "toggle"
+}
+
+// handlePTT latches, releases or toggles PTT.
+func (s *Server) handlePTT(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var req pttRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, actionResponse{Error: "invalid JSON"})
+		return
+	}
+
+	var run func() error
+	switch req.Action {
+	case "on":
+		run = func() error { return s.deviceMgr.SetPTT(true) }
+	case "off":
+		run = func() error { return s.deviceMgr.SetPTT(false) }
+	case "toggle":
+		run = s.deviceMgr.TogglePTT
+	default:
+		writeJSON(w, actionResponse{Error: "action must be one of: on, off, toggle"})
+		return
+	}
+	if err := s.runGuarded("ptt_"+req.Action, run); err != nil {
+		writeJSON(w, actionResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, actionResponse{State: s.deviceMgr.State().String()})
+}
+
+// holdHeartbeat is how often a /ptt/hold stream writes a line. A failed
+// write catches clients that vanished without closing the connection.
+const holdHeartbeat = 2 * time.Second
+
+// handlePTTHold turns PTT on for as long as the request stays open and
+// releases it when the client disconnects, so a client that crashes
+// can't leave PTT on. The response streams a {"state": ...} line per
+// heartbeat and ends if PTT is released elsewhere (hotkey, auto-release).
+func (s *Server) handlePTTHold(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.beginHold(); err != nil {
+		writeJSON(w, actionResponse{Error: err.Error()})
+		return
+	}
+	defer s.endHold()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-store")
+	enc := json.NewEncoder(w)
+	ticker := time.NewTicker(holdHeartbeat)
+	defer ticker.Stop()
+	for {
+		state := s.deviceMgr.State()
+		if err := enc.Encode(actionResponse{State: state.String()}); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		if state != device.PTTActive {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// beginHold presses PTT for a new /ptt/hold stream, as a held key rather
+// than a latch. PTT that is already on is left as it is.
+func (s *Server) beginHold() error {
+	s.holdMu.Lock()
+	defer s.holdMu.Unlock()
+	started, err := s.deviceMgr.HoldPTT()
+	if err != nil {
+		return err
+	}
+	if started {
+		s.heldByStream.Store(true)
+	}
+	s.holds++
+	return nil
+}
+
+// endHold releases PTT once the last open /ptt/hold stream has ended, if
+// the streams turned it on. PTT that was already on, that went off and
+// was turned on again some other way, or that was latched while a stream
+// was open, e.g. with the hotkey, stays on.
+func (s *Server) endHold() {
+	s.holdMu.Lock()
+	defer s.holdMu.Unlock()
+	s.holds--
+	if s.holds > 0 || !s.heldByStream.Swap(false) {
+		return
+	}
+	if err := s.deviceMgr.ReleaseHeldPTT(); err != nil {
+		log.Printf("[server] release held PTT: %v", err)
+	}
+}
+
+// handleSwipe performs the next alternating swipe, or one in the
+// direction given by an optional {"direction": "left"|"right"} body.
+func (s *Server) handleSwipe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1024))
+	if err != nil {
+		writeJSON(w, actionResponse{Error: "failed to read request"})
+		return
+	}
+	if err := action.Validate("swipe", body); err != nil {
+		writeJSON(w, actionResponse{Error: err.Error()})
+		return
+	}
+	if err := s.runGuarded("swipe", func() error { return action.Run(s.deviceMgr, "swipe", body) }); err != nil {
+		writeJSON(w, actionResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, actionResponse{State: s.deviceMgr.State().String()})
+}
+
+// handleWake turns the R1's screen on. GET is accepted too, for
+// smart-home routines that can only open a URL.
+func (s *Server) handleWake(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" && r.Method != "GET" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	// A GET can be set off by any page or link, so it needs the wake
+	// token. A POST from another site's page is refused by withSameOrigin
+	// before it gets here.
+	if r.Method == "GET" && !s.cfg.IsWakeToken(r.URL.Query().Get("token")) {
+		http.Error(w, "forbidden: GET needs ?token= with the wake link's token; use POST otherwise", http.StatusForbidden)
+		return
+	}
+	if err := s.runGuarded("wake", s.deviceMgr.Wake); err != nil {
+		writeJSON(w, actionResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, actionResponse{State: s.deviceMgr.State().String()})
+}
+
+// displayRequest is the JSON body for POST /experimental/display.
+type displayRequest struct {
+	Control string `json:"control"`           // "brightness_up" or "brightness_down"
+	Confirm string `json:"confirm,omitempty"` // the challenge, if the control is guarded
+}
+
+// displayResponse is the JSON response for /experimental/display. Product
+// and Serial identify the unit, for reporting results per firmware.
+type displayResponse struct {
+	State     string `json:"state,omitempty"`
+	Product   string `json:"product,omitempty"`
+	Serial    string `json:"serial,omitempty"`
+	Note      string `json:"note,omitempty"`
+	Challenge string `json:"challenge,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// handleDisplay sends an experimental display control usage.
+func (s *Server) handleDisplay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var req displayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, displayResponse{Error: "invalid JSON"})
+		return
+	}
+	if err := s.checkGuard(req.Control, nil, req.Confirm); err != nil {
+		writeJSON(w, displayResponse{Error: err.Error(), Challenge: challengeOf(err)})
+		return
+	}
+	if err := s.deviceMgr.DisplayKey(req.Control); err != nil {
+		writeJSON(w, displayResponse{Error: err.Error()})
+		return
+	}
+	s.ranGuarded(req.Control)
+	info := s.deviceMgr.Info()
+	writeJSON(w, displayResponse{
+		State:   s.deviceMgr.State().String(),
+		Product: info.Product,
+		Serial:  info.Serial,
+		Note:    "sent; whether the screen changed depends on the firmware — please report what you saw",
+	})
+}
+
+// SetSelfTestHandler sets a callback that receives the results of each
+// self test, wherever it was started.
+func (s *Server) SetSelfTestHandler(fn func(checks []selftest.Check)) {
+	s.selfTestMu.Lock()
+	defer s.selfTestMu.Unlock()
+	s.onSelfTest = fn
+}
+
+// SelfTest runs the onboarding self test against the connected R1, the
+// hotkeys and this server.
+func (s *Server) SelfTest(ctx context.Context) []selftest.Check {
+	s.selfTestMu.Lock()
+	defer s.selfTestMu.Unlock()
+
+	statusURL := ""
+	if u := s.URL(); u != "" {
+		statusURL = u + apiPrefix + "/status"
+	}
+	checks := selftest.Run(ctx, selftest.Target{
+		Device: s.deviceMgr,
+		Hotkeys: []selftest.Hotkey{
+			{Name: "PTT hotkey", Manager: s.hotkeyMgr, Enabled: s.cfg.GetHotkey().Enabled && !s.cfg.GetDisableHotkeys()},
+			{Name: "Swipe hotkey", Manager: s.swipeHkMgr, Enabled: s.cfg.GetSwipeHotkey().Enabled && !s.cfg.GetDisableHotkeys()},
+		},
+		StatusURL: statusURL,
+	})
+	log.Printf("[server] %s", selftest.Summary(checks))
+	if s.onSelfTest != nil {
+		s.onSelfTest(checks)
+	}
+	return checks
+}
+
+// selfTestResponse is the JSON response for POST /selftest.
+type selfTestResponse struct {
+	Passed bool             `json:"passed"`
+	Checks []selftest.Check `json:"checks"`
+}
+
+// handleSelfTest runs the self test and returns the checklist.
+func (s *Server) handleSelfTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	checks := s.SelfTest(r.Context())
+	writeJSON(w, selfTestResponse{Passed: selftest.Passed(checks), Checks: checks})
+}
+
+// tapRequest is the JSON body for POST /tap.
+type tapRequest struct {
+	X uint16 `json:"x"` // 0-32767
+	Y uint16 `json:"y"` // 0-32767
+}
+
+// handleTap sends a single touch tap at the given coordinates.
+func (s *Server) handleTap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var req tapRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, actionResponse{Error: "invalid JSON"})
+		return
+	}
+	if req.X > 32767 || req.Y > 32767 {
+		writeJSON(w, actionResponse{Error: "x and y must be in range 0-32767"})
+		return
+	}
+
+	if err := s.runGuarded("tap", func() error { return s.deviceMgr.Tap(req.X, req.Y) }); err != nil {
+		writeJSON(w, actionResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, actionResponse{State: s.deviceMgr.State().String()})
+}
+
+// actionRequest is the JSON body for POST /action.
+type actionRequest struct {
+	Action  string          `json:"action"`            // e.g. "ptt_toggle", "swipe", "tap"
+	Payload json.RawMessage `json:"payload,omitempty"` // action-specific, e.g. {"x":100,"y":200}
+	Confirm string          `json:"confirm,omitempty"` // a guarded action's challenge, sent back
+}
+
+// handleAction runs any named action, as used by quick actions and the
+// command-line client.
+func (s *Server) handleAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var req actionRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+		writeJSON(w, actionResponse{Error: "invalid JSON"})
+		return
+	}
+	if err := action.Validate(req.Action, req.Payload); err != nil {
+		writeJSON(w, actionResponse{Error: err.Error()})
+		return
+	}
+	if err := s.checkGuard(req.Action, req.Payload, req.Confirm); err != nil {
+		writeJSON(w, actionResponse{Error: err.Error(), Challenge: challengeOf(err)})
+		return
+	}
+	if err := action.Run(s.deviceMgr, req.Action, req.Payload); err != nil {
+		writeJSON(w, actionResponse{Error: err.Error()})
+		return
+	}
+	s.ranGuarded(req.Action)
+	writeJSON(w, actionResponse{State: s.deviceMgr.State().String()})
+}
+
+// quickActionsResponse is the JSON response for /quickactions.
+type quickActionsResponse struct {
+	QuickActions []config.QuickAction `json:"quick_actions"`
+	Actions      []string             `json:"actions"` // supported action names
+	Error        string               `json:"error,omitempty"`
+}
+
+// handlePrompt types a prompt on the R1 (POST {text, submit}).
+func (s *Server) handlePrompt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 4096))
+	if err != nil {
+		writeJSON(w, actionResponse{Error: "failed to read request"})
+		return
+	}
+	if err := action.Validate("type_text", body); err != nil {
+		writeJSON(w, actionResponse{Error: err.Error()})
+		return
+	}
+	if err := s.runGuarded("type_text", func() error { return action.Run(s.deviceMgr, "type_text", body) }); err != nil {
+		writeJSON(w, actionResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, actionResponse{State: s.deviceMgr.State().String()})
+}
+
+// handleQuickActions returns (GET) or replaces (POST) the quick-action buttons.
+func (s *Server) handleQuickActions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		writeJSON(w, quickActionsResponse{QuickActions: s.cfg.GetQuickActions(), Actions: action.Names()})
+	case "POST":
+		var qa []config.QuickAction
+		if err := json.NewDecoder(r.Body).Decode(&qa); err != nil {
+			writeJSON(w, quickActionsResponse{Error: "invalid JSON"})
+			return
+		}
+		for i, a := range qa {
+			if a.Label == "" {
+				writeJSON(w, quickActionsResponse{Error: fmt.Sprintf("quick action %d: label required", i)})
+				return
+			}
+			if err := action.Validate(a.Action, a.Payload); err != nil {
+				writeJSON(w, quickActionsResponse{Error: fmt.Sprintf("quick action %d: %v", i, err)})
+				return
+			}
+		}
+		if err := s.cfg.SetQuickActions(qa); err != nil {
+			log.Printf("[server] save quick actions: %v", err)
+			writeJSON(w, quickActionsResponse{Error: "failed to persist quick actions"})
+			return
+		}
+		log.Printf("[server] quick actions updated (%d)", len(qa))
+		writeJSON(w, quickActionsResponse{QuickActions: qa, Actions: action.Names()})
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+// quickActionRunRequest is the JSON body for POST /quickactions/run.
+type quickActionRunRequest struct {
+	Index int `json:"index"`
+}
+
+// handleQuickActionRun runs the quick action at the given index.
+func (s *Server) handleQuickActionRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var req quickActionRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, actionResponse{Error: "invalid JSON"})
+		return
+	}
+
+	qa := s.cfg.GetQuickActions()
+	if req.Index < 0 || req.Index >= len(qa) {
+		writeJSON(w, actionResponse{Error: "no such quick action"})
+		return
+	}
+
+	a := qa[req.Index]
+	if err := s.checkSteps([]string{a.Action}); err != nil {
+		writeJSON(w, actionResponse{Error: err.Error()})
+		return
+	}
+	if err := action.Run(s.deviceMgr, a.Action, a.Payload); err != nil {
+		writeJSON(w, actionResponse{Error: err.Error()})
+		return
+	}
+	s.ranGuarded(a.Action)
+	writeJSON(w, actionResponse{State: s.deviceMgr.State().String()})
+}
+
 func writeJSON(w http.ResponseWriter, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(v)
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/web"
+)
+
+// tokenCookie carries a paired client's token, so the remote page can
+// call the API after pairing.
+const tokenCookie = "r1_token"
+
+// StartLAN serves the phone remote on port on all interfaces: its page,
+// pairing, and the API endpoints remotes and paired scripts use. The
+// settings page and the API that changes settings stay on localhost.
+// Requests must carry the token of a paired client; unpaired browsers
+// are sent to the pairing page. Call after Start. Returns the remote
+// page URL.
+func (s *Server) StartLAN(port int) (string, error) {
+	if s.mux == nil {
+		return "", fmt.Errorf("settings server not started")
+	}
+	mux, err := s.lanMux()
+	if err != nil {
+		return "", err
+	}
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return "", fmt.Errorf("listen: %w", err)
+	}
+
+	s.lanServer = &http.Server{
+		Handler:      s.withRecovery(withLogging(withSameOrigin(s.requireClient(mux)))),
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := s.lanServer.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("[server] LAN error: %v", err)
+		}
+	}()
+
+	s.lanURL = fmt.Sprintf("http://%s:%d/remote", lanAddress(), port)
+	log.Printf("[server] phone remote at %s", s.lanURL)
+	return s.lanURL, nil
+}
+
+// lanStatic are the static files the LAN server serves, those of the
+// remote and pairing pages.
+var lanStatic = map[string]bool{
+	"style.css": true,
+	"remote.js": true,
+	"pair.js":   true,
+}
+
+// lanMux routes the LAN server. It shares the settings server's rate
+// limits, so a remote can't double them.
+func (s *Server) lanMux() (*http.ServeMux, error) {
+	staticFS, err := fs.Sub(web.StaticFiles, "static")
+	if err != nil {
+		return nil, fmt.Errorf("static fs: %w", err)
+	}
+	files := http.StripPrefix("/static/", http.FileServer(http.FS(staticFS)))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/static/", func(w http.ResponseWriter, r *http.Request) {
+		if !lanStatic[strings.TrimPrefix(r.URL.Path, "/static/")] {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, "/remote", http.StatusSeeOther)
+	})
+	mux.HandleFunc("/remote", s.handleRemotePage)
+	mux.HandleFunc("/pair", s.handlePairPage)
+
+	handleAPI(mux, "/status", s.handleStatus)
+	mux.HandleFunc(apiPrefix+"/stats", s.handleStats)
+	mux.HandleFunc(apiPrefix+"/diagnostics", s.handleDiagnostics)
+	mux.HandleFunc(apiPrefix+"/pair/start", rateLimitedByIP(s.pairLimit, s.handlePairStart))
+	mux.HandleFunc(apiPrefix+"/pair/confirm", rateLimitedByIP(s.pairLimit, s.handlePairConfirm))
+
+	mux.HandleFunc(apiPrefix+"/ptt", rateLimited(s.actionLimit, s.handlePTT))
+	mux.HandleFunc(apiPrefix+"/ptt/hold", rateLimited(s.actionLimit, s.handlePTTHold))
+	mux.HandleFunc(apiPrefix+"/swipe", rateLimited(s.actionLimit, s.handleSwipe))
+	mux.HandleFunc(apiPrefix+"/tap", rateLimited(s.actionLimit, s.handleTap))
+	mux.HandleFunc(apiPrefix+"/wake", rateLimited(s.actionLimit, s.handleWake))
+	mux.HandleFunc(apiPrefix+"/action", rateLimited(s.actionLimit, s.handleAction))
+	mux.HandleFunc(apiPrefix+"/quickactions", s.handleQuickActions)
+	mux.HandleFunc(apiPrefix+"/quickactions/run", rateLimited(s.actionLimit, s.handleQuickActionRun))
+	mux.HandleFunc(apiPrefix+"/ws", s.handleWS(s.actionLimit))
+	return mux, nil
+}
+
+// pairingPaths are reachable without a token, so a new client can pair.
+var pairingPaths = map[string]bool{
+	"/pair":                     true,
+	apiPrefix + "/pair/start":   true,
+	apiPrefix + "/pair/confirm": true,
+	"/static/style.css":         true,
+	"/static/pair.js":           true,
+}
+
+// readPaths are the endpoints read-only clients may GET.
+var readPaths = map[string]bool{
+	apiPrefix + "/status":       true,
+	"/status":                   true, // deprecated alias
+	apiPrefix + "/stats":        true,
+	apiPrefix + "/diagnostics":  true,
+	apiPrefix + "/quickactions": true, // the remote's buttons; changing them stays on localhost
+}
+
+// controlPaths are what control clients may call besides readPaths: the
+// remote page and the device actions. Anything else is refused, even if
+// it is routed on the LAN.
+var controlPaths = map[string]bool{
+	"/":                             true,
+	"/remote":                       true,
+	"/static/remote.js":             true,
+	apiPrefix + "/ptt":              true,
+	apiPrefix + "/ptt/hold":         true,
+	apiPrefix + "/swipe":            true,
+	apiPrefix + "/tap":              true,
+	apiPrefix + "/wake":             true,
+	apiPrefix + "/action":           true,
+	apiPrefix + "/quickactions/run": true,
+	apiPrefix + "/ws":               true,
+}
+
+// queryTokenPaths take the wake token as ?token=, for tools that can only
+// call a plain URL. A client's own token is never taken from the URL,
+// where it would end up in bookmarks, history and proxy logs.
+var queryTokenPaths = map[string]bool{
+	apiPrefix + "/wake": true,
+}
+
+// requireClient rejects requests without a paired client's token, given
+// as a bearer token or in tokenCookie, or for queryTokenPaths the wake
+// token in the URL. Pages redirect to pairing instead. Read-only clients
+// are limited to readPaths, control clients to those and controlPaths.
+func (s *Server) requireClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pairingPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if c, err := r.Cookie(tokenCookie); err == nil && token == "" {
+			token = c.Value
+		}
+		if token == "" && queryTokenPaths[r.URL.Path] && s.cfg.IsWakeToken(r.URL.Query().Get("token")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if cl, ok := s.cfg.LANClientByToken(token); ok {
+			switch {
+			case r.Method == "GET" && readPaths[r.URL.Path]:
+			case !cl.CanControl():
+				http.Error(w, "forbidden: this client is read-only", http.StatusForbidden)
+				return
+			case !controlPaths[r.URL.Path]:
+				http.Error(w, "forbidden: not available to remote clients", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Method == "GET" && !strings.HasPrefix(r.URL.Path, "/api/") {
+			http.Redirect(w, r, "/pair", http.StatusSeeOther)
+			return
+		}
+		http.Error(w, "unauthorized: pair this client first", http.StatusUnauthorized)
+	})
+}
+
+// lanAddress returns this machine's first private IPv4 address, or
+// "localhost" if there is none.
+func lanAddress() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "localhost"
+	}
+	for _, a := range addrs {
+		ipn, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ip := ipn.IP.To4(); ip != nil && ip.IsPrivate() {
+			return ip.String()
+		}
+	}
+	return "localhost"
+}
+
+// isLoopback reports whether the request came from this machine.
+func isLoopback(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,308 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/macro"
+)
+
+// macros holds the macro recorder and player; nil until SetMacros.
+type macros struct {
+	recorder *macro.Recorder
+	player   *macro.Player
+}
+
+// SetMacros sets the recorder behind /macros/record and the player
+// behind /macros/run. Set before Start.
+func (s *Server) SetMacros(rec *macro.Recorder, player *macro.Player) {
+	s.macros = macros{recorder: rec, player: player}
+}
+
+// macrosResponse is the JSON response for /macros.
+type macrosResponse struct {
+	Macros  []config.Macro `json:"macros"`
+	Running string         `json:"running,omitempty"` // macro being played
+	Error   string         `json:"error,omitempty"`
+}
+
+// macroResponse is the JSON response for POST /macros with one macro and
+// for /macros/<name>.
+type macroResponse struct {
+	Macro *config.Macro `json:"macro,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+// maxMacrosSize bounds the body of a request setting macros.
+const maxMacrosSize = 1 << 20
+
+// handleMacros returns (GET) the macros, or (POST) replaces them with a
+// list or adds a single macro.
+func (s *Server) handleMacros(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		var running string
+		if s.macros.player != nil {
+			running = s.macros.player.Running()
+		}
+		writeJSON(w, macrosResponse{Macros: s.cfg.GetMacros(), Running: running})
+	case "POST":
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxMacrosSize))
+		if err != nil {
+			writeJSON(w, macrosResponse{Error: "failed to read request"})
+			return
+		}
+		if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+			s.addMacro(w, body)
+			return
+		}
+		var ms []config.Macro
+		if err := json.Unmarshal(body, &ms); err != nil {
+			writeJSON(w, macrosResponse{Error: "invalid JSON"})
+			return
+		}
+		names := make(map[string]bool, len(ms))
+		for i, m := range ms {
+			if err := macro.Validate(m); err != nil {
+				writeJSON(w, macrosResponse{Error: fmt.Sprintf("macro %d: %v", i, err)})
+				return
+			}
+			if names[m.Name] {
+				writeJSON(w, macrosResponse{Error: fmt.Sprintf("macro %d: name %q is used twice", i, m.Name)})
+				return
+			}
+			names[m.Name] = true
+		}
+		if err := s.cfg.SetMacros(ms); err != nil {
+			log.Printf("[server] save macros: %v", err)
+			writeJSON(w, macrosResponse{Error: "failed to persist macros"})
+			return
+		}
+		log.Printf("[server] macros updated (%d)", len(ms))
+		writeJSON(w, macrosResponse{Macros: ms})
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+// addMacro adds the macro in body, for POST /macros.
+func (s *Server) addMacro(w http.ResponseWriter, body []byte) {
+	var m config.Macro
+	if err := json.Unmarshal(body, &m); err != nil {
+		writeJSON(w, macroResponse{Error: "invalid JSON"})
+		return
+	}
+	if err := macro.Validate(m); err != nil {
+		writeJSON(w, macroResponse{Error: err.Error()})
+		return
+	}
+	if err := s.cfg.AddMacro(m); err != nil {
+		writeJSON(w, macroResponse{Error: err.Error()})
+		return
+	}
+	log.Printf("[server] macro %q added", m.Name)
+	writeJSON(w, macroResponse{Macro: &m})
+}
+
+// handleMacro returns (GET), adds or replaces (PUT) or deletes (DELETE)
+// the macro named in the path, e.g. /macros/tap-grid. Macros named "run"
+// or "record" are only reachable through /macros.
+func (s *Server) handleMacro(w http.ResponseWriter, r *http.Request) {
+	name, ok := pathName(r, apiPrefix+"/macros/")
+	if !ok {
+		http.Error(w, "not found", 404)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		m, ok := macro.Find(s.cfg.GetMacros(), name)
+		if !ok {
+			writeJSON(w, macroResponse{Error: "no such macro"})
+			return
+		}
+		writeJSON(w, macroResponse{Macro: &m})
+	case "PUT":
+		var m config.Macro
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxMacrosSize)).Decode(&m); err != nil {
+			writeJSON(w, macroResponse{Error: "invalid JSON"})
+			return
+		}
+		if m.Name != "" && m.Name != name {
+			writeJSON(w, macroResponse{Error: "the macro's name doesn't match the URL"})
+			return
+		}
+		m.Name = name
+		if err := macro.Validate(m); err != nil {
+			writeJSON(w, macroResponse{Error: err.Error()})
+			return
+		}
+		if err := s.cfg.PutMacro(m); err != nil {
+			log.Printf("[server] save macros: %v", err)
+			writeJSON(w, macroResponse{Error: "failed to persist macros"})
+			return
+		}
+		log.Printf("[server] macro %q saved", name)
+		writeJSON(w, macroResponse{Macro: &m})
+	case "DELETE":
+		ok, err := s.cfg.DeleteMacro(name)
+		if !ok {
+			writeJSON(w, macroResponse{Error: "no such macro"})
+			return
+		}
+		if err != nil {
+			log.Printf("[server] save macros: %v", err)
+			writeJSON(w, macroResponse{Error: "failed to persist macros"})
+			return
+		}
+		log.Printf("[server] macro %q deleted", name)
+		writeJSON(w, macroResponse{})
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+// macroRunRequest is the JSON body for POST /macros/run.
+type macroRunRequest struct {
+	Name   string       `json:"name"`
+	Params macro.Params `json:"params,omitempty"` // values for the macro's parameters
+}
+
+// handleMacroRun starts playing a macro in the background, since its
+// delays may outlast the request. One macro plays at a time.
+func (s *Server) handleMacroRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var req macroRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, macrosResponse{Error: "invalid JSON"})
+		return
+	}
+	s.startMacro(w, req.Name, req.Params)
+}
+
+// handleMacroTrigger runs the macro named in the path, e.g. POST
+// /macro/tap-grid, with the body's fields as its parameters.
+func (s *Server) handleMacroTrigger(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, apiPrefix+"/macro/")
+	var params macro.Params
+	body, err := io.ReadAll(io.LimitReader(r.Body, 4096))
+	if err != nil {
+		writeJSON(w, macrosResponse{Error: "failed to read request"})
+		return
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &params); err != nil {
+			writeJSON(w, macrosResponse{Error: "parameters must be a JSON object"})
+			return
+		}
+	}
+	s.startMacro(w, name, params)
+}
+
+// startMacro starts the named macro and reports the outcome. The guards
+// of its steps are checked up front; each step's cooldown starts once it
+// has run.
+func (s *Server) startMacro(w http.ResponseWriter, name string, params macro.Params) {
+	if s.macros.player == nil {
+		writeJSON(w, macrosResponse{Error: "macros are not available"})
+		return
+	}
+	m, ok := macro.Find(s.cfg.GetMacros(), name)
+	if !ok {
+		writeJSON(w, macrosResponse{Error: "no such macro"})
+		return
+	}
+	var steps []string
+	for _, st := range m.Steps {
+		steps = append(steps, st.Action)
+	}
+	if err := s.checkSteps(steps); err != nil {
+		writeJSON(w, macrosResponse{Error: err.Error()})
+		return
+	}
+	if err := s.macros.player.Start(m, params, s.ranGuarded); err != nil {
+		writeJSON(w, macrosResponse{Error: err.Error(), Running: s.macros.player.Running()})
+		return
+	}
+	writeJSON(w, macrosResponse{Running: m.Name})
+}
+
+// macroRecordRequest is the JSON body for POST /macros/record.
+type macroRecordRequest struct {
+	Name    string `json:"name"`
+	Seconds int    `json:"seconds,omitempty"` // 0 = 10
+}
+
+// macroRecordResponse is the JSON response for /macros/record and
+// /macros/record/stop.
+type macroRecordResponse struct {
+	macro.Status
+	Macro *config.Macro `json:"macro,omitempty"` // the recorded macro, after stopping
+	Error string        `json:"error,omitempty"`
+}
+
+// handleMacroRecord reports (GET) or starts (POST) recording a macro from
+// the actions and PTT presses that follow, whatever triggers them.
+func (s *Server) handleMacroRecord(w http.ResponseWriter, r *http.Request) {
+	rec := s.macros.recorder
+	if rec == nil {
+		writeJSON(w, macroRecordResponse{Error: "macro recording is not available"})
+		return
+	}
+	switch r.Method {
+	case "GET":
+		writeJSON(w, macroRecordResponse{Status: rec.Status()})
+	case "POST":
+		var req macroRecordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, macroRecordResponse{Error: "invalid JSON"})
+			return
+		}
+		if err := rec.Start(req.Name, time.Duration(req.Seconds)*time.Second); err != nil {
+			writeJSON(w, macroRecordResponse{Status: rec.Status(), Error: err.Error()})
+			return
+		}
+		writeJSON(w, macroRecordResponse{Status: rec.Status()})
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+// handleMacroRecordStop ends the recording early and saves it.
+func (s *Server) handleMacroRecordStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	rec := s.macros.recorder
+	if rec == nil {
+		writeJSON(w, macroRecordResponse{Error: "macro recording is not available"})
+		return
+	}
+	m, err := rec.Stop()
+	if err != nil {
+		// Show what was recorded with why it wasn't saved
+		resp := macroRecordResponse{Error: err.Error()}
+		if len(m.Steps) > 0 {
+			resp.Macro = &m
+		}
+		writeJSON(w, resp)
+		return
+	}
+	writeJSON(w, macroRecordResponse{Macro: &m})
+}
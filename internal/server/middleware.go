@@ -0,0 +1,227 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/events"
+)
+
+// Action rate limit: a short burst is allowed, then requests are refilled
+// at a steady rate. Keeps a runaway script from flooding the USB pipeline.
+const (
+	actionBurst      = 10
+	actionRatePerSec = 5
+)
+
+// Pairing rate limit, per client address: slow enough that guessing a
+// six-digit PIN before it expires is hopeless, while one host sending bad
+// PINs can't lock everyone else out of pairing.
+const (
+	pairBurst      = 5
+	pairRatePerSec = 0.5
+)
+
+// statusRecorder captures the response status code for request logging.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, for
+// flushing and deadlines on streaming responses.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// withLogging logs one key=value line per request after it completes.
+// Successful GETs are skipped so the settings page's status polling
+// doesn't flood the log, as are the WebUSB tab's per-transfer results.
+func withLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if (r.Method == "GET" || r.URL.Path == apiPrefix+"/webusb/done") && rec.status < 400 {
+			return
+		}
+		log.Printf("[server] method=%s path=%s status=%d duration=%s remote=%s",
+			r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Microsecond), r.RemoteAddr)
+	})
+}
+
+// withSameOrigin refuses requests that may change something (anything but
+// GET, HEAD and OPTIONS) sent by a page from another origin. With the
+// settings server on a fixed port, any web page could otherwise send
+// simple cross-site POSTs, which browsers deliver without a preflight.
+// Requests without an Origin, from outside a browser, pass, as do the
+// extensions withExtensionCORS has already let through.
+func withSameOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "HEAD", "OPTIONS":
+		default:
+			if !sameOrigin(r) && !isExtensionOrigin(r.Header.Get("Origin")) {
+				http.Error(w, "forbidden: cross-origin request", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withLocalHost refuses requests to the settings server whose Host isn't
+// its loopback address. A web page that points its own name at 127.0.0.1
+// (DNS rebinding) sends matching Origin and Host headers, so the Origin
+// checks alone can't tell it from the app's pages.
+func (s *Server) withLocalHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, port, err := net.SplitHostPort(r.Host)
+		_, want, _ := net.SplitHostPort(s.listener.Addr().String())
+		if err != nil || port != want || (host != "127.0.0.1" && host != "localhost" && host != "::1") {
+			http.Error(w, "forbidden: unexpected Host", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withRecovery turns a panicking handler into a 500 response instead of
+// taking down the whole tray app, and reports it on the event bus.
+func (s *Server) withRecovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if v := recover(); v != nil {
+				log.Printf("[server] panic in %s %s: %v\n%s", r.Method, r.URL.Path, v, debug.Stack())
+				if s.bus != nil {
+					s.bus.Publish(events.Event{Type: events.TypeProblem, Name: "panic",
+						Detail: fmt.Sprintf("%s %s crashed: %v", r.Method, r.URL.Path, v)})
+				}
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tokenBucket is a minimal token-bucket rate limiter.
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	max    float64
+	rate   float64 // tokens per second
+	last   time.Time
+}
+
+func newTokenBucket(burst int, ratePerSec float64) *tokenBucket {
+	return &tokenBucket{
+		tokens: float64(burst),
+		max:    float64(burst),
+		rate:   ratePerSec,
+		last:   time.Now(),
+	}
+}
+
+// allow takes a token if one is available. If not, it returns false and
+// how long until the next token is refilled.
+func (b *tokenBucket) allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.max, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	return false, wait
+}
+
+// full reports whether the bucket has refilled completely, so it can be
+// forgotten without changing what it allows.
+func (b *tokenBucket) full() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens+time.Since(b.last).Seconds()*b.rate >= b.max
+}
+
+// ipBuckets keeps one token bucket per remote IP.
+type ipBuckets struct {
+	mu      sync.Mutex
+	burst   int
+	rate    float64
+	buckets map[string]*tokenBucket
+}
+
+func newIPBuckets(burst int, ratePerSec float64) *ipBuckets {
+	return &ipBuckets{burst: burst, rate: ratePerSec, buckets: make(map[string]*tokenBucket)}
+}
+
+// bucket returns the bucket for the request's remote IP. Buckets that
+// have refilled are dropped, so the map only holds recent clients.
+func (l *ipBuckets) bucket(r *http.Request) *tokenBucket {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for k, b := range l.buckets {
+		if k != ip && b.full() {
+			delete(l.buckets, k)
+		}
+	}
+	b := l.buckets[ip]
+	if b == nil {
+		b = newTokenBucket(l.burst, l.rate)
+		l.buckets[ip] = b
+	}
+	return b
+}
+
+// rateLimited rejects requests with 429 once the bucket is exhausted.
+func rateLimited(b *tokenBucket, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !admit(w, b) {
+			return
+		}
+		h(w, r)
+	}
+}
+
+// rateLimitedByIP is rateLimited with a bucket per remote IP.
+func rateLimitedByIP(l *ipBuckets, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !admit(w, l.bucket(r)) {
+			return
+		}
+		h(w, r)
+	}
+}
+
+// admit takes a token from b, or answers 429 with a Retry-After.
+func admit(w http.ResponseWriter, b *tokenBucket) bool {
+	if ok, wait := b.allow(); !ok {
+		secs := int(math.Ceil(wait.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
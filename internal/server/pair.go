@@ -0,0 +1,375 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+)
+
+// Pairing limits: a PIN is valid for pinTTL and allows maxPINAttempts
+// guesses; at most maxPendingPairs requests can wait at once.
+const (
+	pinTTL          = 2 * time.Minute
+	maxPINAttempts  = 5
+	maxPendingPairs = 3
+	maxClientName   = 40
+)
+
+// pairRequest is a client waiting for its PIN to be entered.
+type pairRequest struct {
+	id       string
+	name     string
+	role     string
+	pin      string
+	expires  time.Time
+	attempts int
+}
+
+// pairing tracks pending pair requests.
+type pairing struct {
+	mu      sync.Mutex
+	pending map[string]*pairRequest
+	onPIN   func(name, pin string) // shows the PIN on this computer; "" clears it
+}
+
+// SetPairingHandler sets a callback that shows a new client's pairing PIN
+// on this computer (e.g. in the tray). It is called with empty strings
+// once no pairing is pending.
+func (s *Server) SetPairingHandler(fn func(name, pin string)) {
+	s.pairs.mu.Lock()
+	defer s.pairs.mu.Unlock()
+	s.pairs.onPIN = fn
+}
+
+// newPIN returns a random six-digit PIN.
+func newPIN() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// start registers a pair request and shows its PIN.
+func (p *pairing) start(name, role string) (*pairRequest, error) {
+	pin, err := newPIN()
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expireLocked()
+	if len(p.pending) >= maxPendingPairs {
+		return nil, fmt.Errorf("too many pairing requests, try again in a minute")
+	}
+	if p.pending == nil {
+		p.pending = make(map[string]*pairRequest)
+	}
+	req := &pairRequest{id: hex.EncodeToString(b), name: name, role: role, pin: pin, expires: time.Now().Add(pinTTL)}
+	p.pending[req.id] = req
+	time.AfterFunc(pinTTL, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.expireLocked()
+	})
+	p.showLocked()
+	return req, nil
+}
+
+// confirm checks pin for request id and returns the request. It is used
+// up on success or once it runs out of attempts.
+func (p *pairing) confirm(id, pin string) (*pairRequest, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expireLocked()
+	req, ok := p.pending[id]
+	if !ok {
+		return nil, fmt.Errorf("pairing request expired, start again")
+	}
+	if pin != req.pin {
+		req.attempts++
+		if req.attempts >= maxPINAttempts {
+			delete(p.pending, id)
+			p.showLocked()
+			return nil, fmt.Errorf("wrong PIN, too many attempts — start again")
+		}
+		return nil, fmt.Errorf("wrong PIN")
+	}
+	delete(p.pending, id)
+	p.showLocked()
+	return req, nil
+}
+
+// list returns the pending requests for display on this computer.
+func (p *pairing) list() []pairingStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expireLocked()
+	out := make([]pairingStatus, 0, len(p.pending))
+	for _, req := range p.pending {
+		out = append(out, pairingStatus{Name: req.name, Role: req.role, PIN: req.pin})
+	}
+	return out
+}
+
+// expireLocked drops expired requests. Must be called with p.mu held.
+func (p *pairing) expireLocked() {
+	now := time.Now()
+	changed := false
+	for id, req := range p.pending {
+		if now.After(req.expires) {
+			delete(p.pending, id)
+			changed = true
+		}
+	}
+	if changed {
+		p.showLocked()
+	}
+}
+
+// showLocked reports the newest pending PIN, or clears it. Must be called
+// with p.mu held.
+func (p *pairing) showLocked() {
+	if p.onPIN == nil {
+		return
+	}
+	var newest *pairRequest
+	for _, req := range p.pending {
+		if newest == nil || req.expires.After(newest.expires) {
+			newest = req
+		}
+	}
+	if newest == nil {
+		p.onPIN("", "")
+		return
+	}
+	name := newest.name
+	if newest.role == config.RoleRead {
+		name += " (read-only)"
+	}
+	p.onPIN(name, newest.pin)
+}
+
+// pairingStatus is a pending pairing shown on the settings page.
+type pairingStatus struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+	PIN  string `json:"pin"`
+}
+
+// pairStartRequest is the JSON body for POST /pair/start.
+type pairStartRequest struct {
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"` // "control" (default) or "read"
+}
+
+// parseRole validates a requested client role.
+func parseRole(role string) (string, error) {
+	switch role {
+	case "", config.RoleControl:
+		return config.RoleControl, nil
+	case config.RoleRead:
+		return config.RoleRead, nil
+	}
+	return "", fmt.Errorf("role must be %q or %q", config.RoleControl, config.RoleRead)
+}
+
+// pairResponse is the JSON response for the pairing endpoints.
+type pairResponse struct {
+	ID        string `json:"id,omitempty"`         // pair request, from /pair/start
+	ExpiresIn int    `json:"expires_in,omitempty"` // seconds until the PIN expires
+	ClientID  string `json:"client_id,omitempty"`  // from /pair/confirm
+	Token     string `json:"token,omitempty"`      // from /pair/confirm, for use as a bearer token
+	Error     string `json:"error,omitempty"`
+}
+
+// handlePairStart begins pairing a new client: a PIN is shown on this
+// computer, to be entered on the client.
+func (s *Server) handlePairStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var req pairStartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, pairResponse{Error: "invalid JSON"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxClientName {
+		writeJSON(w, pairResponse{Error: fmt.Sprintf("name must be 1-%d characters", maxClientName)})
+		return
+	}
+
+	role, err := parseRole(req.Role)
+	if err != nil {
+		writeJSON(w, pairResponse{Error: err.Error()})
+		return
+	}
+
+	pr, err := s.pairs.start(name, role)
+	if err != nil {
+		writeJSON(w, pairResponse{Error: err.Error()})
+		return
+	}
+	log.Printf("[server] pairing requested by %q (%s) from %s", name, role, r.RemoteAddr)
+	writeJSON(w, pairResponse{ID: pr.id, ExpiresIn: int(pinTTL.Seconds())})
+}
+
+// pairConfirmRequest is the JSON body for POST /pair/confirm.
+type pairConfirmRequest struct {
+	ID  string `json:"id"`
+	PIN string `json:"pin"`
+}
+
+// handlePairConfirm issues a token to a client that entered the right
+// PIN. Browsers get it as a cookie; scripts can use it as a bearer token.
+func (s *Server) handlePairConfirm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	var req pairConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, pairResponse{Error: "invalid JSON"})
+		return
+	}
+	pr, err := s.pairs.confirm(req.ID, strings.TrimSpace(req.PIN))
+	if err != nil {
+		writeJSON(w, pairResponse{Error: err.Error()})
+		return
+	}
+
+	cl, err := s.cfg.AddLANClient(pr.name, pr.role)
+	if err != nil {
+		log.Printf("[server] save paired client: %v", err)
+		writeJSON(w, pairResponse{Error: "failed to persist pairing"})
+		return
+	}
+	log.Printf("[server] paired %q (%s, %s)", cl.Name, cl.ID, cl.Role)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     tokenCookie,
+		Value:    cl.Token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   365 * 24 * 60 * 60,
+	})
+	writeJSON(w, pairResponse{ClientID: cl.ID, Token: cl.Token})
+}
+
+// pairedClient is a paired client as listed on the settings page.
+type pairedClient struct {
+	ID     string    `json:"id"`
+	Name   string    `json:"name"`
+	Role   string    `json:"role"`
+	Paired time.Time `json:"paired"`
+}
+
+// pairClientsRequest is the JSON body for POST /pair/clients: revoke the
+// client, change its role if Role is set, or replace the wake link if
+// RotateWake is set.
+type pairClientsRequest struct {
+	ID         string `json:"id"`
+	Role       string `json:"role,omitempty"`
+	RotateWake bool   `json:"rotate_wake,omitempty"`
+}
+
+// pairClientsResponse is the JSON response for /pair/clients.
+type pairClientsResponse struct {
+	Clients []pairedClient `json:"clients"`
+	WakeURL string         `json:"wake_url,omitempty"` // only wakes the R1; "" = the remote is off
+	Error   string         `json:"error,omitempty"`
+}
+
+// handlePairClients lists paired clients (GET), or revokes one or changes
+// its role (POST). Only available from this computer.
+func (s *Server) handlePairClients(w http.ResponseWriter, r *http.Request) {
+	if !isLoopback(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "POST":
+		var req pairClientsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, pairClientsResponse{Error: "invalid JSON"})
+			return
+		}
+		var ok bool
+		var err error
+		if req.RotateWake {
+			if _, err := s.cfg.WakeToken(true); err != nil {
+				log.Printf("[server] new wake link: %v", err)
+				writeJSON(w, pairClientsResponse{Error: "failed to persist config"})
+				return
+			}
+			log.Printf("[server] replaced the wake link")
+			break
+		}
+		if req.Role != "" {
+			role, perr := parseRole(req.Role)
+			if perr != nil {
+				writeJSON(w, pairClientsResponse{Error: perr.Error()})
+				return
+			}
+			ok, err = s.cfg.SetLANClientRole(req.ID, role)
+		} else {
+			ok, err = s.cfg.RemoveLANClient(req.ID)
+		}
+		if err != nil {
+			log.Printf("[server] config save failed: %v", err)
+			writeJSON(w, pairClientsResponse{Error: "failed to persist config"})
+			return
+		}
+		if !ok {
+			writeJSON(w, pairClientsResponse{Error: "no such client"})
+			return
+		}
+		if req.Role != "" {
+			log.Printf("[server] client %s is now %s", req.ID, req.Role)
+		} else {
+			log.Printf("[server] revoked client %s", req.ID)
+		}
+	default:
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	clients := []pairedClient{}
+	for _, cl := range s.cfg.GetLAN().Clients {
+		role := config.RoleRead
+		if cl.CanControl() {
+			role = config.RoleControl
+		}
+		clients = append(clients, pairedClient{ID: cl.ID, Name: cl.Name, Role: role, Paired: cl.Paired})
+	}
+	resp := pairClientsResponse{Clients: clients}
+	if s.lanURL != "" {
+		if tok, err := s.cfg.WakeToken(false); err != nil {
+			log.Printf("[server] wake link: %v", err)
+		} else {
+			resp.WakeURL = strings.TrimSuffix(s.lanURL, "/remote") + apiPrefix + "/wake?token=" + tok
+		}
+	}
+	writeJSON(w, resp)
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/hostcontext"
+)
+
+// SetProfileHandler sets the callback that switches in a profile and
+// applies its settings to the running app, for POST /profile.
+func (s *Server) SetProfileHandler(fn func(name string) error) {
+	s.onProfile = fn
+}
+
+// profileRequest is the JSON body for POST /profile.
+type profileRequest struct {
+	Name string `json:"name"`
+}
+
+// profileResponse is the JSON response for /profile.
+type profileResponse struct {
+	Active   string                `json:"active"` // profile last switched in; "" = none
+	Profiles []config.Profile      `json:"profiles"`
+	Context  *hostcontext.Snapshot `json:"context,omitempty"` // what the context rules see now, for GET
+	Rule     int                   `json:"rule,omitempty"`    // the context rule matching it, from 1; 0 = none
+	Error    string                `json:"error,omitempty"`
+}
+
+// handleProfile returns (GET) the profiles, the active one and what the
+// context rules see now, or switches (POST) to the named profile.
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		snap := hostcontext.Detect()
+		writeJSON(w, profileResponse{
+			Active:   s.cfg.GetActiveProfile(),
+			Profiles: s.cfg.GetProfiles(),
+			Context:  &snap,
+			Rule:     hostcontext.Match(s.cfg.GetContextRules(), snap) + 1,
+		})
+	case "POST":
+		if s.onProfile == nil {
+			writeJSON(w, profileResponse{Error: "profiles are not available"})
+			return
+		}
+		var req profileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, profileResponse{Error: "invalid JSON"})
+			return
+		}
+		if err := s.onProfile(req.Name); err != nil {
+			writeJSON(w, profileResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, profileResponse{Active: s.cfg.GetActiveProfile(), Profiles: s.cfg.GetProfiles()})
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
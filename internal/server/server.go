@@ -8,38 +8,107 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/HopIT-Hub/R1-Control/internal/config"
 	"github.com/HopIT-Hub/R1-Control/internal/device"
+	"github.com/HopIT-Hub/R1-Control/internal/events"
+	"github.com/HopIT-Hub/R1-Control/internal/focus"
 	"github.com/HopIT-Hub/R1-Control/internal/hotkey"
+	"github.com/HopIT-Hub/R1-Control/internal/selftest"
+	"github.com/HopIT-Hub/R1-Control/internal/stats"
+	"github.com/HopIT-Hub/R1-Control/internal/telemetry"
 	"github.com/HopIT-Hub/R1-Control/internal/web"
+	"github.com/HopIT-Hub/R1-Control/internal/webusb"
 )
 
+// apiPrefix is the base path for the current version of the HTTP API.
+// Breaking changes to request/response shapes get a new version prefix.
+const apiPrefix = "/api/v1"
+
 // Server serves the settings UI on localhost.
 type Server struct {
-	httpServer  *http.Server
-	listener    net.Listener
-	hotkeyMgr   *hotkey.Manager
-	swipeHkMgr  *hotkey.Manager
-	deviceMgr   *device.Manager
-	cfg         *config.Config
-	version     string
+	httpServer   *http.Server
+	listener     net.Listener
+	mux          *http.ServeMux
+	lanServer    *http.Server // phone remote; nil unless LAN mode is on
+	lanURL       string
+	hotkeyMgr    *hotkey.Manager
+	swipeHkMgr   *hotkey.Manager
+	promptHkMgr  *hotkey.Manager // nil until SetHotkeyManagers
+	confirmHkMgr *hotkey.Manager // nil until SetHotkeyManagers
+	deviceMgr    *device.Manager
+	cfg          *config.Config
+	stats        *stats.Store // nil if usage statistics couldn't be loaded
+	version      string
+
+	captureMu     sync.Mutex
+	captureCancel context.CancelFunc // cancels a running hotkey capture
+
+	holdMu       sync.Mutex
+	holds        int         // open /ptt/hold streams
+	heldByStream atomic.Bool // the streams turned PTT on, and it hasn't gone off since
+
+	pairs pairing // pending LAN client pairings
+
+	macros macros // macro recorder and player
+
+	guards guards // confirmations and cooldowns of guarded actions
+
+	focus *focus.Timer // nil until SetFocus
+
+	telemetry *telemetry.Reporter // nil until SetTelemetry
+
+	onProfile func(name string) error // switches in a profile; nil until SetProfileHandler
+
+	webusb *webusb.Relay // nil unless the webusb backend is in use
+
+	selfTestMu sync.Mutex                    // one self test at a time
+	onSelfTest func(checks []selftest.Check) // shows results outside the settings UI, e.g. in the tray
+
+	onRestart func() // restarts the app; set before Start
+
+	port int // localhost port; 0 = any free port
+
+	actionLimit *tokenBucket // device actions, shared by both servers; set by Start
+	pairLimit   *ipBuckets   // pairing attempts per client; set by Start
+
+	problemsMu sync.Mutex
+	published  map[string]string // problems from the bus, by source
+
+	bus *events.Bus // streamed to /ws clients; nil until Subscribe
 }
 
 // New creates a settings server.
-func New(hotkeyMgr *hotkey.Manager, swipeHkMgr *hotkey.Manager, deviceMgr *device.Manager, cfg *config.Config, version string) *Server {
+func New(hotkeyMgr *hotkey.Manager, swipeHkMgr *hotkey.Manager, deviceMgr *device.Manager, cfg *config.Config, st *stats.Store, version string) *Server {
 	return &Server{
 		hotkeyMgr:  hotkeyMgr,
 		swipeHkMgr: swipeHkMgr,
 		deviceMgr:  deviceMgr,
 		cfg:        cfg,
+		stats:      st,
 		version:    version,
 	}
 }
 
-// Start begins serving on a random localhost port.
-// Returns the URL to open in the browser.
+// SetHotkeyManagers sets the prompt and confirm hotkey managers, so
+// /status reports them next to PTT and swipe. Set before Start.
+func (s *Server) SetHotkeyManagers(prompt, confirm *hotkey.Manager) {
+	s.promptHkMgr = prompt
+	s.confirmHkMgr = confirm
+}
+
+// SetPort makes Start listen on a fixed localhost port instead of a
+// random one, e.g. so it can be forwarded. Set before Start.
+func (s *Server) SetPort(port int) {
+	s.port = port
+}
+
+// Start begins serving on localhost, on a random port unless SetPort
+// fixed one. Returns the URL to open in the browser.
 func (s *Server) Start() (string, error) {
 	mux := http.NewServeMux()
 
@@ -52,23 +121,85 @@ func (s *Server) Start() (string, error) {
 
 	// Settings page
 	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/settings", s.handleSettingsPage)
+	mux.HandleFunc("/prompt", s.handlePromptPage)
+	mux.HandleFunc("/remote", s.handleRemotePage)
+	mux.HandleFunc("/pair", s.handlePairPage)
+	mux.HandleFunc("/webusb", s.handleWebUSBPage)
+
+	// API endpoints (versioned, with deprecated unversioned aliases)
+	handleAPI(mux, "/status", s.handleStatus)
+	mux.HandleFunc(apiPrefix+"/diagnostics", s.handleDiagnostics)
+	mux.HandleFunc(apiPrefix+"/device/descriptors", s.handleDescriptors)
+	mux.HandleFunc(apiPrefix+"/stats", s.handleStats)
+	mux.HandleFunc(apiPrefix+"/telemetry", s.handleTelemetry)
+	mux.HandleFunc(apiPrefix+"/changelog", s.handleChangelog)
+	mux.HandleFunc(apiPrefix+"/gestures", s.handleGestures)
+	mux.HandleFunc(apiPrefix+"/gestures/", s.handleGesture)
+	mux.HandleFunc(apiPrefix+"/config/validate", s.handleConfigValidate)
+	mux.HandleFunc(apiPrefix+"/config/backups", s.handleConfigBackups)
+	mux.HandleFunc(apiPrefix+"/config/restore", s.handleConfigRestore)
+	handleAPI(mux, "/hotkey", s.handleHotkey)
+	handleAPI(mux, "/swipe-hotkey", s.handleSwipeHotkey)
+	mux.HandleFunc(apiPrefix+"/hotkey/capture/start", s.handleHotkeyCaptureStart)
+	mux.HandleFunc(apiPrefix+"/hotkey/capture/cancel", s.handleHotkeyCaptureCancel)
+	handleAPI(mux, "/autostart", s.handleAutoStart)
+	handleAPI(mux, "/keepawake", s.handleKeepAwake)
+	mux.HandleFunc(apiPrefix+"/keepawake/schedule", s.handleKeepAwakeSchedule)
+	mux.HandleFunc(apiPrefix+"/ptt-auto-release", s.handlePTTAutoRelease)
+	mux.HandleFunc(apiPrefix+"/ptt-mode", s.handlePTTMode)
+	mux.HandleFunc(apiPrefix+"/swipe-mode", s.handleSwipeMode)
+	mux.HandleFunc(apiPrefix+"/loglevel", s.handleLogLevel)
 
-	// API endpoints
-	mux.HandleFunc("/status", s.handleStatus)
-	mux.HandleFunc("/hotkey", s.handleHotkey)
-	mux.HandleFunc("/swipe-hotkey", s.handleSwipeHotkey)
-	mux.HandleFunc("/autostart", s.handleAutoStart)
-	mux.HandleFunc("/keepawake", s.handleKeepAwake)
+	// WebUSB relay for the webusb backend; the tab polls for transfers
+	mux.HandleFunc(apiPrefix+"/webusb/attach", s.handleWebUSBAttach)
+	mux.HandleFunc(apiPrefix+"/webusb/detach", s.handleWebUSBDetach)
+	mux.HandleFunc(apiPrefix+"/webusb/next", s.handleWebUSBNext)
+	mux.HandleFunc(apiPrefix+"/webusb/done", s.handleWebUSBDone)
 
-	// Bind to random localhost port
-	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	// Phone remote pairing (rate limited against PIN guessing)
+	s.pairLimit = newIPBuckets(pairBurst, pairRatePerSec)
+	mux.HandleFunc(apiPrefix+"/pair/start", rateLimitedByIP(s.pairLimit, s.handlePairStart))
+	mux.HandleFunc(apiPrefix+"/pair/confirm", rateLimitedByIP(s.pairLimit, s.handlePairConfirm))
+	mux.HandleFunc(apiPrefix+"/pair/clients", s.handlePairClients)
+
+	// Device actions (rate limited, one bucket shared by all actions)
+	s.actionLimit = newTokenBucket(actionBurst, actionRatePerSec)
+	actions := s.actionLimit
+	mux.HandleFunc(apiPrefix+"/ptt", rateLimited(actions, s.handlePTT))
+	mux.HandleFunc(apiPrefix+"/ptt/hold", rateLimited(actions, s.handlePTTHold))
+	mux.HandleFunc(apiPrefix+"/swipe", rateLimited(actions, s.handleSwipe))
+	mux.HandleFunc(apiPrefix+"/tap", rateLimited(actions, s.handleTap))
+	mux.HandleFunc(apiPrefix+"/wake", rateLimited(actions, s.handleWake))
+	mux.HandleFunc(apiPrefix+"/experimental/display", rateLimited(actions, s.handleDisplay))
+	mux.HandleFunc(apiPrefix+"/selftest", rateLimited(actions, s.handleSelfTest))
+	mux.HandleFunc(apiPrefix+"/action", rateLimited(actions, s.handleAction))
+	mux.HandleFunc(apiPrefix+"/quickactions", s.handleQuickActions)
+	mux.HandleFunc(apiPrefix+"/quickactions/run", rateLimited(actions, s.handleQuickActionRun))
+	mux.HandleFunc(apiPrefix+"/macros", s.handleMacros)
+	mux.HandleFunc(apiPrefix+"/macros/", s.handleMacro)
+	mux.HandleFunc(apiPrefix+"/macros/run", rateLimited(actions, s.handleMacroRun))
+	mux.HandleFunc(apiPrefix+"/macro/", rateLimited(actions, s.handleMacroTrigger))
+	mux.HandleFunc(apiPrefix+"/macros/record", s.handleMacroRecord)
+	mux.HandleFunc(apiPrefix+"/macros/record/stop", s.handleMacroRecordStop)
+	mux.HandleFunc(apiPrefix+"/prompt", rateLimited(actions, s.handlePrompt))
+	mux.HandleFunc(apiPrefix+"/focus", s.handleFocus)
+	mux.HandleFunc(apiPrefix+"/profile", s.handleProfile)
+	mux.HandleFunc(apiPrefix+"/focus/start", rateLimited(actions, s.handleFocusCommand("start")))
+	mux.HandleFunc(apiPrefix+"/focus/stop", s.handleFocusCommand("stop"))
+	mux.HandleFunc(apiPrefix+"/focus/skip", rateLimited(actions, s.handleFocusCommand("skip")))
+	mux.HandleFunc(apiPrefix+"/ws", s.handleWS(actions))
+
+	// Bind to localhost
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(s.port)))
 	if err != nil {
 		return "", fmt.Errorf("listen: %w", err)
 	}
 	s.listener = ln
+	s.mux = mux
 
 	s.httpServer = &http.Server{
-		Handler:      mux,
+		Handler:      s.withRecovery(withLogging(s.withLocalHost(s.withExtensionCORS(withSameOrigin(mux))))),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
@@ -84,6 +215,19 @@ func (s *Server) Start() (string, error) {
 	return url, nil
 }
 
+// handleAPI registers h under the versioned API prefix and at its original
+// unversioned path. The unversioned alias is deprecated: responses carry
+// Deprecation and Link headers pointing at the versioned route.
+func handleAPI(mux *http.ServeMux, path string, h http.HandlerFunc) {
+	versioned := apiPrefix + path
+	mux.HandleFunc(versioned, h)
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+versioned+">; rel=\"successor-version\"")
+		h(w, r)
+	})
+}
+
 // Stop shuts down the HTTP server.
 func (s *Server) Stop() {
 	if s.httpServer != nil {
@@ -91,6 +235,11 @@ func (s *Server) Stop() {
 		defer cancel()
 		s.httpServer.Shutdown(ctx)
 	}
+	if s.lanServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		s.lanServer.Shutdown(ctx)
+	}
 }
 
 // URL returns the server's URL, or empty string if not started.
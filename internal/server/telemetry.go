@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/HopIT-Hub/R1-Control/internal/telemetry"
+)
+
+// SetTelemetry sets the usage reporter behind /telemetry. Set before
+// Start.
+func (s *Server) SetTelemetry(r *telemetry.Reporter) {
+	s.telemetry = r
+}
+
+// telemetryRequest is the JSON body for POST /telemetry.
+type telemetryRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// telemetryResponse is the JSON response for /telemetry.
+type telemetryResponse struct {
+	*telemetry.Status
+	Error string `json:"error,omitempty"`
+}
+
+// handleTelemetry returns (GET) the usage report's settings along with
+// the report exactly as it would be sent now, or turns it on or off
+// (POST). Turning it off forgets what was counted.
+func (s *Server) handleTelemetry(w http.ResponseWriter, r *http.Request) {
+	if s.telemetry == nil {
+		writeJSON(w, telemetryResponse{Error: "usage reports are unavailable"})
+		return
+	}
+	switch r.Method {
+	case "GET":
+	case "POST":
+		var req telemetryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, telemetryResponse{Error: "invalid JSON"})
+			return
+		}
+		if err := s.cfg.SetTelemetryEnabled(req.Enabled); err != nil {
+			log.Printf("[server] save telemetry: %v", err)
+			writeJSON(w, telemetryResponse{Error: "failed to save setting"})
+			return
+		}
+		if !req.Enabled {
+			s.telemetry.Reset()
+		}
+		log.Printf("[server] usage reports: %v", req.Enabled)
+	default:
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	st := s.telemetry.Status()
+	writeJSON(w, telemetryResponse{Status: &st})
+}
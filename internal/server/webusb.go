@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/HopIT-Hub/R1-Control/internal/webusb"
+)
+
+// SetWebUSB sets the relay behind /webusb, for the webusb backend. Set
+// before Start.
+func (s *Server) SetWebUSB(r *webusb.Relay) {
+	s.webusb = r
+}
+
+// handleWebUSBPage serves the page that shares the R1 over WebUSB.
+func (s *Server) handleWebUSBPage(w http.ResponseWriter, r *http.Request) {
+	servePage(w, "webusb.html")
+}
+
+// webusbStatus is the JSON response for /webusb/attach and /webusb/detach.
+type webusbStatus struct {
+	Enabled bool         `json:"enabled"` // the webusb backend is in use
+	Shared  *webusb.Info `json:"shared,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// handleWebUSBAttach reports (GET) or starts (POST) sharing the R1 from
+// the calling tab.
+func (s *Server) handleWebUSBAttach(w http.ResponseWriter, r *http.Request) {
+	if s.webusb == nil {
+		writeJSON(w, webusbStatus{Error: `set "device": {"backend": "webusb"} in the config to use WebUSB`})
+		return
+	}
+	switch r.Method {
+	case "GET":
+		st := webusbStatus{Enabled: true}
+		if info, ok := s.webusb.Shared(); ok {
+			st.Shared = &info
+		}
+		writeJSON(w, st)
+	case "POST":
+		var info webusb.Info
+		if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+			writeJSON(w, webusbStatus{Enabled: true, Error: "invalid JSON"})
+			return
+		}
+		s.webusb.Attach(info)
+		log.Printf("[server] R1 %q shared over WebUSB", info.Serial)
+		writeJSON(w, webusbStatus{Enabled: true, Shared: &info})
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+// handleWebUSBDetach stops sharing, e.g. when the R1 is unplugged.
+func (s *Server) handleWebUSBDetach(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	if s.webusb != nil {
+		s.webusb.Detach()
+		log.Printf("[server] R1 no longer shared over WebUSB")
+	}
+	writeJSON(w, webusbStatus{Enabled: s.webusb != nil})
+}
+
+// handleWebUSBNext hands the tab its next transfer, waiting up to
+// webusb.PollWait; 204 if there is none, 404 if the tab must attach
+// first.
+func (s *Server) handleWebUSBNext(w http.ResponseWriter, r *http.Request) {
+	if s.webusb == nil {
+		http.Error(w, "WebUSB is off", 404)
+		return
+	}
+	t, ok, err := s.webusb.Next()
+	if err != nil {
+		http.Error(w, err.Error(), 404)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, t)
+}
+
+// webusbResult is the JSON body for POST /webusb/done.
+type webusbResult struct {
+	ID    uint64 `json:"id"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleWebUSBDone takes the result of a transfer from the tab.
+func (s *Server) handleWebUSBDone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	if s.webusb == nil {
+		http.Error(w, "WebUSB is off", 404)
+		return
+	}
+	var res webusbResult
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		http.Error(w, "invalid JSON", 400)
+		return
+	}
+	s.webusb.Done(res.ID, res.Error)
+	w.WriteHeader(http.StatusNoContent)
+}
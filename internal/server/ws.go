@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/action"
+	"github.com/HopIT-Hub/R1-Control/internal/events"
+	"github.com/HopIT-Hub/R1-Control/internal/logging"
+	"github.com/HopIT-Hub/R1-Control/internal/ws"
+)
+
+// wsPingInterval is how often an idle WebSocket is pinged.
+const wsPingInterval = 30 * time.Second
+
+// wsQueue is how many events may wait for a slow WebSocket client before
+// further ones are dropped.
+const wsQueue = 64
+
+// wsMessage is a message to a WebSocket client.
+type wsMessage struct {
+	Type      string          `json:"type"`              // "hello", "event", "ack" or "error"
+	ID        json.RawMessage `json:"id,omitempty"`      // the command's id, for "ack" and "error"
+	Version   string          `json:"version,omitempty"` // app version, in "hello"
+	State     string          `json:"state,omitempty"`   // device state, in "hello" and "ack"
+	Event     *events.Event   `json:"event,omitempty"`
+	Challenge string          `json:"challenge,omitempty"` // a guarded action's, in "error"
+	Error     string          `json:"error,omitempty"`
+}
+
+// wsCommand is a message from a WebSocket client: an action, as for POST
+// /action, with an id to match the reply.
+type wsCommand struct {
+	ID      json.RawMessage `json:"id"`
+	Action  string          `json:"action"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Confirm string          `json:"confirm,omitempty"`
+}
+
+// handleWS returns the handler for /ws: a WebSocket that streams the
+// app's events and takes commands, each answered with an "ack" or an
+// "error" carrying its id. Commands share limit with the HTTP actions.
+func (s *Server) handleWS(limit *tokenBucket) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !sameOrigin(r) {
+			http.Error(w, "forbidden: cross-origin WebSocket", http.StatusForbidden)
+			return
+		}
+		c, err := ws.Upgrade(w, r)
+		if err != nil {
+			logging.Debugf("[server] websocket: %v", err)
+			return
+		}
+		defer c.Close()
+
+		queue := make(chan events.Event, wsQueue)
+		done := make(chan struct{})
+		defer close(done)
+		if s.bus != nil {
+			unsubscribe := s.bus.Subscribe(func(e events.Event) {
+				select {
+				case queue <- e:
+				default:
+					logging.Debugf("[server] websocket %s is behind, dropped a %s event", r.RemoteAddr, e.Type)
+				}
+			})
+			defer unsubscribe()
+		}
+		go s.wsWriteEvents(c, queue, done)
+
+		if err := c.WriteJSON(wsMessage{Type: "hello", Version: s.version, State: s.deviceMgr.State().String()}); err != nil {
+			return
+		}
+		for {
+			data, err := c.Read()
+			if err != nil {
+				if err != ws.ErrClosed {
+					logging.Debugf("[server] websocket %s: %v", r.RemoteAddr, err)
+				}
+				return
+			}
+			if err := c.WriteJSON(s.wsRun(limit, data)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// wsWriteEvents sends queued events to c, and pings it while idle, until
+// done is closed.
+func (s *Server) wsWriteEvents(c *ws.Conn, queue <-chan events.Event, done <-chan struct{}) {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case e := <-queue:
+			if err := c.WriteJSON(wsMessage{Type: "event", Event: &e}); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := c.Ping(); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// wsRun runs the command in data and returns the reply.
+func (s *Server) wsRun(limit *tokenBucket, data []byte) wsMessage {
+	var cmd wsCommand
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		return wsMessage{Type: "error", Error: "invalid JSON"}
+	}
+	reply := wsMessage{Type: "error", ID: cmd.ID}
+	if ok, wait := limit.allow(); !ok {
+		reply.Error = fmt.Sprintf("rate limit exceeded; retry in %ds", int(math.Ceil(wait.Seconds())))
+		return reply
+	}
+	if err := action.Validate(cmd.Action, cmd.Payload); err != nil {
+		reply.Error = err.Error()
+		return reply
+	}
+	if err := s.checkGuard(cmd.Action, cmd.Payload, cmd.Confirm); err != nil {
+		reply.Error, reply.Challenge = err.Error(), challengeOf(err)
+		return reply
+	}
+	if err := action.Run(s.deviceMgr, cmd.Action, cmd.Payload); err != nil {
+		reply.Error = err.Error()
+		return reply
+	}
+	s.ranGuarded(cmd.Action)
+	return wsMessage{Type: "ack", ID: cmd.ID, State: s.deviceMgr.State().String()}
+}
+
+// sameOrigin reports whether r comes from one of the app's own pages, or
+// from outside a browser. Browsers let any site open a WebSocket to
+// localhost, so other origins are refused. On the settings server,
+// withLocalHost has already checked the Host it is compared with.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
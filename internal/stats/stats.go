@@ -0,0 +1,242 @@
+// Package stats keeps per-day usage counters (PTT time, swipes,
+// reconnects, keep-awake time) in a small JSON file next to the config.
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/device"
+	"github.com/HopIT-Hub/R1-Control/internal/events"
+	"github.com/HopIT-Hub/R1-Control/internal/safefile"
+)
+
+// Days older than this are dropped when the store is saved.
+const retainDays = 365
+
+const dateLayout = "2006-01-02"
+
+// Day holds the usage counters for one local calendar day.
+type Day struct {
+	Date             string  `json:"date"` // YYYY-MM-DD, local time
+	PTTSeconds       float64 `json:"ptt_seconds"`
+	PTTSessions      int     `json:"ptt_sessions"`
+	Swipes           int     `json:"swipes"`
+	Taps             int     `json:"taps"`
+	Reconnects       int     `json:"reconnects"`
+	KeepAwakeSeconds float64 `json:"keep_awake_seconds"`
+}
+
+// Store aggregates usage counters and persists them to disk.
+type Store struct {
+	mu    sync.Mutex
+	path  string
+	days  map[string]*Day
+	dirty bool
+
+	state     device.State
+	pttStart  time.Time // when PTT became active
+	connected bool      // a connection has been seen this session
+}
+
+// Path returns the full path to the stats file.
+func Path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "stats.json"), nil
+}
+
+// Open loads the stats file, starting empty if it doesn't exist yet.
+func Open() (*Store, error) {
+	p, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{path: p, days: make(map[string]*Day)}
+
+	var days []*Day
+	_, err = safefile.Read(p, func(data []byte) error {
+		days = nil
+		return json.Unmarshal(data, &days)
+	})
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read stats: %w", err)
+	}
+	for _, d := range days {
+		s.days[d.Date] = d
+	}
+	return s, nil
+}
+
+// day returns the counters for t's date. Must be called with s.mu held.
+func (s *Store) day(t time.Time) *Day {
+	date := t.Format(dateLayout)
+	d, ok := s.days[date]
+	if !ok {
+		d = &Day{Date: date}
+		s.days[date] = d
+	}
+	s.dirty = true
+	return d
+}
+
+// Subscribe records the state changes and actions published on bus.
+func (s *Store) Subscribe(bus *events.Bus) {
+	bus.Subscribe(func(e events.Event) {
+		switch e.Type {
+		case events.TypeState:
+			if state, ok := e.Value.(device.State); ok {
+				s.SetState(state)
+			}
+		case events.TypeAction:
+			s.RecordAction(e.Name)
+		}
+	}, events.TypeState, events.TypeAction)
+}
+
+// SetState records a device state change. PTT time is counted from
+// entering PTTActive until leaving it and attributed to the day it ends;
+// every connect after the first one this session counts as a reconnect.
+func (s *Store) SetState(state device.State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	prev := s.state
+	s.state = state
+
+	if prev == state {
+		return
+	}
+	if state == device.PTTActive {
+		s.pttStart = now
+	} else if prev == device.PTTActive && !s.pttStart.IsZero() {
+		d := s.day(now)
+		d.PTTSeconds += now.Sub(s.pttStart).Seconds()
+		d.PTTSessions++
+		s.pttStart = time.Time{}
+	}
+	if !prev.Online() && state.Online() {
+		if s.connected {
+			s.day(now).Reconnects++
+		}
+		s.connected = true
+	}
+}
+
+// RecordAction counts a device action ("swipe", "tap", ...). Actions
+// without a counter are ignored.
+func (s *Store) RecordAction(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch name {
+	case "swipe":
+		s.day(time.Now()).Swipes++
+	case "tap":
+		s.day(time.Now()).Taps++
+	}
+}
+
+// Days returns the counters for the last n days (including today), oldest
+// first. Days without activity are included with zero counters.
+func (s *Store) Days(n int) []Day {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	out := make([]Day, 0, n)
+	for i := n - 1; i >= 0; i-- {
+		date := now.AddDate(0, 0, -i).Format(dateLayout)
+		if d, ok := s.days[date]; ok {
+			out = append(out, *d)
+		} else {
+			out = append(out, Day{Date: date})
+		}
+	}
+	return out
+}
+
+// Run samples keep-awake time and saves the store every interval until
+// ctx is cancelled. keepingAwake reports whether keep-awake pings are
+// currently being sent.
+func (s *Store) Run(ctx context.Context, interval time.Duration, keepingAwake func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if keepingAwake() {
+				s.mu.Lock()
+				s.day(time.Now()).KeepAwakeSeconds += interval.Seconds()
+				s.mu.Unlock()
+			}
+			if err := s.Save(); err != nil {
+				log.Printf("[stats] save: %v", err)
+			}
+		}
+	}
+}
+
+// Save writes the store to disk atomically if anything changed. An
+// in-progress PTT session is not flushed; it is counted when it ends.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -retainDays).Format(dateLayout)
+	days := make([]*Day, 0, len(s.days))
+	for date, d := range s.days {
+		if date < cutoff {
+			delete(s.days, date)
+			continue
+		}
+		days = append(days, d)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	data, err := json.MarshalIndent(days, "", "  ")
+	s.dirty = false
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("marshal stats: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("create stats dir: %w", err)
+	}
+	if err := safefile.Write(s.path, data); err != nil {
+		return fmt.Errorf("save stats: %w", err)
+	}
+	return nil
+}
+
+// Sum adds up the counters of days. The result has no date.
+func Sum(days []Day) Day {
+	var t Day
+	for _, d := range days {
+		t.PTTSeconds += d.PTTSeconds
+		t.PTTSessions += d.PTTSessions
+		t.Swipes += d.Swipes
+		t.Taps += d.Taps
+		t.Reconnects += d.Reconnects
+		t.KeepAwakeSeconds += d.KeepAwakeSeconds
+	}
+	return t
+}
@@ -0,0 +1,329 @@
+// Package telemetry sends the opt-in anonymous usage report: about once a
+// week, the app version, OS, R1 firmware, which optional features are on
+// and how often they were used. Nothing in it identifies the user, the
+// computer or the R1 — no serials, names, addresses, hotkeys, macros or
+// payloads — and nothing is counted or sent until the report is turned on
+// in Settings, which also shows the exact payload.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/internal/config"
+	"github.com/HopIT-Hub/R1-Control/internal/events"
+	"github.com/HopIT-Hub/R1-Control/internal/logging"
+	"github.com/HopIT-Hub/R1-Control/internal/safefile"
+	"github.com/HopIT-Hub/R1-Control/internal/stats"
+)
+
+// schema versions the Report layout, so the receiving end can tell
+// reports from different app versions apart.
+const schema = 1
+
+const (
+	interval    = 7 * 24 * time.Hour // between reports
+	firstCheck  = 5 * time.Minute    // after startup, before the first check
+	checkEvery  = time.Hour          // how often a report is checked for being due
+	sendTimeout = 15 * time.Second
+)
+
+// Report is the usage report, exactly as sent.
+type Report struct {
+	Schema   int            `json:"schema"`
+	Version  string         `json:"version"`
+	OS       string         `json:"os"`                 // GOOS/GOARCH
+	Firmware string         `json:"firmware,omitempty"` // RabbitOS version, as set in gesture.firmware
+	Backend  string         `json:"backend"`            // "libusb", "webusb" or "bridge"
+	Features []string       `json:"features"`           // optional features turned on
+	Actions  map[string]int `json:"actions"`            // device actions run since the last report, by name
+
+	// Usage over the last 7 days, from the Usage table
+	PTTSessions int `json:"ptt_sessions"`
+	PTTMinutes  int `json:"ptt_minutes"`
+	Swipes      int `json:"swipes"`
+	Taps        int `json:"taps"`
+	Reconnects  int `json:"reconnects"`
+}
+
+// Status is what Settings shows about the report.
+type Status struct {
+	Enabled  bool      `json:"enabled"`
+	Endpoint string    `json:"endpoint"` // "" = this build has nowhere to send reports
+	LastSent time.Time `json:"last_sent,omitzero"`
+	Next     Report    `json:"next"` // the report as it would be sent now
+}
+
+// state is what the reporter keeps between runs, in telemetry.json.
+type state struct {
+	LastSent time.Time      `json:"last_sent,omitzero"`
+	Actions  map[string]int `json:"actions"`
+}
+
+// Reporter counts feature use while the report is on and sends it when
+// due.
+type Reporter struct {
+	cfg             *config.Config
+	stats           *stats.Store // nil if usage statistics couldn't be loaded
+	version         string
+	defaultEndpoint string
+	path            string
+	client          *http.Client
+
+	mu    sync.Mutex
+	st    state
+	dirty bool
+}
+
+// Path returns the full path to the reporter's state file.
+func Path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "telemetry.json"), nil
+}
+
+// Open loads the reporter's state. defaultEndpoint is where reports go
+// unless the config sets telemetry.endpoint; "" = nowhere.
+func Open(cfg *config.Config, st *stats.Store, version, defaultEndpoint string) (*Reporter, error) {
+	p, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	r := &Reporter{
+		cfg:             cfg,
+		stats:           st,
+		version:         version,
+		defaultEndpoint: defaultEndpoint,
+		path:            p,
+		client:          &http.Client{Timeout: sendTimeout},
+	}
+	_, err = safefile.Read(p, func(data []byte) error {
+		r.st = state{}
+		return json.Unmarshal(data, &r.st)
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read telemetry state: %w", err)
+	}
+	if r.st.Actions == nil {
+		r.st.Actions = make(map[string]int)
+	}
+	return r, nil
+}
+
+// Subscribe counts the device actions published on bus while the report
+// is on. Only action names are counted, never their payloads.
+func (r *Reporter) Subscribe(bus *events.Bus) {
+	bus.Subscribe(func(e events.Event) {
+		if !r.cfg.GetTelemetry().Enabled {
+			return
+		}
+		r.mu.Lock()
+		r.st.Actions[e.Name]++
+		r.dirty = true
+		r.mu.Unlock()
+	}, events.TypeAction)
+}
+
+// endpoint returns where reports go; "" = nowhere.
+func (r *Reporter) endpoint() string {
+	if e := r.cfg.GetTelemetry().Endpoint; e != "" {
+		return e
+	}
+	return r.defaultEndpoint
+}
+
+// Status returns the report's settings and the report as it would be
+// sent now.
+func (r *Reporter) Status() Status {
+	r.mu.Lock()
+	lastSent := r.st.LastSent
+	r.mu.Unlock()
+	return Status{
+		Enabled:  r.cfg.GetTelemetry().Enabled,
+		Endpoint: r.endpoint(),
+		LastSent: lastSent,
+		Next:     r.Build(),
+	}
+}
+
+// Build assembles the report from the config, usage statistics and the
+// actions counted since the last report.
+func (r *Reporter) Build() Report {
+	dev := r.cfg.GetDevice()
+	backend := dev.Backend
+	switch {
+	case dev.Bridge.Address != "":
+		backend = "bridge"
+	case backend == "":
+		backend = "libusb"
+	}
+
+	r.mu.Lock()
+	actions := maps.Clone(r.st.Actions)
+	r.mu.Unlock()
+
+	rep := Report{
+		Schema:   schema,
+		Version:  r.version,
+		OS:       runtime.GOOS + "/" + runtime.GOARCH,
+		Firmware: r.cfg.GetGesture().Firmware,
+		Backend:  backend,
+		Features: features(r.cfg),
+		Actions:  actions,
+	}
+	if r.stats != nil {
+		week := stats.Sum(r.stats.Days(7))
+		rep.PTTSessions = week.PTTSessions
+		rep.PTTMinutes = int(math.Round(week.PTTSeconds / 60))
+		rep.Swipes = week.Swipes
+		rep.Taps = week.Taps
+		rep.Reconnects = week.Reconnects
+	}
+	return rep
+}
+
+// features lists the optional features turned on in cfg. Only whether a
+// feature is on is reported, never how it is set up.
+func features(cfg *config.Config) []string {
+	mode, _ := cfg.GetPTTMode()
+	on := []struct {
+		name string
+		on   bool
+	}{
+		{"ptt_mode_" + mode, mode != ""},
+		{"keep_awake", cfg.GetKeepAwake()},
+		{"mic_sync", cfg.GetMicSync()},
+		{"prevent_host_sleep", cfg.GetPreventHostSleep()},
+		{"media_passthrough", cfg.GetMediaPassthrough()},
+		{"overlay", cfg.GetOverlay().Enabled},
+		{"game_mode", cfg.GetGameMode().Enabled},
+		{"lan", cfg.GetLAN().Enabled},
+		{"event_log", cfg.GetEventLog().Enabled},
+		{"pedal", cfg.GetPedal().Enabled},
+		{"notify_wake", cfg.GetNotifyWake().Enabled},
+		{"openrgb", cfg.GetOpenRGB().Enabled},
+		{"composite_hid", cfg.GetDevice().CompositeHID},
+		{"taskbar_menu", cfg.GetTaskbarMenu()},
+		{"toast_actions", cfg.GetToastActions()},
+		{"macros", len(cfg.GetMacros()) > 0},
+		{"context_rules", len(cfg.GetContextRules()) > 0},
+		{"disable_hotkeys", cfg.GetDisableHotkeys()},
+	}
+	names := []string{}
+	for _, f := range on {
+		if f.on {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// Run sends the report whenever it is on and a week has passed since the
+// last one, until ctx is done.
+func (r *Reporter) Run(ctx context.Context) {
+	t := time.NewTimer(firstCheck)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		t.Reset(checkEvery)
+
+		if !r.cfg.GetTelemetry().Enabled {
+			continue
+		}
+		endpoint := r.endpoint()
+		r.mu.Lock()
+		due := time.Since(r.st.LastSent) >= interval
+		r.mu.Unlock()
+		if endpoint == "" || !due {
+			continue
+		}
+		if err := r.send(ctx, endpoint); err != nil {
+			logging.Warnf("[telemetry] send report: %v", err)
+		}
+	}
+}
+
+// send posts the report to endpoint and, once it is accepted, starts
+// counting afresh.
+func (r *Reporter) send(ctx context.Context, endpoint string) error {
+	rep := r.Build()
+	body, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "R1-Control/"+r.version)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s answered %s", endpoint, resp.Status)
+	}
+
+	r.mu.Lock()
+	r.st.LastSent = time.Now()
+	for name, n := range rep.Actions {
+		if r.st.Actions[name] -= n; r.st.Actions[name] <= 0 {
+			delete(r.st.Actions, name)
+		}
+	}
+	r.dirty = true
+	r.mu.Unlock()
+	logging.Debugf("[telemetry] report sent to %s", endpoint)
+	return r.Save()
+}
+
+// Reset forgets the actions counted so far, e.g. when the report is
+// turned off.
+func (r *Reporter) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.st.Actions) > 0 {
+		clear(r.st.Actions)
+		r.dirty = true
+	}
+}
+
+// Save writes the reporter's state if it changed.
+func (r *Reporter) Save() error {
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(r.st, "", "  ")
+	r.dirty = false
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("marshal telemetry state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("create telemetry dir: %w", err)
+	}
+	if err := safefile.Write(r.path, data); err != nil {
+		return fmt.Errorf("save telemetry state: %w", err)
+	}
+	return nil
+}
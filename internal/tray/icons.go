@@ -10,3 +10,6 @@ var IconConnected []byte
 
 //go:embed assets/active.png
 var IconActive []byte
+
+//go:embed assets/warning.png
+var IconWarning []byte
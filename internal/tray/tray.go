@@ -2,27 +2,169 @@
 package tray
 
 import (
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"sort"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/HopIT-Hub/R1-Control/internal/device"
+	"github.com/HopIT-Hub/R1-Control/internal/events"
+	"github.com/HopIT-Hub/R1-Control/internal/selftest"
 
 	"fyne.io/systray"
 )
 
 // RunOpts configures the system tray.
 type RunOpts struct {
-	Version          string // app version string (e.g., "1.0.0")
-	AutoStartEnabled bool   // initial state of "Start on Login" checkbox
-	KeepAwakeEnabled bool   // initial state of "Keep Awake" checkbox
-	OnReady          func()
-	OnSettings       func()
-	OnAutoStart      func(enabled bool) // called when user toggles auto-start
-	OnKeepAwake      func(enabled bool) // called when user toggles keep-awake
-	OnQuit           func()
+	Version             string // app version string (e.g., "1.0.0")
+	AutoStartEnabled    bool   // initial state of "Start on Login" checkbox
+	KeepAwakeEnabled    bool   // initial state of "Keep Awake" checkbox
+	OverlayEnabled      bool   // initial state of "PTT Overlay" checkbox
+	MicSyncEnabled      bool   // initial state of "Sync Host Mic" checkbox
+	PreventSleep        bool   // initial state of "Prevent Host Sleep" checkbox
+	MediaPassthrough    bool   // initial state of "R1 Media Keys" checkbox
+	PTTHotkeyEnabled    bool   // initial state of "PTT Hotkey" checkbox
+	SwipeHotkeyEnabled  bool   // initial state of "Swipe Hotkey" checkbox
+	PromptHotkeyEnabled bool   // initial state of "Prompt Hotkey" checkbox
+	HotkeysPaused       bool   // initial state of "Pause Hotkeys" checkbox
+	OnReady             func()
+	OnSettings          func()
+	OnAutoStart         func(enabled bool) // called when user toggles auto-start
+	OnKeepAwake         func(enabled bool) // called when user toggles keep-awake
+	OnOverlay           func(enabled bool) // called when user toggles the PTT overlay
+	OnMicSync           func(enabled bool) // called when user toggles host mic sync
+	OnPreventSleep      func(enabled bool) // called when user toggles blocking host sleep
+	OnMediaPassthrough  func(enabled bool) // called when user toggles sending media keys to the R1
+	OnFocus             func(enabled bool) // called when user starts/stops the focus timer
+	OnPTTHotkey         func(enabled bool) // called when user enables/disables the PTT hotkey
+	OnSwipeHotkey       func(enabled bool) // called when user enables/disables the swipe hotkey
+	OnPromptHotkey      func(enabled bool) // called when user enables/disables the prompt hotkey
+	OnPauseHotkeys      func(paused bool)  // called when user pauses/resumes all hotkeys
+	OnReconnect         func()             // called when user asks for an immediate reconnect
+	OnSelfTest          func()             // called when user runs the hardware self test
+	OnWhatsNew          func()             // called when user opens the release notes after an update
+	OnOpenApp           func(name string)  // called when user picks a launcher app
+	OnRestart           func()             // called before the tray exits for a restart
+	OnQuit              func()
+
+	// PTTMode reports how PTT is active: "latched", "held" or "" when
+	// off. Polled every second for the latched PTT line.
+	PTTMode func() string
+
+	// PTTDeadline returns when a latched PTT will be auto-released
+	// (zero time if none). Used for the countdown.
+	PTTDeadline func() time.Time
+
+	// Apps are the launcher apps listed under "Open App", which is
+	// hidden if there are none.
+	Apps []string
+
+	// LatchedSince returns when PTT was latched on (zero time if it
+	// isn't). Polled with PTTMode for the menu bar timer.
+	LatchedSince func() time.Time
+
+	// MenuBarTimer shows how long PTT has been latched next to the icon
+	// in the macOS menu bar, e.g. "🎙 0:42".
+	MenuBarTimer bool
+}
+
+// Tray is the system tray icon and menu. Its Set methods can be called
+// from any goroutine, before or after Run: each queues an update, and a
+// single goroutine applies them in order, so the menu is only touched
+// from there.
+type Tray struct {
+	updates  chan func()
+	headless atomic.Bool   // RunHeadless instead of Run
+	quit     chan struct{} // ends RunHeadless
+
+	// Owned by the update goroutine
+	items       *menuItems // nil until Run has built the menu
+	state       device.State
+	info        device.Info // connected R1; zero until one connects
+	lastSeen    time.Time   // zero = never connected
+	problems    map[string]string
+	lastErr     string // most recent problem, kept after it clears
+	lastErrAt   time.Time
+	linkWarning string
+	suspended   string // foreground app that suspended the hotkeys
+	pairing     string // menu title for the pairing PIN; "" = hidden
+	whatsNew    string // version with unseen release notes; "" = none
+	selfTest    []selftest.Check
+	selfTestRan bool
+	pttMode     string    // "latched", "held" or ""
+	deadline    time.Time // latched PTT auto-release; zero = none
+	since       time.Time // when PTT was latched; zero = not latched
+	barTimer    bool      // show since in the menu bar
+	focusPhase  string    // focus timer period, e.g. "work"; "" = stopped
+	focusUntil  string    // "HH:MM" the period ends
+	toggles     *Toggles  // set since the menu was built; nil = as in RunOpts
+	profile     string    // profile last switched in; "" = none
+}
+
+// menuItems are the menu lines that change while the app runs.
+type menuItems struct {
+	status      *systray.MenuItem
+	device      *systray.MenuItem
+	ptt         *systray.MenuItem
+	lastSeen    *systray.MenuItem
+	suspended   *systray.MenuItem
+	linkWarning *systray.MenuItem
+	problem     *systray.MenuItem
+	lastErr     *systray.MenuItem
+	pairing     *systray.MenuItem
+	whatsNew    *systray.MenuItem
+	selfTest    *systray.MenuItem
+	focus       *systray.MenuItem // checkbox, checked while the timer runs
+	focusLine   *systray.MenuItem
+	profile     *systray.MenuItem
+
+	// Checkboxes for settings that can change outside the menu
+	keepAwake    *systray.MenuItem
+	micSync      *systray.MenuItem
+	preventSleep *systray.MenuItem
+	media        *systray.MenuItem
+}
+
+// Toggles are the settings shown as checkboxes that can change outside
+// the menu, e.g. when a profile is switched in.
+type Toggles struct {
+	KeepAwake        bool
+	MicSync          bool
+	PreventSleep     bool
+	MediaPassthrough bool
+}
+
+// updateQueue is how many updates can wait before Set methods block.
+const updateQueue = 64
+
+// New returns a tray and starts applying its updates. Call Run to show
+// it.
+func New() *Tray {
+	t := &Tray{
+		updates:  make(chan func(), updateQueue),
+		quit:     make(chan struct{}, 1),
+		problems: map[string]string{},
+	}
+	go func() {
+		for fn := range t.updates {
+			fn()
+		}
+	}()
+	return t
+}
+
+// do queues fn to run on the update goroutine.
+func (t *Tray) do(fn func()) {
+	t.updates <- fn
 }
 
 // Run starts the system tray. It blocks on the main thread.
-func Run(opts RunOpts) {
+func (t *Tray) Run(opts RunOpts) {
 	systray.Run(func() {
 		systray.SetIcon(IconDisconnected)
 		systray.SetTitle("")
@@ -35,29 +177,94 @@ func Run(opts RunOpts) {
 		}
 		mVersion := systray.AddMenuItem(versionLabel, "")
 		mVersion.Disable()
+		mWhatsNew := systray.AddMenuItem("", "See what changed in this update")
+		mWhatsNew.Hide()
 
 		systray.AddSeparator()
 
 		mSettings := systray.AddMenuItem("Settings...", "Configure hotkeys")
 		mAutoStart := systray.AddMenuItemCheckbox("Start on Login", "Launch automatically on login", opts.AutoStartEnabled)
 		mKeepAwake := systray.AddMenuItemCheckbox("Keep Awake", "Prevent R1 from sleeping while docked", opts.KeepAwakeEnabled)
+		mOverlay := systray.AddMenuItemCheckbox("PTT Overlay", "Show an on-screen indicator while PTT is active", opts.OverlayEnabled)
+		mMicSync := systray.AddMenuItemCheckbox("Sync Host Mic", "Mute this computer's microphone while PTT is off", opts.MicSyncEnabled)
+		mPreventSleep := systray.AddMenuItemCheckbox("Prevent Host Sleep", "Keep this computer awake while PTT is on or a macro runs", opts.PreventSleep)
+		mMedia := systray.AddMenuItemCheckbox("R1 Media Keys", "Send this computer's volume and play/pause keys to the R1", opts.MediaPassthrough)
+		mFocus := systray.AddMenuItemCheckbox("Focus Timer", "Work and break periods, with R1 actions as each begins", false)
+		if len(opts.Apps) > 0 {
+			mApps := systray.AddMenuItem("Open App", "Open an app from the R1's launcher")
+			for _, name := range opts.Apps {
+				item := mApps.AddSubMenuItem(name, "Open "+name+" on the R1")
+				go func() {
+					for range item.ClickedCh {
+						if opts.OnOpenApp != nil {
+							go opts.OnOpenApp(name)
+						}
+					}
+				}()
+			}
+		}
 
 		systray.AddSeparator()
 
-		mStatus := systray.AddMenuItem("Status: Disconnected", "")
-		mStatus.Disable()
+		mPTTHotkey := systray.AddMenuItemCheckbox("PTT Hotkey", "Enable the push-to-talk hotkey", opts.PTTHotkeyEnabled)
+		mSwipeHotkey := systray.AddMenuItemCheckbox("Swipe Hotkey", "Enable the swipe hotkey", opts.SwipeHotkeyEnabled)
+		mPromptHotkey := systray.AddMenuItemCheckbox("Prompt Hotkey", "Enable the hotkey that opens the ask rabbit prompt", opts.PromptHotkeyEnabled)
+		mPauseHotkeys := systray.AddMenuItemCheckbox("Pause Hotkeys", "Temporarily turn off all global hotkeys", opts.HotkeysPaused)
 
 		systray.AddSeparator()
 
+		// Status lines, updated by the Set methods
+		items := &menuItems{
+			status:      systray.AddMenuItem("Status: Disconnected", ""),
+			device:      systray.AddMenuItem("", "The connected R1"),
+			ptt:         systray.AddMenuItem("", "Press the PTT hotkey again to release"),
+			lastSeen:    systray.AddMenuItem("", "When an R1 was last connected"),
+			suspended:   systray.AddMenuItem("", "Hotkeys are suspended while this app is in the foreground"),
+			linkWarning: systray.AddMenuItem("", ""),
+			problem:     systray.AddMenuItem("", ""),
+			lastErr:     systray.AddMenuItem("", "The most recent error, even if it has cleared"),
+			pairing:     systray.AddMenuItem("", "Enter this PIN on the device being paired"),
+			focusLine:   systray.AddMenuItem("", "Click Focus Timer to stop it"),
+			profile:     systray.AddMenuItem("", "Switched in by a context rule or the settings API"),
+		}
+		for _, item := range []*systray.MenuItem{items.status, items.device, items.ptt, items.lastSeen,
+			items.suspended, items.linkWarning, items.problem, items.lastErr, items.pairing, items.focusLine, items.profile} {
+			item.Disable()
+			item.Hide()
+		}
+		items.status.Show()
+		items.whatsNew = mWhatsNew
+		items.focus = mFocus
+		items.keepAwake, items.micSync, items.preventSleep, items.media = mKeepAwake, mMicSync, mPreventSleep, mMedia
+
+		systray.AddSeparator()
+
+		mReconnect := systray.AddMenuItem("Reconnect Now", "Reconnect to the R1 without waiting")
+		mSelfTest := systray.AddMenuItem("Run Self Test", "Check the R1 connection, hotkeys and settings server")
+		items.selfTest = systray.AddMenuItem("", "")
+		items.selfTest.Disable()
+		items.selfTest.Hide()
+
+		systray.AddSeparator()
+
+		mRestart := systray.AddMenuItem("Restart", "Restart R1 Control")
 		mQuit := systray.AddMenuItem("Quit", "Exit R1 Control")
 
-		// Store status item for updates
-		statusItem = mStatus
+		// Show everything set before the menu existed
+		t.do(func() {
+			t.items = items
+			t.barTimer = opts.MenuBarTimer && opts.LatchedSince != nil && runtime.GOOS == "darwin"
+			t.refreshAll()
+		})
 
 		if opts.OnReady != nil {
 			opts.OnReady()
 		}
 
+		if opts.PTTMode != nil && opts.PTTDeadline != nil {
+			go t.pollPTT(opts.PTTMode, opts.PTTDeadline, opts.LatchedSince)
+		}
+
 		go func() {
 			for {
 				select {
@@ -66,29 +273,46 @@ func Run(opts RunOpts) {
 						opts.OnSettings()
 					}
 				case <-mAutoStart.ClickedCh:
-					if mAutoStart.Checked() {
-						mAutoStart.Uncheck()
-						if opts.OnAutoStart != nil {
-							opts.OnAutoStart(false)
-						}
-					} else {
-						mAutoStart.Check()
-						if opts.OnAutoStart != nil {
-							opts.OnAutoStart(true)
-						}
-					}
+					toggleCheckbox(mAutoStart, opts.OnAutoStart)
 				case <-mKeepAwake.ClickedCh:
-					if mKeepAwake.Checked() {
-						mKeepAwake.Uncheck()
-						if opts.OnKeepAwake != nil {
-							opts.OnKeepAwake(false)
-						}
-					} else {
-						mKeepAwake.Check()
-						if opts.OnKeepAwake != nil {
-							opts.OnKeepAwake(true)
-						}
+					toggleCheckbox(mKeepAwake, opts.OnKeepAwake)
+				case <-mOverlay.ClickedCh:
+					toggleCheckbox(mOverlay, opts.OnOverlay)
+				case <-mMicSync.ClickedCh:
+					toggleCheckbox(mMicSync, opts.OnMicSync)
+				case <-mPreventSleep.ClickedCh:
+					toggleCheckbox(mPreventSleep, opts.OnPreventSleep)
+				case <-mMedia.ClickedCh:
+					toggleCheckbox(mMedia, opts.OnMediaPassthrough)
+				case <-mFocus.ClickedCh:
+					toggleCheckbox(mFocus, opts.OnFocus)
+				case <-mPTTHotkey.ClickedCh:
+					toggleCheckbox(mPTTHotkey, opts.OnPTTHotkey)
+				case <-mSwipeHotkey.ClickedCh:
+					toggleCheckbox(mSwipeHotkey, opts.OnSwipeHotkey)
+				case <-mPromptHotkey.ClickedCh:
+					toggleCheckbox(mPromptHotkey, opts.OnPromptHotkey)
+				case <-mPauseHotkeys.ClickedCh:
+					toggleCheckbox(mPauseHotkeys, opts.OnPauseHotkeys)
+				case <-mWhatsNew.ClickedCh:
+					t.SetWhatsNew("")
+					if opts.OnWhatsNew != nil {
+						opts.OnWhatsNew()
+					}
+				case <-mReconnect.ClickedCh:
+					if opts.OnReconnect != nil {
+						opts.OnReconnect()
+					}
+				case <-mSelfTest.ClickedCh:
+					if opts.OnSelfTest != nil {
+						t.SetSelfTestResult(nil)
+						go opts.OnSelfTest()
+					}
+				case <-mRestart.ClickedCh:
+					if opts.OnRestart != nil {
+						opts.OnRestart()
 					}
+					systray.Quit()
 				case <-mQuit.ClickedCh:
 					if opts.OnQuit != nil {
 						opts.OnQuit()
@@ -102,33 +326,534 @@ func Run(opts RunOpts) {
 	})
 }
 
-var statusItem *systray.MenuItem
+// RunHeadless runs the app without a tray icon, e.g. where there is no
+// desktop tray: it calls OnReady, then blocks until the process is
+// interrupted or terminated, which calls OnQuit, or until Quit. Only
+// OnReady and OnQuit of opts are used.
+func (t *Tray) RunHeadless(opts RunOpts) {
+	t.headless.Store(true)
+	if opts.OnReady != nil {
+		opts.OnReady()
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	select {
+	case <-sig:
+		if opts.OnQuit != nil {
+			opts.OnQuit()
+		}
+	case <-t.quit:
+	}
+}
 
-// SetState updates the tray icon and tooltip based on device state.
-func SetState(state device.State) {
-	switch state {
-	case device.Disconnected:
-		systray.SetIcon(IconDisconnected)
-		systray.SetTooltip("R1 Control — No device")
-		if statusItem != nil {
-			statusItem.SetTitle("Status: Disconnected")
+// toggleCheckbox flips a checkbox menu item and reports its new state.
+func toggleCheckbox(item *systray.MenuItem, cb func(enabled bool)) {
+	if item.Checked() {
+		item.Uncheck()
+	} else {
+		item.Check()
+	}
+	if cb != nil {
+		cb(item.Checked())
+	}
+}
+
+// setChecked checks or unchecks item.
+func setChecked(item *systray.MenuItem, on bool) {
+	if on {
+		item.Check()
+	} else {
+		item.Uncheck()
+	}
+}
+
+// showLine sets item's title and shows it, or hides it when title is
+// empty.
+func showLine(item *systray.MenuItem, title string) {
+	if title == "" {
+		item.Hide()
+		return
+	}
+	item.SetTitle(title)
+	item.Show()
+}
+
+// refreshAll redraws the menu, icon and tooltip.
+func (t *Tray) refreshAll() {
+	t.refreshState()
+	t.refreshDevice()
+	t.refreshProblems()
+	t.refreshLinkWarning()
+	t.refreshSuspended()
+	t.refreshPairing()
+	t.refreshWhatsNew()
+	t.refreshSelfTest()
+	t.refreshFocus()
+	t.refreshToggles()
+	t.refreshProfile()
+}
+
+// Subscribe shows the device state, connected R1, link warnings, problems,
+// game mode, the focus timer and profile switches published on bus in
+// the menu and icon.
+func (t *Tray) Subscribe(bus *events.Bus) {
+	bus.Subscribe(func(e events.Event) {
+		switch e.Type {
+		case events.TypeState:
+			if state, ok := e.Value.(device.State); ok {
+				t.SetState(state)
+			}
+		case events.TypeConnect:
+			if info, ok := e.Value.(device.Info); ok {
+				t.SetDevice(info)
+			}
+			t.SetLastSeen(e.Time)
+		case events.TypeLink:
+			t.SetLinkWarning(e.Detail)
+		case events.TypeProblem:
+			t.SetProblem(e.Name, e.Detail)
+		case events.TypeGameMode:
+			t.SetHotkeysSuspended(e.Detail) // "" when resumed
+		case events.TypeFocus:
+			if e.Name == "done" || e.Name == "stopped" {
+				t.SetFocus("", "")
+			} else {
+				t.SetFocus(e.Name, e.Detail)
+			}
+		case events.TypeProfile:
+			t.SetProfile(e.Name)
 		}
-	case device.Connected:
+	}, events.TypeState, events.TypeConnect, events.TypeLink, events.TypeProblem, events.TypeGameMode, events.TypeFocus, events.TypeProfile)
+}
+
+// SetToggles checks or unchecks the setting checkboxes to match tg.
+func (t *Tray) SetToggles(tg Toggles) {
+	t.do(func() {
+		t.toggles = &tg
+		t.refreshToggles()
+	})
+}
+
+func (t *Tray) refreshToggles() {
+	if t.items == nil || t.toggles == nil {
+		return
+	}
+	setChecked(t.items.keepAwake, t.toggles.KeepAwake)
+	setChecked(t.items.micSync, t.toggles.MicSync)
+	setChecked(t.items.preventSleep, t.toggles.PreventSleep)
+	setChecked(t.items.media, t.toggles.MediaPassthrough)
+}
+
+// SetProfile shows the profile last switched in; "" hides the line.
+func (t *Tray) SetProfile(name string) {
+	t.do(func() {
+		t.profile = name
+		t.refreshProfile()
+	})
+}
+
+func (t *Tray) refreshProfile() {
+	if t.items == nil {
+		return
+	}
+	title := ""
+	if t.profile != "" {
+		title = "Profile: " + t.profile
+	}
+	showLine(t.items.profile, title)
+}
+
+// SetFocus shows the focus timer's current period ("work", "break" or
+// "long_break") and when it ends, or that the timer is stopped when
+// phase is empty.
+func (t *Tray) SetFocus(phase, until string) {
+	t.do(func() {
+		t.focusPhase, t.focusUntil = phase, until
+		t.refreshFocus()
+	})
+}
+
+// refreshFocus updates the focus timer checkbox and line.
+func (t *Tray) refreshFocus() {
+	if t.items == nil {
+		return
+	}
+	if t.focusPhase == "" {
+		t.items.focus.Uncheck()
+		t.items.focusLine.Hide()
+		return
+	}
+	t.items.focus.Check()
+	if t.focusPhase == "work" {
+		showLine(t.items.focusLine, "Focus: working until "+t.focusUntil)
+	} else {
+		showLine(t.items.focusLine, "Focus: break until "+t.focusUntil)
+	}
+}
+
+// SetProblem shows an error from source (e.g. "ptt_hotkey", "device") in
+// the menu and switches to the warning icon; an empty msg clears it. Only
+// the first line of msg is shown in the menu; the tooltip has everything.
+// The most recent error stays on the "Last error" line after it clears.
+func (t *Tray) SetProblem(source, msg string) {
+	now := time.Now()
+	t.do(func() {
+		if msg == "" {
+			delete(t.problems, source)
+		} else {
+			t.problems[source] = msg
+			t.lastErr, t.lastErrAt = msg, now
+		}
+		t.refreshProblems()
+	})
+}
+
+// refreshProblems updates the problem and last error lines and the icon.
+func (t *Tray) refreshProblems() {
+	t.setIcon()
+	if t.items == nil {
+		return
+	}
+
+	var msgs []string
+	for _, m := range t.problems {
+		msgs = append(msgs, m)
+	}
+	sort.Strings(msgs)
+	if len(msgs) == 0 {
+		t.items.problem.Hide()
+	} else {
+		title := firstLine(msgs[0])
+		if len(msgs) > 1 {
+			title += fmt.Sprintf(" (+%d more)", len(msgs)-1)
+		}
+		t.items.problem.SetTitle("⚠ " + title)
+		t.items.problem.SetTooltip(strings.Join(msgs, "\n"))
+		t.items.problem.Show()
+	}
+
+	// Only once it has cleared; while active it is on the problem line
+	active := false
+	for _, m := range msgs {
+		active = active || m == t.lastErr
+	}
+	if t.lastErr == "" || active {
+		t.items.lastErr.Hide()
+		return
+	}
+	t.items.lastErr.SetTitle(fmt.Sprintf("Last error %s: %s", formatLastSeen(t.lastErrAt, time.Now()), firstLine(t.lastErr)))
+	t.items.lastErr.SetTooltip(t.lastErr)
+	t.items.lastErr.Show()
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	return strings.SplitN(s, "\n", 2)[0]
+}
+
+// setIcon picks the tray icon. Problems show the warning icon, except
+// while talking, where the active icon matters more. Nothing is drawn
+// before Run has built the menu.
+func (t *Tray) setIcon() {
+	if t.items == nil {
+		return
+	}
+	switch {
+	case t.state == device.PTTActive:
+		systray.SetIcon(IconActive)
+	case len(t.problems) > 0:
+		systray.SetIcon(IconWarning)
+	case t.state == device.Connected:
 		systray.SetIcon(IconConnected)
-		systray.SetTooltip("R1 Control — Ready")
-		if statusItem != nil {
-			statusItem.SetTitle("Status: Connected")
+	default:
+		systray.SetIcon(IconDisconnected)
+	}
+}
+
+// SetLinkWarning shows a dock/cable health warning in the menu, or hides
+// it when warning is empty.
+func (t *Tray) SetLinkWarning(warning string) {
+	t.do(func() {
+		t.linkWarning = warning
+		t.refreshLinkWarning()
+	})
+}
+
+func (t *Tray) refreshLinkWarning() {
+	if t.items == nil {
+		return
+	}
+	if t.linkWarning == "" {
+		t.items.linkWarning.Hide()
+		return
+	}
+	t.items.linkWarning.SetTitle("⚠ " + t.linkWarning)
+	t.items.linkWarning.SetTooltip("Try a different cable, USB port or dock")
+	t.items.linkWarning.Show()
+}
+
+// SetDevice names the connected R1 in the menu. The line is shown while
+// it is connected.
+func (t *Tray) SetDevice(info device.Info) {
+	t.do(func() {
+		t.info = info
+		t.refreshDevice()
+	})
+}
+
+// SetLastSeen records when an R1 was last connected (zero = never). It
+// is shown in the menu while no device is connected.
+func (t *Tray) SetLastSeen(when time.Time) {
+	t.do(func() {
+		t.lastSeen = when
+		t.refreshDevice()
+	})
+}
+
+// refreshDevice updates the device line while an R1 is connected, or
+// the "Last seen" line while none is.
+func (t *Tray) refreshDevice() {
+	if t.items == nil {
+		return
+	}
+	if t.state.Online() {
+		t.items.lastSeen.Hide()
+		name := t.info.Product
+		if name == "" {
+			name = "Rabbit R1"
+		}
+		if t.info.Serial != "" {
+			name += " · " + t.info.Serial
+		}
+		showLine(t.items.device, "Device: "+name)
+		return
+	}
+
+	t.items.device.Hide()
+	if t.lastSeen.IsZero() {
+		t.items.lastSeen.SetTitle("No R1 detected yet")
+	} else {
+		t.items.lastSeen.SetTitle("Last seen: " + formatLastSeen(t.lastSeen, time.Now()))
+	}
+	t.items.lastSeen.Show()
+}
+
+// formatLastSeen formats t relative to now, e.g. "today 14:32",
+// "yesterday 09:05" or "Mar 3 18:20".
+func formatLastSeen(t, now time.Time) string {
+	t = t.Local()
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	switch {
+	case !t.Before(today):
+		return "today " + t.Format("15:04")
+	case !t.Before(today.AddDate(0, 0, -1)):
+		return "yesterday " + t.Format("15:04")
+	case t.Year() == now.Year():
+		return t.Format("Jan 2 15:04")
+	default:
+		return t.Format("Jan 2 2006")
+	}
+}
+
+// SetPairingPIN shows the PIN a remote client named name must enter to
+// pair, or hides the line when pin is empty.
+func (t *Tray) SetPairingPIN(name, pin string) {
+	t.do(func() {
+		t.pairing = ""
+		if pin != "" {
+			t.pairing = fmt.Sprintf("Pair %q: PIN %s", name, pin)
+		}
+		t.refreshPairing()
+	})
+}
+
+func (t *Tray) refreshPairing() {
+	if t.items != nil {
+		showLine(t.items.pairing, t.pairing)
+	}
+}
+
+// SetWhatsNew shows a "What's new" entry for version after an update,
+// or hides it when version is empty.
+func (t *Tray) SetWhatsNew(version string) {
+	t.do(func() {
+		t.whatsNew = version
+		t.refreshWhatsNew()
+	})
+}
+
+func (t *Tray) refreshWhatsNew() {
+	if t.items == nil {
+		return
+	}
+	title := ""
+	if t.whatsNew != "" {
+		title = "What's new in v" + strings.TrimPrefix(t.whatsNew, "v")
+	}
+	showLine(t.items.whatsNew, title)
+}
+
+// SetSelfTestResult shows the outcome of a self test, with the full
+// checklist in the tooltip. nil checks mean a self test is running.
+func (t *Tray) SetSelfTestResult(checks []selftest.Check) {
+	t.do(func() {
+		t.selfTest = checks
+		t.selfTestRan = true
+		t.refreshSelfTest()
+	})
+}
+
+func (t *Tray) refreshSelfTest() {
+	if t.items == nil || !t.selfTestRan {
+		return
+	}
+	item := t.items.selfTest
+	if t.selfTest == nil {
+		item.SetTitle("Self test running…")
+		item.SetTooltip("")
+		item.Show()
+		return
+	}
+
+	var lines []string
+	for _, c := range t.selfTest {
+		line := "✓ " + c.Name
+		if !c.OK {
+			line = "✗ " + c.Name
 		}
+		if c.Detail != "" {
+			line += " — " + c.Detail
+		}
+		lines = append(lines, line)
+	}
+	title := selftest.Summary(t.selfTest)
+	if !selftest.Passed(t.selfTest) {
+		title = "⚠ " + title
+	}
+	item.SetTitle(title)
+	item.SetTooltip(strings.Join(lines, "\n"))
+	item.Show()
+}
+
+// SetHotkeysSuspended shows which foreground app has suspended the global
+// hotkeys, or hides the line when app is empty.
+func (t *Tray) SetHotkeysSuspended(app string) {
+	t.do(func() {
+		t.suspended = app
+		t.refreshSuspended()
+	})
+}
+
+func (t *Tray) refreshSuspended() {
+	if t.items == nil {
+		return
+	}
+	title := ""
+	if t.suspended != "" {
+		title = "Hotkeys suspended: " + t.suspended
+	}
+	showLine(t.items.suspended, title)
+}
+
+// pollPTT shows whether PTT is latched, refreshing the auto-release
+// countdown and the menu bar timer every second. since may be nil. The
+// device is asked from this goroutine rather than the update goroutine,
+// which must never wait on the device.
+func (t *Tray) pollPTT(mode func() string, deadline, since func() time.Time) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		m, d := mode(), deadline()
+		var s time.Time
+		if since != nil {
+			s = since()
+		}
+		t.do(func() {
+			if m == t.pttMode && d.IsZero() && t.deadline.IsZero() && s.IsZero() && t.since.IsZero() {
+				return // nothing to count
+			}
+			t.pttMode, t.deadline, t.since = m, d, s
+			t.refreshState()
+		})
+	}
+}
+
+// SetState updates the tray icon, tooltip and status lines based on
+// device state.
+func (t *Tray) SetState(state device.State) {
+	t.do(func() {
+		t.state = state
+		if state != device.PTTActive {
+			t.pttMode, t.deadline, t.since = "", time.Time{}, time.Time{}
+		}
+		t.refreshState()
+		t.refreshDevice()
+	})
+}
+
+// refreshState updates the icon, tooltip, status line and latched PTT
+// line.
+func (t *Tray) refreshState() {
+	t.setIcon()
+
+	status, tooltip := "Disconnected", "No device"
+	switch t.state {
+	case device.Connecting, device.Registering:
+		status, tooltip = "Connecting…", "Connecting…"
+	case device.Backoff:
+		status, tooltip = "Retrying…", "Connection failed, retrying"
+	case device.Error:
+		status, tooltip = "Connection Error", "Connection failed — see Settings"
+	case device.Connected:
+		status, tooltip = "Connected", "Ready"
 	case device.PTTActive:
-		systray.SetIcon(IconActive)
-		systray.SetTooltip("R1 Control — TALKING")
-		if statusItem != nil {
-			statusItem.SetTitle("Status: PTT Active")
+		status, tooltip = "PTT Active", "TALKING"
+	}
+
+	latched := ""
+	if t.state == device.PTTActive && t.pttMode == "latched" {
+		latched = "PTT latched"
+		if !t.deadline.IsZero() {
+			left := max(time.Until(t.deadline).Round(time.Second), 0)
+			countdown := fmt.Sprintf("auto-release in %d:%02d", int(left.Minutes()), int(left.Seconds())%60)
+			latched += " — " + countdown
+			tooltip += " (" + countdown + ")"
+		}
+	}
+
+	if t.items != nil {
+		systray.SetTooltip("R1 Control — " + tooltip)
+		t.items.status.SetTitle("Status: " + status)
+		showLine(t.items.ptt, latched)
+		if t.barTimer {
+			systray.SetTitle(t.barTitle())
 		}
 	}
 }
 
-// Quit stops the system tray.
-func Quit() {
+// barTitle returns the menu bar text: how long PTT has been latched, or
+// "" when it isn't.
+func (t *Tray) barTitle() string {
+	if t.state != device.PTTActive || t.pttMode != "latched" || t.since.IsZero() {
+		return ""
+	}
+	d := time.Since(t.since).Truncate(time.Second)
+	if d >= time.Hour {
+		return fmt.Sprintf("🎙 %d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("🎙 %d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// Quit stops the system tray, or ends RunHeadless.
+func (t *Tray) Quit() {
+	if t.headless.Load() {
+		select {
+		case t.quit <- struct{}{}:
+		default:
+		}
+		return
+	}
 	systray.Quit()
 }
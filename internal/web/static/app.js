@@ -3,10 +3,24 @@
 (function() {
     'use strict';
 
+    // Versioned API base path
+    const API = '/api/v1';
+
     const deviceStatus = document.getElementById('device-status');
+    const lastSeen = document.getElementById('last-seen');
+    const remoteSection = document.getElementById('remote-section');
+    const remoteLink = document.getElementById('remote-link');
+    const pairingPins = document.getElementById('pairing-pins');
+    const clientList = document.getElementById('client-list');
+    const wakeLinkRow = document.getElementById('wake-link-row');
+    const wakeLink = document.getElementById('wake-link');
+    const wakeRotateBtn = document.getElementById('wake-rotate-btn');
+    const linkWarning = document.getElementById('link-warning');
+    const problemsList = document.getElementById('problems');
     const currentHotkey = document.getElementById('current-hotkey');
     const currentSwipeHotkey = document.getElementById('current-swipe-hotkey');
     const recordBtn = document.getElementById('record-btn');
+    const captureBtn = document.getElementById('capture-btn');
     const recordingOverlay = document.getElementById('recording-overlay');
     const cancelBtn = document.getElementById('cancel-btn');
     const preview = document.getElementById('preview');
@@ -15,6 +29,7 @@
     const discardBtn = document.getElementById('discard-btn');
 
     const swipeRecordBtn = document.getElementById('swipe-record-btn');
+    const swipeCaptureBtn = document.getElementById('swipe-capture-btn');
     const swipeRecordingOverlay = document.getElementById('swipe-recording-overlay');
     const swipeCancelBtn = document.getElementById('swipe-cancel-btn');
     const swipePreview = document.getElementById('swipe-preview');
@@ -22,10 +37,51 @@
     const swipeSaveBtn = document.getElementById('swipe-save-btn');
     const swipeDiscardBtn = document.getElementById('swipe-discard-btn');
     const autostartToggle = document.getElementById('autostart-toggle');
+    const startPausedToggle = document.getElementById('start-paused-toggle');
+    const startDelaySelect = document.getElementById('start-delay-select');
     const keepawakeToggle = document.getElementById('keepawake-toggle');
     const sleepAfterSelect = document.getElementById('sleep-after-select');
     const sleepAfterRow = document.getElementById('sleep-after-row');
+    const dimToggle = document.getElementById('dim-toggle');
+    const dimRow = document.getElementById('dim-row');
+    const pttAutoReleaseSelect = document.getElementById('ptt-auto-release-select');
+    const pttModeSelect = document.getElementById('ptt-mode-select');
+    const toggleThresholdSelect = document.getElementById('toggle-threshold-select');
+    const toggleThresholdRow = document.getElementById('toggle-threshold-row');
+    const swipeModeSelect = document.getElementById('swipe-mode-select');
+    const swipeHoldSelect = document.getElementById('swipe-hold-select');
+    const swipeHoldRow = document.getElementById('swipe-hold-row');
+    const swipeResetSelect = document.getElementById('swipe-reset-select');
+    const swipeResetRow = document.getElementById('swipe-reset-row');
     const versionFooter = document.getElementById('version-footer');
+    const quickActionsPanel = document.getElementById('quick-actions');
+    const usageToday = document.getElementById('usage-today');
+    const usageWeek = document.getElementById('usage-week');
+    const telemetryToggle = document.getElementById('telemetry-toggle');
+    const telemetryDesc = document.getElementById('telemetry-desc');
+    const telemetryViewBtn = document.getElementById('telemetry-view-btn');
+    const telemetryPayload = document.getElementById('telemetry-payload');
+    const keepAwakeCalendar = document.getElementById('keepawake-calendar');
+    const keepAwakePeriods = document.getElementById('keepawake-periods');
+    const periodDays = document.getElementById('period-days');
+    const periodFrom = document.getElementById('period-from');
+    const periodTo = document.getElementById('period-to');
+    const periodSleep = document.getElementById('period-sleep');
+    const periodDim = document.getElementById('period-dim');
+    const periodAddBtn = document.getElementById('period-add-btn');
+    const macroList = document.getElementById('macro-list');
+    const macroName = document.getElementById('macro-name');
+    const macroSeconds = document.getElementById('macro-seconds');
+    const macroRecordBtn = document.getElementById('macro-record-btn');
+    const focusStatus = document.getElementById('focus-status');
+    const focusWork = document.getElementById('focus-work');
+    const focusBreak = document.getElementById('focus-break');
+    const macroRecordStatus = document.getElementById('macro-record-status');
+    const selfTestBtn = document.getElementById('self-test-btn');
+    const selfTestResults = document.getElementById('self-test-results');
+    const backupList = document.getElementById('backup-list');
+    const whatsNew = document.getElementById('whats-new');
+    const changelogList = document.getElementById('changelog');
 
     let pendingHotkey = null;
     let pendingSwipeHotkey = null;
@@ -33,23 +89,94 @@
     // --- Status polling ---
     async function pollStatus() {
         try {
-            const res = await fetch('/status');
+            const res = await fetch(API + '/status');
             const data = await res.json();
 
             // Update device status
-            deviceStatus.textContent = formatState(data.state);
+            deviceStatus.textContent = formatState(data.state) + (data.ptt ? ' (' + data.ptt + ')' : '');
             deviceStatus.className = 'status ' + data.state;
 
+            // When an R1 was last connected, shown while none is
+            if (lastSeen) {
+                if (data.state === 'connected' || data.state === 'ptt_active') {
+                    lastSeen.classList.add('hidden');
+                } else {
+                    lastSeen.textContent = data.last_seen
+                        ? 'Last seen: ' + new Date(data.last_seen.time).toLocaleString()
+                        : 'No R1 detected yet';
+                    lastSeen.classList.remove('hidden');
+                }
+            }
+
+            // Phone remote (LAN mode): address and PINs waiting to be entered
+            if (remoteSection) {
+                const wasHidden = remoteSection.classList.contains('hidden');
+                remoteSection.classList.toggle('hidden', !data.remote_url);
+                if (data.remote_url) {
+                    remoteLink.href = data.remote_url;
+                    remoteLink.textContent = data.remote_url;
+                    if (wasHidden) loadClients();
+                }
+                const pins = data.pairing || [];
+                const key = JSON.stringify(pins);
+                if (pairingPins._key !== key) {
+                    const hadPins = pairingPins._key && pairingPins._key !== '[]';
+                    pairingPins._key = key;
+                    pairingPins.innerHTML = '';
+                    pins.forEach(function(p) {
+                        const li = document.createElement('li');
+                        li.textContent = 'Pairing "' + p.name + '"' + (p.role === 'read' ? ' (read-only)' : '') + ': ';
+                        const pin = document.createElement('span');
+                        pin.className = 'pairing-pin';
+                        pin.textContent = p.pin;
+                        li.appendChild(pin);
+                        pairingPins.appendChild(li);
+                    });
+                    // A pairing finished or expired
+                    if (hadPins) loadClients();
+                }
+            }
+
+            // Dock/cable health warning
+            if (linkWarning) {
+                linkWarning.textContent = data.link_warning || '';
+                linkWarning.classList.toggle('hidden', !data.link_warning);
+            }
+
+            // Hotkey conflicts and device errors
+            if (problemsList) {
+                const problems = data.problems || [];
+                problemsList.innerHTML = '';
+                problems.forEach(function(p) {
+                    const li = document.createElement('li');
+                    li.textContent = p;
+                    problemsList.appendChild(li);
+                });
+                problemsList.classList.toggle('hidden', problems.length === 0);
+            }
+
+            // Hotkeys turned off entirely, e.g. on a kiosk without a desktop
+            document.querySelectorAll('.hotkey-section').forEach(function(el) {
+                el.classList.toggle('hidden', !!data.hotkeys_disabled);
+            });
+
             // Update hotkey displays
-            currentHotkey.textContent = data.hotkey;
+            const hotkeys = data.hotkeys || {};
+            currentHotkey.textContent = data.hotkey + hotkeySuffix(data.hotkey_enabled, hotkeys.ptt);
             if (currentSwipeHotkey) {
-                currentSwipeHotkey.textContent = data.swipe_hotkey;
+                currentSwipeHotkey.textContent = data.swipe_hotkey + hotkeySuffix(data.swipe_hotkey_enabled, hotkeys.swipe);
             }
 
             // Update autostart toggle
             if (autostartToggle && !autostartToggle._userChanging) {
                 autostartToggle.checked = data.auto_start;
             }
+            if (startPausedToggle && !startPausedToggle._userChanging) {
+                startPausedToggle.checked = data.auto_start_paused;
+            }
+            if (startDelaySelect && !startDelaySelect._userChanging) {
+                startDelaySelect.value = String(data.auto_start_delay_seconds);
+            }
 
             // Update keep-awake controls
             if (keepawakeToggle && !keepawakeToggle._userChanging) {
@@ -59,6 +186,35 @@
             if (sleepAfterSelect && !sleepAfterSelect._userChanging) {
                 sleepAfterSelect.value = String(data.sleep_after_minutes);
             }
+            if (dimToggle && !dimToggle._userChanging) {
+                dimToggle.checked = data.keep_awake_dim;
+            }
+
+            // Update PTT auto-release
+            if (pttAutoReleaseSelect && !pttAutoReleaseSelect._userChanging) {
+                pttAutoReleaseSelect.value = String(data.ptt_auto_release_minutes);
+            }
+
+            // Update PTT hotkey mode
+            if (pttModeSelect && !pttModeSelect._userChanging) {
+                pttModeSelect.value = data.ptt_mode || 'auto';
+                updateToggleThresholdVisibility(pttModeSelect.value);
+            }
+            if (toggleThresholdSelect && !toggleThresholdSelect._userChanging) {
+                toggleThresholdSelect.value = String(data.toggle_threshold_ms);
+            }
+
+            // Update swipe hotkey mode
+            if (swipeModeSelect && !swipeModeSelect._userChanging) {
+                swipeModeSelect.value = data.swipe_mode || 'alternate';
+                updateSwipeModeVisibility(swipeModeSelect.value);
+            }
+            if (swipeHoldSelect && !swipeHoldSelect._userChanging) {
+                swipeHoldSelect.value = String(data.swipe_hold_ms);
+            }
+            if (swipeResetSelect && !swipeResetSelect._userChanging) {
+                swipeResetSelect.value = String(data.swipe_reset_seconds);
+            }
 
             // Update version footer (once)
             if (versionFooter && data.version && !versionFooter.textContent) {
@@ -70,26 +226,594 @@
         }
     }
 
+    // hotkeySuffix flags a hotkey that is disabled, or enabled but not
+    // actually registered with the OS.
+    function hotkeySuffix(enabled, status) {
+        if (!enabled) return ' (disabled)';
+        if (status && !status.registered) return ' (not registered)';
+        return '';
+    }
+
     function formatState(state) {
         switch (state) {
             case 'disconnected': return 'Disconnected';
+            case 'connecting':
+            case 'registering': return 'Connecting…';
+            case 'backoff': return 'Retrying…';
+            case 'error': return 'Connection error — retrying…';
             case 'connected': return 'Connected';
             case 'ptt_active': return 'PTT Active';
             default: return state;
         }
     }
 
+    function updateToggleThresholdVisibility(mode) {
+        if (toggleThresholdRow) {
+            toggleThresholdRow.style.opacity = mode === 'auto' ? '1' : '0.4';
+            toggleThresholdRow.style.pointerEvents = mode === 'auto' ? 'auto' : 'none';
+        }
+    }
+
+    function updateSwipeModeVisibility(mode) {
+        [[swipeHoldRow, mode === 'hold'], [swipeResetRow, mode === 'alternate']].forEach(function(r) {
+            if (r[0]) {
+                r[0].style.opacity = r[1] ? '1' : '0.4';
+                r[0].style.pointerEvents = r[1] ? 'auto' : 'none';
+            }
+        });
+    }
+
     function updateSleepAfterVisibility(keepAwakeEnabled) {
-        if (sleepAfterRow) {
-            sleepAfterRow.style.opacity = keepAwakeEnabled ? '1' : '0.4';
-            sleepAfterRow.style.pointerEvents = keepAwakeEnabled ? 'auto' : 'none';
+        [sleepAfterRow, dimRow].forEach(function(row) {
+            if (!row) return;
+            row.style.opacity = keepAwakeEnabled ? '1' : '0.4';
+            row.style.pointerEvents = keepAwakeEnabled ? 'auto' : 'none';
+        });
+    }
+
+    // --- Paired remote clients ---
+    async function loadClients(body) {
+        try {
+            const res = await fetch(API + '/pair/clients', body ? {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(body)
+            } : undefined);
+            const data = await res.json();
+            if (data.error) {
+                showToast(data.error, true);
+                return;
+            }
+
+            wakeLinkRow.classList.toggle('hidden', !data.wake_url);
+            wakeLink.textContent = data.wake_url || '';
+
+            clientList.innerHTML = '';
+            (data.clients || []).forEach(function(c) {
+                const li = document.createElement('li');
+                const label = document.createElement('span');
+                label.textContent = c.name + (c.role === 'read' ? ', read-only' : '') +
+                    ' (paired ' + new Date(c.paired).toLocaleDateString() + ')';
+                const role = document.createElement('button');
+                role.className = 'btn btn-secondary';
+                role.textContent = c.role === 'read' ? 'Allow control' : 'Make read-only';
+                role.addEventListener('click', function() {
+                    loadClients({ id: c.id, role: c.role === 'read' ? 'control' : 'read' });
+                });
+                const btn = document.createElement('button');
+                btn.className = 'btn btn-secondary';
+                btn.textContent = 'Revoke';
+                btn.addEventListener('click', function() { loadClients({ id: c.id }); });
+                li.appendChild(label);
+                li.appendChild(role);
+                li.appendChild(btn);
+                clientList.appendChild(li);
+            });
+        } catch (e) {
+            showToast('Failed to load paired devices', true);
+        }
+    }
+
+    if (wakeRotateBtn) {
+        wakeRotateBtn.addEventListener('click', function() {
+            loadClients({ rotate_wake: true });
+        });
+    }
+
+    // --- Quick actions ---
+    async function loadQuickActions() {
+        if (!quickActionsPanel) return;
+        try {
+            const res = await fetch(API + '/quickactions');
+            const data = await res.json();
+
+            quickActionsPanel.innerHTML = '';
+            (data.quick_actions || []).forEach(function(qa, index) {
+                const btn = document.createElement('button');
+                btn.className = 'btn btn-secondary';
+                btn.textContent = qa.label;
+                btn.addEventListener('click', function() { runQuickAction(index, qa.label); });
+                quickActionsPanel.appendChild(btn);
+            });
+        } catch (e) {
+            showToast('Failed to load quick actions', true);
+        }
+    }
+
+    async function runQuickAction(index, label) {
+        try {
+            const res = await fetch(API + '/quickactions/run', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ index: index })
+            });
+
+            const data = await res.json();
+
+            if (data.error) {
+                showToast(data.error, true);
+            } else {
+                showToast(label);
+            }
+        } catch (e) {
+            showToast('Failed to run action', true);
         }
     }
 
+    loadQuickActions();
+
+    // --- Macros ---
+    let macroRecording = false;
+
+    async function loadMacros() {
+        if (!macroList) return;
+        try {
+            const res = await fetch(API + '/macros');
+            const data = await res.json();
+
+            macroList.innerHTML = '';
+            (data.macros || []).forEach(function(m) {
+                const li = document.createElement('li');
+                const label = document.createElement('span');
+                label.textContent = m.name + ' (' + m.steps.length + (m.steps.length === 1 ? ' step)' : ' steps)');
+                const btn = document.createElement('button');
+                btn.className = 'btn btn-secondary';
+                btn.textContent = 'Run';
+                btn.addEventListener('click', function() { runMacro(m.name); });
+                const del = document.createElement('button');
+                del.className = 'btn btn-secondary';
+                del.textContent = 'Delete';
+                del.addEventListener('click', function() { deleteMacro(m.name); });
+                li.appendChild(label);
+                li.appendChild(btn);
+                li.appendChild(del);
+                macroList.appendChild(li);
+            });
+        } catch (e) {
+            showToast('Failed to load macros', true);
+        }
+    }
+
+    async function runMacro(name) {
+        try {
+            const res = await fetch(API + '/macros/run', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ name: name })
+            });
+            const data = await res.json();
+            showToast(data.error || 'Running ' + name, !!data.error);
+        } catch (e) {
+            showToast('Failed to run macro', true);
+        }
+    }
+
+    async function deleteMacro(name) {
+        if (!confirm('Delete the macro \u201c' + name + '\u201d?')) return;
+        try {
+            const res = await fetch(API + '/macros/' + encodeURIComponent(name), { method: 'DELETE' });
+            const data = await res.json();
+            showToast(data.error || 'Deleted ' + name, !!data.error);
+        } catch (e) {
+            showToast('Failed to delete macro', true);
+        }
+        loadMacros();
+    }
+
+    // pollMacroRecording shows the recording countdown until it ends.
+    async function pollMacroRecording() {
+        try {
+            const res = await fetch(API + '/macros/record');
+            const data = await res.json();
+            if (data.recording) {
+                macroRecordStatus.textContent = 'Recording \u201c' + data.name + '\u201d \u2014 ' +
+                    data.remaining_seconds + ' s left, ' + data.steps + (data.steps === 1 ? ' step' : ' steps');
+                macroRecordStatus.classList.remove('hidden');
+                setTimeout(pollMacroRecording, 1000);
+                return;
+            }
+        } catch (e) {
+            // fall through and reset the controls
+        }
+        macroRecording = false;
+        macroRecordBtn.textContent = 'Record Macro';
+        macroRecordStatus.classList.add('hidden');
+        loadMacros();
+    }
+
+    if (macroRecordBtn) {
+        macroRecordBtn.addEventListener('click', async function() {
+            if (macroRecording) {
+                try {
+                    const res = await fetch(API + '/macros/record/stop', { method: 'POST' });
+                    const data = await res.json();
+                    showToast(data.error || 'Saved ' + data.macro.name, !!data.error);
+                } catch (e) {
+                    showToast('Failed to stop recording', true);
+                }
+                return;
+            }
+            const name = macroName.value.trim();
+            if (!name) {
+                showToast('Give the macro a name first', true);
+                return;
+            }
+            try {
+                const res = await fetch(API + '/macros/record', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ name: name, seconds: parseInt(macroSeconds.value, 10) })
+                });
+                const data = await res.json();
+                if (data.error) {
+                    showToast(data.error, true);
+                    return;
+                }
+                macroRecording = true;
+                macroRecordBtn.textContent = 'Stop';
+                pollMacroRecording();
+            } catch (e) {
+                showToast('Failed to start recording', true);
+            }
+        });
+    }
+
+    loadMacros();
+
+    // --- Self test ---
+    if (selfTestBtn) {
+        selfTestBtn.addEventListener('click', async function() {
+            selfTestBtn.disabled = true;
+            selfTestBtn.textContent = 'Running\u2026';
+            try {
+                const res = await fetch(API + '/selftest', { method: 'POST' });
+                const data = await res.json();
+                selfTestResults.innerHTML = '';
+                (data.checks || []).forEach(function(check) {
+                    const li = document.createElement('li');
+                    const name = document.createElement('span');
+                    name.textContent = check.name;
+                    const result = document.createElement('span');
+                    result.className = check.ok ? 'pass' : 'fail';
+                    result.textContent = (check.ok ? '\u2713' : '\u2717') + (check.detail ? ' ' + check.detail : '');
+                    li.appendChild(name);
+                    li.appendChild(result);
+                    selfTestResults.appendChild(li);
+                });
+                selfTestResults.classList.remove('hidden');
+                showToast(data.passed ? 'Self test passed' : 'Self test found problems', !data.passed);
+            } catch (e) {
+                showToast('Failed to run self test', true);
+            }
+            selfTestBtn.disabled = false;
+            selfTestBtn.textContent = 'Run Self Test';
+        });
+    }
+
+    // --- Usage statistics ---
+    function formatDuration(seconds) {
+        const mins = Math.round(seconds / 60);
+        if (mins < 60) return mins + ' min';
+        return Math.floor(mins / 60) + ' h ' + (mins % 60) + ' min';
+    }
+
+    function fillUsageRow(row, day) {
+        if (!row) return;
+        const cells = row.querySelectorAll('td');
+        cells[0].textContent = formatDuration(day.ptt_seconds);
+        cells[1].textContent = day.ptt_sessions;
+        cells[2].textContent = day.swipes;
+        cells[3].textContent = day.reconnects;
+        cells[4].textContent = formatDuration(day.keep_awake_seconds);
+    }
+
+    async function loadStats() {
+        try {
+            const res = await fetch(API + '/stats?days=7');
+            const data = await res.json();
+            if (data.error || !data.days) return;
+            fillUsageRow(usageToday, data.days[data.days.length - 1]);
+            fillUsageRow(usageWeek, data.total);
+        } catch (e) {
+            // non-critical; leave the placeholders
+        }
+    }
+
+    loadStats();
+    setInterval(loadStats, 60000);
+
+    // --- Usage reports ---
+    function showTelemetry(data) {
+        telemetryToggle.checked = data.enabled;
+        if (!data.endpoint) {
+            telemetryDesc.textContent = 'This build has nowhere to send reports; set telemetry.endpoint in config.json';
+        } else if (data.last_sent) {
+            telemetryDesc.textContent = 'To ' + data.endpoint + ', last sent ' + new Date(data.last_sent).toLocaleDateString();
+        } else {
+            telemetryDesc.textContent = 'To ' + data.endpoint + ', not sent yet';
+        }
+        telemetryPayload.textContent = JSON.stringify(data.next, null, 2);
+    }
+
+    async function loadTelemetry() {
+        try {
+            const res = await fetch(API + '/telemetry');
+            const data = await res.json();
+            if (data.error) {
+                telemetryDesc.textContent = data.error;
+                telemetryToggle.disabled = true;
+                telemetryViewBtn.disabled = true;
+                return;
+            }
+            showTelemetry(data);
+        } catch (e) {
+            // non-critical; leave the placeholder
+        }
+    }
+
+    if (telemetryToggle) {
+        telemetryToggle.addEventListener('change', async function() {
+            const enabled = telemetryToggle.checked;
+            try {
+                const res = await fetch(API + '/telemetry', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ enabled: enabled })
+                });
+                const data = await res.json();
+                if (data.error) {
+                    showToast(data.error, true);
+                    telemetryToggle.checked = !enabled; // revert
+                    return;
+                }
+                showTelemetry(data);
+                showToast(enabled ? 'Usage reports on' : 'Usage reports off');
+            } catch (e) {
+                showToast('Failed to update setting', true);
+                telemetryToggle.checked = !enabled; // revert
+            }
+        });
+
+        telemetryViewBtn.addEventListener('click', async function() {
+            if (telemetryPayload.classList.toggle('hidden')) {
+                telemetryViewBtn.textContent = 'Show Report';
+                return;
+            }
+            telemetryViewBtn.textContent = 'Hide Report';
+            await loadTelemetry();
+        });
+
+        loadTelemetry();
+    }
+
+    // --- Config backups ---
+    async function loadBackups() {
+        if (!backupList) return;
+        try {
+            const res = await fetch(API + '/config/backups');
+            const data = await res.json();
+            if (data.error) return;
+
+            backupList.innerHTML = '';
+            const backups = data.backups || [];
+            if (!backups.length) {
+                const li = document.createElement('li');
+                li.textContent = 'No backups yet';
+                backupList.appendChild(li);
+            }
+            backups.forEach(function(b) {
+                const li = document.createElement('li');
+                const label = document.createElement('span');
+                label.textContent = 'Before ' + new Date(b.time).toLocaleString();
+                const btn = document.createElement('button');
+                btn.className = 'btn btn-secondary';
+                btn.textContent = 'Restore';
+                btn.addEventListener('click', function() { restoreBackup(b); });
+                li.appendChild(label);
+                li.appendChild(btn);
+                backupList.appendChild(li);
+            });
+        } catch (e) {
+            // non-critical; leave the list empty
+        }
+    }
+
+    async function restoreBackup(b) {
+        if (!confirm('Restore the settings from ' + new Date(b.time).toLocaleString() + '? R1 Control will restart.')) return;
+        try {
+            const res = await fetch(API + '/config/restore', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ name: b.name })
+            });
+            const data = await res.json();
+            if (data.error) {
+                showToast(data.error, true);
+                return;
+            }
+            showToast(data.restarting ? 'Restored — restarting R1 Control' : 'Restored — restart R1 Control to apply');
+        } catch (e) {
+            showToast('Failed to restore settings', true);
+        }
+    }
+
+    loadBackups();
+
+    // --- What's new ---
+    // Release notes, with the actions and endpoints each change added.
+    function codeList(label, names) {
+        const span = document.createElement('span');
+        span.className = 'hint';
+        span.appendChild(document.createTextNode(' ' + label + ': '));
+        names.forEach(function(name, i) {
+            if (i > 0) span.appendChild(document.createTextNode(', '));
+            const code = document.createElement('code');
+            code.textContent = name;
+            span.appendChild(code);
+        });
+        return span;
+    }
+
+    async function loadChangelog() {
+        if (!whatsNew) return;
+        try {
+            const res = await fetch(API + '/changelog');
+            const data = await res.json();
+            if (!data.releases || !data.releases.length) return;
+
+            changelogList.innerHTML = '';
+            data.releases.forEach(function(rel) {
+                const h = document.createElement('h3');
+                h.textContent = rel.version === 'unreleased' ? 'Upcoming' : 'v' + rel.version.replace(/^v/, '');
+                changelogList.appendChild(h);
+                const ul = document.createElement('ul');
+                rel.notes.forEach(function(note) {
+                    const li = document.createElement('li');
+                    li.textContent = note.text;
+                    if (note.actions) li.appendChild(codeList('Actions', note.actions));
+                    if (note.endpoints) li.appendChild(codeList('Endpoints', note.endpoints));
+                    ul.appendChild(li);
+                });
+                changelogList.appendChild(ul);
+            });
+            whatsNew.classList.remove('hidden');
+            if (location.hash === '#whats-new') whatsNew.scrollIntoView();
+        } catch (e) {
+            // non-critical; leave the section hidden
+        }
+    }
+
+    loadChangelog();
+
     // Poll every 2 seconds
     pollStatus();
     setInterval(pollStatus, 2000);
 
+    // State changes also arrive over a WebSocket as they happen
+    function watchEvents() {
+        const s = new WebSocket((location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + API + '/ws');
+        s.onmessage = function(msg) {
+            const data = JSON.parse(msg.data);
+            if (data.type === 'event' && data.event.type === 'state') pollStatus();
+            if (data.type === 'event' && data.event.type === 'focus') loadFocus();
+        };
+        s.onclose = function() { setTimeout(watchEvents, 5000); };
+    }
+    watchEvents();
+
+    // --- Focus timer ---
+    let focusConfig = null;
+
+    // setFocusMinutes selects minutes in sel, adding it if config.json has
+    // a value the list doesn't offer.
+    function setFocusMinutes(sel, label, minutes) {
+        const v = String(minutes);
+        if (!Array.from(sel.options).some(function(o) { return o.value === v; })) {
+            const opt = document.createElement('option');
+            opt.value = v;
+            opt.textContent = label + ' ' + v + ' min';
+            sel.appendChild(opt);
+        }
+        sel.value = v;
+    }
+
+    function renderFocus(data) {
+        if (data.config) {
+            focusConfig = data.config;
+            setFocusMinutes(focusWork, 'Work', data.config.work_minutes);
+            setFocusMinutes(focusBreak, 'Break', data.config.break_minutes);
+        }
+        if (!data.phase) {
+            focusStatus.textContent = 'Stopped';
+            return;
+        }
+        const ends = new Date(data.ends_at).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' });
+        let text = (data.phase === 'work' ? 'Working' : data.phase === 'long_break' ? 'Long break' : 'Break') + ' until ' + ends;
+        text += ' \u00b7 ' + data.completed + (data.cycles ? ' of ' + data.cycles : '') + ' done';
+        focusStatus.textContent = text;
+    }
+
+    async function loadFocus() {
+        if (!focusStatus) return;
+        try {
+            const res = await fetch(API + '/focus');
+            const data = await res.json();
+            if (data.error) {
+                showToast(data.error, true);
+                return;
+            }
+            renderFocus(data);
+        } catch (e) {
+            showToast('Failed to load the focus timer', true);
+        }
+    }
+
+    async function saveFocus() {
+        if (!focusConfig) return;
+        const fc = Object.assign({}, focusConfig, {
+            work_minutes: parseInt(focusWork.value, 10),
+            break_minutes: parseInt(focusBreak.value, 10)
+        });
+        try {
+            const res = await fetch(API + '/focus', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(fc)
+            });
+            const data = await res.json();
+            if (data.error) {
+                showToast(data.error, true);
+                return;
+            }
+            renderFocus(data);
+            showToast('Focus timer saved; applies from the next start');
+        } catch (e) {
+            showToast('Failed to save the focus timer', true);
+        }
+    }
+
+    async function focusCommand(cmd) {
+        try {
+            const res = await fetch(API + '/focus/' + cmd, { method: 'POST' });
+            const data = await res.json();
+            if (data.error) {
+                showToast(data.error, true);
+            }
+            renderFocus(data);
+        } catch (e) {
+            showToast('Failed to ' + cmd + ' the focus timer', true);
+        }
+    }
+
+    if (focusStatus) {
+        focusWork.addEventListener('change', saveFocus);
+        focusBreak.addEventListener('change', saveFocus);
+        document.getElementById('focus-start-btn').addEventListener('click', function() { focusCommand('start'); });
+        document.getElementById('focus-skip-btn').addEventListener('click', function() { focusCommand('skip'); });
+        document.getElementById('focus-stop-btn').addEventListener('click', function() { focusCommand('stop'); });
+        loadFocus();
+    }
+
     // --- Auto-start toggle ---
     if (autostartToggle) {
         autostartToggle.addEventListener('change', async function() {
@@ -97,7 +821,7 @@
             const enabled = autostartToggle.checked;
 
             try {
-                const res = await fetch('/autostart', {
+                const res = await fetch(API + '/autostart', {
                     method: 'POST',
                     headers: { 'Content-Type': 'application/json' },
                     body: JSON.stringify({ enabled: enabled })
@@ -120,6 +844,64 @@
         });
     }
 
+    // --- Start-paused toggle ---
+    if (startPausedToggle) {
+        startPausedToggle.addEventListener('change', async function() {
+            startPausedToggle._userChanging = true;
+            const paused = startPausedToggle.checked;
+
+            try {
+                const res = await fetch(API + '/autostart', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ enabled: autostartToggle.checked, start_paused: paused })
+                });
+
+                const data = await res.json();
+
+                if (data.error) {
+                    showToast(data.error, true);
+                    startPausedToggle.checked = !paused; // revert
+                } else {
+                    showToast(paused ? 'Hotkeys will start paused' : 'Hotkeys will start active');
+                }
+            } catch (e) {
+                showToast('Failed to update setting', true);
+                startPausedToggle.checked = !paused; // revert
+            }
+
+            startPausedToggle._userChanging = false;
+        });
+    }
+
+    // --- Login delay ---
+    if (startDelaySelect) {
+        startDelaySelect.addEventListener('change', async function() {
+            startDelaySelect._userChanging = true;
+            const delay = parseInt(startDelaySelect.value, 10);
+
+            try {
+                const res = await fetch(API + '/autostart', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ enabled: autostartToggle.checked, delay_seconds: delay })
+                });
+
+                const data = await res.json();
+
+                if (data.error) {
+                    showToast(data.error, true);
+                } else {
+                    showToast(delay > 0 ? 'Login delay: ' + startDelaySelect.selectedOptions[0].textContent : 'No login delay');
+                }
+            } catch (e) {
+                showToast('Failed to update setting', true);
+            }
+
+            startDelaySelect._userChanging = false;
+        });
+    }
+
     // --- Keep-awake toggle ---
     if (keepawakeToggle) {
         keepawakeToggle.addEventListener('change', async function() {
@@ -130,7 +912,7 @@
             updateSleepAfterVisibility(enabled);
 
             try {
-                const res = await fetch('/keepawake', {
+                const res = await fetch(API + '/keepawake', {
                     method: 'POST',
                     headers: { 'Content-Type': 'application/json' },
                     body: JSON.stringify({ enabled: enabled, sleep_after_minutes: sleepAfter })
@@ -163,7 +945,7 @@
             const enabled = keepawakeToggle.checked;
 
             try {
-                const res = await fetch('/keepawake', {
+                const res = await fetch(API + '/keepawake', {
                     method: 'POST',
                     headers: { 'Content-Type': 'application/json' },
                     body: JSON.stringify({ enabled: enabled, sleep_after_minutes: sleepAfter })
@@ -185,12 +967,293 @@
         });
     }
 
+    // --- Theater mode toggle ---
+    if (dimToggle) {
+        dimToggle.addEventListener('change', async function() {
+            dimToggle._userChanging = true;
+            const dim = dimToggle.checked;
+
+            try {
+                const res = await fetch(API + '/keepawake', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({
+                        enabled: keepawakeToggle.checked,
+                        sleep_after_minutes: parseInt(sleepAfterSelect.value, 10),
+                        dim: dim
+                    })
+                });
+
+                const data = await res.json();
+
+                if (data.error) {
+                    showToast(data.error, true);
+                    dimToggle.checked = !dim; // revert
+                } else {
+                    showToast(dim ? 'Theater mode on' : 'Theater mode off');
+                }
+            } catch (e) {
+                showToast('Failed to update setting', true);
+                dimToggle.checked = !dim; // revert
+            }
+
+            dimToggle._userChanging = false;
+        });
+    }
+
+    // --- Keep-awake schedule ---
+    const calendarDays = ['mon', 'tue', 'wed', 'thu', 'fri', 'sat', 'sun'];
+    let schedulePeriods = [];
+
+    function minutesOf(hhmm) {
+        const parts = hhmm.split(':');
+        return parseInt(parts[0], 10) * 60 + parseInt(parts[1], 10);
+    }
+
+    function renderKeepAwakeSchedule(data) {
+        schedulePeriods = data.periods || [];
+
+        keepAwakeCalendar.innerHTML = '';
+        calendarDays.forEach(function(day) {
+            const row = document.createElement('div');
+            row.className = 'day';
+            const name = document.createElement('span');
+            name.className = 'day-name';
+            name.textContent = day.charAt(0).toUpperCase() + day.slice(1);
+            const track = document.createElement('div');
+            track.className = 'day-track';
+            (data.calendar || []).forEach(function(sp) {
+                if (sp.day !== day) return;
+                const from = minutesOf(sp.from);
+                const to = minutesOf(sp.to);
+                const block = document.createElement('div');
+                block.className = 'day-span';
+                block.style.left = (from / 1440 * 100) + '%';
+                block.style.width = ((to - from) / 1440 * 100) + '%';
+                block.title = sp.from + '\u2013' + sp.to + ', ' +
+                    (sp.sleep_after_minutes === 0 ? 'never sleeps' : 'sleeps after ' + formatMinutes(sp.sleep_after_minutes) + ' idle') +
+                    (sp.dim ? ', dimmed' : '');
+                track.appendChild(block);
+            });
+            row.appendChild(name);
+            row.appendChild(track);
+            keepAwakeCalendar.appendChild(row);
+        });
+        keepAwakeCalendar.classList.toggle('hidden', schedulePeriods.length === 0);
+
+        keepAwakePeriods.innerHTML = '';
+        schedulePeriods.forEach(function(p, i) {
+            const li = document.createElement('li');
+            const label = document.createElement('span');
+            let text = (p.days && p.days.length ? p.days.join(', ') : 'every day') + ' ' + (p.between || 'all day');
+            if (p.sleep_after_minutes !== undefined && p.sleep_after_minutes !== null) {
+                text += p.sleep_after_minutes === 0 ? ', never sleeps' : ', sleeps after ' + formatMinutes(p.sleep_after_minutes);
+            }
+            if (p.dim !== undefined && p.dim !== null) {
+                text += p.dim ? ', dimmed' : ', not dimmed';
+            }
+            label.textContent = text;
+            const btn = document.createElement('button');
+            btn.className = 'btn btn-secondary';
+            btn.textContent = 'Remove';
+            btn.addEventListener('click', function() {
+                saveKeepAwakeSchedule(schedulePeriods.filter(function(_, j) { return j !== i; }));
+            });
+            li.appendChild(label);
+            li.appendChild(btn);
+            keepAwakePeriods.appendChild(li);
+        });
+    }
+
+    async function loadKeepAwakeSchedule() {
+        if (!keepAwakeCalendar) return;
+        try {
+            const res = await fetch(API + '/keepawake/schedule');
+            renderKeepAwakeSchedule(await res.json());
+        } catch (e) {
+            showToast('Failed to load keep-awake schedule', true);
+        }
+    }
+
+    async function saveKeepAwakeSchedule(periods) {
+        try {
+            const res = await fetch(API + '/keepawake/schedule', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(periods)
+            });
+            const data = await res.json();
+            if (data.error) {
+                showToast(data.error, true);
+                return;
+            }
+            renderKeepAwakeSchedule(data);
+            showToast(periods.length ? 'Keep-awake schedule saved' : 'Keep-awake schedule cleared');
+        } catch (e) {
+            showToast('Failed to save keep-awake schedule', true);
+        }
+    }
+
+    if (periodAddBtn) {
+        periodAddBtn.addEventListener('click', function() {
+            if (!periodFrom.value || !periodTo.value) {
+                showToast('Pick a start and end time', true);
+                return;
+            }
+            const p = { between: periodFrom.value + '-' + periodTo.value };
+            if (periodDays.value) p.days = [periodDays.value];
+            if (periodSleep.value !== '') p.sleep_after_minutes = parseInt(periodSleep.value, 10);
+            if (periodDim.value !== '') p.dim = periodDim.value === 'true';
+            saveKeepAwakeSchedule(schedulePeriods.concat([p]));
+        });
+    }
+
+    loadKeepAwakeSchedule();
+
+    // --- PTT auto-release dropdown ---
+    if (pttAutoReleaseSelect) {
+        pttAutoReleaseSelect.addEventListener('change', async function() {
+            pttAutoReleaseSelect._userChanging = true;
+            const minutes = parseInt(pttAutoReleaseSelect.value, 10);
+
+            try {
+                const res = await fetch(API + '/ptt-auto-release', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ minutes: minutes })
+                });
+
+                const data = await res.json();
+
+                if (data.error) {
+                    showToast(data.error, true);
+                } else {
+                    showToast('Auto-release latched PTT: ' + (minutes === 0 ? 'Never' : formatMinutes(minutes)));
+                }
+            } catch (e) {
+                showToast('Failed to update setting', true);
+            }
+
+            pttAutoReleaseSelect._userChanging = false;
+        });
+    }
+
+    // --- PTT hotkey mode ---
+    async function savePTTMode(select) {
+        select._userChanging = true;
+        const body = {
+            mode: pttModeSelect.value,
+            toggle_threshold_ms: parseInt(toggleThresholdSelect.value, 10)
+        };
+        updateToggleThresholdVisibility(body.mode);
+
+        try {
+            const res = await fetch(API + '/ptt-mode', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(body)
+            });
+
+            const data = await res.json();
+
+            if (data.error) {
+                showToast(data.error, true);
+            } else {
+                showToast('PTT hotkey: ' + pttModeSelect.options[pttModeSelect.selectedIndex].text);
+            }
+        } catch (e) {
+            showToast('Failed to update setting', true);
+        }
+
+        select._userChanging = false;
+    }
+
+    if (pttModeSelect && toggleThresholdSelect) {
+        pttModeSelect.addEventListener('change', function() { savePTTMode(pttModeSelect); });
+        toggleThresholdSelect.addEventListener('change', function() { savePTTMode(toggleThresholdSelect); });
+    }
+
+    // --- Swipe hotkey mode ---
+    async function saveSwipeMode(select) {
+        select._userChanging = true;
+        const body = {
+            mode: swipeModeSelect.value,
+            hold_ms: parseInt(swipeHoldSelect.value, 10),
+            reset_seconds: parseInt(swipeResetSelect.value, 10)
+        };
+        updateSwipeModeVisibility(body.mode);
+
+        try {
+            const res = await fetch(API + '/swipe-mode', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(body)
+            });
+
+            const data = await res.json();
+
+            if (data.error) {
+                showToast(data.error, true);
+            } else {
+                showToast('Swipe hotkey: ' + swipeModeSelect.options[swipeModeSelect.selectedIndex].text);
+            }
+        } catch (e) {
+            showToast('Failed to update setting', true);
+        }
+
+        select._userChanging = false;
+    }
+
+    if (swipeModeSelect && swipeHoldSelect && swipeResetSelect) {
+        [swipeModeSelect, swipeHoldSelect, swipeResetSelect].forEach(function(select) {
+            select.addEventListener('change', function() { saveSwipeMode(select); });
+        });
+    }
+
     function formatMinutes(mins) {
         if (mins < 60) return mins + ' min';
         const hrs = mins / 60;
         return hrs === 1 ? '1 hour' : hrs + ' hours';
     }
 
+    // --- Host-side capture (sees OS-reserved combos the browser can't) ---
+    async function captureHostHotkey(onCaptured) {
+        showToast('Press the key combination now...');
+        try {
+            const res = await fetch(API + '/hotkey/capture/start', { method: 'POST' });
+            const data = await res.json();
+
+            if (data.error) {
+                showToast(data.error, true);
+                return;
+            }
+
+            onCaptured({
+                modifiers: data.modifiers,
+                key: data.key,
+                display: data.hotkey
+            });
+        } catch (e) {
+            showToast('Capture failed: ' + e.message, true);
+        }
+    }
+
+    captureBtn.addEventListener('click', function() {
+        captureHostHotkey(function(hk) {
+            pendingHotkey = hk;
+            previewHotkey.textContent = hk.display;
+            preview.classList.remove('hidden');
+        });
+    });
+
+    swipeCaptureBtn.addEventListener('click', function() {
+        captureHostHotkey(function(hk) {
+            pendingSwipeHotkey = hk;
+            swipePreviewHotkey.textContent = hk.display;
+            swipePreview.classList.remove('hidden');
+        });
+    });
+
     // --- Hotkey recording ---
     recordBtn.addEventListener('click', startRecording);
     cancelBtn.addEventListener('click', stopRecording);
@@ -200,11 +1263,33 @@
     function startRecording() {
         recordingOverlay.classList.remove('hidden');
         document.addEventListener('keydown', captureKey);
+        document.addEventListener('mouseup', captureMouseButton);
     }
 
     function stopRecording() {
         recordingOverlay.classList.add('hidden');
         document.removeEventListener('keydown', captureKey);
+        document.removeEventListener('mouseup', captureMouseButton);
+    }
+
+    // Side buttons are 3 (back) and 4 (forward) in the browser; the host
+    // calls them Mouse4 and Mouse5. They're bound without modifiers.
+    function captureMouseButton(e) {
+        if (e.button !== 3 && e.button !== 4) return;
+        e.preventDefault();
+        e.stopPropagation();
+
+        const n = e.button + 1;
+        pendingHotkey = {
+            modifiers: [],
+            key: 'mouse' + n,
+            display: 'Mouse' + n
+        };
+
+        stopRecording();
+
+        previewHotkey.textContent = pendingHotkey.display;
+        preview.classList.remove('hidden');
     }
 
     function captureKey(e) {
@@ -264,12 +1349,13 @@
         if (!pendingHotkey) return;
 
         try {
-            const res = await fetch('/hotkey', {
+            const res = await fetch(API + '/hotkey', {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
                 body: JSON.stringify({
                     modifiers: pendingHotkey.modifiers,
-                    js_code: pendingHotkey.jsCode
+                    js_code: pendingHotkey.jsCode,
+                    key: pendingHotkey.key
                 })
             });
 
@@ -362,12 +1448,13 @@
         if (!pendingSwipeHotkey) return;
 
         try {
-            const res = await fetch('/swipe-hotkey', {
+            const res = await fetch(API + '/swipe-hotkey', {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
                 body: JSON.stringify({
                     modifiers: pendingSwipeHotkey.modifiers,
-                    js_code: pendingSwipeHotkey.jsCode
+                    js_code: pendingSwipeHotkey.jsCode,
+                    key: pendingSwipeHotkey.key
                 })
             });
 
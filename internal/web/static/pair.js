@@ -0,0 +1,82 @@
+// R1 Control remote pairing — client-side JavaScript
+
+(function() {
+    'use strict';
+
+    const API = '/api/v1';
+
+    const nameStep = document.getElementById('name-step');
+    const pinStep = document.getElementById('pin-step');
+    const nameInput = document.getElementById('client-name');
+    const pinInput = document.getElementById('pin');
+    const requestBtn = document.getElementById('request-btn');
+    const confirmBtn = document.getElementById('confirm-btn');
+
+    let requestId = null;
+
+    async function post(path, body) {
+        const res = await fetch(API + path, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(body)
+        });
+        if (!res.ok) {
+            throw new Error((await res.text()).trim());
+        }
+        return res.json();
+    }
+
+    requestBtn.addEventListener('click', async function() {
+        const name = nameInput.value.trim();
+        if (!name) {
+            showToast('Enter a name for this device', true);
+            return;
+        }
+        requestBtn.disabled = true;
+        try {
+            const data = await post('/pair/start', { name: name });
+            if (data.error) {
+                showToast(data.error, true);
+                return;
+            }
+            requestId = data.id;
+            nameStep.classList.add('hidden');
+            pinStep.classList.remove('hidden');
+            pinInput.focus();
+        } catch (e) {
+            showToast('Pairing failed: ' + e.message, true);
+        } finally {
+            requestBtn.disabled = false;
+        }
+    });
+
+    confirmBtn.addEventListener('click', async function() {
+        confirmBtn.disabled = true;
+        try {
+            const data = await post('/pair/confirm', { id: requestId, pin: pinInput.value });
+            if (data.error) {
+                showToast(data.error, true);
+                // An expired or used-up request has to be started again
+                if (!/^wrong PIN$/.test(data.error)) {
+                    pinStep.classList.add('hidden');
+                    nameStep.classList.remove('hidden');
+                }
+                pinInput.value = '';
+                return;
+            }
+            location.href = '/remote';
+        } catch (e) {
+            showToast('Pairing failed: ' + e.message, true);
+        } finally {
+            confirmBtn.disabled = false;
+        }
+    });
+
+    function showToast(message, isError) {
+        const toast = document.createElement('div');
+        toast.className = 'toast' + (isError ? ' error' : '');
+        toast.textContent = message;
+        document.body.appendChild(toast);
+        setTimeout(() => toast.remove(), 2500);
+    }
+})();
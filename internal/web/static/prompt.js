@@ -0,0 +1,56 @@
+// R1 Control "Ask rabbit" prompt — client-side JavaScript
+
+(function() {
+    'use strict';
+
+    const API = '/api/v1';
+
+    const text = document.getElementById('prompt-text');
+    const submit = document.getElementById('prompt-submit');
+    const sendBtn = document.getElementById('prompt-send');
+
+    async function send() {
+        const prompt = text.value.trim();
+        if (!prompt) return;
+
+        sendBtn.disabled = true;
+        try {
+            const res = await fetch(API + '/prompt', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ text: prompt, submit: submit.checked })
+            });
+            const data = await res.json();
+
+            if (data.error) {
+                showToast(data.error, true);
+            } else {
+                showToast('Sent to R1');
+                text.value = '';
+            }
+        } catch (e) {
+            showToast('Failed to send prompt', true);
+        } finally {
+            sendBtn.disabled = false;
+            text.focus();
+        }
+    }
+
+    sendBtn.addEventListener('click', send);
+
+    // Ctrl/Cmd+Enter sends; plain Enter adds a new line
+    text.addEventListener('keydown', function(e) {
+        if (e.key === 'Enter' && (e.ctrlKey || e.metaKey)) {
+            e.preventDefault();
+            send();
+        }
+    });
+
+    function showToast(message, isError) {
+        const toast = document.createElement('div');
+        toast.className = 'toast' + (isError ? ' error' : '');
+        toast.textContent = message;
+        document.body.appendChild(toast);
+        setTimeout(() => toast.remove(), 2500);
+    }
+})();
@@ -0,0 +1,202 @@
+// R1 Control phone remote — client-side JavaScript
+
+(function() {
+    'use strict';
+
+    const API = '/api/v1';
+
+    const deviceStatus = document.getElementById('device-status');
+    const holdBtn = document.getElementById('hold-btn');
+    const quickActionsPanel = document.getElementById('quick-actions');
+
+    // --- Status polling ---
+    async function pollStatus() {
+        try {
+            const res = await fetch(API + '/status');
+            const data = await res.json();
+            deviceStatus.textContent = formatState(data.state);
+            deviceStatus.className = 'status ' + data.state;
+        } catch (e) {
+            deviceStatus.textContent = 'Unreachable';
+            deviceStatus.className = 'status disconnected';
+        }
+    }
+
+    function formatState(state) {
+        switch (state) {
+            case 'disconnected': return 'Disconnected';
+            case 'connecting':
+            case 'registering': return 'Connecting…';
+            case 'backoff': return 'Retrying…';
+            case 'error': return 'Connection error — retrying…';
+            case 'connected': return 'Connected';
+            case 'ptt_active': return 'PTT Active';
+            default: return state;
+        }
+    }
+
+    // --- Hold to talk ---
+    // PTT stays on while the /ptt/hold request is open. Releasing the
+    // button aborts it; if the phone loses the connection, the app
+    // releases PTT on its own.
+    let hold = null;
+
+    async function startHold(e) {
+        e.preventDefault();
+        if (hold) return;
+        const ctrl = new AbortController();
+        hold = ctrl;
+        holdBtn.classList.add('active');
+        try {
+            const res = await fetch(API + '/ptt/hold', { method: 'POST', signal: ctrl.signal });
+            const reader = res.body.getReader();
+            const decoder = new TextDecoder();
+            for (;;) {
+                const { value, done } = await reader.read();
+                if (done) break;
+                const lines = decoder.decode(value).trim().split('\n');
+                const last = JSON.parse(lines[lines.length - 1]);
+                if (last.error) {
+                    showToast(last.error, true);
+                }
+                pollStatus();
+            }
+        } catch (err) {
+            if (err.name !== 'AbortError') {
+                showToast('PTT failed: ' + err.message, true);
+            }
+        }
+        // Ended on release, or PTT was released elsewhere
+        if (hold === ctrl) {
+            hold = null;
+            holdBtn.classList.remove('active');
+        }
+        pollStatus();
+    }
+
+    function stopHold() {
+        if (!hold) return;
+        hold.abort();
+        hold = null;
+        holdBtn.classList.remove('active');
+    }
+
+    holdBtn.addEventListener('pointerdown', startHold);
+    holdBtn.addEventListener('pointerup', stopHold);
+    holdBtn.addEventListener('pointercancel', stopHold);
+    holdBtn.addEventListener('pointerleave', stopHold);
+    holdBtn.addEventListener('contextmenu', function(e) { e.preventDefault(); });
+    document.addEventListener('visibilitychange', function() {
+        if (document.hidden) stopHold();
+    });
+
+    // --- Command channel ---
+    // While the WebSocket is open, buttons send their action over it and
+    // state changes arrive as they happen; otherwise they fall back to
+    // plain requests.
+    let socket = null;
+    let nextID = 1;
+    const waiting = {};
+
+    function connectSocket() {
+        const s = new WebSocket((location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + API + '/ws');
+        s.onopen = function() { socket = s; };
+        s.onmessage = function(msg) {
+            const data = JSON.parse(msg.data);
+            if (data.type === 'event') {
+                if (data.event.type === 'state') pollStatus();
+            } else if (waiting[data.id]) {
+                waiting[data.id](data);
+                delete waiting[data.id];
+            }
+        };
+        s.onclose = function() {
+            if (socket === s) socket = null;
+            Object.keys(waiting).forEach(function(id) {
+                waiting[id]({ error: 'Connection lost' });
+                delete waiting[id];
+            });
+            setTimeout(connectSocket, 5000);
+        };
+    }
+
+    // run performs action over the WebSocket, or else POSTs body to path.
+    function run(action, payload, path, body) {
+        if (!socket) {
+            post(path, body);
+            return;
+        }
+        const id = nextID++;
+        waiting[id] = function(data) {
+            if (data.error) showToast(data.error, true);
+        };
+        socket.send(JSON.stringify({ id: id, action: action, payload: payload }));
+    }
+
+    // --- Buttons ---
+    async function post(path, body) {
+        try {
+            const res = await fetch(API + path, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(body)
+            });
+            if (!res.ok) {
+                showToast((await res.text()).trim(), true);
+                return;
+            }
+            const data = await res.json();
+            if (data.error) {
+                showToast(data.error, true);
+            }
+        } catch (e) {
+            showToast('Request failed: ' + e.message, true);
+        }
+        pollStatus();
+    }
+
+    document.getElementById('swipe-left-btn').addEventListener('click', function() {
+        run('swipe', { direction: 'left' }, '/swipe', { direction: 'left' });
+    });
+    document.getElementById('swipe-right-btn').addEventListener('click', function() {
+        run('swipe', { direction: 'right' }, '/swipe', { direction: 'right' });
+    });
+    document.getElementById('toggle-btn').addEventListener('click', function() {
+        run('ptt_toggle', null, '/ptt', { action: 'toggle' });
+    });
+    document.getElementById('wake-btn').addEventListener('click', function() {
+        run('wake', null, '/action', { action: 'wake' });
+    });
+
+    // --- Quick actions ---
+    async function loadQuickActions() {
+        try {
+            const res = await fetch(API + '/quickactions');
+            const data = await res.json();
+
+            quickActionsPanel.innerHTML = '';
+            (data.quick_actions || []).forEach(function(qa, index) {
+                const btn = document.createElement('button');
+                btn.className = 'btn btn-secondary';
+                btn.textContent = qa.label;
+                btn.addEventListener('click', function() { post('/quickactions/run', { index: index }); });
+                quickActionsPanel.appendChild(btn);
+            });
+        } catch (e) {
+            showToast('Failed to load quick actions', true);
+        }
+    }
+
+    function showToast(message, isError) {
+        const toast = document.createElement('div');
+        toast.className = 'toast' + (isError ? ' error' : '');
+        toast.textContent = message;
+        document.body.appendChild(toast);
+        setTimeout(() => toast.remove(), 2500);
+    }
+
+    pollStatus();
+    loadQuickActions();
+    connectSocket();
+    setInterval(pollStatus, 2000);
+})();
@@ -0,0 +1,174 @@
+// R1 Control WebUSB relay — performs the app's USB transfers on the R1
+
+(function() {
+    'use strict';
+
+    const API = '/api/v1';
+    const R1 = { vendorId: 0x0e8d, productId: 0x2304 };
+    const TYPES = ['standard', 'class', 'vendor'];
+    const RECIPIENTS = ['device', 'interface', 'endpoint', 'other'];
+
+    const statusEl = document.getElementById('webusb-status');
+    const shareBtn = document.getElementById('share-btn');
+
+    let device = null;
+
+    function setStatus(text) {
+        statusEl.textContent = text;
+    }
+
+    async function post(path, body) {
+        const res = await fetch(API + path, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(body)
+        });
+        if (!res.ok) {
+            throw new Error((await res.text()).trim());
+        }
+        return res.status === 204 ? null : res.json();
+    }
+
+    function decode(b64) {
+        const bin = atob(b64 || '');
+        const out = new Uint8Array(bin.length);
+        for (let i = 0; i < bin.length; i++) {
+            out[i] = bin.charCodeAt(i);
+        }
+        return out;
+    }
+
+    async function perform(t) {
+        if (!device || !device.opened) {
+            throw new Error('R1 is not open');
+        }
+        if (t.op === 'ping') {
+            return;
+        }
+        const rt = t.request_type || 0;
+        const result = await device.controlTransferOut({
+            requestType: TYPES[(rt >> 5) & 3],
+            recipient: RECIPIENTS[rt & 3],
+            request: t.request || 0,
+            value: t.value || 0,
+            index: t.index || 0
+        }, decode(t.data));
+        if (result.status !== 'ok') {
+            throw new Error('transfer ' + result.status);
+        }
+    }
+
+    function sleep(ms) {
+        return new Promise(r => setTimeout(r, ms));
+    }
+
+    // Polls for transfers while this tab shares the R1. After an error it
+    // waits 1s, doubling up to 30s; if the app lost track of the tab,
+    // e.g. because it restarted, it shares the R1 again.
+    async function relay(shared) {
+        let backoff = 1000;
+        const retry = async function(text) {
+            setStatus(text);
+            await sleep(backoff);
+            backoff = Math.min(backoff * 2, 30000);
+        };
+        while (device === shared) {
+            let res;
+            try {
+                res = await fetch(API + '/webusb/next');
+            } catch (e) {
+                await retry('R1 Control is not running — retrying…');
+                continue;
+            }
+            if (res.status === 404) {
+                try {
+                    await attach(shared);
+                    backoff = 1000;
+                } catch (e) {
+                    await retry('Could not share the R1: ' + e.message + ' — retrying…');
+                }
+                continue;
+            }
+            if (res.status === 204) {
+                backoff = 1000;
+                continue;
+            }
+            if (res.status !== 200) {
+                await retry('R1 Control answered ' + res.status + ' — retrying…');
+                continue;
+            }
+            backoff = 1000;
+            const t = await res.json();
+            let error = '';
+            try {
+                await perform(t);
+            } catch (e) {
+                error = e.message || String(e);
+            }
+            await post('/webusb/done', { id: t.id, error: error }).catch(() => {});
+        }
+    }
+
+    async function share(dev) {
+        await dev.open();
+        if (dev.configuration === null) {
+            await dev.selectConfiguration(1);
+        }
+        try {
+            await attach(dev);
+        } catch (e) {
+            await dev.close();
+            throw e;
+        }
+        device = dev;
+        relay(dev);
+    }
+
+    // Tells the app this tab shares dev
+    async function attach(dev) {
+        const data = await post('/webusb/attach', { serial: dev.serialNumber || '', product: dev.productName || '' });
+        if (data.error) {
+            throw new Error(data.error);
+        }
+        setStatus('Sharing ' + (dev.productName || 'R1') + '. Keep this tab open.');
+    }
+
+    shareBtn.addEventListener('click', async function() {
+        shareBtn.disabled = true;
+        try {
+            await share(await navigator.usb.requestDevice({ filters: [R1] }));
+        } catch (e) {
+            setStatus('Could not share the R1: ' + e.message);
+        } finally {
+            shareBtn.disabled = false;
+        }
+    });
+
+    if (!navigator.usb) {
+        setStatus('This browser has no WebUSB; open this page in Chrome.');
+        shareBtn.disabled = true;
+        return;
+    }
+
+    navigator.usb.addEventListener('disconnect', function(e) {
+        if (e.device === device) {
+            device = null;
+            setStatus('R1 unplugged. Plug it back in and share it again.');
+            post('/webusb/detach', {}).catch(() => {});
+        }
+    });
+
+    window.addEventListener('pagehide', function() {
+        if (device) {
+            navigator.sendBeacon(API + '/webusb/detach', new Blob(['{}'], { type: 'application/json' }));
+        }
+    });
+
+    // Share an R1 this page was allowed to use before
+    navigator.usb.getDevices().then(function(devs) {
+        const dev = devs.find(d => d.vendorId === R1.vendorId && d.productId === R1.productId);
+        if (dev) {
+            share(dev).catch(e => setStatus('Could not share the R1: ' + e.message));
+        }
+    });
+})();
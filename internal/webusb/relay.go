@@ -0,0 +1,281 @@
+// Package webusb reaches the R1 through a Chrome tab instead of libusb,
+// for systems where the app can't open the R1 itself, e.g. ChromeOS. The
+// tab (/webusb on the settings server) opens the R1 with WebUSB and
+// polls the app for control transfers to perform on it, posting back
+// each result. The app treats it like a local device, minus bus-level
+// checks.
+package webusb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/HopIT-Hub/R1-Control/aoa"
+	"github.com/HopIT-Hub/R1-Control/internal/device"
+)
+
+// Timings of the relay.
+const (
+	// PollWait is how long a poll for the next transfer waits before
+	// returning nothing; the tab polls again right away.
+	PollWait = 5 * time.Second
+
+	// transferTimeout bounds a transfer from queueing to its result; it
+	// covers the R1's own 1s USB timeout and the round trip to the tab.
+	transferTimeout = 3 * time.Second
+
+	// staleAfter is how long without a poll before the tab counts as
+	// gone, e.g. closed without saying so.
+	staleAfter = PollWait + 5*time.Second
+)
+
+// Operations a transfer asks the tab to perform.
+const (
+	OpControl = "control" // control transfer out
+	OpPing    = "ping"    // check the R1 is still open
+)
+
+// errNotShared is returned while no tab shares an R1.
+var errNotShared = errors.New("no R1 shared over WebUSB — open /webusb from the settings page in Chrome")
+
+// errUnshared fails transfers on a connection whose tab stopped sharing.
+var errUnshared = errors.New("the browser tab stopped sharing the R1")
+
+// Info identifies the R1 a tab shares.
+type Info struct {
+	Serial  string `json:"serial"`
+	Product string `json:"product"`
+}
+
+// Transfer is one operation for the tab to perform.
+type Transfer struct {
+	ID          uint64 `json:"id"`
+	Op          string `json:"op"`
+	RequestType uint8  `json:"request_type,omitempty"` // bmRequestType
+	Request     uint8  `json:"request,omitempty"`
+	Value       uint16 `json:"value,omitempty"`
+	Index       uint16 `json:"index,omitempty"`
+	Data        []byte `json:"data,omitempty"`
+}
+
+// pending is a transfer waiting for its result.
+type pending struct {
+	Transfer
+	done chan error
+}
+
+// Relay hands transfers to the tab sharing the R1 and their results back.
+type Relay struct {
+	mu       sync.Mutex
+	info     *Info  // nil while no tab shares an R1
+	session  uint64 // bumped whenever a tab starts or stops sharing
+	lastPoll time.Time
+	nextID   uint64
+	queue    chan *pending
+	waiting  map[uint64]*pending
+}
+
+// New returns a relay with no tab attached.
+func New() *Relay {
+	return &Relay{
+		queue:   make(chan *pending, 16),
+		waiting: make(map[uint64]*pending),
+	}
+}
+
+// Opener returns a device.Opener that opens the R1 shared by the tab.
+func (r *Relay) Opener() device.Opener {
+	return func() (device.Transport, error) {
+		c, err := r.open()
+		if err != nil {
+			return nil, err
+		}
+		return aoa.NewDevice(c), nil
+	}
+}
+
+// Attach starts a session for a tab sharing the R1 described by info,
+// ending any previous one.
+func (r *Relay) Attach(info Info) {
+	r.mu.Lock()
+	r.endLocked()
+	r.info = &info
+	r.lastPoll = time.Now()
+	r.mu.Unlock()
+}
+
+// Detach ends the session, e.g. when the R1 is unplugged from the tab.
+func (r *Relay) Detach() {
+	r.mu.Lock()
+	r.endLocked()
+	r.mu.Unlock()
+}
+
+// endLocked fails the transfers of the current session and forgets its
+// R1. Must be called with r.mu held.
+func (r *Relay) endLocked() {
+	r.info = nil
+	r.session++
+	for id, p := range r.waiting {
+		p.done <- errUnshared
+		delete(r.waiting, id)
+	}
+	for {
+		select {
+		case <-r.queue:
+		default:
+			return
+		}
+	}
+}
+
+// Shared returns the R1 a tab shares, if one does.
+func (r *Relay) Shared() (Info, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.liveLocked() {
+		return Info{}, false
+	}
+	return *r.info, true
+}
+
+// liveLocked reports whether a tab shares an R1 and still polls. Must be
+// called with r.mu held.
+func (r *Relay) liveLocked() bool {
+	return r.info != nil && time.Since(r.lastPoll) < staleAfter
+}
+
+// Next waits up to PollWait for a transfer for the tab. ok is false if
+// there is none; it returns an error if the tab isn't attached, e.g.
+// after the app restarted, so the tab knows to attach again. Transfers
+// that timed out while queued are dropped rather than handed out late.
+func (r *Relay) Next() (t Transfer, ok bool, err error) {
+	r.mu.Lock()
+	if r.info == nil {
+		r.mu.Unlock()
+		return Transfer{}, false, errNotShared
+	}
+	r.lastPoll = time.Now()
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.lastPoll = time.Now()
+		r.mu.Unlock()
+	}()
+	timer := time.NewTimer(PollWait)
+	defer timer.Stop()
+	for {
+		select {
+		case p := <-r.queue:
+			r.mu.Lock()
+			_, live := r.waiting[p.ID]
+			r.mu.Unlock()
+			if live {
+				return p.Transfer, true, nil
+			}
+		case <-timer.C:
+			return Transfer{}, false, nil
+		}
+	}
+}
+
+// Done records the result of transfer id; errMsg is "" on success.
+func (r *Relay) Done(id uint64, errMsg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.waiting[id]
+	if !ok {
+		return // timed out, or from an ended session
+	}
+	delete(r.waiting, id)
+	if errMsg != "" {
+		p.done <- errors.New(errMsg)
+	} else {
+		p.done <- nil
+	}
+}
+
+// open returns a connection to the shared R1.
+func (r *Relay) open() (*conn, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.liveLocked() {
+		return nil, errNotShared
+	}
+	return &conn{relay: r, session: r.session, info: *r.info}, nil
+}
+
+// do queues t for the tab of session and waits for its result.
+func (r *Relay) do(session uint64, t Transfer) error {
+	r.mu.Lock()
+	if session != r.session || !r.liveLocked() {
+		r.mu.Unlock()
+		return errUnshared
+	}
+	r.nextID++
+	t.ID = r.nextID
+	p := &pending{Transfer: t, done: make(chan error, 1)}
+	r.waiting[t.ID] = p
+	select {
+	case r.queue <- p:
+	default:
+		delete(r.waiting, t.ID)
+		r.mu.Unlock()
+		return fmt.Errorf("webusb: too many transfers queued")
+	}
+	r.mu.Unlock()
+
+	timer := time.NewTimer(transferTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-p.done:
+		return err
+	case <-timer.C:
+		r.mu.Lock()
+		delete(r.waiting, t.ID)
+		r.mu.Unlock()
+		return fmt.Errorf("webusb: %s timed out", t.Op)
+	}
+}
+
+// conn implements aoa.USB through the tab of one session.
+type conn struct {
+	relay   *Relay
+	session uint64
+	info    Info
+}
+
+var _ aoa.USB = (*conn)(nil)
+
+func (c *conn) Control(rType, request uint8, val, idx uint16, data []byte) (int, error) {
+	if rType&0x80 != 0 {
+		return 0, fmt.Errorf("webusb: control transfers in aren't supported")
+	}
+	err := c.relay.do(c.session, Transfer{Op: OpControl, RequestType: rType, Request: request, Value: val, Index: idx, Data: data})
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (c *conn) Ping() error {
+	return c.relay.do(c.session, Transfer{Op: OpPing})
+}
+
+// Close leaves the tab sharing the R1, for the next connection.
+func (c *conn) Close() {}
+
+func (c *conn) Serial() string  { return c.info.Serial }
+func (c *conn) Product() string { return c.info.Product }
+
+// Location is unknown: the browser doesn't expose the bus.
+func (c *conn) Location() aoa.Location { return aoa.Location{} }
+
+// Speed is unknown: the browser doesn't expose it.
+func (c *conn) Speed() string { return "" }
+
+// Configured is true: the tab selects a configuration before sharing.
+func (c *conn) Configured() bool { return true }
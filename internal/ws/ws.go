@@ -0,0 +1,292 @@
+// Package ws is the server side of the WebSocket protocol (RFC 6455),
+// as much of it as the settings page and scripts need to exchange JSON
+// messages with the app: text messages, fragmentation, ping/pong and
+// close. Binary messages and extensions such as compression aren't
+// supported.
+package ws
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxMessage bounds a message from the client, fragments included.
+const MaxMessage = 64 << 10
+
+// writeTimeout bounds writing a frame, so a client that stopped reading
+// can't block its writers.
+const writeTimeout = 10 * time.Second
+
+// ErrClosed is returned by Read once the client has closed the
+// connection.
+var ErrClosed = errors.New("ws: connection closed")
+
+// Opcodes.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close status codes.
+const (
+	closeNormal      = 1000
+	closeProtocol    = 1002
+	closeUnsupported = 1003
+	closeTooBig      = 1009
+)
+
+// acceptGUID is appended to the client's key to compute the accept key.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Conn is an upgraded WebSocket connection. Read must be called from one
+// goroutine at a time; the write methods may be called from any.
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	wmu    sync.Mutex // one frame at a time
+	closed bool       // a close frame was sent
+}
+
+// IsUpgrade reports whether r asks for a WebSocket connection.
+func IsUpgrade(r *http.Request) bool {
+	return headerHas(r.Header, "Connection", "upgrade") && headerHas(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade completes the WebSocket handshake for r and takes over its
+// connection. On failure it has written an error response.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	switch {
+	case r.Method != "GET" || !IsUpgrade(r) || key == "":
+		http.Error(w, "expected a WebSocket handshake", http.StatusBadRequest)
+		return nil, errors.New("ws: not a WebSocket handshake")
+	case r.Header.Get("Sec-WebSocket-Version") != "13":
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("ws: unsupported version")
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("ws: hijack: %w", err)
+	}
+	// The server's read and write timeouts would end the connection
+	conn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := io.WriteString(conn, resp); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ws: handshake: %w", err)
+	}
+	conn.SetWriteDeadline(time.Time{})
+	return &Conn{conn: conn, br: brw.Reader}, nil
+}
+
+// headerHas reports whether the comma-separated header name has token,
+// ignoring case.
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Read returns the next text message. Pings are answered on the way. It
+// returns ErrClosed when the client closes the connection, and closes it
+// itself on a protocol error.
+func (c *Conn) Read() ([]byte, error) {
+	var msg []byte
+	started := false
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			if errors.Is(err, errTooBig) {
+				c.closeWith(closeTooBig, "message too big")
+			}
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.closeWith(closeNormal, "")
+			return nil, ErrClosed
+		case opBinary:
+			c.closeWith(closeUnsupported, "binary messages aren't supported")
+			return nil, errors.New("ws: binary message")
+		case opText:
+			if started {
+				c.closeWith(closeProtocol, "")
+				return nil, errors.New("ws: new message inside a fragmented one")
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				c.closeWith(closeProtocol, "")
+				return nil, errors.New("ws: continuation without a message")
+			}
+		default:
+			c.closeWith(closeProtocol, "")
+			return nil, fmt.Errorf("ws: unknown opcode %#x", op)
+		}
+		if len(msg)+len(payload) > MaxMessage {
+			c.closeWith(closeTooBig, "message too big")
+			return nil, errTooBig
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+var errTooBig = errors.New("ws: message too big")
+
+// readFrame reads one frame from the client and unmasks its payload.
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(c.br, hdr[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = hdr[0]&0x80 != 0
+	op = hdr[0] & 0x0F
+	if hdr[0]&0x70 != 0 {
+		c.closeWith(closeProtocol, "")
+		return false, 0, nil, errors.New("ws: reserved bits set")
+	}
+	if hdr[1]&0x80 == 0 {
+		c.closeWith(closeProtocol, "")
+		return false, 0, nil, errors.New("ws: unmasked frame from the client")
+	}
+
+	n := uint64(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= opClose && (n > 125 || !fin) {
+		c.closeWith(closeProtocol, "")
+		return false, 0, nil, errors.New("ws: invalid control frame")
+	}
+	if n > MaxMessage {
+		return false, 0, nil, errTooBig
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// Write sends data as a text message.
+func (c *Conn) Write(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// WriteJSON sends v encoded as JSON.
+func (c *Conn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Write(data)
+}
+
+// Ping sends a ping, which keeps proxies from dropping an idle
+// connection; a client that is gone makes a later write fail.
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// writeFrame sends one unfragmented, unmasked frame.
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if op == opClose {
+		c.closed = true
+	}
+
+	hdr := make([]byte, 2, 10)
+	hdr[0] = 0x80 | op
+	switch n := len(payload); {
+	case n <= 125:
+		hdr[1] = byte(n)
+	case n <= 0xFFFF:
+		hdr[1] = 126
+		hdr = binary.BigEndian.AppendUint16(hdr, uint16(n))
+	default:
+		hdr[1] = 127
+		hdr = binary.BigEndian.AppendUint64(hdr, uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	defer c.conn.SetWriteDeadline(time.Time{})
+	if _, err := c.conn.Write(append(hdr, payload...)); err != nil {
+		return fmt.Errorf("ws: write: %w", err)
+	}
+	return nil
+}
+
+// closeWith sends a close frame with code and reason, if none was sent
+// yet.
+func (c *Conn) closeWith(code uint16, reason string) {
+	payload := binary.BigEndian.AppendUint16(nil, code)
+	c.writeFrame(opClose, append(payload, reason...))
+}
+
+// Close says goodbye to the client and closes the connection.
+func (c *Conn) Close() error {
+	c.closeWith(closeNormal, "")
+	return c.conn.Close()
+}